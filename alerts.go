@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/candb"
+)
+
+// Alert conditions of AlertRule.
+const (
+	alertChange = "change"
+)
+
+// AlertRule watches a byte or a decoded signal of received frames.
+type AlertRule struct {
+	Name string `json:"name"`
+	// Interface is the interface whose frames are watched, empty for all.
+	Interface string `json:"interface"`
+	// ID and Extended select the frames of byte rules. Signal rules find the message
+	// of the signal in the loaded databases.
+	ID       uint32 `json:"id"`
+	Extended bool   `json:"extended"`
+	// Signal is the watched signal, empty to watch the byte at index Byte.
+	Signal string `json:"signal"`
+	// Message is the message of Signal, needed when several messages have a signal
+	// of that name.
+	Message string `json:"message"`
+	Byte    int    `json:"byte"`
+	// Mask selects the watched bits of the byte, 0 for all.
+	Mask uint8 `json:"mask"`
+	// Condition is "change" to alert when the value changes, or one of >, >=, <,
+	// <=, == and != to alert when the comparison with Value becomes true.
+	Condition string  `json:"condition"`
+	Value     float64 `json:"value"`
+}
+
+// AlertRuleInfo describes an alert rule added with AddAlertRule.
+type AlertRuleInfo struct {
+	Handle int       `json:"handle"`
+	Rule   AlertRule `json:"rule"`
+	Hits   uint64    `json:"hits"`
+}
+
+// AlertEvent is emitted on "can:alert" when a rule triggers.
+type AlertEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Handle    int       `json:"handle"`
+	Name      string    `json:"name"`
+	Interface string    `json:"interface"`
+	ID        uint32    `json:"id"`
+	Extended  bool      `json:"extended"`
+	// Before is the value of the previous frame, nil for the first frame of the rule.
+	Before *float64 `json:"before"`
+	After  float64  `json:"after"`
+	Data   []uint32 `json:"data"`
+}
+
+type alertRule struct {
+	handle int
+	rule   AlertRule
+	hits   uint64
+	// last and active are the previous value and comparison result by interface.
+	last   map[string]float64
+	active map[string]bool
+}
+
+// AddAlertRule registers a watch rule, eg byte 3 of ID 0x1A0 changes or signal
+// EngineSpeed > 3000, and returns a handle for RemoveAlertRule. Rules are evaluated
+// on every received frame and emit "can:alert" with the value before and after.
+func (a *App) AddAlertRule(rule AlertRule) (int, error) {
+	rule.Interface = strings.TrimSpace(rule.Interface)
+	rule.Signal = strings.TrimSpace(rule.Signal)
+	rule.Message = strings.TrimSpace(rule.Message)
+	switch rule.Condition {
+	case alertChange, ">", ">=", "<", "<=", "==", "!=":
+	default:
+		return 0, fmt.Errorf("invalid condition %q, want change, >, >=, <, <=, == or !=", rule.Condition)
+	}
+	if rule.Signal != "" {
+		m, err := a.signalMessage(rule.Message, rule.Signal)
+		if err != nil {
+			return 0, err
+		}
+		rule.Message, rule.ID, rule.Extended = m.Name, m.ID, m.IsExtended
+	} else if rule.Byte < 0 || rule.Byte >= canbus.MaxFDDataLength {
+		return 0, fmt.Errorf("byte index %d out of range 0-%d", rule.Byte, canbus.MaxFDDataLength-1)
+	}
+	if err := (&canbus.Frame{ID: rule.ID, IsExtended: rule.Extended}).Validate(); err != nil {
+		return 0, err
+	}
+
+	a.alertMu.Lock()
+	defer a.alertMu.Unlock()
+	a.nextAlert++
+	a.alerts = append(a.alerts, &alertRule{
+		handle: a.nextAlert,
+		rule:   rule,
+		last:   make(map[string]float64),
+		active: make(map[string]bool),
+	})
+	return a.nextAlert, nil
+}
+
+// RemoveAlertRule removes an alert rule.
+func (a *App) RemoveAlertRule(handle int) error {
+	a.alertMu.Lock()
+	defer a.alertMu.Unlock()
+
+	for i, r := range a.alerts {
+		if r.handle == handle {
+			a.alerts = append(a.alerts[:i:i], a.alerts[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no alert rule with handle %d", handle)
+}
+
+// ClearAlertRules removes all the alert rules.
+func (a *App) ClearAlertRules() {
+	a.alertMu.Lock()
+	a.alerts = nil
+	a.alertMu.Unlock()
+}
+
+// ListAlertRules returns the alert rules by handle.
+func (a *App) ListAlertRules() []AlertRuleInfo {
+	a.alertMu.Lock()
+	defer a.alertMu.Unlock()
+
+	infos := make([]AlertRuleInfo, len(a.alerts))
+	for i, r := range a.alerts {
+		infos[i] = AlertRuleInfo{Handle: r.handle, Rule: r.rule, Hits: r.hits}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Handle < infos[j].Handle })
+	return infos
+}
+
+// signalMessage finds the message of a signal in the loaded databases.
+func (a *App) signalMessage(message, signal string) (*candb.Message, error) {
+	a.dbMu.RLock()
+	defer a.dbMu.RUnlock()
+
+	for _, db := range a.databases {
+		for _, m := range db.Messages {
+			if message != "" && m.Name != message {
+				continue
+			}
+			if _, ok := m.Signal(signal); ok {
+				return m, nil
+			}
+		}
+	}
+	if message != "" {
+		return nil, fmt.Errorf("no signal %s in message %s of the loaded databases", signal, message)
+	}
+	return nil, fmt.Errorf("no signal %s in the loaded databases", signal)
+}
+
+// checkAlerts evaluates the alert rules on a frame received on iface.
+func (a *App) checkAlerts(iface string, ts time.Time, f *canbus.Frame) {
+	if f.IsRemote {
+		return
+	}
+	a.alertMu.Lock()
+	if len(a.alerts) == 0 {
+		a.alertMu.Unlock()
+		return
+	}
+	var events []AlertEvent
+	var msg *candb.Message
+	for _, r := range a.alerts {
+		if r.rule.ID != f.ID || r.rule.Extended != f.IsExtended || (r.rule.Interface != "" && r.rule.Interface != iface) {
+			continue
+		}
+		var value float64
+		if r.rule.Signal != "" {
+			if msg == nil {
+				var ok bool
+				if msg, ok = a.lookupMessage(f.ID, f.IsExtended); !ok {
+					continue
+				}
+			}
+			s, ok := msg.Signal(r.rule.Signal)
+			if !ok {
+				continue
+			}
+			v, ok := s.Decode(f.Payload())
+			if !ok {
+				continue
+			}
+			value = v.Physical
+		} else {
+			if r.rule.Byte >= int(f.Length) {
+				continue
+			}
+			b := f.Data[r.rule.Byte]
+			if r.rule.Mask != 0 {
+				b &= r.rule.Mask
+			}
+			value = float64(b)
+		}
+
+		before, seen := r.last[iface]
+		r.last[iface] = value
+		var fire bool
+		if r.rule.Condition == alertChange {
+			fire = seen && before != value
+		} else {
+			active := compare(value, r.rule.Condition, r.rule.Value)
+			fire = active && !r.active[iface]
+			r.active[iface] = active
+		}
+		if !fire {
+			continue
+		}
+		r.hits++
+		ev := AlertEvent{
+			Timestamp: ts,
+			Handle:    r.handle,
+			Name:      r.rule.Name,
+			Interface: iface,
+			ID:        f.ID,
+			Extended:  f.IsExtended,
+			After:     value,
+			Data:      dataWords(f.Payload()),
+		}
+		if seen {
+			ev.Before = &before
+		}
+		events = append(events, ev)
+	}
+	a.alertMu.Unlock()
+
+	for _, ev := range events {
+		a.emit("can:alert", ev)
+	}
+}
+
+func compare(v float64, op string, ref float64) bool {
+	switch op {
+	case ">":
+		return v > ref
+	case ">=":
+		return v >= ref
+	case "<":
+		return v < ref
+	case "<=":
+		return v <= ref
+	case "==":
+		return v == ref
+	case "!=":
+		return v != ref
+	}
+	return false
+}
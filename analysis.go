@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"canproject/analysis"
+	"canproject/capture"
+)
+
+// BitAnalysis is the result of AnalyzeBits.
+type BitAnalysis struct {
+	Interface string `json:"interface"`
+	ID        uint32 `json:"id"`
+	Extended  bool   `json:"extended"`
+	// Samples is the number of analysed frames.
+	Samples int `json:"samples"`
+	// Length is the longest payload.
+	Length     int     `json:"length"`
+	DurationMs float64 `json:"durationMs"`
+	// Changed has a bit set for every payload bit which toggled at least once.
+	Changed []uint32 `json:"changed"`
+	// Constant holds the value of the bits which never toggled.
+	Constant  []uint32            `json:"constant"`
+	Bits      []analysis.BitStats `json:"bits"`
+	Counters  []analysis.Counter  `json:"counters"`
+	Checksums []analysis.Checksum `json:"checksums"`
+}
+
+// AnalyzeBits reports, for the buffered frames of one ID within timeRange, which
+// payload bits toggle and how often, and which bytes are candidate rolling counters
+// or checksums. iface empty analyses the frames of all interfaces. Sent frames are
+// left out, they tell nothing about the sender of the ID.
+func (a *App) AnalyzeBits(iface string, id uint32, extended bool, timeRange TimeRange) (BitAnalysis, error) {
+	iface = strings.TrimSpace(iface)
+	mask := uint32(0x7ff)
+	if extended {
+		mask = 0x1fffffff
+	}
+	keep, err := captureMatcher(CaptureFilter{
+		Interface: iface,
+		IDs:       []CANFilter{{ID: id, Mask: mask, Extended: extended}},
+		Direction: "rx",
+	}, timeRange)
+	if err != nil {
+		return BitAnalysis{}, err
+	}
+	records := a.capture.Select(func(r *capture.Record) bool {
+		return !r.Frame.IsRemote && !r.Frame.IsError && keep(r)
+	})
+	if len(records) == 0 {
+		return BitAnalysis{}, errors.New("no frames of this ID in the capture buffer")
+	}
+	samples := make([]analysis.Sample, len(records))
+	for i := range records {
+		samples[i] = analysis.Sample{Time: records[i].Timestamp, Data: records[i].Frame.Payload()}
+	}
+	r := analysis.Bits(samples)
+	return BitAnalysis{
+		Interface:  iface,
+		ID:         id,
+		Extended:   extended,
+		Samples:    r.Samples,
+		Length:     r.Length,
+		DurationMs: milliseconds(r.Duration),
+		Changed:    dataWords(r.Changed),
+		Constant:   dataWords(r.Constant),
+		Bits:       r.Bits,
+		Counters:   r.Counters,
+		Checksums:  r.Checksums,
+	}, nil
+}
@@ -0,0 +1,279 @@
+// Package analysis helps reverse-engineering unknown messages from recorded
+// traffic: which bits of a payload change, how often, and which bytes look like
+// rolling counters or checksums.
+package analysis
+
+import (
+	"time"
+
+	"canproject/checksum"
+)
+
+// Sample is a payload received at a time.
+type Sample struct {
+	Time time.Time
+	Data []byte
+}
+
+// BitStats describes one payload bit, bit 0 being the least significant bit of the byte.
+type BitStats struct {
+	Byte int `json:"byte"`
+	Bit  int `json:"bit"`
+	// Toggles is the number of consecutive samples the bit differs in.
+	Toggles int `json:"toggles"`
+	// Ratio is Toggles over the number of compared sample pairs, 1 for a bit
+	// flipping in every frame.
+	Ratio float64 `json:"ratio"`
+	// Rate is the toggle frequency in Hz over the analysed window.
+	Rate float64 `json:"rate"`
+	// Ones is the fraction of samples the bit is set in.
+	Ones float64 `json:"ones"`
+}
+
+// Counter is a byte or nibble which increases by a constant step between samples.
+type Counter struct {
+	Byte int `json:"byte"`
+	// Mask selects the bits of the counter in the byte: 0xFF, 0x0F or 0xF0.
+	Mask uint8 `json:"mask"`
+	Step int   `json:"step"`
+	// Confidence is the fraction of sample pairs following the step.
+	Confidence float64 `json:"confidence"`
+}
+
+// Checksum is a byte which is a function of the other bytes of the payload, or
+// which changes like one.
+type Checksum struct {
+	Byte int `json:"byte"`
+	// Kind is the matching algorithm: "xor", "sum", "crc8-j1850", "crc8-autosar",
+	// or "unknown" for a byte that changes in nearly every frame with all its bits
+	// toggling randomly.
+	Kind string `json:"kind"`
+	// Confidence is the fraction of samples the algorithm matches.
+	Confidence float64 `json:"confidence"`
+}
+
+// BitReport is the result of Bits.
+type BitReport struct {
+	Samples int
+	// Length is the longest payload.
+	Length int
+	// Duration is the time between the first and the last sample.
+	Duration time.Duration
+	// Changed has a bit set for every payload bit which toggled at least once.
+	Changed []byte
+	// Constant holds the value of the bits which never toggled, from the last sample.
+	Constant  []byte
+	Bits      []BitStats
+	Counters  []Counter
+	Checksums []Checksum
+}
+
+// MinConfidence is the fraction of samples a counter or checksum candidate must match.
+const MinConfidence = 0.9
+
+// Bits analyses the payloads of one message in time order.
+func Bits(samples []Sample) BitReport {
+	r := BitReport{Samples: len(samples), Counters: []Counter{}, Checksums: []Checksum{}}
+	for i := range samples {
+		r.Length = max(r.Length, len(samples[i].Data))
+	}
+	r.Changed = make([]byte, r.Length)
+	r.Constant = make([]byte, r.Length)
+	r.Bits = make([]BitStats, 0, 8*r.Length)
+	if len(samples) == 0 {
+		return r
+	}
+	r.Duration = samples[len(samples)-1].Time.Sub(samples[0].Time)
+
+	toggles := make([]int, 8*r.Length)
+	ones := make([]int, 8*r.Length)
+	present := make([]int, r.Length)
+	pairs := make([]int, r.Length)
+	for i := range samples {
+		data := samples[i].Data
+		for n, b := range data {
+			present[n]++
+			for bit := 0; bit < 8; bit++ {
+				if b&(1<<bit) != 0 {
+					ones[8*n+bit]++
+				}
+			}
+		}
+		if i == 0 {
+			continue
+		}
+		prev := samples[i-1].Data
+		for n := 0; n < min(len(prev), len(data)); n++ {
+			pairs[n]++
+			diff := prev[n] ^ data[n]
+			for bit := 0; bit < 8; bit++ {
+				if diff&(1<<bit) != 0 {
+					toggles[8*n+bit]++
+				}
+			}
+		}
+	}
+
+	last := samples[len(samples)-1].Data
+	seconds := r.Duration.Seconds()
+	for n := 0; n < r.Length; n++ {
+		for bit := 0; bit < 8; bit++ {
+			k := 8*n + bit
+			s := BitStats{Byte: n, Bit: bit, Toggles: toggles[k]}
+			if pairs[n] > 0 {
+				s.Ratio = float64(toggles[k]) / float64(pairs[n])
+			}
+			if seconds > 0 {
+				s.Rate = float64(toggles[k]) / seconds
+			}
+			if present[n] > 0 {
+				s.Ones = float64(ones[k]) / float64(present[n])
+			}
+			if toggles[k] > 0 {
+				r.Changed[n] |= 1 << bit
+			} else if n < len(last) {
+				r.Constant[n] |= last[n] & (1 << bit)
+			}
+			r.Bits = append(r.Bits, s)
+		}
+	}
+
+	counterBytes := make(map[int]bool)
+	for n := 0; n < r.Length; n++ {
+		if r.Changed[n] == 0 {
+			continue
+		}
+		// a nibble counter wrapping around looks like a byte counter missing a
+		// step, keep the best match, the whole byte on a tie
+		var best Counter
+		for _, mask := range []uint8{0xff, 0x0f, 0xf0} {
+			if r.Changed[n]&mask == 0 {
+				continue
+			}
+			if c, ok := counter(samples, n, mask); ok && c.Confidence > best.Confidence {
+				best = c
+			}
+		}
+		if best.Mask != 0 {
+			r.Counters = append(r.Counters, best)
+			counterBytes[n] = true
+		}
+	}
+	for n := 0; n < r.Length; n++ {
+		if r.Changed[n] == 0 || counterBytes[n] {
+			continue
+		}
+		if c, ok := findChecksum(samples, n); ok {
+			r.Checksums = append(r.Checksums, c)
+		} else if randomByte(r.Bits[8*n:8*n+8], pairs[n]) {
+			r.Checksums = append(r.Checksums, Checksum{Byte: n, Kind: "unknown", Confidence: changeRatio(samples, n)})
+		}
+	}
+	return r
+}
+
+// counter tests whether the masked bits of byte n increase by a constant step.
+func counter(samples []Sample, n int, mask uint8) (Counter, bool) {
+	shift := 0
+	if mask == 0xf0 {
+		shift = 4
+	}
+	modulo := int(mask>>shift) + 1
+	steps := make(map[int]int)
+	pairs := 0
+	for i := 1; i < len(samples); i++ {
+		prev, data := samples[i-1].Data, samples[i].Data
+		if n >= len(prev) || n >= len(data) {
+			continue
+		}
+		a := int(prev[n]&mask) >> shift
+		b := int(data[n]&mask) >> shift
+		steps[(b-a+modulo)%modulo]++
+		pairs++
+	}
+	if pairs < 2 {
+		return Counter{}, false
+	}
+	best, hits := 0, 0
+	for step, count := range steps {
+		if count > hits || count == hits && step < best {
+			best, hits = step, count
+		}
+	}
+	confidence := float64(hits) / float64(pairs)
+	if best == 0 || confidence < MinConfidence {
+		return Counter{}, false
+	}
+	if best > modulo/2 {
+		// a decreasing counter
+		best -= modulo
+	}
+	return Counter{Byte: n, Mask: mask, Step: best, Confidence: confidence}, true
+}
+
+// checksums are the algorithms checksum tries, the first one wins a tie.
+var checksums = []checksum.Algorithm{
+	checksum.Algorithms["xor"],
+	checksum.Algorithms["sum"],
+	checksum.Algorithms["crc8-j1850"],
+	checksum.Algorithms["crc8-autosar"],
+}
+
+// findChecksum tests whether byte n is a checksum of the other bytes of the payload.
+func findChecksum(samples []Sample, n int) (Checksum, bool) {
+	var best Checksum
+	for _, c := range checksums {
+		matched, total := 0, 0
+		buf := make([]byte, 0, 64)
+		for i := range samples {
+			data := samples[i].Data
+			if n >= len(data) {
+				continue
+			}
+			buf = append(append(buf[:0], data[:n]...), data[n+1:]...)
+			total++
+			if c.Sum(buf) == data[n] {
+				matched++
+			}
+		}
+		if total < 2 {
+			return Checksum{}, false
+		}
+		if confidence := float64(matched) / float64(total); confidence > best.Confidence {
+			best = Checksum{Byte: n, Kind: c.Name, Confidence: confidence}
+		}
+	}
+	return best, best.Confidence >= MinConfidence
+}
+
+// randomByte reports whether all the bits of a byte toggle about every other frame.
+func randomByte(bits []BitStats, pairs int) bool {
+	if pairs < 8 {
+		return false
+	}
+	for _, b := range bits {
+		if b.Ratio < 0.25 || b.Ratio > 0.75 {
+			return false
+		}
+	}
+	return true
+}
+
+// changeRatio returns the fraction of sample pairs byte n differs in.
+func changeRatio(samples []Sample, n int) float64 {
+	changed, pairs := 0, 0
+	for i := 1; i < len(samples); i++ {
+		prev, data := samples[i-1].Data, samples[i].Data
+		if n >= len(prev) || n >= len(data) {
+			continue
+		}
+		pairs++
+		if prev[n] != data[n] {
+			changed++
+		}
+	}
+	if pairs == 0 {
+		return 0
+	}
+	return float64(changed) / float64(pairs)
+}
@@ -0,0 +1,153 @@
+package analysis
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+// CycleTolerance is the relative difference two median periods may have and
+// still be considered the same cycle time.
+const CycleTolerance = 0.1
+
+// Key identifies the frames of a CAN ID.
+type Key struct {
+	ID       uint32
+	Extended bool
+}
+
+// Difference describes an ID of both traces whose timing or payloads differ.
+type Difference struct {
+	Key
+	CountA, CountB int
+	// CycleA and CycleB are the median periods, 0 for an ID seen once.
+	CycleA, CycleB time.Duration
+	// CycleDiffers is set when the periods differ by more than CycleTolerance.
+	CycleDiffers bool
+	// LengthA and LengthB are the longest payloads.
+	LengthA, LengthB int
+	// ChangedA and ChangedB have a bit set for the payload bits which toggle in
+	// that trace only.
+	ChangedA, ChangedB []byte
+	// ConstantDiffers has a bit set for the bits constant in both traces, with
+	// different values.
+	ConstantDiffers []byte
+	// PatternDiffers is set when the lengths or any of the bit masks differ.
+	PatternDiffers bool
+}
+
+// Comparison is the result of Compare.
+type Comparison struct {
+	// OnlyA and OnlyB are the IDs present in one trace only, sorted.
+	OnlyA, OnlyB []Key
+	// Differences is sorted by ID.
+	Differences []Difference
+	// Same is the number of IDs of both traces with the same timing and payload
+	// pattern.
+	Same int
+}
+
+// Compare aligns the frames of two traces by ID and reports the IDs present
+// in one trace only and those whose cycle time or payload pattern differs. The
+// samples of each ID are in time order.
+func Compare(a, b map[Key][]Sample) Comparison {
+	c := Comparison{OnlyA: []Key{}, OnlyB: []Key{}, Differences: []Difference{}}
+	for k, sa := range a {
+		sb, ok := b[k]
+		if !ok {
+			c.OnlyA = append(c.OnlyA, k)
+			continue
+		}
+		if d, differs := compareID(k, sa, sb); differs {
+			c.Differences = append(c.Differences, d)
+		} else {
+			c.Same++
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			c.OnlyB = append(c.OnlyB, k)
+		}
+	}
+	sortKeys(c.OnlyA)
+	sortKeys(c.OnlyB)
+	sort.Slice(c.Differences, func(i, j int) bool { return keyLess(c.Differences[i].Key, c.Differences[j].Key) })
+	return c
+}
+
+func compareID(k Key, sa, sb []Sample) (Difference, bool) {
+	ra, rb := Bits(sa), Bits(sb)
+	d := Difference{
+		Key:     k,
+		CountA:  len(sa),
+		CountB:  len(sb),
+		CycleA:  medianPeriod(sa),
+		CycleB:  medianPeriod(sb),
+		LengthA: ra.Length,
+		LengthB: rb.Length,
+	}
+	d.CycleDiffers = cycleDiffers(d.CycleA, d.CycleB)
+
+	n := max(ra.Length, rb.Length)
+	d.ChangedA = make([]byte, n)
+	d.ChangedB = make([]byte, n)
+	d.ConstantDiffers = make([]byte, n)
+	d.PatternDiffers = ra.Length != rb.Length
+	for i := 0; i < n; i++ {
+		ca, cb := byteAt(ra.Changed, i), byteAt(rb.Changed, i)
+		d.ChangedA[i] = ca &^ cb
+		d.ChangedB[i] = cb &^ ca
+		// the bytes of the longer payloads alone have no constant to compare
+		if i < min(ra.Length, rb.Length) {
+			d.ConstantDiffers[i] = ^(ca | cb) & (ra.Constant[i] ^ rb.Constant[i])
+		}
+		if d.ChangedA[i]|d.ChangedB[i]|d.ConstantDiffers[i] != 0 {
+			d.PatternDiffers = true
+		}
+	}
+	return d, d.CycleDiffers || d.PatternDiffers
+}
+
+func byteAt(b []byte, i int) byte {
+	if i < len(b) {
+		return b[i]
+	}
+	return 0
+}
+
+// medianPeriod returns the median interval between the samples, which a few
+// late or missing frames do not move.
+func medianPeriod(samples []Sample) time.Duration {
+	if len(samples) < 2 {
+		return 0
+	}
+	periods := make([]time.Duration, 0, len(samples)-1)
+	for i := 1; i < len(samples); i++ {
+		periods = append(periods, samples[i].Time.Sub(samples[i-1].Time))
+	}
+	slices.Sort(periods)
+	return periods[len(periods)/2]
+}
+
+func cycleDiffers(a, b time.Duration) bool {
+	if a == 0 || b == 0 {
+		return a != b
+	}
+	diff := a - b
+	if diff < 0 {
+		diff = -diff
+	}
+	return float64(diff) > CycleTolerance*float64(max(a, b))
+}
+
+func sortKeys(keys []Key) {
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+}
+
+// keyLess orders the standard IDs before the extended ones.
+func keyLess(a, b Key) bool {
+	if a.Extended != b.Extended {
+		return !a.Extended
+	}
+	return a.ID < b.ID
+}
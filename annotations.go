@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"canproject/capture"
+)
+
+// maxAnnotationLength bounds the comments and tags of AnnotateFrame and AddBookmark.
+const maxAnnotationLength = 4096
+
+// Bookmark marks a time of the capture buffer, see AddBookmark.
+type Bookmark struct {
+	ID        uint64    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Comment   string    `json:"comment,omitempty"`
+	Tag       string    `json:"tag,omitempty"`
+}
+
+// AnnotateFrame sets the comment and tag of a buffered frame, identified by the
+// Seq of QueryCapture. Empty ones remove the annotation. The annotations are
+// returned by QueryCapture and written by ExportCapture, and dropped with their
+// frames.
+func (a *App) AnnotateFrame(seq uint64, comment, tag string) error {
+	note, err := annotation(comment, tag)
+	if err != nil {
+		return err
+	}
+	return a.capture.Annotate(seq, note)
+}
+
+// AddBookmark marks a time of the capture with a comment and a tag, now when
+// timestampMs (Unix milliseconds) is 0. The bookmarks are kept until
+// ClearCapture and written by ExportCapture with the frames around them.
+func (a *App) AddBookmark(timestampMs int64, comment, tag string) (Bookmark, error) {
+	note, err := annotation(comment, tag)
+	if err != nil {
+		return Bookmark{}, err
+	}
+	ts := time.Now()
+	if timestampMs != 0 {
+		ts = time.UnixMilli(timestampMs)
+	}
+	bm, err := a.capture.AddBookmark(ts, note)
+	if err != nil {
+		return Bookmark{}, err
+	}
+	return bookmark(bm), nil
+}
+
+// RemoveBookmark removes a bookmark added with AddBookmark.
+func (a *App) RemoveBookmark(id uint64) error {
+	return a.capture.RemoveBookmark(id)
+}
+
+// ListBookmarks returns the bookmarks in timeRange, sorted by timestamp.
+func (a *App) ListBookmarks(timeRange TimeRange) []Bookmark {
+	list := []Bookmark{}
+	for _, bm := range a.capture.Bookmarks(timeRange.bounds()) {
+		list = append(list, bookmark(bm))
+	}
+	return list
+}
+
+func annotation(comment, tag string) (capture.Annotation, error) {
+	note := capture.Annotation{Comment: strings.TrimSpace(comment), Tag: strings.TrimSpace(tag)}
+	if len(note.Comment) > maxAnnotationLength || len(note.Tag) > maxAnnotationLength {
+		return capture.Annotation{}, fmt.Errorf("comment or tag longer than %d bytes", maxAnnotationLength)
+	}
+	return note, nil
+}
+
+func bookmark(bm capture.Bookmark) Bookmark {
+	return Bookmark{ID: bm.ID, Timestamp: bm.Timestamp, Comment: bm.Comment, Tag: bm.Tag}
+}
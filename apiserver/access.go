@@ -0,0 +1,73 @@
+package apiserver
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CheckOrigin reports whether a browser request comes from the origin of the
+// server or one of origins, "*" for any. Requests without an Origin header,
+// which browsers always send cross-site, are allowed.
+func CheckOrigin(r *http.Request, origins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, o := range origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// CheckHost reports whether the Host of a request is "localhost", one of
+// hosts, or the IP address the request was received on. Other names are
+// rejected so a web page cannot reach the server through DNS rebinding, a
+// name of the attacker resolving to the address of the server.
+func CheckHost(r *http.Request, hosts []string) bool {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	for _, h := range hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	if !ok {
+		return false
+	}
+	tcp, ok := local.(*net.TCPAddr)
+	return ok && tcp.IP.Equal(ip)
+}
+
+// CheckToken reports whether the request carries token as
+// "Authorization: Bearer <token>", or in the token query parameter with
+// query.
+func CheckToken(r *http.Request, token string, query bool) bool {
+	got := ""
+	if query {
+		got = r.URL.Query().Get("token")
+	}
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		got = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
@@ -0,0 +1,508 @@
+// Package apiserver exposes the exported methods of an API object over WebSocket,
+// so the backend can be driven without the UI, and pushes its events to the
+// clients which subscribed to them.
+//
+// Clients send JSON requests and get a response with the same id:
+//
+//	{"id": 1, "method": "SendFrame", "params": ["vcan0", 291, [1, 2], false]}
+//	{"id": 1, "result": null}
+//	{"id": 2, "method": "GetStats", "params": ["can0"]}
+//	{"id": 2, "error": "CAN not started on can0"}
+//
+// The params are the arguments of the method in order. The methods "subscribe" and
+// "unsubscribe" take event patterns like "can:frame" or "can:*", the subscribed
+// events are pushed as {"event": "can:frame", "data": {...}}. "methods" returns the
+// names of the callable methods.
+//
+// Every client has its own flow control: its events are queued apart from the
+// responses, "flow" takes {"maxRate": 100, "dropOldest": true} to bound the
+// events per second it gets and to drop the oldest queued events rather than
+// the new ones when it does not keep up, and "stats" returns its counters. The
+// queue length is set with the queue query parameter of the connection, eg
+// /ws?queue=256.
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// sendQueue is the number of messages queued for a client by default,
+	// events are dropped when a client does not keep up; maxQueue bounds the
+	// queue parameter.
+	sendQueue    = 1024
+	maxQueue     = 65536
+	writeTimeout = 10 * time.Second
+	pingInterval = 30 * time.Second
+)
+
+// Options configures a Server.
+type Options struct {
+	// Token, if set, must be sent as "Authorization: Bearer <token>" or in the token
+	// query parameter.
+	Token string
+	// Origins are the browser origins allowed to connect besides the origin of the
+	// server, "*" for any. Clients sending no Origin header are always allowed.
+	Origins []string
+	// Hosts are the host names accepted in the Host header besides
+	// "localhost" and the IP addresses of the server, see CheckHost.
+	Hosts []string
+}
+
+// Server serves the methods of an API object. It is an http.Handler upgrading
+// requests to WebSocket connections.
+type Server struct {
+	opts     Options
+	methods  map[string]reflect.Value
+	upgrader websocket.Upgrader
+
+	// dropped counts the events not delivered to slow clients.
+	dropped atomic.Uint64
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+}
+
+type request struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	ID     json.RawMessage `json:"id"`
+	Result any             `json:"result"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type event struct {
+	Event string `json:"event"`
+	Data  any    `json:"data"`
+}
+
+// Flow is the flow control of the events of a client.
+type Flow struct {
+	// MaxRate bounds the events per second pushed to the client, the excess
+	// is dropped. Zero is unbounded.
+	MaxRate float64 `json:"maxRate"`
+	// DropOldest drops the oldest queued events when the queue is full, to
+	// keep the latest state, instead of the new events.
+	DropOldest bool `json:"dropOldest"`
+}
+
+// ClientStats describes a connected client.
+type ClientStats struct {
+	Remote        string   `json:"remote"`
+	Subscriptions []string `json:"subscriptions"`
+	Flow          Flow     `json:"flow"`
+	// Queue is the length of the event queue and Queued the events waiting
+	// in it.
+	Queue  int `json:"queue"`
+	Queued int `json:"queued"`
+	// Sent counts the events written to the client, Dropped the events lost
+	// because it did not keep up and Limited the events over its MaxRate.
+	Sent    uint64 `json:"sent"`
+	Dropped uint64 `json:"dropped"`
+	Limited uint64 `json:"limited"`
+}
+
+type client struct {
+	conn   *websocket.Conn
+	remote string
+	// send queues the responses, events the events
+	send   chan []byte
+	events chan []byte
+	done   chan struct{}
+	once   sync.Once
+
+	sent, dropped, limited atomic.Uint64
+
+	mu   sync.Mutex
+	subs []string
+	flow Flow
+	// tokens and refilled are the token bucket of flow.MaxRate.
+	tokens   float64
+	refilled time.Time
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// New returns a server calling the exported methods of api.
+func New(api any, opts Options) *Server {
+	s := &Server{
+		opts:    opts,
+		methods: make(map[string]reflect.Value),
+		clients: make(map[*client]struct{}),
+	}
+	s.upgrader.CheckOrigin = s.checkOrigin
+	v := reflect.ValueOf(api)
+	t := v.Type()
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if m.Type.IsVariadic() {
+			continue
+		}
+		s.methods[m.Name] = v.Method(i)
+	}
+	return s
+}
+
+// Methods returns the names of the callable methods, sorted.
+func (s *Server) Methods() []string {
+	names := make([]string, 0, len(s.methods))
+	for name := range s.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Emit pushes an event to the clients subscribed to it, within their flow
+// control. It does not block, a client which does not keep up misses events.
+func (s *Server) Emit(name string, payload any) {
+	now := time.Now()
+	s.mu.Lock()
+	var targets []*client
+	for c := range s.clients {
+		if c.accept(name, now) {
+			targets = append(targets, c)
+		}
+	}
+	s.mu.Unlock()
+	if len(targets) == 0 {
+		return
+	}
+
+	msg, err := json.Marshal(event{Event: name, Data: payload})
+	if err != nil {
+		return
+	}
+	for _, c := range targets {
+		if !c.queue(msg) {
+			c.dropped.Add(1)
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Clients returns the connected clients.
+func (s *Server) Clients() []ClientStats {
+	s.mu.Lock()
+	clients := make([]*client, 0, len(s.clients))
+	for c := range s.clients {
+		clients = append(clients, c)
+	}
+	s.mu.Unlock()
+	stats := make([]ClientStats, len(clients))
+	for i, c := range clients {
+		stats[i] = c.stats()
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Remote < stats[j].Remote })
+	return stats
+}
+
+// Dropped returns the number of events dropped because a client did not keep up.
+func (s *Server) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close disconnects the clients.
+func (s *Server) Close() {
+	s.mu.Lock()
+	clients := s.clients
+	s.clients = make(map[*client]struct{})
+	s.mu.Unlock()
+	for c := range clients {
+		c.close()
+	}
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and serves it.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !CheckHost(r, s.opts.Hosts) {
+		http.Error(w, "invalid host", http.StatusForbidden)
+		return
+	}
+	if s.opts.Token != "" && !s.authorized(r) {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	queue := sendQueue
+	if v := r.URL.Query().Get("queue"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxQueue {
+			http.Error(w, fmt.Sprintf("invalid queue %q, want 1 to %d", v, maxQueue), http.StatusBadRequest)
+			return
+		}
+		queue = n
+	}
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader answered the request
+		return
+	}
+	c := &client{
+		conn:   conn,
+		remote: r.RemoteAddr,
+		send:   make(chan []byte, sendQueue),
+		events: make(chan []byte, queue),
+		done:   make(chan struct{}),
+	}
+	s.mu.Lock()
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	go c.writeLoop()
+	s.readLoop(c)
+
+	s.mu.Lock()
+	delete(s.clients, c)
+	s.mu.Unlock()
+	c.close()
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	return CheckToken(r, s.opts.Token, true)
+}
+
+func (s *Server) checkOrigin(r *http.Request) bool {
+	return CheckOrigin(r, s.opts.Origins)
+}
+
+func (s *Server) readLoop(c *client) {
+	for {
+		_, msg, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req request
+		if err := json.Unmarshal(msg, &req); err != nil {
+			c.reply(response{Error: fmt.Sprintf("invalid request: %v", err)})
+			continue
+		}
+		// calls can take a while, eg UDS requests, serve the next requests meanwhile
+		go func() {
+			result, err := s.call(c, req.Method, req.Params)
+			resp := response{ID: req.ID, Result: result}
+			if err != nil {
+				resp.Result, resp.Error = nil, err.Error()
+			}
+			c.reply(resp)
+		}()
+	}
+}
+
+// call runs a method with the JSON encoded params.
+func (s *Server) call(c *client, method string, params []json.RawMessage) (result any, err error) {
+	switch method {
+	case "subscribe", "unsubscribe":
+		patterns := make([]string, len(params))
+		for i, p := range params {
+			if err := json.Unmarshal(p, &patterns[i]); err != nil {
+				return nil, fmt.Errorf("param %d: %w", i+1, err)
+			}
+			if _, err := path.Match(patterns[i], ""); err != nil {
+				return nil, fmt.Errorf("param %d: invalid pattern %q", i+1, patterns[i])
+			}
+		}
+		return c.subscribe(patterns, method == "subscribe"), nil
+	case "flow":
+		var f Flow
+		if len(params) != 1 {
+			return nil, fmt.Errorf("flow takes 1 param, got %d", len(params))
+		}
+		if err := json.Unmarshal(params[0], &f); err != nil {
+			return nil, fmt.Errorf("param 1: %w", err)
+		}
+		if f.MaxRate < 0 {
+			return nil, fmt.Errorf("invalid maxRate %g", f.MaxRate)
+		}
+		c.setFlow(f)
+		return c.stats(), nil
+	case "stats":
+		return c.stats(), nil
+	case "methods":
+		return s.Methods(), nil
+	}
+
+	m, ok := s.methods[method]
+	if !ok {
+		return nil, fmt.Errorf("no method %q", method)
+	}
+	t := m.Type()
+	if len(params) != t.NumIn() {
+		return nil, fmt.Errorf("%s takes %d params, got %d", method, t.NumIn(), len(params))
+	}
+	args := make([]reflect.Value, len(params))
+	for i, p := range params {
+		arg := reflect.New(t.In(i))
+		if err := json.Unmarshal(p, arg.Interface()); err != nil {
+			return nil, fmt.Errorf("param %d: %w", i+1, err)
+		}
+		args[i] = arg.Elem()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", method, r)
+		}
+	}()
+	out := m.Call(args)
+	if n := len(out); n > 0 && t.Out(n-1) == errorType {
+		if e := out[n-1].Interface(); e != nil {
+			err = e.(error)
+		}
+		out = out[:n-1]
+	}
+	if len(out) > 0 {
+		result = out[0].Interface()
+	}
+	return result, err
+}
+
+func (c *client) reply(resp response) {
+	msg, err := json.Marshal(resp)
+	if err != nil {
+		msg, _ = json.Marshal(response{ID: resp.ID, Error: fmt.Sprintf("encode result: %v", err)})
+	}
+	select {
+	case c.send <- msg:
+	case <-c.done:
+	}
+}
+
+// subscribe adds or removes event patterns and returns the subscriptions.
+func (c *client) subscribe(patterns []string, add bool) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, p := range patterns {
+		i := sort.SearchStrings(c.subs, p)
+		found := i < len(c.subs) && c.subs[i] == p
+		switch {
+		case add && !found:
+			c.subs = append(c.subs[:i], append([]string{p}, c.subs[i:]...)...)
+		case !add && found:
+			c.subs = append(c.subs[:i], c.subs[i+1:]...)
+		}
+	}
+	return append([]string{}, c.subs...)
+}
+
+// accept reports whether the event name is pushed to the client: it is
+// subscribed to it and under its rate.
+func (c *client) accept(name string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	subscribed := false
+	for _, p := range c.subs {
+		if ok, _ := path.Match(p, name); ok {
+			subscribed = true
+			break
+		}
+	}
+	if !subscribed || c.flow.MaxRate == 0 {
+		return subscribed
+	}
+	// the bucket holds up to a second of events
+	c.tokens = min(c.tokens+now.Sub(c.refilled).Seconds()*c.flow.MaxRate, max(c.flow.MaxRate, 1))
+	c.refilled = now
+	if c.tokens < 1 {
+		c.limited.Add(1)
+		return false
+	}
+	c.tokens--
+	return true
+}
+
+// queue queues an event, making room by dropping the oldest one with
+// Flow.DropOldest. It reports whether the event was queued without dropping
+// any.
+func (c *client) queue(msg []byte) bool {
+	select {
+	case c.events <- msg:
+		return true
+	default:
+	}
+	c.mu.Lock()
+	dropOldest := c.flow.DropOldest
+	c.mu.Unlock()
+	if !dropOldest {
+		return false
+	}
+	select {
+	case <-c.events:
+	default:
+	}
+	select {
+	case c.events <- msg:
+	default:
+		// another event took the room, msg is the one dropped
+	}
+	return false
+}
+
+func (c *client) setFlow(f Flow) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flow = f
+	c.tokens, c.refilled = max(f.MaxRate, 1), time.Now()
+}
+
+func (c *client) stats() ClientStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ClientStats{
+		Remote:        c.remote,
+		Subscriptions: append([]string{}, c.subs...),
+		Flow:          c.flow,
+		Queue:         cap(c.events),
+		Queued:        len(c.events),
+		Sent:          c.sent.Load(),
+		Dropped:       c.dropped.Load(),
+		Limited:       c.limited.Load(),
+	}
+}
+
+func (c *client) writeLoop() {
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			err = c.conn.WriteMessage(websocket.TextMessage, msg)
+		case msg := <-c.events:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err = c.conn.WriteMessage(websocket.TextMessage, msg); err == nil {
+				c.sent.Add(1)
+			}
+		case <-ping.C:
+			err = c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+		}
+		if err != nil {
+			c.close()
+			return
+		}
+	}
+}
+
+func (c *client) close() {
+	c.once.Do(func() {
+		close(c.done)
+		_ = c.conn.Close()
+	})
+}
@@ -5,93 +5,447 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"go.einride.tech/can"
-	"go.einride.tech/can/pkg/socketcan"
-
 	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"canproject/canbus"
+	"canproject/candb"
+	"canproject/canopen"
+	"canproject/canstats"
+	"canproject/capture"
+	"canproject/dissect"
+	"canproject/dtc"
+	"canproject/j1939"
+	"canproject/nm"
+	"canproject/nmea2000"
+	"canproject/sequence"
+	"canproject/timing"
 )
 
+// eventSink receives the events emitted by the app.
+type eventSink interface {
+	Emit(event string, payload any)
+}
+
 // App struct
 type App struct {
 	ctx context.Context
 
-	mu      sync.Mutex
-	session *canSession
+	mu       sync.Mutex
+	sessions map[string]*canSession
+
+	dbMu      sync.RWMutex
+	databases []*candb.Database
+	// txTemplates are the payloads last sent with EncodeAndSend by message name.
+	txTemplates map[string][]byte
+	// dbModified are the paths of the databases edited and not saved.
+	dbModified map[string]bool
+
+	cyclicMu   sync.Mutex
+	cyclicJobs map[int]*cyclicJob
+	nextCyclic int
+	// cyclicIDs are the IDs of cyclicJobs checked for conflicts with the received
+	// frames, conflicts the conflicts detected.
+	cyclicIDs  atomic.Pointer[map[conflictKey]int]
+	conflictMu sync.Mutex
+	conflicts  map[conflictKey]*idConflict
+	// txProcs are the processors of SetTXProcessor, txProcMu serializes their changes.
+	txProcMu sync.Mutex
+	txProcs  atomic.Pointer[map[messageKey]*txProcessor]
+	// e2eMessages are the protections of SetE2EProtection by interface and ID,
+	// e2eChangeMu serializes their changes and e2eMu guards their states.
+	e2eChangeMu sync.Mutex
+	e2eMessages atomic.Pointer[map[conflictKey]*e2eMessage]
+	e2eMu       sync.Mutex
+
+	// selfTest is the run of SelfTest in progress.
+	selfTest atomic.Pointer[selfTestRun]
+
+	// journal are the actions of the session, journalReplay cancels the running
+	// ReplaySession.
+	journalMu     sync.Mutex
+	journal       []JournalEntry
+	journalReplay context.CancelFunc
+
+	logMu  sync.Mutex
+	logger *frameLogger
+	pcap   *frameLogger
+	mdf    *frameLogger
+
+	// capture keeps the recent frames of all interfaces for QueryCapture and ExportCapture.
+	capture *capture.Buffer
+
+	// generators are the traffic generators of StartGenerator by handle.
+	genMu         sync.Mutex
+	generators    map[int]*trafficGenerator
+	nextGenerator int
+
+	// fuzzers are the fuzzers of StartFuzzer by handle, fuzzFindings their
+	// findings.
+	fuzzMu       sync.Mutex
+	fuzzers      map[int]*canFuzzer
+	nextFuzzer   int
+	fuzzFindings []FuzzFinding
+
+	replayMu sync.Mutex
+	replay   *replayer
+
+	// isotpMu guards the ISO-TP channels and the DoIP connections, which share
+	// their handles.
+	isotpMu       sync.Mutex
+	isotpChannels map[int]*isotpChannel
+	doipConns     map[int]*doipConnection
+	nextIsoTP     int
+
+	// dtcDatabase describes the DTCs read with UDSReadDTCs and OBDReadDTCs.
+	dtcDatabase atomic.Pointer[dtc.Database]
+
+	// odx is the diagnostic description of LoadODX.
+	odx atomic.Pointer[odxDescription]
+
+	// securityAlgo is the seed-key algorithm of UDSSecurityAccess.
+	securityAlgo atomic.Pointer[securityAlgorithm]
+
+	// keepAlives are the TesterPresent keep-alives of SetUDSKeepAlive by handle.
+	keepAliveMu sync.Mutex
+	keepAlives  map[int]*keepAlive
+
+	// diagRec is the diagnostic recording of StartDiagRecording, diagReport
+	// the report of the last one stopped.
+	diagMu     sync.Mutex
+	diagRec    atomic.Pointer[diagRecorder]
+	diagReport *DiagReport
+
+	// flashJobs are the firmware downloads of UDSFlash by ISO-TP channel.
+	flashMu   sync.Mutex
+	flashJobs map[int]*flashJob
+
+	obdMu      sync.Mutex
+	obdPollers map[string]*obdPoller
+	obdWaiters map[*obdWaiter]struct{}
+
+	txHistMu      sync.Mutex
+	txHistory     []txRecord
+	nextTxHistory int
+
+	seqMu     sync.Mutex
+	sequences map[string]*sequence.Sequence
+	seqRuns   map[string]*sequenceRun
+
+	scriptMu   sync.Mutex
+	scripts    []*frameScript
+	nextScript int
+
+	// responder is the loaded ECU simulation profile, paused while responderOff is set.
+	responder    atomic.Pointer[ecuResponder]
+	responderOff atomic.Bool
+
+	// batcher is set while received frames are emitted in batches, batchMu serializes its changes.
+	batchMu sync.Mutex
+	batcher atomic.Pointer[frameBatcher]
+
+	// trigger is the trigger armed with ArmTrigger, triggerMu serializes its changes.
+	triggerMu sync.Mutex
+	trigger   atomic.Pointer[frameTrigger]
+
+	// frameChans are the subscriptions of SubscribeFrames, frameChanMu serializes
+	// their changes.
+	frameChanMu  sync.Mutex
+	frameChans   atomic.Pointer[frameChannels]
+	nextFrameSub int
+
+	// gapRules are the rules of AddGapTransmit, gapMu serializes their changes.
+	gapMu    sync.Mutex
+	gapRules atomic.Pointer[[]*gapRule]
+	nextGap  int
+
+	// linClusters are the LDFs of LoadLDF, linData the payloads of SetLINFrameData
+	// by frame and linSchedules the schedule tables running by interface.
+	linMu        sync.Mutex
+	linClusters  []*linCluster
+	linData      map[string][]byte
+	linSchedules map[string]*linSchedule
+
+	// groups are the frame groups of SetFrameGroups, replaced as a whole.
+	groups atomic.Pointer[[]FrameGroup]
+
+	// txPanels are the TX panels of SaveTXPanel by name.
+	txPanelMu sync.Mutex
+	txPanels  map[string]TXPanel
+
+	// overview is set while the overview mode is enabled, overviewMu serializes its changes.
+	overviewMu sync.Mutex
+	overview   atomic.Pointer[idOverview]
+
+	// alerts are the watch rules of AddAlertRule, evaluated on every received frame.
+	alertMu   sync.Mutex
+	alerts    []*alertRule
+	nextAlert int
+
+	// sdoClients are the CANopen SDO clients and dictionaries the object dictionaries
+	// of LoadEDS, by interface and node.
+	canopenMu    sync.Mutex
+	sdoClients   map[sdoKey]*canopen.SDOClient
+	dictionaries map[sdoKey]*canopen.ObjectDictionary
+
+	// xcpSessions are the XCP masters of XCPConnect by handle.
+	xcpMu       sync.Mutex
+	xcpSessions map[int]*xcpSession
+	nextXCP     int
+
+	// rest is the REST API of StartRESTServer.
+	restMu sync.Mutex
+	rest   *restServer
+
+	// dissectors are the dissectors of LoadDissectorPlugin, dissectMu
+	// serializes their loading.
+	dissectMu  sync.Mutex
+	dissectors dissect.Registry
+
+	// bridge is the input bridge of StartInputBridge.
+	bridgeMu sync.Mutex
+	bridge   *inputBridge
+
+	// gps is the receiver of StartGPS.
+	gpsMu sync.Mutex
+	gps   *gpsReceiver
+
+	// events is the event server of StartEventServer.
+	eventsMu sync.Mutex
+	events   *eventServer
+
+	// mqtt is the MQTT bridge of StartMQTTBridge.
+	mqttMu sync.Mutex
+	mqtt   *mqttBridge
+
+	// tsdb is the time series recording of StartSignalRecording.
+	tsdbMu sync.Mutex
+	tsdb   *tsdbRecorder
+
+	// plots are the signal histories of TrackPlotSignals, replaced as a whole.
+	plotMu sync.Mutex
+	plots  atomic.Pointer[map[PlotSignal]*plotSeries]
+
+	// sinks receive the events besides the UI, eg the clients of the headless
+	// mode or of StartEventServer, replaced as a whole under sinkMu.
+	sinkMu sync.Mutex
+	sinks  atomic.Pointer[[]eventSink]
+
+	// rxPipeline are the stages of the received frames.
+	rxPipeline *framePipeline
+
+	// linkStates are the last reported states of the SocketCAN interfaces by name.
+	linkMu     sync.Mutex
+	linkStates map[string]linkState
 }
 
 type canSession struct {
 	iface  string
 	ctx    context.Context
 	cancel context.CancelFunc
-	conn   net.Conn
-	rx     *socketcan.Receiver
-	tx     *socketcan.Transmitter
+	conn   canbus.Bus
+	fd     bool
 	done   chan struct{}
+	// opts are the options the session was started with, to reconnect it.
+	opts CANOptions
+	// reconnect is set when the session reconnects after its connection failed,
+	// linkUp wakes it up when the interface comes back.
+	reconnect atomic.Bool
+	linkUp    chan struct{}
+	// reconnecting is set while reconnect waits for the interface to come back.
+	reconnecting atomic.Bool
+
+	// filters are the receive filters applied with SetFilters, nil when all frames are received.
+	filters []CANFilter
+	// sockOpts are the socket options set with SetSocketOptions.
+	sockOpts SocketOptions
+	// j1939 reassembles J1939 messages when decoding is enabled with SetJ1939Decoding.
+	j1939 atomic.Pointer[j1939.Reassembler]
+	// canopen tracks the CANopen nodes when decoding is enabled with SetCANopenDecoding.
+	canopen atomic.Pointer[canopenNodes]
+	// nmea2000 decodes NMEA 2000 messages when enabled with SetNMEA2000Decoding.
+	nmea2000 atomic.Pointer[nmea2000.Decoder]
+	// nm tracks the network management of the bus when enabled with SetNMDecoding.
+	nm atomic.Pointer[nm.Tracker]
+
+	// stats counts the traffic of the interface, lastStats is the last "can:stats" event.
+	stats     *canstats.Collector
+	lastStats atomic.Pointer[CANStats]
+	// kernelDrops counts the frames the kernel dropped because the socket
+	// receive queue was full, over all the connections of the session.
+	kernelDrops atomic.Uint64
+	// timing learns the cycle times of the received IDs and reports their violations.
+	timing *timing.Monitor
+
+	// txq is the TX queue of QueueFrame, created on first use.
+	txq *txQueue
+
+	// bus tracks the controller state reported by error frames and the kernel.
+	bus *busMonitor
+
+	// timestampMode is set with SetTimestampMode, clockOffset is the offset of
+	// the controller clock measured in synced mode once clockSynced is set.
+	timestampMode atomic.Int32
+	clockOffset   atomic.Int64
+	clockSynced   atomic.Bool
+}
+
+// CANOptions configures a session opened with StartCANWithOptions.
+type CANOptions struct {
+	// FD enables CAN FD frames (up to 64 bytes) on the socket.
+	FD bool `json:"fd"`
+	// AutoReconnect reconnects the interface when its connection fails, see SetAutoReconnect.
+	AutoReconnect bool `json:"autoReconnect"`
+	// ReadOnly opens the session without a transmitter: every API sending on the
+	// interface fails, so that sniffing a live vehicle cannot inject frames by
+	// mistake. It lasts until the interface is stopped.
+	ReadOnly bool `json:"readOnly"`
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	a := &App{
+		sessions:      make(map[string]*canSession),
+		cyclicJobs:    make(map[int]*cyclicJob),
+		isotpChannels: make(map[int]*isotpChannel),
+		obdPollers:    make(map[string]*obdPoller),
+		obdWaiters:    make(map[*obdWaiter]struct{}),
+		sdoClients:    make(map[sdoKey]*canopen.SDOClient),
+		dictionaries:  make(map[sdoKey]*canopen.ObjectDictionary),
+		xcpSessions:   make(map[int]*xcpSession),
+		capture:       capture.NewBuffer(capture.DefaultSize),
+	}
+	a.rxPipeline = a.newRxPipeline()
+	return a
 }
 
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.start(ctx)
+}
+
+// start starts the background monitors of the app, which run until ctx is done.
+func (a *App) start(ctx context.Context) {
+	go a.watchLinks(ctx)
+	go a.e2eLoop(ctx)
 }
 
 func (a *App) shutdown(ctx context.Context) {
-	_ = a.StopCAN()
+	_ = a.StopAllCAN()
+	_, _ = a.StopLogging()
+	_, _ = a.StopPcapCapture()
+	_, _ = a.StopMDFRecording()
+	_ = a.SetFrameBatching(FrameBatchOptions{})
+	_ = a.SetOverview(OverviewOptions{})
+	_ = a.StopRESTServer()
+	_ = a.StopEventServer()
+	_ = a.StopMQTTBridge()
+	_, _ = a.StopInputBridge()
+	_, _ = a.StopGPS()
+	_, _ = a.StopSignalRecording()
+	a.DisarmTrigger()
+	a.ClearGapTransmits()
+	a.stopKeepAlives()
+	a.closeDoIPConnections()
+	_ = a.capture.Close()
 }
 
 type CANFrameEvent struct {
 	Timestamp time.Time `json:"timestamp"`
-	Interface string    `json:"interface"`
-	ID        uint32    `json:"id"`
-	Extended  bool      `json:"extended"`
-	Remote    bool      `json:"remote"`
-	DLC       uint8     `json:"dlc"`
-	Data      []uint32  `json:"data"`
+	// TimestampSource is "hardware" or "kernel" when Timestamp is the reception time
+	// reported by the CAN controller or the kernel, "app" when the app read the frame.
+	TimestampSource string `json:"timestampSource"`
+	// Direction is "rx" for received frames and "tx" for the frames sent by the app.
+	Direction string   `json:"direction"`
+	Interface string   `json:"interface"`
+	ID        uint32   `json:"id"`
+	Extended  bool     `json:"extended"`
+	Remote    bool     `json:"remote"`
+	FD        bool     `json:"fd"`
+	BRS       bool     `json:"brs"`
+	ESI       bool     `json:"esi"`
+	DLC       uint8    `json:"dlc"`
+	Data      []uint32 `json:"data"`
+	// Group is the frame group of the ID (see SetFrameGroups), empty if none.
+	Group string `json:"group,omitempty"`
+}
+
+func frameEvent(iface string, info canbus.RxInfo, f *canbus.Frame, tx bool) CANFrameEvent {
+	return CANFrameEvent{
+		Timestamp:       info.Time,
+		TimestampSource: info.Source.String(),
+		Direction:       direction(tx),
+		Interface:       iface,
+		ID:              f.ID,
+		Extended:        f.IsExtended,
+		Remote:          f.IsRemote,
+		FD:              f.IsFD,
+		BRS:             f.BRS,
+		ESI:             f.ESI,
+		DLC:             f.DLC(),
+		Data:            dataWords(f.Payload()),
+	}
 }
 
 // StartCAN connects to a SocketCAN interface (eg: vcan0 or can0), starts a goroutine and emits frames via "can:frame".
+// Several interfaces can be started in parallel; frames are tagged with the interface they were received on.
+// A "tcp://host:port/can0" interface opens the bus can0 of a remote socketcand server and
+// "slcan:///dev/ttyACM0?bitrate=500000" an SLCAN adapter on a serial port; ListTransports
+// returns the other backends, eg "gsusb://0?bitrate=500000" for candleLight adapters.
 func (a *App) StartCAN(iface string) error {
+	return a.StartCANWithOptions(iface, CANOptions{})
+}
+
+// StartCANFD is StartCAN with CAN FD frames enabled on the socket.
+func (a *App) StartCANFD(iface string) error {
+	return a.StartCANWithOptions(iface, CANOptions{FD: true})
+}
+
+// StartCANWithOptions is StartCAN with explicit session options.
+func (a *App) StartCANWithOptions(iface string, opts CANOptions) error {
 	iface = strings.TrimSpace(iface)
 	if iface == "" {
 		iface = "vcan0"
 	}
 
 	a.mu.Lock()
-	if a.session != nil {
+	if _, ok := a.sessions[iface]; ok {
 		a.mu.Unlock()
-		return errors.New("CAN already started")
+		return fmt.Errorf("CAN already started on %s", iface)
 	}
 	ctx, cancel := context.WithCancel(context.Background())
 	sess := &canSession{
-		iface:  iface,
-		ctx:    ctx,
-		cancel: cancel,
-		done:   make(chan struct{}),
+		iface:    iface,
+		ctx:      ctx,
+		cancel:   cancel,
+		fd:       opts.FD,
+		opts:     opts,
+		linkUp:   make(chan struct{}, 1),
+		sockOpts: defaultSocketOptions,
+		done:     make(chan struct{}),
+		stats:    newStatsCollector(iface),
+		timing:   timing.NewMonitor(timing.DefaultTolerance),
+		bus:      newBusMonitor(),
 	}
-	a.session = sess
+	sess.reconnect.Store(opts.AutoReconnect)
+	a.sessions[iface] = sess
 	a.mu.Unlock()
 
-	conn, err := socketcan.DialContext(ctx, "can", iface)
+	conn, err := a.dialBus(ctx, iface, opts)
 	if err != nil {
 		if ctx.Err() == nil {
 			a.emitError(fmt.Errorf("dial %s: %w", iface, err))
 		}
 		cancel()
 		close(sess.done)
-		a.mu.Lock()
-		if a.session == sess {
-			a.session = nil
-		}
-		a.mu.Unlock()
+		a.removeSession(sess)
 		return err
 	}
 
@@ -99,21 +453,19 @@ func (a *App) StartCAN(iface string) error {
 		_ = conn.Close()
 		cancel()
 		close(sess.done)
-		a.mu.Lock()
-		if a.session == sess {
-			a.session = nil
-		}
-		a.mu.Unlock()
+		a.removeSession(sess)
 		return ctx.Err()
 	}
 
 	a.mu.Lock()
 	sess.conn = conn
-	sess.rx = socketcan.NewReceiver(conn)
-	sess.tx = socketcan.NewTransmitter(conn)
 	a.mu.Unlock()
 
 	go a.receiveLoop(sess)
+	go a.statsLoop(sess)
+	go a.timingLoop(sess)
+	go a.busStateLoop(sess)
+	a.record(JournalEntry{Action: actionStartCAN, Interface: iface, Options: &opts})
 	return nil
 }
 
@@ -123,71 +475,178 @@ func (a *App) receiveLoop(sess *canSession) {
 		if sess.conn != nil {
 			_ = sess.conn.Close()
 		}
-		a.mu.Lock()
-		if a.session == sess {
-			a.session = nil
-		}
-		a.mu.Unlock()
+		a.removeSession(sess)
 	}()
 
-	for sess.rx.Receive() {
+	// the socket is read on its own goroutine so the frames are read, and
+	// stamped, while the previous ones are processed
+	q := newRxQueue(rxQueueSize)
+	go a.readLoop(sess, q)
+
+	rx := &rxFrame{}
+	var e rxEntry
+	for q.pop(&e) {
 		if sess.ctx.Err() != nil {
-			return
+			// drain the queue until the reader stops
+			continue
 		}
+		if e.info.Own {
+			// sent frames are logged and counted when written, only show them
+			a.emitFrame(frameEvent(sess.iface, e.info, &e.frame, true))
+			continue
+		}
+		*rx = rxFrame{sess: sess, info: e.info, frame: e.frame}
+		a.rxPipeline.run(rx)
+	}
+}
 
-		if sess.rx.HasErrorFrame() {
-			if sess.ctx.Err() == nil {
-				ef := sess.rx.ErrorFrame()
-				a.emitError(fmt.Errorf("CAN error frame: class=%s controller=%s protocol=%s location=%s transceiver=%s",
-					ef.ErrorClass,
-					ef.ControllerError,
-					ef.ProtocolError,
-					ef.ProtocolViolationErrorLocation,
-					ef.TransceiverError,
-				))
+// readLoop reads the frames of sess into q until the session stops or its
+// connection fails for good, reading several frames per call when the bus
+// supports it.
+func (a *App) readLoop(sess *canSession, q *rxQueue) {
+	defer q.close()
+
+	frames := make([]canbus.Frame, canbus.MaxBatch)
+	infos := make([]canbus.RxInfo, canbus.MaxBatch)
+	// dropped is the kernel drop counter of the current connection, clock
+	// maps its hardware timestamps
+	var dropped uint64
+	var clock canbus.ClockMapper
+	for {
+		n, err := readFrames(sess.conn, frames, infos)
+		stampFrames(sess, &clock, infos[:n])
+		if n > 0 && !q.push(sess.ctx, frames[:n], infos[:n]) {
+			return
+		}
+		if dc, ok := sess.conn.(canbus.DropCounter); ok {
+			if d := dc.Dropped(); d > dropped {
+				sess.kernelDrops.Add(d - dropped)
+				dropped = d
+			}
+		}
+		if err != nil {
+			if sess.ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			if !sess.reconnect.Load() {
+				a.emitError(fmt.Errorf("receive: %w", err))
+				return
 			}
+			if !a.reconnect(sess, err) {
+				return
+			}
+			dropped = 0
+			clock.Reset()
 			continue
 		}
+		if sess.ctx.Err() != nil {
+			return
+		}
+	}
+}
 
-		f := sess.rx.Frame()
-		data := make([]uint32, f.Length)
-		for i := 0; i < int(f.Length); i++ {
-			data[i] = uint32(f.Data[i])
+// readFrames reads the next frames of conn, stamped with the kernel or hardware
+// reception time when the bus reports it.
+func readFrames(conn canbus.Bus, frames []canbus.Frame, infos []canbus.RxInfo) (int, error) {
+	switch r := conn.(type) {
+	case canbus.BatchReader:
+		return r.ReadFrames(frames, infos)
+	case canbus.InfoReader:
+		f, info, err := r.ReadFrameInfo()
+		if err != nil {
+			return 0, err
 		}
+		frames[0], infos[0] = f, info
+		return 1, nil
+	}
+	f, err := conn.ReadFrame()
+	if err != nil {
+		return 0, err
+	}
+	frames[0], infos[0] = f, canbus.RxInfo{Time: time.Now()}
+	return 1, nil
+}
+
+// reportErrorFrame updates the bus state with an error frame and reports it on "can:error".
+func (a *App) reportErrorFrame(sess *canSession, ts time.Time, f *canbus.Frame) {
+	a.updateBusState(sess, ts, f)
+	ef := f.ErrorFrame()
+	a.emitError(fmt.Errorf("CAN error frame: class=%s controller=%s protocol=%s location=%s transceiver=%s",
+		ef.ErrorClass,
+		ef.ControllerError,
+		ef.ProtocolError,
+		ef.ProtocolViolationErrorLocation,
+		ef.TransceiverError,
+	))
+}
 
-		runtime.EventsEmit(a.ctx, "can:frame", CANFrameEvent{
-			Timestamp: time.Now(),
-			Interface: sess.iface,
-			ID:        f.ID,
-			Extended:  f.IsExtended,
-			Remote:    f.IsRemote,
-			DLC:       f.Length,
-			Data:      data,
-		})
+// StopCAN stops the receive goroutine of iface and closes its SocketCAN connection.
+// Other interfaces keep running.
+func (a *App) StopCAN(iface string) error {
+	iface = strings.TrimSpace(iface)
+	if iface == "" {
+		iface = "vcan0"
 	}
 
-	if err := sess.rx.Err(); err != nil && sess.ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
-		a.emitError(fmt.Errorf("receive: %w", err))
+	a.mu.Lock()
+	sess := a.sessions[iface]
+	a.mu.Unlock()
+
+	if sess == nil {
+		return nil
 	}
+	a.stopSession(sess)
+	a.record(JournalEntry{Action: actionStopCAN, Interface: iface})
+	return nil
 }
 
-// StopCAN stops the receive goroutine and closes the SocketCAN connection.
-func (a *App) StopCAN() error {
+// StopAllCAN stops every running interface.
+func (a *App) StopAllCAN() error {
 	a.mu.Lock()
-	sess := a.session
-	var cancel context.CancelFunc
-	var conn net.Conn
-	var done chan struct{}
-	if sess != nil {
-		cancel = sess.cancel
-		conn = sess.conn
-		done = sess.done
+	sessions := make([]*canSession, 0, len(a.sessions))
+	for _, sess := range a.sessions {
+		sessions = append(sessions, sess)
 	}
 	a.mu.Unlock()
 
-	if sess == nil {
-		return nil
+	for _, sess := range sessions {
+		a.stopSession(sess)
+		a.record(JournalEntry{Action: actionStopCAN, Interface: sess.iface})
+	}
+	return nil
+}
+
+// ActiveInterfaces returns the names of the interfaces that are currently started, sorted by name.
+func (a *App) ActiveInterfaces() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	names := make([]string, 0, len(a.sessions))
+	for name := range a.sessions {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
+
+func (a *App) stopSession(sess *canSession) {
+	a.stopCyclicFrames(sess.iface)
+	a.StopLINSchedule(sess.iface)
+	a.stopGenerators(sess.iface)
+	a.stopFuzzers(sess.iface)
+	a.stopReplayOn(sess.iface)
+	a.closeIsoTPChannels(sess.iface)
+	a.stopOBDPolling(sess.iface)
+	a.closeSDOClients(sess.iface)
+	a.closeXCPSessions(sess.iface)
+	a.stopTxQueue(sess)
+	a.stopBusMonitor(sess)
+
+	a.mu.Lock()
+	cancel := sess.cancel
+	conn := sess.conn
+	done := sess.done
+	a.mu.Unlock()
 
 	if cancel != nil {
 		cancel()
@@ -197,55 +656,181 @@ func (a *App) StopCAN() error {
 	}
 	<-done
 
+	// after the last frame was checked
+	a.stopE2E(sess.iface)
+	a.removeSession(sess)
+}
+
+func (a *App) removeSession(sess *canSession) {
 	a.mu.Lock()
-	if a.session == sess {
-		a.session = nil
+	if a.sessions[sess.iface] == sess {
+		delete(a.sessions, sess.iface)
 	}
 	a.mu.Unlock()
-	return nil
 }
 
-// SendFrame sends a CAN frame on the currently connected interface.
-func (a *App) SendFrame(id uint32, data []byte, extended bool) error {
-	if len(data) > 8 {
-		return fmt.Errorf("data length must be <= 8 (got %d)", len(data))
-	}
+// SendFrame sends a CAN frame on the given started interface.
+// On a CAN FD session, payloads longer than 8 bytes are sent as CAN FD frames
+// and zero-padded to the next valid CAN FD length.
+func (a *App) SendFrame(iface string, id uint32, data []byte, extended bool) error {
+	return a.sendFrame(iface, id, data, extended, len(data) > canbus.MaxDataLength, false)
+}
 
-	a.mu.Lock()
-	var tx *socketcan.Transmitter
-	if a.session != nil {
-		tx = a.session.tx
+// SendFDFrame sends a CAN FD frame (up to 64 bytes) on the given started interface.
+// brs requests the bit rate switch for the data phase.
+func (a *App) SendFDFrame(iface string, id uint32, data []byte, extended bool, brs bool) error {
+	return a.sendFrame(iface, id, data, extended, true, brs)
+}
+
+func (a *App) sendFrame(iface string, id uint32, data []byte, extended, fd, brs bool) error {
+	f, err := newFrame(id, data, extended, fd, brs)
+	if err != nil {
+		return err
 	}
-	a.mu.Unlock()
+	return a.transmitRecorded(strings.TrimSpace(iface), f)
+}
 
-	if tx == nil {
-		return errors.New("CAN not started")
+// newFrame builds and validates a frame. CAN FD payloads are zero-padded to the next valid length.
+func newFrame(id uint32, data []byte, extended, fd, brs bool) (canbus.Frame, error) {
+	maxLen := canbus.MaxDataLength
+	if fd {
+		maxLen = canbus.MaxFDDataLength
+	}
+	if len(data) > maxLen {
+		return canbus.Frame{}, fmt.Errorf("data length must be <= %d (got %d)", maxLen, len(data))
 	}
 
-	var d can.Data
-	copy(d[:], data)
-	f := can.Frame{
+	f := canbus.Frame{
 		ID:         id,
 		Length:     uint8(len(data)),
-		Data:       d,
 		IsExtended: extended,
+		IsFD:       fd,
+		BRS:        brs,
 	}
+	if fd {
+		f.Length = uint8(canbus.PaddedLength(len(data)))
+	}
+	copy(f.Data[:], data)
 	if err := f.Validate(); err != nil {
+		return canbus.Frame{}, err
+	}
+	return f, nil
+}
+
+// errReadOnly is returned by the APIs sending on an interface started read-only.
+var errReadOnly = errors.New("interface started read-only, transmission is disabled")
+
+// txConn returns the connection of a started interface that frames can be written to.
+func (a *App) txConn(iface string, fd bool) (canbus.Bus, error) {
+	iface = strings.TrimSpace(iface)
+
+	a.mu.Lock()
+	var conn canbus.Bus
+	var sessFD, readOnly bool
+	if sess := a.sessions[iface]; sess != nil {
+		conn = sess.conn
+		sessFD = sess.fd
+		readOnly = sess.opts.ReadOnly
+	}
+	a.mu.Unlock()
+
+	if conn == nil {
+		return nil, fmt.Errorf("CAN not started on %s", iface)
+	}
+	if readOnly {
+		return nil, fmt.Errorf("%s: %w", iface, errReadOnly)
+	}
+	if fd && !sessFD {
+		return nil, fmt.Errorf("CAN FD not enabled on %s", iface)
+	}
+	return conn, nil
+}
+
+// checkWritable fails if iface is started read-only, for the APIs writing to
+// an interface on sockets of their own.
+func (a *App) checkWritable(iface string) error {
+	a.mu.Lock()
+	sess := a.sessions[strings.TrimSpace(iface)]
+	a.mu.Unlock()
+	if sess != nil && sess.opts.ReadOnly {
+		return fmt.Errorf("%s: %w", strings.TrimSpace(iface), errReadOnly)
+	}
+	return nil
+}
+
+// transmit writes f on a started interface and reports write failures on "can:error".
+func (a *App) transmit(iface string, f canbus.Frame) error {
+	iface = strings.TrimSpace(iface)
+	conn, err := a.txConn(iface, f.IsFD)
+	if err != nil {
 		return err
 	}
+	if err := a.writeFrame(iface, conn, f); err != nil {
+		a.emitError(err)
+		return err
+	}
+	return nil
+}
 
+// send is transmit for background senders that report failures themselves.
+func (a *App) send(iface string, f canbus.Frame) error {
+	iface = strings.TrimSpace(iface)
+	conn, err := a.txConn(iface, f.IsFD)
+	if err != nil {
+		return err
+	}
+	return a.writeFrame(iface, conn, f)
+}
+
+// writeFrame writes f to the connection of iface, logs it and emits it on "can:frame"
+// with direction "tx", unless the interface receives its own messages back: the
+// received copy is emitted then.
+func (a *App) writeFrame(iface string, conn canbus.Bus, f canbus.Frame) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
-	if err := tx.TransmitFrame(ctx, f); err != nil {
-		a.emitError(err)
+	if err := conn.WriteFrame(ctx, f); err != nil {
 		return err
 	}
+	ts := time.Now()
+	a.logFrame(iface, ts, &f, true)
+	a.countTx(iface, ts, &f)
+
+	a.mu.Lock()
+	own := false
+	if sess := a.sessions[iface]; sess != nil {
+		own = sess.sockOpts.ReceiveOwn
+	}
+	a.mu.Unlock()
+	if !own {
+		a.emitFrame(frameEvent(iface, canbus.RxInfo{Time: ts}, &f, true))
+	}
 	return nil
 }
 
+// dataWords widens payload bytes so they are serialized as a JSON number array rather than base64.
+func dataWords(b []byte) []uint32 {
+	data := make([]uint32, len(b))
+	for i, v := range b {
+		data[i] = uint32(v)
+	}
+	return data
+}
+
+func (a *App) emit(event string, payload interface{}) {
+	if sinks := a.sinks.Load(); sinks != nil {
+		for _, s := range *sinks {
+			s.Emit(event, payload)
+		}
+	}
+	if a.ctx == nil {
+		return
+	}
+	runtime.EventsEmit(a.ctx, event, payload)
+}
+
 func (a *App) emitError(err error) {
-	if err == nil || a.ctx == nil {
+	if err == nil {
 		return
 	}
-	runtime.EventsEmit(a.ctx, "can:error", err.Error())
+	a.emit("can:error", err.Error())
 }
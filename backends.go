@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"go.bug.st/serial"
+
+	"canproject/canbus"
+	"canproject/gsusb"
+	"canproject/kvaser"
+	"canproject/lin"
+	"canproject/pcan"
+	"canproject/slcan"
+	"canproject/socketcand"
+)
+
+// RemoteStatus is the state of a socketcand connection, emitted on "can:remote" when
+// it drops, is restored or a new latency is measured.
+type RemoteStatus struct {
+	Interface  string  `json:"interface"`
+	Connected  bool    `json:"connected"`
+	LatencyMs  float64 `json:"latencyMs"`
+	Reconnects int     `json:"reconnects"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// transport is a CAN backend. Interfaces whose name starts with prefix are
+// opened with it; the SocketCAN transport has no prefix and takes the rest.
+type transport struct {
+	name    string
+	prefix  string
+	example string
+	fd      bool
+	// lin is set for the LIN backends, whose frames have the LIN IDs.
+	lin bool
+	// available reports whether the backend can be used in this build and OS.
+	available bool
+	dial      func(a *App, ctx context.Context, iface string, opts CANOptions) (canbus.Bus, error)
+}
+
+// TransportInfo describes a CAN backend for the interface picker.
+type TransportInfo struct {
+	Name      string `json:"name"`
+	Prefix    string `json:"prefix"`
+	Example   string `json:"example"`
+	FD        bool   `json:"fd"`
+	LIN       bool   `json:"lin"`
+	Available bool   `json:"available"`
+}
+
+var transports = []transport{
+	{
+		name:      "SLCAN",
+		prefix:    "slcan://",
+		example:   "slcan:///dev/ttyACM0?bitrate=500000",
+		available: true,
+		dial: func(_ *App, _ context.Context, iface string, _ CANOptions) (canbus.Bus, error) {
+			cfg, err := slcan.ParseURL(iface)
+			if err != nil {
+				return nil, err
+			}
+			return slcan.Dial(cfg)
+		},
+	},
+	{
+		name:      "socketcand",
+		prefix:    "tcp://",
+		example:   "tcp://192.168.1.10:29536/can0",
+		available: true,
+		dial: func(a *App, ctx context.Context, iface string, _ CANOptions) (canbus.Bus, error) {
+			return socketcand.Dial(ctx, iface, socketcand.WithStatusHandler(func(s socketcand.Status) {
+				a.emit("can:remote", remoteStatus(iface, s))
+			}))
+		},
+	},
+	{
+		name:      "gs_usb",
+		prefix:    "gsusb://",
+		example:   "gsusb://0?bitrate=500000",
+		available: gsusb.Available,
+		dial: func(_ *App, _ context.Context, iface string, _ CANOptions) (canbus.Bus, error) {
+			cfg, err := gsusb.ParseURL(iface)
+			if err != nil {
+				return nil, err
+			}
+			return gsusb.Dial(cfg)
+		},
+	},
+	{
+		name:      "PCAN",
+		prefix:    "pcan://",
+		example:   "pcan://usb1?bitrate=500000",
+		available: pcan.Available,
+		dial: func(_ *App, _ context.Context, iface string, _ CANOptions) (canbus.Bus, error) {
+			cfg, err := pcan.ParseURL(iface)
+			if err != nil {
+				return nil, err
+			}
+			return pcan.Dial(cfg)
+		},
+	},
+	{
+		name:      "Kvaser",
+		prefix:    "kvaser://",
+		example:   "kvaser://0?bitrate=500000",
+		available: kvaser.Available,
+		dial: func(_ *App, _ context.Context, iface string, _ CANOptions) (canbus.Bus, error) {
+			cfg, err := kvaser.ParseURL(iface)
+			if err != nil {
+				return nil, err
+			}
+			return kvaser.Dial(cfg)
+		},
+	},
+	{
+		name:      "LIN serial",
+		prefix:    "lin://",
+		example:   "lin:///dev/ttyUSB0?bitrate=19200",
+		lin:       true,
+		available: true,
+		dial: func(_ *App, _ context.Context, iface string, _ CANOptions) (canbus.Bus, error) {
+			cfg, err := lin.ParseURL(iface)
+			if err != nil {
+				return nil, err
+			}
+			return lin.Dial(cfg)
+		},
+	},
+	{
+		// sllin LIN interfaces are SocketCAN interfaces too
+		name:      "SocketCAN",
+		example:   "can0",
+		fd:        true,
+		available: runtime.GOOS == "linux",
+		dial: func(a *App, _ context.Context, iface string, opts CANOptions) (canbus.Bus, error) {
+			dialOpts := []canbus.DialOption{canbus.WithReceiveErrorFrames(), canbus.WithXL()}
+			if opts.FD {
+				dialOpts = append(dialOpts, canbus.WithFD())
+			}
+			conn, err := canbus.Dial(iface, dialOpts...)
+			if err != nil {
+				return nil, err
+			}
+			if err := conn.XLError(); err != nil {
+				a.emitUnsupported(iface, featureCANXL, err)
+			}
+			return conn, nil
+		},
+	},
+}
+
+// dialBus opens iface with the transport its name selects.
+func (a *App) dialBus(ctx context.Context, iface string, opts CANOptions) (canbus.Bus, error) {
+	for _, t := range transports {
+		if !strings.HasPrefix(iface, t.prefix) {
+			continue
+		}
+		if opts.FD && !t.fd {
+			return nil, fmt.Errorf("CAN FD is not supported by %s (%s)", t.name, iface)
+		}
+		return t.dial(a, ctx, iface, opts)
+	}
+	return nil, fmt.Errorf("no transport for %s", iface)
+}
+
+// ListTransports returns the CAN backends and the interface names that select them.
+func (a *App) ListTransports() []TransportInfo {
+	infos := make([]TransportInfo, len(transports))
+	for i, t := range transports {
+		infos[i] = TransportInfo{
+			Name:      t.name,
+			Prefix:    t.prefix,
+			Example:   t.example,
+			FD:        t.fd,
+			LIN:       t.lin,
+			Available: t.available,
+		}
+	}
+	return infos
+}
+
+// GetRemoteStatus returns the connection state of a started socketcand interface.
+func (a *App) GetRemoteStatus(iface string) (RemoteStatus, error) {
+	sess, err := a.session(iface)
+	if err != nil {
+		return RemoteStatus{}, err
+	}
+	c, ok := sess.conn.(*socketcand.Conn)
+	if !ok {
+		return RemoteStatus{}, fmt.Errorf("%s is not a socketcand interface", sess.iface)
+	}
+	return remoteStatus(sess.iface, c.Status()), nil
+}
+
+func remoteStatus(iface string, s socketcand.Status) RemoteStatus {
+	rs := RemoteStatus{
+		Interface:  iface,
+		Connected:  s.Connected,
+		LatencyMs:  float64(s.Latency) / float64(time.Millisecond),
+		Reconnects: s.Reconnects,
+	}
+	if s.Err != nil {
+		rs.Error = s.Err.Error()
+	}
+	return rs
+}
+
+// ListSerialPorts returns the serial ports SLCAN adapters can be opened on,
+// eg /dev/ttyACM0 or COM3, for "slcan://<port>?bitrate=500000" interfaces.
+func (a *App) ListSerialPorts() ([]string, error) {
+	ports, err := serial.GetPortsList()
+	if err != nil {
+		return nil, err
+	}
+	if ports == nil {
+		ports = []string{}
+	}
+	return ports, nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatchInterval   = 50 * time.Millisecond
+	defaultBatchMaxFrames  = 500
+	defaultBatchBufferSize = 10000
+)
+
+// FrameBatchOptions configures the batching of received frames set with SetFrameBatching.
+type FrameBatchOptions struct {
+	// Enabled emits frames in batches on "can:frames" instead of one "can:frame" event per frame.
+	Enabled bool `json:"enabled"`
+	// IntervalMs is the maximum time a frame waits in the buffer, 0 for 50 ms.
+	IntervalMs int `json:"intervalMs"`
+	// MaxFrames emits a batch as soon as it holds that many frames, 0 for 500.
+	MaxFrames int `json:"maxFrames"`
+	// BufferSize is the capacity of the ring buffer, 0 for 10000. When the frontend
+	// does not keep up the oldest frames are dropped.
+	BufferSize int `json:"bufferSize"`
+}
+
+// CANFramesEvent is a batch of received frames emitted on "can:frames".
+type CANFramesEvent struct {
+	Frames []CANFrameEvent `json:"frames"`
+	// Dropped is the number of frames lost since the previous batch because the buffer was full.
+	Dropped uint64 `json:"dropped"`
+	// TotalDropped is the number of frames lost since batching was enabled.
+	TotalDropped uint64 `json:"totalDropped"`
+}
+
+// frameBatcher buffers frame events in a ring buffer and emits them in batches.
+type frameBatcher struct {
+	opts     FrameBatchOptions
+	interval time.Duration
+
+	// kick asks the loop to emit a full batch early.
+	kick chan struct{}
+	stop chan struct{}
+	done chan struct{}
+
+	mu           sync.Mutex
+	ring         []CANFrameEvent
+	head, n      int
+	dropped      uint64
+	totalDropped uint64
+}
+
+// SetFrameBatching enables or disables the batching of received frames. With batching
+// frames are buffered and emitted as arrays on "can:frames" every IntervalMs or
+// MaxFrames frames, which keeps the event overhead low at high bus loads.
+func (a *App) SetFrameBatching(opts FrameBatchOptions) error {
+	if opts.IntervalMs < 0 || opts.MaxFrames < 0 || opts.BufferSize < 0 {
+		return fmt.Errorf("batching options must be >= 0")
+	}
+	if opts.IntervalMs == 0 {
+		opts.IntervalMs = int(defaultBatchInterval / time.Millisecond)
+	}
+	if opts.MaxFrames == 0 {
+		opts.MaxFrames = defaultBatchMaxFrames
+	}
+	if opts.BufferSize == 0 {
+		opts.BufferSize = defaultBatchBufferSize
+	}
+	if opts.BufferSize < opts.MaxFrames {
+		return fmt.Errorf("buffer size (%d) must be >= max frames (%d)", opts.BufferSize, opts.MaxFrames)
+	}
+
+	a.batchMu.Lock()
+	defer a.batchMu.Unlock()
+
+	var b *frameBatcher
+	if opts.Enabled {
+		b = &frameBatcher{
+			opts:     opts,
+			interval: time.Duration(opts.IntervalMs) * time.Millisecond,
+			kick:     make(chan struct{}, 1),
+			stop:     make(chan struct{}),
+			done:     make(chan struct{}),
+			ring:     make([]CANFrameEvent, opts.BufferSize),
+		}
+		go a.batchLoop(b)
+	}
+	if old := a.batcher.Swap(b); old != nil {
+		close(old.stop)
+		<-old.done
+	}
+	return nil
+}
+
+// GetFrameBatching returns the batching options, Enabled is false when frames are emitted one by one.
+func (a *App) GetFrameBatching() FrameBatchOptions {
+	if b := a.batcher.Load(); b != nil {
+		return b.opts
+	}
+	return FrameBatchOptions{}
+}
+
+// emitFrame emits a received frame on "can:frame" and the channels subscribed to
+// it, or queues it for the next batch.
+func (a *App) emitFrame(ev CANFrameEvent) {
+	ev.Group = a.frameGroup(ev.ID, ev.Extended)
+	if b := a.batcher.Load(); b != nil {
+		b.push(ev)
+		return
+	}
+	c := a.frameChans.Load()
+	if c == nil || !c.noGlobal {
+		a.emit("can:frame", ev)
+	}
+	if c != nil {
+		a.emitSubscribed(c, &ev)
+	}
+}
+
+func (a *App) batchLoop(b *frameBatcher) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stop:
+			// flush what is left so no frame is lost when batching is turned off
+			for {
+				ev, ok := b.take()
+				if !ok {
+					return
+				}
+				a.emitBatch(ev)
+			}
+		case <-ticker.C:
+		case <-b.kick:
+		}
+		if ev, ok := b.take(); ok {
+			a.emitBatch(ev)
+		}
+	}
+}
+
+// push adds a frame, overwriting the oldest one when the buffer is full.
+func (b *frameBatcher) push(ev CANFrameEvent) {
+	b.mu.Lock()
+	if b.n == len(b.ring) {
+		b.ring[b.head] = ev
+		b.head = (b.head + 1) % len(b.ring)
+		b.dropped++
+		b.totalDropped++
+	} else {
+		b.ring[(b.head+b.n)%len(b.ring)] = ev
+		b.n++
+	}
+	full := b.n >= b.opts.MaxFrames
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// take removes up to MaxFrames frames from the buffer.
+func (b *frameBatcher) take() (CANFramesEvent, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.n == 0 && b.dropped == 0 {
+		return CANFramesEvent{}, false
+	}
+	count := min(b.n, b.opts.MaxFrames)
+	ev := CANFramesEvent{
+		Frames:       make([]CANFrameEvent, count),
+		Dropped:      b.dropped,
+		TotalDropped: b.totalDropped,
+	}
+	for i := range ev.Frames {
+		idx := (b.head + i) % len(b.ring)
+		ev.Frames[i] = b.ring[idx]
+		b.ring[idx] = CANFrameEvent{}
+	}
+	b.head = (b.head + count) % len(b.ring)
+	b.n -= count
+	b.dropped = 0
+
+	// more than a batch is waiting, emit the next one without waiting for the ticker
+	if b.n >= b.opts.MaxFrames {
+		select {
+		case b.kick <- struct{}{}:
+		default:
+		}
+	}
+	return ev, true
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+)
+
+// busStatePollInterval is the period the controller state and error counters are read from the kernel.
+const busStatePollInterval = time.Second
+
+// BusState is the error state of the CAN controller of an interface, emitted on
+// "can:busstate" when it changes.
+type BusState struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	// State is "ERROR-ACTIVE", "ERROR-WARNING", "ERROR-PASSIVE", "BUS-OFF", "STOPPED" or "SLEEPING".
+	State string `json:"state"`
+	// Previous is the state before the transition.
+	Previous string `json:"previous"`
+	// TxErrors and RxErrors are the transmit/receive error counters (TEC/REC) as last reported
+	// by the driver, when it reports them.
+	TxErrors    uint16 `json:"txErrors"`
+	RxErrors    uint16 `json:"rxErrors"`
+	HasCounters bool   `json:"hasCounters"`
+	ErrorFrames uint64 `json:"errorFrames"`
+	BusOffCount int    `json:"busOffCount"`
+	// AutoRestart and RestartDelayMs are the bus-off recovery set with SetBusOffRecovery.
+	AutoRestart    bool `json:"autoRestart"`
+	RestartDelayMs int  `json:"restartDelayMs"`
+}
+
+// busMonitor tracks the controller state of a session.
+type busMonitor struct {
+	mu           sync.Mutex
+	state        canbus.ControllerState
+	previous     canbus.ControllerState
+	changed      time.Time
+	tx, rx       uint16
+	hasCounters  bool
+	errorFrames  uint64
+	busOffs      int
+	autoRestart  bool
+	restartDelay time.Duration
+	restart      *time.Timer
+}
+
+// GetBusState returns the controller state and error counters of a started interface.
+func (a *App) GetBusState(iface string) (BusState, error) {
+	sess, err := a.session(iface)
+	if err != nil {
+		return BusState{}, err
+	}
+	return sess.bus.snapshot(sess.iface), nil
+}
+
+// SetBusOffRecovery enables the automatic restart of a started interface delayMs
+// milliseconds after its controller went bus-off. Restarting requires CAP_NET_ADMIN.
+func (a *App) SetBusOffRecovery(iface string, enabled bool, delayMs int) error {
+	if delayMs < 0 {
+		return fmt.Errorf("restart delay must be >= 0 ms (got %d)", delayMs)
+	}
+	sess, err := a.session(iface)
+	if err != nil {
+		return err
+	}
+	m := sess.bus
+	m.mu.Lock()
+	m.autoRestart = enabled
+	m.restartDelay = time.Duration(delayMs) * time.Millisecond
+	busOff := m.state == canbus.StateBusOff
+	m.mu.Unlock()
+
+	if enabled && busOff {
+		a.scheduleRestart(sess)
+	}
+	return nil
+}
+
+// RestartInterface restarts the CAN controller of an interface after bus-off,
+// like "ip link set <iface> type can restart". It requires CAP_NET_ADMIN.
+func (a *App) RestartInterface(iface string) error {
+	return canbus.RestartLink(strings.TrimSpace(iface))
+}
+
+func newBusMonitor() *busMonitor {
+	return &busMonitor{state: canbus.StateErrorActive, previous: canbus.StateErrorActive, changed: time.Now()}
+}
+
+// updateBusState applies the state and counters reported by an error frame.
+func (a *App) updateBusState(sess *canSession, ts time.Time, f *canbus.Frame) {
+	m := sess.bus
+	m.mu.Lock()
+	m.errorFrames++
+	if tx, rx, ok := f.ErrorCounters(); ok {
+		m.tx, m.rx, m.hasCounters = uint16(tx), uint16(rx), true
+	}
+	m.mu.Unlock()
+
+	if state, ok := f.ControllerState(); ok {
+		a.setBusState(sess, ts, state)
+	}
+}
+
+// setBusState records a controller state and emits "can:busstate" on transitions.
+func (a *App) setBusState(sess *canSession, ts time.Time, state canbus.ControllerState) {
+	m := sess.bus
+	m.mu.Lock()
+	if m.state == state {
+		m.mu.Unlock()
+		return
+	}
+	m.previous, m.state, m.changed = m.state, state, ts
+	if state == canbus.StateBusOff {
+		m.busOffs++
+	} else if m.restart != nil {
+		m.restart.Stop()
+		m.restart = nil
+	}
+	restart := state == canbus.StateBusOff && m.autoRestart
+	m.mu.Unlock()
+
+	a.emit("can:busstate", m.snapshot(sess.iface))
+	if restart {
+		a.scheduleRestart(sess)
+	}
+}
+
+// scheduleRestart restarts the controller of sess after the recovery delay.
+func (a *App) scheduleRestart(sess *canSession) {
+	m := sess.bus
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.restart != nil {
+		return
+	}
+	m.restart = time.AfterFunc(m.restartDelay, func() {
+		m.mu.Lock()
+		m.restart = nil
+		busOff := m.state == canbus.StateBusOff
+		m.mu.Unlock()
+
+		if !busOff || sess.ctx.Err() != nil {
+			return
+		}
+		if err := canbus.RestartLink(sess.iface); err != nil {
+			a.emitError(fmt.Errorf("bus-off recovery: %w", err))
+		}
+	})
+}
+
+// stopBusMonitor cancels a pending bus-off restart of sess.
+func (a *App) stopBusMonitor(sess *canSession) {
+	m := sess.bus
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.restart != nil {
+		m.restart.Stop()
+		m.restart = nil
+	}
+}
+
+// busStateLoop polls the controller state and error counters of hardware interfaces,
+// which drivers do not always report with error frames.
+func (a *App) busStateLoop(sess *canSession) {
+	if l, err := canbus.LinkByName(sess.iface); err != nil || !l.HasController {
+		return
+	}
+
+	ticker := time.NewTicker(busStatePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sess.ctx.Done():
+			return
+		case now := <-ticker.C:
+			l, err := canbus.LinkByName(sess.iface)
+			if err != nil {
+				continue
+			}
+			if l.HasCounters {
+				sess.bus.mu.Lock()
+				sess.bus.tx, sess.bus.rx, sess.bus.hasCounters = l.TxErrors, l.RxErrors, true
+				sess.bus.mu.Unlock()
+			}
+			a.setBusState(sess, now, l.State)
+		}
+	}
+}
+
+func (m *busMonitor) snapshot(iface string) BusState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return BusState{
+		Timestamp:      m.changed,
+		Interface:      iface,
+		State:          m.state.String(),
+		Previous:       m.previous.String(),
+		TxErrors:       m.tx,
+		RxErrors:       m.rx,
+		HasCounters:    m.hasCounters,
+		ErrorFrames:    m.errorFrames,
+		BusOffCount:    m.busOffs,
+		AutoRestart:    m.autoRestart,
+		RestartDelayMs: int(m.restartDelay / time.Millisecond),
+	}
+}
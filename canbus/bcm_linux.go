@@ -0,0 +1,116 @@
+//go:build linux
+
+package canbus
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// bcm opcodes and flags, see linux/can/bcm.h.
+const (
+	bcmTxSetup  = 1
+	bcmTxDelete = 2
+
+	bcmSetTimer   = 0x0001
+	bcmStartTimer = 0x0002
+	bcmFDFrame    = 0x0800
+)
+
+// bcmTimeval mirrors struct bcm_timeval. C long has the size of Go int on Linux.
+type bcmTimeval struct {
+	sec  int
+	usec int
+}
+
+// bcmMsgHead mirrors struct bcm_msg_head without the trailing frames.
+type bcmMsgHead struct {
+	opcode  uint32
+	flags   uint32
+	count   uint32
+	ival1   bcmTimeval
+	ival2   bcmTimeval
+	canID   uint32
+	nframes uint32
+}
+
+func durationToTimeval(d time.Duration) bcmTimeval {
+	return bcmTimeval{
+		sec:  int(d / time.Second),
+		usec: int(d % time.Second / time.Microsecond),
+	}
+}
+
+// BCM is a SocketCAN broadcast manager socket. The kernel transmits the
+// frames registered with StartCyclic without any userspace involvement.
+type BCM struct {
+	iface string
+	f     *os.File
+}
+
+// DialBCM opens a broadcast manager socket on the named device.
+func DialBCM(device string) (bcm *BCM, err error) {
+	defer func() {
+		if err != nil {
+			err = &net.OpError{Op: "dial", Net: "can-bcm", Addr: addr(device), Err: err}
+		}
+	}()
+	ifi, err := net.InterfaceByName(device)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", device, err)
+	}
+	fd, err := unix.Socket(unix.AF_CAN, unix.SOCK_DGRAM, unix.CAN_BCM)
+	if err != nil {
+		return nil, fmt.Errorf("socket: %w", err)
+	}
+	if err := unix.Connect(fd, &unix.SockaddrCAN{Ifindex: ifi.Index}); err != nil {
+		_ = unix.Close(fd)
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	return &BCM{iface: device, f: os.NewFile(uintptr(fd), device)}, nil
+}
+
+// StartCyclic makes the kernel transmit f every period until StopCyclic or Close.
+// Registering a frame with an ID that is already cyclic replaces its data and period.
+func (b *BCM) StartCyclic(f Frame, period time.Duration) error {
+	head := bcmMsgHead{
+		opcode:  bcmTxSetup,
+		flags:   bcmSetTimer | bcmStartTimer,
+		ival2:   durationToTimeval(period),
+		canID:   f.idAndFlags(),
+		nframes: 1,
+	}
+	if f.IsFD {
+		head.flags |= bcmFDFrame
+	}
+	return b.write(head, f.marshalBinary())
+}
+
+// StopCyclic removes the cyclic transmission of the frame with the given ID.
+func (b *BCM) StopCyclic(id uint32, extended bool) error {
+	f := Frame{ID: id, IsExtended: extended}
+	return b.write(bcmMsgHead{opcode: bcmTxDelete, canID: f.idAndFlags()}, nil)
+}
+
+// Close closes the socket, which also stops every cyclic transmission registered on it.
+func (b *BCM) Close() error {
+	return b.f.Close()
+}
+
+func (b *BCM) write(head bcmMsgHead, frame []byte) error {
+	headSize := int(unsafe.Sizeof(head))
+	// frames are aligned to 8 bytes after the header
+	frameOffset := (headSize + 7) &^ 7
+	msg := make([]byte, frameOffset+len(frame))
+	copy(msg, unsafe.Slice((*byte)(unsafe.Pointer(&head)), headSize))
+	copy(msg[frameOffset:], frame)
+	if _, err := b.f.Write(msg); err != nil {
+		return &net.OpError{Op: "write", Net: "can-bcm", Addr: addr(b.iface), Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+//go:build !linux
+
+package canbus
+
+import (
+	"net"
+	"time"
+)
+
+// BCM is a SocketCAN broadcast manager socket.
+type BCM struct{}
+
+// DialBCM opens a broadcast manager socket on the named device.
+func DialBCM(device string) (*BCM, error) {
+	return nil, &net.OpError{Op: "dial", Net: "can-bcm", Addr: addr(device), Err: errUnsupported}
+}
+
+// StartCyclic makes the kernel transmit f every period.
+func (b *BCM) StartCyclic(Frame, time.Duration) error {
+	return errUnsupported
+}
+
+// StopCyclic removes the cyclic transmission of the frame with the given ID.
+func (b *BCM) StopCyclic(uint32, bool) error {
+	return errUnsupported
+}
+
+// Close closes the socket.
+func (b *BCM) Close() error {
+	return nil
+}
@@ -0,0 +1,63 @@
+package canbus
+
+import "time"
+
+// DefaultClockWindow is the window over which a ClockMapper measures the offset
+// between the controller and the system clocks.
+const DefaultClockWindow = time.Second
+
+// ClockMapper maps the hardware timestamps of a controller onto the system
+// clock, so that the frames of several interfaces share a time base. It relates
+// the two clocks with the kernel timestamps of the same frames: the kernel
+// stamps a frame some variable time after the controller, the smallest
+// difference seen over a window is the offset with the least latency. Each
+// window starts from the minimum of the last one, which follows the drift of
+// the controller clock. The zero value uses DefaultClockWindow. A ClockMapper
+// is not safe for concurrent use.
+type ClockMapper struct {
+	Window time.Duration
+
+	// offset is the current estimate, min the minimum seen in the window
+	// starting at start.
+	offset time.Duration
+	min    time.Duration
+	start  time.Time
+	valid  bool
+}
+
+// Map returns the system time of a frame stamped hw by the controller and
+// kernel by the kernel. A frame without kernel time is returned as is.
+func (m *ClockMapper) Map(hw, kernel time.Time) time.Time {
+	if kernel.IsZero() {
+		return hw
+	}
+	window := m.Window
+	if window <= 0 {
+		window = DefaultClockWindow
+	}
+	d := kernel.Sub(hw)
+	switch {
+	case !m.valid:
+		m.offset, m.min, m.start, m.valid = d, d, kernel, true
+	case kernel.Sub(m.start) >= window || kernel.Before(m.start):
+		// the next window; a lower offset is taken at once, a higher one
+		// when the controller clock runs slower than the system clock
+		m.offset = min(m.min, d)
+		m.min, m.start = d, kernel
+	default:
+		m.min = min(m.min, d)
+		m.offset = min(m.offset, d)
+	}
+	return hw.Add(m.offset)
+}
+
+// Offset returns the difference from the controller clock to the system clock,
+// ok is false before the first frame.
+func (m *ClockMapper) Offset() (time.Duration, bool) {
+	return m.offset, m.valid
+}
+
+// Reset forgets the offset, eg when the controller clock restarted.
+func (m *ClockMapper) Reset() {
+	*m = ClockMapper{Window: m.Window}
+}
@@ -0,0 +1,152 @@
+package canbus
+
+import (
+	"context"
+	"time"
+)
+
+const network = "can"
+
+// Bus is a connection to a CAN bus, implemented by the SocketCAN Conn and the
+// other backends of the application.
+type Bus interface {
+	// ReadFrame blocks until the next frame is received. It is not safe to call
+	// ReadFrame from multiple goroutines.
+	ReadFrame() (Frame, error)
+	// WriteFrame transmits a frame. The context deadline, if any, bounds the write.
+	WriteFrame(ctx context.Context, f Frame) error
+	// SetFilters installs receive filters. Frames matching any of the filters are
+	// delivered; an empty list delivers nothing.
+	SetFilters(filters []Filter) error
+	// Close closes the connection, ReadFrame then returns net.ErrClosed.
+	Close() error
+}
+
+var _ Bus = (*Conn)(nil)
+
+// TimestampSource tells where the reception time of a frame comes from.
+type TimestampSource uint8
+
+const (
+	// TimestampApp is the time the application read the frame.
+	TimestampApp TimestampSource = iota
+	// TimestampKernel is the time the kernel received the frame from the driver.
+	TimestampKernel
+	// TimestampHardware is the time the CAN controller received the frame, in the
+	// clock of the controller which may drift from the system clock.
+	TimestampHardware
+)
+
+// String returns "app", "kernel" or "hardware".
+func (s TimestampSource) String() string {
+	switch s {
+	case TimestampKernel:
+		return "kernel"
+	case TimestampHardware:
+		return "hardware"
+	default:
+		return "app"
+	}
+}
+
+// RxInfo describes the reception of a frame.
+type RxInfo struct {
+	// Time is the reception time of the frame, Source where it comes from.
+	Time   time.Time
+	Source TimestampSource
+	// Kernel is the kernel reception time of a frame stamped by the hardware,
+	// which relates the controller clock to the system clock (see ClockMapper).
+	Kernel time.Time
+	// Own is true for a frame sent on the connection itself, received back with
+	// SetReceiveOwnMessages.
+	Own bool
+}
+
+// InfoReader is implemented by the buses that report how frames were received.
+type InfoReader interface {
+	// ReadFrameInfo is ReadFrame returning the reception details of the frame.
+	ReadFrameInfo() (Frame, RxInfo, error)
+}
+
+var _ InfoReader = (*Conn)(nil)
+
+// MaxBatch is the most frames a BatchReader reads at once.
+const MaxBatch = 64
+
+// BatchReader is implemented by the buses that read several frames per call,
+// which keeps up with a fully loaded bus where a call per frame does not.
+type BatchReader interface {
+	// ReadFrames blocks until frames are received and stores up to
+	// min(len(frames), len(infos), MaxBatch) of them with their reception details.
+	// It returns the number of frames stored; when err is not nil, the frames
+	// before the error are still valid.
+	ReadFrames(frames []Frame, infos []RxInfo) (n int, err error)
+}
+
+// DropCounter is implemented by the buses that report the frames the kernel
+// dropped because the receive queue of the socket was full.
+type DropCounter interface {
+	// Dropped returns the number of frames dropped since the connection was opened,
+	// as of the last frame read.
+	Dropped() uint64
+}
+
+var (
+	_ BatchReader = (*Conn)(nil)
+	_ DropCounter = (*Conn)(nil)
+)
+
+// addr is the address of a SocketCAN connection, i.e. the device name.
+type addr string
+
+func (a addr) Network() string { return network }
+
+func (a addr) String() string { return string(a) }
+
+// DialOption configures a connection opened with Dial.
+type DialOption func(*dialOpts)
+
+type dialOpts struct {
+	fd             bool
+	xl             bool
+	errorFrameMask *int
+}
+
+// WithFD returns a DialOption which enables CAN FD frames on the socket.
+// The interface must be configured for CAN FD (mtu 72).
+func WithFD() DialOption {
+	return func(o *dialOpts) {
+		o.fd = true
+	}
+}
+
+// WithXL returns a DialOption which enables the reception of CAN XL frames on
+// the interfaces configured for CAN XL, and of CAN FD frames with them. Where
+// the kernel lacks support the connection is opened without, see XLError.
+func WithXL() DialOption {
+	return func(o *dialOpts) {
+		o.xl = true
+	}
+}
+
+// Interface returns the name of the device the connection is bound to.
+func (c *Conn) Interface() string {
+	return c.iface
+}
+
+// FD reports whether CAN FD frames are enabled on the connection.
+func (c *Conn) FD() bool {
+	return c.fd
+}
+
+// XL reports whether CAN XL frames are received on the connection.
+func (c *Conn) XL() bool {
+	return c.xl
+}
+
+// XLError returns why CAN XL frames are not received on a connection opened
+// WithXL on a CAN XL interface, nil when they are or were not requested. It
+// wraps ErrXLUnsupported.
+func (c *Conn) XLError() error {
+	return c.xlErr
+}
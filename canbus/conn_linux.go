@@ -0,0 +1,424 @@
+//go:build linux
+
+package canbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Conn is a raw SocketCAN connection bound to a single interface.
+type Conn struct {
+	iface string
+	fd    bool
+	// xl is set when CAN XL frames are received, xlErr tells why they are not
+	// on a CAN XL interface.
+	xl    bool
+	xlErr error
+	f     *os.File
+	rc    syscall.RawConn
+	// buf, and the buffers of batch, fit the largest frame of the connection.
+	buf []byte
+	// oob receives the timestamp control messages, stamps is the SO_TIMESTAMPING
+	// or SO_TIMESTAMPNS option enabled on the socket, 0 for none.
+	oob    [oobSize]byte
+	stamps int
+	closed atomic.Bool
+	// batch holds the buffers of ReadFrames, allocated on first use.
+	batch *rxBatch
+	// dropped is the SO_RXQ_OVFL counter of the last frame read.
+	dropped atomic.Uint32
+}
+
+// oobSize fits the timestamp and the drop counter control messages of a frame.
+const oobSize = 128
+
+// mmsghdr is struct mmsghdr of recvmmsg.
+type mmsghdr struct {
+	hdr unix.Msghdr
+	len uint32
+}
+
+type rxBatch struct {
+	msgs []mmsghdr
+	iovs []unix.Iovec
+	bufs [][]byte
+	oobs [][oobSize]byte
+}
+
+func newRxBatch(n, size int) *rxBatch {
+	b := &rxBatch{
+		msgs: make([]mmsghdr, n),
+		iovs: make([]unix.Iovec, n),
+		bufs: make([][]byte, n),
+		oobs: make([][oobSize]byte, n),
+	}
+	for i := range b.msgs {
+		b.bufs[i] = make([]byte, size)
+		b.iovs[i].Base = &b.bufs[i][0]
+		b.iovs[i].SetLen(size)
+		b.msgs[i].hdr.Iov = &b.iovs[i]
+		b.msgs[i].hdr.SetIovlen(1)
+		b.msgs[i].hdr.Control = &b.oobs[i][0]
+	}
+	return b
+}
+
+// timestampingFlags requests the hardware and the kernel receive timestamps.
+const timestampingFlags = unix.SOF_TIMESTAMPING_RX_HARDWARE | unix.SOF_TIMESTAMPING_RAW_HARDWARE |
+	unix.SOF_TIMESTAMPING_RX_SOFTWARE | unix.SOF_TIMESTAMPING_SOFTWARE
+
+// Dial opens a raw SocketCAN socket on the named device (e.g. can0, vcan0).
+func Dial(device string, opt ...DialOption) (conn *Conn, err error) {
+	defer func() {
+		if err != nil {
+			err = &net.OpError{Op: "dial", Net: network, Addr: addr(device), Err: err}
+		}
+	}()
+	opts := dialOpts{}
+	for _, f := range opt {
+		f(&opts)
+	}
+	ifi, err := net.InterfaceByName(device)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", device, err)
+	}
+	xlLink := ifi.MTU >= xlMinMTU && ifi.MTU <= xlMTU
+	if opts.fd && ifi.MTU != fdMTU && !xlLink {
+		return nil, fmt.Errorf("interface %s does not support CAN FD (mtu %d)", device, ifi.MTU)
+	}
+	fd, err := unix.Socket(unix.AF_CAN, unix.SOCK_RAW, unix.CAN_RAW)
+	if err != nil {
+		return nil, fmt.Errorf("socket: %w", err)
+	}
+	closeOnErr := func(e error) (*Conn, error) {
+		_ = unix.Close(fd)
+		return nil, e
+	}
+	if opts.fd {
+		if err := unix.SetsockoptInt(fd, unix.SOL_CAN_RAW, unix.CAN_RAW_FD_FRAMES, 1); err != nil {
+			return closeOnErr(fmt.Errorf("enable CAN FD frames: %w", err))
+		}
+	}
+	if opts.errorFrameMask != nil {
+		if err := unix.SetsockoptInt(fd, unix.SOL_CAN_RAW, unix.CAN_RAW_ERR_FILTER, *opts.errorFrameMask); err != nil {
+			return closeOnErr(fmt.Errorf("set error filter: %w", err))
+		}
+	}
+	// CAN XL reception is best effort too, the kernels before 6.2 lack it
+	var xl bool
+	var xlErr error
+	if opts.xl && xlLink {
+		if err := unix.SetsockoptInt(fd, unix.SOL_CAN_RAW, canRawXLFrames, 1); err != nil {
+			xlErr = xlError(err)
+		} else {
+			xl = true
+		}
+	}
+	// receive timestamps are best effort, frames are stamped on read without them
+	stamps := 0
+	if unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_TIMESTAMPING, timestampingFlags) == nil {
+		stamps = unix.SO_TIMESTAMPING
+	} else if unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_TIMESTAMPNS, 1) == nil {
+		stamps = unix.SO_TIMESTAMPNS
+	}
+	// so are the drop counts of the receive queue
+	_ = unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_RXQ_OVFL, 1)
+	// put fd in non-blocking mode so the created file will be registered by the runtime poller
+	if err := unix.SetNonblock(fd, true); err != nil {
+		return closeOnErr(fmt.Errorf("set nonblock: %w", err))
+	}
+	if err := unix.Bind(fd, &unix.SockaddrCAN{Ifindex: ifi.Index}); err != nil {
+		return closeOnErr(fmt.Errorf("bind: %w", err))
+	}
+	f := os.NewFile(uintptr(fd), device)
+	rc, err := f.SyscallConn()
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("syscall conn: %w", err)
+	}
+	size := fdMTU
+	if xl {
+		size = xlMTU
+	}
+	return &Conn{iface: device, fd: opts.fd, xl: xl, xlErr: xlErr, f: f, rc: rc, buf: make([]byte, size), stamps: stamps}, nil
+}
+
+// canRawXLFrames is the CAN_RAW_XL_FRAMES socket option, missing from x/sys.
+const canRawXLFrames = 7
+
+// xlError wraps the error of enabling CAN XL frames.
+func xlError(err error) error {
+	if errors.Is(err, unix.ENOPROTOOPT) {
+		return fmt.Errorf("%w: the kernel lacks CAN_RAW_XL_FRAMES (Linux 6.2 or later)", ErrXLUnsupported)
+	}
+	return fmt.Errorf("%w: enable CAN XL frames: %v", ErrXLUnsupported, err)
+}
+
+// checkXL probes once whether the kernel supports the reception of CAN XL frames.
+var checkXL = sync.OnceValue(func() error {
+	fd, err := unix.Socket(unix.AF_CAN, unix.SOCK_RAW, unix.CAN_RAW)
+	if err != nil {
+		return fmt.Errorf("%w: socket: %v", ErrXLUnsupported, err)
+	}
+	defer unix.Close(fd)
+	if err := unix.SetsockoptInt(fd, unix.SOL_CAN_RAW, canRawXLFrames, 1); err != nil {
+		return xlError(err)
+	}
+	return nil
+})
+
+// CheckXL returns nil when the kernel receives CAN XL frames on raw sockets,
+// else an error wrapping ErrXLUnsupported.
+func CheckXL() error {
+	return checkXL()
+}
+
+// WithReceiveErrorFrames returns a DialOption which enables
+// CAN error frame reception on the socket.
+func WithReceiveErrorFrames() DialOption {
+	return func(o *dialOpts) {
+		canErrMask := unix.CAN_ERR_MASK
+		o.errorFrameMask = &canErrMask
+	}
+}
+
+// ReadFrame blocks until the next frame is received. It is not safe to call
+// ReadFrame from multiple goroutines.
+func (c *Conn) ReadFrame() (Frame, error) {
+	f, _, err := c.ReadFrameInfo()
+	return f, err
+}
+
+// ReadFrameInfo is ReadFrame returning the reception time of the frame, the
+// hardware timestamp when the driver reports one and the kernel timestamp
+// otherwise, and whether the connection sent it.
+func (c *Conn) ReadFrameInfo() (Frame, RxInfo, error) {
+	var n, oobn, flags int
+	var recvErr error
+	err := c.rc.Read(func(fd uintptr) bool {
+		n, oobn, flags, _, recvErr = unix.Recvmsg(int(fd), c.buf[:], c.oob[:], 0)
+		return recvErr != unix.EAGAIN
+	})
+	if err == nil {
+		err = recvErr
+	}
+	if err != nil {
+		if c.closed.Load() {
+			err = net.ErrClosed
+		}
+		return Frame{}, RxInfo{}, c.opError("read", err)
+	}
+	var f Frame
+	if err := f.unmarshalBinary(c.buf[:n]); err != nil {
+		return Frame{}, RxInfo{}, c.opError("read", err)
+	}
+	info := c.control(c.oob[:oobn])
+	info.Own = flags&unix.MSG_DONTROUTE != 0
+	return f, info, nil
+}
+
+// ReadFrames reads up to MaxBatch frames with a single recvmmsg call.
+func (c *Conn) ReadFrames(frames []Frame, infos []RxInfo) (int, error) {
+	n := min(len(frames), len(infos), MaxBatch)
+	if n == 0 {
+		return 0, nil
+	}
+	if c.batch == nil {
+		c.batch = newRxBatch(MaxBatch, len(c.buf))
+	}
+	b := c.batch
+	for i := 0; i < n; i++ {
+		b.msgs[i].hdr.SetControllen(oobSize)
+		b.msgs[i].hdr.Flags = 0
+	}
+	var got int
+	var recvErr error
+	err := c.rc.Read(func(fd uintptr) bool {
+		for {
+			r, _, errno := unix.Syscall6(unix.SYS_RECVMMSG, fd, uintptr(unsafe.Pointer(&b.msgs[0])), uintptr(n),
+				unix.MSG_DONTWAIT, 0, 0)
+			switch errno {
+			case 0:
+				got, recvErr = int(r), nil
+				return true
+			case unix.EINTR:
+				continue
+			case unix.EAGAIN:
+				return false
+			}
+			recvErr = errno
+			return true
+		}
+	})
+	if err == nil {
+		err = recvErr
+	}
+	if err != nil {
+		if c.closed.Load() {
+			err = net.ErrClosed
+		}
+		return 0, c.opError("read", err)
+	}
+	for i := 0; i < got; i++ {
+		m := &b.msgs[i]
+		frames[i] = Frame{}
+		if err := frames[i].unmarshalBinary(b.bufs[i][:m.len]); err != nil {
+			return i, c.opError("read", err)
+		}
+		infos[i] = c.control(b.oobs[i][:m.hdr.Controllen])
+		infos[i].Own = m.hdr.Flags&unix.MSG_DONTROUTE != 0
+	}
+	return got, nil
+}
+
+// Dropped returns the frames the kernel dropped because the receive queue of the
+// socket was full, as of the last frame read.
+func (c *Conn) Dropped() uint64 {
+	return uint64(c.dropped.Load())
+}
+
+// control returns the reception time of the control messages of a frame, and
+// records the drop counter they carry.
+func (c *Conn) control(oob []byte) RxInfo {
+	if len(oob) > 0 {
+		msgs, _ := unix.ParseSocketControlMessage(oob)
+		for _, m := range msgs {
+			if m.Header.Level == unix.SOL_SOCKET && m.Header.Type == unix.SO_RXQ_OVFL && len(m.Data) >= 4 {
+				c.dropped.Store(*(*uint32)(unsafe.Pointer(&m.Data[0])))
+				continue
+			}
+			if c.stamps == 0 || m.Header.Level != unix.SOL_SOCKET || int(m.Header.Type) != c.stamps {
+				continue
+			}
+			const size = int(unsafe.Sizeof(unix.Timespec{}))
+			stamp := func(i int) (unix.Timespec, bool) {
+				if len(m.Data) < (i+1)*size {
+					return unix.Timespec{}, false
+				}
+				ts := *(*unix.Timespec)(unsafe.Pointer(&m.Data[i*size]))
+				return ts, ts.Sec != 0 || ts.Nsec != 0
+			}
+			sw, swOK := stamp(0)
+			if c.stamps == unix.SO_TIMESTAMPING {
+				// scm_timestamping: software, deprecated, raw hardware
+				if ts, ok := stamp(2); ok {
+					info := RxInfo{Time: time.Unix(ts.Unix()), Source: TimestampHardware}
+					if swOK {
+						info.Kernel = time.Unix(sw.Unix())
+					}
+					return info
+				}
+			}
+			if swOK {
+				return RxInfo{Time: time.Unix(sw.Unix()), Source: TimestampKernel}
+			}
+		}
+	}
+	return RxInfo{Time: time.Now(), Source: TimestampApp}
+}
+
+// WriteFrame transmits a frame. The context deadline, if any, is used as write deadline.
+func (c *Conn) WriteFrame(ctx context.Context, f Frame) error {
+	if f.XL != nil {
+		return c.opError("write", fmt.Errorf("%w: CAN XL frames cannot be sent", ErrXLUnsupported))
+	}
+	if f.IsFD && !c.fd {
+		return c.opError("write", errors.New("CAN FD is not enabled on this connection"))
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := c.f.SetWriteDeadline(deadline); err != nil {
+			return c.opError("set write deadline", err)
+		}
+	}
+	if _, err := c.f.Write(f.marshalBinary()); err != nil {
+		return c.opError("write", err)
+	}
+	return nil
+}
+
+// SetFilters installs CAN_RAW_FILTER receive filters on the socket. Frames
+// matching any of the filters are delivered; an empty list delivers nothing.
+func (c *Conn) SetFilters(filters []Filter) error {
+	if len(filters) > unix.CAN_RAW_FILTER_MAX {
+		return fmt.Errorf("too many filters: %d > %d", len(filters), unix.CAN_RAW_FILTER_MAX)
+	}
+	raw := make([]unix.CanFilter, len(filters))
+	for i, f := range filters {
+		raw[i] = unix.CanFilter{Id: f.canID(), Mask: f.canMask()}
+	}
+	return c.setsockopt("set filters", func(fd int) error {
+		if len(raw) == 0 {
+			// SetsockoptCanRawFilter cannot take an empty slice
+			return unix.SetsockoptString(fd, unix.SOL_CAN_RAW, unix.CAN_RAW_FILTER, "")
+		}
+		return unix.SetsockoptCanRawFilter(fd, unix.SOL_CAN_RAW, unix.CAN_RAW_FILTER, raw)
+	})
+}
+
+// SetLoopback sets CAN_RAW_LOOPBACK: whether the frames sent on the connection are
+// seen by the other sockets of the host. It is enabled by default.
+func (c *Conn) SetLoopback(enabled bool) error {
+	return c.setsockopt("set loopback", func(fd int) error {
+		return unix.SetsockoptInt(fd, unix.SOL_CAN_RAW, unix.CAN_RAW_LOOPBACK, boolInt(enabled))
+	})
+}
+
+// SetReceiveOwnMessages sets CAN_RAW_RECV_OWN_MSGS: whether the frames sent on the
+// connection are also received by it, once transmitted, with RxInfo.Own set.
+// It is disabled by default and requires the loopback.
+func (c *Conn) SetReceiveOwnMessages(enabled bool) error {
+	return c.setsockopt("set receive own messages", func(fd int) error {
+		return unix.SetsockoptInt(fd, unix.SOL_CAN_RAW, unix.CAN_RAW_RECV_OWN_MSGS, boolInt(enabled))
+	})
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (c *Conn) setsockopt(op string, fn func(fd int) error) error {
+	var opErr error
+	if err := c.rc.Control(func(fd uintptr) {
+		opErr = fn(int(fd))
+	}); err != nil {
+		return c.opError(op, err)
+	}
+	if opErr != nil {
+		return c.opError(op, opErr)
+	}
+	return nil
+}
+
+// Close closes the socket. Blocked ReadFrame calls return net.ErrClosed.
+func (c *Conn) Close() error {
+	c.closed.Store(true)
+	if err := c.f.Close(); err != nil {
+		return c.opError("close", err)
+	}
+	return nil
+}
+
+func (c *Conn) opError(op string, err error) error {
+	var pe *os.PathError
+	if errors.As(err, &pe) {
+		err = pe.Err
+	}
+	if errors.Is(err, os.ErrClosed) {
+		err = net.ErrClosed
+	}
+	return &net.OpError{Op: op, Net: network, Addr: addr(c.iface), Err: err}
+}
@@ -0,0 +1,83 @@
+//go:build !linux
+
+package canbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+var errUnsupported = errors.New("SocketCAN is only supported on Linux")
+
+// Conn is a raw SocketCAN connection bound to a single interface.
+type Conn struct {
+	iface string
+	fd    bool
+	xl    bool
+	xlErr error
+}
+
+// Dial opens a raw SocketCAN socket on the named device (e.g. can0, vcan0).
+func Dial(device string, _ ...DialOption) (*Conn, error) {
+	return nil, &net.OpError{Op: "dial", Net: network, Addr: addr(device), Err: errUnsupported}
+}
+
+// WithReceiveErrorFrames returns a DialOption which enables
+// CAN error frame reception on the socket.
+func WithReceiveErrorFrames() DialOption {
+	return func(*dialOpts) {}
+}
+
+// CheckXL returns an error wrapping ErrXLUnsupported, CAN XL frames are only
+// received by SocketCAN.
+func CheckXL() error {
+	return fmt.Errorf("%w: %v", ErrXLUnsupported, errUnsupported)
+}
+
+// ReadFrame blocks until the next frame is received.
+func (c *Conn) ReadFrame() (Frame, error) {
+	return Frame{}, errUnsupported
+}
+
+// ReadFrameInfo blocks until the next frame is received and returns its reception details.
+func (c *Conn) ReadFrameInfo() (Frame, RxInfo, error) {
+	return Frame{}, RxInfo{}, errUnsupported
+}
+
+// ReadFrames blocks until frames are received and returns their reception details.
+func (c *Conn) ReadFrames([]Frame, []RxInfo) (int, error) {
+	return 0, errUnsupported
+}
+
+// Dropped returns the number of frames the kernel dropped.
+func (c *Conn) Dropped() uint64 {
+	return 0
+}
+
+// WriteFrame transmits a frame.
+func (c *Conn) WriteFrame(context.Context, Frame) error {
+	return errUnsupported
+}
+
+// SetFilters installs receive filters on the socket.
+func (c *Conn) SetFilters([]Filter) error {
+	return errUnsupported
+}
+
+// SetLoopback sets whether the frames sent on the connection are seen by the other
+// sockets of the host.
+func (c *Conn) SetLoopback(enabled bool) error {
+	return errUnsupported
+}
+
+// SetReceiveOwnMessages sets whether the frames sent on the connection are also received by it.
+func (c *Conn) SetReceiveOwnMessages(enabled bool) error {
+	return errUnsupported
+}
+
+// Close closes the socket.
+func (c *Conn) Close() error {
+	return nil
+}
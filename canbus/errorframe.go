@@ -0,0 +1,165 @@
+package canbus
+
+import (
+	"fmt"
+
+	"go.einride.tech/can/pkg/socketcan"
+)
+
+// error frame data byte indices, see linux/can/error.h.
+const (
+	indexOfLostArbitrationBit             = 0
+	indexOfControllerError                = 1
+	indexOfProtocolError                  = 2
+	indexOfProtocolViolationErrorLocation = 3
+	indexOfTransceiverError               = 4
+	indexOfControllerSpecificInformation  = 5
+)
+
+// ErrorFrame decodes the error information carried by an error frame.
+func (f *Frame) ErrorFrame() socketcan.ErrorFrame {
+	ef := socketcan.ErrorFrame{
+		ErrorClass:                     socketcan.ErrorClass(f.ID),
+		LostArbitrationBit:             f.Data[indexOfLostArbitrationBit],
+		ControllerError:                socketcan.ControllerError(f.Data[indexOfControllerError]),
+		ProtocolError:                  socketcan.ProtocolViolationError(f.Data[indexOfProtocolError]),
+		ProtocolViolationErrorLocation: socketcan.ProtocolViolationErrorLocation(f.Data[indexOfProtocolViolationErrorLocation]),
+		TransceiverError:               socketcan.TransceiverError(f.Data[indexOfTransceiverError]),
+	}
+	copy(ef.ControllerSpecificInformation[:], f.Data[indexOfControllerSpecificInformation:])
+	return ef
+}
+
+// error classes and controller status bits of error frames, see linux/can/error.h.
+const (
+	errClassController = 0x004
+	errClassBusOff     = 0x040
+	errClassRestarted  = 0x100
+	errClassCounters   = 0x200
+
+	ctrlRxWarning = 0x04
+	ctrlTxWarning = 0x08
+	ctrlRxPassive = 0x10
+	ctrlTxPassive = 0x20
+	ctrlActive    = 0x40
+
+	indexOfTxErrorCounter = 6
+	indexOfRxErrorCounter = 7
+)
+
+// ControllerState returns the controller state an error frame reports, if any.
+func (f *Frame) ControllerState() (ControllerState, bool) {
+	if !f.IsError {
+		return 0, false
+	}
+	switch {
+	case f.ID&errClassBusOff != 0:
+		return StateBusOff, true
+	case f.ID&errClassRestarted != 0:
+		return StateErrorActive, true
+	case f.ID&errClassController == 0:
+		return 0, false
+	}
+	ctrl := f.Data[indexOfControllerError]
+	switch {
+	case ctrl&(ctrlTxPassive|ctrlRxPassive) != 0:
+		return StateErrorPassive, true
+	case ctrl&(ctrlTxWarning|ctrlRxWarning) != 0:
+		return StateErrorWarning, true
+	case ctrl&ctrlActive != 0:
+		return StateErrorActive, true
+	}
+	return 0, false
+}
+
+// ErrorCounters returns the transmit and receive error counters carried by an
+// error frame. Only drivers setting CAN_ERR_CNT report them.
+func (f *Frame) ErrorCounters() (tx, rx uint8, ok bool) {
+	if !f.IsError || f.ID&errClassCounters == 0 {
+		return 0, 0, false
+	}
+	return f.Data[indexOfTxErrorCounter], f.Data[indexOfRxErrorCounter], true
+}
+
+// more error classes and the protocol error bits, see linux/can/error.h.
+const (
+	errClassProtocol = 0x008
+	errClassNoAck    = 0x020
+	errClassBusError = 0x080
+
+	protBit   = 0x01
+	protForm  = 0x02
+	protStuff = 0x04
+	protTx    = 0x80
+
+	locCRCSequence = 0x08
+	locACKSlot     = 0x19
+
+	// errorFrameLength is CAN_ERR_DLC.
+	errorFrameLength = 8
+)
+
+// ErrorKind is a condition reported by the error frames of NewErrorFrame.
+type ErrorKind string
+
+// Error kinds of NewErrorFrame.
+const (
+	ErrorBusOff    ErrorKind = "bus-off"
+	ErrorPassive   ErrorKind = "error-passive"
+	ErrorWarning   ErrorKind = "error-warning"
+	ErrorRestarted ErrorKind = "restarted"
+	ErrorNoAck     ErrorKind = "no-ack"
+	ErrorBit       ErrorKind = "bit"
+	ErrorForm      ErrorKind = "form"
+	ErrorStuff     ErrorKind = "stuff"
+	ErrorCRC       ErrorKind = "crc"
+)
+
+// ErrorKinds lists the error kinds of NewErrorFrame.
+var ErrorKinds = []ErrorKind{ErrorBusOff, ErrorPassive, ErrorWarning, ErrorRestarted, ErrorNoAck, ErrorBit, ErrorForm, ErrorStuff, ErrorCRC}
+
+// NewErrorFrame returns the error frame a SocketCAN driver reports for kind,
+// carrying the error counters tx and rx. The passive and warning states are
+// reported for the transmitter when tx >= rx, for the receiver otherwise; the
+// bit, form and stuff errors are reported as detected while transmitting.
+func NewErrorFrame(kind ErrorKind, tx, rx uint8) (Frame, error) {
+	f := Frame{ID: errClassCounters, Length: errorFrameLength, IsError: true}
+	f.Data[indexOfTxErrorCounter] = tx
+	f.Data[indexOfRxErrorCounter] = rx
+	switch kind {
+	case ErrorBusOff:
+		f.ID |= errClassBusOff
+	case ErrorPassive:
+		f.ID |= errClassController
+		f.Data[indexOfControllerError] = ctrlRxPassive
+		if tx >= rx {
+			f.Data[indexOfControllerError] = ctrlTxPassive
+		}
+	case ErrorWarning:
+		f.ID |= errClassController
+		f.Data[indexOfControllerError] = ctrlRxWarning
+		if tx >= rx {
+			f.Data[indexOfControllerError] = ctrlTxWarning
+		}
+	case ErrorRestarted:
+		f.ID |= errClassRestarted
+	case ErrorNoAck:
+		f.ID |= errClassNoAck | errClassBusError
+		f.Data[indexOfProtocolViolationErrorLocation] = locACKSlot
+	case ErrorBit:
+		f.ID |= errClassProtocol | errClassBusError
+		f.Data[indexOfProtocolError] = protBit | protTx
+	case ErrorForm:
+		f.ID |= errClassProtocol | errClassBusError
+		f.Data[indexOfProtocolError] = protForm | protTx
+	case ErrorStuff:
+		f.ID |= errClassProtocol | errClassBusError
+		f.Data[indexOfProtocolError] = protStuff | protTx
+	case ErrorCRC:
+		f.ID |= errClassProtocol | errClassBusError
+		f.Data[indexOfProtocolViolationErrorLocation] = locCRCSequence
+	default:
+		return Frame{}, fmt.Errorf("unknown error kind %q", kind)
+	}
+	return f, nil
+}
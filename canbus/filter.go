@@ -0,0 +1,76 @@
+package canbus
+
+// Filter is a receive filter. A frame matches when
+//
+//	frame.ID & Mask == ID & Mask
+//
+// and its frame format (standard or extended) equals Extended. The zero
+// Filter is the exception: it matches every frame.
+// Invert delivers the frames that do not match instead.
+type Filter struct {
+	ID       uint32
+	Mask     uint32
+	Extended bool
+	Invert   bool
+}
+
+// AcceptAll is the filter list of a fresh socket, delivering every frame.
+var AcceptAll = []Filter{{}}
+
+// idFlagInvert is CAN_INV_FILTER.
+const idFlagInvert = 0x20000000
+
+func (f Filter) canID() uint32 {
+	id := f.ID & f.idMask()
+	if f.Extended {
+		id |= idFlagExtended
+	}
+	if f.Invert {
+		id |= idFlagInvert
+	}
+	return id
+}
+
+func (f Filter) canMask() uint32 {
+	if f.isAcceptAll() {
+		return 0
+	}
+	// always compare the frame format so an 11-bit filter does not match 29-bit IDs
+	return f.Mask&f.idMask() | idFlagExtended
+}
+
+func (f Filter) idMask() uint32 {
+	if f.Extended {
+		return idMaskExtended
+	}
+	return idMaskStandard
+}
+
+func (f Filter) isAcceptAll() bool {
+	return f == Filter{}
+}
+
+// Match reports whether a frame passes the filter, mirroring the kernel logic.
+func (f Filter) Match(fr *Frame) bool {
+	id := fr.ID
+	if fr.IsExtended {
+		id |= idFlagExtended
+	}
+	mask := f.canMask()
+	matched := id&mask == f.canID()&^idFlagInvert&mask
+	return matched != f.Invert
+}
+
+// MatchAny reports whether a frame passes any of the filters, for backends
+// filtering in user space. A nil list accepts every frame.
+func MatchAny(filters []Filter, fr *Frame) bool {
+	if filters == nil {
+		return true
+	}
+	for i := range filters {
+		if filters[i].Match(fr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+// Package canbus provides the frame model and the raw SocketCAN connection
+// used by the application for both classic CAN and CAN FD traffic, and the
+// reception of CAN XL frames.
+package canbus
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.einride.tech/can"
+)
+
+const (
+	// MaxDataLength is the max payload length of a classic CAN frame.
+	MaxDataLength = 8
+	// MaxFDDataLength is the max payload length of a CAN FD frame.
+	MaxFDDataLength = 64
+)
+
+// fdLengths maps the CAN FD DLC codes 9..15 to payload lengths.
+var fdLengths = [...]uint8{12, 16, 20, 24, 32, 48, 64}
+
+// Frame represents a classic CAN or CAN FD frame.
+type Frame struct {
+	// ID is the CAN ID.
+	ID uint32
+	// Length is the number of bytes of data in the frame.
+	Length uint8
+	// Data is the frame data, only the first Length bytes are meaningful.
+	Data [MaxFDDataLength]byte
+	// IsRemote is true for remote frames.
+	IsRemote bool
+	// IsExtended is true for extended frames, i.e. frames with 29-bit IDs.
+	IsExtended bool
+	// IsError is true for error frames reported by the CAN controller.
+	IsError bool
+	// IsFD is true for CAN FD frames.
+	IsFD bool
+	// BRS is the CAN FD bit rate switch flag.
+	BRS bool
+	// ESI is the CAN FD error state indicator flag.
+	ESI bool
+	// XL is set for CAN XL frames, whose ID is then the priority and Length 0.
+	XL *XLFrame
+}
+
+// FromCAN converts a classic einride frame.
+func FromCAN(f can.Frame) Frame {
+	out := Frame{
+		ID:         f.ID,
+		Length:     f.Length,
+		IsRemote:   f.IsRemote,
+		IsExtended: f.IsExtended,
+	}
+	copy(out.Data[:], f.Data[:])
+	return out
+}
+
+// CAN converts the frame to a classic einride frame. The payload is truncated to 8 bytes.
+func (f Frame) CAN() can.Frame {
+	out := can.Frame{
+		ID:         f.ID,
+		Length:     f.Length,
+		IsRemote:   f.IsRemote,
+		IsExtended: f.IsExtended,
+	}
+	if out.Length > MaxDataLength {
+		out.Length = MaxDataLength
+	}
+	copy(out.Data[:], f.Data[:MaxDataLength])
+	return out
+}
+
+// Payload returns the meaningful bytes of the frame data.
+func (f *Frame) Payload() []byte {
+	return f.Data[:f.Length]
+}
+
+// DLC returns the data length code of the frame.
+func (f *Frame) DLC() uint8 {
+	if !f.IsFD {
+		return f.Length
+	}
+	return LengthToDLC(int(f.Length))
+}
+
+// Validate returns an error if the Frame is not a valid CAN or CAN FD frame.
+func (f *Frame) Validate() error {
+	if f.IsExtended && f.ID > can.MaxExtendedID {
+		return fmt.Errorf("invalid extended CAN id: %v does not fit in 29 bits", f.ID)
+	} else if !f.IsExtended && f.ID > can.MaxID {
+		return fmt.Errorf("invalid standard CAN id: %v does not fit in 11 bits", f.ID)
+	}
+	if !f.IsFD {
+		if f.Length > MaxDataLength {
+			return fmt.Errorf("invalid data length: %v", f.Length)
+		}
+		if f.BRS || f.ESI {
+			return fmt.Errorf("BRS/ESI flags require a CAN FD frame")
+		}
+		return nil
+	}
+	if f.IsRemote {
+		return fmt.Errorf("CAN FD does not support remote frames")
+	}
+	if int(f.Length) != PaddedLength(int(f.Length)) {
+		return fmt.Errorf("invalid CAN FD data length: %v", f.Length)
+	}
+	return nil
+}
+
+// DLCToLength maps a CAN FD data length code (0..15) to a payload length.
+func DLCToLength(dlc uint8) uint8 {
+	switch {
+	case dlc <= MaxDataLength:
+		return dlc
+	case dlc <= 15:
+		return fdLengths[dlc-9]
+	default:
+		return MaxFDDataLength
+	}
+}
+
+// LengthToDLC maps a payload length to the smallest CAN FD data length code that can hold it.
+func LengthToDLC(n int) uint8 {
+	if n <= MaxDataLength {
+		if n < 0 {
+			return 0
+		}
+		return uint8(n)
+	}
+	for i, l := range fdLengths {
+		if n <= int(l) {
+			return uint8(9 + i)
+		}
+	}
+	return 15
+}
+
+// PaddedLength rounds a payload length up to the nearest length a CAN FD frame can carry.
+func PaddedLength(n int) int {
+	return int(DLCToLength(LengthToDLC(n)))
+}
+
+// String returns an ASCII representation of the frame in candump(1) log file format.
+//
+// Classic frames are formatted as "123#DEADBEEF" (or "123#R" for remote frames),
+// CAN FD frames as "123##<flags>DEADBEEF" where flags is a single hex digit.
+func (f Frame) String() string {
+	var id string
+	if f.IsExtended || f.IsError {
+		id = fmt.Sprintf("%08X", f.idWithErrorFlag())
+	} else {
+		id = fmt.Sprintf("%03X", f.ID)
+	}
+	data := strings.ToUpper(hex.EncodeToString(f.Payload()))
+	switch {
+	case f.IsFD:
+		return id + "##" + strconv.FormatUint(uint64(f.fdFlags()), 16) + data
+	case f.IsRemote && f.Length == 0:
+		return id + "#R"
+	case f.IsRemote:
+		return id + "#R" + strconv.Itoa(int(f.Length))
+	default:
+		return id + "#" + data
+	}
+}
+
+func (f *Frame) idWithErrorFlag() uint32 {
+	if f.IsError {
+		return f.ID | idFlagError
+	}
+	return f.ID
+}
+
+func (f *Frame) fdFlags() uint8 {
+	var flags uint8
+	if f.BRS {
+		flags |= fdFlagBRS
+	}
+	if f.ESI {
+		flags |= fdFlagESI
+	}
+	return flags
+}
+
+// UnmarshalString sets *f using the candump(1) ASCII representation of a frame.
+func (f *Frame) UnmarshalString(s string) error {
+	idPart, dataPart, ok := strings.Cut(s, "#")
+	if !ok {
+		return fmt.Errorf("invalid frame format: %v", s)
+	}
+	var frame Frame
+	if len(idPart) != 3 && len(idPart) != 8 {
+		return fmt.Errorf("invalid ID length: %v", s)
+	}
+	id, err := strconv.ParseUint(idPart, 16, 32)
+	if err != nil {
+		return fmt.Errorf("invalid frame ID: %v", s)
+	}
+	if len(idPart) == 8 {
+		frame.IsError = id&idFlagError != 0
+		frame.IsExtended = !frame.IsError
+		frame.ID = uint32(id) & idMaskExtended
+	} else {
+		frame.ID = uint32(id)
+	}
+	if strings.HasPrefix(dataPart, "#") {
+		if len(dataPart) < 2 {
+			return fmt.Errorf("missing CAN FD flags: %v", s)
+		}
+		flags, err := strconv.ParseUint(dataPart[1:2], 16, 8)
+		if err != nil {
+			return fmt.Errorf("invalid CAN FD flags: %v", s)
+		}
+		frame.IsFD = true
+		frame.BRS = flags&fdFlagBRS != 0
+		frame.ESI = flags&fdFlagESI != 0
+		dataPart = dataPart[2:]
+	} else if strings.HasPrefix(dataPart, "R") {
+		frame.IsRemote = true
+		if len(dataPart) > 2 {
+			return fmt.Errorf("invalid remote length: %v", s)
+		} else if len(dataPart) == 2 {
+			n, err := strconv.Atoi(dataPart[1:2])
+			if err != nil {
+				return fmt.Errorf("invalid remote length: %v: %w", s, err)
+			}
+			frame.Length = uint8(n)
+		}
+		*f = frame
+		return nil
+	}
+	dataPart = strings.ReplaceAll(dataPart, ".", "")
+	maxLen := MaxDataLength
+	if frame.IsFD {
+		maxLen = MaxFDDataLength
+	}
+	if len(dataPart) > 2*maxLen || len(dataPart)%2 != 0 {
+		return fmt.Errorf("invalid data length: %v", s)
+	}
+	n, err := hex.Decode(frame.Data[:], []byte(dataPart))
+	if err != nil {
+		return fmt.Errorf("invalid data: %v: %w", s, err)
+	}
+	frame.Length = uint8(n)
+	if frame.IsFD {
+		frame.Length = uint8(PaddedLength(n))
+	}
+	*f = frame
+	return nil
+}
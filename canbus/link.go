@@ -0,0 +1,100 @@
+package canbus
+
+import "fmt"
+
+// ControllerState is the error state of a CAN controller.
+type ControllerState uint32
+
+// Controller states reported by the kernel (linux/can/netlink.h).
+const (
+	StateErrorActive ControllerState = iota
+	StateErrorWarning
+	StateErrorPassive
+	StateBusOff
+	StateStopped
+	StateSleeping
+)
+
+// String returns the state as printed by ip(8), eg "ERROR-ACTIVE".
+func (s ControllerState) String() string {
+	switch s {
+	case StateErrorActive:
+		return "ERROR-ACTIVE"
+	case StateErrorWarning:
+		return "ERROR-WARNING"
+	case StateErrorPassive:
+		return "ERROR-PASSIVE"
+	case StateBusOff:
+		return "BUS-OFF"
+	case StateStopped:
+		return "STOPPED"
+	case StateSleeping:
+		return "SLEEPING"
+	default:
+		return fmt.Sprintf("STATE-%d", uint32(s))
+	}
+}
+
+// Link describes a SocketCAN network interface.
+type Link struct {
+	Name  string
+	Index int
+	// Up is true when the interface is administratively up.
+	Up bool
+	// Running is true when the interface is up and operational, a CAN controller
+	// which is bus-off is not.
+	Running bool
+	MTU     int
+	// Kind is the link type, eg "can", "vcan" or "vxcan". It is empty for
+	// drivers without netlink support such as slcan.
+	Kind string
+	// Driver is the kernel driver of the device, eg "gs_usb", or Kind for virtual interfaces.
+	Driver string
+
+	// HasController is true for hardware interfaces that report a controller
+	// state and bit timing; the fields below are only meaningful then.
+	HasController bool
+	State         ControllerState
+	Bitrate       uint32
+	// SamplePoint is the sample point of the arbitration phase in percent, eg 87.5.
+	SamplePoint float64
+	// DataBitrate and DataSamplePoint describe the CAN FD data phase, zero when FD is off.
+	DataBitrate     uint32
+	DataSamplePoint float64
+	// ClockHz is the CAN controller clock frequency.
+	ClockHz uint32
+	// CtrlMode holds the CAN_CTRLMODE_* flags of the controller.
+	CtrlMode uint32
+	// RestartMs is the automatic bus-off restart delay, zero when disabled.
+	RestartMs uint32
+	// TxErrors and RxErrors are the error counters of the controller (TEC/REC), only
+	// reported by drivers supporting berr-counter.
+	TxErrors    uint16
+	RxErrors    uint16
+	HasCounters bool
+}
+
+// FD reports whether the interface is configured for CAN FD frames, which
+// CAN XL interfaces take too.
+func (l *Link) FD() bool {
+	return l.MTU == fdMTU || l.XL()
+}
+
+// XL reports whether the interface is configured for CAN XL frames.
+func (l *Link) XL() bool {
+	return l.MTU >= xlMinMTU && l.MTU <= xlMTU
+}
+
+// LinkConfig is the bit timing programmed by ConfigureLink.
+type LinkConfig struct {
+	// Bitrate is the arbitration phase bitrate in bit/s.
+	Bitrate uint32
+	// SamplePoint is the arbitration phase sample point in percent (eg 87.5), zero for the driver default.
+	SamplePoint float64
+	// DataBitrate enables CAN FD with the given data phase bitrate, zero disables CAN FD.
+	DataBitrate uint32
+	// DataSamplePoint is the data phase sample point in percent, zero for the driver default.
+	DataSamplePoint float64
+	// RestartMs is the automatic restart delay after bus-off, zero to disable automatic restarts.
+	RestartMs uint32
+}
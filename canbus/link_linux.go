@@ -0,0 +1,263 @@
+package canbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Links returns the SocketCAN interfaces of the system ordered by name.
+func Links() ([]Link, error) {
+	info := make([]byte, unix.SizeofIfInfomsg)
+	info[0] = unix.AF_UNSPEC
+	msgs, err := routeRequest(unix.RTM_GETLINK, unix.NLM_F_DUMP, info)
+	if err != nil {
+		return nil, fmt.Errorf("list links: %w", err)
+	}
+
+	var links []Link
+	for _, m := range msgs {
+		if l, ok := parseLink(m); ok {
+			links = append(links, l)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		return links[i].Name < links[j].Name
+	})
+	return links, nil
+}
+
+// LinkByName returns the SocketCAN interface with the given name.
+func LinkByName(name string) (Link, error) {
+	links, err := Links()
+	if err != nil {
+		return Link{}, err
+	}
+	for _, l := range links {
+		if l.Name == name {
+			return l, nil
+		}
+	}
+	return Link{}, fmt.Errorf("no CAN interface named %s", name)
+}
+
+// parseLink decodes a RTM_NEWLINK message. It reports false for non-CAN interfaces.
+func parseLink(m []byte) (Link, bool) {
+	if len(m) < unix.SizeofIfInfomsg {
+		return Link{}, false
+	}
+	if binary.NativeEndian.Uint16(m[2:4]) != unix.ARPHRD_CAN {
+		return Link{}, false
+	}
+	flags := binary.NativeEndian.Uint32(m[8:12])
+	l := Link{
+		Index:   int(int32(binary.NativeEndian.Uint32(m[4:8]))),
+		Up:      flags&unix.IFF_UP != 0,
+		Running: flags&unix.IFF_RUNNING != 0,
+	}
+	attrs := parseAttrs(m[unix.SizeofIfInfomsg:])
+	l.Name = strings.TrimRight(string(attrs[unix.IFLA_IFNAME]), "\x00")
+	if b := attrs[unix.IFLA_MTU]; len(b) >= 4 {
+		l.MTU = int(binary.NativeEndian.Uint32(b))
+	}
+	if b, ok := attrs[unix.IFLA_LINKINFO]; ok {
+		info := parseAttrs(b)
+		l.Kind = strings.TrimRight(string(info[unix.IFLA_INFO_KIND]), "\x00")
+		if data, ok := info[unix.IFLA_INFO_DATA]; ok && l.Kind == "can" {
+			parseCANAttrs(&l, parseAttrs(data))
+		}
+	}
+	l.Driver = l.Kind
+	if target, err := os.Readlink(filepath.Join("/sys/class/net", l.Name, "device", "driver")); err == nil {
+		l.Driver = filepath.Base(target)
+	}
+	return l, true
+}
+
+// parseCANAttrs decodes the IFLA_CAN_* attributes of a CAN controller.
+func parseCANAttrs(l *Link, attrs map[uint16][]byte) {
+	u32 := func(b []byte, i int) uint32 {
+		if len(b) < 4*(i+1) {
+			return 0
+		}
+		return binary.NativeEndian.Uint32(b[4*i:])
+	}
+	// struct can_bittiming starts with bitrate and sample_point (in tenths of a percent)
+	if b, ok := attrs[unix.IFLA_CAN_BITTIMING]; ok {
+		l.Bitrate = u32(b, 0)
+		l.SamplePoint = float64(u32(b, 1)) / 10
+	}
+	if b, ok := attrs[unix.IFLA_CAN_DATA_BITTIMING]; ok {
+		l.DataBitrate = u32(b, 0)
+		l.DataSamplePoint = float64(u32(b, 1)) / 10
+	}
+	if b, ok := attrs[unix.IFLA_CAN_STATE]; ok {
+		l.HasController = true
+		l.State = ControllerState(u32(b, 0))
+	}
+	if b, ok := attrs[unix.IFLA_CAN_CLOCK]; ok {
+		l.ClockHz = u32(b, 0)
+	}
+	// struct can_ctrlmode is {mask, flags}
+	if b, ok := attrs[unix.IFLA_CAN_CTRLMODE]; ok {
+		l.CtrlMode = u32(b, 1)
+	}
+	if b, ok := attrs[unix.IFLA_CAN_RESTART_MS]; ok {
+		l.RestartMs = u32(b, 0)
+	}
+	// struct can_berr_counter is {txerr, rxerr} as 16-bit values
+	if b, ok := attrs[unix.IFLA_CAN_BERR_COUNTER]; ok && len(b) >= 4 {
+		l.HasCounters = true
+		l.TxErrors = binary.NativeEndian.Uint16(b[0:2])
+		l.RxErrors = binary.NativeEndian.Uint16(b[2:4])
+	}
+}
+
+// RestartLink restarts a CAN controller that is bus-off, like "ip link set <name> type can restart".
+// It requires CAP_NET_ADMIN.
+func RestartLink(name string) error {
+	l, err := LinkByName(name)
+	if err != nil {
+		return err
+	}
+	if l.Kind != "can" {
+		return fmt.Errorf("%s is not a CAN controller (kind %q)", name, l.Kind)
+	}
+	var info []byte
+	info = appendAttr(info, unix.IFLA_INFO_KIND, []byte("can"))
+	info = appendAttr(info, unix.IFLA_INFO_DATA|unix.NLA_F_NESTED, appendAttr(nil, unix.IFLA_CAN_RESTART, u32s(1)))
+	if err := setLink(l.Index, false, appendAttr(nil, unix.IFLA_LINKINFO|unix.NLA_F_NESTED, info)); err != nil {
+		return linkError("restart "+name, err)
+	}
+	return nil
+}
+
+// SetLinkUp brings a CAN interface up or down. It requires CAP_NET_ADMIN.
+func SetLinkUp(name string, up bool) error {
+	l, err := LinkByName(name)
+	if err != nil {
+		return err
+	}
+	if err := setLink(l.Index, up, nil); err != nil {
+		return linkError("set "+name+" up/down", err)
+	}
+	return nil
+}
+
+// ConfigureLink programs the bit timing of a CAN controller like
+// "ip link set <name> type can bitrate ...". The interface is brought down while it
+// is configured and up again afterwards. It requires CAP_NET_ADMIN.
+func ConfigureLink(name string, cfg LinkConfig) error {
+	l, err := LinkByName(name)
+	if err != nil {
+		return err
+	}
+	if l.Kind != "can" {
+		return fmt.Errorf("%s is not a CAN controller (kind %q), its bit timing cannot be configured", name, l.Kind)
+	}
+	if cfg.Bitrate == 0 {
+		return fmt.Errorf("bitrate must be > 0")
+	}
+	if cfg.SamplePoint < 0 || cfg.SamplePoint >= 100 || cfg.DataSamplePoint < 0 || cfg.DataSamplePoint >= 100 {
+		return fmt.Errorf("sample points must be within 0..100 %%")
+	}
+
+	// struct can_bittiming: bitrate, sample_point (tenths of a percent), then the
+	// time quanta and segments the kernel computes when left zero
+	var data []byte
+	data = appendAttr(data, unix.IFLA_CAN_BITTIMING, u32s(cfg.Bitrate, uint32(cfg.SamplePoint*10+0.5), 0, 0, 0, 0, 0, 0))
+	var ctrlFlags uint32
+	if cfg.DataBitrate > 0 {
+		ctrlFlags = unix.CAN_CTRLMODE_FD
+		data = appendAttr(data, unix.IFLA_CAN_DATA_BITTIMING, u32s(cfg.DataBitrate, uint32(cfg.DataSamplePoint*10+0.5), 0, 0, 0, 0, 0, 0))
+	}
+	data = appendAttr(data, unix.IFLA_CAN_CTRLMODE, u32s(unix.CAN_CTRLMODE_FD, ctrlFlags))
+	data = appendAttr(data, unix.IFLA_CAN_RESTART_MS, u32s(cfg.RestartMs))
+
+	var info []byte
+	info = appendAttr(info, unix.IFLA_INFO_KIND, []byte("can"))
+	info = appendAttr(info, unix.IFLA_INFO_DATA|unix.NLA_F_NESTED, data)
+	attrs := appendAttr(nil, unix.IFLA_LINKINFO|unix.NLA_F_NESTED, info)
+
+	if l.Up {
+		if err := setLink(l.Index, false, nil); err != nil {
+			return linkError("set "+name+" down", err)
+		}
+	}
+	if err := setLink(l.Index, false, attrs); err != nil {
+		return linkError("configure "+name, err)
+	}
+	if err := setLink(l.Index, true, nil); err != nil {
+		return linkError("set "+name+" up", err)
+	}
+	return nil
+}
+
+// CreateVirtualLink creates a vcan interface and brings it up, like
+// "ip link add dev <name> type vcan && ip link set <name> up".
+// It requires CAP_NET_ADMIN and the vcan kernel module.
+func CreateVirtualLink(name string) error {
+	if name == "" || len(name) >= unix.IFNAMSIZ || strings.ContainsAny(name, "/ \t\n") {
+		return fmt.Errorf("invalid interface name %q", name)
+	}
+	msg := make([]byte, unix.SizeofIfInfomsg)
+	msg[0] = unix.AF_UNSPEC
+	msg = appendAttr(msg, unix.IFLA_IFNAME, append([]byte(name), 0))
+	msg = appendAttr(msg, unix.IFLA_LINKINFO|unix.NLA_F_NESTED, appendAttr(nil, unix.IFLA_INFO_KIND, []byte("vcan")))
+	if _, err := routeRequest(unix.RTM_NEWLINK, unix.NLM_F_CREATE|unix.NLM_F_EXCL|unix.NLM_F_ACK, msg); err != nil {
+		if errors.Is(err, unix.EOPNOTSUPP) {
+			return fmt.Errorf("create %s: %w (is the vcan module loaded?)", name, err)
+		}
+		return linkError("create "+name, err)
+	}
+	return SetLinkUp(name, true)
+}
+
+// DeleteVirtualLink deletes a vcan interface like "ip link delete <name>".
+// It requires CAP_NET_ADMIN.
+func DeleteVirtualLink(name string) error {
+	l, err := LinkByName(name)
+	if err != nil {
+		return err
+	}
+	if l.Kind != "vcan" {
+		return fmt.Errorf("%s is not a virtual CAN interface (kind %q)", name, l.Kind)
+	}
+	msg := make([]byte, unix.SizeofIfInfomsg)
+	msg[0] = unix.AF_UNSPEC
+	binary.NativeEndian.PutUint32(msg[4:8], uint32(l.Index))
+	if _, err := routeRequest(unix.RTM_DELLINK, unix.NLM_F_ACK, msg); err != nil {
+		return linkError("delete "+name, err)
+	}
+	return nil
+}
+
+// setLink sends a RTM_NEWLINK request changing the IFF_UP flag of a link and setting attrs.
+// With attrs the up flag is left unchanged.
+func setLink(index int, up bool, attrs []byte) error {
+	msg := make([]byte, unix.SizeofIfInfomsg, unix.SizeofIfInfomsg+len(attrs))
+	msg[0] = unix.AF_UNSPEC
+	binary.NativeEndian.PutUint32(msg[4:8], uint32(index))
+	if attrs == nil {
+		if up {
+			binary.NativeEndian.PutUint32(msg[8:12], unix.IFF_UP)
+		}
+		binary.NativeEndian.PutUint32(msg[12:16], unix.IFF_UP)
+	}
+	msg = append(msg, attrs...)
+	_, err := routeRequest(unix.RTM_NEWLINK, unix.NLM_F_ACK, msg)
+	return err
+}
+
+func linkError(op string, err error) error {
+	if errors.Is(err, unix.EPERM) {
+		return fmt.Errorf("%s: %w (CAP_NET_ADMIN required)", op, err)
+	}
+	return fmt.Errorf("%s: %w", op, err)
+}
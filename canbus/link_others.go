@@ -0,0 +1,45 @@
+//go:build !linux
+
+package canbus
+
+import "context"
+
+// Links returns the SocketCAN interfaces of the system ordered by name.
+func Links() ([]Link, error) {
+	return nil, errUnsupported
+}
+
+// LinkByName returns the SocketCAN interface with the given name.
+func LinkByName(name string) (Link, error) {
+	return Link{}, errUnsupported
+}
+
+// SetLinkUp brings a CAN interface up or down.
+func SetLinkUp(name string, up bool) error {
+	return errUnsupported
+}
+
+// ConfigureLink programs the bit timing of a CAN controller.
+func ConfigureLink(name string, cfg LinkConfig) error {
+	return errUnsupported
+}
+
+// RestartLink restarts a CAN controller that is bus-off.
+func RestartLink(name string) error {
+	return errUnsupported
+}
+
+// CreateVirtualLink creates a vcan interface and brings it up.
+func CreateVirtualLink(name string) error {
+	return errUnsupported
+}
+
+// DeleteVirtualLink deletes a vcan interface.
+func DeleteVirtualLink(name string) error {
+	return errUnsupported
+}
+
+// WatchLinks calls fn for every change of a CAN interface until ctx is done.
+func WatchLinks(ctx context.Context, fn func(l Link, removed bool)) error {
+	return errUnsupported
+}
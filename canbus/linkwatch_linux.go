@@ -0,0 +1,61 @@
+package canbus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// WatchLinks calls fn for every change of a CAN interface reported by the kernel,
+// such as an interface brought up or down or a USB adapter plugged or unplugged,
+// until ctx is done. removed is true when the interface was deleted.
+func WatchLinks(ctx context.Context, fn func(l Link, removed bool)) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_ROUTE)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: unix.RTMGRP_LINK}); err != nil {
+		unix.Close(fd)
+		return os.NewSyscallError("bind", err)
+	}
+	// a pollable file so closing it unblocks the read
+	f := os.NewFile(uintptr(fd), "netlink")
+	defer f.Close()
+	stop := context.AfterFunc(ctx, func() { f.Close() })
+	defer stop()
+
+	buf := make([]byte, 1<<16)
+	for {
+		n, err := f.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, unix.ENOBUFS) {
+				// the kernel dropped notifications, the next ones are still valid
+				continue
+			}
+			return err
+		}
+		b := buf[:n]
+		for len(b) >= unix.SizeofNlMsghdr {
+			l := int(binary.NativeEndian.Uint32(b[0:4]))
+			typ := binary.NativeEndian.Uint16(b[4:6])
+			if l < unix.SizeofNlMsghdr || l > len(b) {
+				break
+			}
+			if typ == unix.RTM_NEWLINK || typ == unix.RTM_DELLINK {
+				if link, ok := parseLink(b[unix.SizeofNlMsghdr:l]); ok {
+					fn(link, typ == unix.RTM_DELLINK)
+				}
+			}
+			if attrAlign(l) >= len(b) {
+				break
+			}
+			b = b[attrAlign(l):]
+		}
+	}
+}
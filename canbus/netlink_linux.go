@@ -0,0 +1,124 @@
+package canbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"golang.org/x/sys/unix"
+)
+
+// netlinkSeq numbers the route netlink requests of the process.
+var netlinkSeq atomic.Uint32
+
+// attrAlign rounds a netlink attribute length up to its 4-byte alignment.
+func attrAlign(n int) int {
+	return (n + unix.NLA_ALIGNTO - 1) &^ (unix.NLA_ALIGNTO - 1)
+}
+
+// parseAttrs splits a netlink attribute stream by type. The nested and byte order
+// flags are stripped from the types.
+func parseAttrs(b []byte) map[uint16][]byte {
+	attrs := make(map[uint16][]byte)
+	for len(b) >= unix.SizeofRtAttr {
+		n := int(binary.NativeEndian.Uint16(b[0:2]))
+		typ := binary.NativeEndian.Uint16(b[2:4]) &^ (unix.NLA_F_NESTED | unix.NLA_F_NET_BYTEORDER)
+		if n < unix.SizeofRtAttr || n > len(b) {
+			break
+		}
+		attrs[typ] = b[unix.SizeofRtAttr:n]
+		if attrAlign(n) >= len(b) {
+			break
+		}
+		b = b[attrAlign(n):]
+	}
+	return attrs
+}
+
+// routeRequest sends a route netlink request and returns the payloads of the answered
+// messages. Dump requests collect every part until NLMSG_DONE; other requests
+// should set NLM_F_ACK and return no payload.
+func routeRequest(msgType uint16, flags uint16, payload []byte) ([][]byte, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	defer unix.Close(fd)
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	seq := netlinkSeq.Add(1)
+	msg := make([]byte, unix.SizeofNlMsghdr+len(payload))
+	binary.NativeEndian.PutUint32(msg[0:4], uint32(len(msg)))
+	binary.NativeEndian.PutUint16(msg[4:6], msgType)
+	binary.NativeEndian.PutUint16(msg[6:8], flags|unix.NLM_F_REQUEST)
+	binary.NativeEndian.PutUint32(msg[8:12], seq)
+	copy(msg[unix.SizeofNlMsghdr:], payload)
+	if err := unix.Sendto(fd, msg, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return nil, os.NewSyscallError("sendto", err)
+	}
+
+	var out [][]byte
+	buf := make([]byte, 1<<16)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return nil, os.NewSyscallError("recvfrom", err)
+		}
+		b := buf[:n]
+		for len(b) >= unix.SizeofNlMsghdr {
+			l := int(binary.NativeEndian.Uint32(b[0:4]))
+			typ := binary.NativeEndian.Uint16(b[4:6])
+			if l < unix.SizeofNlMsghdr || l > len(b) {
+				return nil, errors.New("netlink: malformed message")
+			}
+			body := b[unix.SizeofNlMsghdr:l]
+			if binary.NativeEndian.Uint32(b[8:12]) == seq {
+				switch typ {
+				case unix.NLMSG_DONE:
+					return out, nil
+				case unix.NLMSG_ERROR:
+					if len(body) < 4 {
+						return nil, errors.New("netlink: malformed error message")
+					}
+					if code := int32(binary.NativeEndian.Uint32(body[0:4])); code != 0 {
+						return nil, fmt.Errorf("netlink: %w", unix.Errno(-code))
+					}
+					return out, nil
+				default:
+					out = append(out, append([]byte(nil), body...))
+				}
+				if flags&unix.NLM_F_DUMP != unix.NLM_F_DUMP && flags&unix.NLM_F_ACK == 0 {
+					return out, nil
+				}
+			}
+			if attrAlign(l) >= len(b) {
+				break
+			}
+			b = b[attrAlign(l):]
+		}
+	}
+}
+
+// appendAttr appends a netlink attribute to b.
+func appendAttr(b []byte, typ uint16, data []byte) []byte {
+	n := unix.SizeofRtAttr + len(data)
+	hdr := make([]byte, unix.SizeofRtAttr)
+	binary.NativeEndian.PutUint16(hdr[0:2], uint16(n))
+	binary.NativeEndian.PutUint16(hdr[2:4], typ)
+	b = append(b, hdr...)
+	b = append(b, data...)
+	return append(b, make([]byte, attrAlign(n)-n)...)
+}
+
+// u32s encodes native endian 32-bit words.
+func u32s(v ...uint32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.NativeEndian.PutUint32(b[4*i:], x)
+	}
+	return b
+}
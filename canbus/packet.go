@@ -0,0 +1,31 @@
+package canbus
+
+// Network is the type of bus a record of a trace or of the capture buffer was
+// seen on.
+type Network string
+
+// Networks of the records.
+const (
+	NetworkCAN      Network = "can"
+	NetworkCANXL    Network = "canxl"
+	NetworkLIN      Network = "lin"
+	NetworkFlexRay  Network = "flexray"
+	NetworkEthernet Network = "ethernet"
+	// NetworkGPS are the samples of a GPS receiver, see package gps.
+	NetworkGPS Network = "gps"
+)
+
+// Packet is a frame of a network other than CAN, or a CAN XL frame. Its content is kept as an
+// opaque payload so that traces of several networks can be merged into a
+// single timeline with the CAN frames.
+type Packet struct {
+	Network Network
+	// ID is the LIN frame ID, the FlexRay slot ID or the CAN XL priority, 0
+	// for Ethernet and GPS.
+	ID uint32
+	// Cycle is the FlexRay cycle count.
+	Cycle uint8
+	// Payload is the data of the frame, the whole frame for Ethernet, the
+	// encoded gps.Sample for GPS.
+	Payload []byte
+}
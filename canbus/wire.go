@@ -0,0 +1,114 @@
+package canbus
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// classicMTU is the size of struct can_frame.
+	classicMTU = 16
+	// fdMTU is the size of struct canfd_frame.
+	fdMTU = 72
+	// indexOfData is the index of the first data byte in both frame layouts.
+	indexOfData = 8
+)
+
+// id flags (copied from x/sys/unix).
+const (
+	idFlagExtended = 0x80000000
+	idFlagRemote   = 0x40000000
+	idFlagError    = 0x20000000
+	idMaskExtended = 0x1fffffff
+	idMaskStandard = 0x7ff
+)
+
+// canfd_frame flags.
+const (
+	fdFlagBRS = 0x01
+	fdFlagESI = 0x02
+	fdFlagFDF = 0x04
+)
+
+// marshalBinary encodes the frame using the SocketCAN layout:
+//
+//	struct can_frame {               struct canfd_frame {
+//	        canid_t can_id;                  canid_t can_id;
+//	        __u8    len;                     __u8    len;
+//	        __u8    __pad;                   __u8    flags;
+//	        __u8    __res0;                  __u8    __res0;
+//	        __u8    len8_dlc;                __u8    __res1;
+//	        __u8    data[8];                 __u8    data[64];
+//	};                               };
+func (f *Frame) marshalBinary() []byte {
+	return f.MarshalSocketCAN(binary.NativeEndian)
+}
+
+// MarshalSocketCAN encodes the frame like marshalBinary with can_id in the given
+// byte order, eg big endian for the LINKTYPE_CAN_SOCKETCAN pcap link type.
+func (f *Frame) MarshalSocketCAN(order binary.ByteOrder) []byte {
+	size := classicMTU
+	if f.IsFD {
+		size = fdMTU
+	}
+	b := make([]byte, size)
+	order.PutUint32(b[0:4], f.idAndFlags())
+	b[4] = f.Length
+	if f.IsFD {
+		b[5] = f.fdFlags() | fdFlagFDF
+	}
+	copy(b[indexOfData:], f.Data[:size-indexOfData])
+	return b
+}
+
+// idAndFlags returns the canid_t of the frame.
+func (f *Frame) idAndFlags() uint32 {
+	idAndFlags := f.ID
+	if f.IsExtended {
+		idAndFlags |= idFlagExtended
+	}
+	if f.IsRemote {
+		idAndFlags |= idFlagRemote
+	}
+	if f.IsError {
+		idAndFlags |= idFlagError
+	}
+	return idAndFlags
+}
+
+// unmarshalBinary decodes a frame read from a SocketCAN socket. The frame
+// layout is selected by the size of b, and the XLF flag of CAN XL frames.
+func (f *Frame) unmarshalBinary(b []byte) error {
+	if isXL(b) {
+		return f.unmarshalXL(b)
+	}
+	if len(b) != classicMTU && len(b) != fdMTU {
+		return fmt.Errorf("unexpected frame size %d", len(b))
+	}
+	idAndFlags := binary.NativeEndian.Uint32(b[0:4])
+	*f = Frame{
+		Length:     b[4],
+		IsExtended: idAndFlags&idFlagExtended != 0,
+		IsRemote:   idAndFlags&idFlagRemote != 0,
+		IsError:    idAndFlags&idFlagError != 0,
+		IsFD:       len(b) == fdMTU,
+	}
+	switch {
+	case f.IsError:
+		f.ID = idAndFlags & idMaskExtended
+	case f.IsExtended:
+		f.ID = idAndFlags & idMaskExtended
+	default:
+		f.ID = idAndFlags & idMaskStandard
+	}
+	if f.IsFD {
+		f.BRS = b[5]&fdFlagBRS != 0
+		f.ESI = b[5]&fdFlagESI != 0
+	}
+	maxLen := len(b) - indexOfData
+	if int(f.Length) > maxLen {
+		f.Length = uint8(maxLen)
+	}
+	copy(f.Data[:], b[indexOfData:])
+	return nil
+}
@@ -0,0 +1,86 @@
+package canbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// MaxXLDataLength is the max payload length of a CAN XL frame.
+const MaxXLDataLength = 2048
+
+const (
+	// xlHeaderSize is the size of struct canxl_frame without data.
+	xlHeaderSize = 12
+	// xlMinMTU and xlMTU bound the MTU of CAN XL capable interfaces, xlMTU
+	// being the size of struct canxl_frame.
+	xlMinMTU = xlHeaderSize + MaxFDDataLength
+	xlMTU    = xlHeaderSize + MaxXLDataLength
+)
+
+// canxl_frame flags.
+const (
+	xlFlagSEC = 0x01
+	xlFlagRRS = 0x02
+	xlFlagXLF = 0x80
+)
+
+// xlVCIDShift is the position of the virtual CAN network ID in the prio field.
+const xlVCIDShift = 16
+
+// ErrXLUnsupported is wrapped by the errors of the CAN XL features the kernel
+// or the connection lack.
+var ErrXLUnsupported = errors.New("CAN XL is not supported")
+
+// XLFrame is a CAN XL frame. Its payload does not fit a Frame, which carries it
+// in Frame.XL.
+type XLFrame struct {
+	// Priority is the 11-bit priority ID of the arbitration, VCID the virtual
+	// CAN network ID.
+	Priority uint16
+	VCID     uint8
+	// SDT is the SDU type of the payload and AF the acceptance field.
+	SDT uint8
+	AF  uint32
+	// SEC is the simple extended content flag and RRS the remote request
+	// substitution bit.
+	SEC bool
+	RRS bool
+	// Data is the payload, 1 to MaxXLDataLength bytes.
+	Data []byte
+}
+
+// isXL reports whether b holds a struct canxl_frame: its flags, where the
+// length of the other layouts is, have the XLF bit.
+func isXL(b []byte) bool {
+	return len(b) >= xlHeaderSize && b[4]&xlFlagXLF != 0
+}
+
+// unmarshalXL decodes a struct canxl_frame:
+//
+//	struct canxl_frame {
+//	        canid_t prio;
+//	        __u8    flags;
+//	        __u8    sdt;
+//	        __u16   len;
+//	        __u32   af;
+//	        __u8    data[CANXL_MAX_DLEN];
+//	};
+func (f *Frame) unmarshalXL(b []byte) error {
+	n := int(binary.NativeEndian.Uint16(b[6:8]))
+	if n < 1 || n > MaxXLDataLength || len(b) < xlHeaderSize+n {
+		return fmt.Errorf("invalid CAN XL frame of %d bytes with length %d", len(b), n)
+	}
+	prio := binary.NativeEndian.Uint32(b[0:4])
+	xl := &XLFrame{
+		Priority: uint16(prio & idMaskStandard),
+		VCID:     uint8(prio >> xlVCIDShift),
+		SDT:      b[5],
+		AF:       binary.NativeEndian.Uint32(b[8:12]),
+		SEC:      b[4]&xlFlagSEC != 0,
+		RRS:      b[4]&xlFlagRRS != 0,
+		Data:     append([]byte(nil), b[xlHeaderSize:xlHeaderSize+n]...),
+	}
+	*f = Frame{ID: uint32(xl.Priority), XL: xl}
+	return nil
+}
@@ -0,0 +1,477 @@
+package candb
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadARXML reads and parses an AUTOSAR system description (.arxml).
+func LoadARXML(path string) (*Database, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseARXML(path, data)
+}
+
+// arNode is an element of an ARXML document.
+type arNode struct {
+	tag      string
+	text     string
+	parent   *arNode
+	children []*arNode
+}
+
+// child returns the first element at the path of tags below n.
+func (n *arNode) child(tags ...string) *arNode {
+	for _, tag := range tags {
+		if n == nil {
+			return nil
+		}
+		var next *arNode
+		for _, c := range n.children {
+			if c.tag == tag {
+				next = c
+				break
+			}
+		}
+		n = next
+	}
+	return n
+}
+
+// value returns the text of the element at the path of tags below n.
+func (n *arNode) value(tags ...string) string {
+	if c := n.child(tags...); c != nil {
+		return c.text
+	}
+	return ""
+}
+
+// find returns the descendants of n with the given tag, in document order.
+func (n *arNode) find(tag string) []*arNode {
+	var found []*arNode
+	for _, c := range n.children {
+		if c.tag == tag {
+			found = append(found, c)
+		}
+		found = append(found, c.find(tag)...)
+	}
+	return found
+}
+
+func (n *arNode) name() string {
+	return n.value("SHORT-NAME")
+}
+
+type arxmlParser struct {
+	// paths are the elements with a SHORT-NAME by their reference path.
+	paths map[string]*arNode
+}
+
+// ParseARXML parses an AUTOSAR 4 system description: every CAN frame triggering
+// of its CAN clusters becomes a message with the signals of its I-PDUs,
+// multiplexed I-PDUs a multiplexed message and container I-PDUs a container
+// message. Frames triggered on several channels with the same ID are merged,
+// the first definition wins. filename is only used for error positions.
+func ParseARXML(filename string, data []byte) (*Database, error) {
+	root, err := parseARTree(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filename, err)
+	}
+	if root.tag != "AUTOSAR" {
+		return nil, fmt.Errorf("parse %s: not an AUTOSAR document", filename)
+	}
+	p := &arxmlParser{paths: make(map[string]*arNode)}
+	p.index(root, "")
+
+	db := &Database{SourceFile: filename}
+	for _, ecu := range root.find("ECU-INSTANCE") {
+		db.Nodes = append(db.Nodes, ecu.name())
+	}
+	db.reindex()
+	for _, ft := range root.find("CAN-FRAME-TRIGGERING") {
+		m, err := p.message(ft)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", filename, err)
+		}
+		if _, dup := db.Message(m.ID, m.IsExtended); dup {
+			continue
+		}
+		db.Messages = append(db.Messages, m)
+		db.index[indexKey(m.ID, m.IsExtended)] = m
+	}
+	sortDatabase(db)
+	return db, nil
+}
+
+func parseARTree(data []byte) (*arNode, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	top := &arNode{}
+	cur := top
+	for {
+		tok, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			n := &arNode{tag: tok.Name.Local, parent: cur}
+			cur.children = append(cur.children, n)
+			cur = n
+		case xml.EndElement:
+			cur.text = strings.TrimSpace(cur.text)
+			if cur.parent != nil {
+				cur = cur.parent
+			}
+		case xml.CharData:
+			cur.text += string(tok)
+		}
+	}
+	if len(top.children) == 0 {
+		return nil, errors.New("empty document")
+	}
+	return top.children[0], nil
+}
+
+// index records the elements with a SHORT-NAME below n, whose parent path is prefix.
+func (p *arxmlParser) index(n *arNode, prefix string) {
+	if name := n.name(); name != "" {
+		prefix += "/" + name
+		p.paths[prefix] = n
+	}
+	for _, c := range n.children {
+		p.index(c, prefix)
+	}
+}
+
+// ref resolves the reference at the path of tags below n.
+func (p *arxmlParser) ref(n *arNode, tags ...string) (*arNode, error) {
+	r := n.value(tags...)
+	if r == "" {
+		return nil, nil
+	}
+	target, ok := p.paths[r]
+	if !ok {
+		return nil, fmt.Errorf("%s %s: unresolved reference %s", n.tag, n.name(), r)
+	}
+	return target, nil
+}
+
+func (p *arxmlParser) message(ft *arNode) (*Message, error) {
+	frame, err := p.ref(ft, "FRAME-REF")
+	if err != nil {
+		return nil, err
+	}
+	if frame == nil {
+		return nil, fmt.Errorf("frame triggering %s has no frame", ft.name())
+	}
+	id, err := strconv.ParseUint(ft.value("IDENTIFIER"), 0, 32)
+	if err != nil {
+		return nil, fmt.Errorf("frame triggering %s: invalid identifier %q", ft.name(), ft.value("IDENTIFIER"))
+	}
+	m := &Message{
+		Name:        frame.name(),
+		ID:          uint32(id),
+		IsExtended:  ft.value("CAN-ADDRESSING-MODE") == "EXTENDED",
+		Description: arDescription(frame),
+	}
+	if m.Length, err = strconv.Atoi(frame.value("FRAME-LENGTH")); err != nil || m.Length < 0 || m.Length > 64 {
+		return nil, fmt.Errorf("frame %s: invalid length %q", m.Name, frame.value("FRAME-LENGTH"))
+	}
+	for _, pr := range ft.find("FRAME-PORT-REF") {
+		port := p.paths[pr.text]
+		if port == nil || port.value("COMMUNICATION-DIRECTION") != "OUT" {
+			continue
+		}
+		for n := port.parent; n != nil; n = n.parent {
+			if n.tag == "ECU-INSTANCE" {
+				m.Sender = n.name()
+				break
+			}
+		}
+	}
+
+	for _, mapping := range frame.find("PDU-TO-FRAME-MAPPING") {
+		pdu, err := p.ref(mapping, "PDU-REF")
+		if err != nil {
+			return nil, err
+		}
+		if pdu == nil {
+			continue
+		}
+		offset, _ := strconv.Atoi(mapping.value("START-POSITION"))
+		if err := p.addPDU(m, pdu, offset); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// addPDU adds the signals of pdu, mapped at the bit offset of m.
+func (p *arxmlParser) addPDU(m *Message, pdu *arNode, offset int) error {
+	if m.CycleTime == 0 {
+		m.CycleTime = arCycleTime(pdu)
+	}
+	switch pdu.tag {
+	case "I-SIGNAL-I-PDU", "NM-PDU":
+		return p.addSignals(m, pdu, offset, nil, 0)
+
+	case "MULTIPLEXED-I-PDU":
+		sw := &Signal{
+			Name:          pdu.name() + "_Selector",
+			Length:        1,
+			IsBigEndian:   pdu.value("SELECTOR-FIELD-BYTE-ORDER") == "MOST-SIGNIFICANT-BYTE-FIRST",
+			Scale:         1,
+			IsMultiplexer: true,
+		}
+		start, err := strconv.Atoi(pdu.value("SELECTOR-FIELD-START-POSITION"))
+		if err != nil {
+			return fmt.Errorf("multiplexed PDU %s: invalid selector position", pdu.name())
+		}
+		sw.Start = start + offset
+		if sw.Length, err = strconv.Atoi(pdu.value("SELECTOR-FIELD-LENGTH")); err != nil {
+			return fmt.Errorf("multiplexed PDU %s: invalid selector length", pdu.name())
+		}
+		if err := addSignal(m, sw, nil, 0); err != nil {
+			return err
+		}
+		for _, part := range pdu.find("STATIC-PART") {
+			static, err := p.ref(part, "I-PDU-REF")
+			if err != nil {
+				return err
+			}
+			if static != nil {
+				if err := p.addSignals(m, static, offset, nil, 0); err != nil {
+					return err
+				}
+			}
+		}
+		for _, alt := range pdu.find("DYNAMIC-PART-ALTERNATIVE") {
+			dynamic, err := p.ref(alt, "I-PDU-REF")
+			if err != nil {
+				return err
+			}
+			code, err := strconv.ParseUint(alt.value("SELECTOR-FIELD-CODE"), 0, 64)
+			if dynamic == nil || err != nil {
+				continue
+			}
+			sw.ValueDescriptions = append(sw.ValueDescriptions, ValueDescription{Value: int64(code), Description: dynamic.name()})
+			if err := p.addSignals(m, dynamic, offset, sw, code); err != nil {
+				return err
+			}
+		}
+
+	case "CONTAINER-I-PDU":
+		m.ContainerHeader = ContainerHeaderShort
+		if pdu.value("HEADER-TYPE") == "LONG-HEADER" {
+			m.ContainerHeader = ContainerHeaderLong
+		}
+		for _, tr := range pdu.find("CONTAINED-PDU-TRIGGERING-REF") {
+			pt := p.paths[tr.text]
+			if pt == nil {
+				return fmt.Errorf("container PDU %s: unresolved reference %s", pdu.name(), tr.text)
+			}
+			ipdu, err := p.ref(pt, "I-PDU-REF")
+			if err != nil {
+				return err
+			}
+			if ipdu == nil {
+				continue
+			}
+			header := ipdu.value("CONTAINED-I-PDU-PROPS", "HEADER-ID-SHORT-HEADER")
+			if m.ContainerHeader == ContainerHeaderLong {
+				header = ipdu.value("CONTAINED-I-PDU-PROPS", "HEADER-ID-LONG-HEADER")
+			}
+			id, err := strconv.ParseUint(header, 0, 32)
+			if err != nil {
+				return fmt.Errorf("contained PDU %s: invalid header ID %q", ipdu.name(), header)
+			}
+			contained := &Message{Name: ipdu.name(), ID: uint32(id), Description: arDescription(ipdu)}
+			contained.Length, _ = strconv.Atoi(ipdu.value("LENGTH"))
+			if err := p.addPDU(contained, ipdu, 0); err != nil {
+				return err
+			}
+			m.Contained = append(m.Contained, contained)
+		}
+	}
+	return nil
+}
+
+// addSignals adds the I-signals mapped to pdu, multiplexed by sw at value when
+// sw is not nil.
+func (p *arxmlParser) addSignals(m *Message, pdu *arNode, offset int, sw *Signal, value uint64) error {
+	for _, mapping := range pdu.find("I-SIGNAL-TO-I-PDU-MAPPING") {
+		// mappings of signal groups have no I-SIGNAL-REF
+		isig, err := p.ref(mapping, "I-SIGNAL-REF")
+		if err != nil {
+			return err
+		}
+		if isig == nil {
+			continue
+		}
+		s, err := p.signal(isig)
+		if err != nil {
+			return err
+		}
+		start, err := strconv.Atoi(mapping.value("START-POSITION"))
+		if err != nil {
+			return fmt.Errorf("%s: invalid start position of %s", pdu.name(), s.Name)
+		}
+		// the start position of big endian signals is their most significant bit,
+		// as in DBC files
+		s.Start = start + offset
+		s.IsBigEndian = mapping.value("PACKING-BYTE-ORDER") == "MOST-SIGNIFICANT-BYTE-FIRST"
+		if err := addSignal(m, s, sw, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *arxmlParser) signal(isig *arNode) (*Signal, error) {
+	s := &Signal{Name: isig.name(), Scale: 1, Description: arDescription(isig)}
+	var err error
+	if s.Length, err = strconv.Atoi(isig.value("LENGTH")); err != nil {
+		return nil, fmt.Errorf("signal %s: invalid length %q", s.Name, isig.value("LENGTH"))
+	}
+	sys, err := p.ref(isig, "SYSTEM-SIGNAL-REF")
+	if err != nil {
+		return nil, err
+	}
+	if sys != nil && s.Description == "" {
+		s.Description = arDescription(sys)
+	}
+
+	// the network representation of the I-signal overrides the physical
+	// properties of its system signal
+	props := isig.child("NETWORK-REPRESENTATION-PROPS", "SW-DATA-DEF-PROPS-VARIANTS", "SW-DATA-DEF-PROPS-CONDITIONAL")
+	if props.child("COMPU-METHOD-REF") == nil && sys != nil {
+		props = sys.child("PHYSICAL-PROPS", "SW-DATA-DEF-PROPS-VARIANTS", "SW-DATA-DEF-PROPS-CONDITIONAL")
+	}
+	if props == nil {
+		return s, nil
+	}
+	base, err := p.ref(props, "BASE-TYPE-REF")
+	if err != nil {
+		return nil, err
+	}
+	if base != nil {
+		switch base.value("BASE-TYPE-ENCODING") {
+		case "2C":
+			s.IsSigned = true
+		case "IEEE754":
+			switch s.Length {
+			case 32:
+				s.ValueType = ValueTypeFloat32
+			case 64:
+				s.ValueType = ValueTypeFloat64
+			}
+		}
+	}
+	compu, err := p.ref(props, "COMPU-METHOD-REF")
+	if err != nil {
+		return nil, err
+	}
+	unit, err := p.ref(props, "UNIT-REF")
+	if err != nil {
+		return nil, err
+	}
+	if compu != nil {
+		if unit == nil {
+			if unit, err = p.ref(compu, "UNIT-REF"); err != nil {
+				return nil, err
+			}
+		}
+		if err := applyCompuMethod(s, compu); err != nil {
+			return nil, err
+		}
+	}
+	if unit != nil {
+		s.Unit = unit.value("DISPLAY-NAME")
+		if s.Unit == "" {
+			s.Unit = unit.name()
+		}
+	}
+	return s, nil
+}
+
+// applyCompuMethod sets the scale, offset and range of s from the first linear
+// scale of compu, and its value descriptions from the text table scales.
+func applyCompuMethod(s *Signal, compu *arNode) error {
+	linear := false
+	for _, scale := range compu.child("COMPU-INTERNAL-TO-PHYS").find("COMPU-SCALE") {
+		lower, lerr := strconv.ParseFloat(scale.value("LOWER-LIMIT"), 64)
+		upper, uerr := strconv.ParseFloat(scale.value("UPPER-LIMIT"), 64)
+		if text := scale.value("COMPU-CONST", "VT"); text != "" {
+			if lerr == nil && lower == math.Trunc(lower) {
+				s.ValueDescriptions = append(s.ValueDescriptions, ValueDescription{Value: int64(lower), Description: text})
+			}
+			continue
+		}
+		coeffs := scale.child("COMPU-RATIONAL-COEFFS")
+		if coeffs == nil || linear {
+			continue
+		}
+		var num []float64
+		for _, v := range coeffs.child("COMPU-NUMERATOR").find("V") {
+			x, err := strconv.ParseFloat(v.text, 64)
+			if err != nil {
+				return fmt.Errorf("compu method %s: invalid coefficient %q", compu.name(), v.text)
+			}
+			num = append(num, x)
+		}
+		den := 1.0
+		if d := coeffs.value("COMPU-DENOMINATOR", "V"); d != "" {
+			var err error
+			if den, err = strconv.ParseFloat(d, 64); err != nil || den == 0 {
+				return fmt.Errorf("compu method %s: invalid denominator %q", compu.name(), d)
+			}
+		}
+		if len(num) < 2 {
+			continue
+		}
+		linear = true
+		s.Offset, s.Scale = num[0]/den, num[1]/den
+		if lerr == nil && uerr == nil {
+			s.Min, s.Max = s.ToPhysical(lower), s.ToPhysical(upper)
+			if s.Min > s.Max {
+				s.Min, s.Max = s.Max, s.Min
+			}
+		}
+	}
+	return nil
+}
+
+// arCycleTime returns the period of the cyclic transmission of pdu, if any.
+func arCycleTime(pdu *arNode) time.Duration {
+	for _, cyclic := range pdu.find("CYCLIC-TIMING") {
+		period := cyclic.value("TIME-PERIOD", "VALUE")
+		if period == "" {
+			// AUTOSAR before 4.1
+			period = cyclic.value("REPETITION-PERIOD", "VALUE")
+		}
+		if sec, err := strconv.ParseFloat(period, 64); err == nil && sec > 0 {
+			return time.Duration(sec * float64(time.Second)).Round(time.Microsecond)
+		}
+	}
+	return 0
+}
+
+func arDescription(n *arNode) string {
+	if d := n.value("DESC", "L-2"); d != "" {
+		return d
+	}
+	return n.value("LONG-NAME", "L-4")
+}
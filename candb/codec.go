@@ -0,0 +1,269 @@
+package candb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Value is the decoded value of a signal.
+type Value struct {
+	Name     string  `json:"name"`
+	Raw      float64 `json:"raw"`
+	Physical float64 `json:"value"`
+	Unit     string  `json:"unit"`
+	Label    string  `json:"label,omitempty"`
+}
+
+// Decode decodes every signal of m from the payload. Signals that do not
+// fit into the payload or that the multiplexer value excludes are skipped.
+// The signals of the PDUs of a container are named "PDU.Signal".
+func (m *Message) Decode(data []byte) []Value {
+	if m.ContainerHeader != ContainerHeaderNone {
+		return m.decodeContained(data)
+	}
+	values := make([]Value, 0, len(m.Signals))
+	for _, s := range m.Signals {
+		if !m.Present(s, data) {
+			continue
+		}
+		v, ok := s.Decode(data)
+		if !ok {
+			continue
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// decodeContained decodes the PDUs of a container until the payload ends or a
+// header of zeros (padding). The headers are big endian.
+func (m *Message) decodeContained(data []byte) []Value {
+	size := 4
+	if m.ContainerHeader == ContainerHeaderLong {
+		size = 8
+	}
+	var values []Value
+	for len(data) >= size {
+		var id, length uint32
+		if size == 4 {
+			id = uint32(data[0])<<16 | uint32(data[1])<<8 | uint32(data[2])
+			length = uint32(data[3])
+		} else {
+			id = binary.BigEndian.Uint32(data)
+			length = binary.BigEndian.Uint32(data[4:])
+		}
+		data = data[size:]
+		if id == 0 && length == 0 || uint64(length) > uint64(len(data)) {
+			break
+		}
+		for _, pdu := range m.Contained {
+			if pdu.ID == id {
+				for _, v := range pdu.Decode(data[:length]) {
+					v.Name = pdu.Name + "." + v.Name
+					values = append(values, v)
+				}
+				break
+			}
+		}
+		data = data[length:]
+	}
+	return values
+}
+
+// Decode decodes the signal from the payload. It reports false if the
+// signal does not fit into data.
+func (s *Signal) Decode(data []byte) (Value, bool) {
+	bits, ok := s.UnpackBits(data)
+	if !ok {
+		return Value{}, false
+	}
+	v := Value{Name: s.Name, Unit: s.Unit}
+	switch s.ValueType {
+	case ValueTypeFloat32:
+		v.Raw = float64(math.Float32frombits(uint32(bits)))
+	case ValueTypeFloat64:
+		v.Raw = math.Float64frombits(bits)
+	default:
+		if s.IsSigned {
+			raw := signExtend(bits, s.Length)
+			v.Raw = float64(raw)
+			v.Label, _ = s.ValueDescription(raw)
+		} else {
+			v.Raw = float64(bits)
+			v.Label, _ = s.ValueDescription(int64(bits))
+		}
+	}
+	v.Physical = s.ToPhysical(v.Raw)
+	return v, true
+}
+
+// ToPhysical converts a raw value to its physical value.
+func (s *Signal) ToPhysical(raw float64) float64 {
+	scale := s.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	return raw*scale + s.Offset
+}
+
+// UnpackBits extracts the raw bits of the signal from the payload.
+func (s *Signal) UnpackBits(data []byte) (uint64, bool) {
+	if s.Length <= 0 || s.Length > 64 {
+		return 0, false
+	}
+	var v uint64
+	if !s.IsBigEndian {
+		for i := 0; i < s.Length; i++ {
+			pos := s.Start + i
+			if pos/8 >= len(data) {
+				return 0, false
+			}
+			if data[pos/8]>>(pos%8)&1 != 0 {
+				v |= 1 << i
+			}
+		}
+		return v, true
+	}
+	pos := s.Start
+	for i := 0; i < s.Length; i++ {
+		if pos < 0 || pos/8 >= len(data) {
+			return 0, false
+		}
+		v = v<<1 | uint64(data[pos/8]>>(pos%8)&1)
+		pos = nextBigEndianBit(pos)
+	}
+	return v, true
+}
+
+// Encode writes the physical values of the named signals into data, which holds
+// at least m.Length bytes. The bits of the other signals are left as they are.
+// Multiplexed signals need a multiplexer value they are present for.
+func (m *Message) Encode(data []byte, values map[string]float64) error {
+	if len(data) < m.Length {
+		return fmt.Errorf("%s: payload of %d bytes, want %d", m.Name, len(data), m.Length)
+	}
+	for name, v := range values {
+		s, ok := m.Signal(name)
+		if !ok {
+			return fmt.Errorf("%s has no signal %s", m.Name, name)
+		}
+		if err := s.Encode(data, v); err != nil {
+			return err
+		}
+	}
+	for name := range values {
+		if s, _ := m.Signal(name); !m.Present(s, data) {
+			return fmt.Errorf("%s: %s is not present for the value of its multiplexer %s", m.Name, name, s.MultiplexerSwitch)
+		}
+	}
+	return nil
+}
+
+// Encode writes the physical value v of the signal into data.
+func (s *Signal) Encode(data []byte, v float64) error {
+	if s.Max > s.Min && (v < s.Min || v > s.Max) {
+		return fmt.Errorf("%s: %g is out of range [%g, %g]", s.Name, v, s.Min, s.Max)
+	}
+	bits, err := s.rawBits(s.FromPhysical(v))
+	if err != nil {
+		return fmt.Errorf("%s: %g: %w", s.Name, v, err)
+	}
+	if !s.PackBits(data, bits) {
+		return fmt.Errorf("%s does not fit in %d bytes", s.Name, len(data))
+	}
+	return nil
+}
+
+// FromPhysical converts a physical value to its raw value.
+func (s *Signal) FromPhysical(physical float64) float64 {
+	scale := s.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	return (physical - s.Offset) / scale
+}
+
+// rawBits returns the bits of a raw value, rounded to an integer for integer signals.
+func (s *Signal) rawBits(raw float64) (uint64, error) {
+	switch s.ValueType {
+	case ValueTypeFloat32:
+		return uint64(math.Float32bits(float32(raw))), nil
+	case ValueTypeFloat64:
+		return math.Float64bits(raw), nil
+	}
+	raw = math.Round(raw)
+	if s.IsSigned {
+		lo, hi := -math.Ldexp(1, s.Length-1), math.Ldexp(1, s.Length-1)-1
+		if raw < lo || raw > hi {
+			return 0, fmt.Errorf("raw value %g does not fit in %d signed bits", raw, s.Length)
+		}
+		return uint64(int64(raw)) & lengthMask(s.Length), nil
+	}
+	if raw < 0 || raw > math.Ldexp(1, s.Length)-1 {
+		return 0, fmt.Errorf("raw value %g does not fit in %d bits", raw, s.Length)
+	}
+	return uint64(raw), nil
+}
+
+// PackBits writes the raw bits of the signal into the payload, the inverse of
+// UnpackBits. It reports false if the signal does not fit into data.
+func (s *Signal) PackBits(data []byte, v uint64) bool {
+	if s.Length <= 0 || s.Length > 64 {
+		return false
+	}
+	if !s.IsBigEndian {
+		if (s.Start+s.Length-1)/8 >= len(data) {
+			return false
+		}
+		for i := 0; i < s.Length; i++ {
+			setBit(data, s.Start+i, v>>i&1 != 0)
+		}
+		return true
+	}
+	positions := make([]int, s.Length)
+	pos := s.Start
+	for i := range positions {
+		if pos < 0 || pos/8 >= len(data) {
+			return false
+		}
+		positions[i] = pos
+		pos = nextBigEndianBit(pos)
+	}
+	for i, pos := range positions {
+		setBit(data, pos, v>>(s.Length-1-i)&1 != 0)
+	}
+	return true
+}
+
+func setBit(data []byte, pos int, set bool) {
+	if set {
+		data[pos/8] |= 1 << (pos % 8)
+	} else {
+		data[pos/8] &^= 1 << (pos % 8)
+	}
+}
+
+func lengthMask(length int) uint64 {
+	if length >= 64 {
+		return math.MaxUint64
+	}
+	return 1<<length - 1
+}
+
+// nextBigEndianBit returns the next less significant bit position using
+// the DBC saw-tooth numbering for Motorola signals.
+func nextBigEndianBit(pos int) int {
+	if pos%8 == 0 {
+		return pos + 15
+	}
+	return pos - 1
+}
+
+func signExtend(bits uint64, length int) int64 {
+	if length >= 64 {
+		return int64(bits)
+	}
+	shift := 64 - uint(length)
+	return int64(bits<<shift) >> shift
+}
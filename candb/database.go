@@ -0,0 +1,171 @@
+// Package candb is the signal database used to decode and encode CAN messages.
+//
+// A Database is loaded from a description file (see Load) and maps CAN IDs
+// to messages and their signals.
+package candb
+
+import "time"
+
+// ValueType is the representation of a signal's raw value.
+type ValueType int
+
+const (
+	// ValueTypeInteger is a signed or unsigned integer.
+	ValueTypeInteger ValueType = iota
+	// ValueTypeFloat32 is an IEEE 754 single precision float.
+	ValueTypeFloat32
+	// ValueTypeFloat64 is an IEEE 754 double precision float.
+	ValueTypeFloat64
+)
+
+// ContainerHeader is the header layout of the PDUs of an AUTOSAR container PDU.
+type ContainerHeader int
+
+const (
+	// ContainerHeaderNone is a message that is not a container.
+	ContainerHeaderNone ContainerHeader = iota
+	// ContainerHeaderShort is a 3-byte header ID and a 1-byte length.
+	ContainerHeaderShort
+	// ContainerHeaderLong is a 4-byte header ID and a 4-byte length.
+	ContainerHeaderLong
+)
+
+// Database is a collection of message definitions.
+type Database struct {
+	// SourceFile is the path the database was loaded from.
+	SourceFile string
+	// Version is the version string of the database, if any.
+	Version string
+	// Nodes are the names of the network nodes.
+	Nodes []string
+	// Messages are the message definitions sorted by ID.
+	Messages []*Message
+
+	index map[uint32]*Message
+}
+
+// Message describes a CAN message.
+type Message struct {
+	// Name of the message.
+	Name string
+	// ID is the CAN ID of the message.
+	ID uint32
+	// IsExtended is true if ID is a 29-bit ID.
+	IsExtended bool
+	// Length is the payload length in bytes.
+	Length int
+	// Sender is the name of the transmitting node.
+	Sender string
+	// Description of the message.
+	Description string
+	// CycleTime is the nominal transmission period, zero for event messages.
+	CycleTime time.Duration
+	// Signals of the message.
+	Signals []*Signal
+	// ContainerHeader is set for an AUTOSAR container PDU: its payload is a
+	// sequence of the Contained PDUs, each with a header giving its header ID
+	// (the ID of the contained message) and its length.
+	ContainerHeader ContainerHeader
+	Contained       []*Message
+}
+
+// Signal describes a signal within a message.
+type Signal struct {
+	// Name of the signal.
+	Name string
+	// Start bit. For little-endian signals the position of the least significant bit,
+	// for big-endian signals the position of the most significant bit (saw-tooth numbering).
+	Start int
+	// Length in bits.
+	Length int
+	// IsBigEndian is true if the signal's byte order is Motorola.
+	IsBigEndian bool
+	// IsSigned is true if the raw value is two's complement.
+	IsSigned bool
+	// ValueType is the representation of the raw value.
+	ValueType ValueType
+	// Scale for the raw to physical transform.
+	Scale float64
+	// Offset for the raw to physical transform.
+	Offset float64
+	// Min physical value.
+	Min float64
+	// Max physical value.
+	Max float64
+	// Unit of the physical value.
+	Unit string
+	// Description of the signal.
+	Description string
+	// Receivers are the names of the nodes receiving the signal.
+	Receivers []string
+	// ValueDescriptions map raw values to labels.
+	ValueDescriptions []ValueDescription
+	// IsMultiplexer is true if the signal is the multiplexer switch of its message.
+	IsMultiplexer bool
+	// IsMultiplexed is true if the signal is only present for some multiplexer values.
+	// A signal can be both a multiplexer and multiplexed (extended multiplexing).
+	IsMultiplexed bool
+	// MultiplexerValue is the multiplexer value the signal is present for.
+	MultiplexerValue uint64
+	// MultiplexerSwitch is the name of the multiplexer of a multiplexed signal and
+	// MultiplexerRanges the multiplexer values it is present for: MultiplexerValue
+	// unless extended multiplexing (SG_MUL_VAL_) gives others.
+	MultiplexerSwitch string
+	MultiplexerRanges []MultiplexerRange
+}
+
+// ValueDescription is a label for a raw signal value.
+type ValueDescription struct {
+	Value       int64
+	Description string
+}
+
+// Message returns the message with the given CAN ID.
+func (db *Database) Message(id uint32, extended bool) (*Message, bool) {
+	m, ok := db.index[indexKey(id, extended)]
+	return m, ok
+}
+
+// MessageByName returns the message with the given name.
+func (db *Database) MessageByName(name string) (*Message, bool) {
+	for _, m := range db.Messages {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// Signal returns the signal of m with the given name.
+func (m *Message) Signal(name string) (*Signal, bool) {
+	for _, s := range m.Signals {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// ValueDescription returns the label for the provided raw value.
+func (s *Signal) ValueDescription(value int64) (string, bool) {
+	for _, vd := range s.ValueDescriptions {
+		if vd.Value == value {
+			return vd.Description, true
+		}
+	}
+	return "", false
+}
+
+func (db *Database) reindex() {
+	db.index = make(map[uint32]*Message, len(db.Messages))
+	for _, m := range db.Messages {
+		db.index[indexKey(m.ID, m.IsExtended)] = m
+	}
+}
+
+func indexKey(id uint32, extended bool) uint32 {
+	if extended {
+		return id | 0x80000000
+	}
+	return id
+}
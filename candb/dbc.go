@@ -0,0 +1,159 @@
+package candb
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.einride.tech/can/pkg/dbc"
+)
+
+// LoadDBC reads and parses a DBC file.
+func LoadDBC(path string) (*Database, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDBC(path, data)
+}
+
+// ParseDBC parses DBC source. filename is only used for error positions.
+func ParseDBC(filename string, data []byte) (*Database, error) {
+	data, mux := parseExtendedMux(data)
+	p := dbc.NewParser(filename, data)
+	if err := p.Parse(); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filename, err)
+	}
+	db := &Database{SourceFile: filename}
+	defs := p.Defs()
+	for _, def := range defs {
+		switch def := def.(type) {
+		case *dbc.VersionDef:
+			db.Version = def.Version
+		case *dbc.NodesDef:
+			for _, node := range def.NodeNames {
+				db.Nodes = append(db.Nodes, string(node))
+			}
+		case *dbc.MessageDef:
+			if def.MessageID == dbc.IndependentSignalsMessageID {
+				continue
+			}
+			db.Messages = append(db.Messages, messageFromDef(def))
+		}
+	}
+	db.reindex()
+	for _, def := range defs {
+		applyMetadata(db, def)
+	}
+	applyMultiplexing(db, mux)
+	sortDatabase(db)
+	return db, nil
+}
+
+func messageFromDef(def *dbc.MessageDef) *Message {
+	m := &Message{
+		Name:       string(def.Name),
+		ID:         def.MessageID.ToCAN(),
+		IsExtended: def.MessageID.IsExtended(),
+		Length:     int(def.Size),
+		Sender:     string(def.Transmitter),
+	}
+	if m.Sender == noNode {
+		m.Sender = ""
+	}
+	for _, sd := range def.Signals {
+		s := &Signal{
+			Name:             string(sd.Name),
+			Start:            int(sd.StartBit),
+			Length:           int(sd.Size),
+			IsBigEndian:      sd.IsBigEndian,
+			IsSigned:         sd.IsSigned,
+			Scale:            sd.Factor,
+			Offset:           sd.Offset,
+			Min:              sd.Minimum,
+			Max:              sd.Maximum,
+			Unit:             sd.Unit,
+			IsMultiplexer:    sd.IsMultiplexerSwitch,
+			IsMultiplexed:    sd.IsMultiplexed,
+			MultiplexerValue: sd.MultiplexerSwitch,
+		}
+		for _, r := range sd.Receivers {
+			if r != noNode {
+				s.Receivers = append(s.Receivers, string(r))
+			}
+		}
+		m.Signals = append(m.Signals, s)
+	}
+	return m
+}
+
+func (db *Database) signal(id dbc.MessageID, name dbc.Identifier) (*Signal, bool) {
+	m, ok := db.Message(id.ToCAN(), id.IsExtended())
+	if !ok {
+		return nil, false
+	}
+	return m.Signal(string(name))
+}
+
+func applyMetadata(db *Database, def dbc.Def) {
+	switch def := def.(type) {
+	case *dbc.SignalValueTypeDef:
+		if s, ok := db.signal(def.MessageID, def.SignalName); ok {
+			switch def.SignalValueType {
+			case dbc.SignalValueTypeFloat32:
+				s.ValueType = ValueTypeFloat32
+			case dbc.SignalValueTypeFloat64:
+				s.ValueType = ValueTypeFloat64
+			}
+		}
+	case *dbc.CommentDef:
+		switch def.ObjectType {
+		case dbc.ObjectTypeMessage:
+			if m, ok := db.Message(def.MessageID.ToCAN(), def.MessageID.IsExtended()); ok {
+				m.Description = unescape(def.Comment)
+			}
+		case dbc.ObjectTypeSignal:
+			if s, ok := db.signal(def.MessageID, def.SignalName); ok {
+				s.Description = unescape(def.Comment)
+			}
+		}
+	case *dbc.ValueDescriptionsDef:
+		if def.ObjectType != dbc.ObjectTypeSignal {
+			return
+		}
+		if s, ok := db.signal(def.MessageID, def.SignalName); ok {
+			for _, vd := range def.ValueDescriptions {
+				s.ValueDescriptions = append(s.ValueDescriptions, ValueDescription{
+					Value:       int64(vd.Value),
+					Description: unescape(vd.Description),
+				})
+			}
+		}
+	case *dbc.AttributeValueForObjectDef:
+		if def.ObjectType == dbc.ObjectTypeMessage && def.AttributeName == "GenMsgCycleTime" {
+			if m, ok := db.Message(def.MessageID.ToCAN(), def.MessageID.IsExtended()); ok {
+				m.CycleTime = time.Duration(def.IntValue) * time.Millisecond
+			}
+		}
+	}
+}
+
+// unescape removes the escapes of the quotes of a string, which the DBC parser keeps.
+func unescape(s string) string {
+	return strings.ReplaceAll(s, `\"`, `"`)
+}
+
+func sortDatabase(db *Database) {
+	sort.Slice(db.Messages, func(i, j int) bool {
+		return db.Messages[i].ID < db.Messages[j].ID
+	})
+	for _, m := range db.Messages {
+		for _, s := range m.Signals {
+			sort.Slice(s.ValueDescriptions, func(i, j int) bool {
+				return s.ValueDescriptions[i].Value < s.ValueDescriptions[j].Value
+			})
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package candb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// noNode is the DBC placeholder of a missing sender or receiver.
+const noNode = "Vector__XXX"
+
+// SaveDBC writes db to path in the DBC format.
+func (db *Database) SaveDBC(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := db.WriteDBC(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteDBC writes db in the DBC format: the messages and signals with their
+// comments, value descriptions, cycle times (GenMsgCycleTime), float value types
+// and extended multiplexing, so ParseDBC reads back the same database. DBC has
+// no container PDUs: containers are written without their contained PDUs.
+func (db *Database) WriteDBC(w io.Writer) error {
+	for _, m := range db.Messages {
+		if err := m.Validate(); err != nil {
+			return err
+		}
+	}
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "VERSION %s\n\n", dbcString(db.Version))
+	b.WriteString("NS_ :\n\tNS_DESC_\n\tCM_\n\tBA_DEF_\n\tBA_\n\tVAL_\n\tBA_DEF_DEF_\n\tSIG_VALTYPE_\n\tSG_MUL_VAL_\n\n")
+	b.WriteString("BS_:\n\n")
+	b.WriteString("BU_:")
+	for _, n := range db.nodeNames() {
+		b.WriteString(" " + n)
+	}
+	b.WriteString("\n\n")
+
+	for _, m := range db.Messages {
+		sender := m.Sender
+		if sender == "" {
+			sender = noNode
+		}
+		fmt.Fprintf(b, "BO_ %d %s: %d %s\n", dbcID(m), m.Name, m.Length, sender)
+		for _, s := range m.Signals {
+			receivers := strings.Join(s.Receivers, ",")
+			if receivers == "" {
+				receivers = noNode
+			}
+			order, sign := "1", "+"
+			if s.IsBigEndian {
+				order = "0"
+			}
+			if s.IsSigned {
+				sign = "-"
+			}
+			fmt.Fprintf(b, " SG_ %s%s : %d|%d@%s%s (%s,%s) [%s|%s] %s %s\n", s.Name, muxIndicator(s),
+				s.Start, s.Length, order, sign, dbcFloat(scale(s)), dbcFloat(s.Offset),
+				dbcFloat(s.Min), dbcFloat(s.Max), dbcString(s.Unit), receivers)
+		}
+		b.WriteString("\n")
+	}
+
+	for _, m := range db.Messages {
+		if m.Description != "" {
+			fmt.Fprintf(b, "CM_ BO_ %d %s;\n", dbcID(m), dbcString(m.Description))
+		}
+		for _, s := range m.Signals {
+			if s.Description != "" {
+				fmt.Fprintf(b, "CM_ SG_ %d %s %s;\n", dbcID(m), s.Name, dbcString(s.Description))
+			}
+		}
+	}
+
+	cycles := false
+	for _, m := range db.Messages {
+		cycles = cycles || m.CycleTime > 0
+	}
+	if cycles {
+		b.WriteString("BA_DEF_ BO_  \"GenMsgCycleTime\" INT 0 65535;\n")
+		b.WriteString("BA_DEF_DEF_  \"GenMsgCycleTime\" 0;\n")
+		for _, m := range db.Messages {
+			if m.CycleTime > 0 {
+				fmt.Fprintf(b, "BA_ \"GenMsgCycleTime\" BO_ %d %d;\n", dbcID(m), m.CycleTime/time.Millisecond)
+			}
+		}
+	}
+
+	for _, m := range db.Messages {
+		for _, s := range m.Signals {
+			if len(s.ValueDescriptions) == 0 {
+				continue
+			}
+			fmt.Fprintf(b, "VAL_ %d %s", dbcID(m), s.Name)
+			for _, vd := range s.ValueDescriptions {
+				fmt.Fprintf(b, " %d %s", vd.Value, dbcString(vd.Description))
+			}
+			b.WriteString(" ;\n")
+		}
+	}
+	for _, m := range db.Messages {
+		for _, s := range m.Signals {
+			switch s.ValueType {
+			case ValueTypeFloat32:
+				fmt.Fprintf(b, "SIG_VALTYPE_ %d %s : 1;\n", dbcID(m), s.Name)
+			case ValueTypeFloat64:
+				fmt.Fprintf(b, "SIG_VALTYPE_ %d %s : 2;\n", dbcID(m), s.Name)
+			}
+		}
+	}
+	for _, m := range db.Messages {
+		if !extendedMultiplexing(m) {
+			continue
+		}
+		for _, s := range m.Signals {
+			if !s.IsMultiplexed {
+				continue
+			}
+			ranges := make([]string, len(s.MultiplexerRanges))
+			for i, r := range s.MultiplexerRanges {
+				ranges[i] = fmt.Sprintf("%d-%d", r.Min, r.Max)
+			}
+			fmt.Fprintf(b, "SG_MUL_VAL_ %d %s %s %s;\n", dbcID(m), s.Name, s.MultiplexerSwitch, strings.Join(ranges, ", "))
+		}
+	}
+	return b.Flush()
+}
+
+// nodeNames returns the nodes of db and the senders and receivers it references.
+func (db *Database) nodeNames() []string {
+	seen := make(map[string]bool)
+	var nodes []string
+	add := func(n string) {
+		if n != "" && n != noNode && !seen[n] {
+			seen[n] = true
+			nodes = append(nodes, n)
+		}
+	}
+	for _, n := range db.Nodes {
+		add(n)
+	}
+	var extra []string
+	for _, m := range db.Messages {
+		extra = append(extra, m.Sender)
+		for _, s := range m.Signals {
+			extra = append(extra, s.Receivers...)
+		}
+	}
+	sort.Strings(extra)
+	for _, n := range extra {
+		add(n)
+	}
+	return nodes
+}
+
+// extendedMultiplexing reports whether m needs SG_MUL_VAL_ entries: its signals
+// have several switches or several values.
+func extendedMultiplexing(m *Message) bool {
+	for _, s := range m.Signals {
+		if !s.IsMultiplexed {
+			continue
+		}
+		if s.IsMultiplexer || len(s.MultiplexerRanges) != 1 ||
+			s.MultiplexerRanges[0] != (MultiplexerRange{Min: s.MultiplexerValue, Max: s.MultiplexerValue}) {
+			return true
+		}
+		if sw, ok := m.Signal(s.MultiplexerSwitch); ok && sw.IsMultiplexed {
+			return true
+		}
+	}
+	return false
+}
+
+func muxIndicator(s *Signal) string {
+	switch {
+	case s.IsMultiplexed && s.IsMultiplexer:
+		return fmt.Sprintf(" m%dM", s.MultiplexerValue)
+	case s.IsMultiplexed:
+		return fmt.Sprintf(" m%d", s.MultiplexerValue)
+	case s.IsMultiplexer:
+		return " M"
+	}
+	return ""
+}
+
+func dbcID(m *Message) uint32 {
+	if m.IsExtended {
+		return m.ID | 0x80000000
+	}
+	return m.ID
+}
+
+func scale(s *Signal) float64 {
+	if s.Scale == 0 {
+		return 1
+	}
+	return s.Scale
+}
+
+func dbcFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func dbcString(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
@@ -0,0 +1,237 @@
+package candb
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// identifier matches the names DBC files accept for messages, signals and nodes.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewDatabase returns an empty database to be saved to sourceFile.
+func NewDatabase(sourceFile string) *Database {
+	db := &Database{SourceFile: sourceFile}
+	db.reindex()
+	return db
+}
+
+// Clone returns a deep copy of db, which can be edited while db is in use.
+func (db *Database) Clone() *Database {
+	c := &Database{
+		SourceFile: db.SourceFile,
+		Version:    db.Version,
+		Nodes:      append([]string(nil), db.Nodes...),
+		Messages:   make([]*Message, len(db.Messages)),
+	}
+	for i, m := range db.Messages {
+		c.Messages[i] = m.Clone()
+	}
+	c.reindex()
+	return c
+}
+
+// Clone returns a deep copy of m.
+func (m *Message) Clone() *Message {
+	c := *m
+	c.Signals = make([]*Signal, len(m.Signals))
+	for i, s := range m.Signals {
+		c.Signals[i] = s.Clone()
+	}
+	if m.Contained != nil {
+		c.Contained = make([]*Message, len(m.Contained))
+		for i, cm := range m.Contained {
+			c.Contained[i] = cm.Clone()
+		}
+	}
+	return &c
+}
+
+// Clone returns a deep copy of s.
+func (s *Signal) Clone() *Signal {
+	c := *s
+	c.Receivers = append([]string(nil), s.Receivers...)
+	c.ValueDescriptions = append([]ValueDescription(nil), s.ValueDescriptions...)
+	c.MultiplexerRanges = append([]MultiplexerRange(nil), s.MultiplexerRanges...)
+	return &c
+}
+
+// SetMessage adds m to the database, or replaces the message named old when old
+// is not empty. m is validated first and must not reuse the name or ID of
+// another message.
+func (db *Database) SetMessage(old string, m *Message) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	i := -1
+	if old != "" {
+		if i = db.messageIndex(old); i < 0 {
+			return fmt.Errorf("no message %s", old)
+		}
+	}
+	for j, other := range db.Messages {
+		if j == i {
+			continue
+		}
+		if other.Name == m.Name {
+			return fmt.Errorf("message %s already exists", m.Name)
+		}
+		if other.ID == m.ID && other.IsExtended == m.IsExtended {
+			return fmt.Errorf("ID 0x%X is already used by %s", m.ID, other.Name)
+		}
+	}
+	if i < 0 {
+		db.Messages = append(db.Messages, m)
+	} else {
+		db.Messages[i] = m
+	}
+	db.reindex()
+	sortDatabase(db)
+	return nil
+}
+
+// RemoveMessage deletes the message with the given name.
+func (db *Database) RemoveMessage(name string) error {
+	i := db.messageIndex(name)
+	if i < 0 {
+		return fmt.Errorf("no message %s", name)
+	}
+	db.Messages = append(db.Messages[:i], db.Messages[i+1:]...)
+	db.reindex()
+	return nil
+}
+
+func (db *Database) messageIndex(name string) int {
+	for i, m := range db.Messages {
+		if m.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetSignal adds s to m, or replaces the signal named old when old is not empty.
+// The message is validated by Database.SetMessage.
+func (m *Message) SetSignal(old string, s *Signal) error {
+	i := -1
+	if old != "" {
+		if i = m.signalIndex(old); i < 0 {
+			return fmt.Errorf("%s has no signal %s", m.Name, old)
+		}
+	}
+	if j := m.signalIndex(s.Name); j >= 0 && j != i {
+		return fmt.Errorf("%s already has a signal %s", m.Name, s.Name)
+	}
+	if i < 0 {
+		m.Signals = append(m.Signals, s)
+	} else {
+		m.Signals[i] = s
+	}
+	return nil
+}
+
+// RemoveSignal deletes the signal of m with the given name.
+func (m *Message) RemoveSignal(name string) error {
+	i := m.signalIndex(name)
+	if i < 0 {
+		return fmt.Errorf("%s has no signal %s", m.Name, name)
+	}
+	m.Signals = append(m.Signals[:i], m.Signals[i+1:]...)
+	return nil
+}
+
+func (m *Message) signalIndex(name string) int {
+	for i, s := range m.Signals {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Validate checks that m can be saved to a DBC file and decoded: valid names, an
+// ID of its format, signals within the payload. Multiplexed signals without a
+// switch get the multiplexer of the message and their MultiplexerValue.
+func (m *Message) Validate() error {
+	if !identifier.MatchString(m.Name) {
+		return fmt.Errorf("invalid message name %q", m.Name)
+	}
+	if m.IsExtended && m.ID > 0x1FFFFFFF || !m.IsExtended && m.ID > 0x7FF {
+		return fmt.Errorf("%s: ID 0x%X out of range", m.Name, m.ID)
+	}
+	if m.Length < 0 || m.Length > 64 {
+		return fmt.Errorf("%s: length %d out of range 0..64", m.Name, m.Length)
+	}
+	if m.Sender != "" && !identifier.MatchString(m.Sender) {
+		return fmt.Errorf("%s: invalid sender %q", m.Name, m.Sender)
+	}
+	if m.CycleTime < 0 {
+		return fmt.Errorf("%s: negative cycle time", m.Name)
+	}
+
+	var main *Signal
+	names := make(map[string]bool, len(m.Signals))
+	payload := make([]byte, m.Length)
+	for _, s := range m.Signals {
+		if err := s.validate(payload); err != nil {
+			return fmt.Errorf("%s: %w", m.Name, err)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("%s: duplicate signal %s", m.Name, s.Name)
+		}
+		names[s.Name] = true
+		if s.IsMultiplexer && !s.IsMultiplexed {
+			if main != nil {
+				return fmt.Errorf("%s: %s and %s are both the multiplexer", m.Name, main.Name, s.Name)
+			}
+			main = s
+		}
+	}
+	for _, s := range m.Signals {
+		if !s.IsMultiplexed {
+			s.MultiplexerSwitch, s.MultiplexerRanges = "", nil
+			continue
+		}
+		if s.MultiplexerSwitch == "" {
+			if main == nil {
+				return fmt.Errorf("%s: %s is multiplexed but the message has no multiplexer", m.Name, s.Name)
+			}
+			s.MultiplexerSwitch = main.Name
+		}
+		if len(s.MultiplexerRanges) == 0 {
+			s.MultiplexerRanges = []MultiplexerRange{{Min: s.MultiplexerValue, Max: s.MultiplexerValue}}
+		}
+		sw, ok := m.Signal(s.MultiplexerSwitch)
+		if !ok || !sw.IsMultiplexer || sw == s {
+			return fmt.Errorf("%s: %s is not a multiplexer of %s", m.Name, s.MultiplexerSwitch, s.Name)
+		}
+	}
+	return nil
+}
+
+func (s *Signal) validate(payload []byte) error {
+	if !identifier.MatchString(s.Name) {
+		return fmt.Errorf("invalid signal name %q", s.Name)
+	}
+	switch {
+	case s.Length < 1 || s.Length > 64:
+		return fmt.Errorf("%s: length %d out of range 1..64", s.Name, s.Length)
+	case s.ValueType == ValueTypeFloat32 && s.Length != 32:
+		return fmt.Errorf("%s: float32 signals are 32 bits", s.Name)
+	case s.ValueType == ValueTypeFloat64 && s.Length != 64:
+		return fmt.Errorf("%s: float64 signals are 64 bits", s.Name)
+	case s.Start < 0 || !s.PackBits(payload, 0):
+		return fmt.Errorf("%s: bits %d..%d do not fit in %d bytes", s.Name, s.Start, s.Start+s.Length-1, len(payload))
+	case s.Min > s.Max:
+		return fmt.Errorf("%s: minimum %g above maximum %g", s.Name, s.Min, s.Max)
+	}
+	for _, r := range s.Receivers {
+		if !identifier.MatchString(r) {
+			return fmt.Errorf("%s: invalid receiver %q", s.Name, r)
+		}
+	}
+	if s.IsMultiplexer && s.ValueType != ValueTypeInteger {
+		return errors.New(s.Name + ": a multiplexer must be an integer")
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package candb
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadKCD reads and parses a Kayak .kcd file.
+func LoadKCD(path string) (*Database, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKCD(path, data)
+}
+
+type kcdNetwork struct {
+	Document struct {
+		Name    string `xml:"name,attr"`
+		Version string `xml:"version,attr"`
+	} `xml:"Document"`
+	Nodes []kcdNode `xml:"Node"`
+	Buses []kcdBus  `xml:"Bus"`
+}
+
+type kcdNode struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+}
+
+type kcdBus struct {
+	Name     string       `xml:"name,attr"`
+	Messages []kcdMessage `xml:"Message"`
+}
+
+type kcdMessage struct {
+	ID        string         `xml:"id,attr"`
+	Name      string         `xml:"name,attr"`
+	Length    string         `xml:"length,attr"`
+	Interval  string         `xml:"interval,attr"`
+	Format    string         `xml:"format,attr"`
+	Notes     string         `xml:"Notes"`
+	Producers []kcdNodeRef   `xml:"Producer>NodeRef"`
+	Signals   []kcdSignal    `xml:"Signal"`
+	Multiplex []kcdMultiplex `xml:"Multiplex"`
+}
+
+type kcdNodeRef struct {
+	ID string `xml:"id,attr"`
+}
+
+type kcdSignal struct {
+	Name      string       `xml:"name,attr"`
+	Offset    int          `xml:"offset,attr"`
+	Length    string       `xml:"length,attr"`
+	Endianess string       `xml:"endianess,attr"`
+	Notes     string       `xml:"Notes"`
+	Consumers []kcdNodeRef `xml:"Consumer>NodeRef"`
+	Value     *kcdValue    `xml:"Value"`
+	Labels    []kcdLabel   `xml:"LabelSet>Label"`
+}
+
+type kcdValue struct {
+	Type      string `xml:"type,attr"`
+	Slope     string `xml:"slope,attr"`
+	Intercept string `xml:"intercept,attr"`
+	Unit      string `xml:"unit,attr"`
+	Min       string `xml:"min,attr"`
+	Max       string `xml:"max,attr"`
+}
+
+type kcdLabel struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type kcdMultiplex struct {
+	kcdSignal
+	Groups []kcdMuxGroup `xml:"MuxGroup"`
+}
+
+type kcdMuxGroup struct {
+	Count   uint64      `xml:"count,attr"`
+	Signals []kcdSignal `xml:"Signal"`
+}
+
+// ParseKCD parses the source of a Kayak .kcd file. The messages of all its buses
+// are merged, the first definition of an ID wins. filename is only used for
+// error positions.
+func ParseKCD(filename string, data []byte) (*Database, error) {
+	var net kcdNetwork
+	if err := xml.Unmarshal(data, &net); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filename, err)
+	}
+	db := &Database{SourceFile: filename, Version: net.Document.Version}
+	nodes := make(map[string]string, len(net.Nodes))
+	for _, n := range net.Nodes {
+		nodes[n.ID] = n.Name
+		db.Nodes = append(db.Nodes, n.Name)
+	}
+	db.reindex()
+	for _, bus := range net.Buses {
+		for _, km := range bus.Messages {
+			m, err := kcdMessageDef(km, nodes)
+			if err != nil {
+				return nil, fmt.Errorf("parse %s: %w", filename, err)
+			}
+			if _, dup := db.Message(m.ID, m.IsExtended); dup {
+				continue
+			}
+			db.Messages = append(db.Messages, m)
+			db.index[indexKey(m.ID, m.IsExtended)] = m
+		}
+	}
+	sortDatabase(db)
+	return db, nil
+}
+
+func kcdMessageDef(km kcdMessage, nodes map[string]string) (*Message, error) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(km.ID), "0x"), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("message %s: invalid id %q", km.Name, km.ID)
+	}
+	m := &Message{
+		Name:        km.Name,
+		ID:          uint32(id),
+		IsExtended:  km.Format == "extended",
+		Description: strings.TrimSpace(km.Notes),
+	}
+	if len(km.Producers) > 0 {
+		m.Sender = nodes[km.Producers[0].ID]
+	}
+	if ms, err := strconv.Atoi(km.Interval); err == nil && ms > 0 {
+		m.CycleTime = time.Duration(ms) * time.Millisecond
+	}
+
+	add := func(ks kcdSignal, sw *Signal, value uint64) error {
+		s, err := kcdSignalDef(ks, nodes)
+		if err != nil {
+			return fmt.Errorf("message %s: %w", km.Name, err)
+		}
+		return addSignal(m, s, sw, value)
+	}
+	for _, ks := range km.Signals {
+		if err := add(ks, nil, 0); err != nil {
+			return nil, err
+		}
+	}
+	for _, kx := range km.Multiplex {
+		sw, err := kcdSignalDef(kx.kcdSignal, nodes)
+		if err != nil {
+			return nil, fmt.Errorf("message %s: %w", km.Name, err)
+		}
+		if len(km.Multiplex) > 1 {
+			return nil, fmt.Errorf("message %s: several multiplexers", km.Name)
+		}
+		sw.IsMultiplexer = true
+		m.Signals = append(m.Signals, sw)
+		for _, g := range kx.Groups {
+			for _, ks := range g.Signals {
+				if err := add(ks, sw, g.Count); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	switch km.Length {
+	case "", "auto":
+		m.Length = payloadLength(m)
+	default:
+		if m.Length, err = strconv.Atoi(km.Length); err != nil || m.Length < 0 || m.Length > 64 {
+			return nil, fmt.Errorf("message %s: invalid length %q", km.Name, km.Length)
+		}
+	}
+	return m, nil
+}
+
+func kcdSignalDef(ks kcdSignal, nodes map[string]string) (*Signal, error) {
+	s := &Signal{
+		Name:        ks.Name,
+		Length:      1,
+		IsBigEndian: ks.Endianess == "big",
+		Scale:       1,
+		Description: strings.TrimSpace(ks.Notes),
+	}
+	if ks.Length != "" {
+		n, err := strconv.Atoi(ks.Length)
+		if err != nil {
+			return nil, fmt.Errorf("signal %s: invalid length %q", ks.Name, ks.Length)
+		}
+		s.Length = n
+	}
+	s.Start = symStart(ks.Offset, s.IsBigEndian)
+	for _, c := range ks.Consumers {
+		if n := nodes[c.ID]; n != "" {
+			s.Receivers = append(s.Receivers, n)
+		}
+	}
+	if v := ks.Value; v != nil {
+		switch v.Type {
+		case "", "unsigned":
+		case "signed":
+			s.IsSigned = true
+		case "single":
+			s.ValueType = ValueTypeFloat32
+		case "double":
+			s.ValueType = ValueTypeFloat64
+		default:
+			return nil, fmt.Errorf("signal %s: unknown type %q", ks.Name, v.Type)
+		}
+		s.Unit = v.Unit
+		for _, f := range []struct {
+			attr string
+			dst  *float64
+		}{{v.Slope, &s.Scale}, {v.Intercept, &s.Offset}, {v.Min, &s.Min}, {v.Max, &s.Max}} {
+			if f.attr == "" {
+				continue
+			}
+			x, err := strconv.ParseFloat(f.attr, 64)
+			if err != nil {
+				return nil, fmt.Errorf("signal %s: invalid number %q", ks.Name, f.attr)
+			}
+			*f.dst = x
+		}
+		if v.Min == "" || v.Max == "" {
+			// one bound alone does not limit the encoded values
+			s.Min, s.Max = 0, 0
+		}
+	}
+	for _, l := range ks.Labels {
+		n, err := strconv.ParseInt(l.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("signal %s: invalid label value %q", ks.Name, l.Value)
+		}
+		s.ValueDescriptions = append(s.ValueDescriptions, ValueDescription{Value: n, Description: l.Name})
+	}
+	return s, nil
+}
@@ -0,0 +1,57 @@
+package candb
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Load reads the database file at path in the format of its extension: DBC
+// (.dbc), PCAN Symbol Editor (.sym), Kayak (.kcd) or AUTOSAR (.arxml).
+func Load(path string) (*Database, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".dbc":
+		return LoadDBC(path)
+	case ".sym":
+		return LoadSYM(path)
+	case ".kcd":
+		return LoadKCD(path)
+	case ".arxml":
+		return LoadARXML(path)
+	}
+	return nil, fmt.Errorf("%s: unknown database format, expected .dbc, .sym, .kcd or .arxml", path)
+}
+
+// addSignal appends s to m, multiplexed by the switch sw at value when sw is not
+// nil. A multiplexed signal repeated at the same position for another value of
+// sw is present for both.
+func addSignal(m *Message, s *Signal, sw *Signal, value uint64) error {
+	if sw != nil {
+		s.IsMultiplexed, s.MultiplexerValue = true, value
+		s.MultiplexerSwitch = sw.Name
+		s.MultiplexerRanges = []MultiplexerRange{{Min: value, Max: value}}
+	}
+	if prev, dup := m.Signal(s.Name); dup {
+		if !s.IsMultiplexed || !prev.IsMultiplexed || prev.Start != s.Start || prev.Length != s.Length {
+			return fmt.Errorf("%s: duplicate signal %s", m.Name, s.Name)
+		}
+		prev.MultiplexerRanges = append(prev.MultiplexerRanges, s.MultiplexerRanges...)
+		return nil
+	}
+	m.Signals = append(m.Signals, s)
+	return nil
+}
+
+// payloadLength returns the bytes the signals of a message without a length cover.
+func payloadLength(m *Message) int {
+	n := 0
+	for _, s := range m.Signals {
+		for l := n; l <= 64; l++ {
+			if s.PackBits(make([]byte, l), 0) {
+				n = l
+				break
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,139 @@
+package candb
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.einride.tech/can/pkg/dbc"
+)
+
+// MultiplexerRange is a range of multiplexer switch values, bounds included.
+type MultiplexerRange struct {
+	Min uint64
+	Max uint64
+}
+
+// Present reports whether the signal s of m is present in data: signals that are not
+// multiplexed always are, multiplexed signals when the value of their multiplexer
+// switch is within their ranges and the switch itself is present.
+func (m *Message) Present(s *Signal, data []byte) bool {
+	// a switch chain longer than the signals has a cycle
+	for range m.Signals {
+		if !s.IsMultiplexed {
+			return true
+		}
+		sw, ok := m.Signal(s.MultiplexerSwitch)
+		if !ok {
+			return false
+		}
+		v, ok := sw.UnpackBits(data)
+		if !ok || !s.multiplexedBy(v) {
+			return false
+		}
+		s = sw
+	}
+	return false
+}
+
+func (s *Signal) multiplexedBy(v uint64) bool {
+	for _, r := range s.MultiplexerRanges {
+		if v >= r.Min && v <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	messageLine = regexp.MustCompile(`^\s*BO_\s+(\d+)\s`)
+	// extendedMuxSignal matches the signals multiplexed by a multiplexer which are
+	// multiplexers themselves ("m3M"), the DBC parser only knows "m3" or "M".
+	extendedMuxSignal = regexp.MustCompile(`^(\s*SG_\s+\w+\s+m\d+)M(\s*:)`)
+	muxValueLine      = regexp.MustCompile(`^\s*SG_MUL_VAL_\s+(\d+)\s+(\w+)\s+(\w+)\s+([^;]*);`)
+	muxRange          = regexp.MustCompile(`^\s*(\d+)\s*-\s*(\d+)\s*$`)
+)
+
+// muxValues is an SG_MUL_VAL_ entry: the switch and switch values of a multiplexed signal.
+type muxValues struct {
+	id     dbc.MessageID
+	signal string
+	sw     string
+	ranges []MultiplexerRange
+}
+
+// extendedMux holds the extended multiplexing definitions the DBC parser does not
+// know: the multiplexed signals which are also switches and the SG_MUL_VAL_ entries.
+type extendedMux struct {
+	switches map[dbc.MessageID][]string
+	values   []muxValues
+}
+
+// parseExtendedMux collects the extended multiplexing definitions of a DBC source and
+// returns the source rewritten for the DBC parser, with the same lines.
+func parseExtendedMux(data []byte) ([]byte, extendedMux) {
+	mux := extendedMux{switches: make(map[dbc.MessageID][]string)}
+	lines := bytes.Split(data, []byte("\n"))
+	var id dbc.MessageID
+	for i, line := range lines {
+		if m := messageLine.FindSubmatch(line); m != nil {
+			n, _ := strconv.ParseUint(string(m[1]), 10, 32)
+			id = dbc.MessageID(n)
+			continue
+		}
+		if m := extendedMuxSignal.FindSubmatchIndex(line); m != nil {
+			name := strings.Fields(string(line[:m[3]]))[1]
+			mux.switches[id] = append(mux.switches[id], name)
+			lines[i] = append(append([]byte(nil), line[:m[3]]...), line[m[4]:]...)
+			continue
+		}
+		if m := muxValueLine.FindSubmatch(line); m != nil {
+			n, _ := strconv.ParseUint(string(m[1]), 10, 32)
+			v := muxValues{id: dbc.MessageID(n), signal: string(m[2]), sw: string(m[3])}
+			for _, r := range strings.Split(string(m[4]), ",") {
+				if rm := muxRange.FindStringSubmatch(r); rm != nil {
+					lo, _ := strconv.ParseUint(rm[1], 10, 64)
+					hi, _ := strconv.ParseUint(rm[2], 10, 64)
+					v.ranges = append(v.ranges, MultiplexerRange{Min: lo, Max: hi})
+				}
+			}
+			mux.values = append(mux.values, v)
+		}
+	}
+	return bytes.Join(lines, []byte("\n")), mux
+}
+
+// applyMultiplexing sets the switch and switch values of the multiplexed signals:
+// those of SG_MUL_VAL_ when given, otherwise the plain multiplexer ("M") of the
+// message and the value of the signal.
+func applyMultiplexing(db *Database, mux extendedMux) {
+	for id, names := range mux.switches {
+		for _, name := range names {
+			if s, ok := db.signal(id, dbc.Identifier(name)); ok {
+				s.IsMultiplexer = true
+			}
+		}
+	}
+	for _, m := range db.Messages {
+		var main *Signal
+		for _, s := range m.Signals {
+			if s.IsMultiplexer && !s.IsMultiplexed {
+				main = s
+				break
+			}
+		}
+		for _, s := range m.Signals {
+			if s.IsMultiplexed && main != nil {
+				s.MultiplexerSwitch = main.Name
+				s.MultiplexerRanges = []MultiplexerRange{{Min: s.MultiplexerValue, Max: s.MultiplexerValue}}
+			}
+		}
+	}
+	for _, v := range mux.values {
+		if s, ok := db.signal(v.id, dbc.Identifier(v.signal)); ok && s.IsMultiplexed {
+			s.MultiplexerSwitch = v.sw
+			s.MultiplexerRanges = v.ranges
+		}
+	}
+}
@@ -0,0 +1,431 @@
+package candb
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadSYM reads and parses a PCAN Symbol Editor .sym file.
+func LoadSYM(path string) (*Database, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSYM(path, data)
+}
+
+// symVar is a variable definition of a .sym file, a signal without its position.
+type symVar struct {
+	signal Signal
+	enum   string
+}
+
+type symParser struct {
+	filename string
+	line     int
+	enums    map[string][]ValueDescription
+	// signals are the definitions of the {SIGNALS} section, referenced by Sig= lines.
+	signals map[string]symVar
+	db      *Database
+	// byName are the messages by section name, as a message has a section per
+	// multiplexer value; muxes are their multiplexer switch.
+	byName map[string]*Message
+	muxes  map[*Message]*Signal
+}
+
+// ParseSYM parses the source of a PCAN Symbol Editor .sym file (format version 5
+// or 6). The sections of a message multiplexed with Mux= become a message with a
+// multiplexer switch named after its first Mux= line, whose values are labelled
+// with the names of the sections. filename is only used for error positions.
+func ParseSYM(filename string, data []byte) (*Database, error) {
+	p := &symParser{
+		filename: filename,
+		enums:    make(map[string][]ValueDescription),
+		signals:  make(map[string]symVar),
+		db:       &Database{SourceFile: filename},
+		byName:   make(map[string]*Message),
+		muxes:    make(map[*Message]*Signal),
+	}
+	// the enums and signals can be defined after the messages using them
+	if err := p.parse(data, true); err != nil {
+		return nil, err
+	}
+	if err := p.parse(data, false); err != nil {
+		return nil, err
+	}
+	p.db.reindex()
+	sortDatabase(p.db)
+	return p.db, nil
+}
+
+func (p *symParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("parse %s:%d: %s", p.filename, p.line, fmt.Sprintf(format, args...))
+}
+
+// parse reads the definitions of the {ENUMS} and {SIGNALS} sections when defs is
+// set, the messages otherwise.
+func (p *symParser) parse(data []byte, defs bool) error {
+	section := ""
+	var msg *Message
+	var mux *muxVariant
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20)
+	p.line = 0
+	for sc.Scan() {
+		p.line++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if strings.HasPrefix(line, "{") && strings.HasSuffix(line, "}") {
+			section, msg, mux = strings.ToUpper(line), nil, nil
+			continue
+		}
+
+		switch {
+		case section == "":
+			if k, v, ok := strings.Cut(line, "="); ok && strings.EqualFold(strings.TrimSpace(k), "Title") {
+				p.db.Version = strings.Trim(strings.TrimSpace(v), `"`)
+			}
+		case section == "{ENUMS}":
+			if !defs {
+				continue
+			}
+			if err := p.parseEnum(line); err != nil {
+				return err
+			}
+		case section == "{SIGNALS}":
+			if !defs {
+				continue
+			}
+			if k, v, ok := strings.Cut(line, "="); ok && strings.TrimSpace(k) == "Sig" {
+				name, rest, _ := strings.Cut(strings.TrimSpace(v), " ")
+				sv, err := p.parseVar(name, rest, false)
+				if err != nil {
+					return err
+				}
+				p.signals[name] = sv
+			}
+		case defs:
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			msg, mux = p.byName[name], nil
+			if msg == nil {
+				msg = &Message{Name: name, Length: -1}
+				p.byName[name] = msg
+				p.db.Messages = append(p.db.Messages, msg)
+			}
+		case msg == nil:
+			// lines of the send and receive sections outside of a message
+		default:
+			var err error
+			if mux, err = p.parseMessageLine(msg, mux, line); err != nil {
+				return err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("parse %s: %w", p.filename, err)
+	}
+	if !defs {
+		for _, m := range p.db.Messages {
+			if m.Length < 0 {
+				m.Length = payloadLength(m)
+			}
+		}
+	}
+	return nil
+}
+
+// muxVariant is the multiplexer value of the current section of a message.
+type muxVariant struct {
+	value uint64
+}
+
+func (p *symParser) parseMessageLine(msg *Message, mux *muxVariant, line string) (*muxVariant, error) {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return mux, nil
+	}
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	switch strings.ToLower(key) {
+	case "id":
+		// a range of IDs ("100h-1FFh") takes the first one
+		first, _, _ := strings.Cut(value, "-")
+		id, err := parseSYMNumber(first)
+		if err != nil || id > 0x1FFFFFFF {
+			return nil, p.errorf("invalid ID %q", value)
+		}
+		msg.ID = uint32(id)
+		if id > 0x7FF {
+			msg.IsExtended = true
+		}
+	case "type":
+		switch strings.ToLower(value) {
+		case "extended", "fdextended":
+			msg.IsExtended = true
+		case "standard", "fdstandard":
+			msg.IsExtended = false
+		}
+	case "dlc", "len":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > 64 {
+			return nil, p.errorf("invalid length %q", value)
+		}
+		msg.Length = n
+	case "cycletime":
+		if ms, err := strconv.Atoi(strings.Fields(value + " 0")[0]); err == nil && ms > 0 {
+			msg.CycleTime = time.Duration(ms) * time.Millisecond
+		}
+	case "mux":
+		return p.parseMux(msg, value)
+	case "var", "sig":
+		name, rest, _ := strings.Cut(value, " ")
+		var sv symVar
+		var err error
+		if strings.EqualFold(key, "var") {
+			sv, err = p.parseVar(name, rest, true)
+		} else {
+			def, ok := p.signals[name]
+			if !ok {
+				return nil, p.errorf("unknown signal %s", name)
+			}
+			sv = def
+			sv.signal.Receivers = nil
+			fields := strings.Fields(rest)
+			if len(fields) == 0 {
+				return nil, p.errorf("signal %s has no start bit", name)
+			}
+			if sv.signal.Start, err = strconv.Atoi(fields[0]); err != nil {
+				return nil, p.errorf("invalid start bit %q", fields[0])
+			}
+			sv.signal.Start = symStart(sv.signal.Start, sv.signal.IsBigEndian)
+		}
+		if err != nil {
+			return nil, err
+		}
+		var sw *Signal
+		var value uint64
+		if mux != nil {
+			sw, value = p.muxes[msg], mux.value
+		}
+		if err := addSignal(msg, sv.signal.Clone(), sw, value); err != nil {
+			return nil, p.errorf("%v", err)
+		}
+	}
+	return mux, nil
+}
+
+// parseMux reads "Mux=Name start,length value [-m]": the section holds the signals
+// present when the multiplexer at start is value.
+func (p *symParser) parseMux(msg *Message, value string) (*muxVariant, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 3 {
+		return nil, p.errorf("invalid Mux %q", value)
+	}
+	start, length, err := symPosition(fields[1])
+	if err != nil {
+		return nil, p.errorf("invalid Mux %q", value)
+	}
+	v, err := parseSYMNumber(fields[2])
+	if err != nil {
+		return nil, p.errorf("invalid Mux value %q", fields[2])
+	}
+	bigEndian := false
+	for _, f := range fields[3:] {
+		bigEndian = bigEndian || f == "-m"
+	}
+	sw := p.muxes[msg]
+	if sw == nil {
+		sw = &Signal{
+			Name:          fields[0],
+			Start:         symStart(start, bigEndian),
+			Length:        length,
+			IsBigEndian:   bigEndian,
+			Scale:         1,
+			IsMultiplexer: true,
+		}
+		p.muxes[msg] = sw
+		msg.Signals = append(msg.Signals, sw)
+	}
+	sw.ValueDescriptions = append(sw.ValueDescriptions, ValueDescription{Value: int64(v), Description: fields[0]})
+	return &muxVariant{value: v}, nil
+}
+
+// parseVar reads "type start,length flags" for a Var= line, "type length flags"
+// for the signals of the {SIGNALS} section when positioned is not set.
+func (p *symParser) parseVar(name, rest string, positioned bool) (symVar, error) {
+	rest, comment, _ := strings.Cut(rest, "//")
+	fields := symFields(rest)
+	if len(fields) < 2 {
+		return symVar{}, p.errorf("invalid signal %s", name)
+	}
+	sv := symVar{signal: Signal{Name: name, Scale: 1, Description: strings.TrimSpace(comment)}}
+	s := &sv.signal
+	switch strings.ToLower(fields[0]) {
+	case "unsigned", "bit", "char", "string", "raw":
+	case "signed":
+		s.IsSigned = true
+	case "float":
+		s.ValueType = ValueTypeFloat32
+	case "double":
+		s.ValueType = ValueTypeFloat64
+	default:
+		return symVar{}, p.errorf("%s: unknown type %q", name, fields[0])
+	}
+	var err error
+	if positioned {
+		s.Start, s.Length, err = symPosition(fields[1])
+	} else {
+		s.Length, err = strconv.Atoi(fields[1])
+	}
+	if err != nil {
+		return symVar{}, p.errorf("%s: invalid position %q", name, fields[1])
+	}
+
+	hasMin, hasMax := false, false
+	for _, f := range fields[2:] {
+		switch {
+		case f == "-m":
+			s.IsBigEndian = true
+		case strings.HasPrefix(f, "/u:"):
+			s.Unit = strings.Trim(f[3:], `"`)
+		case strings.HasPrefix(f, "/f:"):
+			s.Scale, err = strconv.ParseFloat(f[3:], 64)
+		case strings.HasPrefix(f, "/o:"):
+			s.Offset, err = strconv.ParseFloat(f[3:], 64)
+		case strings.HasPrefix(f, "/min:"):
+			s.Min, err = strconv.ParseFloat(f[5:], 64)
+			hasMin = true
+		case strings.HasPrefix(f, "/max:"):
+			s.Max, err = strconv.ParseFloat(f[5:], 64)
+			hasMax = true
+		case strings.HasPrefix(f, "/e:"):
+			sv.enum = f[3:]
+		case strings.HasPrefix(f, "/ln:") && s.Description == "":
+			s.Description = strings.Trim(f[4:], `"`)
+		}
+		if err != nil {
+			return symVar{}, p.errorf("%s: invalid %q", name, f)
+		}
+	}
+	if hasMin != hasMax {
+		// one bound alone does not limit the encoded values
+		s.Min, s.Max = 0, 0
+	}
+	if strings.EqualFold(fields[0], "bit") {
+		s.Length = 1
+	}
+	if positioned {
+		s.Start = symStart(s.Start, s.IsBigEndian)
+	}
+	if sv.enum != "" {
+		labels, ok := p.enums[sv.enum]
+		if !ok {
+			return symVar{}, p.errorf("%s: unknown enum %s", name, sv.enum)
+		}
+		s.ValueDescriptions = append([]ValueDescription(nil), labels...)
+	}
+	return sv, nil
+}
+
+// parseEnum reads `enum Name(0="Off", 1="On")`.
+func (p *symParser) parseEnum(line string) error {
+	rest, ok := strings.CutPrefix(line, "enum ")
+	if !ok {
+		return nil
+	}
+	name, body, ok := strings.Cut(rest, "(")
+	if !ok {
+		return p.errorf("invalid enum %q", line)
+	}
+	body = strings.TrimSuffix(strings.TrimSpace(body), ")")
+	var labels []ValueDescription
+	for body = strings.TrimSpace(body); body != ""; body = strings.TrimSpace(body) {
+		v, after, ok := strings.Cut(body, "=")
+		if !ok {
+			return p.errorf("invalid enum %q", line)
+		}
+		n, err := parseSYMNumber(strings.TrimSpace(v))
+		if err != nil {
+			return p.errorf("invalid enum value %q", v)
+		}
+		after = strings.TrimSpace(after)
+		var label string
+		if strings.HasPrefix(after, `"`) {
+			end := strings.Index(after[1:], `"`)
+			if end < 0 {
+				return p.errorf("unterminated enum label in %q", line)
+			}
+			label, after = after[1:1+end], after[2+end:]
+		} else {
+			label, after, _ = strings.Cut(after, ",")
+			label = strings.TrimSpace(label)
+		}
+		labels = append(labels, ValueDescription{Value: int64(n), Description: label})
+		body = strings.TrimPrefix(strings.TrimSpace(after), ",")
+	}
+	p.enums[strings.TrimSpace(name)] = labels
+	return nil
+}
+
+// symFields splits a definition into fields, keeping quoted strings together.
+func symFields(s string) []string {
+	var fields []string
+	var cur strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			cur.WriteRune(r)
+		case !quoted && (r == ' ' || r == '\t'):
+			if cur.Len() > 0 {
+				fields = append(fields, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
+
+// symPosition parses "start,length".
+func symPosition(s string) (start, length int, err error) {
+	a, b, ok := strings.Cut(s, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid position %q", s)
+	}
+	if start, err = strconv.Atoi(a); err != nil {
+		return 0, 0, err
+	}
+	length, err = strconv.Atoi(b)
+	return start, length, err
+}
+
+// symStart converts a start bit of a .sym file to the DBC numbering: the most
+// significant bit of big endian signals is numbered from bit 7 of byte 0 down.
+func symStart(start int, bigEndian bool) int {
+	if !bigEndian {
+		return start
+	}
+	return 8*(start/8) + 7 - start%8
+}
+
+// parseSYMNumber parses a decimal number or a hex one with an h suffix ("1A0h").
+func parseSYMNumber(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if h, ok := strings.CutSuffix(strings.ToLower(s), "h"); ok {
+		return strconv.ParseUint(h, 16, 64)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
@@ -0,0 +1,351 @@
+package canlog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+)
+
+// ascTimeLayout is the date format of ASC headers, eg "Wed Oct 14 10:20:30.123 am 2026".
+const ascTimeLayout = "Mon Jan 02 03:04:05.000 pm 2006"
+
+// CAN FD flags of ASC CANFD lines.
+const (
+	ascFlagEDL = 0x1000
+	ascFlagBRS = 0x2000
+	ascFlagESI = 0x4000
+)
+
+// ASCWriter writes frames in the Vector ASC text format read by CANoe and
+// CANalyzer. Interfaces are numbered as channels 1, 2, ... in the order they
+// appear; a comment line records the mapping:
+//
+//	0.012345 1  123             Rx   d 3 01 02 03
+//	0.023456 CANFD   1 Tx      1ABCDEFx                                   1 0 9 12 ...
+type ASCWriter struct {
+	w        *bufio.Writer
+	start    time.Time
+	channels map[string]int
+}
+
+// NewASCWriter returns a writer that buffers output to w. The header is written
+// with the first frame, whose timestamp starts the measurement. Call Close to
+// write the end of the trigger block.
+func NewASCWriter(w io.Writer) *ASCWriter {
+	return &ASCWriter{w: bufio.NewWriter(w), channels: make(map[string]int)}
+}
+
+// WriteFrame writes one line; tx marks frames sent by the host.
+func (w *ASCWriter) WriteFrame(ts time.Time, iface string, f canbus.Frame, tx bool) error {
+	if w.start.IsZero() {
+		w.start = ts
+		date := ts.Format(ascTimeLayout)
+		if _, err := fmt.Fprintf(w.w, "date %s\nbase hex  timestamps absolute\ninternal events logged\n// version 9.0.0\nBegin Triggerblock %s\n   0.000000 Start of measurement\n", date, date); err != nil {
+			return err
+		}
+	}
+	ch, ok := w.channels[iface]
+	if !ok {
+		ch = len(w.channels) + 1
+		w.channels[iface] = ch
+		if _, err := fmt.Fprintf(w.w, "// channel %d: %s\n", ch, iface); err != nil {
+			return err
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%11.6f ", ts.Sub(w.start).Seconds())
+	dir := "Rx"
+	if tx {
+		dir = "Tx"
+	}
+	id := fmt.Sprintf("%X", f.ID)
+	if f.IsExtended {
+		id += "x"
+	}
+	switch {
+	case f.IsError:
+		fmt.Fprintf(&b, "%d  ErrorFrame", ch)
+	case f.IsFD:
+		flags := ascFlagEDL
+		brs, esi := 0, 0
+		if f.BRS {
+			flags |= ascFlagBRS
+			brs = 1
+		}
+		if f.ESI {
+			flags |= ascFlagESI
+			esi = 1
+		}
+		fmt.Fprintf(&b, "CANFD %3d %-4s %8s %32s %d %d %x %2d", ch, dir, id, "", brs, esi, f.DLC(), f.Length)
+		for _, v := range f.Payload() {
+			fmt.Fprintf(&b, " %02X", v)
+		}
+		// message duration and length, CRC and bit timings are not known
+		fmt.Fprintf(&b, " %8d %4d %8X %8d %8d %8d %8d %8d", 0, 0, flags, 0, 0, 0, 0, 0)
+	case f.IsRemote:
+		fmt.Fprintf(&b, "%-2d %-15s %-4s r %x", ch, id, dir, f.Length)
+	default:
+		fmt.Fprintf(&b, "%-2d %-15s %-4s d %x", ch, id, dir, f.Length)
+		for _, v := range f.Payload() {
+			fmt.Fprintf(&b, " %02X", v)
+		}
+	}
+	b.WriteByte('\n')
+	_, err := w.w.WriteString(b.String())
+	return err
+}
+
+// Flush writes buffered lines to the underlying writer.
+func (w *ASCWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// Close ends the trigger block and flushes the writer; it does not close the underlying writer.
+func (w *ASCWriter) Close() error {
+	if !w.start.IsZero() {
+		if _, err := w.w.WriteString("End TriggerBlock\n"); err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
+
+// maxASCWarnings bounds the warnings ReadASC returns; the last one counts the rest.
+const maxASCWarnings = 20
+
+// ReadASC reads the CAN and CAN FD frames of a Vector ASC trace. Records of other
+// types (statistics, status and bus events, other buses) are skipped with a
+// warning instead of failing the read. Error frames are returned as error frames.
+// Timestamps are offsets from the date of the header, or from the Unix epoch
+// when it has none; relative timestamps are accumulated. The interface of the
+// records is the channel number.
+func ReadASC(r io.Reader) ([]Record, []string, error) {
+	var (
+		records  []Record
+		warnings []string
+		skipped  int
+		start    = time.Unix(0, 0)
+		hex      = true
+		relative bool
+		last     time.Duration
+	)
+	warn := func(lineNo int, format string, args ...interface{}) {
+		if skipped++; skipped <= maxASCWarnings {
+			warnings = append(warnings, fmt.Sprintf("line %d: ", lineNo)+fmt.Sprintf(format, args...))
+		}
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		lower := strings.ToLower(line)
+		switch {
+		case line == "", strings.HasPrefix(line, "//"),
+			strings.HasPrefix(lower, "begin triggerblock"), strings.HasPrefix(lower, "end triggerblock"),
+			strings.HasSuffix(lower, "internal events logged"), strings.HasSuffix(lower, "start of measurement"):
+			continue
+		case strings.HasPrefix(lower, "date "):
+			if ts, ok := parseASCDate(line[len("date "):]); ok {
+				start = ts
+			}
+			continue
+		case strings.HasPrefix(lower, "base "):
+			fields := strings.Fields(lower)
+			hex = len(fields) < 2 || fields[1] != "dec"
+			relative = len(fields) >= 4 && fields[3] == "relative"
+			continue
+		}
+
+		fields := strings.Fields(line)
+		offset, err := parseASCTime(fields[0])
+		if err != nil {
+			warn(lineNo, "skipped line without timestamp")
+			continue
+		}
+		if relative {
+			offset += last
+		}
+		last = offset
+
+		rec := Record{Timestamp: start.Add(offset)}
+		if err := parseASCFrame(fields[1:], hex, &rec); err != nil {
+			warn(lineNo, "%v", err)
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, err
+	}
+	if skipped > maxASCWarnings {
+		warnings = append(warnings, fmt.Sprintf("%d more lines skipped", skipped-maxASCWarnings))
+	}
+	return records, warnings, nil
+}
+
+var errASCUnsupported = errors.New("unsupported record skipped")
+
+// parseASCFrame parses the fields of a frame line after the timestamp.
+func parseASCFrame(fields []string, hex bool, rec *Record) error {
+	if len(fields) < 2 {
+		return errASCUnsupported
+	}
+	if strings.EqualFold(fields[0], "CANFD") {
+		return parseASCFDFrame(fields[1:], hex, rec)
+	}
+	if _, err := strconv.Atoi(fields[0]); err != nil {
+		// "Start of measurement", "Statistic:", LIN and other bus events
+		return errASCUnsupported
+	}
+	rec.Interface = fields[0]
+	if fields[1] == "ErrorFrame" {
+		rec.Frame = canbus.Frame{IsError: true, Length: canbus.MaxDataLength}
+		return nil
+	}
+	// <channel> <id>[x] <Rx|Tx> <d|r> <dlc> <data>...
+	if len(fields) < 4 || !isASCDirection(fields[2]) {
+		return errASCUnsupported
+	}
+	rec.TX = strings.EqualFold(fields[2], "Tx")
+	f := &rec.Frame
+	if err := parseASCID(fields[1], hex, f); err != nil {
+		return err
+	}
+	switch strings.ToLower(fields[3]) {
+	case "r":
+		f.IsRemote = true
+		if len(fields) > 4 {
+			dlc, err := parseASCByte(fields[4], true)
+			if err != nil || dlc > canbus.MaxDataLength {
+				return fmt.Errorf("invalid DLC %q", fields[4])
+			}
+			f.Length = dlc
+		}
+		return nil
+	case "d":
+		if len(fields) < 5 {
+			return fmt.Errorf("missing DLC")
+		}
+		dlc, err := parseASCByte(fields[4], true)
+		if err != nil || dlc > 0xf {
+			return fmt.Errorf("invalid DLC %q", fields[4])
+		}
+		// DLCs 9 to 15 of classic frames mean 8 bytes
+		f.Length = min(dlc, canbus.MaxDataLength)
+		return parseASCData(fields[5:], int(f.Length), hex, f)
+	default:
+		return errASCUnsupported
+	}
+}
+
+// parseASCFDFrame parses "<channel> <dir> <id>[x] [name] <brs> <esi> <dlc> <length> <data>...".
+func parseASCFDFrame(fields []string, hex bool, rec *Record) error {
+	if len(fields) < 3 || !isASCDirection(fields[1]) {
+		// CAN FD error frames and other events
+		return errASCUnsupported
+	}
+	rec.Interface = fields[0]
+	rec.TX = strings.EqualFold(fields[1], "Tx")
+	f := &rec.Frame
+	if err := parseASCID(fields[2], hex, f); err != nil {
+		return err
+	}
+	rest := fields[3:]
+	// the symbolic name of the message is optional
+	if len(rest) > 0 && rest[0] != "0" && rest[0] != "1" {
+		rest = rest[1:]
+	}
+	if len(rest) < 4 {
+		return fmt.Errorf("truncated CAN FD frame")
+	}
+	f.IsFD = true
+	f.BRS = rest[0] == "1"
+	f.ESI = rest[1] == "1"
+	dlc, err := strconv.ParseUint(rest[2], 16, 8)
+	if err != nil || dlc > 0xf {
+		return fmt.Errorf("invalid DLC %q", rest[2])
+	}
+	n, err := strconv.Atoi(rest[3])
+	if err != nil || n != int(canbus.DLCToLength(uint8(dlc))) {
+		return fmt.Errorf("invalid data length %q for DLC %X", rest[3], dlc)
+	}
+	f.Length = uint8(n)
+	return parseASCData(rest[4:], n, hex, f)
+}
+
+func parseASCID(s string, hex bool, f *canbus.Frame) error {
+	id := strings.TrimSuffix(strings.TrimSuffix(s, "x"), "X")
+	f.IsExtended = id != s
+	base := 10
+	if hex {
+		base = 16
+	}
+	v, err := strconv.ParseUint(id, base, 32)
+	if err != nil {
+		return fmt.Errorf("invalid ID %q", s)
+	}
+	f.ID = uint32(v)
+	return f.Validate()
+}
+
+func parseASCData(fields []string, n int, hex bool, f *canbus.Frame) error {
+	if len(fields) < n {
+		return fmt.Errorf("%d data bytes, want %d", len(fields), n)
+	}
+	for i := 0; i < n; i++ {
+		v, err := parseASCByte(fields[i], hex)
+		if err != nil {
+			return fmt.Errorf("invalid data byte %q", fields[i])
+		}
+		f.Data[i] = v
+	}
+	return nil
+}
+
+func parseASCByte(s string, hex bool) (uint8, error) {
+	base := 10
+	if hex {
+		base = 16
+	}
+	v, err := strconv.ParseUint(s, base, 8)
+	return uint8(v), err
+}
+
+func isASCDirection(s string) bool {
+	return strings.EqualFold(s, "Rx") || strings.EqualFold(s, "Tx")
+}
+
+func parseASCTime(s string) (time.Duration, error) {
+	sec, err := strconv.ParseFloat(s, 64)
+	if err != nil || sec < 0 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	return time.Duration(sec * float64(time.Second)), nil
+}
+
+// parseASCDate parses the header date, written with an am/pm or 24 hour clock.
+func parseASCDate(s string) (time.Time, bool) {
+	s = strings.Join(strings.Fields(s), " ")
+	for _, layout := range []string{
+		ascTimeLayout,
+		"Mon Jan 2 03:04:05.000 pm 2006",
+		"Mon Jan 2 03:04:05 pm 2006",
+		"Mon Jan 2 15:04:05.000 2006",
+		"Mon Jan 2 15:04:05 2006",
+	} {
+		if ts, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return ts, true
+		}
+	}
+	return time.Time{}, false
+}
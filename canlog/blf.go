@@ -0,0 +1,663 @@
+package canlog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"canproject/canbus"
+)
+
+// BLF layout, following the Vector binlog object definitions.
+const (
+	blfFileHeaderSize = 144
+	blfObjHeaderSize  = 32 // base header and header version 1
+	blfContainerHead  = 32 // base header and container fields
+
+	blfObjCANMessage   = 1
+	blfObjLogContainer = 10
+	blfObjCANErrorExt  = 73
+	blfObjCANFDMessage = 101
+
+	blfCompressionNone = 0
+	blfCompressionZlib = 2
+	blfTimeTenMicros   = 1
+	blfTimeOneNanos    = 2
+
+	blfCANFlagTx     = 0x01
+	blfCANFlagRemote = 0x80
+
+	blfFDFlagEDL = 0x1000
+	blfFDFlagBRS = 0x2000
+	blfFDFlagESI = 0x4000
+
+	// objects read besides the ones written; 100 is the CAN FD message object
+	// preceding the 64 byte one written as blfObjCANFDMessage
+	blfObjCANError        = 2
+	blfObjLINMessage      = 11
+	blfObjFlexRayRcv      = 50
+	blfObjLINMessage2     = 57
+	blfObjFlexRayRcvEx    = 66
+	blfObjEthernetFrame   = 71
+	blfObjCANMessage2     = 86
+	blfObjCANFD           = 100
+	blfObjEthernetFrameEx = 120
+
+	blfFDFlagRemote = 0x0010
+	blfFDEDL        = 0x1
+	blfFDBRS        = 0x2
+	blfFDESI        = 0x4
+
+	// blfContainerSize is the uncompressed size at which a container is written.
+	blfContainerSize = 128 * 1024
+	// blfApplicationID identifies the writer in the file header, 5 is used by third party tools.
+	blfApplicationID = 5
+)
+
+// BLFWriter writes frames in the Vector binary logging format read by CANoe and
+// CANalyzer. Objects are grouped in zlib compressed containers. Interfaces are
+// numbered as channels 1, 2, ... in the order they appear.
+type BLFWriter struct {
+	w        io.WriteSeeker
+	buf      bytes.Buffer
+	start    time.Time
+	last     time.Time
+	channels map[string]uint16
+
+	fileSize         uint64
+	uncompressedSize uint64
+	objects          uint32
+}
+
+// NewBLFWriter writes a provisional file header to w. Objects are buffered until
+// a container is full; call Close to write the last container and the final file
+// header, which needs w to be seekable.
+func NewBLFWriter(w io.WriteSeeker) (*BLFWriter, error) {
+	bw := &BLFWriter{
+		w:                w,
+		channels:         make(map[string]uint16),
+		fileSize:         blfFileHeaderSize,
+		uncompressedSize: blfFileHeaderSize,
+	}
+	if _, err := w.Write(bw.header()); err != nil {
+		return nil, err
+	}
+	return bw, nil
+}
+
+// WriteFrame appends a CAN, CAN FD or error frame object; tx marks frames sent by the host.
+func (w *BLFWriter) WriteFrame(ts time.Time, iface string, f canbus.Frame, tx bool) error {
+	if w.start.IsZero() {
+		w.start = ts
+	}
+	w.last = ts
+	ch, ok := w.channels[iface]
+	if !ok {
+		ch = uint16(len(w.channels) + 1)
+		w.channels[iface] = ch
+	}
+
+	le := binary.LittleEndian
+	id := f.ID
+	if f.IsExtended {
+		id |= 0x80000000
+	}
+	var objType uint32
+	var data []byte
+	switch {
+	case f.IsError:
+		objType = blfObjCANErrorExt
+		data = le.AppendUint16(nil, ch)
+		data = le.AppendUint16(data, 0) // length
+		data = le.AppendUint32(data, 0) // flags
+		data = append(data, 0, 0, f.Length, 0)
+		data = le.AppendUint32(data, 0) // frame length in ns
+		data = le.AppendUint32(data, id)
+		data = le.AppendUint16(data, 0) // extended flags
+		data = le.AppendUint16(data, 0)
+		data = append(data, f.Data[:canbus.MaxDataLength]...)
+	case f.IsFD:
+		objType = blfObjCANFDMessage
+		flags := uint32(blfFDFlagEDL)
+		if f.BRS {
+			flags |= blfFDFlagBRS
+		}
+		if f.ESI {
+			flags |= blfFDFlagESI
+		}
+		var dir uint8
+		if tx {
+			dir = 1
+		}
+		data = append(data, uint8(ch), f.DLC(), f.Length, 0)
+		data = le.AppendUint32(data, id)
+		data = le.AppendUint32(data, 0) // frame length in ns
+		data = le.AppendUint32(data, flags)
+		data = le.AppendUint32(data, 0) // arbitration phase bit timing
+		data = le.AppendUint32(data, 0) // data phase bit timing
+		data = le.AppendUint32(data, 0) // BRS time offset
+		data = le.AppendUint32(data, 0) // CRC delimiter time offset
+		data = le.AppendUint16(data, 0) // bit count
+		data = append(data, dir, 0)
+		data = le.AppendUint32(data, 0) // CRC
+		data = append(data, f.Payload()...)
+	default:
+		objType = blfObjCANMessage
+		var flags uint8
+		if tx {
+			flags |= blfCANFlagTx
+		}
+		if f.IsRemote {
+			flags |= blfCANFlagRemote
+		}
+		data = le.AppendUint16(nil, ch)
+		data = append(data, flags, f.Length)
+		data = le.AppendUint32(data, id)
+		data = append(data, f.Data[:canbus.MaxDataLength]...)
+	}
+
+	size := uint32(blfObjHeaderSize + len(data))
+	obj := append([]byte("LOBJ"), le.AppendUint16(nil, blfObjHeaderSize)...)
+	obj = le.AppendUint16(obj, 1) // header version
+	obj = le.AppendUint32(obj, size)
+	obj = le.AppendUint32(obj, objType)
+	obj = le.AppendUint32(obj, blfTimeOneNanos)
+	obj = le.AppendUint16(obj, 0) // client index
+	obj = le.AppendUint16(obj, 0) // object version
+	obj = le.AppendUint64(obj, uint64(ts.Sub(w.start)))
+	w.buf.Write(obj)
+	w.buf.Write(data)
+	// objects are followed by size%4 padding bytes
+	w.buf.Write(make([]byte, size%4))
+	w.objects++
+
+	if w.buf.Len() >= blfContainerSize {
+		return w.Flush()
+	}
+	return nil
+}
+
+// Flush compresses the buffered objects into a container and writes it.
+func (w *BLFWriter) Flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	if _, err := zw.Write(w.buf.Bytes()); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	le := binary.LittleEndian
+	size := uint32(blfContainerHead + z.Len())
+	c := append([]byte("LOBJ"), le.AppendUint16(nil, 16)...)
+	c = le.AppendUint16(c, 1)
+	c = le.AppendUint32(c, size)
+	c = le.AppendUint32(c, blfObjLogContainer)
+	c = le.AppendUint16(c, blfCompressionZlib)
+	c = append(c, make([]byte, 6)...)
+	c = le.AppendUint32(c, uint32(w.buf.Len()))
+	c = append(c, make([]byte, 4)...)
+	c = append(c, z.Bytes()...)
+	c = append(c, make([]byte, size%4)...)
+	if _, err := w.w.Write(c); err != nil {
+		return err
+	}
+	w.fileSize += uint64(len(c))
+	w.uncompressedSize += uint64(blfContainerHead + w.buf.Len())
+	w.buf.Reset()
+	return nil
+}
+
+// Close writes the buffered objects and the final file header; it does not
+// close the underlying writer.
+func (w *BLFWriter) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if _, err := w.w.Seek(0, io.SeekStart); err != nil {
+		return errors.Join(errors.New("blf: cannot update the file header"), err)
+	}
+	if _, err := w.w.Write(w.header()); err != nil {
+		return err
+	}
+	_, err := w.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+func (w *BLFWriter) header() []byte {
+	le := binary.LittleEndian
+	h := append([]byte("LOGG"), le.AppendUint32(nil, blfFileHeaderSize)...)
+	// application ID and version, binlog version 2.6.8.1
+	h = append(h, blfApplicationID, 0, 0, 0, 2, 6, 8, 1)
+	h = le.AppendUint64(h, w.fileSize)
+	h = le.AppendUint64(h, w.uncompressedSize)
+	h = le.AppendUint32(h, w.objects)
+	h = le.AppendUint32(h, 0) // objects read
+	h = appendSystemTime(h, w.start)
+	h = appendSystemTime(h, w.last)
+	return append(h, make([]byte, blfFileHeaderSize-len(h))...)
+}
+
+// appendSystemTime appends a Windows SYSTEMTIME, zero for the zero time.
+func appendSystemTime(b []byte, t time.Time) []byte {
+	le := binary.LittleEndian
+	if t.IsZero() {
+		return append(b, make([]byte, 16)...)
+	}
+	for _, v := range []int{t.Year(), int(t.Month()), int(t.Weekday()), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond() / 1e6} {
+		b = le.AppendUint16(b, uint16(v))
+	}
+	return b
+}
+
+// maxBLFWarnings bounds the warnings about malformed objects ReadBLF returns.
+const maxBLFWarnings = 20
+
+// maxBLFObjectSize bounds the size of an object and of the uncompressed data
+// of a container, which the writers keep around 128 KiB, so a corrupt file
+// cannot make ReadBLF allocate gigabytes.
+const maxBLFObjectSize = 64 << 20
+
+// ReadBLF reads a Vector BLF trace, as written by CANoe, CANalyzer or
+// BLFWriter. CAN, CAN FD and error frames are read as frames, LIN, FlexRay
+// and Ethernet frames as packets, so the buses of a mixed trace share a
+// timeline. Other objects are skipped with a warning per object type. CAN
+// interfaces are the channel numbers, like in ReadASC, those of the other
+// networks name the network and the channel, eg "lin1" or "eth1".
+// Timestamps are offsets from the measurement start of the file header, read
+// as local time.
+func ReadBLF(r io.Reader) ([]Record, []string, error) {
+	br := bufio.NewReader(r)
+	le := binary.LittleEndian
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(br, head); err != nil || string(head[:4]) != "LOGG" {
+		return nil, nil, errors.New("blf: not a BLF file")
+	}
+	size := le.Uint32(head[4:])
+	if size < 56 || size > 64*1024 {
+		return nil, nil, fmt.Errorf("blf: invalid file header size %d", size)
+	}
+	head = append(head, make([]byte, size-8)...)
+	if _, err := io.ReadFull(br, head[8:]); err != nil {
+		return nil, nil, fmt.Errorf("blf: truncated file header: %w", err)
+	}
+
+	d := blfDecoder{start: parseSystemTime(head[40:56]), skipped: make(map[uint32]int)}
+	for {
+		obj, err := readBLFObject(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if le.Uint32(obj[12:]) != blfObjLogContainer {
+			d.object(obj)
+			continue
+		}
+		data, err := blfContainerData(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := d.feed(data); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(d.pending) > 0 {
+		d.warn("truncated object at the end of the file")
+	}
+	return d.records, d.warnings(), nil
+}
+
+// readBLFObject reads an object of the file and its padding.
+func readBLFObject(br *bufio.Reader) ([]byte, error) {
+	base := make([]byte, 16)
+	if _, err := io.ReadFull(br, base); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("blf: truncated object")
+		}
+		return nil, err
+	}
+	size, err := blfObjectSize(base)
+	if err != nil {
+		return nil, err
+	}
+	obj := append(base, make([]byte, size-16)...)
+	if _, err := io.ReadFull(br, obj[16:]); err != nil {
+		return nil, errors.New("blf: truncated object")
+	}
+	// the padding of the last object may be missing
+	_, _ = br.Discard(size % 4)
+	return obj, nil
+}
+
+// blfObjectSize checks the base header of an object and returns its size.
+func blfObjectSize(base []byte) (int, error) {
+	le := binary.LittleEndian
+	if string(base[:4]) != "LOBJ" {
+		return 0, errors.New("blf: invalid object signature")
+	}
+	size := int(le.Uint32(base[8:]))
+	if headerSize := int(le.Uint16(base[4:])); headerSize < 16 || size < max(headerSize, 32) || size > maxBLFObjectSize {
+		return 0, fmt.Errorf("blf: invalid object size %d", size)
+	}
+	return size, nil
+}
+
+// blfContainerData returns the uncompressed objects of a log container.
+func blfContainerData(obj []byte) ([]byte, error) {
+	le := binary.LittleEndian
+	data := obj[blfContainerHead:]
+	switch method := le.Uint16(obj[16:]); method {
+	case blfCompressionNone:
+		return data, nil
+	case blfCompressionZlib:
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("blf: container: %w", err)
+		}
+		size := int64(le.Uint32(obj[24:]))
+		if size > maxBLFObjectSize {
+			return nil, fmt.Errorf("blf: invalid object size %d", size)
+		}
+		buf := bytes.NewBuffer(make([]byte, 0, size))
+		// one byte more than declared tells a stream expanding beyond its size
+		if _, err := io.Copy(buf, io.LimitReader(zr, size+1)); err != nil {
+			return nil, fmt.Errorf("blf: container: %w", err)
+		}
+		if int64(buf.Len()) > size {
+			return nil, fmt.Errorf("blf: invalid object size %d", size)
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("blf: unsupported container compression %d", method)
+	}
+}
+
+// blfDecoder decodes the objects of the containers of a file, which may span
+// containers.
+type blfDecoder struct {
+	start   time.Time
+	records []Record
+	pending []byte
+	// padding is the part of the padding of the last object not received yet
+	padding  int
+	skipped  map[uint32]int
+	invalid  int
+	messages []string
+}
+
+func (d *blfDecoder) feed(data []byte) error {
+	n := min(d.padding, len(data))
+	d.padding -= n
+	d.pending = append(d.pending, data[n:]...)
+	for len(d.pending) >= 16 {
+		size, err := blfObjectSize(d.pending)
+		if err != nil {
+			return err
+		}
+		if len(d.pending) < size {
+			break
+		}
+		d.object(d.pending[:size])
+		pad := min(size%4, len(d.pending)-size)
+		d.padding = size%4 - pad
+		d.pending = d.pending[size+pad:]
+	}
+	// keep the incomplete object apart from the container it came with
+	d.pending = slices.Clone(d.pending)
+	return nil
+}
+
+func (d *blfDecoder) warn(format string, args ...any) {
+	if d.invalid++; d.invalid <= maxBLFWarnings {
+		d.messages = append(d.messages, fmt.Sprintf(format, args...))
+	}
+}
+
+func (d *blfDecoder) warnings() []string {
+	w := d.messages
+	if d.invalid > maxBLFWarnings {
+		w = append(w, fmt.Sprintf("%d more objects skipped", d.invalid-maxBLFWarnings))
+	}
+	types := make([]uint32, 0, len(d.skipped))
+	for t := range d.skipped {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	for _, t := range types {
+		w = append(w, fmt.Sprintf("%d objects of unsupported type %d skipped", d.skipped[t], t))
+	}
+	return w
+}
+
+// object decodes an object with its header.
+func (d *blfDecoder) object(obj []byte) {
+	le := binary.LittleEndian
+	typ := le.Uint32(obj[12:])
+	headerSize := int(le.Uint16(obj[4:]))
+	if headerSize < 32 {
+		d.warn("object type %d: invalid header size %d", typ, headerSize)
+		return
+	}
+	ts := time.Duration(le.Uint64(obj[24:]))
+	if le.Uint32(obj[16:]) == blfTimeTenMicros {
+		ts *= 10 * time.Microsecond
+	}
+	rec := Record{Timestamp: d.start.Add(ts)}
+	var ok bool
+	data := obj[headerSize:]
+	switch typ {
+	case blfObjCANMessage, blfObjCANMessage2:
+		ok = readBLFCAN(data, &rec)
+	case blfObjCANError, blfObjCANErrorExt:
+		if ok = len(data) >= 2; ok {
+			rec.Interface = strconv.Itoa(int(le.Uint16(data)))
+			rec.Frame = canbus.Frame{IsError: true, Length: canbus.MaxDataLength}
+		}
+	case blfObjCANFD:
+		ok = readBLFCANFD(data, &rec)
+	case blfObjCANFDMessage:
+		ok = readBLFCANFD64(data, &rec)
+	case blfObjLINMessage, blfObjLINMessage2:
+		ok = readBLFLIN(typ, data, &rec)
+	case blfObjFlexRayRcv, blfObjFlexRayRcvEx:
+		ok = readBLFFlexRay(typ, data, &rec)
+	case blfObjEthernetFrame, blfObjEthernetFrameEx:
+		ok = readBLFEthernet(typ, data, &rec)
+	default:
+		d.skipped[typ]++
+		return
+	}
+	if !ok {
+		d.warn("object type %d at %v: truncated or invalid", typ, ts)
+		return
+	}
+	d.records = append(d.records, rec)
+}
+
+func blfID(v uint32, f *canbus.Frame) {
+	f.IsExtended = v&0x80000000 != 0
+	f.ID = v &^ 0x80000000
+}
+
+// readBLFCAN reads a CAN message object.
+func readBLFCAN(data []byte, rec *Record) bool {
+	if len(data) < 16 {
+		return false
+	}
+	f := &rec.Frame
+	rec.Interface = strconv.Itoa(int(binary.LittleEndian.Uint16(data)))
+	rec.TX = data[2]&blfCANFlagTx != 0
+	f.IsRemote = data[2]&blfCANFlagRemote != 0
+	f.Length = min(data[3], canbus.MaxDataLength)
+	blfID(binary.LittleEndian.Uint32(data[4:]), f)
+	if !f.IsRemote {
+		copy(f.Data[:], data[8:8+int(f.Length)])
+	}
+	return f.Validate() == nil
+}
+
+// readBLFCANFD reads a CAN FD message object of the short layout.
+func readBLFCANFD(data []byte, rec *Record) bool {
+	if len(data) < 20 {
+		return false
+	}
+	f := &rec.Frame
+	rec.Interface = strconv.Itoa(int(binary.LittleEndian.Uint16(data)))
+	rec.TX = data[2]&blfCANFlagTx != 0
+	blfID(binary.LittleEndian.Uint32(data[4:]), f)
+	fd := data[13]
+	f.IsFD = fd&blfFDEDL != 0
+	f.BRS = fd&blfFDBRS != 0
+	f.ESI = fd&blfFDESI != 0
+	f.IsRemote = !f.IsFD && data[2]&blfCANFlagRemote != 0
+	return readBLFData(data[20:], data[14], f)
+}
+
+// readBLFCANFD64 reads a CAN FD message object of the 64 byte layout.
+func readBLFCANFD64(data []byte, rec *Record) bool {
+	if len(data) < 40 {
+		return false
+	}
+	le := binary.LittleEndian
+	f := &rec.Frame
+	rec.Interface = strconv.Itoa(int(data[0]))
+	rec.TX = data[34] == 1
+	blfID(le.Uint32(data[4:]), f)
+	flags := le.Uint32(data[12:])
+	f.IsFD = flags&blfFDFlagEDL != 0
+	f.BRS = flags&blfFDFlagBRS != 0
+	f.ESI = flags&blfFDFlagESI != 0
+	f.IsRemote = !f.IsFD && flags&blfFDFlagRemote != 0
+	return readBLFData(data[40:], data[2], f)
+}
+
+// readBLFData copies n bytes of data to a CAN or CAN FD frame.
+func readBLFData(data []byte, n uint8, f *canbus.Frame) bool {
+	limit := uint8(canbus.MaxDataLength)
+	if f.IsFD {
+		limit = canbus.MaxFDDataLength
+	}
+	f.Length = min(n, limit)
+	if f.IsRemote {
+		return f.Validate() == nil
+	}
+	if len(data) < int(f.Length) {
+		return false
+	}
+	copy(f.Data[:], data[:f.Length])
+	return f.Validate() == nil
+}
+
+// readBLFLIN reads a LIN message object.
+func readBLFLIN(typ uint32, data []byte, rec *Record) bool {
+	// offsets of the channel, the ID, the data and the direction
+	ch, id, payload, dir := 0, 2, 4, 18
+	if typ == blfObjLINMessage2 {
+		ch, id, payload, dir = 12, 37, 112, 122
+	}
+	if len(data) <= dir {
+		return false
+	}
+	n := min(int(data[id+1]), 8)
+	rec.Interface = fmt.Sprintf("%s%d", canbus.NetworkLIN, binary.LittleEndian.Uint16(data[ch:]))
+	rec.TX = data[dir] != 0
+	rec.Packet = &canbus.Packet{
+		Network: canbus.NetworkLIN,
+		ID:      uint32(data[id] & 0x3f),
+		Payload: slices.Clone(data[payload : payload+n]),
+	}
+	return true
+}
+
+// readBLFFlexRay reads a FlexRay receive message object.
+func readBLFFlexRay(typ uint32, data []byte, rec *Record) bool {
+	le := binary.LittleEndian
+	payload := 44
+	if typ == blfObjFlexRayRcvEx {
+		payload = 84
+	}
+	if len(data) < payload {
+		return false
+	}
+	n := int(le.Uint16(data[24:]))
+	if len(data) < payload+n {
+		return false
+	}
+	rec.Interface = fmt.Sprintf("%s%d", canbus.NetworkFlexRay, le.Uint16(data))
+	rec.TX = le.Uint16(data[6:]) != 0
+	rec.Packet = &canbus.Packet{
+		Network: canbus.NetworkFlexRay,
+		ID:      uint32(le.Uint16(data[16:])),
+		Cycle:   uint8(le.Uint16(data[26:])),
+		Payload: slices.Clone(data[payload : payload+n]),
+	}
+	return true
+}
+
+// readBLFEthernet reads an Ethernet frame object. The frame of the old layout
+// is rebuilt from its addresses, VLAN tag, EtherType and payload.
+func readBLFEthernet(typ uint32, data []byte, rec *Record) bool {
+	le := binary.LittleEndian
+	var frame []byte
+	var ch, dir uint16
+	if typ == blfObjEthernetFrameEx {
+		if len(data) < 32 {
+			return false
+		}
+		n := int(le.Uint16(data[22:]))
+		if len(data) < 32+n {
+			return false
+		}
+		ch, dir = le.Uint16(data[4:]), le.Uint16(data[20:])
+		frame = slices.Clone(data[32 : 32+n])
+	} else {
+		if len(data) < 24 {
+			return false
+		}
+		n := int(le.Uint16(data[22:]))
+		if len(data) < 24+n {
+			return false
+		}
+		ch, dir = le.Uint16(data[6:]), le.Uint16(data[14:])
+		frame = append(frame, data[8:14]...) // destination
+		frame = append(frame, data[0:6]...)  // source
+		if tpid := le.Uint16(data[18:]); tpid != 0 {
+			frame = binary.BigEndian.AppendUint16(frame, tpid)
+			frame = binary.BigEndian.AppendUint16(frame, le.Uint16(data[20:]))
+		}
+		frame = binary.BigEndian.AppendUint16(frame, le.Uint16(data[16:]))
+		// the payload ends the object
+		frame = append(frame, data[len(data)-n:]...)
+	}
+	rec.Interface = fmt.Sprintf("eth%d", ch)
+	rec.TX = dir != 0
+	rec.Packet = &canbus.Packet{Network: canbus.NetworkEthernet, Payload: frame}
+	return true
+}
+
+// parseSystemTime parses a Windows SYSTEMTIME as local time, the zero time of
+// Unix when it is zero.
+func parseSystemTime(b []byte) time.Time {
+	le := binary.LittleEndian
+	year := int(le.Uint16(b))
+	if year == 0 {
+		return time.Unix(0, 0)
+	}
+	month, day := time.Month(le.Uint16(b[2:])), int(le.Uint16(b[6:]))
+	hour, minute, sec := int(le.Uint16(b[8:])), int(le.Uint16(b[10:])), int(le.Uint16(b[12:]))
+	ms := int(le.Uint16(b[14:]))
+	return time.Date(year, month, day, hour, minute, sec, ms*int(time.Millisecond), time.Local)
+}
@@ -0,0 +1,114 @@
+// Package canlog reads and writes CAN trace files.
+package canlog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+)
+
+// CandumpWriter writes frames in the can-utils candump log format
+// ("candump -l"), which can be replayed with canplayer:
+//
+//	(1436509052.249713) vcan0 123#DEADBEEF
+type CandumpWriter struct {
+	w *bufio.Writer
+}
+
+// NewCandumpWriter returns a writer that buffers output to w. Call Flush to
+// write out buffered lines.
+func NewCandumpWriter(w io.Writer) *CandumpWriter {
+	return &CandumpWriter{w: bufio.NewWriter(w)}
+}
+
+// WriteFrame writes one log line.
+func (w *CandumpWriter) WriteFrame(ts time.Time, iface string, f canbus.Frame) error {
+	_, err := fmt.Fprintf(w.w, "(%d.%06d) %s %s\n", ts.Unix(), ts.Nanosecond()/1000, iface, f)
+	return err
+}
+
+// Flush writes buffered lines to the underlying writer.
+func (w *CandumpWriter) Flush() error {
+	return w.w.Flush()
+}
+
+// Record is one frame read from a trace file.
+type Record struct {
+	Timestamp time.Time
+	Interface string
+	// Frame is the CAN frame of the record, zero when Packet is set.
+	Frame canbus.Frame
+	// Packet is the frame of a record of another network, nil for CAN frames.
+	Packet *canbus.Packet
+	// TX is true for frames the logger marked as sent, when the format has a direction.
+	TX bool
+}
+
+// ParseCandumpLine parses one line of a candump log. A trailing direction
+// flag as written by newer can-utils versions is ignored.
+func ParseCandumpLine(line string) (Record, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return Record{}, fmt.Errorf("invalid candump line: %q", line)
+	}
+	ts := fields[0]
+	if len(ts) < 3 || ts[0] != '(' || ts[len(ts)-1] != ')' {
+		return Record{}, fmt.Errorf("invalid candump timestamp: %q", ts)
+	}
+	secPart, fracPart, _ := strings.Cut(ts[1:len(ts)-1], ".")
+	sec, err := strconv.ParseInt(secPart, 10, 64)
+	if err != nil {
+		return Record{}, fmt.Errorf("invalid candump timestamp: %q", ts)
+	}
+	var nsec int64
+	if fracPart != "" {
+		if len(fracPart) > 9 {
+			fracPart = fracPart[:9]
+		}
+		frac, err := strconv.ParseInt(fracPart, 10, 64)
+		if err != nil {
+			return Record{}, fmt.Errorf("invalid candump timestamp: %q", ts)
+		}
+		for i := len(fracPart); i < 9; i++ {
+			frac *= 10
+		}
+		nsec = frac
+	}
+	rec := Record{
+		Timestamp: time.Unix(sec, nsec),
+		Interface: fields[1],
+	}
+	if err := rec.Frame.UnmarshalString(fields[2]); err != nil {
+		return Record{}, err
+	}
+	return rec, nil
+}
+
+// ReadCandump reads every record of a candump log. Blank lines and lines
+// starting with '#' are skipped.
+func ReadCandump(r io.Reader) ([]Record, error) {
+	var records []Record
+	sc := bufio.NewScanner(r)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rec, err := ParseCandumpLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		records = append(records, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
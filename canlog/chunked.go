@@ -0,0 +1,405 @@
+package canlog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+
+	"canproject/canbus"
+)
+
+// The chunked capture format is append-only, so a crash loses at most the chunk
+// being written:
+//
+//	file   = "CANCHNK1" chunk*
+//	chunk  = "CHNK" length:u32 records:u32 crc32:u32 first:i64 last:i64 record*
+//	record = time:i64 flags:u8 ifaceLen:u8 iface id:u32 dataLen:u8 data
+//
+// in little endian, length being the size of the records, crc32 their IEEE
+// checksum and the times Unix nanoseconds. The sidecar index, at the path of
+// the file plus ".idx", has an entry per chunk written:
+//
+//	index  = "CANCIDX1" entry*
+//	entry  = offset:i64 size:u32 records:u32 first:i64 last:i64
+//
+// locating each chunk and its time span without reading the file. The file is
+// the reference: RecoverChunked rebuilds the index from its complete chunks.
+const (
+	chunkedMagic = "CANCHNK1"
+	chunkMagic   = "CHNK"
+	indexMagic   = "CANCIDX1"
+
+	chunkHeaderSize = 32
+	indexEntrySize  = 32
+
+	// ChunkSize is the size of the records a chunk holds before it is
+	// written, shorter chunks are written by Flush.
+	ChunkSize = 64 << 10
+	// maxChunkSize bounds the chunks read, against a corrupted length.
+	maxChunkSize = 64 << 20
+)
+
+// IndexPath is the path of the index of the chunked capture at path.
+func IndexPath(path string) string {
+	return path + ".idx"
+}
+
+// Record flags of the chunked format.
+const (
+	chunkFlagTX = 1 << iota
+	chunkFlagExtended
+	chunkFlagRemote
+	chunkFlagError
+	chunkFlagFD
+	chunkFlagBRS
+	chunkFlagESI
+)
+
+// ChunkInfo is an entry of the index of a chunked capture.
+type ChunkInfo struct {
+	// Offset is the position of the chunk in the file and Size its size,
+	// header included.
+	Offset  int64
+	Size    int
+	Records int
+	First   time.Time
+	Last    time.Time
+}
+
+// ChunkedWriter writes frames in the chunked capture format, with its index.
+// The frames are buffered in a chunk written when it holds ChunkSize bytes
+// or on Flush, which also syncs the file and the index to the disk.
+type ChunkedWriter struct {
+	f     *os.File
+	index *os.File
+
+	chunk   bytes.Buffer
+	records int
+	first   time.Time
+	last    time.Time
+	offset  int64
+}
+
+// NewChunkedWriter writes the file header to f and creates its index, see
+// IndexPath.
+func NewChunkedWriter(f *os.File) (*ChunkedWriter, error) {
+	index, err := os.Create(IndexPath(f.Name()))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := index.WriteString(indexMagic); err != nil {
+		_ = index.Close()
+		return nil, err
+	}
+	if _, err := f.WriteString(chunkedMagic); err != nil {
+		_ = index.Close()
+		return nil, err
+	}
+	return &ChunkedWriter{f: f, index: index, offset: int64(len(chunkedMagic))}, nil
+}
+
+// WriteFrame appends a record; tx marks frames sent by the host.
+func (w *ChunkedWriter) WriteFrame(ts time.Time, iface string, f canbus.Frame, tx bool) error {
+	if len(iface) > 255 {
+		return fmt.Errorf("interface name %q too long", iface)
+	}
+	if w.records == 0 {
+		w.first = ts
+	}
+	w.last = ts
+	w.records++
+
+	var flags byte
+	for _, b := range [...]struct {
+		set  bool
+		flag byte
+	}{{tx, chunkFlagTX}, {f.IsExtended, chunkFlagExtended}, {f.IsRemote, chunkFlagRemote}, {f.IsError, chunkFlagError},
+		{f.IsFD, chunkFlagFD}, {f.BRS, chunkFlagBRS}, {f.ESI, chunkFlagESI}} {
+		if b.set {
+			flags |= b.flag
+		}
+	}
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(ts.UnixNano()))
+	w.chunk.Write(b[:])
+	w.chunk.WriteByte(flags)
+	w.chunk.WriteByte(byte(len(iface)))
+	w.chunk.WriteString(iface)
+	binary.LittleEndian.PutUint32(b[:4], f.ID)
+	w.chunk.Write(b[:4])
+	data := f.Payload()
+	w.chunk.WriteByte(byte(len(data)))
+	w.chunk.Write(data)
+
+	if w.chunk.Len() >= ChunkSize {
+		return w.writeChunk()
+	}
+	return nil
+}
+
+// writeChunk appends the buffered records as a chunk and its index entry.
+func (w *ChunkedWriter) writeChunk() error {
+	if w.records == 0 {
+		return nil
+	}
+	payload := w.chunk.Bytes()
+	hdr := make([]byte, chunkHeaderSize, chunkHeaderSize+len(payload))
+	copy(hdr, chunkMagic)
+	binary.LittleEndian.PutUint32(hdr[4:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(hdr[8:], uint32(w.records))
+	binary.LittleEndian.PutUint32(hdr[12:], crc32.ChecksumIEEE(payload))
+	binary.LittleEndian.PutUint64(hdr[16:], uint64(w.first.UnixNano()))
+	binary.LittleEndian.PutUint64(hdr[24:], uint64(w.last.UnixNano()))
+	if _, err := w.f.Write(append(hdr, payload...)); err != nil {
+		return err
+	}
+	info := ChunkInfo{Offset: w.offset, Size: chunkHeaderSize + len(payload), Records: w.records, First: w.first, Last: w.last}
+	if _, err := w.index.Write(indexEntry(info)); err != nil {
+		return err
+	}
+	w.offset += int64(info.Size)
+	w.chunk.Reset()
+	w.records = 0
+	return nil
+}
+
+// Flush writes the buffered records as a chunk and syncs the file and the
+// index, so they survive a crash.
+func (w *ChunkedWriter) Flush() error {
+	if err := w.writeChunk(); err != nil {
+		return err
+	}
+	if err := w.f.Sync(); err != nil {
+		return err
+	}
+	return w.index.Sync()
+}
+
+// Close flushes the records and closes the index; the file is left open.
+func (w *ChunkedWriter) Close() error {
+	err := w.Flush()
+	if cerr := w.index.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func indexEntry(c ChunkInfo) []byte {
+	b := make([]byte, indexEntrySize)
+	binary.LittleEndian.PutUint64(b[0:], uint64(c.Offset))
+	binary.LittleEndian.PutUint32(b[8:], uint32(c.Size))
+	binary.LittleEndian.PutUint32(b[12:], uint32(c.Records))
+	binary.LittleEndian.PutUint64(b[16:], uint64(c.First.UnixNano()))
+	binary.LittleEndian.PutUint64(b[24:], uint64(c.Last.UnixNano()))
+	return b
+}
+
+// ReadChunkIndex reads the complete entries of an index; a torn last entry
+// is ignored.
+func ReadChunkIndex(r io.Reader) ([]ChunkInfo, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < len(indexMagic) || string(b[:len(indexMagic)]) != indexMagic {
+		return nil, errors.New("not a chunked capture index")
+	}
+	var chunks []ChunkInfo
+	for b = b[len(indexMagic):]; len(b) >= indexEntrySize; b = b[indexEntrySize:] {
+		chunks = append(chunks, ChunkInfo{
+			Offset:  int64(binary.LittleEndian.Uint64(b[0:])),
+			Size:    int(binary.LittleEndian.Uint32(b[8:])),
+			Records: int(binary.LittleEndian.Uint32(b[12:])),
+			First:   time.Unix(0, int64(binary.LittleEndian.Uint64(b[16:]))),
+			Last:    time.Unix(0, int64(binary.LittleEndian.Uint64(b[24:]))),
+		})
+	}
+	return chunks, nil
+}
+
+// ReadChunked reads the records of the complete chunks of a chunked capture.
+// A torn or corrupted chunk ends the capture, it is reported as a warning.
+func ReadChunked(r io.Reader) ([]Record, []string, error) {
+	records, _, err := readChunks(bufio.NewReader(r), nil)
+	if errors.Is(err, errTornChunk) {
+		return records, []string{err.Error()}, nil
+	}
+	return records, nil, err
+}
+
+// Recovery is the result of RecoverChunked.
+type Recovery struct {
+	Records []Record
+	Chunks  []ChunkInfo
+	// Indexed is the number of chunks the index had, Truncated the size of
+	// the torn tail removed from the file.
+	Indexed   int
+	Truncated int64
+	// Torn describes the incomplete chunk removed, empty when the file ended
+	// with a complete chunk.
+	Torn string
+}
+
+// RecoverChunked salvages a chunked capture left by a crash: it reads the
+// complete chunks, truncates the file after the last one and rebuilds the
+// index, so the capture can be read and its index trusted again.
+func RecoverChunked(path string) (Recovery, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return Recovery{}, err
+	}
+	defer f.Close()
+
+	var rec Recovery
+	if idx, err := os.Open(IndexPath(path)); err == nil {
+		chunks, _ := ReadChunkIndex(idx)
+		_ = idx.Close()
+		rec.Indexed = len(chunks)
+	}
+	records, end, err := readChunks(bufio.NewReader(f), func(c ChunkInfo) { rec.Chunks = append(rec.Chunks, c) })
+	rec.Records = records
+	if errors.Is(err, errTornChunk) {
+		rec.Torn = err.Error()
+	} else if err != nil {
+		return Recovery{}, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		return Recovery{}, err
+	}
+	if rec.Truncated = fi.Size() - end; rec.Truncated > 0 {
+		if err := f.Truncate(end); err != nil {
+			return Recovery{}, err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		return Recovery{}, err
+	}
+	return rec, writeIndex(path, rec.Chunks)
+}
+
+// writeIndex replaces the index of path with chunks.
+func writeIndex(path string, chunks []ChunkInfo) error {
+	tmp := IndexPath(path) + ".tmp"
+	b := []byte(indexMagic)
+	for _, c := range chunks {
+		b = append(b, indexEntry(c)...)
+	}
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if serr := f.Sync(); err == nil {
+		err = serr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, IndexPath(path))
+}
+
+// errTornChunk is wrapped by the errors of the chunks that are incomplete or
+// fail their checksum, the end of a capture cut by a crash.
+var errTornChunk = errors.New("incomplete chunk")
+
+// readChunks reads the complete chunks of br, calling chunk for each, and
+// returns their records and the end offset of the last one.
+func readChunks(br *bufio.Reader, chunk func(ChunkInfo)) ([]Record, int64, error) {
+	magic := make([]byte, len(chunkedMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != chunkedMagic {
+		return nil, 0, errors.New("not a chunked capture")
+	}
+	offset := int64(len(chunkedMagic))
+	var records []Record
+	hdr := make([]byte, chunkHeaderSize)
+	for {
+		n, err := io.ReadFull(br, hdr)
+		if err == io.EOF {
+			return records, offset, nil
+		}
+		if err != nil {
+			return records, offset, fmt.Errorf("%w at offset %d: header of %d bytes", errTornChunk, offset, n)
+		}
+		if string(hdr[:4]) != chunkMagic {
+			return records, offset, fmt.Errorf("%w at offset %d: bad magic", errTornChunk, offset)
+		}
+		size := binary.LittleEndian.Uint32(hdr[4:])
+		if size > maxChunkSize {
+			return records, offset, fmt.Errorf("%w at offset %d: length %d", errTornChunk, offset, size)
+		}
+		payload := make([]byte, size)
+		if n, err := io.ReadFull(br, payload); err != nil {
+			return records, offset, fmt.Errorf("%w at offset %d: %d of %d bytes", errTornChunk, offset, n, size)
+		}
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(hdr[12:]) {
+			return records, offset, fmt.Errorf("%w at offset %d: checksum mismatch", errTornChunk, offset)
+		}
+		recs, err := decodeChunk(payload)
+		if err != nil || len(recs) != int(binary.LittleEndian.Uint32(hdr[8:])) {
+			return records, offset, fmt.Errorf("%w at offset %d: invalid records", errTornChunk, offset)
+		}
+		records = append(records, recs...)
+		if chunk != nil {
+			chunk(ChunkInfo{
+				Offset:  offset,
+				Size:    chunkHeaderSize + int(size),
+				Records: len(recs),
+				First:   time.Unix(0, int64(binary.LittleEndian.Uint64(hdr[16:]))),
+				Last:    time.Unix(0, int64(binary.LittleEndian.Uint64(hdr[24:]))),
+			})
+		}
+		offset += chunkHeaderSize + int64(size)
+	}
+}
+
+func decodeChunk(b []byte) ([]Record, error) {
+	var records []Record
+	for len(b) > 0 {
+		if len(b) < 10 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		ts := time.Unix(0, int64(binary.LittleEndian.Uint64(b)))
+		flags := b[8]
+		n := int(b[9])
+		b = b[10:]
+		if len(b) < n+5 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		iface := string(b[:n])
+		id := binary.LittleEndian.Uint32(b[n:])
+		dlen := int(b[n+4])
+		b = b[n+5:]
+		if len(b) < dlen {
+			return nil, io.ErrUnexpectedEOF
+		}
+		f := canbus.Frame{
+			ID:         id,
+			IsExtended: flags&chunkFlagExtended != 0,
+			IsRemote:   flags&chunkFlagRemote != 0,
+			IsError:    flags&chunkFlagError != 0,
+			IsFD:       flags&chunkFlagFD != 0,
+			BRS:        flags&chunkFlagBRS != 0,
+			ESI:        flags&chunkFlagESI != 0,
+			Length:     uint8(dlen),
+		}
+		if dlen > canbus.MaxFDDataLength {
+			return nil, fmt.Errorf("record of %d bytes", dlen)
+		}
+		copy(f.Data[:], b[:dlen])
+		b = b[dlen:]
+		records = append(records, Record{Timestamp: ts, Interface: iface, Frame: f, TX: flags&chunkFlagTX != 0})
+	}
+	return records, nil
+}
@@ -0,0 +1,124 @@
+package canlog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"canproject/canbus"
+)
+
+// pcapng block types and options.
+const (
+	blockSectionHeader  = 0x0a0d0d0a
+	blockInterfaceDesc  = 0x00000001
+	blockEnhancedPacket = 0x00000006
+	byteOrderMagic      = 0x1a2b3c4d
+
+	optEndOfOpt  = 0
+	optIfName    = 2
+	optIfTsresol = 9
+	optEpbFlags  = 2
+
+	// epb_flags direction bits.
+	epbInbound  = 1
+	epbOutbound = 2
+
+	// linkTypeCANSocketCAN is LINKTYPE_CAN_SOCKETCAN: struct can(fd)_frame with can_id in big endian.
+	linkTypeCANSocketCAN = 227
+)
+
+// PcapngWriter writes frames to a pcapng capture with the SocketCAN link type,
+// which Wireshark dissects with its CAN dissectors. Each interface gets an
+// interface description block; the direction is stored in the packet flags.
+type PcapngWriter struct {
+	w      *bufio.Writer
+	ifaces map[string]uint32
+}
+
+// NewPcapngWriter writes the section header to w and returns a writer that
+// buffers output. Call Flush to write out buffered blocks.
+func NewPcapngWriter(w io.Writer) (*PcapngWriter, error) {
+	pw := &PcapngWriter{w: bufio.NewWriter(w), ifaces: make(map[string]uint32)}
+	le := binary.LittleEndian
+	body := le.AppendUint32(nil, byteOrderMagic)
+	body = le.AppendUint16(body, 1)          // major version
+	body = le.AppendUint16(body, 0)          // minor version
+	body = le.AppendUint64(body, ^uint64(0)) // section length not specified
+	body = appendOption(body, optEndOfOpt, nil)
+	if err := pw.writeBlock(blockSectionHeader, body); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// WriteFrame writes an enhanced packet block; tx marks frames sent by the host.
+func (w *PcapngWriter) WriteFrame(ts time.Time, iface string, f canbus.Frame, tx bool) error {
+	id, ok := w.ifaces[iface]
+	if !ok {
+		id = uint32(len(w.ifaces))
+		if err := w.writeInterface(iface); err != nil {
+			return err
+		}
+		w.ifaces[iface] = id
+	}
+
+	le := binary.LittleEndian
+	packet := f.MarshalSocketCAN(binary.BigEndian)
+	nanos := uint64(ts.UnixNano())
+	body := le.AppendUint32(nil, id)
+	body = le.AppendUint32(body, uint32(nanos>>32))
+	body = le.AppendUint32(body, uint32(nanos))
+	body = le.AppendUint32(body, uint32(len(packet)))
+	body = le.AppendUint32(body, uint32(len(packet)))
+	// both frame layouts are a multiple of 32 bits, no padding needed
+	body = append(body, packet...)
+	flags := uint32(epbInbound)
+	if tx {
+		flags = epbOutbound
+	}
+	body = appendOption(body, optEpbFlags, le.AppendUint32(nil, flags))
+	body = appendOption(body, optEndOfOpt, nil)
+	return w.writeBlock(blockEnhancedPacket, body)
+}
+
+// Flush writes buffered blocks to the underlying writer.
+func (w *PcapngWriter) Flush() error {
+	return w.w.Flush()
+}
+
+func (w *PcapngWriter) writeInterface(iface string) error {
+	le := binary.LittleEndian
+	body := le.AppendUint16(nil, linkTypeCANSocketCAN)
+	body = le.AppendUint16(body, 0)  // reserved
+	body = le.AppendUint32(body, 72) // snap length, a struct canfd_frame
+	body = appendOption(body, optIfName, []byte(iface))
+	// timestamps in nanoseconds
+	body = appendOption(body, optIfTsresol, []byte{9})
+	body = appendOption(body, optEndOfOpt, nil)
+	return w.writeBlock(blockInterfaceDesc, body)
+}
+
+func (w *PcapngWriter) writeBlock(blockType uint32, body []byte) error {
+	le := binary.LittleEndian
+	total := uint32(12 + len(body))
+	b := le.AppendUint32(nil, blockType)
+	b = le.AppendUint32(b, total)
+	b = append(b, body...)
+	b = le.AppendUint32(b, total)
+	_, err := w.w.Write(b)
+	return err
+}
+
+// appendOption appends a pcapng option padded to 32 bits.
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	le := binary.LittleEndian
+	b = le.AppendUint16(b, code)
+	b = le.AppendUint16(b, uint16(len(value)))
+	b = append(b, value...)
+	for n := len(value); n%4 != 0; n++ {
+		b = append(b, 0)
+	}
+	return b
+}
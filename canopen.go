@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+	"canproject/canopen"
+)
+
+// CANopenEvent is a decoded CANopen frame emitted on "canopen:message".
+type CANopenEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	ID        uint32    `json:"id"`
+	// Kind is NMT, SYNC, EMCY, TIME, TPDO, RPDO, SDO request, SDO response, Heartbeat or unknown.
+	Kind string `json:"kind"`
+	Node uint8  `json:"node"`
+	// PDO is the PDO number 1..4 of TPDO and RPDO messages.
+	PDO int `json:"pdo,omitempty"`
+	// Command is the NMT command, State the heartbeat state.
+	Command string `json:"command,omitempty"`
+	State   string `json:"state,omitempty"`
+	// ErrorCode and ErrorRegister are set for emergency messages.
+	ErrorCode     uint16 `json:"errorCode,omitempty"`
+	ErrorRegister uint8  `json:"errorRegister,omitempty"`
+	// Index and Subindex are set for SDO initiate and abort frames.
+	Index    uint16   `json:"index,omitempty"`
+	Subindex uint8    `json:"subindex,omitempty"`
+	Data     []uint32 `json:"data"`
+	// Values are the objects mapped into a PDO, decoded with the object dictionary
+	// loaded for the node.
+	Values []CANopenValue `json:"values,omitempty"`
+}
+
+// CANopenValue is a typed value of an object dictionary entry.
+type CANopenValue struct {
+	Name     string `json:"name"`
+	Index    uint16 `json:"index"`
+	Subindex uint8  `json:"subindex"`
+	DataType string `json:"dataType"`
+	// Text is the value as text, Number the value of numeric types.
+	Text    string  `json:"text"`
+	Number  float64 `json:"number"`
+	Numeric bool    `json:"numeric"`
+}
+
+// EDSInfo describes an object dictionary loaded with LoadEDS.
+type EDSInfo struct {
+	Interface string `json:"interface"`
+	Node      uint8  `json:"node"`
+	File      string `json:"file"`
+	Device    string `json:"device"`
+	Vendor    string `json:"vendor"`
+	Objects   int    `json:"objects"`
+}
+
+// CANopenObjectInfo is an entry of a loaded object dictionary.
+type CANopenObjectInfo struct {
+	Index      uint16 `json:"index"`
+	Subindex   uint8  `json:"subindex"`
+	Name       string `json:"name"`
+	DataType   string `json:"dataType"`
+	Access     string `json:"access"`
+	PDOMapping bool   `json:"pdoMapping"`
+	Default    string `json:"default"`
+}
+
+// CANopenNodeEvent is emitted on "canopen:node" when the NMT state of a node changes.
+type CANopenNodeEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	Node      uint8     `json:"node"`
+	State     string    `json:"state"`
+	// Previous is the state of the previous heartbeat, empty for the first one.
+	Previous string `json:"previous"`
+}
+
+// CANopenNodeInfo is a node seen on a bus with CANopen decoding enabled.
+type CANopenNodeInfo struct {
+	Node     uint8     `json:"node"`
+	State    string    `json:"state"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// canopenNodes tracks the NMT state of the nodes of a bus.
+type canopenNodes struct {
+	mu    sync.Mutex
+	nodes map[uint8]CANopenNodeInfo
+}
+
+type sdoKey struct {
+	iface string
+	node  uint8
+}
+
+// SetCANopenDecoding enables or disables CANopen decoding of the frames received on a
+// started interface. Decoded frames are emitted on "canopen:message" and the NMT state
+// changes reported by heartbeats on "canopen:node".
+func (a *App) SetCANopenDecoding(iface string, enabled bool) error {
+	iface = strings.TrimSpace(iface)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sess := a.sessions[iface]
+	if sess == nil || sess.conn == nil {
+		return fmt.Errorf("CAN not started on %s", iface)
+	}
+	if !enabled {
+		sess.canopen.Store(nil)
+	} else if sess.canopen.Load() == nil {
+		sess.canopen.Store(&canopenNodes{nodes: make(map[uint8]CANopenNodeInfo)})
+	}
+	return nil
+}
+
+// GetCANopenNodes returns the nodes that sent a heartbeat since CANopen decoding was
+// enabled on iface.
+func (a *App) GetCANopenNodes(iface string) ([]CANopenNodeInfo, error) {
+	sess, err := a.session(iface)
+	if err != nil {
+		return nil, err
+	}
+	n := sess.canopen.Load()
+	if n == nil {
+		return nil, fmt.Errorf("CANopen decoding is not enabled on %s", sess.iface)
+	}
+	n.mu.Lock()
+	infos := make([]CANopenNodeInfo, 0, len(n.nodes))
+	for _, info := range n.nodes {
+		infos = append(infos, info)
+	}
+	n.mu.Unlock()
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Node < infos[j].Node })
+	return infos, nil
+}
+
+// SendNMT sends an NMT command (start, stop, pre-operational, reset node or reset
+// communication) to node, or to all nodes when node is 0.
+func (a *App) SendNMT(iface string, command string, node uint8) error {
+	cmd, err := canopen.ParseNMTCommand(strings.ToLower(strings.TrimSpace(command)))
+	if err != nil {
+		return err
+	}
+	f, err := canopen.NMTFrame(cmd, node)
+	if err != nil {
+		return err
+	}
+	return a.transmit(strings.TrimSpace(iface), f)
+}
+
+// ReadSDO reads the object index:subindex of a node with an SDO upload. The server
+// chooses between an expedited and a segmented transfer.
+func (a *App) ReadSDO(iface string, node uint8, index uint16, subindex uint8) ([]uint32, error) {
+	c, err := a.sdoClient(strings.TrimSpace(iface), node)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.Upload(context.Background(), index, subindex)
+	if err != nil {
+		return nil, err
+	}
+	return dataWords(data), nil
+}
+
+// WriteSDO writes data to the object index:subindex of a node with an SDO download,
+// expedited up to 4 bytes and segmented for more.
+func (a *App) WriteSDO(iface string, node uint8, index uint16, subindex uint8, data []byte) error {
+	c, err := a.sdoClient(strings.TrimSpace(iface), node)
+	if err != nil {
+		return err
+	}
+	return c.Download(context.Background(), index, subindex, data)
+}
+
+// LoadEDS loads the EDS or DCF file of a node, so its objects can be read and written
+// by name with ReadSDOByName and WriteSDOByName and its PDOs are decoded. node 0 takes
+// the node ID of a DCF. It replaces the dictionary loaded for the node before.
+func (a *App) LoadEDS(iface string, node uint8, path string) (EDSInfo, error) {
+	iface = strings.TrimSpace(iface)
+	od, err := canopen.LoadEDS(strings.TrimSpace(path))
+	if err != nil {
+		return EDSInfo{}, err
+	}
+	if node == 0 {
+		node = od.NodeID
+	}
+	if node < 1 || node > canopen.MaxNodeID {
+		return EDSInfo{}, fmt.Errorf("node ID must be within 1..%d (got %d)", canopen.MaxNodeID, node)
+	}
+
+	a.canopenMu.Lock()
+	a.dictionaries[sdoKey{iface, node}] = od
+	a.canopenMu.Unlock()
+	return EDSInfo{
+		Interface: iface,
+		Node:      node,
+		File:      od.File,
+		Device:    od.DeviceName,
+		Vendor:    od.VendorName,
+		Objects:   len(od.Entries),
+	}, nil
+}
+
+// UnloadEDS removes the object dictionary of a node.
+func (a *App) UnloadEDS(iface string, node uint8) error {
+	key := sdoKey{strings.TrimSpace(iface), node}
+	a.canopenMu.Lock()
+	defer a.canopenMu.Unlock()
+	if a.dictionaries[key] == nil {
+		return fmt.Errorf("no EDS loaded for node %d on %s", node, key.iface)
+	}
+	delete(a.dictionaries, key)
+	return nil
+}
+
+// ListCANopenObjects returns the entries of the object dictionary of a node.
+func (a *App) ListCANopenObjects(iface string, node uint8) ([]CANopenObjectInfo, error) {
+	od, err := a.dictionary(strings.TrimSpace(iface), node)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]CANopenObjectInfo, len(od.Entries))
+	for i, e := range od.Entries {
+		infos[i] = CANopenObjectInfo{
+			Index:      e.Index,
+			Subindex:   e.Subindex,
+			Name:       e.Name,
+			DataType:   e.DataType.String(),
+			Access:     e.Access,
+			PDOMapping: e.PDOMapping,
+			Default:    e.Default,
+		}
+	}
+	return infos, nil
+}
+
+// ReadSDOByName reads an object of a node by the parameter name of its EDS, eg
+// "Identity object.Vendor-ID", or by address ("1018:1"), and decodes it by its data type.
+func (a *App) ReadSDOByName(iface string, node uint8, name string) (CANopenValue, error) {
+	iface = strings.TrimSpace(iface)
+	e, err := a.lookupObject(iface, node, name)
+	if err != nil {
+		return CANopenValue{}, err
+	}
+	if e.Access == "wo" {
+		return CANopenValue{}, fmt.Errorf("%s is write only", e.Name)
+	}
+	c, err := a.sdoClient(iface, node)
+	if err != nil {
+		return CANopenValue{}, err
+	}
+	data, err := c.Upload(context.Background(), e.Index, e.Subindex)
+	if err != nil {
+		return CANopenValue{}, err
+	}
+	v, err := e.DataType.Decode(data)
+	if err != nil {
+		return CANopenValue{}, fmt.Errorf("%s: %w", e.Name, err)
+	}
+	return canopenValue(e, v), nil
+}
+
+// WriteSDOByName writes an object of a node addressed as in ReadSDOByName. value is
+// converted by the data type of the object: numbers in decimal or 0x hex, booleans as
+// true or false and octet strings as hex bytes.
+func (a *App) WriteSDOByName(iface string, node uint8, name string, value string) error {
+	iface = strings.TrimSpace(iface)
+	e, err := a.lookupObject(iface, node, name)
+	if err != nil {
+		return err
+	}
+	if e.Access == "ro" || e.Access == "const" {
+		return fmt.Errorf("%s is read only", e.Name)
+	}
+	data, err := e.DataType.Encode(value)
+	if err != nil {
+		return err
+	}
+	c, err := a.sdoClient(iface, node)
+	if err != nil {
+		return err
+	}
+	return c.Download(context.Background(), e.Index, e.Subindex, data)
+}
+
+func (a *App) dictionary(iface string, node uint8) (*canopen.ObjectDictionary, error) {
+	a.canopenMu.Lock()
+	od := a.dictionaries[sdoKey{iface, node}]
+	a.canopenMu.Unlock()
+	if od == nil {
+		return nil, fmt.Errorf("no EDS loaded for node %d on %s", node, iface)
+	}
+	return od, nil
+}
+
+func (a *App) lookupObject(iface string, node uint8, name string) (*canopen.Entry, error) {
+	od, err := a.dictionary(iface, node)
+	if err != nil {
+		return nil, err
+	}
+	return od.Lookup(name)
+}
+
+func canopenValue(e *canopen.Entry, v canopen.Value) CANopenValue {
+	return CANopenValue{
+		Name:     e.Name,
+		Index:    e.Index,
+		Subindex: e.Subindex,
+		DataType: e.DataType.String(),
+		Text:     v.Text,
+		Number:   v.Number,
+		Numeric:  v.Numeric,
+	}
+}
+
+// sdoClient returns the SDO client of node on a started interface.
+func (a *App) sdoClient(iface string, node uint8) (*canopen.SDOClient, error) {
+	if node < 1 || node > canopen.MaxNodeID {
+		return nil, fmt.Errorf("node ID must be within 1..%d (got %d)", canopen.MaxNodeID, node)
+	}
+	if _, err := a.txConn(iface, false); err != nil {
+		return nil, err
+	}
+
+	a.canopenMu.Lock()
+	defer a.canopenMu.Unlock()
+	key := sdoKey{iface, node}
+	c := a.sdoClients[key]
+	if c == nil {
+		c = &canopen.SDOClient{
+			Node: node,
+			Send: func(f canbus.Frame) error { return a.transmit(iface, f) },
+		}
+		a.sdoClients[key] = c
+	}
+	return c, nil
+}
+
+// dispatchCANopen passes SDO responses to the clients and decodes the frames of a
+// session with CANopen decoding enabled.
+func (a *App) dispatchCANopen(sess *canSession, ts time.Time, f *canbus.Frame) {
+	if !f.IsExtended && f.ID&^0x7f == canopen.FuncSDOTx {
+		a.canopenMu.Lock()
+		c := a.sdoClients[sdoKey{sess.iface, uint8(f.ID & 0x7f)}]
+		a.canopenMu.Unlock()
+		if c != nil {
+			c.HandleFrame(f)
+		}
+	}
+
+	nodes := sess.canopen.Load()
+	if nodes == nil {
+		return
+	}
+	m, ok := canopen.Decode(f)
+	if !ok {
+		return
+	}
+	ev := CANopenEvent{
+		Timestamp:     ts,
+		Interface:     sess.iface,
+		ID:            f.ID,
+		Kind:          string(m.Kind),
+		Node:          m.Node,
+		PDO:           m.PDO,
+		ErrorCode:     m.ErrorCode,
+		ErrorRegister: m.ErrorRegister,
+		Index:         m.Index,
+		Subindex:      m.Subindex,
+		Data:          dataWords(m.Data),
+	}
+	switch m.Kind {
+	case canopen.KindNMT:
+		ev.Command = m.Command.String()
+	case canopen.KindHeartbeat:
+		ev.State = m.State.String()
+	case canopen.KindTPDO, canopen.KindRPDO:
+		if od, err := a.dictionary(sess.iface, m.Node); err == nil {
+			for _, mv := range od.DecodePDO(m.Kind == canopen.KindTPDO, m.PDO, m.Data) {
+				ev.Values = append(ev.Values, canopenValue(mv.Entry, mv.Value))
+			}
+		}
+	}
+	a.emit("canopen:message", ev)
+
+	if m.Kind == canopen.KindHeartbeat {
+		nodes.mu.Lock()
+		prev, seen := nodes.nodes[m.Node]
+		nodes.nodes[m.Node] = CANopenNodeInfo{Node: m.Node, State: ev.State, LastSeen: ts}
+		nodes.mu.Unlock()
+		if !seen || prev.State != ev.State || m.State == canopen.StateBootUp {
+			a.emit("canopen:node", CANopenNodeEvent{
+				Timestamp: ts,
+				Interface: sess.iface,
+				Node:      m.Node,
+				State:     ev.State,
+				Previous:  prev.State,
+			})
+		}
+	}
+}
+
+// closeSDOClients drops the SDO clients of iface.
+func (a *App) closeSDOClients(iface string) {
+	a.canopenMu.Lock()
+	defer a.canopenMu.Unlock()
+	for key := range a.sdoClients {
+		if key.iface == iface {
+			delete(a.sdoClients, key)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// Package canopen decodes CiA 301 CANopen frames by their COB-ID (NMT, SYNC,
+// emergency, time stamp, PDOs, SDOs and heartbeats), builds NMT commands,
+// implements an SDO client for expedited and segmented transfers and parses the
+// object dictionaries of EDS and DCF files.
+package canopen
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"canproject/canbus"
+)
+
+// Function codes of the predefined connection set, the upper 4 bits of the 11-bit COB-ID.
+const (
+	FuncNMT       = 0x000
+	FuncSync      = 0x080 // SYNC with node 0, EMCY otherwise
+	FuncTime      = 0x100
+	FuncTPDO1     = 0x180
+	FuncRPDO1     = 0x200
+	FuncTPDO2     = 0x280
+	FuncRPDO2     = 0x300
+	FuncTPDO3     = 0x380
+	FuncRPDO3     = 0x400
+	FuncTPDO4     = 0x480
+	FuncRPDO4     = 0x500
+	FuncSDOTx     = 0x580 // server to client
+	FuncSDORx     = 0x600 // client to server
+	FuncHeartbeat = 0x700
+)
+
+// MaxNodeID is the highest CANopen node ID.
+const MaxNodeID = 127
+
+// Kind is the type of a CANopen message.
+type Kind string
+
+// Message kinds of Decode.
+const (
+	KindNMT         Kind = "NMT"
+	KindSync        Kind = "SYNC"
+	KindEmergency   Kind = "EMCY"
+	KindTime        Kind = "TIME"
+	KindTPDO        Kind = "TPDO"
+	KindRPDO        Kind = "RPDO"
+	KindSDOResponse Kind = "SDO response"
+	KindSDORequest  Kind = "SDO request"
+	KindHeartbeat   Kind = "Heartbeat"
+	KindUnknown     Kind = "unknown"
+)
+
+// NMTCommand is the command specifier of an NMT frame.
+type NMTCommand uint8
+
+// NMT commands.
+const (
+	NMTStart              NMTCommand = 0x01
+	NMTStop               NMTCommand = 0x02
+	NMTPreOperational     NMTCommand = 0x80
+	NMTResetNode          NMTCommand = 0x81
+	NMTResetCommunication NMTCommand = 0x82
+)
+
+func (c NMTCommand) String() string {
+	switch c {
+	case NMTStart:
+		return "start"
+	case NMTStop:
+		return "stop"
+	case NMTPreOperational:
+		return "pre-operational"
+	case NMTResetNode:
+		return "reset node"
+	case NMTResetCommunication:
+		return "reset communication"
+	}
+	return fmt.Sprintf("0x%02X", uint8(c))
+}
+
+// ParseNMTCommand parses a command name as returned by NMTCommand.String.
+func ParseNMTCommand(s string) (NMTCommand, error) {
+	for _, c := range []NMTCommand{NMTStart, NMTStop, NMTPreOperational, NMTResetNode, NMTResetCommunication} {
+		if c.String() == s {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("canopen: unknown NMT command %q, want start, stop, pre-operational, reset node or reset communication", s)
+}
+
+// State is the NMT state reported by heartbeats.
+type State uint8
+
+// NMT states.
+const (
+	StateBootUp         State = 0x00
+	StateStopped        State = 0x04
+	StateOperational    State = 0x05
+	StatePreOperational State = 0x7f
+)
+
+func (s State) String() string {
+	switch s {
+	case StateBootUp:
+		return "boot-up"
+	case StateStopped:
+		return "stopped"
+	case StateOperational:
+		return "operational"
+	case StatePreOperational:
+		return "pre-operational"
+	}
+	return fmt.Sprintf("0x%02X", uint8(s))
+}
+
+// Message is a decoded CANopen frame.
+type Message struct {
+	Kind Kind
+	// Node is the node ID of the COB-ID, or the addressed node of NMT commands (0 for all).
+	Node uint8
+	// PDO is the PDO number 1..4 of TPDO and RPDO messages.
+	PDO int
+
+	// Command is set for NMT messages.
+	Command NMTCommand
+	// State is set for heartbeats.
+	State State
+	// ErrorCode, ErrorRegister and ManufacturerData are set for emergency messages.
+	ErrorCode        uint16
+	ErrorRegister    uint8
+	ManufacturerData []byte
+	// Index and Subindex are set for SDO initiate and abort messages.
+	Index    uint16
+	Subindex uint8
+	// Data is the payload.
+	Data []byte
+}
+
+// Decode decodes a frame of the predefined connection set. Extended frames and
+// error frames are not CANopen frames.
+func Decode(f *canbus.Frame) (Message, bool) {
+	if f.IsExtended || f.IsError || f.ID > 0x7ff {
+		return Message{}, false
+	}
+	data := f.Payload()
+	fn := f.ID &^ 0x7f
+	node := uint8(f.ID & 0x7f)
+	m := Message{Node: node, Data: append([]byte(nil), data...)}
+	switch {
+	case f.ID == FuncNMT:
+		if len(data) < 2 {
+			return Message{}, false
+		}
+		m.Kind, m.Command, m.Node = KindNMT, NMTCommand(data[0]), data[1]
+	case f.ID == FuncSync:
+		m.Kind = KindSync
+	case fn == FuncSync:
+		m.Kind = KindEmergency
+		if len(data) >= 3 {
+			m.ErrorCode = binary.LittleEndian.Uint16(data)
+			m.ErrorRegister = data[2]
+			m.ManufacturerData = m.Data[3:]
+		}
+	case f.ID == FuncTime:
+		m.Kind = KindTime
+	case node == 0:
+		m.Kind = KindUnknown
+	case fn == FuncTPDO1 || fn == FuncTPDO2 || fn == FuncTPDO3 || fn == FuncTPDO4:
+		m.Kind, m.PDO = KindTPDO, int((fn-FuncTPDO1)/0x100)+1
+	case fn == FuncRPDO1 || fn == FuncRPDO2 || fn == FuncRPDO3 || fn == FuncRPDO4:
+		m.Kind, m.PDO = KindRPDO, int((fn-FuncRPDO1)/0x100)+1
+	case fn == FuncSDOTx || fn == FuncSDORx:
+		m.Kind = KindSDOResponse
+		if fn == FuncSDORx {
+			m.Kind = KindSDORequest
+		}
+		if len(data) >= 4 && sdoHasIndex(data[0], fn == FuncSDORx) {
+			m.Index = binary.LittleEndian.Uint16(data[1:])
+			m.Subindex = data[3]
+		}
+	case fn == FuncHeartbeat:
+		if len(data) < 1 {
+			return Message{}, false
+		}
+		m.Kind, m.State = KindHeartbeat, State(data[0]&0x7f)
+	default:
+		m.Kind = KindUnknown
+	}
+	return m, true
+}
+
+// NMTFrame returns the NMT frame sending command to node, 0 for all nodes.
+func NMTFrame(command NMTCommand, node uint8) (canbus.Frame, error) {
+	if node > MaxNodeID {
+		return canbus.Frame{}, fmt.Errorf("canopen: node ID must be within 0..%d (got %d)", MaxNodeID, node)
+	}
+	f := canbus.Frame{ID: FuncNMT, Length: 2}
+	f.Data[0], f.Data[1] = byte(command), node
+	return f, nil
+}
+
+// sdoHasIndex reports whether an SDO frame with command byte cmd carries an index:
+// initiate and abort frames do, segments do not.
+func sdoHasIndex(cmd byte, request bool) bool {
+	cs := cmd >> 5
+	if cs == csAbort {
+		return true
+	}
+	if request {
+		return cs == ccsInitiateDownload || cs == ccsInitiateUpload
+	}
+	return cs == scsInitiateDownload || cs == scsInitiateUpload
+}
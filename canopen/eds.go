@@ -0,0 +1,564 @@
+package canopen
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DataType is a CiA 301 data type index.
+type DataType uint16
+
+// Data types of object dictionary entries.
+const (
+	Boolean       DataType = 0x01
+	Integer8      DataType = 0x02
+	Integer16     DataType = 0x03
+	Integer32     DataType = 0x04
+	Unsigned8     DataType = 0x05
+	Unsigned16    DataType = 0x06
+	Unsigned32    DataType = 0x07
+	Real32        DataType = 0x08
+	VisibleString DataType = 0x09
+	OctetString   DataType = 0x0a
+	UnicodeString DataType = 0x0b
+	Domain        DataType = 0x0f
+	Integer24     DataType = 0x10
+	Real64        DataType = 0x11
+	Integer40     DataType = 0x12
+	Integer48     DataType = 0x13
+	Integer56     DataType = 0x14
+	Integer64     DataType = 0x15
+	Unsigned24    DataType = 0x16
+	Unsigned40    DataType = 0x18
+	Unsigned48    DataType = 0x19
+	Unsigned56    DataType = 0x1a
+	Unsigned64    DataType = 0x1b
+)
+
+var dataTypeNames = map[DataType]string{
+	Boolean:       "BOOLEAN",
+	Integer8:      "INTEGER8",
+	Integer16:     "INTEGER16",
+	Integer24:     "INTEGER24",
+	Integer32:     "INTEGER32",
+	Integer40:     "INTEGER40",
+	Integer48:     "INTEGER48",
+	Integer56:     "INTEGER56",
+	Integer64:     "INTEGER64",
+	Unsigned8:     "UNSIGNED8",
+	Unsigned16:    "UNSIGNED16",
+	Unsigned24:    "UNSIGNED24",
+	Unsigned32:    "UNSIGNED32",
+	Unsigned40:    "UNSIGNED40",
+	Unsigned48:    "UNSIGNED48",
+	Unsigned56:    "UNSIGNED56",
+	Unsigned64:    "UNSIGNED64",
+	Real32:        "REAL32",
+	Real64:        "REAL64",
+	VisibleString: "VISIBLE_STRING",
+	OctetString:   "OCTET_STRING",
+	UnicodeString: "UNICODE_STRING",
+	Domain:        "DOMAIN",
+}
+
+func (t DataType) String() string {
+	if s, ok := dataTypeNames[t]; ok {
+		return s
+	}
+	return fmt.Sprintf("0x%04X", uint16(t))
+}
+
+// Size returns the size in bytes of fixed size types, 0 for strings and domains.
+func (t DataType) Size() int {
+	switch t {
+	case Boolean, Integer8, Unsigned8:
+		return 1
+	case Integer16, Unsigned16:
+		return 2
+	case Integer24, Unsigned24:
+		return 3
+	case Integer32, Unsigned32, Real32:
+		return 4
+	case Integer40, Unsigned40:
+		return 5
+	case Integer48, Unsigned48:
+		return 6
+	case Integer56, Unsigned56:
+		return 7
+	case Integer64, Unsigned64, Real64:
+		return 8
+	}
+	return 0
+}
+
+func (t DataType) signed() bool {
+	switch t {
+	case Integer8, Integer16, Integer24, Integer32, Integer40, Integer48, Integer56, Integer64:
+		return true
+	}
+	return false
+}
+
+// Value is a typed value of an object dictionary entry.
+type Value struct {
+	// Text is the value as text: strings as is, octet strings and domains as hex
+	// bytes and numbers in decimal.
+	Text string
+	// Number is the value of numeric types, Numeric is false for the others.
+	Number  float64
+	Numeric bool
+}
+
+// Decode converts data transferred by SDO or mapped into a PDO.
+func (t DataType) Decode(data []byte) (Value, error) {
+	switch t {
+	case VisibleString:
+		return Value{Text: string(bytes.TrimRight(data, "\x00"))}, nil
+	case OctetString, Domain, UnicodeString:
+		return Value{Text: fmt.Sprintf("% X", data)}, nil
+	}
+	size := t.Size()
+	if size == 0 {
+		return Value{Text: fmt.Sprintf("% X", data)}, nil
+	}
+	if len(data) < size {
+		return Value{}, fmt.Errorf("canopen: %d bytes for a %s", len(data), t)
+	}
+	var buf [8]byte
+	copy(buf[:], data[:size])
+	bits := binary.LittleEndian.Uint64(buf[:])
+	switch t {
+	case Real32:
+		v := float64(math.Float32frombits(uint32(bits)))
+		return Value{Number: v, Numeric: true, Text: strconv.FormatFloat(v, 'g', -1, 32)}, nil
+	case Real64:
+		v := math.Float64frombits(bits)
+		return Value{Number: v, Numeric: true, Text: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case Boolean:
+		return Value{Number: float64(bits & 1), Numeric: true, Text: strconv.FormatBool(bits&1 != 0)}, nil
+	}
+	if t.signed() {
+		shift := 64 - 8*size
+		v := int64(bits<<shift) >> shift
+		return Value{Number: float64(v), Numeric: true, Text: strconv.FormatInt(v, 10)}, nil
+	}
+	return Value{Number: float64(bits), Numeric: true, Text: strconv.FormatUint(bits, 10)}, nil
+}
+
+// Encode converts a value given as text, eg "1500", "0x1F" or "true", to the bytes of an SDO
+// download. Octet strings and domains are given as hex bytes.
+func (t DataType) Encode(s string) ([]byte, error) {
+	switch t {
+	case VisibleString:
+		return []byte(s), nil
+	case OctetString, Domain:
+		b, err := parseHexBytes(s)
+		if err != nil {
+			return nil, fmt.Errorf("canopen: invalid %s %q: %w", t, s, err)
+		}
+		return b, nil
+	}
+	size := t.Size()
+	if size == 0 {
+		return nil, fmt.Errorf("canopen: cannot encode a %s", t)
+	}
+	s = strings.TrimSpace(s)
+	var bits uint64
+	switch t {
+	case Real32:
+		v, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return nil, fmt.Errorf("canopen: invalid %s %q", t, s)
+		}
+		bits = uint64(math.Float32bits(float32(v)))
+	case Real64:
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("canopen: invalid %s %q", t, s)
+		}
+		bits = math.Float64bits(v)
+	case Boolean:
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("canopen: invalid %s %q", t, s)
+		}
+		if v {
+			bits = 1
+		}
+	default:
+		if t.signed() {
+			v, err := strconv.ParseInt(s, 0, 8*size)
+			if err != nil {
+				return nil, fmt.Errorf("canopen: invalid %s %q", t, s)
+			}
+			bits = uint64(v)
+		} else {
+			v, err := strconv.ParseUint(s, 0, 8*size)
+			if err != nil {
+				return nil, fmt.Errorf("canopen: invalid %s %q", t, s)
+			}
+			bits = v
+		}
+	}
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], bits)
+	return buf[:size], nil
+}
+
+// Entry is a variable of an object dictionary, a VAR object or a sub-index of an
+// ARRAY or RECORD object.
+type Entry struct {
+	Index    uint16
+	Subindex uint8
+	// Name is the parameter name, for sub-indices prefixed with the object name and a
+	// dot, eg "Identity object.Vendor-ID".
+	Name     string
+	DataType DataType
+	// Access is ro, wo, rw, rwr, rww or const.
+	Access     string
+	PDOMapping bool
+	// Default is the DefaultValue of an EDS, or the ParameterValue of a DCF if set,
+	// with $NODEID expressions kept.
+	Default string
+}
+
+// ObjectDictionary is the object dictionary of a device, parsed from an EDS or DCF file.
+type ObjectDictionary struct {
+	File        string
+	DeviceName  string
+	VendorName  string
+	ProductName string
+	// NodeID is the node ID of a DCF, 0 if unset.
+	NodeID uint8
+	// Entries are sorted by index and sub-index.
+	Entries []*Entry
+
+	byAddr map[uint32]*Entry
+}
+
+// LoadEDS parses an EDS or DCF file.
+func LoadEDS(path string) (*ObjectDictionary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseEDS(filepath.Base(path), data)
+}
+
+// ParseEDS parses the content of an EDS or DCF file as defined by CiA 306.
+func ParseEDS(name string, data []byte) (*ObjectDictionary, error) {
+	sections, err := parseINI(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	od := &ObjectDictionary{File: name, byAddr: make(map[uint32]*Entry)}
+	if info := sections["deviceinfo"]; info != nil {
+		od.VendorName = info["vendorname"]
+		od.ProductName = info["productname"]
+	}
+	if fi := sections["fileinfo"]; fi != nil {
+		od.DeviceName = fi["description"]
+	}
+	if dc := sections["devicecomissioning"]; dc != nil {
+		if v, err := strconv.ParseUint(strings.TrimSpace(dc["nodeid"]), 0, 8); err == nil && v <= MaxNodeID {
+			od.NodeID = uint8(v)
+		}
+	}
+	if od.DeviceName == "" {
+		od.DeviceName = od.ProductName
+	}
+
+	for sec, keys := range sections {
+		index, sub, isSub, ok := parseSectionName(sec)
+		if !ok {
+			continue
+		}
+		if isSub {
+			parent := sections[fmt.Sprintf("%04x", index)]
+			e, err := newEntry(index, sub, keys)
+			if err != nil {
+				return nil, fmt.Errorf("%s: [%s]: %w", name, sec, err)
+			}
+			if parent != nil && parent["parametername"] != "" {
+				e.Name = parent["parametername"] + "." + e.Name
+			}
+			od.add(e)
+			continue
+		}
+		// ARRAY and RECORD objects only hold their sub-index sections
+		if n, _ := strconv.ParseUint(strings.TrimSpace(keys["subnumber"]), 0, 8); n > 0 {
+			continue
+		}
+		if ot, _ := strconv.ParseUint(strings.TrimSpace(keys["objecttype"]), 0, 8); ot == 8 || ot == 9 {
+			continue
+		}
+		e, err := newEntry(index, 0, keys)
+		if err != nil {
+			return nil, fmt.Errorf("%s: [%s]: %w", name, sec, err)
+		}
+		od.add(e)
+	}
+	if len(od.Entries) == 0 {
+		return nil, fmt.Errorf("%s: no objects", name)
+	}
+	sort.Slice(od.Entries, func(i, j int) bool {
+		return addr(od.Entries[i].Index, od.Entries[i].Subindex) < addr(od.Entries[j].Index, od.Entries[j].Subindex)
+	})
+	return od, nil
+}
+
+// Entry returns the entry at index:subindex.
+func (od *ObjectDictionary) Entry(index uint16, subindex uint8) (*Entry, bool) {
+	e, ok := od.byAddr[addr(index, subindex)]
+	return e, ok
+}
+
+// Lookup finds an entry by parameter name, case insensitive, or by address given as
+// "0x1018:1", "1018:01" or "1018sub1".
+func (od *ObjectDictionary) Lookup(name string) (*Entry, error) {
+	name = strings.TrimSpace(name)
+	for _, e := range od.Entries {
+		if strings.EqualFold(e.Name, name) {
+			return e, nil
+		}
+	}
+	if index, sub, ok := parseAddress(name); ok {
+		if e, ok := od.Entry(index, sub); ok {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("canopen: no object %q in %s", name, od.File)
+}
+
+// MappedObject is an object mapped into a PDO.
+type MappedObject struct {
+	Index    uint16
+	Subindex uint8
+	Bits     int
+	// Entry is the mapped entry, nil when it is not in the object dictionary.
+	Entry *Entry
+}
+
+// DecodeMapping decodes a PDO mapping entry (index << 16 | subindex << 8 | length in bits).
+func DecodeMapping(v uint32) (index uint16, subindex uint8, bits int) {
+	return uint16(v >> 16), uint8(v >> 8), int(v & 0xff)
+}
+
+// PDOMapping returns the objects mapped into TPDO (tx) or RPDO n (1..4) by the default
+// values of the mapping parameter (0x1A00+n-1 or 0x1600+n-1), nil when not mapped.
+func (od *ObjectDictionary) PDOMapping(tx bool, n int) []MappedObject {
+	index := uint16(0x1600 + n - 1)
+	if tx {
+		index = uint16(0x1a00 + n - 1)
+	}
+	count, ok := od.defaultUint(index, 0)
+	if !ok {
+		return nil
+	}
+	var objs []MappedObject
+	for sub := 1; sub <= int(count) && sub <= 64; sub++ {
+		v, ok := od.defaultUint(index, uint8(sub))
+		if !ok || v == 0 {
+			continue
+		}
+		idx, s, bits := DecodeMapping(uint32(v))
+		mo := MappedObject{Index: idx, Subindex: s, Bits: bits}
+		mo.Entry, _ = od.Entry(idx, s)
+		objs = append(objs, mo)
+	}
+	return objs
+}
+
+// MappedValue is a value decoded from a PDO.
+type MappedValue struct {
+	Entry *Entry
+	Value Value
+}
+
+// DecodePDO decodes the objects mapped into a PDO payload. Objects that are not
+// byte aligned or do not fit are skipped.
+func (od *ObjectDictionary) DecodePDO(tx bool, n int, data []byte) []MappedValue {
+	var values []MappedValue
+	bit := 0
+	for _, mo := range od.PDOMapping(tx, n) {
+		start := bit
+		bit += mo.Bits
+		if mo.Entry == nil || start%8 != 0 || mo.Bits%8 != 0 || bit > 8*len(data) {
+			continue
+		}
+		v, err := mo.Entry.DataType.Decode(data[start/8 : bit/8])
+		if err != nil {
+			continue
+		}
+		values = append(values, MappedValue{Entry: mo.Entry, Value: v})
+	}
+	return values
+}
+
+func (od *ObjectDictionary) add(e *Entry) {
+	od.Entries = append(od.Entries, e)
+	od.byAddr[addr(e.Index, e.Subindex)] = e
+}
+
+// defaultUint evaluates the default value of an entry, with $NODEID replaced by the
+// node ID of a DCF.
+func (od *ObjectDictionary) defaultUint(index uint16, subindex uint8) (uint64, bool) {
+	e, ok := od.Entry(index, subindex)
+	if !ok {
+		return 0, false
+	}
+	return evalValue(e.Default, od.NodeID)
+}
+
+func newEntry(index uint16, sub uint8, keys map[string]string) (*Entry, error) {
+	e := &Entry{
+		Index:      index,
+		Subindex:   sub,
+		Name:       strings.TrimSpace(keys["parametername"]),
+		Access:     strings.ToLower(strings.TrimSpace(keys["accesstype"])),
+		PDOMapping: strings.TrimSpace(keys["pdomapping"]) == "1",
+		Default:    strings.TrimSpace(keys["defaultvalue"]),
+	}
+	if v := strings.TrimSpace(keys["parametervalue"]); v != "" {
+		e.Default = v
+	}
+	if e.Name == "" {
+		e.Name = fmt.Sprintf("%04X:%02X", index, sub)
+	}
+	dt, err := strconv.ParseUint(strings.TrimSpace(keys["datatype"]), 0, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DataType %q", keys["datatype"])
+	}
+	e.DataType = DataType(dt)
+	return e, nil
+}
+
+// evalValue evaluates a value such as "0x180", "10" or "$NODEID+0x180".
+func evalValue(s string, node uint8) (uint64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	var total uint64
+	for _, term := range strings.Split(s, "+") {
+		term = strings.TrimSpace(term)
+		if strings.EqualFold(term, "$NODEID") {
+			total += uint64(node)
+			continue
+		}
+		v, err := strconv.ParseUint(term, 0, 64)
+		if err != nil {
+			return 0, false
+		}
+		total += v
+	}
+	return total, true
+}
+
+// parseSectionName parses "1018" and "1018sub1" section names, sub-indices are hex.
+func parseSectionName(sec string) (index uint16, sub uint8, isSub bool, ok bool) {
+	idx, rest, isSub := strings.Cut(sec, "sub")
+	if len(idx) != 4 {
+		return 0, 0, false, false
+	}
+	i, err := strconv.ParseUint(idx, 16, 16)
+	if err != nil {
+		return 0, 0, false, false
+	}
+	if isSub {
+		s, err := strconv.ParseUint(rest, 16, 8)
+		if err != nil {
+			return 0, 0, false, false
+		}
+		sub = uint8(s)
+	}
+	return uint16(i), sub, isSub, true
+}
+
+// parseAddress parses "0x1018:1", "1018:01" and "1018sub1".
+func parseAddress(s string) (uint16, uint8, bool) {
+	s = strings.TrimPrefix(strings.ToLower(s), "0x")
+	if index, sub, _, ok := parseSectionName(s); ok {
+		return index, sub, true
+	}
+	idx, sub, found := strings.Cut(s, ":")
+	if !found {
+		return 0, 0, false
+	}
+	i, err := strconv.ParseUint(idx, 16, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+	j, err := strconv.ParseUint(strings.TrimPrefix(sub, "0x"), 16, 8)
+	if err != nil {
+		return 0, 0, false
+	}
+	return uint16(i), uint8(j), true
+}
+
+// parseINI parses the sections of an INI file, section and key names are lower-cased.
+func parseINI(data []byte) (map[string]map[string]string, error) {
+	sections := make(map[string]map[string]string)
+	var cur map[string]string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated section name", n)
+			}
+			name := strings.ToLower(strings.TrimSpace(line[1:end]))
+			cur = sections[name]
+			if cur == nil {
+				cur = make(map[string]string)
+				sections[name] = cur
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || cur == nil {
+			return nil, fmt.Errorf("line %d: expected key=value in a section", n)
+		}
+		cur[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(sections) == 0 {
+		return nil, errors.New("not an EDS file")
+	}
+	return sections, nil
+}
+
+func parseHexBytes(s string) ([]byte, error) {
+	s = strings.NewReplacer(" ", "", ":", "").Replace(strings.TrimSpace(s))
+	if len(s)%2 != 0 {
+		return nil, errors.New("odd number of hex digits")
+	}
+	out := make([]byte, len(s)/2)
+	for i := range out {
+		v, err := strconv.ParseUint(s[2*i:2*i+2], 16, 8)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = byte(v)
+	}
+	return out, nil
+}
+
+func addr(index uint16, subindex uint8) uint32 {
+	return uint32(index)<<8 | uint32(subindex)
+}
@@ -0,0 +1,314 @@
+package canopen
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+)
+
+// command specifiers (upper 3 bits of the first byte of an SDO frame).
+const (
+	ccsDownloadSegment  = 0
+	ccsInitiateDownload = 1
+	ccsInitiateUpload   = 2
+	ccsUploadSegment    = 3
+
+	scsUploadSegment    = 0
+	scsDownloadSegment  = 1
+	scsInitiateUpload   = 2
+	scsInitiateDownload = 3
+
+	csAbort = 4
+)
+
+// DefaultSDOTimeout is the time the client waits for each server response.
+const DefaultSDOTimeout = time.Second
+
+// MaxSDOSize bounds the size of segmented uploads.
+const MaxSDOSize = 1 << 20
+
+// Abort codes of CiA 301.
+const (
+	AbortToggle       = 0x05030000
+	AbortTimeout      = 0x05040000
+	AbortCommand      = 0x05040001
+	AbortOutOfMemory  = 0x05040005
+	AbortGeneral      = 0x08000000
+	AbortNoObject     = 0x06020000
+	AbortNoSubindex   = 0x06090011
+	AbortReadOnly     = 0x06010002
+	AbortWriteOnly    = 0x06010001
+	AbortLengthHigh   = 0x06070012
+	AbortLengthLow    = 0x06070013
+	AbortUnsupported  = 0x06010000
+	AbortValueRange   = 0x06090030
+	AbortDeviceState  = 0x08000022
+	AbortTransferData = 0x08000020
+)
+
+var abortText = map[uint32]string{
+	AbortToggle:       "toggle bit not alternated",
+	AbortTimeout:      "SDO protocol timed out",
+	AbortCommand:      "command specifier not valid or unknown",
+	AbortOutOfMemory:  "out of memory",
+	AbortUnsupported:  "unsupported access to an object",
+	AbortWriteOnly:    "attempt to read a write only object",
+	AbortReadOnly:     "attempt to write a read only object",
+	AbortNoObject:     "object does not exist in the object dictionary",
+	AbortLengthHigh:   "data type does not match, length of service parameter too high",
+	AbortLengthLow:    "data type does not match, length of service parameter too low",
+	AbortNoSubindex:   "sub-index does not exist",
+	AbortValueRange:   "invalid value for parameter",
+	AbortGeneral:      "general error",
+	AbortTransferData: "data cannot be transferred or stored to the application",
+	AbortDeviceState:  "data cannot be transferred or stored because of the present device state",
+}
+
+// ErrTimeout is returned when the server does not answer in time.
+var ErrTimeout = errors.New("canopen: SDO timeout")
+
+// AbortError is an SDO transfer aborted by the server, or by the client when the
+// server answered out of protocol.
+type AbortError struct {
+	Index    uint16
+	Subindex uint8
+	Code     uint32
+}
+
+func (e *AbortError) Error() string {
+	text, ok := abortText[e.Code]
+	if !ok {
+		text = "unknown abort code"
+	}
+	return fmt.Sprintf("canopen: SDO 0x%04X:%02X aborted: %s (0x%08X)", e.Index, e.Subindex, text, e.Code)
+}
+
+// SDOClient reads and writes the object dictionary of a node. Frames received from
+// the node must be passed to HandleFrame. Transfers are serialized.
+type SDOClient struct {
+	// Node is the node ID of the server.
+	Node uint8
+	// Send transmits a frame.
+	Send func(f canbus.Frame) error
+	// Timeout is the time to wait for each response, zero for DefaultSDOTimeout.
+	Timeout time.Duration
+
+	transfer sync.Mutex
+	mu       sync.Mutex
+	resp     chan canbus.Frame
+}
+
+// HandleFrame passes a frame to the running transfer and reports whether it is a
+// response of the server.
+func (c *SDOClient) HandleFrame(f *canbus.Frame) bool {
+	if f.IsExtended || f.IsRemote || f.IsError || f.ID != FuncSDOTx+uint32(c.Node) {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.resp != nil {
+		select {
+		case c.resp <- *f:
+		default:
+		}
+	}
+	return true
+}
+
+// Upload reads an object, with an expedited or a segmented transfer as the server chooses.
+func (c *SDOClient) Upload(ctx context.Context, index uint16, subindex uint8) ([]byte, error) {
+	c.transfer.Lock()
+	defer c.transfer.Unlock()
+	c.open()
+	defer c.close()
+
+	resp, err := c.request(ctx, index, subindex, initiate(ccsInitiateUpload<<5, index, subindex))
+	if err != nil {
+		return nil, err
+	}
+	cmd := resp[0]
+	if cmd>>5 != scsInitiateUpload {
+		return nil, c.abort(index, subindex, AbortCommand)
+	}
+	if cmd&0x02 != 0 {
+		// expedited, the size is given when s is set
+		n := 4
+		if cmd&0x01 != 0 {
+			n = 4 - int(cmd>>2&0x3)
+		}
+		return append([]byte(nil), resp[4:4+n]...), nil
+	}
+
+	size := -1
+	if cmd&0x01 != 0 {
+		size = int(binary.LittleEndian.Uint32(resp[4:]))
+		if size > MaxSDOSize {
+			return nil, c.abort(index, subindex, AbortOutOfMemory)
+		}
+	}
+	var data []byte
+	toggle := byte(0)
+	for {
+		var req [8]byte
+		req[0] = ccsUploadSegment<<5 | toggle<<4
+		resp, err := c.request(ctx, index, subindex, req)
+		if err != nil {
+			return nil, err
+		}
+		cmd := resp[0]
+		if cmd>>5 != scsUploadSegment {
+			return nil, c.abort(index, subindex, AbortCommand)
+		}
+		if cmd>>4&1 != toggle {
+			return nil, c.abort(index, subindex, AbortToggle)
+		}
+		n := 7 - int(cmd>>1&0x7)
+		data = append(data, resp[1:1+n]...)
+		if len(data) > MaxSDOSize {
+			return nil, c.abort(index, subindex, AbortOutOfMemory)
+		}
+		if cmd&0x01 != 0 {
+			break
+		}
+		toggle ^= 1
+	}
+	if size >= 0 && len(data) != size {
+		return nil, fmt.Errorf("canopen: SDO 0x%04X:%02X: received %d bytes, the server announced %d", index, subindex, len(data), size)
+	}
+	return data, nil
+}
+
+// Download writes an object, with an expedited transfer up to 4 bytes and a
+// segmented one for larger data.
+func (c *SDOClient) Download(ctx context.Context, index uint16, subindex uint8, data []byte) error {
+	if len(data) == 0 {
+		return errors.New("canopen: no data to write")
+	}
+	c.transfer.Lock()
+	defer c.transfer.Unlock()
+	c.open()
+	defer c.close()
+
+	var req [8]byte
+	if len(data) <= 4 {
+		req = initiate(ccsInitiateDownload<<5|byte(4-len(data))<<2|0x03, index, subindex)
+		copy(req[4:], data)
+	} else {
+		req = initiate(ccsInitiateDownload<<5|0x01, index, subindex)
+		binary.LittleEndian.PutUint32(req[4:], uint32(len(data)))
+	}
+	resp, err := c.request(ctx, index, subindex, req)
+	if err != nil {
+		return err
+	}
+	if resp[0]>>5 != scsInitiateDownload {
+		return c.abort(index, subindex, AbortCommand)
+	}
+	if len(data) <= 4 {
+		return nil
+	}
+
+	toggle := byte(0)
+	for len(data) > 0 {
+		n := min(len(data), 7)
+		var req [8]byte
+		req[0] = ccsDownloadSegment<<5 | toggle<<4 | byte(7-n)<<1
+		if n == len(data) {
+			req[0] |= 0x01
+		}
+		copy(req[1:], data[:n])
+		resp, err := c.request(ctx, index, subindex, req)
+		if err != nil {
+			return err
+		}
+		if resp[0]>>5 != scsDownloadSegment {
+			return c.abort(index, subindex, AbortCommand)
+		}
+		if resp[0]>>4&1 != toggle {
+			return c.abort(index, subindex, AbortToggle)
+		}
+		data = data[n:]
+		toggle ^= 1
+	}
+	return nil
+}
+
+func (c *SDOClient) open() {
+	c.mu.Lock()
+	c.resp = make(chan canbus.Frame, 1)
+	c.mu.Unlock()
+}
+
+func (c *SDOClient) close() {
+	c.mu.Lock()
+	c.resp = nil
+	c.mu.Unlock()
+}
+
+// request sends an SDO request and waits for the response, which is returned as 8
+// bytes. Abort responses are returned as *AbortError.
+func (c *SDOClient) request(ctx context.Context, index uint16, subindex uint8, req [8]byte) ([8]byte, error) {
+	c.mu.Lock()
+	ch := c.resp
+	c.mu.Unlock()
+	// drop a late response of a previous request
+	select {
+	case <-ch:
+	default:
+	}
+
+	f := canbus.Frame{ID: FuncSDORx + uint32(c.Node), Length: 8}
+	copy(f.Data[:], req[:])
+	if err := c.Send(f); err != nil {
+		return [8]byte{}, err
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSDOTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		_ = c.abort(index, subindex, AbortGeneral)
+		return [8]byte{}, ctx.Err()
+	case <-timer.C:
+		_ = c.abort(index, subindex, AbortTimeout)
+		return [8]byte{}, ErrTimeout
+	case r := <-ch:
+		var resp [8]byte
+		copy(resp[:], r.Payload())
+		if resp[0]>>5 == csAbort {
+			return resp, &AbortError{
+				Index:    binary.LittleEndian.Uint16(resp[1:]),
+				Subindex: resp[3],
+				Code:     binary.LittleEndian.Uint32(resp[4:]),
+			}
+		}
+		return resp, nil
+	}
+}
+
+// abort sends an abort frame to the server and returns the matching error.
+func (c *SDOClient) abort(index uint16, subindex uint8, code uint32) error {
+	req := initiate(csAbort<<5, index, subindex)
+	binary.LittleEndian.PutUint32(req[4:], code)
+	f := canbus.Frame{ID: FuncSDORx + uint32(c.Node), Length: 8}
+	copy(f.Data[:], req[:])
+	_ = c.Send(f)
+	return &AbortError{Index: index, Subindex: subindex, Code: code}
+}
+
+func initiate(cmd byte, index uint16, subindex uint8) [8]byte {
+	var req [8]byte
+	req[0] = cmd
+	binary.LittleEndian.PutUint16(req[1:], index)
+	req[3] = subindex
+	return req
+}
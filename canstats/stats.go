@@ -0,0 +1,268 @@
+// Package canstats computes traffic statistics of a CAN bus: frame and byte
+// rates, estimated bus load, error frame rate and per-ID counters with
+// inter-arrival times.
+package canstats
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+)
+
+// DefaultBitrate is assumed for the bus load of interfaces without a known bitrate.
+const DefaultBitrate = 500000
+
+// Snapshot is the state of a Collector at a point in time. Rates and the bus
+// load are computed over the window since the previous snapshot.
+type Snapshot struct {
+	Timestamp time.Time
+	Window    time.Duration
+	Bitrate   uint32
+
+	FramesPerSec      float64
+	BytesPerSec       float64
+	ErrorFramesPerSec float64
+	// BusLoad is the estimated share of the bus time used in the window, in percent.
+	BusLoad float64
+
+	TotalFrames uint64
+	TotalBytes  uint64
+	TotalErrors uint64
+	TxFrames    uint64
+
+	IDs []IDStats
+}
+
+// IDStats are the counters of a single CAN ID.
+type IDStats struct {
+	ID       uint32
+	Extended bool
+	Count    uint64
+	Bytes    uint64
+	// Rate is the number of frames per second in the window.
+	Rate float64
+	// MinInterval, AvgInterval and MaxInterval are the inter-arrival times since the
+	// collector was reset, zero until two frames were seen.
+	MinInterval time.Duration
+	AvgInterval time.Duration
+	MaxInterval time.Duration
+}
+
+type idKey struct {
+	id       uint32
+	extended bool
+}
+
+type idCounter struct {
+	count     uint64
+	bytes     uint64
+	window    uint64
+	last      time.Time
+	intervals uint64
+	sum       time.Duration
+	min       time.Duration
+	max       time.Duration
+}
+
+// Collector accumulates the frames of one bus. It is safe for concurrent use.
+type Collector struct {
+	mu       sync.Mutex
+	bitrate  uint32
+	dbitrate uint32
+
+	start     time.Time
+	frames    uint64
+	bytes     uint64
+	errors    uint64
+	tx        uint64
+	winFrames uint64
+	winBytes  uint64
+	winErrors uint64
+	winBits   float64
+	ids       map[idKey]*idCounter
+}
+
+// NewCollector returns a collector for a bus with the given nominal and CAN FD data
+// bitrates. Zero selects DefaultBitrate, a zero data bitrate the nominal one.
+func NewCollector(bitrate, dataBitrate uint32) *Collector {
+	if bitrate == 0 {
+		bitrate = DefaultBitrate
+	}
+	if dataBitrate == 0 {
+		dataBitrate = bitrate
+	}
+	return &Collector{
+		bitrate:  bitrate,
+		dbitrate: dataBitrate,
+		ids:      make(map[idKey]*idCounter),
+	}
+}
+
+// Add counts a frame seen at ts. tx marks frames transmitted by the application.
+func (c *Collector) Add(ts time.Time, f *canbus.Frame, tx bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.start.IsZero() {
+		c.start = ts
+	}
+	c.winBits += FrameBits(f, c.bitrate, c.dbitrate)
+	if f.IsError {
+		c.errors++
+		c.winErrors++
+		return
+	}
+	c.frames++
+	c.winFrames++
+	c.bytes += uint64(f.Length)
+	c.winBytes += uint64(f.Length)
+	if tx {
+		c.tx++
+	}
+
+	key := idKey{f.ID, f.IsExtended}
+	ctr := c.ids[key]
+	if ctr == nil {
+		ctr = &idCounter{}
+		c.ids[key] = ctr
+	}
+	if !ctr.last.IsZero() {
+		d := ts.Sub(ctr.last)
+		if ctr.intervals == 0 || d < ctr.min {
+			ctr.min = d
+		}
+		if d > ctr.max {
+			ctr.max = d
+		}
+		ctr.sum += d
+		ctr.intervals++
+	}
+	ctr.last = ts
+	ctr.count++
+	ctr.window++
+	ctr.bytes += uint64(f.Length)
+}
+
+// Snapshot returns the statistics at now and starts a new rate window.
+func (c *Collector) Snapshot(now time.Time) Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := Snapshot{
+		Timestamp:   now,
+		Bitrate:     c.bitrate,
+		TotalFrames: c.frames,
+		TotalBytes:  c.bytes,
+		TotalErrors: c.errors,
+		TxFrames:    c.tx,
+		IDs:         make([]IDStats, 0, len(c.ids)),
+	}
+	if !c.start.IsZero() {
+		s.Window = now.Sub(c.start)
+	}
+	secs := s.Window.Seconds()
+	for key, ctr := range c.ids {
+		ids := IDStats{
+			ID:          key.id,
+			Extended:    key.extended,
+			Count:       ctr.count,
+			Bytes:       ctr.bytes,
+			MinInterval: ctr.min,
+			MaxInterval: ctr.max,
+		}
+		if ctr.intervals > 0 {
+			ids.AvgInterval = ctr.sum / time.Duration(ctr.intervals)
+		}
+		if secs > 0 {
+			ids.Rate = float64(ctr.window) / secs
+		}
+		ctr.window = 0
+		s.IDs = append(s.IDs, ids)
+	}
+	sort.Slice(s.IDs, func(i, j int) bool {
+		if s.IDs[i].ID != s.IDs[j].ID {
+			return s.IDs[i].ID < s.IDs[j].ID
+		}
+		return !s.IDs[i].Extended
+	})
+
+	if secs > 0 {
+		s.FramesPerSec = float64(c.winFrames) / secs
+		s.BytesPerSec = float64(c.winBytes) / secs
+		s.ErrorFramesPerSec = float64(c.winErrors) / secs
+		s.BusLoad = min(100, 100*c.winBits/(float64(c.bitrate)*secs))
+	}
+	c.winFrames, c.winBytes, c.winErrors, c.winBits = 0, 0, 0, 0
+	c.start = now
+	return s
+}
+
+// Totals returns the counters since the last reset without starting a new rate
+// window, the rates and the per ID statistics are left zero.
+func (c *Collector) Totals() Snapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return Snapshot{
+		Bitrate:     c.bitrate,
+		TotalFrames: c.frames,
+		TotalBytes:  c.bytes,
+		TotalErrors: c.errors,
+		TxFrames:    c.tx,
+	}
+}
+
+// Reset clears every counter.
+func (c *Collector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.start = time.Time{}
+	c.frames, c.bytes, c.errors, c.tx = 0, 0, 0, 0
+	c.winFrames, c.winBytes, c.winErrors, c.winBits = 0, 0, 0, 0
+	c.ids = make(map[idKey]*idCounter)
+}
+
+// FrameBits estimates the bus time of a frame in nominal bit times, including the
+// interframe space and an average bit stuffing overhead. The CAN FD data phase is
+// scaled by the ratio of the nominal and data bitrates when BRS is set.
+func FrameBits(f *canbus.Frame, bitrate, dataBitrate uint32) float64 {
+	const stuffing = 1.1
+	if f.IsError {
+		// error flag, delimiter and interframe space
+		return 6 + 8 + 3
+	}
+	var arbitration, data float64
+	switch {
+	case !f.IsFD && !f.IsExtended:
+		// SOF, ID, RTR, IDE, r0, DLC, CRC, delimiters, ACK, EOF, IFS
+		arbitration, data = 47, 8*float64(f.Length)
+		if f.IsRemote {
+			data = 0
+		}
+	case !f.IsFD:
+		arbitration, data = 67, 8*float64(f.Length)
+		if f.IsRemote {
+			data = 0
+		}
+	default:
+		// the data phase covers ESI, DLC, data, stuff count and CRC (17 or 21 bits)
+		arbitration = 29
+		if f.IsExtended {
+			arbitration = 48
+		}
+		crc := 17.0
+		if f.Length > 16 {
+			crc = 21
+		}
+		data = 1 + 4 + 8*float64(f.Length) + 4 + crc
+		if f.BRS && dataBitrate > bitrate && bitrate > 0 {
+			data = data * float64(bitrate) / float64(dataBitrate)
+		}
+		// CRC delimiter, ACK, EOF, IFS at the nominal rate
+		arbitration += 1 + 2 + 7 + 3
+	}
+	return (arbitration + data) * stuffing
+}
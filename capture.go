@@ -0,0 +1,378 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/canlog"
+	"canproject/capture"
+)
+
+// maxCaptureSize bounds SetCaptureSize, a frame takes about 120 bytes.
+const maxCaptureSize = 10000000
+
+// CaptureFilter selects buffered frames.
+type CaptureFilter struct {
+	// Interface selects the frames of one interface, empty for all.
+	Interface string `json:"interface"`
+	// Network is "can", "canxl", "lin", "flexray", "ethernet", "gps" or empty
+	// for all networks.
+	Network string `json:"network"`
+	// IDs keeps the CAN frames matching any of the filters, all frames when empty.
+	IDs []CANFilter `json:"ids"`
+	// Direction is "rx", "tx" or empty for both.
+	Direction string `json:"direction"`
+	// Data is a hex pattern searched in the payloads, eg "10 ?? 3E", where ?? matches
+	// any byte. Empty matches all payloads.
+	Data string `json:"data"`
+	// Tag selects the frames annotated with this tag, empty for all.
+	Tag string `json:"tag"`
+}
+
+// TimeRange bounds the timestamps of the selected frames, in Unix milliseconds.
+// A zero bound is open.
+type TimeRange struct {
+	StartMs int64 `json:"startMs"`
+	EndMs   int64 `json:"endMs"`
+}
+
+// CapturedFrame is a frame of the capture buffer.
+type CapturedFrame struct {
+	// Seq numbers the frames in arrival order, it is not reset by ClearCapture.
+	Seq       uint64    `json:"seq"`
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	// Network is "can", "canxl" for the CAN XL frames received, "gps" for the
+	// samples of StartGPS or, for the frames of the other networks imported
+	// with ImportCapture, "lin", "flexray" or "ethernet". Their ID is the CAN
+	// XL priority, the LIN frame ID or the FlexRay slot ID and their data the
+	// payload, the whole frame for Ethernet, the encoded sample for GPS.
+	Network   string   `json:"network"`
+	Direction string   `json:"direction"`
+	ID        uint32   `json:"id"`
+	Extended  bool     `json:"extended"`
+	Remote    bool     `json:"remote"`
+	Error     bool     `json:"error"`
+	FD        bool     `json:"fd"`
+	BRS       bool     `json:"brs"`
+	ESI       bool     `json:"esi"`
+	DLC       uint8    `json:"dlc"`
+	Data      []uint32 `json:"data"`
+	// Cycle is the cycle count of the FlexRay frames.
+	Cycle uint8  `json:"cycle,omitempty"`
+	Group string `json:"group,omitempty"`
+	// Comment and Tag are the annotation set with AnnotateFrame.
+	Comment string `json:"comment,omitempty"`
+	Tag     string `json:"tag,omitempty"`
+}
+
+// CapturePage is a result of QueryCapture.
+type CapturePage struct {
+	// Total is the number of buffered frames matching the query.
+	Total  int             `json:"total"`
+	Offset int             `json:"offset"`
+	Frames []CapturedFrame `json:"frames"`
+}
+
+// CaptureStatus describes the capture buffer.
+type CaptureStatus struct {
+	// Size is the number of frames kept in memory, Count the number of
+	// frames buffered, the spilled ones included.
+	Size  int `json:"size"`
+	Count int `json:"count"`
+	// Spilling tells whether SetCaptureSpill is enabled, Spilled is the
+	// number of frames moved to disk and SpillBytes the size of their files.
+	Spilling   bool   `json:"spilling"`
+	SpillDir   string `json:"spillDir,omitempty"`
+	Spilled    int    `json:"spilled"`
+	SpillBytes int64  `json:"spillBytes"`
+	// SpillError is the last error writing a spill file, its frames were
+	// dropped.
+	SpillError string `json:"spillError,omitempty"`
+	// Paused tells whether PauseCapture is in effect, Skipped is the number
+	// of frames left out since the capture was last paused.
+	Paused  bool   `json:"paused"`
+	Skipped uint64 `json:"skipped"`
+}
+
+// CaptureSpillOptions configures SetCaptureSpill.
+type CaptureSpillOptions struct {
+	Enabled bool `json:"enabled"`
+	// MemoryMB caps the memory of the frames kept in memory, it sets the
+	// size of the buffer. Zero keeps the size.
+	MemoryMB int `json:"memoryMB"`
+	// Dir is where the spill files are created, the temporary directory when
+	// empty.
+	Dir string `json:"dir"`
+	// DiskMB bounds the size of the spill files, the oldest frames are
+	// dropped beyond it. Zero is unbounded.
+	DiskMB int `json:"diskMB"`
+}
+
+// QueryCapture returns up to limit buffered frames matching filter and timeRange,
+// oldest first, after skipping offset of them. Frames of all started interfaces are
+// buffered, both received and sent, so the frontend can page through and search
+// past traffic.
+func (a *App) QueryCapture(filter CaptureFilter, timeRange TimeRange, offset, limit int) (CapturePage, error) {
+	if offset < 0 || limit < 0 {
+		return CapturePage{}, fmt.Errorf("invalid offset %d or limit %d", offset, limit)
+	}
+	keep, err := captureMatcher(filter, timeRange)
+	if err != nil {
+		return CapturePage{}, err
+	}
+	records, total := a.capture.Query(keep, offset, limit)
+	page := CapturePage{Total: total, Offset: offset, Frames: make([]CapturedFrame, len(records))}
+	for i := range records {
+		r := &records[i]
+		if p := r.Packet; p != nil {
+			page.Frames[i] = CapturedFrame{
+				Seq:       r.Seq,
+				Timestamp: r.Timestamp,
+				Interface: r.Interface,
+				Network:   string(p.Network),
+				Direction: direction(r.TX),
+				ID:        p.ID,
+				Data:      dataWords(p.Payload),
+				Cycle:     p.Cycle,
+			}
+		} else {
+			page.Frames[i] = a.capturedFrame(r)
+		}
+		if r.Note != nil {
+			page.Frames[i].Comment, page.Frames[i].Tag = r.Note.Comment, r.Note.Tag
+		}
+	}
+	return page, nil
+}
+
+// capturedFrame converts a buffered CAN frame.
+func (a *App) capturedFrame(r *capture.Record) CapturedFrame {
+	f := &r.Frame
+	return CapturedFrame{
+		Seq:       r.Seq,
+		Timestamp: r.Timestamp,
+		Interface: r.Interface,
+		Network:   string(canbus.NetworkCAN),
+		Direction: direction(r.TX),
+		ID:        f.ID,
+		Extended:  f.IsExtended,
+		Remote:    f.IsRemote,
+		Error:     f.IsError,
+		FD:        f.IsFD,
+		BRS:       f.BRS,
+		ESI:       f.ESI,
+		DLC:       f.DLC(),
+		Data:      dataWords(f.Payload()),
+		Group:     a.frameGroup(f.ID, f.IsExtended),
+	}
+}
+
+// ClearCapture drops the buffered frames, their annotations and the bookmarks.
+func (a *App) ClearCapture() {
+	a.capture.Clear()
+}
+
+// SetCaptureSize changes the number of frames the capture buffer keeps, the oldest
+// frames are dropped when it shrinks.
+func (a *App) SetCaptureSize(size int) error {
+	if size < 1 || size > maxCaptureSize {
+		return fmt.Errorf("capture size %d out of range 1-%d", size, maxCaptureSize)
+	}
+	a.capture.Resize(size)
+	return nil
+}
+
+// GetCaptureStatus returns the size and fill level of the capture buffer.
+func (a *App) GetCaptureStatus() CaptureStatus {
+	st := CaptureStatus{Size: a.capture.Size(), Count: a.capture.Len()}
+	st.Paused, st.Skipped = a.capture.Paused()
+	if sp := a.capture.SpillStatus(); sp.Enabled {
+		st.Spilling, st.SpillDir, st.Spilled, st.SpillBytes = true, sp.Dir, sp.Records, sp.Bytes
+		if sp.Err != nil {
+			st.SpillError = sp.Err.Error()
+		}
+	}
+	return st
+}
+
+// PauseCapture stops the capture buffer from accumulating frames while the
+// interfaces stay started: the frames are still received, decoded, logged to
+// the active log files and answered, the cyclic transmissions and responders
+// keep running, but they are left out of the buffer until ResumeCapture. It
+// keeps the buffer for the part of a session of interest, eg while waiting for
+// a rare event.
+func (a *App) PauseCapture() CaptureStatus {
+	a.capture.SetPaused(true)
+	return a.GetCaptureStatus()
+}
+
+// ResumeCapture resumes the capture buffer paused with PauseCapture.
+func (a *App) ResumeCapture() CaptureStatus {
+	a.capture.SetPaused(false)
+	return a.GetCaptureStatus()
+}
+
+// SetCaptureSpill makes the capture buffer move its oldest frames to temporary
+// files when it is full instead of dropping them, so a long session keeps all
+// its frames with the memory bounded to MemoryMB. The spilled frames are read
+// back for QueryCapture, ExportCapture, which streams them, and the analyses of
+// the capture; they are removed by ClearCapture, when the spilling is disabled
+// and when the app exits.
+func (a *App) SetCaptureSpill(opts CaptureSpillOptions) (CaptureStatus, error) {
+	if !opts.Enabled {
+		if err := a.capture.SetSpill(nil); err != nil {
+			return CaptureStatus{}, err
+		}
+		return a.GetCaptureStatus(), nil
+	}
+	if opts.MemoryMB < 0 || opts.DiskMB < 0 {
+		return CaptureStatus{}, fmt.Errorf("invalid memory %d MB or disk %d MB", opts.MemoryMB, opts.DiskMB)
+	}
+	if err := a.capture.SetSpill(&capture.SpillOptions{
+		Dir:      strings.TrimSpace(opts.Dir),
+		MaxBytes: int64(opts.DiskMB) << 20,
+	}); err != nil {
+		return CaptureStatus{}, err
+	}
+	if opts.MemoryMB > 0 {
+		a.capture.Resize(min(capture.SizeForMemory(int64(opts.MemoryMB)<<20), maxCaptureSize))
+	}
+	return a.GetCaptureStatus(), nil
+}
+
+// CaptureImport is the result of ImportCapture.
+type CaptureImport struct {
+	// Frames counts the imported frames by network.
+	Frames map[string]int `json:"frames"`
+	// Warnings lists the records of the trace that could not be read.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ImportCapture adds the records of a candump log, of a Vector ASC (.asc) or
+// BLF (.blf) trace or of a chunked capture (.ccap) to the capture buffer, oldest
+// first, to search, annotate and export them like the frames received. The LIN,
+// FlexRay and Ethernet frames of BLF traces are imported with the CAN frames so
+// the networks of a vehicle share a single timeline, see CapturedFrame.Network.
+func (a *App) ImportCapture(path string) (CaptureImport, error) {
+	if paused, _ := a.capture.Paused(); paused {
+		return CaptureImport{}, errors.New("capture paused, resume it to import")
+	}
+	records, warnings, err := readTraceFile(path)
+	if err != nil {
+		return CaptureImport{}, err
+	}
+	if len(records) == 0 {
+		return CaptureImport{}, fmt.Errorf("%s contains no frames", path)
+	}
+	slices.SortStableFunc(records, func(x, y canlog.Record) int { return x.Timestamp.Compare(y.Timestamp) })
+	res := CaptureImport{Frames: make(map[string]int), Warnings: warnings}
+	for i := range records {
+		r := &records[i]
+		if r.Packet != nil {
+			a.capture.AddPacket(r.Timestamp, r.Interface, r.Packet, r.TX)
+			res.Frames[string(r.Packet.Network)]++
+			continue
+		}
+		a.capture.Add(r.Timestamp, r.Interface, &r.Frame, r.TX)
+		res.Frames[string(canbus.NetworkCAN)]++
+	}
+	return res, nil
+}
+
+// captureMatcher returns the predicate of filter and timeRange.
+func captureMatcher(filter CaptureFilter, timeRange TimeRange) (func(*capture.Record) bool, error) {
+	iface := strings.TrimSpace(filter.Interface)
+	tag := strings.TrimSpace(filter.Tag)
+	network := canbus.Network(strings.ToLower(strings.TrimSpace(filter.Network)))
+	switch network {
+	case "", canbus.NetworkCAN, canbus.NetworkCANXL, canbus.NetworkLIN, canbus.NetworkFlexRay, canbus.NetworkEthernet, canbus.NetworkGPS:
+	default:
+		return nil, fmt.Errorf("invalid network %q, want can, canxl, lin, flexray, ethernet, gps or empty", filter.Network)
+	}
+	var dirTX, anyDir bool
+	switch strings.ToLower(filter.Direction) {
+	case "":
+		anyDir = true
+	case "rx":
+	case "tx":
+		dirTX = true
+	default:
+		return nil, fmt.Errorf("invalid direction %q, want rx, tx or empty", filter.Direction)
+	}
+	var ids []canbus.Filter
+	for _, f := range filter.IDs {
+		ids = append(ids, canbus.Filter{ID: f.ID, Mask: f.Mask, Extended: f.Extended, Invert: f.Invert})
+	}
+	pattern, err := parseDataPattern(filter.Data)
+	if err != nil {
+		return nil, err
+	}
+	start, end := timeRange.bounds()
+	return func(r *capture.Record) bool {
+		return (iface == "" || r.Interface == iface) &&
+			(tag == "" || r.Note != nil && r.Note.Tag == tag) &&
+			(network == "" || r.Network() == network) &&
+			(anyDir || r.TX == dirTX) &&
+			(start.IsZero() || !r.Timestamp.Before(start)) &&
+			(end.IsZero() || r.Timestamp.Before(end)) &&
+			(r.Packet == nil && canbus.MatchAny(ids, &r.Frame) || r.Packet != nil && len(ids) == 0) &&
+			pattern.match(r.Payload())
+	}, nil
+}
+
+// bounds returns the times of r, zero for an open bound.
+func (r TimeRange) bounds() (start, end time.Time) {
+	if r.StartMs != 0 {
+		start = time.UnixMilli(r.StartMs)
+	}
+	if r.EndMs != 0 {
+		end = time.UnixMilli(r.EndMs)
+	}
+	return start, end
+}
+
+// dataPattern is a byte pattern with wildcards, a negative byte matches any byte.
+type dataPattern []int
+
+func parseDataPattern(s string) (dataPattern, error) {
+	s = strings.ReplaceAll(strings.ReplaceAll(strings.TrimSpace(s), " ", ""), ":", "")
+	if len(s)%2 != 0 {
+		return nil, fmt.Errorf("invalid data pattern %q: odd number of digits", s)
+	}
+	p := make(dataPattern, 0, len(s)/2)
+	for i := 0; i < len(s); i += 2 {
+		if s[i:i+2] == "??" {
+			p = append(p, -1)
+			continue
+		}
+		b, err := strconv.ParseUint(s[i:i+2], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data pattern %q: %q is not a byte", s, s[i:i+2])
+		}
+		p = append(p, int(b))
+	}
+	return p, nil
+}
+
+// match reports whether p occurs anywhere in data.
+func (p dataPattern) match(data []byte) bool {
+	for start := 0; start+len(p) <= len(data); start++ {
+		ok := true
+		for i, b := range p {
+			if b >= 0 && data[start+i] != byte(b) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
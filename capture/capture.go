@@ -0,0 +1,427 @@
+// Package capture keeps the most recent frames of all interfaces in memory, or
+// on disk when they overflow it, so they can be searched and exported after the
+// fact.
+package capture
+
+import (
+	"fmt"
+	"iter"
+	"sort"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+)
+
+// DefaultSize is the number of frames a buffer keeps by default.
+const DefaultSize = 100000
+
+// MaxBookmarks bounds the bookmarks of a buffer.
+const MaxBookmarks = 10000
+
+const (
+	// spillFraction is the part of a full buffer moved to a spill file at
+	// once.
+	spillFraction = 8
+	// scanBatch is the number of records All copies at once from memory.
+	scanBatch = 4096
+)
+
+// Annotation is a note on a buffered frame or a bookmark.
+type Annotation struct {
+	Comment string
+	Tag     string
+}
+
+// Bookmark marks a time of the capture.
+type Bookmark struct {
+	// ID numbers the bookmarks of a buffer, it is not reused.
+	ID        uint64
+	Timestamp time.Time
+	Annotation
+}
+
+// Record is a frame of the buffer.
+type Record struct {
+	// Seq numbers the frames in arrival order, it keeps increasing when old frames are dropped.
+	Seq       uint64
+	Timestamp time.Time
+	Interface string
+	// Frame is the CAN frame of the record, zero when Packet is set.
+	Frame canbus.Frame
+	// Packet is the frame of a record of another network, nil for CAN frames.
+	Packet *canbus.Packet
+	// TX is true for frames sent by the app.
+	TX bool
+	// Note is the annotation of the frame, if any. It is set on the records
+	// passed to the keep functions and returned by Query and Select.
+	Note *Annotation
+}
+
+// Network returns the network of the record.
+func (r *Record) Network() canbus.Network {
+	if r.Packet != nil {
+		return r.Packet.Network
+	}
+	return canbus.NetworkCAN
+}
+
+// Payload returns the data of the CAN frame or of the packet.
+func (r *Record) Payload() []byte {
+	if r.Packet != nil {
+		return r.Packet.Payload
+	}
+	return r.Frame.Payload()
+}
+
+// Buffer is a bounded ring of records. It is safe for concurrent use.
+type Buffer struct {
+	mu      sync.Mutex
+	records []Record
+	// next is the index the next record is stored at once the ring is full.
+	next int
+	size int
+	seq  uint64
+
+	// notes are the annotations by sequence number, they are dropped with their
+	// frames. The bookmarks are kept until Clear, sorted by timestamp.
+	notes     map[uint64]Annotation
+	bookmarks []Bookmark
+	bookmark  uint64
+
+	// spill, if set, keeps the records overflowing the ring in files, see
+	// SetSpill.
+	spill *spill
+
+	// paused drops the records added, counted by skipped.
+	paused  bool
+	skipped uint64
+}
+
+// NewBuffer returns a buffer keeping the last size frames.
+func NewBuffer(size int) *Buffer {
+	return &Buffer{size: max(size, 1)}
+}
+
+// Add appends a frame, dropping the oldest one when the buffer is full.
+func (b *Buffer) Add(ts time.Time, iface string, f *canbus.Frame, tx bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.add(Record{Timestamp: ts, Interface: iface, Frame: *f, TX: tx})
+}
+
+// AddPacket appends a frame of another network than CAN, dropping the oldest
+// one when the buffer is full.
+func (b *Buffer) AddPacket(ts time.Time, iface string, p *canbus.Packet, tx bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.add(Record{Timestamp: ts, Interface: iface, Packet: p, TX: tx})
+}
+
+func (b *Buffer) add(rec Record) {
+	if b.paused {
+		b.skipped++
+		return
+	}
+	b.seq++
+	rec.Seq = b.seq
+	if len(b.records) < b.size {
+		b.records = append(b.records, rec)
+		return
+	}
+	if b.spill != nil {
+		b.spillOldest(max(b.size/spillFraction, 1))
+		b.records = append(b.records, rec)
+		return
+	}
+	if len(b.notes) > 0 {
+		delete(b.notes, b.records[b.next].Seq)
+	}
+	b.records[b.next] = rec
+	b.next = (b.next + 1) % b.size
+}
+
+// SetPaused pauses the buffer, which leaves out the records added until it is
+// resumed, or resumes it. Pausing resets the count of Paused.
+func (b *Buffer) SetPaused(paused bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if paused && !b.paused {
+		b.skipped = 0
+	}
+	b.paused = paused
+}
+
+// Paused reports whether the buffer is paused and the number of records left
+// out since it was last paused.
+func (b *Buffer) Paused() (paused bool, skipped uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.paused, b.skipped
+}
+
+// Select returns the records matching keep, oldest first.
+func (b *Buffer) Select(keep func(*Record) bool) []Record {
+	out, _ := b.Query(keep, 0, 0)
+	return out
+}
+
+// Query returns up to limit records matching keep, skipping the first offset ones, and
+// the number of matching records. A limit <= 0 returns all the records after offset.
+func (b *Buffer) Query(keep func(*Record) bool, offset, limit int) ([]Record, int) {
+	var out []Record
+	total := 0
+	for r := range b.All(keep) {
+		if total >= offset && (limit <= 0 || len(out) < limit) {
+			out = append(out, *r)
+		}
+		total++
+	}
+	return out, total
+}
+
+// All returns the records matching keep, oldest first, the spilled ones
+// included. It does not hold the buffer while its loop runs: the records added
+// meanwhile are returned too, so a long export does not block them.
+func (b *Buffer) All(keep func(*Record) bool) iter.Seq[*Record] {
+	return func(yield func(*Record) bool) {
+		// cursor is the sequence number of the last record returned
+		var cursor uint64
+		for {
+			batch, spilled := b.batchAfter(cursor)
+			if len(batch) == 0 {
+				return
+			}
+			cursor = batch[len(batch)-1].Seq
+			if spilled {
+				b.annotate(batch)
+			}
+			for i := range batch {
+				if r := &batch[i]; keep == nil || keep(r) {
+					if !yield(r) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// batchAfter returns the records following the sequence number cursor: those
+// of the first spill file holding some, read without holding the buffer, or up
+// to scanBatch records of the ring with their annotations. spilled tells the
+// records of a file, not annotated yet.
+func (b *Buffer) batchAfter(cursor uint64) (records []Record, spilled bool) {
+	b.mu.Lock()
+	if s := b.spill; s != nil {
+		for _, c := range s.chunks {
+			if c.last <= cursor {
+				continue
+			}
+			b.mu.Unlock()
+			records, err := readChunk(c.path)
+			if err != nil {
+				// dropped meanwhile
+				return b.batchAfter(c.last)
+			}
+			for len(records) > 0 && records[0].Seq <= cursor {
+				records = records[1:]
+			}
+			return records, true
+		}
+	}
+	defer b.mu.Unlock()
+
+	n := len(b.records)
+	if n == 0 {
+		return nil, false
+	}
+	start := 0
+	if oldest := b.records[b.next%n].Seq; cursor >= oldest {
+		start = int(cursor - oldest + 1)
+	}
+	if start >= n {
+		return nil, false
+	}
+	end := min(start+scanBatch, n)
+	records = make([]Record, 0, end-start)
+	for i := start; i < end; i++ {
+		r := b.records[(b.next+i)%n]
+		if note, ok := b.notes[r.Seq]; ok {
+			r.Note = &note
+		}
+		records = append(records, r)
+	}
+	return records, false
+}
+
+// annotate sets the annotations of records read from a spill file.
+func (b *Buffer) annotate(records []Record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.notes) == 0 {
+		return
+	}
+	for i := range records {
+		if note, ok := b.notes[records[i].Seq]; ok {
+			records[i].Note = &note
+		}
+	}
+}
+
+// Clear drops all the records, the spilled ones included, their annotations
+// and the bookmarks. Sequence numbers keep increasing.
+func (b *Buffer) Clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s := b.spill; s != nil {
+		for len(s.chunks) > 0 {
+			b.dropChunk()
+		}
+		s.err = nil
+	}
+	b.records = nil
+	b.next = 0
+	b.notes = nil
+	b.bookmarks = nil
+}
+
+// Resize changes the number of frames the buffer keeps in memory, dropping the
+// oldest ones when it shrinks, or spilling them.
+func (b *Buffer) Resize(size int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	size = max(size, 1)
+	if b.spill != nil && len(b.records) > size {
+		b.spillOldest(len(b.records) - size)
+	}
+	n := min(len(b.records), size)
+	records := make([]Record, 0, n)
+	for i := len(b.records) - n; i < len(b.records); i++ {
+		records = append(records, b.records[(b.next+i)%len(b.records)])
+	}
+	b.records = records
+	b.next = 0
+	b.size = size
+	oldest, ok := b.oldest()
+	if !ok {
+		b.notes = nil
+		return
+	}
+	for seq := range b.notes {
+		if seq < oldest {
+			delete(b.notes, seq)
+		}
+	}
+}
+
+// Size returns the number of frames the buffer keeps in memory.
+func (b *Buffer) Size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size
+}
+
+// Len returns the number of buffered frames, the spilled ones included.
+func (b *Buffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.spill != nil {
+		return b.spill.records + len(b.records)
+	}
+	return len(b.records)
+}
+
+// Annotate sets the annotation of the buffered frame numbered seq, an empty
+// annotation removes it.
+func (b *Buffer) Annotate(seq uint64, note Annotation) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.buffered(seq) {
+		return fmt.Errorf("frame %d is not in the capture buffer", seq)
+	}
+	if note == (Annotation{}) {
+		delete(b.notes, seq)
+		return nil
+	}
+	if b.notes == nil {
+		b.notes = make(map[uint64]Annotation)
+	}
+	b.notes[seq] = note
+	return nil
+}
+
+// buffered reports whether the frame numbered seq is in the buffer. The
+// sequence numbers of the records increase by one from the oldest, the first
+// spilled one.
+func (b *Buffer) buffered(seq uint64) bool {
+	oldest, ok := b.oldest()
+	if !ok || seq < oldest {
+		return false
+	}
+	if n := len(b.records); n > 0 {
+		return seq < b.records[b.next%n].Seq+uint64(n)
+	}
+	chunks := b.spill.chunks
+	return seq <= chunks[len(chunks)-1].last
+}
+
+// oldest returns the sequence number of the oldest record, ok is false when
+// the buffer is empty.
+func (b *Buffer) oldest() (seq uint64, ok bool) {
+	if s := b.spill; s != nil && len(s.chunks) > 0 {
+		return s.chunks[0].first, true
+	}
+	if n := len(b.records); n > 0 {
+		return b.records[b.next%n].Seq, true
+	}
+	return 0, false
+}
+
+// AddBookmark adds a bookmark at ts.
+func (b *Buffer) AddBookmark(ts time.Time, note Annotation) (Bookmark, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.bookmarks) >= MaxBookmarks {
+		return Bookmark{}, fmt.Errorf("too many bookmarks, the limit is %d", MaxBookmarks)
+	}
+	b.bookmark++
+	bm := Bookmark{ID: b.bookmark, Timestamp: ts, Annotation: note}
+	i := sort.Search(len(b.bookmarks), func(i int) bool { return b.bookmarks[i].Timestamp.After(ts) })
+	b.bookmarks = append(b.bookmarks, Bookmark{})
+	copy(b.bookmarks[i+1:], b.bookmarks[i:])
+	b.bookmarks[i] = bm
+	return bm, nil
+}
+
+// RemoveBookmark removes the bookmark numbered id.
+func (b *Buffer) RemoveBookmark(id uint64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := range b.bookmarks {
+		if b.bookmarks[i].ID == id {
+			b.bookmarks = append(b.bookmarks[:i], b.bookmarks[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no bookmark %d", id)
+}
+
+// Bookmarks returns the bookmarks in [start, end), sorted by timestamp. A zero
+// bound is open.
+func (b *Buffer) Bookmarks(start, end time.Time) []Bookmark {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var out []Bookmark
+	for _, bm := range b.bookmarks {
+		if (start.IsZero() || !bm.Timestamp.Before(start)) && (end.IsZero() || bm.Timestamp.Before(end)) {
+			out = append(out, bm)
+		}
+	}
+	return out
+}
@@ -0,0 +1,210 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"unsafe"
+)
+
+// recordBytes is the memory a buffered frame takes, without the payloads of
+// the packets.
+const recordBytes = int64(unsafe.Sizeof(Record{}))
+
+// SizeForMemory returns the number of frames a buffer keeps in about bytes of
+// memory.
+func SizeForMemory(bytes int64) int {
+	return int(max(bytes/recordBytes, 1))
+}
+
+// SpillOptions configures the spilling of a full buffer to disk.
+type SpillOptions struct {
+	// Dir is where the spill files are created, the temporary directory when
+	// empty.
+	Dir string
+	// MaxBytes bounds the size of the spill files, the oldest are dropped
+	// beyond it. Zero is unbounded.
+	MaxBytes int64
+}
+
+// SpillStatus describes the records of a buffer on disk.
+type SpillStatus struct {
+	Enabled bool
+	Dir     string
+	Records int
+	Bytes   int64
+	// Err is the last error writing a spill file, its records were dropped.
+	Err error
+}
+
+type spill struct {
+	// dir is the directory created for the files of the buffer.
+	dir      string
+	parent   string
+	maxBytes int64
+	chunks   []spillChunk
+	records  int
+	bytes    int64
+	err      error
+}
+
+// spillChunk is a file of consecutive records, the oldest first.
+type spillChunk struct {
+	path        string
+	first, last uint64
+	records     int
+	size        int64
+}
+
+// SetSpill makes the buffer move its oldest records to files when it is full,
+// instead of dropping them, so it keeps its records of the whole session in
+// bounded memory; Query and Select read them back. A nil opts stops the
+// spilling and drops the spilled records. Changing MaxBytes keeps them,
+// changing Dir drops them.
+func (b *Buffer) SetSpill(opts *SpillOptions) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if s := b.spill; s != nil {
+		if opts != nil && opts.Dir == s.parent {
+			s.maxBytes = opts.MaxBytes
+			b.trimSpill()
+			return nil
+		}
+		b.dropSpill()
+	}
+	if opts == nil {
+		return nil
+	}
+	dir, err := os.MkdirTemp(opts.Dir, "capture-spill-")
+	if err != nil {
+		return err
+	}
+	b.spill = &spill{dir: dir, parent: opts.Dir, maxBytes: opts.MaxBytes}
+	return nil
+}
+
+// SpillStatus returns the state of the spilling.
+func (b *Buffer) SpillStatus() SpillStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.spill
+	if s == nil {
+		return SpillStatus{}
+	}
+	return SpillStatus{Enabled: true, Dir: s.dir, Records: s.records, Bytes: s.bytes, Err: s.err}
+}
+
+// Close removes the spill files.
+func (b *Buffer) Close() error {
+	return b.SetSpill(nil)
+}
+
+// spillOldest moves the n oldest records to a file. When it cannot be
+// written, they are dropped as without spilling.
+func (b *Buffer) spillOldest(n int) {
+	// put the oldest record first, in place to stay in the memory bound
+	reverse(b.records[:b.next])
+	reverse(b.records[b.next:])
+	reverse(b.records)
+	b.next = 0
+
+	n = min(n, len(b.records))
+	s := b.spill
+	if c, err := s.write(b.records[:n]); err != nil {
+		s.err = err
+		for i := range n {
+			delete(b.notes, b.records[i].Seq)
+		}
+	} else {
+		s.chunks = append(s.chunks, c)
+		s.records += c.records
+		s.bytes += c.size
+		s.err = nil
+	}
+	rest := copy(b.records, b.records[n:])
+	clear(b.records[rest:])
+	b.records = b.records[:rest]
+	b.trimSpill()
+}
+
+func reverse(r []Record) {
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+}
+
+// trimSpill drops the oldest spill files beyond the size bound, but the last.
+func (b *Buffer) trimSpill() {
+	s := b.spill
+	for s.maxBytes > 0 && s.bytes > s.maxBytes && len(s.chunks) > 1 {
+		b.dropChunk()
+	}
+}
+
+// dropChunk removes the oldest spill file and the annotations of its records.
+func (b *Buffer) dropChunk() {
+	s := b.spill
+	c := s.chunks[0]
+	_ = os.Remove(c.path)
+	for seq := range b.notes {
+		if seq <= c.last {
+			delete(b.notes, seq)
+		}
+	}
+	s.chunks = s.chunks[1:]
+	s.records -= c.records
+	s.bytes -= c.size
+}
+
+// dropSpill removes the spill files and stops the spilling.
+func (b *Buffer) dropSpill() {
+	for len(b.spill.chunks) > 0 {
+		b.dropChunk()
+	}
+	_ = os.RemoveAll(b.spill.dir)
+	b.spill = nil
+}
+
+func (s *spill) write(records []Record) (spillChunk, error) {
+	f, err := os.CreateTemp(s.dir, "chunk-*.gob")
+	if err != nil {
+		return spillChunk{}, err
+	}
+	w := bufio.NewWriter(f)
+	err = gob.NewEncoder(w).Encode(records)
+	if err == nil {
+		err = w.Flush()
+	}
+	var size int64
+	if err == nil {
+		size, err = f.Seek(0, 1)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return spillChunk{}, fmt.Errorf("capture spill: %w", err)
+	}
+	return spillChunk{
+		path:    f.Name(),
+		first:   records[0].Seq,
+		last:    records[len(records)-1].Seq,
+		records: len(records),
+		size:    size,
+	}, nil
+}
+
+// readChunk reads the records of a spill file.
+func readChunk(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []Record
+	err = gob.NewDecoder(bufio.NewReader(f)).Decode(&records)
+	return records, err
+}
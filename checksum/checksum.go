@@ -0,0 +1,134 @@
+// Package checksum computes the checksums and CRCs that protect the payloads
+// of automotive messages: the simple XOR and sum checksums of many OEM
+// messages, and the CRCs of the AUTOSAR CRC library that the E2E profiles use.
+package checksum
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CRC8 is an 8-bit CRC, computed MSB first without reflection.
+type CRC8 struct {
+	Poly, Init, XorOut byte
+}
+
+// The 8-bit CRCs of the AUTOSAR CRC library.
+var (
+	// SAEJ1850 is Crc_CalculateCRC8, used by the E2E profiles 1 and 11.
+	SAEJ1850 = CRC8{Poly: 0x1d, Init: 0xff, XorOut: 0xff}
+	// H2F is Crc_CalculateCRC8H2F, used by the E2E profile 2.
+	H2F = CRC8{Poly: 0x2f, Init: 0xff, XorOut: 0xff}
+)
+
+// Checksum returns the CRC of data.
+func (c CRC8) Checksum(data []byte) byte {
+	return c.Update(c.Init, data) ^ c.XorOut
+}
+
+// Update feeds data to the CRC register crc, without the final XOR. A CRC over
+// several buffers starts from Init and ends with XorOut.
+func (c CRC8) Update(crc byte, data []byte) byte {
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ c.Poly
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// CRC16 is a 16-bit CRC, computed MSB first without reflection.
+type CRC16 struct {
+	Poly, Init, XorOut uint16
+}
+
+// CCITT is Crc_CalculateCRC16 of the AUTOSAR CRC library (CRC-16/CCITT-FALSE),
+// used by the E2E profile 5.
+var CCITT = CRC16{Poly: 0x1021, Init: 0xffff, XorOut: 0}
+
+// Checksum returns the CRC of data.
+func (c CRC16) Checksum(data []byte) uint16 {
+	return c.Update(c.Init, data) ^ c.XorOut
+}
+
+// Update feeds data to the CRC register crc, without the final XOR.
+func (c CRC16) Update(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ c.Poly
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// Algorithm is an 8-bit checksum starting from a seed: the initial value of
+// the CRC register, or of the XOR or sum.
+type Algorithm struct {
+	Name string
+	// Seed is the seed of the standard algorithm.
+	Seed byte
+	fn   func(seed byte, data []byte) byte
+}
+
+// Sum returns the checksum of data with the standard seed.
+func (a Algorithm) Sum(data []byte) byte {
+	return a.fn(a.Seed, data)
+}
+
+// SumSeed returns the checksum of data starting from seed.
+func (a Algorithm) SumSeed(seed byte, data []byte) byte {
+	return a.fn(seed, data)
+}
+
+// Algorithms are the 8-bit checksums by name.
+var Algorithms = map[string]Algorithm{
+	"xor": {Name: "xor", fn: func(x byte, data []byte) byte {
+		for _, b := range data {
+			x ^= b
+		}
+		return x
+	}},
+	"sum": {Name: "sum", fn: func(s byte, data []byte) byte {
+		for _, b := range data {
+			s += b
+		}
+		return s
+	}},
+	"crc8-j1850":   crcAlgorithm("crc8-j1850", SAEJ1850),
+	"crc8-autosar": crcAlgorithm("crc8-autosar", H2F),
+}
+
+func crcAlgorithm(name string, c CRC8) Algorithm {
+	return Algorithm{Name: name, Seed: c.Init, fn: func(seed byte, data []byte) byte {
+		return c.Update(seed, data) ^ c.XorOut
+	}}
+}
+
+// Lookup returns the algorithm called name.
+func Lookup(name string) (Algorithm, error) {
+	a, ok := Algorithms[name]
+	if !ok {
+		return Algorithm{}, fmt.Errorf("unknown checksum algorithm %q, want one of %v", name, Names())
+	}
+	return a, nil
+}
+
+// Names returns the names of the algorithms, sorted.
+func Names() []string {
+	names := make([]string, 0, len(Algorithms))
+	for name := range Algorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"canproject/analysis"
+	"canproject/canlog"
+)
+
+// CaptureComparison is the result of CompareCaptures, A and B being the two
+// traces.
+type CaptureComparison struct {
+	FramesA int `json:"framesA"`
+	FramesB int `json:"framesB"`
+	// OnlyA and OnlyB are the IDs present in one trace only.
+	OnlyA []ComparedID `json:"onlyA"`
+	OnlyB []ComparedID `json:"onlyB"`
+	// Differences are the IDs of both traces whose cycle time or payload
+	// pattern differs.
+	Differences []IDDifference `json:"differences"`
+	// Same is the number of IDs of both traces which look the same.
+	Same int `json:"same"`
+	// Warnings are about the records of the traces that were skipped.
+	Warnings []string `json:"warnings"`
+}
+
+// ComparedID is an ID of CompareCaptures.
+type ComparedID struct {
+	ID       uint32 `json:"id"`
+	Extended bool   `json:"extended"`
+	Name     string `json:"name,omitempty"`
+}
+
+// IDDifference describes how an ID differs between the traces of
+// CompareCaptures.
+type IDDifference struct {
+	ComparedID
+	CountA int `json:"countA"`
+	CountB int `json:"countB"`
+	// CycleAMs and CycleBMs are the median periods, 0 for an ID seen once.
+	CycleAMs     float64 `json:"cycleAMs"`
+	CycleBMs     float64 `json:"cycleBMs"`
+	CycleDiffers bool    `json:"cycleDiffers"`
+	LengthA      int     `json:"lengthA"`
+	LengthB      int     `json:"lengthB"`
+	// ChangedA and ChangedB have a bit set for the payload bits which toggle in
+	// that trace only, ConstantDiffers for the bits constant in both traces
+	// with different values.
+	ChangedA        []uint32 `json:"changedA"`
+	ChangedB        []uint32 `json:"changedB"`
+	ConstantDiffers []uint32 `json:"constantDiffers"`
+	PatternDiffers  bool     `json:"patternDiffers"`
+}
+
+// CompareCaptures compares two candump logs or Vector ASC or BLF traces, eg recorded
+// on a car that works and on one that does not. The frames are aligned by ID,
+// whatever their interface: the IDs present in one trace only are listed, and
+// those whose median cycle time differs by more than 10% or whose payloads
+// toggle different bits or hold different constant bits. Remote and error
+// frames are left out.
+func (a *App) CompareCaptures(pathA, pathB string) (CaptureComparison, error) {
+	var cmp CaptureComparison
+	traceA, warningsA, err := readComparedTrace(pathA)
+	if err != nil {
+		return CaptureComparison{}, err
+	}
+	traceB, warningsB, err := readComparedTrace(pathB)
+	if err != nil {
+		return CaptureComparison{}, err
+	}
+	samplesA, samplesB := idSamples(traceA), idSamples(traceB)
+	r := analysis.Compare(samplesA, samplesB)
+
+	cmp.FramesA, cmp.FramesB = len(traceA), len(traceB)
+	cmp.Warnings = append(append([]string{}, warningsA...), warningsB...)
+	cmp.OnlyA = a.comparedIDs(r.OnlyA)
+	cmp.OnlyB = a.comparedIDs(r.OnlyB)
+	cmp.Same = r.Same
+	cmp.Differences = make([]IDDifference, len(r.Differences))
+	for i, d := range r.Differences {
+		cmp.Differences[i] = IDDifference{
+			ComparedID:      a.comparedID(d.Key),
+			CountA:          d.CountA,
+			CountB:          d.CountB,
+			CycleAMs:        milliseconds(d.CycleA),
+			CycleBMs:        milliseconds(d.CycleB),
+			CycleDiffers:    d.CycleDiffers,
+			LengthA:         d.LengthA,
+			LengthB:         d.LengthB,
+			ChangedA:        dataWords(d.ChangedA),
+			ChangedB:        dataWords(d.ChangedB),
+			ConstantDiffers: dataWords(d.ConstantDiffers),
+			PatternDiffers:  d.PatternDiffers,
+		}
+	}
+	return cmp, nil
+}
+
+// readComparedTrace reads a trace of CompareCaptures, prefixing its warnings
+// with its file name.
+func readComparedTrace(path string) ([]canlog.Record, []string, error) {
+	records, warnings, err := readTrace(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("%s: no frames", path)
+	}
+	name := filepath.Base(strings.TrimSpace(path))
+	for i, w := range warnings {
+		warnings[i] = name + ": " + w
+	}
+	return records, warnings, nil
+}
+
+// idSamples groups the payloads of the data frames of a trace by ID.
+func idSamples(records []canlog.Record) map[analysis.Key][]analysis.Sample {
+	samples := make(map[analysis.Key][]analysis.Sample)
+	for i := range records {
+		f := &records[i].Frame
+		if f.IsRemote || f.IsError {
+			continue
+		}
+		k := analysis.Key{ID: f.ID, Extended: f.IsExtended}
+		samples[k] = append(samples[k], analysis.Sample{Time: records[i].Timestamp, Data: f.Payload()})
+	}
+	return samples
+}
+
+func (a *App) comparedIDs(keys []analysis.Key) []ComparedID {
+	ids := make([]ComparedID, len(keys))
+	for i, k := range keys {
+		ids[i] = a.comparedID(k)
+	}
+	return ids
+}
+
+// comparedID names an ID with the loaded databases.
+func (a *App) comparedID(k analysis.Key) ComparedID {
+	id := ComparedID{ID: k.ID, Extended: k.Extended}
+	if m, ok := a.lookupMessage(k.ID, k.Extended); ok {
+		id.Name = m.Name
+	}
+	return id
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"canproject/canbus"
+)
+
+// conflictInterval is the minimum time between two "can:conflict" events of an ID.
+const conflictInterval = time.Second
+
+// IDConflictEvent is emitted on "can:conflict" when a frame with an ID the app
+// transmits cyclically is received from the bus, which hints at another node
+// sending the same ID. It is emitted for the first such frame, then at most
+// every second while the frames keep coming.
+type IDConflictEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	ID        uint32    `json:"id"`
+	Extended  bool      `json:"extended"`
+	// Handle is the cyclic transmission of the ID (see StartCyclicFrame).
+	Handle int `json:"handle"`
+	// Count is the number of conflicting frames since FirstSeen, Data the payload
+	// of the last one.
+	Count     uint64    `json:"count"`
+	FirstSeen time.Time `json:"firstSeen"`
+	Data      []uint32  `json:"data"`
+}
+
+type conflictKey struct {
+	iface    string
+	id       uint32
+	extended bool
+}
+
+type idConflict struct {
+	ev      IDConflictEvent
+	emitted time.Time
+}
+
+// ListIDConflicts returns the conflicts detected since the last ClearIDConflicts,
+// by interface and ID.
+func (a *App) ListIDConflicts() []IDConflictEvent {
+	a.conflictMu.Lock()
+	evs := make([]IDConflictEvent, 0, len(a.conflicts))
+	for _, c := range a.conflicts {
+		evs = append(evs, c.ev)
+	}
+	a.conflictMu.Unlock()
+
+	sort.Slice(evs, func(i, j int) bool {
+		if evs[i].Interface != evs[j].Interface {
+			return evs[i].Interface < evs[j].Interface
+		}
+		return evs[i].ID < evs[j].ID
+	})
+	return evs
+}
+
+// ClearIDConflicts forgets the detected conflicts, the next conflicting frame
+// of an ID is reported again right away.
+func (a *App) ClearIDConflicts() {
+	a.conflictMu.Lock()
+	a.conflicts = nil
+	a.conflictMu.Unlock()
+}
+
+// updateCyclicIDs rebuilds the IDs checked for conflicts from the cyclic
+// transmissions. It is called with cyclicMu held.
+func (a *App) updateCyclicIDs() {
+	ids := make(map[conflictKey]int, len(a.cyclicJobs))
+	for handle, job := range a.cyclicJobs {
+		ids[conflictKey{job.iface, job.frame.ID, job.frame.IsExtended}] = handle
+	}
+	a.cyclicIDs.Store(&ids)
+}
+
+// checkConflict reports a received frame whose ID is transmitted cyclically on
+// its interface.
+func (a *App) checkConflict(iface string, ts time.Time, f *canbus.Frame) {
+	ids := a.cyclicIDs.Load()
+	if ids == nil || len(*ids) == 0 {
+		return
+	}
+	key := conflictKey{iface, f.ID, f.IsExtended}
+	handle, ok := (*ids)[key]
+	if !ok {
+		return
+	}
+
+	a.conflictMu.Lock()
+	if a.conflicts == nil {
+		a.conflicts = make(map[conflictKey]*idConflict)
+	}
+	c := a.conflicts[key]
+	if c == nil {
+		c = &idConflict{ev: IDConflictEvent{Interface: iface, ID: f.ID, Extended: f.IsExtended, FirstSeen: ts}}
+		a.conflicts[key] = c
+	}
+	c.ev.Timestamp, c.ev.Handle, c.ev.Data = ts, handle, dataWords(f.Payload())
+	c.ev.Count++
+	emit := c.emitted.IsZero() || ts.Sub(c.emitted) >= conflictInterval
+	if emit {
+		c.emitted = ts
+	}
+	ev := c.ev
+	a.conflictMu.Unlock()
+
+	if emit {
+		a.emit("can:conflict", ev)
+	}
+}
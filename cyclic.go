@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+)
+
+// CyclicFrameInfo describes a running cyclic transmission.
+type CyclicFrameInfo struct {
+	Handle    int      `json:"handle"`
+	Interface string   `json:"interface"`
+	ID        uint32   `json:"id"`
+	Extended  bool     `json:"extended"`
+	FD        bool     `json:"fd"`
+	Data      []uint32 `json:"data"`
+	PeriodMs  int      `json:"periodMs"`
+	// Kernel is true when the frame is scheduled by the SocketCAN broadcast manager.
+	Kernel bool `json:"kernel"`
+}
+
+type cyclicJob struct {
+	handle int
+	iface  string
+	frame  canbus.Frame
+	period time.Duration
+
+	// bcm is set when the kernel broadcast manager transmits the frame,
+	// otherwise a goroutine does until cancel is called.
+	bcm    *canbus.BCM
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartCyclicFrame transmits a frame on a started interface every periodMs milliseconds
+// and returns a handle for StopCyclicFrame. The kernel broadcast manager is used when
+// available so the cycle time does not depend on the app being scheduled; otherwise
+// a scheduler goroutine transmits the frame.
+func (a *App) StartCyclicFrame(iface string, id uint32, data []byte, extended bool, periodMs int) (int, error) {
+	iface = strings.TrimSpace(iface)
+	if periodMs < 1 {
+		return 0, fmt.Errorf("period must be >= 1 ms (got %d)", periodMs)
+	}
+	f, err := newFrame(id, data, extended, len(data) > canbus.MaxDataLength, false)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := a.txConn(iface, f.IsFD); err != nil {
+		return 0, err
+	}
+
+	job := &cyclicJob{
+		iface:  iface,
+		frame:  f,
+		period: time.Duration(periodMs) * time.Millisecond,
+	}
+	a.schedule(job)
+
+	a.cyclicMu.Lock()
+	a.nextCyclic++
+	job.handle = a.nextCyclic
+	a.cyclicJobs[job.handle] = job
+	a.updateCyclicIDs()
+	a.cyclicMu.Unlock()
+
+	a.record(JournalEntry{Action: actionStartCyclicFrame, Interface: iface, ID: id, Extended: extended, Data: dataWords(f.Payload()), PeriodMs: periodMs, Handle: job.handle})
+	return job.handle, nil
+}
+
+// StopCyclicFrame stops a cyclic transmission started with StartCyclicFrame.
+func (a *App) StopCyclicFrame(handle int) error {
+	a.cyclicMu.Lock()
+	job, ok := a.cyclicJobs[handle]
+	delete(a.cyclicJobs, handle)
+	a.updateCyclicIDs()
+	a.cyclicMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("no cyclic frame with handle %d", handle)
+	}
+	job.stop()
+	a.record(JournalEntry{Action: actionStopCyclicFrame, Interface: job.iface, Handle: handle})
+	return nil
+}
+
+// ListCyclicFrames returns the running cyclic transmissions ordered by handle.
+func (a *App) ListCyclicFrames() []CyclicFrameInfo {
+	a.cyclicMu.Lock()
+	defer a.cyclicMu.Unlock()
+
+	infos := make([]CyclicFrameInfo, 0, len(a.cyclicJobs))
+	for _, job := range a.cyclicJobs {
+		infos = append(infos, job.info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Handle < infos[j].Handle
+	})
+	return infos
+}
+
+// stopCyclicFrames stops every cyclic transmission on iface.
+func (a *App) stopCyclicFrames(iface string) {
+	a.cyclicMu.Lock()
+	var jobs []*cyclicJob
+	for handle, job := range a.cyclicJobs {
+		if job.iface == iface {
+			jobs = append(jobs, job)
+			delete(a.cyclicJobs, handle)
+		}
+	}
+	a.updateCyclicIDs()
+	a.cyclicMu.Unlock()
+
+	for _, job := range jobs {
+		job.stop()
+	}
+}
+
+// restartCyclicFrames schedules the kernel transmitted cyclic frames of iface again
+// after it was reconnected, the broadcast manager drops them with the interface.
+func (a *App) restartCyclicFrames(iface string) {
+	a.cyclicMu.Lock()
+	defer a.cyclicMu.Unlock()
+
+	for _, job := range a.cyclicJobs {
+		if job.iface == iface && job.bcm != nil {
+			_ = job.bcm.Close()
+			job.bcm = nil
+			a.schedule(job)
+		}
+	}
+}
+
+// schedule starts the transmission of job, by the kernel broadcast manager when
+// available and the frame has no TX processor, or else by a goroutine.
+func (a *App) schedule(job *cyclicJob) {
+	job.done = make(chan struct{})
+	job.bcm, job.cancel = nil, nil
+	// the payload of a processed frame changes every cycle
+	if a.txProcessorFor(&job.frame) == nil {
+		if bcm, err := canbus.DialBCM(job.iface); err == nil {
+			if err := bcm.StartCyclic(job.frame, job.period); err == nil {
+				job.bcm = bcm
+				close(job.done)
+				return
+			}
+			_ = bcm.Close()
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job.cancel = cancel
+	go a.cyclicLoop(ctx, job)
+}
+
+func (a *App) cyclicLoop(ctx context.Context, job *cyclicJob) {
+	defer close(job.done)
+
+	ticker := time.NewTicker(job.period)
+	defer ticker.Stop()
+
+	failing := false
+	count := 0
+	for {
+		f := job.frame
+		if p := a.txProcessorFor(&f); p != nil {
+			p.apply(&f, &count)
+		}
+		err := a.send(job.iface, f)
+		// report the first failure of a run only, the next cycles would repeat it
+		if err != nil && !failing && ctx.Err() == nil {
+			a.emitError(fmt.Errorf("cyclic frame %s: %w", job.frame, err))
+		}
+		failing = err != nil
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *cyclicJob) stop() {
+	if j.bcm != nil {
+		_ = j.bcm.Close()
+	}
+	if j.cancel != nil {
+		j.cancel()
+	}
+	<-j.done
+}
+
+func (j *cyclicJob) info() CyclicFrameInfo {
+	return CyclicFrameInfo{
+		Handle:    j.handle,
+		Interface: j.iface,
+		ID:        j.frame.ID,
+		Extended:  j.frame.IsExtended,
+		FD:        j.frame.IsFD,
+		Data:      dataWords(j.frame.Payload()),
+		PeriodMs:  int(j.period / time.Millisecond),
+		Kernel:    j.bcm != nil,
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/candb"
+)
+
+// DBCInfo summarizes a loaded signal database.
+type DBCInfo struct {
+	Path     string `json:"path"`
+	Version  string `json:"version"`
+	Messages int    `json:"messages"`
+	Signals  int    `json:"signals"`
+	// Modified is set when the database was edited since it was loaded or saved.
+	Modified bool `json:"modified"`
+}
+
+// CANSignalsEvent carries the decoded signals of a received frame on "can:signals".
+type CANSignalsEvent struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Interface string        `json:"interface"`
+	ID        uint32        `json:"id"`
+	Extended  bool          `json:"extended"`
+	Message   string        `json:"message"`
+	Signals   []candb.Value `json:"signals"`
+}
+
+// LoadDBC parses a .dbc, .sym (PCAN), .kcd (Kayak) or .arxml (AUTOSAR) database and decodes
+// matching received frames into "can:signals" events.
+// Several databases can be loaded; loading the same path again replaces it.
+func (a *App) LoadDBC(path string) (DBCInfo, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return DBCInfo{}, fmt.Errorf("DBC path is empty")
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	db, err := candb.Load(path)
+	if err != nil {
+		return DBCInfo{}, err
+	}
+
+	a.dbMu.Lock()
+	replaced := false
+	for i, loaded := range a.databases {
+		if loaded.SourceFile == path {
+			a.databases[i] = db
+			replaced = true
+		}
+	}
+	if !replaced {
+		a.databases = append(a.databases, db)
+	}
+	a.markModified(path, false)
+	a.dbMu.Unlock()
+
+	return dbcInfo(db), nil
+}
+
+// UnloadDBC removes a database loaded with LoadDBC.
+func (a *App) UnloadDBC(path string) error {
+	if abs, err := filepath.Abs(strings.TrimSpace(path)); err == nil {
+		path = abs
+	}
+
+	a.dbMu.Lock()
+	defer a.dbMu.Unlock()
+	for i, db := range a.databases {
+		if db.SourceFile == path {
+			a.databases = append(a.databases[:i], a.databases[i+1:]...)
+			a.markModified(path, false)
+			return nil
+		}
+	}
+	return fmt.Errorf("DBC %s is not loaded", path)
+}
+
+// LoadedDBCs returns the databases loaded with LoadDBC, in load order.
+func (a *App) LoadedDBCs() []DBCInfo {
+	a.dbMu.RLock()
+	defer a.dbMu.RUnlock()
+
+	infos := make([]DBCInfo, 0, len(a.databases))
+	for _, db := range a.databases {
+		infos = append(infos, a.dbcInfoLocked(db))
+	}
+	return infos
+}
+
+// lookupMessage finds the message definition for a frame in the loaded databases.
+func (a *App) lookupMessage(id uint32, extended bool) (*candb.Message, bool) {
+	a.dbMu.RLock()
+	defer a.dbMu.RUnlock()
+
+	for _, db := range a.databases {
+		if m, ok := db.Message(id, extended); ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// decodeSignals decodes f with the loaded databases, or the loaded LDFs for a LIN
+// interface; ok is false when no message matches.
+func (a *App) decodeSignals(iface string, ts time.Time, f *canbus.Frame) (ev CANSignalsEvent, ok bool) {
+	if f.IsRemote {
+		return CANSignalsEvent{}, false
+	}
+	var m *candb.Message
+	if isLINInterface(iface) {
+		m, ok = a.lookupLINMessage(f.ID)
+	} else {
+		m, ok = a.lookupMessage(f.ID, f.IsExtended)
+	}
+	if !ok {
+		return CANSignalsEvent{}, false
+	}
+	return CANSignalsEvent{
+		Timestamp: ts,
+		Interface: iface,
+		ID:        f.ID,
+		Extended:  f.IsExtended,
+		Message:   m.Name,
+		Signals:   m.Decode(f.Payload()),
+	}, true
+}
+
+func dbcInfo(db *candb.Database) DBCInfo {
+	info := DBCInfo{
+		Path:     db.SourceFile,
+		Version:  db.Version,
+		Messages: len(db.Messages),
+	}
+	for _, m := range db.Messages {
+		info.Signals += len(m.Signals)
+	}
+	return info
+}
+
+// TxTemplate is the payload a message is built from by EncodeSignals and
+// EncodeAndSend, with its decoded signals.
+type TxTemplate struct {
+	Message  string        `json:"message"`
+	ID       uint32        `json:"id"`
+	Extended bool          `json:"extended"`
+	Data     []uint32      `json:"data"`
+	Signals  []candb.Value `json:"signals"`
+}
+
+// GetTxTemplate returns the transmit template of a message of the loaded databases:
+// the payload last sent with EncodeAndSend, all zero before.
+func (a *App) GetTxTemplate(messageName string) (TxTemplate, error) {
+	m, data, err := a.txTemplate(messageName)
+	if err != nil {
+		return TxTemplate{}, err
+	}
+	return TxTemplate{
+		Message:  m.Name,
+		ID:       m.ID,
+		Extended: m.IsExtended,
+		Data:     dataWords(data),
+		Signals:  m.Decode(data),
+	}, nil
+}
+
+// ResetTxTemplate zeroes the transmit template of a message.
+func (a *App) ResetTxTemplate(messageName string) {
+	a.dbMu.Lock()
+	delete(a.txTemplates, strings.TrimSpace(messageName))
+	a.dbMu.Unlock()
+}
+
+// EncodeSignals encodes the physical values of signals, by name, into the
+// transmit template of a message and returns the payload without sending it.
+// The signals not given keep their template values.
+func (a *App) EncodeSignals(messageName string, values map[string]float64) ([]uint32, error) {
+	_, data, err := a.encodeSignals(messageName, values)
+	if err != nil {
+		return nil, err
+	}
+	return dataWords(data), nil
+}
+
+// EncodeAndSend encodes signal values like EncodeSignals and sends the message on a
+// started interface, eg EncodeAndSend("can0", "SpeedControl", {"TargetSpeed": 50}).
+// The payload becomes the template of the message. Messages longer than 8 bytes
+// are sent as CAN FD frames.
+func (a *App) EncodeAndSend(iface string, messageName string, values map[string]float64) ([]uint32, error) {
+	m, data, err := a.encodeSignals(messageName, values)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.sendFrame(iface, m.ID, data, m.IsExtended, len(data) > canbus.MaxDataLength, false); err != nil {
+		return nil, err
+	}
+	a.dbMu.Lock()
+	if a.txTemplates == nil {
+		a.txTemplates = make(map[string][]byte)
+	}
+	a.txTemplates[m.Name] = data
+	a.dbMu.Unlock()
+	return dataWords(data), nil
+}
+
+func (a *App) encodeSignals(messageName string, values map[string]float64) (*candb.Message, []byte, error) {
+	m, data, err := a.txTemplate(messageName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := m.Encode(data, values); err != nil {
+		return nil, nil, err
+	}
+	return m, data, nil
+}
+
+// txTemplate returns the message of the loaded databases with the given name and a
+// copy of its template payload.
+func (a *App) txTemplate(messageName string) (*candb.Message, []byte, error) {
+	messageName = strings.TrimSpace(messageName)
+	a.dbMu.RLock()
+	defer a.dbMu.RUnlock()
+
+	for _, db := range a.databases {
+		if m, ok := db.MessageByName(messageName); ok {
+			data := make([]byte, m.Length)
+			copy(data, a.txTemplates[m.Name])
+			return m, data, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("no message %s in the loaded DBCs", messageName)
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"canproject/candb"
+)
+
+// DBCMessage is a message definition of a loaded database, as returned by
+// GetDBCMessages and edited with SetDBCMessage.
+type DBCMessage struct {
+	Name        string      `json:"name"`
+	ID          uint32      `json:"id"`
+	Extended    bool        `json:"extended"`
+	Length      int         `json:"length"`
+	Sender      string      `json:"sender"`
+	Description string      `json:"description"`
+	CycleTimeMs int         `json:"cycleTimeMs"`
+	Signals     []DBCSignal `json:"signals"`
+}
+
+// DBCSignal is a signal definition of a DBCMessage.
+type DBCSignal struct {
+	Name string `json:"name"`
+	// StartBit is the least significant bit of little endian (Intel) signals and the
+	// most significant bit of big endian (Motorola) ones, as in DBC files.
+	StartBit  int  `json:"startBit"`
+	Length    int  `json:"length"`
+	BigEndian bool `json:"bigEndian"`
+	Signed    bool `json:"signed"`
+	// ValueType is "integer", the default, "float32" or "float64".
+	ValueType string `json:"valueType"`
+	// Scale and Offset convert the raw value to the physical one, a zero scale is 1.
+	Scale       float64      `json:"scale"`
+	Offset      float64      `json:"offset"`
+	Min         float64      `json:"min"`
+	Max         float64      `json:"max"`
+	Unit        string       `json:"unit"`
+	Description string       `json:"description"`
+	Receivers   []string     `json:"receivers"`
+	Values      []ValueLabel `json:"values"`
+	// Multiplexer marks the multiplexer switch; Multiplexed signals are present when
+	// MultiplexerSwitch, the multiplexer of the message when empty, is MultiplexerValue.
+	Multiplexer       bool   `json:"multiplexer"`
+	Multiplexed       bool   `json:"multiplexed"`
+	MultiplexerValue  uint64 `json:"multiplexerValue"`
+	MultiplexerSwitch string `json:"multiplexerSwitch"`
+}
+
+// ValueLabel is the label of a raw signal value.
+type ValueLabel struct {
+	Value int64  `json:"value"`
+	Label string `json:"label"`
+}
+
+// NewDBC creates an empty database to be saved to path with SaveDBC, and loads it
+// so its messages decode received frames as they are defined.
+func (a *App) NewDBC(path string) (DBCInfo, error) {
+	path = absPath(path)
+	if path == "" {
+		return DBCInfo{}, fmt.Errorf("DBC path is empty")
+	}
+	db := candb.NewDatabase(path)
+
+	a.dbMu.Lock()
+	defer a.dbMu.Unlock()
+	for _, loaded := range a.databases {
+		if loaded.SourceFile == path {
+			return DBCInfo{}, fmt.Errorf("DBC %s is already loaded", path)
+		}
+	}
+	a.databases = append(a.databases, db)
+	a.markModified(path, true)
+	return a.dbcInfoLocked(db), nil
+}
+
+// GetDBCMessages returns the message definitions of a loaded database, sorted by ID.
+func (a *App) GetDBCMessages(path string) ([]DBCMessage, error) {
+	path = absPath(path)
+	a.dbMu.RLock()
+	defer a.dbMu.RUnlock()
+
+	for _, db := range a.databases {
+		if db.SourceFile == path {
+			msgs := make([]DBCMessage, len(db.Messages))
+			for i, m := range db.Messages {
+				msgs[i] = dbcMessage(m)
+			}
+			return msgs, nil
+		}
+	}
+	return nil, fmt.Errorf("DBC %s is not loaded", path)
+}
+
+// SetDBCMessage adds a message to a loaded database, or changes the message named
+// name when name is not empty. A nil Signals list keeps the signals of the edited
+// message. The change takes effect on the received frames at once; SaveDBC writes
+// it to the file.
+func (a *App) SetDBCMessage(path string, name string, msg DBCMessage) (DBCMessage, error) {
+	var saved DBCMessage
+	err := a.editDBC(path, func(db *candb.Database) error {
+		var old *candb.Message
+		if name = strings.TrimSpace(name); name != "" {
+			var ok bool
+			if old, ok = db.MessageByName(name); !ok {
+				return fmt.Errorf("no message %s in %s", name, path)
+			}
+		}
+		m := &candb.Message{
+			Name:        strings.TrimSpace(msg.Name),
+			ID:          msg.ID,
+			IsExtended:  msg.Extended,
+			Length:      msg.Length,
+			Sender:      strings.TrimSpace(msg.Sender),
+			Description: msg.Description,
+			CycleTime:   time.Duration(msg.CycleTimeMs) * time.Millisecond,
+		}
+		if old != nil {
+			// the editor does not show the PDUs of ARXML containers
+			m.ContainerHeader, m.Contained = old.ContainerHeader, old.Contained
+			if msg.Signals == nil {
+				m.Signals = old.Signals
+			}
+		}
+		for _, s := range msg.Signals {
+			sig, err := candbSignal(s, nil)
+			if err != nil {
+				return err
+			}
+			if old != nil {
+				if prev, ok := old.Signal(sig.Name); ok {
+					keepMultiplexing(sig, prev)
+				}
+			}
+			if err := m.SetSignal("", sig); err != nil {
+				return err
+			}
+		}
+		if err := db.SetMessage(name, m); err != nil {
+			return err
+		}
+		saved = dbcMessage(m)
+		return nil
+	})
+	return saved, err
+}
+
+// DeleteDBCMessage removes a message from a loaded database.
+func (a *App) DeleteDBCMessage(path string, name string) error {
+	return a.editDBC(path, func(db *candb.Database) error {
+		return db.RemoveMessage(strings.TrimSpace(name))
+	})
+}
+
+// SetDBCSignal adds a signal to a message of a loaded database, or changes the
+// signal named name when name is not empty, and returns the message.
+func (a *App) SetDBCSignal(path string, message string, name string, sig DBCSignal) (DBCMessage, error) {
+	var saved DBCMessage
+	err := a.editDBC(path, func(db *candb.Database) error {
+		m, ok := db.MessageByName(strings.TrimSpace(message))
+		if !ok {
+			return fmt.Errorf("no message %s in %s", message, path)
+		}
+		name = strings.TrimSpace(name)
+		var prev *candb.Signal
+		if name != "" {
+			if prev, ok = m.Signal(name); !ok {
+				return fmt.Errorf("%s has no signal %s", m.Name, name)
+			}
+		}
+		s, err := candbSignal(sig, prev)
+		if err != nil {
+			return err
+		}
+		if err := m.SetSignal(name, s); err != nil {
+			return err
+		}
+		// the signals multiplexed by a renamed switch follow it
+		if prev != nil && prev.Name != s.Name {
+			for _, other := range m.Signals {
+				if other.MultiplexerSwitch == prev.Name {
+					other.MultiplexerSwitch = s.Name
+				}
+			}
+		}
+		if err := db.SetMessage(m.Name, m); err != nil {
+			return err
+		}
+		saved = dbcMessage(m)
+		return nil
+	})
+	return saved, err
+}
+
+// DeleteDBCSignal removes a signal from a message of a loaded database.
+func (a *App) DeleteDBCSignal(path string, message string, name string) error {
+	return a.editDBC(path, func(db *candb.Database) error {
+		m, ok := db.MessageByName(strings.TrimSpace(message))
+		if !ok {
+			return fmt.Errorf("no message %s in %s", message, path)
+		}
+		if err := m.RemoveSignal(strings.TrimSpace(name)); err != nil {
+			return err
+		}
+		return db.SetMessage(m.Name, m)
+	})
+}
+
+// SaveDBC writes a loaded database to its file, or to dest when dest is not empty;
+// the database is then known by dest. A database loaded from a .sym, .kcd or
+// .arxml file is always written in the DBC format, so it needs a .dbc dest.
+func (a *App) SaveDBC(path string, dest string) (DBCInfo, error) {
+	path = absPath(path)
+	dest = absPath(dest)
+	if dest == "" {
+		dest = path
+	}
+	if !strings.EqualFold(filepath.Ext(dest), ".dbc") {
+		return DBCInfo{}, fmt.Errorf("%s is not a .dbc file", dest)
+	}
+
+	a.dbMu.Lock()
+	defer a.dbMu.Unlock()
+	i := -1
+	for j, db := range a.databases {
+		switch db.SourceFile {
+		case path:
+			i = j
+		case dest:
+			return DBCInfo{}, fmt.Errorf("DBC %s is already loaded", dest)
+		}
+	}
+	if i < 0 {
+		return DBCInfo{}, fmt.Errorf("DBC %s is not loaded", path)
+	}
+	db := a.databases[i]
+	if dest != path {
+		db = db.Clone()
+		db.SourceFile = dest
+	}
+	if err := db.SaveDBC(dest); err != nil {
+		return DBCInfo{}, err
+	}
+	a.databases[i] = db
+	a.markModified(path, false)
+	a.markModified(dest, false)
+	return a.dbcInfoLocked(db), nil
+}
+
+// editDBC applies edit to a copy of a loaded database and replaces it, so the
+// frames decoded meanwhile see either version.
+func (a *App) editDBC(path string, edit func(db *candb.Database) error) error {
+	path = absPath(path)
+	a.dbMu.Lock()
+	defer a.dbMu.Unlock()
+
+	for i, db := range a.databases {
+		if db.SourceFile != path {
+			continue
+		}
+		db = db.Clone()
+		if err := edit(db); err != nil {
+			return err
+		}
+		a.databases[i] = db
+		a.markModified(path, true)
+		return nil
+	}
+	return fmt.Errorf("DBC %s is not loaded", path)
+}
+
+// markModified records whether a database has changes not saved, dbMu must be held.
+func (a *App) markModified(path string, modified bool) {
+	if !modified {
+		delete(a.dbModified, path)
+		return
+	}
+	if a.dbModified == nil {
+		a.dbModified = make(map[string]bool)
+	}
+	a.dbModified[path] = true
+}
+
+// dbcInfoLocked returns the summary of a database, dbMu must be held.
+func (a *App) dbcInfoLocked(db *candb.Database) DBCInfo {
+	info := dbcInfo(db)
+	info.Modified = a.dbModified[db.SourceFile]
+	return info
+}
+
+func candbSignal(s DBCSignal, prev *candb.Signal) (*candb.Signal, error) {
+	sig := &candb.Signal{
+		Name:             strings.TrimSpace(s.Name),
+		Start:            s.StartBit,
+		Length:           s.Length,
+		IsBigEndian:      s.BigEndian,
+		IsSigned:         s.Signed,
+		Scale:            s.Scale,
+		Offset:           s.Offset,
+		Min:              s.Min,
+		Max:              s.Max,
+		Unit:             s.Unit,
+		Description:      s.Description,
+		Receivers:        s.Receivers,
+		IsMultiplexer:    s.Multiplexer,
+		IsMultiplexed:    s.Multiplexed,
+		MultiplexerValue: s.MultiplexerValue,
+	}
+	if sig.Scale == 0 {
+		sig.Scale = 1
+	}
+	switch s.ValueType {
+	case "", "integer":
+	case "float32":
+		sig.ValueType = candb.ValueTypeFloat32
+	case "float64":
+		sig.ValueType = candb.ValueTypeFloat64
+	default:
+		return nil, fmt.Errorf("%s: unknown value type %q", sig.Name, s.ValueType)
+	}
+	for _, v := range s.Values {
+		sig.ValueDescriptions = append(sig.ValueDescriptions, candb.ValueDescription{Value: v.Value, Description: v.Label})
+	}
+	if s.Multiplexed {
+		sig.MultiplexerSwitch = strings.TrimSpace(s.MultiplexerSwitch)
+	}
+	if prev != nil {
+		keepMultiplexing(sig, prev)
+	}
+	return sig, nil
+}
+
+// keepMultiplexing keeps the extended multiplexing ranges of prev, which DBCSignal
+// does not carry, when s is multiplexed the same way.
+func keepMultiplexing(s, prev *candb.Signal) {
+	if !s.IsMultiplexed || !prev.IsMultiplexed || s.MultiplexerValue != prev.MultiplexerValue {
+		return
+	}
+	if s.MultiplexerSwitch == "" || s.MultiplexerSwitch == prev.MultiplexerSwitch {
+		s.MultiplexerSwitch = prev.MultiplexerSwitch
+		s.MultiplexerRanges = prev.MultiplexerRanges
+	}
+}
+
+func dbcMessage(m *candb.Message) DBCMessage {
+	msg := DBCMessage{
+		Name:        m.Name,
+		ID:          m.ID,
+		Extended:    m.IsExtended,
+		Length:      m.Length,
+		Sender:      m.Sender,
+		Description: m.Description,
+		CycleTimeMs: int(m.CycleTime / time.Millisecond),
+		Signals:     make([]DBCSignal, len(m.Signals)),
+	}
+	for i, s := range m.Signals {
+		sig := DBCSignal{
+			Name:              s.Name,
+			StartBit:          s.Start,
+			Length:            s.Length,
+			BigEndian:         s.IsBigEndian,
+			Signed:            s.IsSigned,
+			ValueType:         "integer",
+			Scale:             s.Scale,
+			Offset:            s.Offset,
+			Min:               s.Min,
+			Max:               s.Max,
+			Unit:              s.Unit,
+			Description:       s.Description,
+			Receivers:         append([]string{}, s.Receivers...),
+			Values:            []ValueLabel{},
+			Multiplexer:       s.IsMultiplexer,
+			Multiplexed:       s.IsMultiplexed,
+			MultiplexerValue:  s.MultiplexerValue,
+			MultiplexerSwitch: s.MultiplexerSwitch,
+		}
+		switch s.ValueType {
+		case candb.ValueTypeFloat32:
+			sig.ValueType = "float32"
+		case candb.ValueTypeFloat64:
+			sig.ValueType = "float64"
+		}
+		for _, vd := range s.ValueDescriptions {
+			sig.Values = append(sig.Values, ValueLabel{Value: vd.Value, Label: vd.Description})
+		}
+		msg.Signals[i] = sig
+	}
+	return msg
+}
+
+// absPath returns the absolute form of a trimmed path, empty for an empty path.
+func absPath(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
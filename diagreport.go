@@ -0,0 +1,389 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"canproject/obd2"
+	"canproject/uds"
+)
+
+const (
+	// maxDiagExchanges bounds a diagnostic report, the later exchanges are
+	// counted as dropped.
+	maxDiagExchanges = 100000
+	// diagReportVersion is the format version of the JSON reports.
+	diagReportVersion = 1
+)
+
+// DiagExchange is a diagnostic request and its response in a DiagReport,
+// emitted on "diag:exchange" while recording.
+type DiagExchange struct {
+	Seq int `json:"seq"`
+	// Handle is the ISO-TP channel or DoIP connection of the request, 0 for
+	// the OBD requests of ReadOBDPID sent on Interface.
+	Handle    int    `json:"handle,omitempty"`
+	Interface string `json:"interface,omitempty"`
+	// Protocol is "uds", or "obd" for the OBD services (0x01-0x0A).
+	Protocol    string   `json:"protocol"`
+	Service     uint8    `json:"service"`
+	ServiceName string   `json:"serviceName"`
+	Request     []uint32 `json:"request"`
+	// Response is the final response, empty when none was received.
+	Response []uint32  `json:"response"`
+	Sent     time.Time `json:"sent"`
+	// DurationMs is the time from the request to Response.
+	DurationMs float64 `json:"durationMs"`
+	// Pending counts the "response pending" answers before Response.
+	Pending int    `json:"pending,omitempty"`
+	NRC     uint8  `json:"nrc,omitempty"`
+	NRCName string `json:"nrcName,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// DID, DTCs and OBD decode the positive responses of
+	// ReadDataByIdentifier, ReadDTCInformation and the OBD current data.
+	DID  *DiagDID      `json:"did,omitempty"`
+	DTCs []UDSDTC      `json:"dtcs,omitempty"`
+	OBD  *OBDParameter `json:"obd,omitempty"`
+}
+
+// DiagDID is the value of a data identifier read in a DiagExchange.
+type DiagDID struct {
+	ID   uint16   `json:"id"`
+	Name string   `json:"name"`
+	Data []uint32 `json:"data"`
+	// Text is Data as text when it is printable, eg for the VIN.
+	Text string `json:"text,omitempty"`
+	// Values are the values decoded with the description of LoadODX, up to
+	// DecodeError if the record does not match it.
+	Values      []DIDValue `json:"values,omitempty"`
+	DecodeError string     `json:"decodeError,omitempty"`
+}
+
+// DiagChannel describes a handle used in a DiagReport.
+type DiagChannel struct {
+	Handle int                 `json:"handle"`
+	IsoTP  *IsoTPChannelInfo   `json:"isotp,omitempty"`
+	DoIP   *DoIPConnectionInfo `json:"doip,omitempty"`
+}
+
+// DiagReport is a diagnostic session recorded with StartDiagRecording: every
+// UDS and OBD request with its response and timing.
+type DiagReport struct {
+	Version int       `json:"version"`
+	Title   string    `json:"title"`
+	Started time.Time `json:"started"`
+	// Stopped is zero while the recording runs.
+	Stopped   time.Time      `json:"stopped"`
+	Channels  []DiagChannel  `json:"channels"`
+	Exchanges []DiagExchange `json:"exchanges"`
+	// Negative counts the negative responses, Failed the requests without a
+	// response (timeouts, send errors), Dropped the exchanges beyond 100000.
+	Negative int `json:"negative"`
+	Failed   int `json:"failed"`
+	Dropped  int `json:"dropped"`
+}
+
+// DiagRecordingStatus describes the diagnostic recording.
+type DiagRecordingStatus struct {
+	Active    bool      `json:"active"`
+	Title     string    `json:"title"`
+	Started   time.Time `json:"started"`
+	Exchanges int       `json:"exchanges"`
+}
+
+type diagRecorder struct {
+	mu     sync.Mutex
+	report DiagReport
+	// handles are the handles of report.Channels.
+	handles map[int]bool
+}
+
+// StartDiagRecording records every UDS and OBD request sent from now on, by
+// the UDS and OBD APIs, flash downloads and keep-alives, with its response,
+// timing and decoded DIDs and DTCs, until StopDiagRecording. The report can
+// be exported with ExportDiagReport, eg to attach a flashing or readout
+// session to a ticket.
+func (a *App) StartDiagRecording(title string) error {
+	rec := &diagRecorder{
+		report: DiagReport{
+			Version:   diagReportVersion,
+			Title:     strings.TrimSpace(title),
+			Started:   time.Now(),
+			Channels:  []DiagChannel{},
+			Exchanges: []DiagExchange{},
+		},
+		handles: make(map[int]bool),
+	}
+	a.diagMu.Lock()
+	defer a.diagMu.Unlock()
+	if a.diagRec.Load() != nil {
+		return errors.New("diagnostic recording already running")
+	}
+	a.diagRec.Store(rec)
+	return nil
+}
+
+// StopDiagRecording stops the diagnostic recording and returns its report,
+// which ExportDiagReport exports until the next recording.
+func (a *App) StopDiagRecording() (DiagReport, error) {
+	a.diagMu.Lock()
+	defer a.diagMu.Unlock()
+	rec := a.diagRec.Swap(nil)
+	if rec == nil {
+		return DiagReport{}, errors.New("no diagnostic recording running")
+	}
+	rec.mu.Lock()
+	rec.report.Stopped = time.Now()
+	report := rec.report
+	rec.mu.Unlock()
+	a.diagReport = &report
+	return report, nil
+}
+
+// GetDiagRecordingStatus returns the state of the diagnostic recording.
+func (a *App) GetDiagRecordingStatus() DiagRecordingStatus {
+	rec := a.diagRec.Load()
+	if rec == nil {
+		return DiagRecordingStatus{}
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return DiagRecordingStatus{
+		Active:    true,
+		Title:     rec.report.Title,
+		Started:   rec.report.Started,
+		Exchanges: len(rec.report.Exchanges),
+	}
+}
+
+// ExportDiagReport writes the report of the running diagnostic recording, or
+// else of the last stopped one, to path: as an HTML page when path ends in
+// .html or .htm, as JSON otherwise. It returns the number of exchanges.
+func (a *App) ExportDiagReport(path string) (int, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return 0, errors.New("report path is empty")
+	}
+	var report DiagReport
+	a.diagMu.Lock()
+	if rec := a.diagRec.Load(); rec != nil {
+		rec.mu.Lock()
+		report = rec.report
+		report.Channels = append([]DiagChannel{}, report.Channels...)
+		report.Exchanges = append([]DiagExchange{}, report.Exchanges...)
+		rec.mu.Unlock()
+	} else if a.diagReport != nil {
+		report = *a.diagReport
+	} else {
+		a.diagMu.Unlock()
+		return 0, errors.New("no diagnostic report recorded")
+	}
+	a.diagMu.Unlock()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		err = diagReportPage.Execute(f, &report)
+	default:
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(&report)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(report.Exchanges), nil
+}
+
+// observeUDS returns the observer of the UDS client of handle.
+func (a *App) observeUDS(handle int) func(uds.Exchange) {
+	return func(x uds.Exchange) {
+		rec := a.diagRec.Load()
+		if rec == nil {
+			return
+		}
+		e := DiagExchange{
+			Handle:   handle,
+			Request:  dataWords(x.Request),
+			Response: dataWords(x.Response),
+			Sent:     x.Sent,
+			Pending:  x.Pending,
+		}
+		if len(x.Response) > 0 {
+			e.DurationMs = float64(x.Received.Sub(x.Sent).Microseconds()) / 1000
+		}
+		var nrc *uds.NegativeResponseError
+		switch {
+		case errors.As(x.Err, &nrc):
+			e.NRC, e.NRCName = nrc.Code, uds.NRCName(nrc.Code)
+		case x.Err != nil:
+			e.Error = x.Err.Error()
+		default:
+			a.decodeDiag(&e, x.Request, x.Response)
+		}
+		a.recordDiag(rec, e, x.Request[0])
+	}
+}
+
+// recordOBD records a mode 01 request of pid broadcast by ReadOBDPID and its
+// first answer, received at received.
+func (a *App) recordOBD(iface string, pid byte, sent, received time.Time, ev OBDPIDEvent, err error) {
+	rec := a.diagRec.Load()
+	if rec == nil {
+		return
+	}
+	e := DiagExchange{
+		Interface: iface,
+		Request:   []uint32{obd2.ServiceCurrentData, uint32(pid)},
+		Response:  []uint32{},
+		Sent:      sent,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	} else {
+		e.Response = append([]uint32{obd2.ServiceCurrentData + 0x40, uint32(pid)}, ev.Raw...)
+		e.DurationMs = float64(received.Sub(sent).Microseconds()) / 1000
+		e.OBD = &OBDParameter{PID: pid, Name: ev.Name, Unit: ev.Unit, Value: ev.Value, Known: ev.Known, Raw: ev.Raw}
+	}
+	a.recordDiag(rec, e, obd2.ServiceCurrentData)
+}
+
+// decodeDiag decodes the positive response resp to req into e.
+func (a *App) decodeDiag(e *DiagExchange, req, resp []byte) {
+	switch {
+	case req[0] == uds.ReadDataByIdentifier && len(resp) >= 3:
+		d := a.decodeDID(uint16(resp[1])<<8|uint16(resp[2]), resp[3:])
+		e.DID = &d
+	case req[0] == uds.ReadDTCInformation && len(req) >= 2 && req[1] == 0x02:
+		if dtcs, err := uds.ParseDTCs(resp); err == nil {
+			e.DTCs = a.describeDTCs(dtcs)
+		}
+	case req[0] == obd2.ServiceCurrentData && len(resp) >= 2:
+		if v, err := obd2.Decode(resp[1], resp[2:]); err == nil {
+			e.OBD = &OBDParameter{PID: resp[1], Name: v.Name, Unit: v.Unit, Value: v.Value, Known: true, Raw: dataWords(resp[2:])}
+		}
+	}
+}
+
+func printable(s string) bool {
+	for _, r := range s {
+		if r < 0x20 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// recordDiag adds e, a request of service sid, to the report of rec.
+func (a *App) recordDiag(rec *diagRecorder, e DiagExchange, sid byte) {
+	e.Service = sid
+	if sid <= 0x0a {
+		e.Protocol, e.ServiceName = "obd", fmt.Sprintf("OBD mode %02X", sid)
+	} else {
+		e.Protocol, e.ServiceName = "uds", uds.ServiceName(sid)
+	}
+	var ch *DiagChannel
+	if e.Handle != 0 {
+		ch = a.diagChannel(e.Handle)
+	}
+
+	rec.mu.Lock()
+	r := &rec.report
+	if len(r.Exchanges) >= maxDiagExchanges {
+		r.Dropped++
+		rec.mu.Unlock()
+		return
+	}
+	if ch != nil && !rec.handles[e.Handle] {
+		rec.handles[e.Handle] = true
+		r.Channels = append(r.Channels, *ch)
+	}
+	switch {
+	case e.NRCName != "":
+		r.Negative++
+	case e.Error != "":
+		r.Failed++
+	}
+	e.Seq = len(r.Exchanges) + 1
+	r.Exchanges = append(r.Exchanges, e)
+	rec.mu.Unlock()
+
+	a.emit("diag:exchange", e)
+}
+
+// diagChannel describes an open handle, nil if it was closed.
+func (a *App) diagChannel(handle int) *DiagChannel {
+	a.isotpMu.Lock()
+	defer a.isotpMu.Unlock()
+	if c := a.isotpChannels[handle]; c != nil {
+		info := c.info
+		return &DiagChannel{Handle: handle, IsoTP: &info}
+	}
+	if c := a.doipConns[handle]; c != nil {
+		info := c.info
+		return &DiagChannel{Handle: handle, DoIP: &info}
+	}
+	return nil
+}
+
+var diagReportPage = template.Must(template.New("report").Funcs(template.FuncMap{
+	"hex": func(b []uint32) string {
+		var sb strings.Builder
+		for i, v := range b {
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			fmt.Fprintf(&sb, "%02X", v)
+		}
+		return sb.String()
+	},
+	"time": func(t time.Time) string { return t.Format("2006-01-02 15:04:05.000") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{if .Title}}{{.Title}}{{else}}Diagnostic report{{end}}</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 2px 6px; text-align: left; vertical-align: top; }
+td.hex { font-family: monospace; }
+tr.negative td { background: #fde8e8; }
+tr.failed td { background: #fff4d6; }
+</style>
+</head>
+<body>
+<h1>{{if .Title}}{{.Title}}{{else}}Diagnostic report{{end}}</h1>
+<p>{{time .Started}} to {{if .Stopped.IsZero}}now{{else}}{{time .Stopped}}{{end}}:
+requests: {{len .Exchanges}}, negative responses: {{.Negative}}, without response: {{.Failed}}{{if .Dropped}}, dropped: {{.Dropped}}{{end}}.</p>
+{{if .Channels}}<h2>Channels</h2>
+<table>
+<tr><th>Handle</th><th>Channel</th></tr>
+{{range .Channels}}<tr><td>{{.Handle}}</td><td>{{with .IsoTP}}ISO-TP on {{.Interface}}, TX 0x{{printf "%X" .TxID}}, RX 0x{{printf "%X" .RxID}}{{end}}{{with .DoIP}}DoIP {{.Address}}, entity 0x{{printf "%04X" .EntityAddress}}{{end}}</td></tr>
+{{end}}</table>{{end}}
+<h2>Requests</h2>
+<table>
+<tr><th>#</th><th>Time</th><th>Handle</th><th>Service</th><th>Request</th><th>Response</th><th>ms</th><th>Result</th></tr>
+{{range .Exchanges}}<tr{{if .NRCName}} class="negative"{{else if .Error}} class="failed"{{end}}>
+<td>{{.Seq}}</td><td>{{time .Sent}}</td><td>{{if .Handle}}{{.Handle}}{{else}}{{.Interface}}{{end}}</td><td>{{.ServiceName}}</td>
+<td class="hex">{{hex .Request}}</td><td class="hex">{{hex .Response}}</td>
+<td>{{printf "%.2f" .DurationMs}}{{if .Pending}} ({{.Pending}} pending){{end}}</td>
+<td>{{if .NRCName}}{{.NRCName}} (0x{{printf "%02X" .NRC}}){{else if .Error}}{{.Error}}{{end}}{{with .DID}}{{.Name}}{{if and .Text (not .Values)}}: {{.Text}}{{end}}{{range .Values}}<br>{{.Name}} = {{if .Numeric}}{{printf "%g" .Value}}{{else}}{{.Text}}{{end}}{{if .Unit}} {{.Unit}}{{end}}{{end}}{{if .DecodeError}}<br>{{.DecodeError}}{{end}}{{end}}{{with .OBD}}{{.Name}}{{if .Known}}: {{printf "%g" .Value}} {{.Unit}}{{end}}{{end}}{{range $i, $d := .DTCs}}{{if $i}}<br>{{end}}{{.Name}}{{if .Description}} {{.Description}}{{end}} ({{range $i, $f := .StatusFlags}}{{if $i}}, {{end}}{{$f}}{{end}}){{end}}</td>
+</tr>
+{{end}}</table>
+</body>
+</html>
+`))
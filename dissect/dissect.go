@@ -0,0 +1,265 @@
+// Package dissect is the API of the user-defined protocol dissectors: they
+// decode the frames of the ID ranges they are registered for into trees of
+// named values, so proprietary protocols can be decoded without changing the
+// app.
+//
+// Dissectors are built as Go plugins exporting a Dissectors function:
+//
+//	package main
+//
+//	import (
+//		"fmt"
+//
+//		"canproject/canbus"
+//		"canproject/dissect"
+//	)
+//
+//	type bms struct{}
+//
+//	func (bms) Name() string            { return "acme-bms" }
+//	func (bms) Ranges() []dissect.Range { return []dissect.Range{{From: 0x600, To: 0x61f}} }
+//
+//	func (bms) Dissect(f *canbus.Frame) ([]dissect.Field, error) {
+//		return []dissect.Field{{Name: "cell", Value: fmt.Sprint(f.Data[0])}}, nil
+//	}
+//
+//	func Dissectors() []dissect.Dissector { return []dissect.Dissector{bms{}} }
+//
+// and compiled with go build -buildmode=plugin, with the Go version and the
+// module versions of the app, then loaded with LoadPlugin.
+package dissect
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"canproject/canbus"
+)
+
+// PluginSymbol is the function a plugin exports, of type func() []Dissector.
+const PluginSymbol = "Dissectors"
+
+// ErrPluginsNotAvailable is returned by LoadPlugin in the builds without Go
+// plugin support.
+var ErrPluginsNotAvailable = errors.New("Go plugins are only supported by the Linux, macOS and FreeBSD builds with cgo")
+
+// Field is a node of a decoded tree: a named value, a group of Children, or
+// both.
+type Field struct {
+	Name  string
+	Value string
+	Unit  string
+	// Children are the fields nested in this one, eg the members of a record.
+	Children []Field
+}
+
+// Range is a range of CAN IDs a dissector decodes.
+type Range struct {
+	From, To uint32
+	Extended bool
+}
+
+// Contains reports whether r holds the ID of f.
+func (r Range) Contains(f *canbus.Frame) bool {
+	return f.IsExtended == r.Extended && f.ID >= r.From && f.ID <= r.To
+}
+
+// Dissector decodes the frames of a protocol.
+type Dissector interface {
+	// Name identifies the dissector, eg "acme-bms".
+	Name() string
+	// Ranges are the IDs the dissector decodes by default.
+	Ranges() []Range
+	// Dissect decodes a frame of the ranges of the dissector. It returns no
+	// fields for the frames it does not decode.
+	Dissect(f *canbus.Frame) ([]Field, error)
+}
+
+// Result is the decoding of a frame by a dissector.
+type Result struct {
+	Dissector string
+	Fields    []Field
+	Err       error
+}
+
+// Registration is a dissector of a Registry and the ranges it is registered
+// for.
+type Registration struct {
+	Name      string
+	Dissector Dissector
+	Ranges    []Range
+	// Source is where the dissector comes from, eg the path of its plugin.
+	Source string
+}
+
+// Registry holds the registered dissectors. It is safe for concurrent use.
+type Registry struct {
+	mu   sync.RWMutex
+	regs []Registration
+}
+
+// Register adds a dissector for ranges, its own ranges when ranges is empty.
+// It fails if a dissector of the same name is registered.
+func (r *Registry) Register(d Dissector, ranges []Range, source string) error {
+	name, err := safeName(d)
+	if err != nil {
+		return err
+	}
+	if len(ranges) == 0 {
+		if ranges, err = safeRanges(name, d); err != nil {
+			return err
+		}
+	}
+	if err := validRanges(name, ranges); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, reg := range r.regs {
+		if reg.Name == name {
+			return fmt.Errorf("dissector %q already registered", name)
+		}
+	}
+	r.regs = append(r.regs, Registration{Name: name, Dissector: d, Ranges: append([]Range(nil), ranges...), Source: source})
+	sort.Slice(r.regs, func(i, j int) bool { return r.regs[i].Name < r.regs[j].Name })
+	return nil
+}
+
+// SetRanges changes the ranges of a registered dissector, back to its own
+// ranges when ranges is empty.
+func (r *Registry) SetRanges(name string, ranges []Range) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.regs {
+		reg := &r.regs[i]
+		if reg.Name != name {
+			continue
+		}
+		if len(ranges) == 0 {
+			var err error
+			if ranges, err = safeRanges(reg.Name, reg.Dissector); err != nil {
+				return err
+			}
+		}
+		if err := validRanges(name, ranges); err != nil {
+			return err
+		}
+		reg.Ranges = append([]Range(nil), ranges...)
+		return nil
+	}
+	return fmt.Errorf("no dissector %q", name)
+}
+
+// Unregister removes a dissector.
+func (r *Registry) Unregister(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, reg := range r.regs {
+		if reg.Name == name {
+			r.regs = append(r.regs[:i], r.regs[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no dissector %q", name)
+}
+
+// Registrations returns the registered dissectors by name.
+func (r *Registry) Registrations() []Registration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	regs := make([]Registration, len(r.regs))
+	for i, reg := range r.regs {
+		reg.Ranges = append([]Range(nil), reg.Ranges...)
+		regs[i] = reg
+	}
+	return regs
+}
+
+// Len returns the number of registered dissectors.
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.regs)
+}
+
+// Dissect decodes f with the dissectors registered for its ID. The results
+// without fields are left out, unless they failed; a dissector which panics
+// fails.
+func (r *Registry) Dissect(f *canbus.Frame) []Result {
+	if f.IsError || f.XL != nil {
+		return nil
+	}
+	r.mu.RLock()
+	var matched []Registration
+	for _, reg := range r.regs {
+		for _, rg := range reg.Ranges {
+			if rg.Contains(f) {
+				matched = append(matched, reg)
+				break
+			}
+		}
+	}
+	r.mu.RUnlock()
+
+	var results []Result
+	for _, reg := range matched {
+		fields, err := safeDissect(reg.Dissector, f)
+		if err != nil || len(fields) > 0 {
+			results = append(results, Result{Dissector: reg.Name, Fields: fields, Err: err})
+		}
+	}
+	return results
+}
+
+// ErrPanic is wrapped by the errors of the dissectors which panicked.
+var ErrPanic = errors.New("dissector panicked")
+
+func safeDissect(d Dissector, f *canbus.Frame) (fields []Field, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			fields, err = nil, fmt.Errorf("%w: %v", ErrPanic, p)
+		}
+	}()
+	// the dissector gets a copy, it cannot change the frame of the other stages
+	c := *f
+	return d.Dissect(&c)
+}
+
+func safeName(d Dissector) (name string, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%w: %v", ErrPanic, p)
+		}
+	}()
+	if name = d.Name(); name == "" {
+		return "", errors.New("dissector without name")
+	}
+	return name, nil
+}
+
+func safeRanges(name string, d Dissector) (ranges []Range, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%s: %w: %v", name, ErrPanic, p)
+		}
+	}()
+	return d.Ranges(), nil
+}
+
+func validRanges(name string, ranges []Range) error {
+	if len(ranges) == 0 {
+		return fmt.Errorf("dissector %s has no ID range", name)
+	}
+	for _, rg := range ranges {
+		limit := uint32(0x7ff)
+		if rg.Extended {
+			limit = 0x1fffffff
+		}
+		if rg.To < rg.From || rg.To > limit {
+			return fmt.Errorf("dissector %s: invalid ID range 0x%X-0x%X", name, rg.From, rg.To)
+		}
+	}
+	return nil
+}
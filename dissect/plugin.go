@@ -0,0 +1,29 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+package dissect
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// PluginsAvailable reports whether the app can load Go plugins.
+const PluginsAvailable = true
+
+// LoadPlugin opens a Go plugin and returns the dissectors of its Dissectors
+// function. A plugin cannot be unloaded once opened.
+func LoadPlugin(path string) ([]Dissector, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("dissector plugin %s: %w", path, err)
+	}
+	sym, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("dissector plugin %s: %w", path, err)
+	}
+	fn, ok := sym.(func() []Dissector)
+	if !ok {
+		return nil, fmt.Errorf("dissector plugin %s: %s is a %T, not a func() []dissect.Dissector", path, PluginSymbol, sym)
+	}
+	return fn(), nil
+}
@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd) || !cgo
+
+package dissect
+
+// PluginsAvailable reports whether the app can load Go plugins.
+const PluginsAvailable = false
+
+// LoadPlugin fails with ErrPluginsNotAvailable.
+func LoadPlugin(string) ([]Dissector, error) {
+	return nil, ErrPluginsNotAvailable
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/dissect"
+)
+
+// DissectorRange is a range of CAN IDs a dissector decodes.
+type DissectorRange struct {
+	From     uint32 `json:"from"`
+	To       uint32 `json:"to"`
+	Extended bool   `json:"extended"`
+}
+
+// DissectorInfo describes a loaded dissector.
+type DissectorInfo struct {
+	Name string `json:"name"`
+	// Source is the path of the plugin of the dissector.
+	Source string           `json:"source"`
+	Ranges []DissectorRange `json:"ranges"`
+}
+
+// DissectField is a node of the tree decoded by a dissector.
+type DissectField struct {
+	Name     string         `json:"name"`
+	Value    string         `json:"value"`
+	Unit     string         `json:"unit,omitempty"`
+	Children []DissectField `json:"children,omitempty"`
+}
+
+// DissectResult is the decoding of a frame by a dissector, Error is set when
+// the dissector failed.
+type DissectResult struct {
+	Dissector string         `json:"dissector"`
+	Fields    []DissectField `json:"fields"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// DissectEvent is a received frame decoded by the dissectors, emitted on
+// "can:dissect".
+type DissectEvent struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Interface string          `json:"interface"`
+	ID        uint32          `json:"id"`
+	Extended  bool            `json:"extended"`
+	Results   []DissectResult `json:"results"`
+}
+
+// LoadDissectorPlugin loads the dissectors of a Go plugin, see package dissect
+// for how to write one. They decode the received frames of their ID ranges,
+// emitted on "can:dissect". The plugin must be built with the Go version and
+// the module versions of the app.
+func (a *App) LoadDissectorPlugin(path string) ([]DissectorInfo, error) {
+	path = strings.TrimSpace(path)
+	dissectors, err := dissect.LoadPlugin(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(dissectors) == 0 {
+		return nil, fmt.Errorf("dissector plugin %s has no dissectors", path)
+	}
+
+	a.dissectMu.Lock()
+	defer a.dissectMu.Unlock()
+	var names []string
+	for _, d := range dissectors {
+		if err := a.dissectors.Register(d, nil, path); err != nil {
+			for _, name := range names {
+				_ = a.dissectors.Unregister(name)
+			}
+			return nil, fmt.Errorf("dissector plugin %s: %w", path, err)
+		}
+		names = append(names, d.Name())
+	}
+	if err := a.updateDissectStage(); err != nil {
+		return nil, err
+	}
+	infos := []DissectorInfo{}
+	for _, info := range a.ListDissectors() {
+		for _, name := range names {
+			if info.Name == name {
+				infos = append(infos, info)
+			}
+		}
+	}
+	return infos, nil
+}
+
+// SetDissectorRanges changes the ID ranges a loaded dissector decodes, back to
+// the ranges of the plugin when ranges is empty.
+func (a *App) SetDissectorRanges(name string, ranges []DissectorRange) error {
+	rs := make([]dissect.Range, len(ranges))
+	for i, r := range ranges {
+		rs[i] = dissect.Range{From: r.From, To: r.To, Extended: r.Extended}
+	}
+	return a.dissectors.SetRanges(strings.TrimSpace(name), rs)
+}
+
+// UnloadDissector stops a dissector. Its plugin stays in memory, Go plugins
+// cannot be closed; loading it again registers its dissectors anew.
+func (a *App) UnloadDissector(name string) error {
+	a.dissectMu.Lock()
+	defer a.dissectMu.Unlock()
+	if err := a.dissectors.Unregister(strings.TrimSpace(name)); err != nil {
+		return err
+	}
+	return a.updateDissectStage()
+}
+
+// DissectFrame decodes a frame with the loaded dissectors, eg a frame of the
+// capture buffer.
+func (a *App) DissectFrame(id uint32, data []byte, extended bool) ([]DissectResult, error) {
+	f, err := newFrame(id, data, extended, len(data) > canbus.MaxDataLength, false)
+	if err != nil {
+		return nil, err
+	}
+	return dissectResults(a.dissectors.Dissect(&f)), nil
+}
+
+// ListDissectors returns the loaded dissectors by name.
+func (a *App) ListDissectors() []DissectorInfo {
+	regs := a.dissectors.Registrations()
+	infos := make([]DissectorInfo, len(regs))
+	for i, reg := range regs {
+		infos[i] = DissectorInfo{Name: reg.Name, Source: reg.Source, Ranges: make([]DissectorRange, len(reg.Ranges))}
+		for j, r := range reg.Ranges {
+			infos[i].Ranges[j] = DissectorRange{From: r.From, To: r.To, Extended: r.Extended}
+		}
+	}
+	return infos
+}
+
+// updateDissectStage adds the "dissect" stage to the receive pipeline while
+// dissectors are loaded, after "signals". a.dissectMu must be held.
+func (a *App) updateDissectStage() error {
+	registered := false
+	for _, name := range a.rxPipeline.names() {
+		registered = registered || name == "dissect"
+	}
+	switch n := a.dissectors.Len(); {
+	case n > 0 && !registered:
+		return a.rxPipeline.register(frameProcessor{"dissect", func(rx *rxFrame) bool {
+			a.dispatchDissect(rx)
+			return true
+		}}, "isotp")
+	case n == 0 && registered:
+		return a.rxPipeline.remove("dissect")
+	}
+	return nil
+}
+
+// dispatchDissect decodes a received frame with the dissectors of its ID.
+func (a *App) dispatchDissect(rx *rxFrame) {
+	results := a.dissectors.Dissect(&rx.frame)
+	if len(results) == 0 {
+		return
+	}
+	a.emit("can:dissect", DissectEvent{
+		Timestamp: rx.info.Time,
+		Interface: rx.sess.iface,
+		ID:        rx.frame.ID,
+		Extended:  rx.frame.IsExtended,
+		Results:   dissectResults(results),
+	})
+}
+
+func dissectResults(results []dissect.Result) []DissectResult {
+	out := make([]DissectResult, len(results))
+	for i, r := range results {
+		out[i] = DissectResult{Dissector: r.Dissector, Fields: dissectFields(r.Fields)}
+		if r.Err != nil {
+			out[i].Error = r.Err.Error()
+		}
+	}
+	return out
+}
+
+func dissectFields(fields []dissect.Field) []DissectField {
+	out := make([]DissectField, len(fields))
+	for i, f := range fields {
+		out[i] = DissectField{Name: f.Name, Value: f.Value, Unit: f.Unit}
+		if len(f.Children) > 0 {
+			out[i].Children = dissectFields(f.Children)
+		}
+	}
+	return out
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"canproject/doip"
+	"canproject/uds"
+)
+
+// doipConnectTimeout bounds the connection and routing activation of OpenDoIP.
+const doipConnectTimeout = 10 * time.Second
+
+// DoIPEntity is a DoIP entity found by DiscoverDoIP.
+type DoIPEntity struct {
+	Address        string `json:"address"`
+	VIN            string `json:"vin"`
+	LogicalAddress uint16 `json:"logicalAddress"`
+	// EID and GID are the entity and group identifiers, as MAC addresses.
+	EID string `json:"eid"`
+	GID string `json:"gid"`
+	// FurtherAction is 0x10 when a central security routing activation is required.
+	FurtherAction uint8 `json:"furtherAction"`
+}
+
+// DoIPOptions configures a DoIP connection opened with OpenDoIP.
+type DoIPOptions struct {
+	// SourceAddress is the logical address of the tester, 0 for 0x0E00.
+	SourceAddress uint16 `json:"sourceAddress"`
+	// TargetAddress is the logical address of the ECU the requests are sent to.
+	TargetAddress uint16 `json:"targetAddress"`
+	// ActivationType is the routing activation type, 0 for the default activation.
+	ActivationType uint8 `json:"activationType"`
+}
+
+// DoIPConnectionInfo describes an open DoIP connection.
+type DoIPConnectionInfo struct {
+	Handle  int         `json:"handle"`
+	Address string      `json:"address"`
+	Options DoIPOptions `json:"options"`
+	// EntityAddress is the logical address of the entity that activated the routing.
+	EntityAddress uint16 `json:"entityAddress"`
+}
+
+// DoIPMessageEvent is a diagnostic message received on a DoIP connection,
+// emitted on "doip:message".
+type DoIPMessageEvent struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Handle        int       `json:"handle"`
+	Address       string    `json:"address"`
+	SourceAddress uint16    `json:"sourceAddress"`
+	Data          []uint32  `json:"data"`
+}
+
+type doipConnection struct {
+	info DoIPConnectionInfo
+	conn *doip.Conn
+	// uds answers the diagnostic requests sent on the connection.
+	uds *uds.Client
+}
+
+// DiscoverDoIP sends a vehicle identification request to address, or
+// broadcasts it on the local networks when address is empty, and returns the
+// DoIP entities answering within timeoutMs (0 for 2 s).
+func (a *App) DiscoverDoIP(address string, timeoutMs int) ([]DoIPEntity, error) {
+	if timeoutMs < 0 {
+		return nil, fmt.Errorf("timeout must be >= 0 ms (got %d)", timeoutMs)
+	}
+	entities, err := doip.Discover(context.Background(), strings.TrimSpace(address), time.Duration(timeoutMs)*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]DoIPEntity, len(entities))
+	for i, e := range entities {
+		out[i] = DoIPEntity{
+			Address:        e.Address,
+			VIN:            strings.TrimRight(e.VIN, "\x00\xff"),
+			LogicalAddress: e.LogicalAddress,
+			EID:            net.HardwareAddr(e.EID[:]).String(),
+			GID:            net.HardwareAddr(e.GID[:]).String(),
+			FurtherAction:  e.FurtherAction,
+		}
+	}
+	return out, nil
+}
+
+// OpenDoIP connects to the DoIP entity at address (host or host:port) and
+// activates the routing, for diagnostic messages to opts.TargetAddress. The
+// handle it returns is used like the one of an ISO-TP channel by the UDS
+// methods, SendDoIP sends raw messages. Received messages are emitted on
+// "doip:message".
+func (a *App) OpenDoIP(address string, opts DoIPOptions) (int, error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return 0, fmt.Errorf("DoIP address is empty")
+	}
+	if opts.SourceAddress == 0 {
+		opts.SourceAddress = 0x0e00
+	}
+
+	// the handles are shared with the ISO-TP channels
+	a.isotpMu.Lock()
+	a.nextIsoTP++
+	c := &doipConnection{info: DoIPConnectionInfo{Handle: a.nextIsoTP, Address: address, Options: opts}}
+	a.isotpMu.Unlock()
+	c.uds = uds.NewClient(c)
+	c.uds.Observe(a.observeUDS(c.info.Handle))
+
+	ctx, cancel := context.WithTimeout(context.Background(), doipConnectTimeout)
+	defer cancel()
+	conn, err := doip.Dial(ctx, address, doip.Config{
+		SourceAddress:  opts.SourceAddress,
+		TargetAddress:  opts.TargetAddress,
+		ActivationType: opts.ActivationType,
+		OnMessage: func(source uint16, data []byte) {
+			if source == opts.TargetAddress {
+				c.uds.HandleMessage(data)
+			}
+			a.emit("doip:message", DoIPMessageEvent{
+				Timestamp:     time.Now(),
+				Handle:        c.info.Handle,
+				Address:       address,
+				SourceAddress: source,
+				Data:          dataWords(data),
+			})
+		},
+		OnClose: func(err error) {
+			a.emitError(fmt.Errorf("DoIP %s: %w", address, err))
+			a.isotpMu.Lock()
+			delete(a.doipConns, c.info.Handle)
+			a.isotpMu.Unlock()
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	c.conn = conn
+	c.info.EntityAddress = conn.EntityAddress()
+
+	a.isotpMu.Lock()
+	defer a.isotpMu.Unlock()
+	if a.doipConns == nil {
+		a.doipConns = make(map[int]*doipConnection)
+	}
+	a.doipConns[c.info.Handle] = c
+	return c.info.Handle, nil
+}
+
+// SendDoIP sends a diagnostic message on a DoIP connection and returns once
+// the entity acknowledged it.
+func (a *App) SendDoIP(handle int, data []byte) error {
+	c, err := a.doipConnection(handle)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), isotpSendTimeout)
+	defer cancel()
+	return c.conn.Send(ctx, data)
+}
+
+// CloseDoIP closes a connection opened with OpenDoIP.
+func (a *App) CloseDoIP(handle int) error {
+	a.isotpMu.Lock()
+	c := a.doipConns[handle]
+	delete(a.doipConns, handle)
+	a.isotpMu.Unlock()
+
+	if c == nil {
+		return fmt.Errorf("no DoIP connection with handle %d", handle)
+	}
+	return c.conn.Close()
+}
+
+// ListDoIPConnections returns the open DoIP connections ordered by handle.
+func (a *App) ListDoIPConnections() []DoIPConnectionInfo {
+	a.isotpMu.Lock()
+	defer a.isotpMu.Unlock()
+
+	infos := make([]DoIPConnectionInfo, 0, len(a.doipConns))
+	for _, c := range a.doipConns {
+		infos = append(infos, c.info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Handle < infos[j].Handle
+	})
+	return infos
+}
+
+// Send sends a UDS request on the connection.
+func (c *doipConnection) Send(ctx context.Context, data []byte) error {
+	return c.conn.Send(ctx, data)
+}
+
+func (a *App) doipConnection(handle int) (*doipConnection, error) {
+	a.isotpMu.Lock()
+	defer a.isotpMu.Unlock()
+
+	c := a.doipConns[handle]
+	if c == nil {
+		return nil, fmt.Errorf("no DoIP connection with handle %d", handle)
+	}
+	return c, nil
+}
+
+// udsClient returns the UDS client of an ISO-TP channel or a DoIP connection.
+func (a *App) udsClient(handle int) (*uds.Client, error) {
+	a.isotpMu.Lock()
+	defer a.isotpMu.Unlock()
+
+	if c := a.isotpChannels[handle]; c != nil {
+		if c.info.Server {
+			return nil, fmt.Errorf("ISO-TP channel %d is a server", handle)
+		}
+		return c.uds, nil
+	}
+	if c := a.doipConns[handle]; c != nil {
+		return c.uds, nil
+	}
+	return nil, fmt.Errorf("no ISO-TP channel or DoIP connection with handle %d", handle)
+}
+
+// closeDoIPConnections closes every DoIP connection.
+func (a *App) closeDoIPConnections() {
+	a.isotpMu.Lock()
+	conns := a.doipConns
+	a.doipConns = nil
+	a.isotpMu.Unlock()
+
+	for _, c := range conns {
+		_ = c.conn.Close()
+	}
+}
@@ -0,0 +1,270 @@
+package doip
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	dialTimeout = 5 * time.Second
+	// ctrlTimeout is A_DoIP_Ctrl, the time the entity has to answer the routing
+	// activation, confirmationTimeout the time it has after asking for a
+	// confirmation and ackTimeout A_DoIP_Diagnostic_Message, the time it has to
+	// acknowledge a diagnostic message.
+	ctrlTimeout         = 2 * time.Second
+	confirmationTimeout = 5 * time.Second
+	ackTimeout          = 2 * time.Second
+)
+
+// Config describes a connection opened with Dial.
+type Config struct {
+	// SourceAddress is the logical address of the tester, eg 0x0E00.
+	SourceAddress uint16
+	// TargetAddress is the logical address of the ECU the diagnostic messages are sent to.
+	TargetAddress uint16
+	// ActivationType is the routing activation type, ActivationDefault usually.
+	ActivationType byte
+	// OnMessage is called from the read goroutine with the diagnostic messages
+	// sent to the tester and the address of their source. It must not block.
+	OnMessage func(source uint16, data []byte)
+	// OnClose is called once when the connection drops, with the error that
+	// dropped it, and not when it is closed with Close.
+	OnClose func(err error)
+}
+
+// Conn is a TCP connection to a DoIP entity with an active routing. It
+// implements the uds.Transport interface: Send sends a diagnostic message to
+// the target address and waits for the entity to acknowledge it.
+type Conn struct {
+	conn   net.Conn
+	cfg    Config
+	entity uint16
+
+	writeMu sync.Mutex
+	// sendMu serializes Send, ack receives the acknowledgement of the
+	// outstanding diagnostic message.
+	sendMu sync.Mutex
+	ack    chan error
+
+	closed chan struct{}
+	once   sync.Once
+	err    error
+}
+
+// Dial connects to the DoIP entity at addr, a host or host:port, and activates
+// the routing from cfg.SourceAddress.
+func Dial(ctx context.Context, addr string, cfg Config) (*Conn, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(Port))
+	}
+	d := net.Dialer{Timeout: dialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("doip: %w", err)
+	}
+	c := &Conn{conn: conn, cfg: cfg, ack: make(chan error, 1), closed: make(chan struct{})}
+	if err := c.activate(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// activate sends the routing activation request and waits for its response.
+func (c *Conn) activate(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() { _ = c.conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	req := make([]byte, 7)
+	binary.BigEndian.PutUint16(req, c.cfg.SourceAddress)
+	req[2] = c.cfg.ActivationType
+	if err := c.write(RoutingActivationRequest, req); err != nil {
+		return err
+	}
+	timeout := ctrlTimeout
+	for {
+		if err := c.conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+			return err
+		}
+		m, err := ReadMessage(c.conn)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("doip: routing activation: %w", err)
+		}
+		switch m.PayloadType {
+		case GenericNack:
+			if len(m.Payload) >= 1 {
+				return &GenericNackError{Code: m.Payload[0]}
+			}
+			return &GenericNackError{}
+		case AliveCheckRequest:
+			if err := c.aliveCheckResponse(); err != nil {
+				return err
+			}
+			continue
+		case RoutingActivationResponse:
+		default:
+			continue
+		}
+		if len(m.Payload) < 9 {
+			return fmt.Errorf("doip: routing activation response of %d bytes", len(m.Payload))
+		}
+		switch code := m.Payload[4]; code {
+		case routingSuccess:
+			c.entity = binary.BigEndian.Uint16(m.Payload[2:])
+			return c.conn.SetReadDeadline(time.Time{})
+		case routingConfirmationRequired:
+			// the final response follows once confirmed on the vehicle
+			timeout = confirmationTimeout
+		default:
+			return &RoutingError{Code: code}
+		}
+	}
+}
+
+// EntityAddress returns the logical address of the entity the routing was activated with.
+func (c *Conn) EntityAddress() uint16 {
+	return c.entity
+}
+
+// RemoteAddr returns the address of the entity.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Send sends a diagnostic message to the target address and returns once the
+// entity acknowledged it. A refused message is returned as a *NackError.
+func (c *Conn) Send(ctx context.Context, data []byte) error {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	// drop a late acknowledgement of a message that timed out
+	select {
+	case <-c.ack:
+	default:
+	}
+	msg := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint16(msg, c.cfg.SourceAddress)
+	binary.BigEndian.PutUint16(msg[2:], c.cfg.TargetAddress)
+	copy(msg[4:], data)
+	if err := c.write(DiagnosticMessage, msg); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(ackTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-c.ack:
+		return err
+	case <-timer.C:
+		return errors.New("doip: diagnostic message not acknowledged")
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.closed:
+		return c.closeErr()
+	}
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	c.shutdown(nil)
+	return c.conn.Close()
+}
+
+func (c *Conn) readLoop() {
+	for {
+		m, err := ReadMessage(c.conn)
+		if err != nil {
+			c.shutdown(err)
+			_ = c.conn.Close()
+			return
+		}
+		switch m.PayloadType {
+		case DiagnosticMessage:
+			if len(m.Payload) < 5 || binary.BigEndian.Uint16(m.Payload[2:]) != c.cfg.SourceAddress {
+				continue
+			}
+			if c.cfg.OnMessage != nil {
+				c.cfg.OnMessage(binary.BigEndian.Uint16(m.Payload), m.Payload[4:])
+			}
+		case DiagnosticMessagePositiveAck:
+			c.acknowledge(nil)
+		case DiagnosticMessageNegativeAck:
+			var code byte
+			if len(m.Payload) >= 5 {
+				code = m.Payload[4]
+			}
+			c.acknowledge(&NackError{Code: code})
+		case GenericNack:
+			var code byte
+			if len(m.Payload) >= 1 {
+				code = m.Payload[0]
+			}
+			c.acknowledge(&GenericNackError{Code: code})
+		case AliveCheckRequest:
+			if err := c.aliveCheckResponse(); err != nil {
+				c.shutdown(err)
+				_ = c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (c *Conn) acknowledge(err error) {
+	select {
+	case c.ack <- err:
+	default:
+	}
+}
+
+func (c *Conn) aliveCheckResponse() error {
+	return c.write(AliveCheckResponse, binary.BigEndian.AppendUint16(nil, c.cfg.SourceAddress))
+}
+
+func (c *Conn) write(payloadType uint16, payload []byte) error {
+	m := Message{Version: ProtocolVersion, PayloadType: payloadType, Payload: payload}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := c.conn.Write(m.Bytes()); err != nil {
+		select {
+		case <-c.closed:
+			return c.closeErr()
+		default:
+		}
+		return fmt.Errorf("doip: %w", err)
+	}
+	return nil
+}
+
+// shutdown records why the connection ended, a nil err for Close.
+func (c *Conn) shutdown(err error) {
+	c.once.Do(func() {
+		c.err = err
+		close(c.closed)
+		if err != nil && c.cfg.OnClose != nil {
+			c.cfg.OnClose(c.closeErr())
+		}
+	})
+}
+
+// closeErr is the error of operations on a closed connection.
+func (c *Conn) closeErr() error {
+	if c.err != nil && !errors.Is(c.err, io.EOF) {
+		return fmt.Errorf("doip: %w", c.err)
+	}
+	if c.err != nil {
+		return errors.New("doip: connection closed by the entity")
+	}
+	return net.ErrClosed
+}
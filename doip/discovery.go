@@ -0,0 +1,81 @@
+package doip
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DefaultDiscoveryTimeout is the time Discover waits for answers,
+// A_DoIP_Ctrl in ISO 13400-2.
+const DefaultDiscoveryTimeout = 2 * time.Second
+
+// Discover sends a vehicle identification request to addr, a host or
+// host:port, and returns the entities answering within timeout in the order
+// they answered. An empty addr broadcasts the request on the local networks,
+// a zero timeout waits DefaultDiscoveryTimeout.
+func Discover(ctx context.Context, addr string, timeout time.Duration) ([]Entity, error) {
+	if addr == "" {
+		addr = net.IPv4bcast.String()
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, strconv.Itoa(Port))
+	}
+	raddr, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = DefaultDiscoveryTimeout
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	req := Message{Version: DefaultVersion, PayloadType: VehicleIdentificationRequest}
+	if _, err := conn.WriteToUDP(req.Bytes(), raddr); err != nil {
+		return nil, err
+	}
+
+	entities := []Entity{}
+	seen := make(map[string]bool)
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return entities, ctx.Err()
+		}
+		if err != nil {
+			return entities, err
+		}
+		m, err := ParseMessage(buf[:n])
+		if err != nil || m.PayloadType != VehicleAnnouncement {
+			// not a DoIP entity, or another request
+			continue
+		}
+		e, err := parseAnnouncement(m.Payload)
+		if err != nil {
+			continue
+		}
+		e.Address = from.IP.String()
+		key := e.Address + "/" + strconv.Itoa(int(e.LogicalAddress))
+		if !seen[key] {
+			seen[key] = true
+			entities = append(entities, e)
+		}
+	}
+}
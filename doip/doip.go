@@ -0,0 +1,230 @@
+// Package doip is a client of ISO 13400-2 Diagnostics over IP (DoIP): it
+// discovers the DoIP entities of a network over UDP, activates the routing to
+// a vehicle over TCP and exchanges diagnostic messages with its ECUs, which
+// carry UDS requests like ISO-TP does on CAN.
+package doip
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// Port is the UDP discovery and TCP data port.
+	Port = 13400
+
+	// ProtocolVersion is the ISO 13400-2:2012 version of the headers sent,
+	// DefaultVersion the one of the vehicle identification requests.
+	ProtocolVersion = 0x02
+	DefaultVersion  = 0xff
+
+	headerLength = 8
+	// maxPayload bounds the messages read, 4 GiB are allowed by the header.
+	maxPayload = 1 << 20
+)
+
+// Payload types.
+const (
+	GenericNack                  = 0x0000
+	VehicleIdentificationRequest = 0x0001
+	VehicleIdentificationByEID   = 0x0002
+	VehicleIdentificationByVIN   = 0x0003
+	VehicleAnnouncement          = 0x0004
+	RoutingActivationRequest     = 0x0005
+	RoutingActivationResponse    = 0x0006
+	AliveCheckRequest            = 0x0007
+	AliveCheckResponse           = 0x0008
+	EntityStatusRequest          = 0x4001
+	EntityStatusResponse         = 0x4002
+	PowerModeRequest             = 0x4003
+	PowerModeResponse            = 0x4004
+	DiagnosticMessage            = 0x8001
+	DiagnosticMessagePositiveAck = 0x8002
+	DiagnosticMessageNegativeAck = 0x8003
+)
+
+// Routing activation types.
+const (
+	ActivationDefault       = 0x00
+	ActivationWWHOBD        = 0x01
+	ActivationCentralSecure = 0xe0
+)
+
+// routing activation response codes.
+const (
+	routingSuccess              = 0x10
+	routingConfirmationRequired = 0x11
+)
+
+var routingCodes = map[byte]string{
+	0x00:                        "unknown source address",
+	0x01:                        "all TCP sockets registered and active",
+	0x02:                        "source address already active on another socket",
+	0x03:                        "source address already registered on another socket",
+	0x04:                        "missing authentication",
+	0x05:                        "rejected confirmation",
+	0x06:                        "unsupported routing activation type",
+	0x07:                        "TLS required",
+	routingConfirmationRequired: "confirmation required",
+}
+
+var nackCodes = map[byte]string{
+	0x02: "invalid source address",
+	0x03: "unknown target address",
+	0x04: "diagnostic message too large",
+	0x05: "out of memory",
+	0x06: "target unreachable",
+	0x07: "unknown network",
+	0x08: "transport protocol error",
+}
+
+var genericNackCodes = map[byte]string{
+	0x00: "incorrect pattern format",
+	0x01: "unknown payload type",
+	0x02: "message too large",
+	0x03: "out of memory",
+	0x04: "invalid payload length",
+}
+
+// RoutingError is a routing activation request denied by the entity.
+type RoutingError struct {
+	Code byte
+}
+
+func (e *RoutingError) Error() string {
+	return fmt.Sprintf("doip: routing activation denied: %s (0x%02X)", codeName(routingCodes, e.Code), e.Code)
+}
+
+// NackError is a diagnostic message refused by the entity.
+type NackError struct {
+	Code byte
+}
+
+func (e *NackError) Error() string {
+	return fmt.Sprintf("doip: diagnostic message refused: %s (0x%02X)", codeName(nackCodes, e.Code), e.Code)
+}
+
+// GenericNackError is a message the entity could not process.
+type GenericNackError struct {
+	Code byte
+}
+
+func (e *GenericNackError) Error() string {
+	return fmt.Sprintf("doip: message rejected: %s (0x%02X)", codeName(genericNackCodes, e.Code), e.Code)
+}
+
+func codeName(names map[byte]string, code byte) string {
+	if n, ok := names[code]; ok {
+		return n
+	}
+	return "reserved"
+}
+
+// Message is a DoIP message.
+type Message struct {
+	Version     byte
+	PayloadType uint16
+	Payload     []byte
+}
+
+// Bytes returns the message with its generic header.
+func (m *Message) Bytes() []byte {
+	b := make([]byte, headerLength+len(m.Payload))
+	b[0], b[1] = m.Version, ^m.Version
+	binary.BigEndian.PutUint16(b[2:], m.PayloadType)
+	binary.BigEndian.PutUint32(b[4:], uint32(len(m.Payload)))
+	copy(b[headerLength:], m.Payload)
+	return b
+}
+
+// ParseMessage decodes a message, which must be the whole of b, eg a datagram.
+func ParseMessage(b []byte) (Message, error) {
+	if len(b) < headerLength {
+		return Message{}, fmt.Errorf("doip: message too short (%d bytes)", len(b))
+	}
+	var m Message
+	n, err := parseHeader(&m, b[:headerLength])
+	if err != nil {
+		return Message{}, err
+	}
+	if len(b) != headerLength+n {
+		return Message{}, fmt.Errorf("doip: payload length %d, got %d bytes", n, len(b)-headerLength)
+	}
+	m.Payload = append([]byte(nil), b[headerLength:]...)
+	return m, nil
+}
+
+// ReadMessage reads the next message of a stream.
+func ReadMessage(r io.Reader) (Message, error) {
+	var h [headerLength]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return Message{}, err
+	}
+	var m Message
+	n, err := parseHeader(&m, h[:])
+	if err != nil {
+		return Message{}, err
+	}
+	m.Payload = make([]byte, n)
+	if _, err := io.ReadFull(r, m.Payload); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return Message{}, err
+	}
+	return m, nil
+}
+
+// parseHeader decodes a generic header into m and returns the payload length.
+func parseHeader(m *Message, h []byte) (int, error) {
+	if h[0] != ^h[1] {
+		return 0, fmt.Errorf("doip: invalid header, version 0x%02X and inverse 0x%02X", h[0], h[1])
+	}
+	n := binary.BigEndian.Uint32(h[4:])
+	if n > maxPayload {
+		return 0, fmt.Errorf("doip: payload of %d bytes too large", n)
+	}
+	m.Version = h[0]
+	m.PayloadType = binary.BigEndian.Uint16(h[2:])
+	return int(n), nil
+}
+
+// Entity is a DoIP entity answering a vehicle identification request, or
+// announcing itself.
+type Entity struct {
+	// Address is the IP address of the entity.
+	Address string
+	VIN     string
+	// LogicalAddress is the address of the entity in the diagnostic messages.
+	LogicalAddress uint16
+	// EID identifies the entity, GID the group of entities of a vehicle; both are
+	// usually MAC addresses.
+	EID [6]byte
+	GID [6]byte
+	// FurtherAction is 0x10 when a central security routing activation is required.
+	FurtherAction byte
+	// SyncStatus is 0x00 when the VIN and GID are synchronized, 0x10 otherwise,
+	// HasSyncStatus false when the entity does not report it.
+	SyncStatus    byte
+	HasSyncStatus bool
+}
+
+// parseAnnouncement decodes a vehicle announcement payload.
+func parseAnnouncement(p []byte) (Entity, error) {
+	if len(p) != 32 && len(p) != 33 {
+		return Entity{}, fmt.Errorf("doip: vehicle announcement of %d bytes", len(p))
+	}
+	e := Entity{
+		VIN:            string(p[:17]),
+		LogicalAddress: binary.BigEndian.Uint16(p[17:]),
+		FurtherAction:  p[31],
+	}
+	copy(e.EID[:], p[19:25])
+	copy(e.GID[:], p[25:31])
+	if len(p) == 33 {
+		e.SyncStatus, e.HasSyncStatus = p[32], true
+	}
+	return e, nil
+}
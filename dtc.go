@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"canproject/dtc"
+	"canproject/uds"
+)
+
+// OBDDTC is a diagnostic trouble code read with OBDReadDTCs.
+type OBDDTC struct {
+	Code uint16 `json:"code"`
+	// Name is the code string, eg "P0123".
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// LoadDTCDatabase loads the DTC descriptions of a JSON or YAML file, with the
+// descriptions by code string ("P0123" or "P0123-13") under "codes" and the
+// failure type descriptions by byte in hex under "failureTypes". It replaces the
+// database loaded before and returns the number of codes.
+func (a *App) LoadDTCDatabase(path string) (int, error) {
+	path = strings.TrimSpace(path)
+	doc, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	db, err := dtc.Parse(doc)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	a.dtcDatabase.Store(db)
+	return len(db.Codes), nil
+}
+
+// UnloadDTCDatabase removes the DTC descriptions of LoadDTCDatabase.
+func (a *App) UnloadDTCDatabase() {
+	a.dtcDatabase.Store(nil)
+}
+
+// OBDReadDTCs reads the emission-related DTCs of an ECU on an ISO-TP channel opened
+// with OpenIsoTP (eg 0x7E0/0x7E8): the confirmed ones with mode 03, or the
+// pending ones with mode 07.
+func (a *App) OBDReadDTCs(handle int, pending bool) ([]OBDDTC, error) {
+	mode := byte(0x03)
+	if pending {
+		mode = 0x07
+	}
+	var resp []byte
+	err := a.withUDS(handle, func(ctx context.Context, c *uds.Client) (err error) {
+		resp, err = c.Request(ctx, []byte{mode})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// on CAN the response starts with the number of DTCs
+	if len(resp) < 2 || len(resp) < 2+2*int(resp[1]) {
+		return nil, fmt.Errorf("malformed mode %02X response % X", mode, resp)
+	}
+	db := a.dtcDatabase.Load()
+	records := resp[2 : 2+2*int(resp[1])]
+	out := make([]OBDDTC, 0, len(records)/2)
+	for i := 0; i < len(records); i += 2 {
+		code := uint16(records[i])<<8 | uint16(records[i+1])
+		if code == 0 {
+			continue
+		}
+		name := dtc.Format(code)
+		out = append(out, OBDDTC{Code: code, Name: name, Description: db.Describe(name)})
+	}
+	return out, nil
+}
@@ -0,0 +1,222 @@
+// Package dtc translates diagnostic trouble codes: the SAE J2012 / ISO 15031-6 code
+// strings (P0123), the failure type byte of ISO 14229 DTCs, the DTC status bits and
+// the descriptions of a loaded code database.
+package dtc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format returns the SAE J2012 string of a 2-byte code, eg 0x0123 is "P0123" and 0xC100 "U0100".
+func Format(code uint16) string {
+	const systems = "PCBU"
+	return fmt.Sprintf("%c%04X", systems[code>>14], code&0x3fff)
+}
+
+// FormatUDS returns the string of a 3-byte ISO 14229 DTC, the 2-byte code followed
+// by the failure type byte, eg "P0123-13".
+func FormatUDS(code uint32) string {
+	return fmt.Sprintf("%s-%02X", Format(uint16(code>>8)), byte(code))
+}
+
+// Status bits of an ISO 14229 DTC status byte.
+var statusBits = [8]string{
+	"testFailed",
+	"testFailedThisOperationCycle",
+	"pendingDTC",
+	"confirmedDTC",
+	"testNotCompletedSinceLastClear",
+	"testFailedSinceLastClear",
+	"testNotCompletedThisOperationCycle",
+	"warningIndicatorRequested",
+}
+
+// StatusFlags returns the names of the bits set in an ISO 14229 DTC status byte.
+func StatusFlags(status byte) []string {
+	flags := []string{}
+	for i, name := range statusBits {
+		if status&(1<<i) != 0 {
+			flags = append(flags, name)
+		}
+	}
+	return flags
+}
+
+// failureTypes are the SAE J2012 failure type bytes.
+var failureTypes = map[byte]string{
+	0x00: "no sub type information",
+	0x01: "general electrical failure",
+	0x02: "general signal failure",
+	0x03: "FM/PWM failure",
+	0x04: "system internal failure",
+	0x05: "system programming failure",
+	0x06: "algorithm based failure",
+	0x07: "mechanical failure",
+	0x08: "bus signal/message failure",
+	0x09: "component failure",
+	0x11: "circuit short to ground",
+	0x12: "circuit short to battery",
+	0x13: "circuit open",
+	0x14: "circuit short to ground or open",
+	0x15: "circuit short to battery or open",
+	0x16: "circuit voltage below threshold",
+	0x17: "circuit voltage above threshold",
+	0x18: "circuit current below threshold",
+	0x19: "circuit current above threshold",
+	0x1a: "circuit resistance below threshold",
+	0x1b: "circuit resistance above threshold",
+	0x1c: "circuit voltage out of range",
+	0x1d: "circuit current out of range",
+	0x1e: "circuit resistance out of range",
+	0x1f: "circuit intermittent",
+	0x21: "signal amplitude below minimum",
+	0x22: "signal amplitude above maximum",
+	0x23: "signal stuck low",
+	0x24: "signal stuck high",
+	0x25: "signal shape/waveform failure",
+	0x26: "signal rate of change below threshold",
+	0x27: "signal rate of change above threshold",
+	0x28: "signal bias level out of range",
+	0x29: "signal invalid",
+	0x2f: "signal erratic",
+	0x31: "no signal",
+	0x32: "signal low time below minimum",
+	0x33: "signal low time above maximum",
+	0x34: "signal high time below minimum",
+	0x35: "signal high time above maximum",
+	0x36: "signal frequency too low",
+	0x37: "signal frequency too high",
+	0x38: "signal frequency incorrect",
+	0x39: "too few pulses",
+	0x3a: "too many pulses",
+	0x41: "general checksum failure",
+	0x42: "general memory failure",
+	0x43: "special memory failure",
+	0x44: "data memory failure",
+	0x45: "program memory failure",
+	0x46: "calibration/parameter memory failure",
+	0x47: "watchdog/safety controller failure",
+	0x48: "supervision software failure",
+	0x49: "internal electronic failure",
+	0x4a: "incorrect component installed",
+	0x4b: "over temperature",
+	0x51: "not programmed",
+	0x52: "not activated",
+	0x53: "deactivated",
+	0x54: "missing calibration",
+	0x55: "not configured",
+	0x61: "signal calculation failure",
+	0x62: "signal compare failure",
+	0x63: "circuit/component protection time-out",
+	0x64: "signal plausibility failure",
+	0x65: "signal has too few transitions/events",
+	0x66: "signal has too many transitions/events",
+	0x67: "signal incorrect after event",
+	0x68: "event information",
+	0x71: "actuator stuck",
+	0x72: "actuator stuck open",
+	0x73: "actuator stuck closed",
+	0x74: "actuator slipping",
+	0x75: "emergency position not reachable",
+	0x76: "wrong mounting position",
+	0x77: "commanded position not reachable",
+	0x78: "alignment or adjustment incorrect",
+	0x79: "mechanical linkage failure",
+	0x7a: "fluid leak or seal failure",
+	0x7b: "low fluid level",
+	0x81: "invalid serial data received",
+	0x82: "alive/sequence counter incorrect",
+	0x83: "signal protection calculation incorrect",
+	0x84: "signal below allowable range",
+	0x85: "signal above allowable range",
+	0x86: "signal invalid",
+	0x87: "missing message",
+	0x88: "bus off",
+	0x91: "parametric",
+	0x92: "performance or incorrect operation",
+	0x93: "no operation",
+	0x94: "unexpected operation",
+	0x95: "incorrect assembly",
+	0x96: "component internal failure",
+	0x97: "component or system operation obstructed or blocked",
+	0x98: "component or system over temperature",
+}
+
+// Database holds the descriptions of DTCs and failure types, usually of one
+// manufacturer. It is read-only once parsed.
+type Database struct {
+	// Codes are the descriptions by code string: "P0123" for all its failure
+	// types or "P0123-13" for one.
+	Codes map[string]string `json:"codes"`
+	// FailureTypes override the SAE J2012 failure type descriptions, by byte in hex ("13").
+	FailureTypes map[string]string `json:"failureTypes,omitempty"`
+
+	failureTypes map[byte]string
+}
+
+// Parse decodes a JSON or YAML database:
+//
+//	codes:
+//	  P0123: Throttle position sensor A circuit high
+//	  U0100-87: Lost communication with ECM, missing message
+//	failureTypes:
+//	  "F0": Supplier specific failure
+func Parse(doc []byte) (*Database, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(doc, &v); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	db := &Database{}
+	if err := json.Unmarshal(b, db); err != nil {
+		return nil, err
+	}
+	codes := make(map[string]string, len(db.Codes))
+	for code, desc := range db.Codes {
+		codes[strings.ToUpper(strings.TrimSpace(code))] = desc
+	}
+	db.Codes = codes
+	db.failureTypes = make(map[byte]string, len(db.FailureTypes))
+	for ftb, desc := range db.FailureTypes {
+		n, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ftb)), "0x"), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("failure type %q: not a byte in hex", ftb)
+		}
+		db.failureTypes[byte(n)] = desc
+	}
+	return db, nil
+}
+
+// Describe returns the description of a code string, "P0123-13" falling back to
+// "P0123". db may be nil.
+func (db *Database) Describe(code string) string {
+	if db == nil {
+		return ""
+	}
+	if desc, ok := db.Codes[code]; ok {
+		return desc
+	}
+	if i := strings.IndexByte(code, '-'); i > 0 {
+		return db.Codes[code[:i]]
+	}
+	return ""
+}
+
+// FailureType returns the description of a failure type byte, from the database
+// first. db may be nil.
+func (db *Database) FailureType(ftb byte) string {
+	if db != nil {
+		if desc, ok := db.failureTypes[ftb]; ok {
+			return desc
+		}
+	}
+	return failureTypes[ftb]
+}
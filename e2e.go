@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/e2e"
+)
+
+const (
+	// e2eCheckInterval is the period the timeouts of the protected messages are
+	// checked at.
+	e2eCheckInterval = 20 * time.Millisecond
+	// e2eEventInterval is the minimum time between two "can:e2e" events of a
+	// message with the same status.
+	e2eEventInterval = time.Second
+)
+
+// E2EProtection is the end-to-end protection a received message is checked
+// against, see SetE2EProtection. The offsets are in bits from the start of the
+// payload; unset ones take the standard layout of the profile.
+type E2EProtection struct {
+	// Interface is the interface the message is received on, empty for all.
+	Interface string `json:"interface"`
+	ID        uint32 `json:"id"`
+	Extended  bool   `json:"extended"`
+	// Profile is "P01", "P02", "P05" or "P11".
+	Profile string `json:"profile"`
+	// DataID is the data ID of the profiles 1, 5 and 11, DataIDMode how the
+	// profiles 1 and 11 include it: "both" (default), "alt", "low" or "nibble".
+	DataID     uint16 `json:"dataId"`
+	DataIDMode string `json:"dataIdMode,omitempty"`
+	// DataIDList are the 16 data IDs of the profile 2.
+	DataIDList []uint8 `json:"dataIdList,omitempty"`
+	// CRCOffset (0), CounterOffset (8) and DataIDNibbleOffset (12) are the
+	// layout of the profiles 1 and 11, Offset (0) the header of the profile 5.
+	CRCOffset          *int `json:"crcOffset,omitempty"`
+	CounterOffset      *int `json:"counterOffset,omitempty"`
+	DataIDNibbleOffset *int `json:"dataIdNibbleOffset,omitempty"`
+	Offset             *int `json:"offset,omitempty"`
+	// MaxDeltaCounter is the largest counter increment accepted, 1 when zero.
+	MaxDeltaCounter int `json:"maxDeltaCounter"`
+	// TimeoutMs reports the message when no frame is received for that long
+	// after the last one, 0 for no timeout.
+	TimeoutMs int `json:"timeoutMs"`
+}
+
+// E2EStatus is the state of a protected message on an interface.
+type E2EStatus struct {
+	Interface string `json:"interface"`
+	ID        uint32 `json:"id"`
+	Extended  bool   `json:"extended"`
+	Message   string `json:"message,omitempty"`
+	Profile   string `json:"profile"`
+	// Frames is the number of checked frames, then the failures by kind.
+	Frames        uint64 `json:"frames"`
+	CRCErrors     uint64 `json:"crcErrors"`
+	Lost          uint64 `json:"lost"`
+	Repeated      uint64 `json:"repeated"`
+	WrongSequence uint64 `json:"wrongSequence"`
+	Invalid       uint64 `json:"invalid"`
+	Timeouts      uint64 `json:"timeouts"`
+	// Status is the result of the last check, "timeout" while the message is
+	// missing.
+	Status string    `json:"status"`
+	Last   time.Time `json:"last"`
+}
+
+// E2EEvent is emitted on "can:e2e" when a check of a protected message fails:
+// Status is "crc-error", "lost" (counter skip within MaxDeltaCounter),
+// "repeated", "wrong-sequence", "invalid" or "timeout". It is emitted for the
+// first such failure, then at most every second per message and status while
+// they keep coming; Count is the number of failures of the status.
+type E2EEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	ID        uint32    `json:"id"`
+	Extended  bool      `json:"extended"`
+	Message   string    `json:"message,omitempty"`
+	Profile   string    `json:"profile"`
+	Status    string    `json:"status"`
+	Count     uint64    `json:"count"`
+	// Counter is the received counter and Delta its increment, CRC and
+	// ExpectedCRC the received and computed CRCs.
+	Counter     int      `json:"counter"`
+	Delta       int      `json:"delta"`
+	CRC         uint16   `json:"crc"`
+	ExpectedCRC uint16   `json:"expectedCrc"`
+	Data        []uint32 `json:"data,omitempty"`
+}
+
+// e2eMessage is a protected message, the states by interface are guarded by
+// e2eMu.
+type e2eMessage struct {
+	cfg     E2EProtection
+	check   e2e.Config
+	timeout time.Duration
+	states  map[string]*e2eState
+}
+
+type e2eState struct {
+	checker *e2e.Checker
+	status  E2EStatus
+	// timedOut is set once the timeout of the last frame was reported.
+	timedOut bool
+	emitted  map[string]time.Time
+}
+
+// SetE2EProtection checks the received frames of a message with an AUTOSAR E2E
+// profile, replacing the protection of the same interface and ID. The CRC
+// failures, counter skips and timeouts are counted per message and interface,
+// see ListE2EStatus, and emitted on "can:e2e".
+func (a *App) SetE2EProtection(p E2EProtection) error {
+	p.Interface = strings.TrimSpace(p.Interface)
+	profile, err := e2e.ParseProfile(p.Profile)
+	if err != nil {
+		return err
+	}
+	cfg := e2e.DefaultConfig(profile)
+	cfg.DataID = p.DataID
+	if cfg.DataIDMode, err = e2e.ParseDataIDMode(p.DataIDMode); err != nil {
+		return err
+	}
+	if profile == e2e.Profile2 {
+		if len(p.DataIDList) != len(cfg.DataIDList) {
+			return fmt.Errorf("profile 2 needs %d data IDs, got %d", len(cfg.DataIDList), len(p.DataIDList))
+		}
+		copy(cfg.DataIDList[:], p.DataIDList)
+	}
+	for _, o := range []struct {
+		src *int
+		dst *int
+	}{{p.CRCOffset, &cfg.CRCOffset}, {p.CounterOffset, &cfg.CounterOffset}, {p.DataIDNibbleOffset, &cfg.DataIDNibbleOffset}, {p.Offset, &cfg.Offset}} {
+		if o.src != nil {
+			*o.dst = *o.src
+		}
+	}
+	if p.MaxDeltaCounter != 0 {
+		cfg.MaxDeltaCounter = p.MaxDeltaCounter
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("E2E protection of %s: %w", formatCANID(p.ID, p.Extended), err)
+	}
+	if p.TimeoutMs < 0 {
+		return fmt.Errorf("invalid timeout %d ms", p.TimeoutMs)
+	}
+	p.Profile = string(profile)
+
+	msg := &e2eMessage{cfg: p, check: cfg, timeout: time.Duration(p.TimeoutMs) * time.Millisecond, states: make(map[string]*e2eState)}
+	if p.Interface != "" && msg.timeout > 0 {
+		// the timeout of an interface runs from the configuration until the first
+		// frame
+		msg.states[p.Interface] = a.newE2EState(msg, p.Interface, time.Now())
+	}
+	a.updateE2E(func(msgs map[conflictKey]*e2eMessage) {
+		msgs[conflictKey{p.Interface, p.ID, p.Extended}] = msg
+	})
+	return nil
+}
+
+// RemoveE2EProtection removes the protection of a message set with
+// SetE2EProtection.
+func (a *App) RemoveE2EProtection(iface string, id uint32, extended bool) error {
+	key := conflictKey{strings.TrimSpace(iface), id, extended}
+	if msgs := a.e2eMessages.Load(); msgs == nil || (*msgs)[key] == nil {
+		return fmt.Errorf("no E2E protection of %s on %q", formatCANID(id, extended), key.iface)
+	}
+	a.updateE2E(func(msgs map[conflictKey]*e2eMessage) { delete(msgs, key) })
+	return nil
+}
+
+// ListE2EProtections returns the protections of SetE2EProtection.
+func (a *App) ListE2EProtections() []E2EProtection {
+	list := []E2EProtection{}
+	if msgs := a.e2eMessages.Load(); msgs != nil {
+		for _, m := range *msgs {
+			list = append(list, m.cfg)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Interface != list[j].Interface {
+			return list[i].Interface < list[j].Interface
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// ListE2EStatus returns the state of the protected messages by interface and ID.
+func (a *App) ListE2EStatus() []E2EStatus {
+	list := []E2EStatus{}
+	msgs := a.e2eMessages.Load()
+	if msgs == nil {
+		return list
+	}
+	a.e2eMu.Lock()
+	for _, m := range *msgs {
+		for _, s := range m.states {
+			list = append(list, s.status)
+		}
+	}
+	a.e2eMu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Interface != list[j].Interface {
+			return list[i].Interface < list[j].Interface
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// ResetE2EStatus clears the counts of the protected messages and resynchronizes
+// their counters, the timeouts running from now.
+func (a *App) ResetE2EStatus() {
+	msgs := a.e2eMessages.Load()
+	if msgs == nil {
+		return
+	}
+	now := time.Now()
+	a.e2eMu.Lock()
+	defer a.e2eMu.Unlock()
+	for _, m := range *msgs {
+		for iface := range m.states {
+			m.states[iface] = a.newE2EState(m, iface, now)
+		}
+	}
+}
+
+// stopE2E forgets the state of the protected messages on a stopped interface,
+// they do not time out.
+func (a *App) stopE2E(iface string) {
+	msgs := a.e2eMessages.Load()
+	if msgs == nil {
+		return
+	}
+	a.e2eMu.Lock()
+	defer a.e2eMu.Unlock()
+	for _, m := range *msgs {
+		delete(m.states, iface)
+	}
+}
+
+// updateE2E applies change to a copy of the protected messages and publishes it.
+func (a *App) updateE2E(change func(map[conflictKey]*e2eMessage)) {
+	a.e2eChangeMu.Lock()
+	defer a.e2eChangeMu.Unlock()
+
+	msgs := make(map[conflictKey]*e2eMessage)
+	if old := a.e2eMessages.Load(); old != nil {
+		for k, m := range *old {
+			msgs[k] = m
+		}
+	}
+	change(msgs)
+	a.e2eMessages.Store(&msgs)
+}
+
+func (a *App) newE2EState(m *e2eMessage, iface string, now time.Time) *e2eState {
+	checker, _ := e2e.NewChecker(m.check)
+	s := &e2eState{checker: checker, emitted: make(map[string]time.Time)}
+	s.status = E2EStatus{Interface: iface, ID: m.cfg.ID, Extended: m.cfg.Extended, Profile: m.cfg.Profile, Status: "ok", Last: now}
+	if msg, ok := a.lookupMessage(m.cfg.ID, m.cfg.Extended); ok {
+		s.status.Message = msg.Name
+	}
+	return s
+}
+
+// checkE2E checks a received frame of a protected message.
+func (a *App) checkE2E(iface string, ts time.Time, f *canbus.Frame) {
+	msgs := a.e2eMessages.Load()
+	if msgs == nil || len(*msgs) == 0 || f.IsRemote {
+		return
+	}
+	m := (*msgs)[conflictKey{iface, f.ID, f.IsExtended}]
+	if m == nil {
+		if m = (*msgs)[conflictKey{"", f.ID, f.IsExtended}]; m == nil {
+			return
+		}
+	}
+
+	a.e2eMu.Lock()
+	s := m.states[iface]
+	if s == nil {
+		s = a.newE2EState(m, iface, ts)
+		m.states[iface] = s
+	}
+	if s.timedOut {
+		s.checker.Resync()
+		s.timedOut = false
+	}
+	r := s.checker.Check(f.Payload())
+	st := &s.status
+	st.Frames++
+	st.Status, st.Last = r.Status.String(), ts
+	var count *uint64
+	switch r.Status {
+	case e2e.WrongCRC:
+		count = &st.CRCErrors
+	case e2e.OKSomeLost:
+		count = &st.Lost
+	case e2e.Repeated:
+		count = &st.Repeated
+	case e2e.WrongSequence:
+		count = &st.WrongSequence
+	case e2e.Invalid:
+		count = &st.Invalid
+	}
+	var ev E2EEvent
+	emit := false
+	if count != nil {
+		*count++
+		ev = e2eEvent(s, ts, *count)
+		ev.Counter, ev.Delta, ev.CRC, ev.ExpectedCRC = r.Counter, r.Delta, r.CRC, r.ExpectedCRC
+		ev.Data = dataWords(f.Payload())
+		emit = s.throttle(ev.Status, ts)
+	}
+	a.e2eMu.Unlock()
+
+	if emit {
+		a.emit("can:e2e", ev)
+	}
+}
+
+// e2eLoop reports the protected messages which timed out, until ctx is done.
+func (a *App) e2eLoop(ctx context.Context) {
+	ticker := time.NewTicker(e2eCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, ev := range a.checkE2ETimeouts(now) {
+				a.emit("can:e2e", ev)
+			}
+		}
+	}
+}
+
+func (a *App) checkE2ETimeouts(now time.Time) []E2EEvent {
+	msgs := a.e2eMessages.Load()
+	if msgs == nil {
+		return nil
+	}
+	var evs []E2EEvent
+	a.e2eMu.Lock()
+	defer a.e2eMu.Unlock()
+	for _, m := range *msgs {
+		if m.timeout <= 0 {
+			continue
+		}
+		for _, s := range m.states {
+			if s.timedOut || now.Sub(s.status.Last) < m.timeout {
+				continue
+			}
+			s.timedOut = true
+			s.status.Timeouts++
+			s.status.Status = "timeout"
+			if s.throttle(s.status.Status, now) {
+				evs = append(evs, e2eEvent(s, now, s.status.Timeouts))
+			}
+		}
+	}
+	return evs
+}
+
+// throttle reports whether an event of status should be emitted at ts.
+func (s *e2eState) throttle(status string, ts time.Time) bool {
+	last, ok := s.emitted[status]
+	if ok && ts.Sub(last) < e2eEventInterval {
+		return false
+	}
+	s.emitted[status] = ts
+	return true
+}
+
+func e2eEvent(s *e2eState, ts time.Time, count uint64) E2EEvent {
+	return E2EEvent{
+		Timestamp: ts,
+		Interface: s.status.Interface,
+		ID:        s.status.ID,
+		Extended:  s.status.Extended,
+		Message:   s.status.Message,
+		Profile:   s.status.Profile,
+		Status:    s.status.Status,
+		Count:     count,
+	}
+}
@@ -0,0 +1,318 @@
+// Package e2e checks the AUTOSAR end-to-end protection of received payloads:
+// the CRC and the sequence counter of the E2E profiles 1, 2, 5 and 11.
+//
+// The checks follow the profiles of the AUTOSAR E2E library, as a monitor
+// rather than a receiver: a counter jump is reported once and resynchronizes
+// the checker on the new counter.
+package e2e
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"canproject/checksum"
+)
+
+// Profile is an E2E profile.
+type Profile string
+
+// The supported profiles.
+const (
+	Profile1  Profile = "P01"
+	Profile2  Profile = "P02"
+	Profile5  Profile = "P05"
+	Profile11 Profile = "P11"
+)
+
+// ParseProfile parses "P01", "1", "profile 1"... in any case.
+func ParseProfile(s string) (Profile, error) {
+	n := strings.ToUpper(strings.TrimSpace(s))
+	n = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(n, "PROFILE"), "P"))
+	if v, err := strconv.Atoi(n); err == nil {
+		switch p := Profile(fmt.Sprintf("P%02d", v)); p {
+		case Profile1, Profile2, Profile5, Profile11:
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown E2E profile %q, want P01, P02, P05 or P11", s)
+}
+
+// DataIDMode is how the profiles 1 and 11 include the 16-bit data ID in the CRC.
+type DataIDMode int
+
+// The data ID modes.
+const (
+	// DataIDBoth includes both bytes, low byte first.
+	DataIDBoth DataIDMode = iota
+	// DataIDAlt includes the low byte for the even counters and the high byte for
+	// the odd ones (profile 1 only).
+	DataIDAlt
+	// DataIDLow includes the low byte only (profile 1 only).
+	DataIDLow
+	// DataIDNibble includes the low byte and a zero byte, the low nibble of the
+	// high byte being sent in the payload.
+	DataIDNibble
+)
+
+// ParseDataIDMode parses "both", "alt", "low" or "nibble", empty for both.
+func ParseDataIDMode(s string) (DataIDMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "both":
+		return DataIDBoth, nil
+	case "alt":
+		return DataIDAlt, nil
+	case "low":
+		return DataIDLow, nil
+	case "nibble":
+		return DataIDNibble, nil
+	}
+	return 0, fmt.Errorf("unknown data ID mode %q, want both, alt, low or nibble", s)
+}
+
+// Config is the E2E protection of a message. The offsets are in bits from the
+// start of the payload.
+type Config struct {
+	Profile Profile
+	// DataID identifies the message in the CRC of the profiles 1, 5 and 11.
+	DataID     uint16
+	DataIDMode DataIDMode
+	// DataIDList are the data IDs of the profile 2, selected by the counter.
+	DataIDList [16]byte
+	// CRCOffset, CounterOffset and DataIDNibbleOffset locate the CRC byte, the
+	// counter and the data ID nibble of the profiles 1 and 11.
+	CRCOffset, CounterOffset, DataIDNibbleOffset int
+	// Offset locates the header (CRC and counter) of the profile 5.
+	Offset int
+	// MaxDeltaCounter is the largest counter increment accepted, 1 accepting no
+	// lost frame.
+	MaxDeltaCounter int
+}
+
+// DefaultConfig returns the standard layout of profile p: for the profiles 1
+// and 11 the CRC in byte 0, the counter in the low nibble of byte 1 and the
+// data ID nibble in its high nibble; for the profile 5 the header at the start
+// of the payload.
+func DefaultConfig(p Profile) Config {
+	return Config{Profile: p, CounterOffset: 8, DataIDNibbleOffset: 12, MaxDeltaCounter: 1}
+}
+
+// Validate checks the layout and the options of c.
+func (c *Config) Validate() error {
+	if c.MaxDeltaCounter < 1 || c.MaxDeltaCounter >= c.modulo() {
+		return fmt.Errorf("max delta counter %d out of range 1-%d", c.MaxDeltaCounter, c.modulo()-1)
+	}
+	switch c.Profile {
+	case Profile1, Profile11:
+		if c.CRCOffset < 0 || c.CRCOffset%8 != 0 {
+			return fmt.Errorf("CRC offset %d is not a byte boundary", c.CRCOffset)
+		}
+		if c.CounterOffset < 0 || c.CounterOffset%4 != 0 {
+			return fmt.Errorf("counter offset %d is not a nibble boundary", c.CounterOffset)
+		}
+		if c.CounterOffset/8 == c.CRCOffset/8 {
+			return fmt.Errorf("counter offset %d overlaps the CRC", c.CounterOffset)
+		}
+		if c.DataIDMode == DataIDNibble {
+			if c.DataIDNibbleOffset < 0 || c.DataIDNibbleOffset%4 != 0 {
+				return fmt.Errorf("data ID nibble offset %d is not a nibble boundary", c.DataIDNibbleOffset)
+			}
+			if c.DataIDNibbleOffset/8 == c.CRCOffset/8 || c.DataIDNibbleOffset == c.CounterOffset {
+				return fmt.Errorf("data ID nibble offset %d overlaps the CRC or the counter", c.DataIDNibbleOffset)
+			}
+		}
+		if c.Profile == Profile11 && c.DataIDMode != DataIDBoth && c.DataIDMode != DataIDNibble {
+			return fmt.Errorf("profile 11 supports the data ID modes both and nibble only")
+		}
+	case Profile5:
+		if c.Offset < 0 || c.Offset%8 != 0 {
+			return fmt.Errorf("offset %d is not a byte boundary", c.Offset)
+		}
+	case Profile2:
+	default:
+		return fmt.Errorf("unknown E2E profile %q", c.Profile)
+	}
+	return nil
+}
+
+// modulo is the number of counter values.
+func (c *Config) modulo() int {
+	switch c.Profile {
+	case Profile2:
+		return 16
+	case Profile5:
+		return 256
+	}
+	// the profiles 1 and 11 count from 0 to 14, 15 is invalid
+	return 15
+}
+
+// minLength is the shortest payload the layout fits in.
+func (c *Config) minLength() int {
+	switch c.Profile {
+	case Profile1, Profile11:
+		n := max(c.CRCOffset, c.CounterOffset)/8 + 1
+		if c.DataIDMode == DataIDNibble {
+			n = max(n, c.DataIDNibbleOffset/8+1)
+		}
+		return n
+	case Profile5:
+		return c.Offset/8 + 3
+	}
+	return 2
+}
+
+// Status is the result of a check.
+type Status int
+
+// The check results.
+const (
+	// OK is a frame with a valid CRC and the next counter, or the first frame.
+	OK Status = iota
+	// OKSomeLost is a valid frame after up to MaxDeltaCounter-1 lost ones.
+	OKSomeLost
+	// Repeated is a valid frame with the counter of the previous one.
+	Repeated
+	// WrongSequence is a valid frame whose counter jumped by more than
+	// MaxDeltaCounter.
+	WrongSequence
+	// WrongCRC is a frame whose CRC, or data ID nibble, does not match.
+	WrongCRC
+	// Invalid is a payload too short for the layout, or an invalid counter.
+	Invalid
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "ok"
+	case OKSomeLost:
+		return "lost"
+	case Repeated:
+		return "repeated"
+	case WrongSequence:
+		return "wrong-sequence"
+	case WrongCRC:
+		return "crc-error"
+	}
+	return "invalid"
+}
+
+// Result is the result of Checker.Check.
+type Result struct {
+	Status Status
+	// Counter is the received counter, Delta its increment from the previous
+	// valid frame.
+	Counter int
+	Delta   int
+	// CRC is the received CRC, ExpectedCRC the one computed.
+	CRC, ExpectedCRC uint16
+}
+
+// Checker checks the frames of a message in reception order.
+type Checker struct {
+	cfg     Config
+	counter int
+	synced  bool
+}
+
+// NewChecker returns a checker of the messages protected with cfg.
+func NewChecker(cfg Config) (*Checker, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &Checker{cfg: cfg}, nil
+}
+
+// Resync accepts the counter of the next frame whatever it is, eg after a
+// timeout.
+func (c *Checker) Resync() {
+	c.synced = false
+}
+
+// Check checks the CRC and the counter of a payload.
+func (c *Checker) Check(data []byte) Result {
+	cfg := &c.cfg
+	if len(data) < cfg.minLength() {
+		return Result{Status: Invalid}
+	}
+	var r Result
+	switch cfg.Profile {
+	case Profile1, Profile11:
+		r.Counter = nibble(data, cfg.CounterOffset)
+		if r.Counter == 15 {
+			return Result{Status: Invalid, Counter: r.Counter}
+		}
+		r.CRC, r.ExpectedCRC = uint16(data[cfg.CRCOffset/8]), uint16(cfg.crc8(data, r.Counter))
+		if cfg.DataIDMode == DataIDNibble && nibble(data, cfg.DataIDNibbleOffset) != int(cfg.DataID>>8&0x0f) {
+			r.Status = WrongCRC
+			return r
+		}
+	case Profile2:
+		r.Counter = int(data[1] & 0x0f)
+		crc := checksum.H2F.Update(checksum.H2F.Init, data[1:])
+		crc = checksum.H2F.Update(crc, cfg.DataIDList[r.Counter:r.Counter+1])
+		r.CRC, r.ExpectedCRC = uint16(data[0]), uint16(crc^checksum.H2F.XorOut)
+	case Profile5:
+		o := cfg.Offset / 8
+		r.Counter = int(data[o+2])
+		crc := checksum.CCITT.Update(checksum.CCITT.Init, data[:o])
+		crc = checksum.CCITT.Update(crc, data[o+2:])
+		crc = checksum.CCITT.Update(crc, binary.LittleEndian.AppendUint16(nil, cfg.DataID))
+		r.CRC, r.ExpectedCRC = binary.LittleEndian.Uint16(data[o:]), crc^checksum.CCITT.XorOut
+	}
+	if r.CRC != r.ExpectedCRC {
+		r.Status = WrongCRC
+		return r
+	}
+
+	m := cfg.modulo()
+	r.Delta = ((r.Counter-c.counter)%m + m) % m
+	switch {
+	case !c.synced:
+		r.Status, r.Delta = OK, 0
+	case r.Delta == 0:
+		r.Status = Repeated
+	case r.Delta == 1:
+		r.Status = OK
+	case r.Delta <= cfg.MaxDeltaCounter:
+		r.Status = OKSomeLost
+	default:
+		r.Status = WrongSequence
+	}
+	c.counter, c.synced = r.Counter, true
+	return r
+}
+
+// crc8 computes the CRC of the profiles 1 and 11: the SAE J1850 CRC over the
+// data ID and the payload without the CRC byte, from a zero register and
+// without the final XOR, as the profiles chain Crc_CalculateCRC8.
+func (c *Config) crc8(data []byte, counter int) byte {
+	low, high := byte(c.DataID), byte(c.DataID>>8)
+	var id []byte
+	switch c.DataIDMode {
+	case DataIDBoth:
+		id = []byte{low, high}
+	case DataIDAlt:
+		if counter%2 == 0 {
+			id = []byte{low}
+		} else {
+			id = []byte{high}
+		}
+	case DataIDLow:
+		id = []byte{low}
+	case DataIDNibble:
+		id = []byte{low, 0}
+	}
+	j := checksum.SAEJ1850
+	crc := j.Update(0, id)
+	n := c.CRCOffset / 8
+	crc = j.Update(crc, data[:n])
+	return j.Update(crc, data[n+1:])
+}
+
+// nibble returns the 4 bits at offset, in bits from the start of data.
+func nibble(data []byte, offset int) int {
+	return int(data[offset/8]>>(offset%8)) & 0x0f
+}
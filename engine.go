@@ -0,0 +1,141 @@
+package main
+
+import (
+	"time"
+)
+
+// SessionState is the state of a started interface in EngineState.
+type SessionState struct {
+	Interface string `json:"interface"`
+	// Options are the options the session was started with, AutoReconnect
+	// the current setting of SetAutoReconnect.
+	Options       CANOptions `json:"options"`
+	AutoReconnect bool       `json:"autoReconnect"`
+	// Reconnecting is set while the session waits for its interface to come
+	// back after its connection failed.
+	Reconnecting  bool            `json:"reconnecting"`
+	Filters       []CANFilter     `json:"filters"`
+	SocketOptions SocketOptions   `json:"socketOptions"`
+	Timestamp     TimestampStatus `json:"timestamp"`
+	Bus           BusState        `json:"bus"`
+	// Stats is the last "can:stats" snapshot.
+	Stats CANStats `json:"stats"`
+	// TxQueue is the TX queue of QueueFrame, nil until it is used.
+	TxQueue *TxQueueStatus `json:"txQueue,omitempty"`
+}
+
+// EngineState is the whole current state of the app, see GetEngineState.
+type EngineState struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Sessions  []SessionState `json:"sessions"`
+	// Cyclic, Generators, GapTransmits and Sequences are the transmissions
+	// running in the background.
+	Cyclic       []CyclicFrameInfo `json:"cyclic"`
+	Generators   []GeneratorStatus `json:"generators"`
+	GapTransmits []GapTransmitInfo `json:"gapTransmits"`
+	Sequences    []SequenceInfo    `json:"sequences"`
+	Responder    ResponderStatus   `json:"responder"`
+	Replay       ReplayStatus      `json:"replay"`
+	// Logging, Pcap and MDF are the log files, SignalRecording the time
+	// series recording.
+	Logging         LoggingStatus         `json:"logging"`
+	Pcap            LoggingStatus         `json:"pcap"`
+	MDF             LoggingStatus         `json:"mdf"`
+	SignalRecording SignalRecordingStatus `json:"signalRecording"`
+	Trigger         TriggerStatus         `json:"trigger"`
+	Capture         CaptureStatus         `json:"capture"`
+	Batching        FrameBatchOptions     `json:"batching"`
+	// Subscriptions are the frame subscriptions of SubscribeFrames and
+	// GlobalFrameChannel tells whether every frame is emitted on "can:frame".
+	Subscriptions      []FrameSubscription `json:"subscriptions"`
+	GlobalFrameChannel bool                `json:"globalFrameChannel"`
+	Processors         []string            `json:"processors"`
+	Scripts            []ScriptInfo        `json:"scripts"`
+	Dissectors         []DissectorInfo     `json:"dissectors"`
+	IsoTPChannels      []IsoTPChannelInfo  `json:"isotpChannels"`
+	XCPSessions        []XCPSessionInfo    `json:"xcpSessions"`
+	REST               RESTStatus          `json:"rest"`
+	EventServer        EventServerStatus   `json:"eventServer"`
+	MQTT               MQTTStatus          `json:"mqtt"`
+	InputBridge        InputBridgeStatus   `json:"inputBridge"`
+	GPS                GPSStatus           `json:"gps"`
+}
+
+// GetEngineState returns the current state of the sessions and of every
+// background activity in a single call, so a reloaded or late-attaching
+// frontend can rebuild its view instead of replaying the events it missed. The
+// events emitted after the call update it.
+func (a *App) GetEngineState() EngineState {
+	st := EngineState{
+		Timestamp:          time.Now(),
+		Sessions:           []SessionState{},
+		Cyclic:             a.ListCyclicFrames(),
+		Generators:         a.ListGenerators(),
+		GapTransmits:       a.ListGapTransmits(),
+		Sequences:          a.ListSequences(),
+		Responder:          a.GetResponderStatus(),
+		Replay:             a.GetReplayStatus(),
+		Logging:            a.GetLoggingStatus(),
+		Pcap:               a.GetPcapStatus(),
+		MDF:                a.GetMDFStatus(),
+		SignalRecording:    a.GetSignalRecordingStatus(),
+		Trigger:            a.GetTriggerStatus(),
+		Capture:            a.GetCaptureStatus(),
+		Batching:           a.GetFrameBatching(),
+		Subscriptions:      a.GetFrameSubscriptions(),
+		GlobalFrameChannel: a.GetGlobalFrameChannel(),
+		Processors:         a.ListFrameProcessors(),
+		Scripts:            a.ListScripts(),
+		Dissectors:         a.ListDissectors(),
+		IsoTPChannels:      a.ListIsoTPChannels(),
+		XCPSessions:        a.ListXCPSessions(),
+		REST:               a.GetRESTStatus(),
+		EventServer:        a.GetEventServerStatus(),
+		MQTT:               a.GetMQTTStatus(),
+		InputBridge:        a.GetInputBridgeStatus(),
+		GPS:                a.GetGPSStatus(),
+	}
+	for _, iface := range a.ActiveInterfaces() {
+		if s, ok := a.sessionState(iface); ok {
+			st.Sessions = append(st.Sessions, s)
+		}
+	}
+	return st
+}
+
+// sessionState returns the state of a started interface, ok is false if it
+// was stopped meanwhile.
+func (a *App) sessionState(iface string) (SessionState, bool) {
+	a.mu.Lock()
+	sess := a.sessions[iface]
+	if sess == nil {
+		a.mu.Unlock()
+		return SessionState{}, false
+	}
+	s := SessionState{
+		Interface:     iface,
+		Options:       sess.opts,
+		AutoReconnect: sess.reconnect.Load(),
+		Reconnecting:  sess.reconnecting.Load(),
+		Filters:       append([]CANFilter{}, sess.filters...),
+		SocketOptions: sess.sockOpts,
+	}
+	q := sess.txq
+	a.mu.Unlock()
+
+	var err error
+	if s.Timestamp, err = a.GetTimestampMode(iface); err != nil {
+		return SessionState{}, false
+	}
+	if s.Bus, err = a.GetBusState(iface); err != nil {
+		return SessionState{}, false
+	}
+	if s.Stats, err = a.GetStats(iface); err != nil {
+		return SessionState{}, false
+	}
+	if q != nil {
+		qs := q.status()
+		s.TxQueue = &qs
+	}
+	return s, true
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"canproject/apiserver"
+)
+
+// defaultEventServerPort is the port of the event server when
+// EventServerOptions.Port is 0, the one of the headless mode.
+const defaultEventServerPort = 8765
+
+// EventServerOptions configures the server started with StartEventServer.
+type EventServerOptions struct {
+	// Address is the address to listen on, 127.0.0.1 when empty.
+	Address string `json:"address"`
+	// Port is the TCP port, 0 for 8765.
+	Port int `json:"port"`
+	// Token, if set, must be sent by the clients as "Authorization: Bearer
+	// <token>" or in the token query parameter.
+	Token string `json:"token"`
+	// Origins are the browser origins allowed to connect besides the one of
+	// the server, "*" for any.
+	Origins []string `json:"origins"`
+	// Hosts are the host names accepted besides "localhost" and the IP
+	// addresses of the server, eg the name of the machine when Address is
+	// "0.0.0.0".
+	Hosts []string `json:"hosts"`
+}
+
+// EventClientInfo describes a client of the event server.
+type EventClientInfo struct {
+	Remote        string   `json:"remote"`
+	Subscriptions []string `json:"subscriptions"`
+	// MaxRate bounds the events per second the client gets, 0 for no bound;
+	// DropOldest drops its oldest queued events rather than the new ones.
+	MaxRate    float64 `json:"maxRate"`
+	DropOldest bool    `json:"dropOldest"`
+	Queue      int     `json:"queue"`
+	Queued     int     `json:"queued"`
+	Sent       uint64  `json:"sent"`
+	Dropped    uint64  `json:"dropped"`
+	Limited    uint64  `json:"limited"`
+}
+
+// EventServerStatus describes the event server.
+type EventServerStatus struct {
+	Running bool `json:"running"`
+	// URL is the WebSocket URL of the server while it runs.
+	URL     string            `json:"url"`
+	Clients []EventClientInfo `json:"clients"`
+}
+
+type eventServer struct {
+	api *apiserver.Server
+	srv *http.Server
+	url string
+}
+
+// StartEventServer serves the API of the headless mode on /ws while the UI
+// runs, so more frontends can follow the app besides the main window, eg a
+// detached plot window in a browser or an external viewer. Every client
+// subscribes to its own events and has its own flow control, see package
+// apiserver; a slow client loses events without slowing down the others.
+func (a *App) StartEventServer(opts EventServerOptions) (EventServerStatus, error) {
+	if opts.Port < 0 || opts.Port > 65535 {
+		return EventServerStatus{}, fmt.Errorf("invalid port %d", opts.Port)
+	}
+	addr := strings.TrimSpace(opts.Address)
+	if addr == "" {
+		addr = "127.0.0.1"
+	}
+	port := opts.Port
+	if port == 0 {
+		port = defaultEventServerPort
+	}
+
+	a.eventsMu.Lock()
+	defer a.eventsMu.Unlock()
+	if a.events != nil {
+		return EventServerStatus{}, fmt.Errorf("event server already running on %s", a.events.url)
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(addr, strconv.Itoa(port)))
+	if err != nil {
+		return EventServerStatus{}, err
+	}
+	es := &eventServer{
+		api: apiserver.New(a, apiserver.Options{Token: opts.Token, Origins: opts.Origins, Hosts: opts.Hosts}),
+		url: "ws://" + ln.Addr().String() + "/ws",
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/ws", es.api)
+	es.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	a.events = es
+	a.addSink(es.api)
+	go func() {
+		if err := es.srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			a.emitError(fmt.Errorf("event server: %w", err))
+		}
+	}()
+	return es.status(), nil
+}
+
+// StopEventServer stops the event server and disconnects its clients.
+func (a *App) StopEventServer() error {
+	a.eventsMu.Lock()
+	es := a.events
+	a.events = nil
+	a.eventsMu.Unlock()
+	if es == nil {
+		return nil
+	}
+	a.removeSink(es.api)
+	es.api.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return es.srv.Shutdown(ctx)
+}
+
+// GetEventServerStatus returns the state of the event server and its clients.
+func (a *App) GetEventServerStatus() EventServerStatus {
+	a.eventsMu.Lock()
+	defer a.eventsMu.Unlock()
+	if a.events == nil {
+		return EventServerStatus{}
+	}
+	return a.events.status()
+}
+
+func (es *eventServer) status() EventServerStatus {
+	st := EventServerStatus{Running: true, URL: es.url, Clients: []EventClientInfo{}}
+	for _, c := range es.api.Clients() {
+		st.Clients = append(st.Clients, EventClientInfo{
+			Remote:        c.Remote,
+			Subscriptions: c.Subscriptions,
+			MaxRate:       c.Flow.MaxRate,
+			DropOldest:    c.Flow.DropOldest,
+			Queue:         c.Queue,
+			Queued:        c.Queued,
+			Sent:          c.Sent,
+			Dropped:       c.Dropped,
+			Limited:       c.Limited,
+		})
+	}
+	return st
+}
+
+// addSink adds a receiver of the events.
+func (a *App) addSink(s eventSink) {
+	a.sinkMu.Lock()
+	defer a.sinkMu.Unlock()
+	var sinks []eventSink
+	if cur := a.sinks.Load(); cur != nil {
+		sinks = slices.Clone(*cur)
+	}
+	sinks = append(sinks, s)
+	a.sinks.Store(&sinks)
+}
+
+// removeSink removes a receiver added with addSink.
+func (a *App) removeSink(s eventSink) {
+	a.sinkMu.Lock()
+	defer a.sinkMu.Unlock()
+	cur := a.sinks.Load()
+	if cur == nil {
+		return
+	}
+	sinks := slices.DeleteFunc(slices.Clone(*cur), func(x eventSink) bool { return x == s })
+	a.sinks.Store(&sinks)
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/candb"
+	"canproject/capture"
+	"canproject/parquet"
+)
+
+// Export formats of ExportCapture.
+const (
+	exportCSV          = "csv"
+	exportCSVDecoded   = "csv-decoded"
+	exportJSONL        = "jsonl"
+	exportJSONLDecoded = "jsonl-decoded"
+	exportParquet      = "parquet"
+	exportParquetWide  = "parquet-wide"
+)
+
+// captureRecord is a JSON Lines record of ExportCapture.
+type captureRecord struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Interface string        `json:"interface"`
+	Network   string        `json:"network"`
+	Direction string        `json:"direction"`
+	ID        uint32        `json:"id"`
+	Extended  bool          `json:"extended"`
+	Remote    bool          `json:"remote,omitempty"`
+	Error     bool          `json:"error,omitempty"`
+	FD        bool          `json:"fd,omitempty"`
+	BRS       bool          `json:"brs,omitempty"`
+	Data      []uint32      `json:"data"`
+	Cycle     uint8         `json:"cycle,omitempty"`
+	Group     string        `json:"group,omitempty"`
+	Message   string        `json:"message,omitempty"`
+	Signals   []candb.Value `json:"signals,omitempty"`
+	Comment   string        `json:"comment,omitempty"`
+	Tag       string        `json:"tag,omitempty"`
+}
+
+// bookmarkRecord is a JSON Lines record of ExportCapture for a bookmark, told
+// from the frames by its bookmark field.
+type bookmarkRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	Bookmark  uint64    `json:"bookmark"`
+	Comment   string    `json:"comment,omitempty"`
+	Tag       string    `json:"tag,omitempty"`
+}
+
+// ExportCapture writes the buffered frames selected by filter and timeRange to path and
+// returns the number of frames written. format is "csv" or "jsonl" (one JSON object per
+// line) for raw frames, "csv-decoded" for a row per decoded signal, or "jsonl-decoded"
+// for raw frames with the signals of the loaded databases. The frames carry their
+// annotations, and the bookmarks in timeRange are written between them: in CSV as
+// rows with the direction "bookmark", in JSON Lines as records with a bookmark
+// field. "parquet" writes the decoded signals to an Apache Parquet file, a row
+// per signal value, and "parquet-wide" a row per decoded frame with a column per
+// signal, null for the signals of the other messages; they have no bookmarks.
+// The raw formats have the network of each frame, the frames of the networks
+// other than CAN are left out of the decoded ones but the GPS samples of
+// StartGPS, decoded as the message "GPS".
+func (a *App) ExportCapture(path string, format string, filter CaptureFilter, timeRange TimeRange) (int, error) {
+	path = strings.TrimSpace(path)
+	switch format {
+	case exportCSV, exportCSVDecoded, exportJSONL, exportJSONLDecoded, exportParquet, exportParquetWide:
+	default:
+		return 0, fmt.Errorf("unknown export format %q, want csv, csv-decoded, jsonl, jsonl-decoded, parquet or parquet-wide", format)
+	}
+	keep, err := captureMatcher(filter, timeRange)
+	if err != nil {
+		return 0, err
+	}
+	// the records are streamed from the buffer, which may hold more than the
+	// memory with spilling; n counts the records of the last pass
+	n := 0
+	records := func(yield func(*capture.Record) bool) {
+		n = 0
+		for r := range a.capture.All(keep) {
+			n++
+			if !yield(r) {
+				return
+			}
+		}
+	}
+	bookmarks := a.capture.Bookmarks(timeRange.bounds())
+
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	switch format {
+	case exportCSV:
+		err = a.writeCSV(w, records, bookmarks, false)
+	case exportCSVDecoded:
+		err = a.writeCSV(w, records, bookmarks, true)
+	case exportParquet, exportParquetWide:
+		err = a.writeParquet(w, records, format == exportParquetWide)
+	default:
+		err = a.writeJSONL(w, records, bookmarks, format == exportJSONLDecoded)
+	}
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (a *App) writeCSV(w *bufio.Writer, records iter.Seq[*capture.Record], bookmarks []capture.Bookmark, decoded bool) error {
+	cw := csv.NewWriter(w)
+	header := []string{"timestamp", "interface", "network", "direction", "id", "extended", "remote", "error", "fd", "brs", "dlc", "data", "group", "comment", "tag"}
+	if decoded {
+		header = []string{"timestamp", "interface", "direction", "id", "message", "signal", "value", "unit", "raw", "label", "group", "comment", "tag"}
+	}
+	_ = cw.Write(header)
+	dirColumn := slices.Index(header, "direction")
+	writeBookmarks := func(before time.Time) {
+		for len(bookmarks) > 0 && (before.IsZero() || bookmarks[0].Timestamp.Before(before)) {
+			row := make([]string, len(header))
+			row[0], row[dirColumn] = csvTimestamp(bookmarks[0].Timestamp), "bookmark"
+			row[len(row)-2], row[len(row)-1] = bookmarks[0].Comment, bookmarks[0].Tag
+			_ = cw.Write(row)
+			bookmarks = bookmarks[1:]
+		}
+	}
+	for r := range records {
+		f := &r.Frame
+		writeBookmarks(r.Timestamp)
+		ts := csvTimestamp(r.Timestamp)
+		id := formatCANID(f.ID, f.IsExtended)
+		var note capture.Annotation
+		if r.Note != nil {
+			note = *r.Note
+		}
+		if p := r.Packet; p != nil {
+			if !decoded {
+				_ = cw.Write([]string{ts, r.Interface, string(p.Network), direction(r.TX), fmt.Sprintf("%X", p.ID),
+					"", "", "", "", "", strconv.Itoa(len(p.Payload)), strings.ToUpper(hex.EncodeToString(p.Payload)),
+					"", note.Comment, note.Tag})
+				continue
+			}
+			id = ""
+		}
+		if !decoded {
+			_ = cw.Write([]string{ts, r.Interface, string(canbus.NetworkCAN), direction(r.TX), id,
+				strconv.FormatBool(f.IsExtended), strconv.FormatBool(f.IsRemote), strconv.FormatBool(f.IsError),
+				strconv.FormatBool(f.IsFD), strconv.FormatBool(f.BRS),
+				strconv.Itoa(int(f.DLC())), strings.ToUpper(hex.EncodeToString(f.Payload())),
+				a.frameGroup(f.ID, f.IsExtended), note.Comment, note.Tag})
+			continue
+		}
+		m, values := a.decodeRecord(r)
+		group := a.recordGroup(r)
+		for _, v := range values {
+			_ = cw.Write([]string{ts, r.Interface, direction(r.TX), id, m, v.Name,
+				strconv.FormatFloat(v.Physical, 'g', -1, 64), v.Unit,
+				strconv.FormatFloat(v.Raw, 'g', -1, 64), v.Label, group,
+				note.Comment, note.Tag})
+		}
+	}
+	writeBookmarks(time.Time{})
+	cw.Flush()
+	return cw.Error()
+}
+
+func (a *App) writeJSONL(w *bufio.Writer, records iter.Seq[*capture.Record], bookmarks []capture.Bookmark, decoded bool) error {
+	enc := json.NewEncoder(w)
+	writeBookmarks := func(before time.Time) error {
+		for len(bookmarks) > 0 && (before.IsZero() || bookmarks[0].Timestamp.Before(before)) {
+			bm := &bookmarks[0]
+			if err := enc.Encode(bookmarkRecord{Timestamp: bm.Timestamp, Bookmark: bm.ID, Comment: bm.Comment, Tag: bm.Tag}); err != nil {
+				return err
+			}
+			bookmarks = bookmarks[1:]
+		}
+		return nil
+	}
+	for r := range records {
+		f := &r.Frame
+		if err := writeBookmarks(r.Timestamp); err != nil {
+			return err
+		}
+		if p := r.Packet; p != nil {
+			rec := captureRecord{
+				Timestamp: r.Timestamp,
+				Interface: r.Interface,
+				Network:   string(p.Network),
+				Direction: direction(r.TX),
+				ID:        p.ID,
+				Data:      dataWords(p.Payload),
+				Cycle:     p.Cycle,
+			}
+			if r.Note != nil {
+				rec.Comment, rec.Tag = r.Note.Comment, r.Note.Tag
+			}
+			if decoded {
+				rec.Message, rec.Signals = a.decodeRecord(r)
+			}
+			if err := enc.Encode(rec); err != nil {
+				return err
+			}
+			continue
+		}
+		rec := captureRecord{
+			Timestamp: r.Timestamp,
+			Interface: r.Interface,
+			Network:   string(canbus.NetworkCAN),
+			Direction: direction(r.TX),
+			ID:        f.ID,
+			Extended:  f.IsExtended,
+			Remote:    f.IsRemote,
+			Error:     f.IsError,
+			FD:        f.IsFD,
+			BRS:       f.BRS,
+			Data:      dataWords(f.Payload()),
+			Group:     a.frameGroup(f.ID, f.IsExtended),
+		}
+		if r.Note != nil {
+			rec.Comment, rec.Tag = r.Note.Comment, r.Note.Tag
+		}
+		if decoded {
+			rec.Message, rec.Signals = a.decodeRecord(r)
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return writeBookmarks(time.Time{})
+}
+
+// writeParquet writes the decoded signals of records, a row per signal value,
+// or per frame with a column per signal when wide.
+func (a *App) writeParquet(w *bufio.Writer, records iter.Seq[*capture.Record], wide bool) error {
+	cols := []parquet.Column{
+		{Name: "timestamp", Type: parquet.Timestamp},
+		{Name: "interface", Type: parquet.String},
+		{Name: "direction", Type: parquet.String},
+		{Name: "id", Type: parquet.Int64},
+		{Name: "extended", Type: parquet.Boolean},
+		{Name: "message", Type: parquet.String},
+	}
+	// index is the column of each signal of the wide format, "message.signal"
+	index := map[string]int{}
+	if wide {
+		for r := range records {
+			m, values := a.decodeRecord(r)
+			for _, v := range values {
+				name := m + "." + v.Name
+				if _, ok := index[name]; !ok {
+					index[name] = len(cols)
+					cols = append(cols, parquet.Column{Name: name, Type: parquet.Double, Optional: true})
+				}
+			}
+		}
+	} else {
+		cols = append(cols,
+			parquet.Column{Name: "signal", Type: parquet.String},
+			parquet.Column{Name: "value", Type: parquet.Double},
+			parquet.Column{Name: "unit", Type: parquet.String},
+			parquet.Column{Name: "raw", Type: parquet.Double},
+			parquet.Column{Name: "label", Type: parquet.String},
+			parquet.Column{Name: "group", Type: parquet.String},
+			parquet.Column{Name: "comment", Type: parquet.String},
+			parquet.Column{Name: "tag", Type: parquet.String},
+		)
+	}
+	pw, err := parquet.NewWriter(w, cols)
+	if err != nil {
+		return err
+	}
+	row := make([]any, len(cols))
+	for r := range records {
+		f := &r.Frame
+		m, values := a.decodeRecord(r)
+		if len(values) == 0 {
+			continue
+		}
+		row[0], row[1], row[2], row[3], row[4], row[5] = r.Timestamp, r.Interface, direction(r.TX), int64(f.ID), f.IsExtended, m
+		if wide {
+			clear(row[6:])
+			for _, v := range values {
+				row[index[m+"."+v.Name]] = v.Physical
+			}
+			if err := pw.Write(row...); err != nil {
+				return err
+			}
+			continue
+		}
+		var note capture.Annotation
+		if r.Note != nil {
+			note = *r.Note
+		}
+		for _, v := range values {
+			row[6], row[7], row[8], row[9], row[10] = v.Name, v.Physical, v.Unit, v.Raw, v.Label
+			row[11], row[12], row[13] = a.recordGroup(r), note.Comment, note.Tag
+			if err := pw.Write(row...); err != nil {
+				return err
+			}
+		}
+	}
+	return pw.Close()
+}
+
+// decodeRecord decodes the signals of a buffered CAN frame with the loaded
+// databases, or the values of a GPS sample.
+func (a *App) decodeRecord(r *capture.Record) (string, []candb.Value) {
+	if p := r.Packet; p != nil {
+		if p.Network == canbus.NetworkGPS {
+			return decodeGPS(p.Payload)
+		}
+		return "", nil
+	}
+	f := &r.Frame
+	if f.IsError || f.IsRemote {
+		return "", nil
+	}
+	m, ok := a.lookupMessage(f.ID, f.IsExtended)
+	if !ok {
+		return "", nil
+	}
+	return m.Name, m.Decode(f.Payload())
+}
+
+// recordGroup returns the frame group of a buffered CAN frame, none for the
+// other records.
+func (a *App) recordGroup(r *capture.Record) string {
+	if r.Packet != nil {
+		return ""
+	}
+	return a.frameGroup(r.Frame.ID, r.Frame.IsExtended)
+}
+
+// csvTimestamp formats ts in seconds since the epoch, with microseconds.
+func csvTimestamp(ts time.Time) string {
+	return strconv.FormatFloat(float64(ts.UnixNano())/1e9, 'f', 6, 64)
+}
+
+func direction(tx bool) string {
+	if tx {
+		return "tx"
+	}
+	return "rx"
+}
+
+// formatCANID formats an ID as in candump logs, eg 123 or 1ABCDEF0.
+func formatCANID(id uint32, extended bool) string {
+	if extended {
+		return fmt.Sprintf("%08X", id)
+	}
+	return fmt.Sprintf("%03X", id)
+}
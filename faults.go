@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/generator"
+)
+
+const (
+	// floodFrameBits is the length of the 8 byte frames of StartBusFlood with the
+	// interframe space, without stuff bits; defaultFloodRate is the rate of the
+	// interfaces whose bitrate is unknown.
+	floodFrameBits   = 111
+	defaultFloodRate = 10000
+	// maxFaultFrames bounds the frames of InjectWrongBitrate, busOffFrames the
+	// frames InjectBusOff sends before giving up: 32 transmit errors are enough.
+	maxFaultFrames    = 10000
+	busOffFrames      = 512
+	faultWriteTimeout = 10 * time.Millisecond
+)
+
+// Faults of GetFaultCapabilities.
+const (
+	faultErrorFrame   = "error-frame"
+	faultFlood        = "flood"
+	faultWrongBitrate = "wrong-bitrate"
+	faultBusOff       = "bus-off"
+)
+
+// FaultCapability tells whether a fault can be injected on an interface.
+type FaultCapability struct {
+	Fault     string `json:"fault"`
+	Supported bool   `json:"supported"`
+	// Reason tells why the fault cannot be injected, or what injecting it needs.
+	Reason string `json:"reason,omitempty"`
+}
+
+// FaultCapabilities are the faults an interface supports, see GetFaultCapabilities.
+type FaultCapabilities struct {
+	Interface string            `json:"interface"`
+	Kind      string            `json:"kind"`
+	Driver    string            `json:"driver"`
+	Faults    []FaultCapability `json:"faults"`
+	// ErrorKinds are the kinds of InjectErrorFrame.
+	ErrorKinds []string `json:"errorKinds"`
+}
+
+// FaultResult is the outcome of InjectWrongBitrate and InjectBusOff.
+type FaultResult struct {
+	Interface string `json:"interface"`
+	// Bitrate is the wrong bitrate the frames were sent at.
+	Bitrate uint32 `json:"bitrate"`
+	// Sent and Failed count the frames written and the writes that failed.
+	Sent   int `json:"sent"`
+	Failed int `json:"failed"`
+	// State and the error counters are the last ones the controller reported
+	// before its bit timing was restored.
+	State       string  `json:"state"`
+	TxErrors    uint16  `json:"txErrors"`
+	RxErrors    uint16  `json:"rxErrors"`
+	HasCounters bool    `json:"hasCounters"`
+	BusOff      bool    `json:"busOff"`
+	DurationMs  float64 `json:"durationMs"`
+}
+
+// GetFaultCapabilities reports which faults can be injected on an interface:
+// "error-frame" (InjectErrorFrame) on the virtual interfaces, "flood"
+// (StartBusFlood) on the started ones, "wrong-bitrate" (InjectWrongBitrate) and
+// "bus-off" (InjectBusOff) on the SocketCAN controllers.
+func (a *App) GetFaultCapabilities(iface string) FaultCapabilities {
+	iface = strings.TrimSpace(iface)
+	caps := FaultCapabilities{Interface: iface, ErrorKinds: make([]string, len(canbus.ErrorKinds))}
+	for i, k := range canbus.ErrorKinds {
+		caps.ErrorKinds[i] = string(k)
+	}
+	l, linkErr := canbus.LinkByName(iface)
+	if linkErr == nil {
+		caps.Kind, caps.Driver = l.Kind, l.Driver
+	}
+	add := func(fault string, supported bool, reason string) {
+		caps.Faults = append(caps.Faults, FaultCapability{Fault: fault, Supported: supported, Reason: reason})
+	}
+
+	switch {
+	case linkErr != nil:
+		add(faultErrorFrame, false, "not a SocketCAN interface")
+	case l.Kind == "vcan" || l.Kind == "vxcan":
+		reason := ""
+		if l.Kind == "vxcan" {
+			reason = "received by the peer interface"
+		}
+		add(faultErrorFrame, true, reason)
+	default:
+		add(faultErrorFrame, false, "error frames can only be simulated on vcan and vxcan interfaces, a controller reports its own")
+	}
+
+	if _, err := a.session(iface); err != nil {
+		add(faultFlood, false, err.Error())
+	} else {
+		add(faultFlood, true, "")
+	}
+
+	switch {
+	case linkErr != nil:
+		reason := "the bit timing can only be changed on SocketCAN controllers"
+		add(faultWrongBitrate, false, reason)
+		add(faultBusOff, false, reason)
+	case l.Kind != "can":
+		reason := fmt.Sprintf("%s interfaces have no bit timing", l.Kind)
+		add(faultWrongBitrate, false, reason)
+		add(faultBusOff, false, reason)
+	default:
+		add(faultWrongBitrate, true, "requires CAP_NET_ADMIN, the interface is restarted")
+		add(faultBusOff, true, "requires CAP_NET_ADMIN and another node active on the bus, the interface is restarted")
+	}
+	return caps
+}
+
+// InjectErrorFrame simulates an error reported by the controller of a vcan
+// interface, for testing the handling of bus errors and state changes without
+// hardware: the sockets on the interface, including the started session,
+// receive an error frame of kind (see GetFaultCapabilities) with the error
+// counters txErrors and rxErrors. On a vxcan interface the peer receives it.
+func (a *App) InjectErrorFrame(iface, kind string, txErrors, rxErrors int) error {
+	iface = strings.TrimSpace(iface)
+	if err := a.checkWritable(iface); err != nil {
+		return err
+	}
+	if txErrors < 0 || txErrors > 255 || rxErrors < 0 || rxErrors > 255 {
+		return fmt.Errorf("error counters must be within 0..255 (got %d, %d)", txErrors, rxErrors)
+	}
+	l, err := canbus.LinkByName(iface)
+	if err != nil {
+		return err
+	}
+	if l.Kind != "vcan" && l.Kind != "vxcan" {
+		return fmt.Errorf("%s is not a virtual CAN interface (kind %q), error frames can only be simulated on vcan and vxcan", iface, l.Kind)
+	}
+	f, err := canbus.NewErrorFrame(canbus.ErrorKind(strings.ToLower(strings.TrimSpace(kind))), uint8(txErrors), uint8(rxErrors))
+	if err != nil {
+		return err
+	}
+	// a socket of its own, the session does not receive the frames it sends
+	conn, err := canbus.Dial(iface)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), faultWriteTimeout)
+	defer cancel()
+	return conn.WriteFrame(ctx, f)
+}
+
+// StartBusFlood loads a started interface with 8 byte frames of ID 0x000,
+// which win the arbitration against every other frame, at the rate that fills
+// the bus at its bitrate (10000 frames/s when it is unknown). It runs for
+// durationMs, 0 for no limit, and returns the handle of the generator, which
+// StopGenerator stops earlier.
+func (a *App) StartBusFlood(iface string, durationMs int) (int, error) {
+	iface = strings.TrimSpace(iface)
+	if durationMs < 0 {
+		return 0, fmt.Errorf("duration must be >= 0 ms (got %d)", durationMs)
+	}
+	rate := defaultFloodRate
+	if l, err := canbus.LinkByName(iface); err == nil && l.Bitrate > 0 {
+		rate = int(l.Bitrate / floodFrameBits)
+	}
+	handle, err := a.StartGenerator(GeneratorConfig{
+		Interface: iface,
+		Config:    generator.Config{Mode: generator.ModeCounter, ID: 0, Length: canbus.MaxDataLength},
+		Rate:      rate,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if durationMs > 0 {
+		time.AfterFunc(time.Duration(durationMs)*time.Millisecond, func() {
+			// the generator may have been stopped already
+			_ = a.StopGenerator(handle)
+		})
+	}
+	return handle, nil
+}
+
+// InjectWrongBitrate reprograms a SocketCAN controller at a wrong bitrate and
+// sends frames frames with it, which the other nodes of the bus see as errors,
+// until it goes bus-off. The bit timing of the interface is restored afterwards
+// and a started interface is stopped meanwhile and started again. Used on a
+// second adapter, it disturbs the bus the others are on. It requires
+// CAP_NET_ADMIN.
+func (a *App) InjectWrongBitrate(iface string, bitrate uint32, frames int) (FaultResult, error) {
+	if frames < 1 || frames > maxFaultFrames {
+		return FaultResult{}, fmt.Errorf("frames must be within 1..%d (got %d)", maxFaultFrames, frames)
+	}
+	return a.injectWrongBitrate(strings.TrimSpace(iface), bitrate, frames)
+}
+
+// InjectBusOff drives a SocketCAN controller bus-off, by sending at half its
+// bitrate (or twice when it is low) until the error frames of the other nodes
+// raise its transmit error counter over 255. Another node must be active on
+// the bus; BusOff is false in the result when that was not enough. The bit
+// timing is then restored like with InjectWrongBitrate, which clears the
+// bus-off state. It requires CAP_NET_ADMIN.
+func (a *App) InjectBusOff(iface string) (FaultResult, error) {
+	iface = strings.TrimSpace(iface)
+	l, err := canbus.LinkByName(iface)
+	if err != nil {
+		return FaultResult{}, err
+	}
+	bitrate := l.Bitrate / 2
+	if bitrate < 20000 {
+		bitrate = l.Bitrate * 2
+	}
+	return a.injectWrongBitrate(iface, bitrate, busOffFrames)
+}
+
+func (a *App) injectWrongBitrate(iface string, bitrate uint32, frames int) (FaultResult, error) {
+	if err := a.checkWritable(iface); err != nil {
+		return FaultResult{}, err
+	}
+	l, err := canbus.LinkByName(iface)
+	if err != nil {
+		return FaultResult{}, err
+	}
+	if l.Kind != "can" {
+		return FaultResult{}, fmt.Errorf("%s is not a CAN controller (kind %q), its bit timing cannot be changed", iface, l.Kind)
+	}
+	if bitrate == 0 || bitrate == l.Bitrate {
+		return FaultResult{}, fmt.Errorf("the wrong bitrate must differ from the %d bit/s of %s", l.Bitrate, iface)
+	}
+	restore := canbus.LinkConfig{
+		Bitrate:         l.Bitrate,
+		SamplePoint:     l.SamplePoint,
+		DataBitrate:     l.DataBitrate,
+		DataSamplePoint: l.DataSamplePoint,
+		RestartMs:       l.RestartMs,
+	}
+
+	res := FaultResult{Interface: iface, Bitrate: bitrate}
+	err = a.reconfigure(iface, l.FD(), func() error {
+		// no automatic restart, the controller stays bus-off until restored
+		if err := canbus.ConfigureLink(iface, canbus.LinkConfig{Bitrate: bitrate}); err != nil {
+			return err
+		}
+		sendErr := disturb(iface, frames, &res)
+		if err := canbus.ConfigureLink(iface, restore); err != nil {
+			return fmt.Errorf("restore the bit timing of %s: %w", iface, err)
+		}
+		return sendErr
+	})
+	return res, err
+}
+
+// disturb sends frames on iface until it is bus-off and records the controller
+// state they lead to in res.
+func disturb(iface string, frames int, res *FaultResult) error {
+	conn, err := canbus.Dial(iface)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	f := canbus.Frame{Length: canbus.MaxDataLength}
+	for res.Sent+res.Failed < frames {
+		ctx, cancel := context.WithTimeout(context.Background(), faultWriteTimeout)
+		if conn.WriteFrame(ctx, f) != nil {
+			// the TX queue is full while the controller retries
+			res.Failed++
+		} else {
+			res.Sent++
+		}
+		cancel()
+		time.Sleep(time.Millisecond)
+
+		l, err := canbus.LinkByName(iface)
+		if err != nil {
+			continue
+		}
+		res.State = l.State.String()
+		res.TxErrors, res.RxErrors, res.HasCounters = l.TxErrors, l.RxErrors, l.HasCounters
+		if l.State == canbus.StateBusOff {
+			res.BusOff = true
+			break
+		}
+	}
+	res.DurationMs = milliseconds(time.Since(start))
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"canproject/canbus"
+)
+
+// CANFilter is a kernel receive filter: a frame passes when id&mask == ID&Mask.
+type CANFilter struct {
+	ID       uint32 `json:"id"`
+	Mask     uint32 `json:"mask"`
+	Extended bool   `json:"extended"`
+	Invert   bool   `json:"invert"`
+}
+
+// SetFilters applies CAN_RAW_FILTER filters to the socket of a started interface,
+// so frames are dropped by the kernel instead of the frontend. A frame is received
+// when it matches any of the filters.
+func (a *App) SetFilters(iface string, filters []CANFilter) error {
+	iface = strings.TrimSpace(iface)
+	if len(filters) == 0 {
+		return fmt.Errorf("no filters given, use ClearFilters to receive all frames")
+	}
+
+	raw := make([]canbus.Filter, len(filters))
+	for i, f := range filters {
+		raw[i] = canbus.Filter{ID: f.ID, Mask: f.Mask, Extended: f.Extended, Invert: f.Invert}
+	}
+	return a.applyFilters(iface, raw, filters)
+}
+
+// ClearFilters removes the filters of a started interface and receives every frame again.
+func (a *App) ClearFilters(iface string) error {
+	return a.applyFilters(strings.TrimSpace(iface), canbus.AcceptAll, nil)
+}
+
+// GetFilters returns the filters applied to a started interface, empty when all frames are received.
+func (a *App) GetFilters(iface string) []CANFilter {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sess := a.sessions[strings.TrimSpace(iface)]
+	if sess == nil {
+		return []CANFilter{}
+	}
+	return append([]CANFilter{}, sess.filters...)
+}
+
+func (a *App) applyFilters(iface string, raw []canbus.Filter, filters []CANFilter) error {
+	a.mu.Lock()
+	sess := a.sessions[iface]
+	a.mu.Unlock()
+
+	if sess == nil || sess.conn == nil {
+		return fmt.Errorf("CAN not started on %s", iface)
+	}
+	if err := sess.conn.SetFilters(raw); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	sess.filters = filters
+	a.mu.Unlock()
+	a.record(JournalEntry{Action: actionSetFilters, Interface: iface, Filters: filters})
+	return nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"strings"
+	"sync"
+
+	"canproject/uds"
+)
+
+// Flash stages reported in FlashProgress.Stage, in execution order.
+const (
+	flashSession  = "session"
+	flashSecurity = "security"
+	flashErase    = "erase"
+	flashDownload = "download"
+	flashCheck    = "check"
+)
+
+// FlashOptions configures a firmware download started with UDSFlash.
+type FlashOptions struct {
+	// Path is the binary image to download.
+	Path    string `json:"path"`
+	Address uint32 `json:"address"`
+	// Session is the diagnostic session entered first (2 programming), 0 to stay in the current one.
+	Session uint8 `json:"session"`
+	// SecurityLevel is unlocked with UDSSecurityAccess after the session change, 0 for none.
+	SecurityLevel uint8 `json:"securityLevel"`
+	// EraseRoutine is started before the download with the address and size of the
+	// image (0x44, 4-byte address, 4-byte size), eg 0xFF00. 0 for none.
+	EraseRoutine uint16 `json:"eraseRoutine"`
+	// CheckRoutine is started after the download with the CRC-32 of the image
+	// (4 bytes, big endian), eg 0x0202. 0 for none.
+	CheckRoutine uint16 `json:"checkRoutine"`
+	// Retries is the number of times a failed TransferData block is repeated, 0 for 3.
+	Retries int `json:"retries"`
+}
+
+// FlashProgress is emitted on "uds:flash" when a download changes stage, after each
+// transferred block and when it ends.
+type FlashProgress struct {
+	Handle int    `json:"handle"`
+	Path   string `json:"path"`
+	State  string `json:"state"`
+	Stage  string `json:"stage"`
+	// Sent is the number of bytes transferred, Total the size of the image.
+	Sent  int    `json:"sent"`
+	Total int    `json:"total"`
+	Error string `json:"error,omitempty"`
+}
+
+type flashJob struct {
+	handle int
+	opts   FlashOptions
+	data   []byte
+
+	mu     sync.Mutex
+	state  string
+	stage  string
+	err    error
+	cancel context.CancelFunc
+	// sent is transfer.Offset for progress, transfer is only used by the running job.
+	sent int
+
+	// erased is set once the erase routine succeeded, transfer.Offset counts the
+	// downloaded bytes: a resumed job skips what is done.
+	erased   bool
+	transfer uds.Transfer
+}
+
+// UDSFlash downloads a firmware image on an ISO-TP channel or a DoIP connection
+// in the background: it enters the programming session, unlocks the security
+// level, runs the erase routine, transfers the image with RequestDownload, TransferData and
+// RequestTransferExit, and runs the check routine, each step being optional.
+// Progress is emitted on "uds:flash". A failed download can be continued with UDSResumeFlash.
+func (a *App) UDSFlash(handle int, opts FlashOptions) error {
+	if _, err := a.udsClient(handle); err != nil {
+		return err
+	}
+	if opts.SecurityLevel != 0 && a.securityAlgo.Load() == nil {
+		return errors.New("no security algorithm loaded")
+	}
+	opts.Path = strings.TrimSpace(opts.Path)
+	data, err := os.ReadFile(opts.Path)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("%s is empty", opts.Path)
+	}
+
+	a.flashMu.Lock()
+	defer a.flashMu.Unlock()
+	if job := a.flashJobs[handle]; job != nil && job.running() {
+		return fmt.Errorf("a download is already running on channel %d", handle)
+	}
+	job := &flashJob{
+		handle:   handle,
+		opts:     opts,
+		data:     data,
+		transfer: uds.Transfer{Address: opts.Address, Data: data, Retries: opts.Retries},
+	}
+	if a.flashJobs == nil {
+		a.flashJobs = make(map[int]*flashJob)
+	}
+	a.flashJobs[handle] = job
+	a.startFlash(job)
+	return nil
+}
+
+// UDSResumeFlash continues the failed or stopped download of a channel: the session
+// and security access are repeated, the erase routine and the transferred blocks are not.
+func (a *App) UDSResumeFlash(handle int) error {
+	a.flashMu.Lock()
+	defer a.flashMu.Unlock()
+	job := a.flashJobs[handle]
+	if job == nil {
+		return fmt.Errorf("no download on channel %d", handle)
+	}
+	job.mu.Lock()
+	state := job.state
+	job.mu.Unlock()
+	if state != sequenceFailed && state != sequenceStopped {
+		return fmt.Errorf("the download on channel %d is %s", handle, state)
+	}
+	a.startFlash(job)
+	return nil
+}
+
+// UDSStopFlash aborts the running download of a channel.
+func (a *App) UDSStopFlash(handle int) error {
+	a.flashMu.Lock()
+	job := a.flashJobs[handle]
+	a.flashMu.Unlock()
+	if job == nil || !job.running() {
+		return fmt.Errorf("no download running on channel %d", handle)
+	}
+	job.mu.Lock()
+	job.cancel()
+	job.mu.Unlock()
+	return nil
+}
+
+// GetFlashProgress returns the state of the last download of a channel.
+func (a *App) GetFlashProgress(handle int) (FlashProgress, error) {
+	a.flashMu.Lock()
+	job := a.flashJobs[handle]
+	a.flashMu.Unlock()
+	if job == nil {
+		return FlashProgress{}, fmt.Errorf("no download on channel %d", handle)
+	}
+	return job.progress(), nil
+}
+
+// startFlash runs job in the background, a.flashMu is held.
+func (a *App) startFlash(job *flashJob) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job.mu.Lock()
+	job.state, job.err, job.cancel = sequenceRunning, nil, cancel
+	job.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		err := a.runFlash(ctx, job)
+
+		job.mu.Lock()
+		switch {
+		case errors.Is(err, context.Canceled):
+			job.state = sequenceStopped
+		case err != nil:
+			job.state, job.err = sequenceFailed, err
+		default:
+			job.state = sequenceFinished
+		}
+		job.mu.Unlock()
+
+		a.emit("uds:flash", job.progress())
+		if job.state == sequenceFailed {
+			a.emitError(fmt.Errorf("download of %s: %w", job.opts.Path, err))
+		}
+	}()
+}
+
+func (a *App) runFlash(ctx context.Context, job *flashJob) error {
+	c, err := a.udsClient(job.handle)
+	if err != nil {
+		return err
+	}
+	opts := &job.opts
+	stage := func(name string) {
+		job.mu.Lock()
+		job.stage = name
+		job.mu.Unlock()
+		a.emit("uds:flash", job.progress())
+	}
+
+	if opts.Session != 0 {
+		stage(flashSession)
+		if _, err := c.DiagnosticSessionControl(ctx, opts.Session); err != nil {
+			return err
+		}
+	}
+	if opts.SecurityLevel != 0 {
+		stage(flashSecurity)
+		s := a.securityAlgo.Load()
+		if s == nil {
+			return errors.New("no security algorithm loaded")
+		}
+		if err := c.SecurityAccess(ctx, opts.SecurityLevel, s.algo.Key); err != nil {
+			return err
+		}
+	}
+	if opts.EraseRoutine != 0 && !job.erased {
+		stage(flashErase)
+		addr, size := opts.Address, uint32(len(job.data))
+		params := []byte{0x44,
+			byte(addr >> 24), byte(addr >> 16), byte(addr >> 8), byte(addr),
+			byte(size >> 24), byte(size >> 16), byte(size >> 8), byte(size)}
+		if _, err := c.StartRoutine(ctx, opts.EraseRoutine, params); err != nil {
+			return err
+		}
+		job.erased = true
+	}
+	if job.transfer.Offset < len(job.data) {
+		stage(flashDownload)
+		job.transfer.Progress = func(offset int) {
+			job.mu.Lock()
+			job.sent = offset
+			job.mu.Unlock()
+			a.emit("uds:flash", job.progress())
+		}
+		if err := c.Download(ctx, &job.transfer); err != nil {
+			return err
+		}
+	}
+	if opts.CheckRoutine != 0 {
+		stage(flashCheck)
+		sum := crc32.ChecksumIEEE(job.data)
+		if _, err := c.StartRoutine(ctx, opts.CheckRoutine, []byte{byte(sum >> 24), byte(sum >> 16), byte(sum >> 8), byte(sum)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (job *flashJob) running() bool {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return job.state == sequenceRunning
+}
+
+func (job *flashJob) progress() FlashProgress {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	p := FlashProgress{
+		Handle: job.handle,
+		Path:   job.opts.Path,
+		State:  job.state,
+		Stage:  job.stage,
+		Sent:   job.sent,
+		Total:  len(job.data),
+	}
+	if job.err != nil {
+		p.Error = job.err.Error()
+	}
+	return p
+}
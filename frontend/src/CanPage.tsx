@@ -9,6 +9,9 @@ type CANFrameEvent = {
   id: number;
   extended: boolean;
   remote: boolean;
+  fd: boolean;
+  brs: boolean;
+  esi: boolean;
   dlc: number;
   data: number[];
 };
@@ -31,15 +34,21 @@ export default function CanPage() {
     const onFrame = (payload: CANFrameEvent) => {
       setFrames((prev) => [payload, ...prev].slice(0, 100));
     };
+    const onFrames = (payload: { frames: CANFrameEvent[] }) => {
+      const batch = payload.frames.slice(-100).reverse();
+      setFrames((prev) => [...batch, ...prev].slice(0, 100));
+    };
     const onError = (msg: string) => {
       pushError(msg);
     };
 
     EventsOn("can:frame", onFrame);
+    EventsOn("can:frames", onFrames);
     EventsOn("can:error", onError);
 
     return () => {
       EventsOff("can:frame");
+      EventsOff("can:frames");
       EventsOff("can:error");
     };
   }, []);
@@ -114,7 +123,7 @@ export default function CanPage() {
   async function stop() {
     setBusy("stopping");
     try {
-      await StopCAN();
+      await StopCAN(iface);
     } catch (e: any) {
       pushError(e?.message ?? e);
     } finally {
@@ -125,7 +134,7 @@ export default function CanPage() {
 
   async function sendTestFrame() {
     try {
-      await SendFrame(0x123, [0x01, 0x02, 0x03], false);
+      await SendFrame(iface, 0x123, [0x01, 0x02, 0x03], false);
     } catch (e: any) {
       pushError(e?.message ?? e);
     }
@@ -263,7 +272,7 @@ export default function CanPage() {
                 <tr key={idx} style={styles.tr}>
                   <td style={styles.td}>{formatTimestamp(f.timestamp)}</td>
                   <td style={styles.td}>{formatID(f.id, f.extended)}</td>
-                  <td style={styles.td}>{f.extended ? "EXT" : "STD"}</td>
+                  <td style={styles.td}>{formatType(f)}</td>
                   <td style={styles.td}>{f.dlc}</td>
                   <td style={{ ...styles.td, whiteSpace: "pre" }}>{formatData(f.data)}</td>
                 </tr>
@@ -300,6 +309,12 @@ function formatID(id: number, extended: boolean): string {
   return `0x${id.toString(16).toUpperCase().padStart(width, "0")}`;
 }
 
+function formatType(f: CANFrameEvent): string {
+  const base = f.extended ? "EXT" : "STD";
+  if (!f.fd) return base;
+  return `${base} FD${f.brs ? " BRS" : ""}${f.esi ? " ESI" : ""}`;
+}
+
 function formatData(data: number[]): string {
   return data.map((b) => b.toString(16).toUpperCase().padStart(2, "0")).join(" ");
 }
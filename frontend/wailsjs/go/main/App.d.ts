@@ -1,8 +1,543 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {main} from '../models';
 
-export function SendFrame(arg1:number,arg2:Array<number>,arg3:boolean):Promise<void>;
+export function ActiveInterfaces():Promise<Array<string>>;
+
+export function AddAlertRule(arg1:main.AlertRule):Promise<number>;
+
+export function AddBookmark(arg1:number,arg2:string,arg3:string):Promise<main.Bookmark>;
+
+export function AddGapTransmit(arg1:main.GapTransmitRule):Promise<number>;
+
+export function AnalyzeBits(arg1:string,arg2:number,arg3:boolean,arg4:main.TimeRange):Promise<main.BitAnalysis>;
+
+export function AnnotateFrame(arg1:number,arg2:string,arg3:string):Promise<void>;
+
+export function ArmTrigger(arg1:main.TriggerOptions):Promise<void>;
+
+export function ClearAlertRules():Promise<void>;
+
+export function ClearCapture():Promise<void>;
+
+export function ClearFilters(arg1:string):Promise<void>;
+
+export function ClearFuzzFindings():Promise<void>;
+
+export function ClearGapTransmits():Promise<void>;
+
+export function ClearIDConflicts():Promise<void>;
+
+export function ClearSessionJournal():Promise<void>;
+
+export function ClearTxHistory():Promise<void>;
+
+export function ClearTxQueue(arg1:string):Promise<void>;
+
+export function CloseDoIP(arg1:number):Promise<void>;
+
+export function CloseIsoTP(arg1:number):Promise<void>;
+
+export function CompareCaptures(arg1:string,arg2:string):Promise<main.CaptureComparison>;
+
+export function ConfigureInterface(arg1:string,arg2:number,arg3:number,arg4:number,arg5:number):Promise<void>;
+
+export function ConfigureTxQueue(arg1:string,arg2:number,arg3:number):Promise<void>;
+
+export function CreateVcan(arg1:string):Promise<void>;
+
+export function DeleteDBCMessage(arg1:string,arg2:string):Promise<void>;
+
+export function DeleteDBCSignal(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function DeleteTXPanel(arg1:string):Promise<void>;
+
+export function DeleteVcan(arg1:string):Promise<void>;
+
+export function DisarmTrigger():Promise<main.TriggerStatus>;
+
+export function DiscoverDoIP(arg1:string,arg2:number):Promise<Array<main.DoIPEntity>>;
+
+export function DissectFrame(arg1:number,arg2:Array<number>,arg3:boolean):Promise<Array<main.DissectResult>>;
+
+export function EncodeAndSend(arg1:string,arg2:string,arg3:Record<string, number>):Promise<Array<number>>;
+
+export function EncodeSignals(arg1:string,arg2:Record<string, number>):Promise<Array<number>>;
+
+export function ExportCapture(arg1:string,arg2:string,arg3:main.CaptureFilter,arg4:main.TimeRange):Promise<number>;
+
+export function ExportDiagReport(arg1:string):Promise<number>;
+
+export function ExportFuzzFindings(arg1:string):Promise<number>;
+
+export function ExportSession(arg1:string):Promise<number>;
+
+export function GetBusState(arg1:string):Promise<main.BusState>;
+
+export function GetCANopenNodes(arg1:string):Promise<Array<main.CANopenNodeInfo>>;
+
+export function GetCaptureStatus():Promise<main.CaptureStatus>;
+
+export function GetDBCMessages(arg1:string):Promise<Array<main.DBCMessage>>;
+
+export function GetDiagRecordingStatus():Promise<main.DiagRecordingStatus>;
+
+export function GetEngineState():Promise<main.EngineState>;
+
+export function GetEventServerStatus():Promise<main.EventServerStatus>;
+
+export function GetFaultCapabilities(arg1:string):Promise<main.FaultCapabilities>;
+
+export function GetFilters(arg1:string):Promise<Array<main.CANFilter>>;
+
+export function GetFlashProgress(arg1:number):Promise<main.FlashProgress>;
+
+export function GetFrameBatching():Promise<main.FrameBatchOptions>;
+
+export function GetFrameGroups():Promise<Array<main.FrameGroup>>;
+
+export function GetFrameSubscriptions():Promise<Array<main.FrameSubscription>>;
+
+export function GetFuzzFindings():Promise<Array<main.FuzzFinding>>;
+
+export function GetGPSStatus():Promise<main.GPSStatus>;
+
+export function GetGlobalFrameChannel():Promise<boolean>;
+
+export function GetInputBridgeStatus():Promise<main.InputBridgeStatus>;
+
+export function GetLoggingStatus():Promise<main.LoggingStatus>;
+
+export function GetMDFStatus():Promise<main.LoggingStatus>;
+
+export function GetMQTTStatus():Promise<main.MQTTStatus>;
+
+export function GetNMNodes(arg1:string):Promise<Array<main.NMNodeInfo>>;
+
+export function GetODXInfo():Promise<main.ODXInfo>;
+
+export function GetOverview():Promise<Array<main.OverviewEntry>>;
+
+export function GetOverviewOptions():Promise<main.OverviewOptions>;
+
+export function GetPcapStatus():Promise<main.LoggingStatus>;
+
+export function GetRESTStatus():Promise<main.RESTStatus>;
+
+export function GetRemoteStatus(arg1:string):Promise<main.RemoteStatus>;
+
+export function GetReplayStatus():Promise<main.ReplayStatus>;
+
+export function GetResponderStatus():Promise<main.ResponderStatus>;
+
+export function GetSecurityAlgorithm():Promise<string>;
+
+export function GetSessionJournal():Promise<Array<main.JournalEntry>>;
+
+export function GetSignalRecordingStatus():Promise<main.SignalRecordingStatus>;
+
+export function GetSocketOptions(arg1:string):Promise<main.SocketOptions>;
+
+export function GetStats(arg1:string):Promise<main.CANStats>;
+
+export function GetTXPanel(arg1:string):Promise<main.TXPanel>;
+
+export function GetTimestampMode(arg1:string):Promise<main.TimestampStatus>;
+
+export function GetTiming(arg1:string):Promise<Array<main.MessageTiming>>;
+
+export function GetTriggerStatus():Promise<main.TriggerStatus>;
+
+export function GetTxHistory():Promise<Array<main.TxHistoryEntry>>;
+
+export function GetTxQueueStatus(arg1:string):Promise<main.TxQueueStatus>;
+
+export function GetTxTemplate(arg1:string):Promise<main.TxTemplate>;
+
+export function GetUDSKeepAlive(arg1:number):Promise<main.UDSKeepAliveStatus>;
+
+export function ImportCapture(arg1:string):Promise<main.CaptureImport>;
+
+export function InjectBusOff(arg1:string):Promise<main.FaultResult>;
+
+export function InjectErrorFrame(arg1:string,arg2:string,arg3:number,arg4:number):Promise<void>;
+
+export function InjectWrongBitrate(arg1:string,arg2:number,arg3:number):Promise<main.FaultResult>;
+
+export function ListAlertRules():Promise<Array<main.AlertRuleInfo>>;
+
+export function ListBookmarks(arg1:main.TimeRange):Promise<Array<main.Bookmark>>;
+
+export function ListCANInterfaces():Promise<Array<main.CANInterfaceInfo>>;
+
+export function ListCANopenObjects(arg1:string,arg2:number):Promise<Array<main.CANopenObjectInfo>>;
+
+export function ListCyclicFrames():Promise<Array<main.CyclicFrameInfo>>;
+
+export function ListDissectors():Promise<Array<main.DissectorInfo>>;
+
+export function ListDoIPConnections():Promise<Array<main.DoIPConnectionInfo>>;
+
+export function ListE2EProtections():Promise<Array<main.E2EProtection>>;
+
+export function ListE2EStatus():Promise<Array<main.E2EStatus>>;
+
+export function ListFrameProcessors():Promise<Array<string>>;
+
+export function ListFuzzers():Promise<Array<main.FuzzStatus>>;
+
+export function ListGapTransmits():Promise<Array<main.GapTransmitInfo>>;
+
+export function ListGenerators():Promise<Array<main.GeneratorStatus>>;
+
+export function ListIDConflicts():Promise<Array<main.IDConflictEvent>>;
+
+export function ListIsoTPChannels():Promise<Array<main.IsoTPChannelInfo>>;
+
+export function ListLDFs():Promise<Array<main.LDFInfo>>;
+
+export function ListLINSchedules():Promise<Array<main.LINScheduleStatus>>;
+
+export function ListODXDIDs():Promise<Array<main.ODXDIDInfo>>;
+
+export function ListPlotSignals():Promise<Array<main.PlotSeriesInfo>>;
+
+export function ListProfiles():Promise<Array<main.ProfileInfo>>;
+
+export function ListScripts():Promise<Array<main.ScriptInfo>>;
+
+export function ListSequences():Promise<Array<main.SequenceInfo>>;
+
+export function ListSerialPorts():Promise<Array<string>>;
+
+export function ListTXPanels():Promise<Array<main.TXPanel>>;
+
+export function ListTXProcessors():Promise<Array<main.TXProcessor>>;
+
+export function ListTransports():Promise<Array<main.TransportInfo>>;
+
+export function ListXCPSessions():Promise<Array<main.XCPSessionInfo>>;
+
+export function LoadDBC(arg1:string):Promise<main.DBCInfo>;
+
+export function LoadDTCDatabase(arg1:string):Promise<number>;
+
+export function LoadDissectorPlugin(arg1:string):Promise<Array<main.DissectorInfo>>;
+
+export function LoadEDS(arg1:string,arg2:number,arg3:string):Promise<main.EDSInfo>;
+
+export function LoadLDF(arg1:string):Promise<main.LDFInfo>;
+
+export function LoadODX(arg1:string):Promise<main.ODXInfo>;
+
+export function LoadProfile(arg1:string):Promise<main.ProfileLoadResult>;
+
+export function LoadResponderProfile(arg1:string):Promise<main.ResponderStatus>;
+
+export function LoadScript(arg1:string,arg2:string):Promise<number>;
+
+export function LoadSecurityAlgorithm(arg1:string):Promise<void>;
+
+export function LoadSequences(arg1:string):Promise<Array<main.SequenceInfo>>;
+
+export function LoadedDBCs():Promise<Array<main.DBCInfo>>;
+
+export function MeasureGatewayLatency(arg1:number,arg2:number):Promise<main.GatewayLatency>;
+
+export function NewDBC(arg1:string):Promise<main.DBCInfo>;
+
+export function OBDKnownPIDs():Promise<Array<main.OBDPIDInfo>>;
+
+export function OBDReadDTCs(arg1:number,arg2:boolean):Promise<Array<main.OBDDTC>>;
+
+export function OBDReadFreezeFrame(arg1:number,arg2:number):Promise<main.OBDFreezeFrame>;
+
+export function OBDReadTestResults(arg1:number):Promise<main.OBDTestResults>;
+
+export function OBDReadVehicleInfo(arg1:number):Promise<main.OBDVehicleInfo>;
+
+export function OpenDoIP(arg1:string,arg2:main.DoIPOptions):Promise<number>;
+
+export function OpenIsoTP(arg1:string,arg2:number,arg3:number,arg4:main.IsoTPOptions):Promise<number>;
+
+export function OpenIsoTPServer(arg1:string,arg2:number,arg3:number,arg4:main.IsoTPOptions):Promise<number>;
+
+export function PauseCapture():Promise<main.CaptureStatus>;
+
+export function PauseReplay():Promise<void>;
+
+export function QueryCapture(arg1:main.CaptureFilter,arg2:main.TimeRange,arg3:number,arg4:number):Promise<main.CapturePage>;
+
+export function QueryOBDSupportedPIDs(arg1:string):Promise<Array<number>>;
+
+export function QueryPlot(arg1:main.PlotQuery):Promise<Array<main.PlotSeries>>;
+
+export function QueueFrame(arg1:string,arg2:number,arg3:Array<number>,arg4:boolean):Promise<number>;
+
+export function ReadOBDPID(arg1:string,arg2:number):Promise<main.OBDPIDEvent>;
+
+export function ReadSDO(arg1:string,arg2:number,arg3:number,arg4:number):Promise<Array<number>>;
+
+export function ReadSDOByName(arg1:string,arg2:number,arg3:string):Promise<main.CANopenValue>;
+
+export function RecoverCapture(arg1:string):Promise<main.CaptureRecovery>;
+
+export function RemoveAlertRule(arg1:number):Promise<void>;
+
+export function RemoveBookmark(arg1:number):Promise<void>;
+
+export function RemoveE2EProtection(arg1:string,arg2:number,arg3:boolean):Promise<void>;
+
+export function RemoveGapTransmit(arg1:number):Promise<void>;
+
+export function RemoveTXProcessor(arg1:number,arg2:boolean):Promise<void>;
+
+export function RenameTXPanel(arg1:string,arg2:string):Promise<void>;
+
+export function ReplayLog(arg1:string,arg2:string,arg3:number,arg4:boolean):Promise<void>;
+
+export function ReplaySession(arg1:string):Promise<main.SessionReplayResult>;
+
+export function ResendFrame(arg1:number):Promise<void>;
+
+export function ResetE2EStatus():Promise<void>;
+
+export function ResetOverview():Promise<void>;
+
+export function ResetResponderCounters():Promise<void>;
+
+export function ResetStats(arg1:string):Promise<void>;
+
+export function ResetTiming(arg1:string):Promise<void>;
+
+export function ResetTxTemplate(arg1:string):Promise<void>;
+
+export function RestartInterface(arg1:string):Promise<void>;
+
+export function ResumeCapture():Promise<main.CaptureStatus>;
+
+export function ResumeReplay():Promise<void>;
+
+export function RunSequence(arg1:string):Promise<void>;
+
+export function RunSequenceWithOptions(arg1:string,arg2:main.SequenceOptions):Promise<Array<main.TXWarning>>;
+
+export function SaveDBC(arg1:string,arg2:string):Promise<main.DBCInfo>;
+
+export function SaveProfile(arg1:string):Promise<main.ProfileInfo>;
+
+export function SaveTXPanel(arg1:main.TXPanel):Promise<void>;
+
+export function SelfTest(arg1:string,arg2:string):Promise<main.SelfTestResult>;
+
+export function SendDoIP(arg1:number,arg2:Array<number>):Promise<void>;
+
+export function SendFDFrame(arg1:string,arg2:number,arg3:Array<number>,arg4:boolean,arg5:boolean):Promise<void>;
+
+export function SendFrame(arg1:string,arg2:number,arg3:Array<number>,arg4:boolean):Promise<void>;
+
+export function SendFrameWithOptions(arg1:string,arg2:number,arg3:Array<number>,arg4:boolean,arg5:main.SendOptions):Promise<Array<main.TXWarning>>;
+
+export function SendIsoTP(arg1:number,arg2:Array<number>):Promise<void>;
+
+export function SendNMT(arg1:string,arg2:string,arg3:number):Promise<void>;
+
+export function SendPGN(arg1:string,arg2:number,arg3:number,arg4:number,arg5:number,arg6:Array<number>):Promise<void>;
+
+export function SendTXPanelItem(arg1:string,arg2:number):Promise<number>;
+
+export function SetAutoReconnect(arg1:string,arg2:boolean):Promise<void>;
+
+export function SetBusOffRecovery(arg1:string,arg2:boolean,arg3:number):Promise<void>;
+
+export function SetCANopenDecoding(arg1:string,arg2:boolean):Promise<void>;
+
+export function SetCaptureSize(arg1:number):Promise<void>;
+
+export function SetCaptureSpill(arg1:main.CaptureSpillOptions):Promise<main.CaptureStatus>;
+
+export function SetDBCMessage(arg1:string,arg2:string,arg3:main.DBCMessage):Promise<main.DBCMessage>;
+
+export function SetDBCSignal(arg1:string,arg2:string,arg3:string,arg4:main.DBCSignal):Promise<main.DBCMessage>;
+
+export function SetDissectorRanges(arg1:string,arg2:Array<main.DissectorRange>):Promise<void>;
+
+export function SetE2EProtection(arg1:main.E2EProtection):Promise<void>;
+
+export function SetFilters(arg1:string,arg2:Array<main.CANFilter>):Promise<void>;
+
+export function SetFrameBatching(arg1:main.FrameBatchOptions):Promise<void>;
+
+export function SetFrameGroups(arg1:Array<main.FrameGroup>):Promise<void>;
+
+export function SetGlobalFrameChannel(arg1:boolean):Promise<void>;
+
+export function SetInterfaceUp(arg1:string,arg2:boolean):Promise<void>;
+
+export function SetJ1939Decoding(arg1:string,arg2:boolean):Promise<void>;
+
+export function SetLINFrameData(arg1:string,arg2:Array<number>):Promise<void>;
+
+export function SetNMDecoding(arg1:string,arg2:boolean,arg3:main.NMOptions):Promise<void>;
+
+export function SetNMEA2000Decoding(arg1:string,arg2:boolean):Promise<void>;
+
+export function SetOverview(arg1:main.OverviewOptions):Promise<void>;
+
+export function SetResponderEnabled(arg1:boolean):Promise<void>;
+
+export function SetSocketOptions(arg1:string,arg2:main.SocketOptions):Promise<void>;
+
+export function SetTXProcessor(arg1:main.TXProcessor):Promise<void>;
+
+export function SetTimestampMode(arg1:string,arg2:string):Promise<void>;
+
+export function SetUDSKeepAlive(arg1:number,arg2:main.UDSKeepAliveOptions):Promise<main.UDSKeepAliveStatus>;
+
+export function StartBusFlood(arg1:string,arg2:number):Promise<number>;
 
 export function StartCAN(arg1:string):Promise<void>;
 
-export function StopCAN():Promise<void>;
+export function StartCANFD(arg1:string):Promise<void>;
+
+export function StartCANWithOptions(arg1:string,arg2:main.CANOptions):Promise<void>;
+
+export function StartCyclicFrame(arg1:string,arg2:number,arg3:Array<number>,arg4:boolean,arg5:number):Promise<number>;
+
+export function StartDiagRecording(arg1:string):Promise<void>;
+
+export function StartEventServer(arg1:main.EventServerOptions):Promise<main.EventServerStatus>;
+
+export function StartFuzzer(arg1:main.FuzzConfig):Promise<number>;
+
+export function StartGPS(arg1:main.GPSOptions):Promise<main.GPSStatus>;
+
+export function StartGenerator(arg1:main.GeneratorConfig):Promise<number>;
+
+export function StartInputBridge(arg1:main.InputBridgeOptions):Promise<main.InputBridgeStatus>;
+
+export function StartLINSchedule(arg1:string,arg2:string):Promise<void>;
+
+export function StartLogging(arg1:string,arg2:boolean):Promise<void>;
+
+export function StartMDFRecording(arg1:string,arg2:string):Promise<void>;
+
+export function StartMQTTBridge(arg1:main.MQTTOptions):Promise<main.MQTTStatus>;
+
+export function StartOBDPolling(arg1:string,arg2:Array<number>,arg3:number):Promise<void>;
+
+export function StartPcapCapture(arg1:string):Promise<void>;
+
+export function StartRESTServer(arg1:main.RESTOptions):Promise<main.RESTStatus>;
+
+export function StartSignalRecording(arg1:main.SignalRecordingOptions):Promise<main.SignalRecordingStatus>;
+
+export function StopAllCAN():Promise<void>;
+
+export function StopCAN(arg1:string):Promise<void>;
+
+export function StopCyclicFrame(arg1:number):Promise<void>;
+
+export function StopDiagRecording():Promise<main.DiagReport>;
+
+export function StopEventServer():Promise<void>;
+
+export function StopFuzzer(arg1:number):Promise<void>;
+
+export function StopGPS():Promise<main.GPSStatus>;
+
+export function StopGenerator(arg1:number):Promise<void>;
+
+export function StopInputBridge():Promise<main.InputBridgeStatus>;
+
+export function StopLINSchedule(arg1:string):Promise<void>;
+
+export function StopLogging():Promise<main.LoggingStatus>;
+
+export function StopMDFRecording():Promise<main.LoggingStatus>;
+
+export function StopMQTTBridge():Promise<void>;
+
+export function StopOBDPolling(arg1:string):Promise<void>;
+
+export function StopPcapCapture():Promise<main.LoggingStatus>;
+
+export function StopRESTServer():Promise<void>;
+
+export function StopReplay():Promise<void>;
+
+export function StopSequence(arg1:string):Promise<void>;
+
+export function StopSessionReplay():Promise<void>;
+
+export function StopSignalRecording():Promise<main.SignalRecordingStatus>;
+
+export function SubscribeFrames(arg1:main.FrameSubscription):Promise<main.FrameSubscription>;
+
+export function TrackPlotSignals(arg1:Array<main.PlotSignal>):Promise<void>;
+
+export function UDSDiagnosticSessionControl(arg1:number,arg2:number):Promise<main.UDSSessionTiming>;
+
+export function UDSECUReset(arg1:number,arg2:number):Promise<void>;
+
+export function UDSFlash(arg1:number,arg2:main.FlashOptions):Promise<void>;
+
+export function UDSReadDID(arg1:number,arg2:number):Promise<main.DiagDID>;
+
+export function UDSReadDTCs(arg1:number,arg2:number):Promise<Array<main.UDSDTC>>;
+
+export function UDSReadDataByIdentifier(arg1:number,arg2:number):Promise<Array<number>>;
+
+export function UDSReadMemoryByAddress(arg1:number,arg2:number,arg3:number,arg4:main.UDSMemoryFormat):Promise<main.UDSMemoryDump>;
+
+export function UDSRequest(arg1:number,arg2:Array<number>):Promise<main.UDSResponse>;
+
+export function UDSResumeFlash(arg1:number):Promise<void>;
+
+export function UDSSecurityAccess(arg1:number,arg2:number):Promise<void>;
+
+export function UDSStopFlash(arg1:number):Promise<void>;
+
+export function UDSTesterPresent(arg1:number):Promise<void>;
+
+export function UDSWriteMemoryByAddress(arg1:number,arg2:number,arg3:Array<number>,arg4:main.UDSMemoryFormat):Promise<main.UDSMemoryDump>;
+
+export function UnloadDBC(arg1:string):Promise<void>;
+
+export function UnloadDTCDatabase():Promise<void>;
+
+export function UnloadDissector(arg1:string):Promise<void>;
+
+export function UnloadEDS(arg1:string,arg2:number):Promise<void>;
+
+export function UnloadLDF(arg1:string):Promise<void>;
+
+export function UnloadODX():Promise<void>;
+
+export function UnloadResponderProfile():Promise<void>;
+
+export function UnloadScript(arg1:number):Promise<void>;
+
+export function UnloadSecurityAlgorithm():Promise<void>;
+
+export function UnsubscribeFrames(arg1:string):Promise<void>;
+
+export function UntrackPlotSignals(arg1:Array<main.PlotSignal>):Promise<void>;
+
+export function WriteSDO(arg1:string,arg2:number,arg3:number,arg4:number,arg5:Array<number>):Promise<void>;
+
+export function WriteSDOByName(arg1:string,arg2:number,arg3:string,arg4:string):Promise<void>;
+
+export function XCPConnect(arg1:string,arg2:number,arg3:number,arg4:boolean):Promise<main.XCPSessionInfo>;
+
+export function XCPDisconnect(arg1:number):Promise<void>;
+
+export function XCPDownload(arg1:number,arg2:number,arg3:number,arg4:Array<number>):Promise<void>;
+
+export function XCPSetupDAQ(arg1:number,arg2:Array<main.XCPDAQList>):Promise<void>;
+
+export function XCPShortUpload(arg1:number,arg2:number,arg3:number,arg4:number):Promise<Array<number>>;
+
+export function XCPStartDAQ(arg1:number):Promise<void>;
+
+export function XCPStopDAQ(arg1:number):Promise<void>;
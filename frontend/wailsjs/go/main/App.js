@@ -2,14 +2,1082 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function SendFrame(arg1, arg2, arg3) {
-  return window['go']['main']['App']['SendFrame'](arg1, arg2, arg3);
+export function ActiveInterfaces() {
+  return window['go']['main']['App']['ActiveInterfaces']();
+}
+
+export function AddAlertRule(arg1) {
+  return window['go']['main']['App']['AddAlertRule'](arg1);
+}
+
+export function AddBookmark(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AddBookmark'](arg1, arg2, arg3);
+}
+
+export function AddGapTransmit(arg1) {
+  return window['go']['main']['App']['AddGapTransmit'](arg1);
+}
+
+export function AnalyzeBits(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['AnalyzeBits'](arg1, arg2, arg3, arg4);
+}
+
+export function AnnotateFrame(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AnnotateFrame'](arg1, arg2, arg3);
+}
+
+export function ArmTrigger(arg1) {
+  return window['go']['main']['App']['ArmTrigger'](arg1);
+}
+
+export function ClearAlertRules() {
+  return window['go']['main']['App']['ClearAlertRules']();
+}
+
+export function ClearCapture() {
+  return window['go']['main']['App']['ClearCapture']();
+}
+
+export function ClearFilters(arg1) {
+  return window['go']['main']['App']['ClearFilters'](arg1);
+}
+
+export function ClearFuzzFindings() {
+  return window['go']['main']['App']['ClearFuzzFindings']();
+}
+
+export function ClearGapTransmits() {
+  return window['go']['main']['App']['ClearGapTransmits']();
+}
+
+export function ClearIDConflicts() {
+  return window['go']['main']['App']['ClearIDConflicts']();
+}
+
+export function ClearSessionJournal() {
+  return window['go']['main']['App']['ClearSessionJournal']();
+}
+
+export function ClearTxHistory() {
+  return window['go']['main']['App']['ClearTxHistory']();
+}
+
+export function ClearTxQueue(arg1) {
+  return window['go']['main']['App']['ClearTxQueue'](arg1);
+}
+
+export function CloseDoIP(arg1) {
+  return window['go']['main']['App']['CloseDoIP'](arg1);
+}
+
+export function CloseIsoTP(arg1) {
+  return window['go']['main']['App']['CloseIsoTP'](arg1);
+}
+
+export function CompareCaptures(arg1, arg2) {
+  return window['go']['main']['App']['CompareCaptures'](arg1, arg2);
+}
+
+export function ConfigureInterface(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ConfigureInterface'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function ConfigureTxQueue(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ConfigureTxQueue'](arg1, arg2, arg3);
+}
+
+export function CreateVcan(arg1) {
+  return window['go']['main']['App']['CreateVcan'](arg1);
+}
+
+export function DeleteDBCMessage(arg1, arg2) {
+  return window['go']['main']['App']['DeleteDBCMessage'](arg1, arg2);
+}
+
+export function DeleteDBCSignal(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DeleteDBCSignal'](arg1, arg2, arg3);
+}
+
+export function DeleteTXPanel(arg1) {
+  return window['go']['main']['App']['DeleteTXPanel'](arg1);
+}
+
+export function DeleteVcan(arg1) {
+  return window['go']['main']['App']['DeleteVcan'](arg1);
+}
+
+export function DisarmTrigger() {
+  return window['go']['main']['App']['DisarmTrigger']();
+}
+
+export function DiscoverDoIP(arg1, arg2) {
+  return window['go']['main']['App']['DiscoverDoIP'](arg1, arg2);
+}
+
+export function DissectFrame(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DissectFrame'](arg1, arg2, arg3);
+}
+
+export function EncodeAndSend(arg1, arg2, arg3) {
+  return window['go']['main']['App']['EncodeAndSend'](arg1, arg2, arg3);
+}
+
+export function EncodeSignals(arg1, arg2) {
+  return window['go']['main']['App']['EncodeSignals'](arg1, arg2);
+}
+
+export function ExportCapture(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ExportCapture'](arg1, arg2, arg3, arg4);
+}
+
+export function ExportDiagReport(arg1) {
+  return window['go']['main']['App']['ExportDiagReport'](arg1);
+}
+
+export function ExportFuzzFindings(arg1) {
+  return window['go']['main']['App']['ExportFuzzFindings'](arg1);
+}
+
+export function ExportSession(arg1) {
+  return window['go']['main']['App']['ExportSession'](arg1);
+}
+
+export function GetBusState(arg1) {
+  return window['go']['main']['App']['GetBusState'](arg1);
+}
+
+export function GetCANopenNodes(arg1) {
+  return window['go']['main']['App']['GetCANopenNodes'](arg1);
+}
+
+export function GetCaptureStatus() {
+  return window['go']['main']['App']['GetCaptureStatus']();
+}
+
+export function GetDBCMessages(arg1) {
+  return window['go']['main']['App']['GetDBCMessages'](arg1);
+}
+
+export function GetDiagRecordingStatus() {
+  return window['go']['main']['App']['GetDiagRecordingStatus']();
+}
+
+export function GetEngineState() {
+  return window['go']['main']['App']['GetEngineState']();
+}
+
+export function GetEventServerStatus() {
+  return window['go']['main']['App']['GetEventServerStatus']();
+}
+
+export function GetFaultCapabilities(arg1) {
+  return window['go']['main']['App']['GetFaultCapabilities'](arg1);
+}
+
+export function GetFilters(arg1) {
+  return window['go']['main']['App']['GetFilters'](arg1);
+}
+
+export function GetFlashProgress(arg1) {
+  return window['go']['main']['App']['GetFlashProgress'](arg1);
+}
+
+export function GetFrameBatching() {
+  return window['go']['main']['App']['GetFrameBatching']();
+}
+
+export function GetFrameGroups() {
+  return window['go']['main']['App']['GetFrameGroups']();
+}
+
+export function GetFrameSubscriptions() {
+  return window['go']['main']['App']['GetFrameSubscriptions']();
+}
+
+export function GetFuzzFindings() {
+  return window['go']['main']['App']['GetFuzzFindings']();
+}
+
+export function GetGPSStatus() {
+  return window['go']['main']['App']['GetGPSStatus']();
+}
+
+export function GetGlobalFrameChannel() {
+  return window['go']['main']['App']['GetGlobalFrameChannel']();
+}
+
+export function GetInputBridgeStatus() {
+  return window['go']['main']['App']['GetInputBridgeStatus']();
+}
+
+export function GetLoggingStatus() {
+  return window['go']['main']['App']['GetLoggingStatus']();
+}
+
+export function GetMDFStatus() {
+  return window['go']['main']['App']['GetMDFStatus']();
+}
+
+export function GetMQTTStatus() {
+  return window['go']['main']['App']['GetMQTTStatus']();
+}
+
+export function GetNMNodes(arg1) {
+  return window['go']['main']['App']['GetNMNodes'](arg1);
+}
+
+export function GetODXInfo() {
+  return window['go']['main']['App']['GetODXInfo']();
+}
+
+export function GetOverview() {
+  return window['go']['main']['App']['GetOverview']();
+}
+
+export function GetOverviewOptions() {
+  return window['go']['main']['App']['GetOverviewOptions']();
+}
+
+export function GetPcapStatus() {
+  return window['go']['main']['App']['GetPcapStatus']();
+}
+
+export function GetRESTStatus() {
+  return window['go']['main']['App']['GetRESTStatus']();
+}
+
+export function GetRemoteStatus(arg1) {
+  return window['go']['main']['App']['GetRemoteStatus'](arg1);
+}
+
+export function GetReplayStatus() {
+  return window['go']['main']['App']['GetReplayStatus']();
+}
+
+export function GetResponderStatus() {
+  return window['go']['main']['App']['GetResponderStatus']();
+}
+
+export function GetSecurityAlgorithm() {
+  return window['go']['main']['App']['GetSecurityAlgorithm']();
+}
+
+export function GetSessionJournal() {
+  return window['go']['main']['App']['GetSessionJournal']();
+}
+
+export function GetSignalRecordingStatus() {
+  return window['go']['main']['App']['GetSignalRecordingStatus']();
+}
+
+export function GetSocketOptions(arg1) {
+  return window['go']['main']['App']['GetSocketOptions'](arg1);
+}
+
+export function GetStats(arg1) {
+  return window['go']['main']['App']['GetStats'](arg1);
+}
+
+export function GetTXPanel(arg1) {
+  return window['go']['main']['App']['GetTXPanel'](arg1);
+}
+
+export function GetTimestampMode(arg1) {
+  return window['go']['main']['App']['GetTimestampMode'](arg1);
+}
+
+export function GetTiming(arg1) {
+  return window['go']['main']['App']['GetTiming'](arg1);
+}
+
+export function GetTriggerStatus() {
+  return window['go']['main']['App']['GetTriggerStatus']();
+}
+
+export function GetTxHistory() {
+  return window['go']['main']['App']['GetTxHistory']();
+}
+
+export function GetTxQueueStatus(arg1) {
+  return window['go']['main']['App']['GetTxQueueStatus'](arg1);
+}
+
+export function GetTxTemplate(arg1) {
+  return window['go']['main']['App']['GetTxTemplate'](arg1);
+}
+
+export function GetUDSKeepAlive(arg1) {
+  return window['go']['main']['App']['GetUDSKeepAlive'](arg1);
+}
+
+export function ImportCapture(arg1) {
+  return window['go']['main']['App']['ImportCapture'](arg1);
+}
+
+export function InjectBusOff(arg1) {
+  return window['go']['main']['App']['InjectBusOff'](arg1);
+}
+
+export function InjectErrorFrame(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['InjectErrorFrame'](arg1, arg2, arg3, arg4);
+}
+
+export function InjectWrongBitrate(arg1, arg2, arg3) {
+  return window['go']['main']['App']['InjectWrongBitrate'](arg1, arg2, arg3);
+}
+
+export function ListAlertRules() {
+  return window['go']['main']['App']['ListAlertRules']();
+}
+
+export function ListBookmarks(arg1) {
+  return window['go']['main']['App']['ListBookmarks'](arg1);
+}
+
+export function ListCANInterfaces() {
+  return window['go']['main']['App']['ListCANInterfaces']();
+}
+
+export function ListCANopenObjects(arg1, arg2) {
+  return window['go']['main']['App']['ListCANopenObjects'](arg1, arg2);
+}
+
+export function ListCyclicFrames() {
+  return window['go']['main']['App']['ListCyclicFrames']();
+}
+
+export function ListDissectors() {
+  return window['go']['main']['App']['ListDissectors']();
+}
+
+export function ListDoIPConnections() {
+  return window['go']['main']['App']['ListDoIPConnections']();
+}
+
+export function ListE2EProtections() {
+  return window['go']['main']['App']['ListE2EProtections']();
+}
+
+export function ListE2EStatus() {
+  return window['go']['main']['App']['ListE2EStatus']();
+}
+
+export function ListFrameProcessors() {
+  return window['go']['main']['App']['ListFrameProcessors']();
+}
+
+export function ListFuzzers() {
+  return window['go']['main']['App']['ListFuzzers']();
+}
+
+export function ListGapTransmits() {
+  return window['go']['main']['App']['ListGapTransmits']();
+}
+
+export function ListGenerators() {
+  return window['go']['main']['App']['ListGenerators']();
+}
+
+export function ListIDConflicts() {
+  return window['go']['main']['App']['ListIDConflicts']();
+}
+
+export function ListIsoTPChannels() {
+  return window['go']['main']['App']['ListIsoTPChannels']();
+}
+
+export function ListLDFs() {
+  return window['go']['main']['App']['ListLDFs']();
+}
+
+export function ListLINSchedules() {
+  return window['go']['main']['App']['ListLINSchedules']();
+}
+
+export function ListODXDIDs() {
+  return window['go']['main']['App']['ListODXDIDs']();
+}
+
+export function ListPlotSignals() {
+  return window['go']['main']['App']['ListPlotSignals']();
+}
+
+export function ListProfiles() {
+  return window['go']['main']['App']['ListProfiles']();
+}
+
+export function ListScripts() {
+  return window['go']['main']['App']['ListScripts']();
+}
+
+export function ListSequences() {
+  return window['go']['main']['App']['ListSequences']();
+}
+
+export function ListSerialPorts() {
+  return window['go']['main']['App']['ListSerialPorts']();
+}
+
+export function ListTXPanels() {
+  return window['go']['main']['App']['ListTXPanels']();
+}
+
+export function ListTXProcessors() {
+  return window['go']['main']['App']['ListTXProcessors']();
+}
+
+export function ListTransports() {
+  return window['go']['main']['App']['ListTransports']();
+}
+
+export function ListXCPSessions() {
+  return window['go']['main']['App']['ListXCPSessions']();
+}
+
+export function LoadDBC(arg1) {
+  return window['go']['main']['App']['LoadDBC'](arg1);
+}
+
+export function LoadDTCDatabase(arg1) {
+  return window['go']['main']['App']['LoadDTCDatabase'](arg1);
+}
+
+export function LoadDissectorPlugin(arg1) {
+  return window['go']['main']['App']['LoadDissectorPlugin'](arg1);
+}
+
+export function LoadEDS(arg1, arg2, arg3) {
+  return window['go']['main']['App']['LoadEDS'](arg1, arg2, arg3);
+}
+
+export function LoadLDF(arg1) {
+  return window['go']['main']['App']['LoadLDF'](arg1);
+}
+
+export function LoadODX(arg1) {
+  return window['go']['main']['App']['LoadODX'](arg1);
+}
+
+export function LoadProfile(arg1) {
+  return window['go']['main']['App']['LoadProfile'](arg1);
+}
+
+export function LoadResponderProfile(arg1) {
+  return window['go']['main']['App']['LoadResponderProfile'](arg1);
+}
+
+export function LoadScript(arg1, arg2) {
+  return window['go']['main']['App']['LoadScript'](arg1, arg2);
+}
+
+export function LoadSecurityAlgorithm(arg1) {
+  return window['go']['main']['App']['LoadSecurityAlgorithm'](arg1);
+}
+
+export function LoadSequences(arg1) {
+  return window['go']['main']['App']['LoadSequences'](arg1);
+}
+
+export function LoadedDBCs() {
+  return window['go']['main']['App']['LoadedDBCs']();
+}
+
+export function MeasureGatewayLatency(arg1, arg2) {
+  return window['go']['main']['App']['MeasureGatewayLatency'](arg1, arg2);
+}
+
+export function NewDBC(arg1) {
+  return window['go']['main']['App']['NewDBC'](arg1);
+}
+
+export function OBDKnownPIDs() {
+  return window['go']['main']['App']['OBDKnownPIDs']();
+}
+
+export function OBDReadDTCs(arg1, arg2) {
+  return window['go']['main']['App']['OBDReadDTCs'](arg1, arg2);
+}
+
+export function OBDReadFreezeFrame(arg1, arg2) {
+  return window['go']['main']['App']['OBDReadFreezeFrame'](arg1, arg2);
+}
+
+export function OBDReadTestResults(arg1) {
+  return window['go']['main']['App']['OBDReadTestResults'](arg1);
+}
+
+export function OBDReadVehicleInfo(arg1) {
+  return window['go']['main']['App']['OBDReadVehicleInfo'](arg1);
+}
+
+export function OpenDoIP(arg1, arg2) {
+  return window['go']['main']['App']['OpenDoIP'](arg1, arg2);
+}
+
+export function OpenIsoTP(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['OpenIsoTP'](arg1, arg2, arg3, arg4);
+}
+
+export function OpenIsoTPServer(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['OpenIsoTPServer'](arg1, arg2, arg3, arg4);
+}
+
+export function PauseCapture() {
+  return window['go']['main']['App']['PauseCapture']();
+}
+
+export function PauseReplay() {
+  return window['go']['main']['App']['PauseReplay']();
+}
+
+export function QueryCapture(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['QueryCapture'](arg1, arg2, arg3, arg4);
+}
+
+export function QueryOBDSupportedPIDs(arg1) {
+  return window['go']['main']['App']['QueryOBDSupportedPIDs'](arg1);
+}
+
+export function QueryPlot(arg1) {
+  return window['go']['main']['App']['QueryPlot'](arg1);
+}
+
+export function QueueFrame(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['QueueFrame'](arg1, arg2, arg3, arg4);
+}
+
+export function ReadOBDPID(arg1, arg2) {
+  return window['go']['main']['App']['ReadOBDPID'](arg1, arg2);
+}
+
+export function ReadSDO(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ReadSDO'](arg1, arg2, arg3, arg4);
+}
+
+export function ReadSDOByName(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ReadSDOByName'](arg1, arg2, arg3);
+}
+
+export function RecoverCapture(arg1) {
+  return window['go']['main']['App']['RecoverCapture'](arg1);
+}
+
+export function RemoveAlertRule(arg1) {
+  return window['go']['main']['App']['RemoveAlertRule'](arg1);
+}
+
+export function RemoveBookmark(arg1) {
+  return window['go']['main']['App']['RemoveBookmark'](arg1);
+}
+
+export function RemoveE2EProtection(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RemoveE2EProtection'](arg1, arg2, arg3);
+}
+
+export function RemoveGapTransmit(arg1) {
+  return window['go']['main']['App']['RemoveGapTransmit'](arg1);
+}
+
+export function RemoveTXProcessor(arg1, arg2) {
+  return window['go']['main']['App']['RemoveTXProcessor'](arg1, arg2);
+}
+
+export function RenameTXPanel(arg1, arg2) {
+  return window['go']['main']['App']['RenameTXPanel'](arg1, arg2);
+}
+
+export function ReplayLog(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ReplayLog'](arg1, arg2, arg3, arg4);
+}
+
+export function ReplaySession(arg1) {
+  return window['go']['main']['App']['ReplaySession'](arg1);
+}
+
+export function ResendFrame(arg1) {
+  return window['go']['main']['App']['ResendFrame'](arg1);
+}
+
+export function ResetE2EStatus() {
+  return window['go']['main']['App']['ResetE2EStatus']();
+}
+
+export function ResetOverview() {
+  return window['go']['main']['App']['ResetOverview']();
+}
+
+export function ResetResponderCounters() {
+  return window['go']['main']['App']['ResetResponderCounters']();
+}
+
+export function ResetStats(arg1) {
+  return window['go']['main']['App']['ResetStats'](arg1);
+}
+
+export function ResetTiming(arg1) {
+  return window['go']['main']['App']['ResetTiming'](arg1);
+}
+
+export function ResetTxTemplate(arg1) {
+  return window['go']['main']['App']['ResetTxTemplate'](arg1);
+}
+
+export function RestartInterface(arg1) {
+  return window['go']['main']['App']['RestartInterface'](arg1);
+}
+
+export function ResumeCapture() {
+  return window['go']['main']['App']['ResumeCapture']();
+}
+
+export function ResumeReplay() {
+  return window['go']['main']['App']['ResumeReplay']();
+}
+
+export function RunSequence(arg1) {
+  return window['go']['main']['App']['RunSequence'](arg1);
+}
+
+export function RunSequenceWithOptions(arg1, arg2) {
+  return window['go']['main']['App']['RunSequenceWithOptions'](arg1, arg2);
+}
+
+export function SaveDBC(arg1, arg2) {
+  return window['go']['main']['App']['SaveDBC'](arg1, arg2);
+}
+
+export function SaveProfile(arg1) {
+  return window['go']['main']['App']['SaveProfile'](arg1);
+}
+
+export function SaveTXPanel(arg1) {
+  return window['go']['main']['App']['SaveTXPanel'](arg1);
+}
+
+export function SelfTest(arg1, arg2) {
+  return window['go']['main']['App']['SelfTest'](arg1, arg2);
+}
+
+export function SendDoIP(arg1, arg2) {
+  return window['go']['main']['App']['SendDoIP'](arg1, arg2);
+}
+
+export function SendFDFrame(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['SendFDFrame'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function SendFrame(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SendFrame'](arg1, arg2, arg3, arg4);
+}
+
+export function SendFrameWithOptions(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['SendFrameWithOptions'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function SendIsoTP(arg1, arg2) {
+  return window['go']['main']['App']['SendIsoTP'](arg1, arg2);
+}
+
+export function SendNMT(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SendNMT'](arg1, arg2, arg3);
+}
+
+export function SendPGN(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['SendPGN'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function SendTXPanelItem(arg1, arg2) {
+  return window['go']['main']['App']['SendTXPanelItem'](arg1, arg2);
+}
+
+export function SetAutoReconnect(arg1, arg2) {
+  return window['go']['main']['App']['SetAutoReconnect'](arg1, arg2);
+}
+
+export function SetBusOffRecovery(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetBusOffRecovery'](arg1, arg2, arg3);
+}
+
+export function SetCANopenDecoding(arg1, arg2) {
+  return window['go']['main']['App']['SetCANopenDecoding'](arg1, arg2);
+}
+
+export function SetCaptureSize(arg1) {
+  return window['go']['main']['App']['SetCaptureSize'](arg1);
+}
+
+export function SetCaptureSpill(arg1) {
+  return window['go']['main']['App']['SetCaptureSpill'](arg1);
+}
+
+export function SetDBCMessage(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetDBCMessage'](arg1, arg2, arg3);
+}
+
+export function SetDBCSignal(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SetDBCSignal'](arg1, arg2, arg3, arg4);
+}
+
+export function SetDissectorRanges(arg1, arg2) {
+  return window['go']['main']['App']['SetDissectorRanges'](arg1, arg2);
+}
+
+export function SetE2EProtection(arg1) {
+  return window['go']['main']['App']['SetE2EProtection'](arg1);
+}
+
+export function SetFilters(arg1, arg2) {
+  return window['go']['main']['App']['SetFilters'](arg1, arg2);
+}
+
+export function SetFrameBatching(arg1) {
+  return window['go']['main']['App']['SetFrameBatching'](arg1);
+}
+
+export function SetFrameGroups(arg1) {
+  return window['go']['main']['App']['SetFrameGroups'](arg1);
+}
+
+export function SetGlobalFrameChannel(arg1) {
+  return window['go']['main']['App']['SetGlobalFrameChannel'](arg1);
+}
+
+export function SetInterfaceUp(arg1, arg2) {
+  return window['go']['main']['App']['SetInterfaceUp'](arg1, arg2);
+}
+
+export function SetJ1939Decoding(arg1, arg2) {
+  return window['go']['main']['App']['SetJ1939Decoding'](arg1, arg2);
+}
+
+export function SetLINFrameData(arg1, arg2) {
+  return window['go']['main']['App']['SetLINFrameData'](arg1, arg2);
+}
+
+export function SetNMDecoding(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetNMDecoding'](arg1, arg2, arg3);
+}
+
+export function SetNMEA2000Decoding(arg1, arg2) {
+  return window['go']['main']['App']['SetNMEA2000Decoding'](arg1, arg2);
+}
+
+export function SetOverview(arg1) {
+  return window['go']['main']['App']['SetOverview'](arg1);
+}
+
+export function SetResponderEnabled(arg1) {
+  return window['go']['main']['App']['SetResponderEnabled'](arg1);
+}
+
+export function SetSocketOptions(arg1, arg2) {
+  return window['go']['main']['App']['SetSocketOptions'](arg1, arg2);
+}
+
+export function SetTXProcessor(arg1) {
+  return window['go']['main']['App']['SetTXProcessor'](arg1);
+}
+
+export function SetTimestampMode(arg1, arg2) {
+  return window['go']['main']['App']['SetTimestampMode'](arg1, arg2);
+}
+
+export function SetUDSKeepAlive(arg1, arg2) {
+  return window['go']['main']['App']['SetUDSKeepAlive'](arg1, arg2);
+}
+
+export function StartBusFlood(arg1, arg2) {
+  return window['go']['main']['App']['StartBusFlood'](arg1, arg2);
 }
 
 export function StartCAN(arg1) {
   return window['go']['main']['App']['StartCAN'](arg1);
 }
 
-export function StopCAN() {
-  return window['go']['main']['App']['StopCAN']();
+export function StartCANFD(arg1) {
+  return window['go']['main']['App']['StartCANFD'](arg1);
+}
+
+export function StartCANWithOptions(arg1, arg2) {
+  return window['go']['main']['App']['StartCANWithOptions'](arg1, arg2);
+}
+
+export function StartCyclicFrame(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['StartCyclicFrame'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function StartDiagRecording(arg1) {
+  return window['go']['main']['App']['StartDiagRecording'](arg1);
+}
+
+export function StartEventServer(arg1) {
+  return window['go']['main']['App']['StartEventServer'](arg1);
+}
+
+export function StartFuzzer(arg1) {
+  return window['go']['main']['App']['StartFuzzer'](arg1);
+}
+
+export function StartGPS(arg1) {
+  return window['go']['main']['App']['StartGPS'](arg1);
+}
+
+export function StartGenerator(arg1) {
+  return window['go']['main']['App']['StartGenerator'](arg1);
+}
+
+export function StartInputBridge(arg1) {
+  return window['go']['main']['App']['StartInputBridge'](arg1);
+}
+
+export function StartLINSchedule(arg1, arg2) {
+  return window['go']['main']['App']['StartLINSchedule'](arg1, arg2);
+}
+
+export function StartLogging(arg1, arg2) {
+  return window['go']['main']['App']['StartLogging'](arg1, arg2);
+}
+
+export function StartMDFRecording(arg1, arg2) {
+  return window['go']['main']['App']['StartMDFRecording'](arg1, arg2);
+}
+
+export function StartMQTTBridge(arg1) {
+  return window['go']['main']['App']['StartMQTTBridge'](arg1);
+}
+
+export function StartOBDPolling(arg1, arg2, arg3) {
+  return window['go']['main']['App']['StartOBDPolling'](arg1, arg2, arg3);
+}
+
+export function StartPcapCapture(arg1) {
+  return window['go']['main']['App']['StartPcapCapture'](arg1);
+}
+
+export function StartRESTServer(arg1) {
+  return window['go']['main']['App']['StartRESTServer'](arg1);
+}
+
+export function StartSignalRecording(arg1) {
+  return window['go']['main']['App']['StartSignalRecording'](arg1);
+}
+
+export function StopAllCAN() {
+  return window['go']['main']['App']['StopAllCAN']();
+}
+
+export function StopCAN(arg1) {
+  return window['go']['main']['App']['StopCAN'](arg1);
+}
+
+export function StopCyclicFrame(arg1) {
+  return window['go']['main']['App']['StopCyclicFrame'](arg1);
+}
+
+export function StopDiagRecording() {
+  return window['go']['main']['App']['StopDiagRecording']();
+}
+
+export function StopEventServer() {
+  return window['go']['main']['App']['StopEventServer']();
+}
+
+export function StopFuzzer(arg1) {
+  return window['go']['main']['App']['StopFuzzer'](arg1);
+}
+
+export function StopGPS() {
+  return window['go']['main']['App']['StopGPS']();
+}
+
+export function StopGenerator(arg1) {
+  return window['go']['main']['App']['StopGenerator'](arg1);
+}
+
+export function StopInputBridge() {
+  return window['go']['main']['App']['StopInputBridge']();
+}
+
+export function StopLINSchedule(arg1) {
+  return window['go']['main']['App']['StopLINSchedule'](arg1);
+}
+
+export function StopLogging() {
+  return window['go']['main']['App']['StopLogging']();
+}
+
+export function StopMDFRecording() {
+  return window['go']['main']['App']['StopMDFRecording']();
+}
+
+export function StopMQTTBridge() {
+  return window['go']['main']['App']['StopMQTTBridge']();
+}
+
+export function StopOBDPolling(arg1) {
+  return window['go']['main']['App']['StopOBDPolling'](arg1);
+}
+
+export function StopPcapCapture() {
+  return window['go']['main']['App']['StopPcapCapture']();
+}
+
+export function StopRESTServer() {
+  return window['go']['main']['App']['StopRESTServer']();
+}
+
+export function StopReplay() {
+  return window['go']['main']['App']['StopReplay']();
+}
+
+export function StopSequence(arg1) {
+  return window['go']['main']['App']['StopSequence'](arg1);
+}
+
+export function StopSessionReplay() {
+  return window['go']['main']['App']['StopSessionReplay']();
+}
+
+export function StopSignalRecording() {
+  return window['go']['main']['App']['StopSignalRecording']();
+}
+
+export function SubscribeFrames(arg1) {
+  return window['go']['main']['App']['SubscribeFrames'](arg1);
+}
+
+export function TrackPlotSignals(arg1) {
+  return window['go']['main']['App']['TrackPlotSignals'](arg1);
+}
+
+export function UDSDiagnosticSessionControl(arg1, arg2) {
+  return window['go']['main']['App']['UDSDiagnosticSessionControl'](arg1, arg2);
+}
+
+export function UDSECUReset(arg1, arg2) {
+  return window['go']['main']['App']['UDSECUReset'](arg1, arg2);
+}
+
+export function UDSFlash(arg1, arg2) {
+  return window['go']['main']['App']['UDSFlash'](arg1, arg2);
+}
+
+export function UDSReadDID(arg1, arg2) {
+  return window['go']['main']['App']['UDSReadDID'](arg1, arg2);
+}
+
+export function UDSReadDTCs(arg1, arg2) {
+  return window['go']['main']['App']['UDSReadDTCs'](arg1, arg2);
+}
+
+export function UDSReadDataByIdentifier(arg1, arg2) {
+  return window['go']['main']['App']['UDSReadDataByIdentifier'](arg1, arg2);
+}
+
+export function UDSReadMemoryByAddress(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['UDSReadMemoryByAddress'](arg1, arg2, arg3, arg4);
+}
+
+export function UDSRequest(arg1, arg2) {
+  return window['go']['main']['App']['UDSRequest'](arg1, arg2);
+}
+
+export function UDSResumeFlash(arg1) {
+  return window['go']['main']['App']['UDSResumeFlash'](arg1);
+}
+
+export function UDSSecurityAccess(arg1, arg2) {
+  return window['go']['main']['App']['UDSSecurityAccess'](arg1, arg2);
+}
+
+export function UDSStopFlash(arg1) {
+  return window['go']['main']['App']['UDSStopFlash'](arg1);
+}
+
+export function UDSTesterPresent(arg1) {
+  return window['go']['main']['App']['UDSTesterPresent'](arg1);
+}
+
+export function UDSWriteMemoryByAddress(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['UDSWriteMemoryByAddress'](arg1, arg2, arg3, arg4);
+}
+
+export function UnloadDBC(arg1) {
+  return window['go']['main']['App']['UnloadDBC'](arg1);
+}
+
+export function UnloadDTCDatabase() {
+  return window['go']['main']['App']['UnloadDTCDatabase']();
+}
+
+export function UnloadDissector(arg1) {
+  return window['go']['main']['App']['UnloadDissector'](arg1);
+}
+
+export function UnloadEDS(arg1, arg2) {
+  return window['go']['main']['App']['UnloadEDS'](arg1, arg2);
+}
+
+export function UnloadLDF(arg1) {
+  return window['go']['main']['App']['UnloadLDF'](arg1);
+}
+
+export function UnloadODX() {
+  return window['go']['main']['App']['UnloadODX']();
+}
+
+export function UnloadResponderProfile() {
+  return window['go']['main']['App']['UnloadResponderProfile']();
+}
+
+export function UnloadScript(arg1) {
+  return window['go']['main']['App']['UnloadScript'](arg1);
+}
+
+export function UnloadSecurityAlgorithm() {
+  return window['go']['main']['App']['UnloadSecurityAlgorithm']();
+}
+
+export function UnsubscribeFrames(arg1) {
+  return window['go']['main']['App']['UnsubscribeFrames'](arg1);
+}
+
+export function UntrackPlotSignals(arg1) {
+  return window['go']['main']['App']['UntrackPlotSignals'](arg1);
+}
+
+export function WriteSDO(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['WriteSDO'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function WriteSDOByName(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['WriteSDOByName'](arg1, arg2, arg3, arg4);
+}
+
+export function XCPConnect(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['XCPConnect'](arg1, arg2, arg3, arg4);
+}
+
+export function XCPDisconnect(arg1) {
+  return window['go']['main']['App']['XCPDisconnect'](arg1);
+}
+
+export function XCPDownload(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['XCPDownload'](arg1, arg2, arg3, arg4);
+}
+
+export function XCPSetupDAQ(arg1, arg2) {
+  return window['go']['main']['App']['XCPSetupDAQ'](arg1, arg2);
+}
+
+export function XCPShortUpload(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['XCPShortUpload'](arg1, arg2, arg3, arg4);
+}
+
+export function XCPStartDAQ(arg1) {
+  return window['go']['main']['App']['XCPStartDAQ'](arg1);
+}
+
+export function XCPStopDAQ(arg1) {
+  return window['go']['main']['App']['XCPStopDAQ'](arg1);
 }
@@ -0,0 +1,4875 @@
+export namespace analysis {
+	
+	export class BitStats {
+	    byte: number;
+	    bit: number;
+	    toggles: number;
+	    ratio: number;
+	    rate: number;
+	    ones: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BitStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.byte = source["byte"];
+	        this.bit = source["bit"];
+	        this.toggles = source["toggles"];
+	        this.ratio = source["ratio"];
+	        this.rate = source["rate"];
+	        this.ones = source["ones"];
+	    }
+	}
+	export class Checksum {
+	    byte: number;
+	    kind: string;
+	    confidence: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Checksum(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.byte = source["byte"];
+	        this.kind = source["kind"];
+	        this.confidence = source["confidence"];
+	    }
+	}
+	export class Counter {
+	    byte: number;
+	    mask: number;
+	    step: number;
+	    confidence: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Counter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.byte = source["byte"];
+	        this.mask = source["mask"];
+	        this.step = source["step"];
+	        this.confidence = source["confidence"];
+	    }
+	}
+
+}
+
+export namespace candb {
+	
+	export class Value {
+	    name: string;
+	    raw: number;
+	    value: number;
+	    unit: string;
+	    label?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Value(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.raw = source["raw"];
+	        this.value = source["value"];
+	        this.unit = source["unit"];
+	        this.label = source["label"];
+	    }
+	}
+
+}
+
+export namespace main {
+	
+	export class AlertRule {
+	    name: string;
+	    interface: string;
+	    id: number;
+	    extended: boolean;
+	    signal: string;
+	    message: string;
+	    byte: number;
+	    mask: number;
+	    condition: string;
+	    value: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new AlertRule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.interface = source["interface"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.signal = source["signal"];
+	        this.message = source["message"];
+	        this.byte = source["byte"];
+	        this.mask = source["mask"];
+	        this.condition = source["condition"];
+	        this.value = source["value"];
+	    }
+	}
+	export class AlertRuleInfo {
+	    handle: number;
+	    rule: AlertRule;
+	    hits: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new AlertRuleInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.rule = this.convertValues(source["rule"], AlertRule);
+	        this.hits = source["hits"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BitAnalysis {
+	    interface: string;
+	    id: number;
+	    extended: boolean;
+	    samples: number;
+	    length: number;
+	    durationMs: number;
+	    changed: number[];
+	    constant: number[];
+	    bits: analysis.BitStats[];
+	    counters: analysis.Counter[];
+	    checksums: analysis.Checksum[];
+	
+	    static createFrom(source: any = {}) {
+	        return new BitAnalysis(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.samples = source["samples"];
+	        this.length = source["length"];
+	        this.durationMs = source["durationMs"];
+	        this.changed = source["changed"];
+	        this.constant = source["constant"];
+	        this.bits = this.convertValues(source["bits"], analysis.BitStats);
+	        this.counters = this.convertValues(source["counters"], analysis.Counter);
+	        this.checksums = this.convertValues(source["checksums"], analysis.Checksum);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Bookmark {
+	    id: number;
+	    // Go type: time
+	    timestamp: any;
+	    comment?: string;
+	    tag?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Bookmark(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.comment = source["comment"];
+	        this.tag = source["tag"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BusState {
+	    // Go type: time
+	    timestamp: any;
+	    interface: string;
+	    state: string;
+	    previous: string;
+	    txErrors: number;
+	    rxErrors: number;
+	    hasCounters: boolean;
+	    errorFrames: number;
+	    busOffCount: number;
+	    autoRestart: boolean;
+	    restartDelayMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BusState(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.interface = source["interface"];
+	        this.state = source["state"];
+	        this.previous = source["previous"];
+	        this.txErrors = source["txErrors"];
+	        this.rxErrors = source["rxErrors"];
+	        this.hasCounters = source["hasCounters"];
+	        this.errorFrames = source["errorFrames"];
+	        this.busOffCount = source["busOffCount"];
+	        this.autoRestart = source["autoRestart"];
+	        this.restartDelayMs = source["restartDelayMs"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CANFilter {
+	    id: number;
+	    mask: number;
+	    extended: boolean;
+	    invert: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CANFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.mask = source["mask"];
+	        this.extended = source["extended"];
+	        this.invert = source["invert"];
+	    }
+	}
+	export class CANGroupStats {
+	    group: string;
+	    count: number;
+	    bytes: number;
+	    rate: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CANGroupStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.group = source["group"];
+	        this.count = source["count"];
+	        this.bytes = source["bytes"];
+	        this.rate = source["rate"];
+	    }
+	}
+	export class CANIDStats {
+	    id: number;
+	    extended: boolean;
+	    count: number;
+	    bytes: number;
+	    rate: number;
+	    minIntervalMs: number;
+	    avgIntervalMs: number;
+	    maxIntervalMs: number;
+	    group?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CANIDStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.count = source["count"];
+	        this.bytes = source["bytes"];
+	        this.rate = source["rate"];
+	        this.minIntervalMs = source["minIntervalMs"];
+	        this.avgIntervalMs = source["avgIntervalMs"];
+	        this.maxIntervalMs = source["maxIntervalMs"];
+	        this.group = source["group"];
+	    }
+	}
+	export class CANInterfaceInfo {
+	    name: string;
+	    state: string;
+	    up: boolean;
+	    kind: string;
+	    driver: string;
+	    bitrate: number;
+	    samplePoint: number;
+	    dataBitrate: number;
+	    fd: boolean;
+	    xl: boolean;
+	    xlError?: string;
+	    restartMs: number;
+	    started: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CANInterfaceInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.state = source["state"];
+	        this.up = source["up"];
+	        this.kind = source["kind"];
+	        this.driver = source["driver"];
+	        this.bitrate = source["bitrate"];
+	        this.samplePoint = source["samplePoint"];
+	        this.dataBitrate = source["dataBitrate"];
+	        this.fd = source["fd"];
+	        this.xl = source["xl"];
+	        this.xlError = source["xlError"];
+	        this.restartMs = source["restartMs"];
+	        this.started = source["started"];
+	    }
+	}
+	export class CANOptions {
+	    fd: boolean;
+	    autoReconnect: boolean;
+	    readOnly: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CANOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.fd = source["fd"];
+	        this.autoReconnect = source["autoReconnect"];
+	        this.readOnly = source["readOnly"];
+	    }
+	}
+	export class CANStats {
+	    // Go type: time
+	    timestamp: any;
+	    interface: string;
+	    bitrate: number;
+	    framesPerSec: number;
+	    bytesPerSec: number;
+	    errorFramesPerSec: number;
+	    busLoad: number;
+	    totalFrames: number;
+	    totalBytes: number;
+	    totalErrors: number;
+	    txFrames: number;
+	    kernelDrops: number;
+	    ids: CANIDStats[];
+	    groups: CANGroupStats[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CANStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.interface = source["interface"];
+	        this.bitrate = source["bitrate"];
+	        this.framesPerSec = source["framesPerSec"];
+	        this.bytesPerSec = source["bytesPerSec"];
+	        this.errorFramesPerSec = source["errorFramesPerSec"];
+	        this.busLoad = source["busLoad"];
+	        this.totalFrames = source["totalFrames"];
+	        this.totalBytes = source["totalBytes"];
+	        this.totalErrors = source["totalErrors"];
+	        this.txFrames = source["txFrames"];
+	        this.kernelDrops = source["kernelDrops"];
+	        this.ids = this.convertValues(source["ids"], CANIDStats);
+	        this.groups = this.convertValues(source["groups"], CANGroupStats);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CANopenNodeInfo {
+	    node: number;
+	    state: string;
+	    // Go type: time
+	    lastSeen: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new CANopenNodeInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.node = source["node"];
+	        this.state = source["state"];
+	        this.lastSeen = this.convertValues(source["lastSeen"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CANopenObjectInfo {
+	    index: number;
+	    subindex: number;
+	    name: string;
+	    dataType: string;
+	    access: string;
+	    pdoMapping: boolean;
+	    default: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CANopenObjectInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.subindex = source["subindex"];
+	        this.name = source["name"];
+	        this.dataType = source["dataType"];
+	        this.access = source["access"];
+	        this.pdoMapping = source["pdoMapping"];
+	        this.default = source["default"];
+	    }
+	}
+	export class CANopenValue {
+	    name: string;
+	    index: number;
+	    subindex: number;
+	    dataType: string;
+	    text: string;
+	    number: number;
+	    numeric: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CANopenValue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.index = source["index"];
+	        this.subindex = source["subindex"];
+	        this.dataType = source["dataType"];
+	        this.text = source["text"];
+	        this.number = source["number"];
+	        this.numeric = source["numeric"];
+	    }
+	}
+	export class IDDifference {
+	    id: number;
+	    extended: boolean;
+	    name?: string;
+	    countA: number;
+	    countB: number;
+	    cycleAMs: number;
+	    cycleBMs: number;
+	    cycleDiffers: boolean;
+	    lengthA: number;
+	    lengthB: number;
+	    changedA: number[];
+	    changedB: number[];
+	    constantDiffers: number[];
+	    patternDiffers: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new IDDifference(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.name = source["name"];
+	        this.countA = source["countA"];
+	        this.countB = source["countB"];
+	        this.cycleAMs = source["cycleAMs"];
+	        this.cycleBMs = source["cycleBMs"];
+	        this.cycleDiffers = source["cycleDiffers"];
+	        this.lengthA = source["lengthA"];
+	        this.lengthB = source["lengthB"];
+	        this.changedA = source["changedA"];
+	        this.changedB = source["changedB"];
+	        this.constantDiffers = source["constantDiffers"];
+	        this.patternDiffers = source["patternDiffers"];
+	    }
+	}
+	export class ComparedID {
+	    id: number;
+	    extended: boolean;
+	    name?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ComparedID(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.name = source["name"];
+	    }
+	}
+	export class CaptureComparison {
+	    framesA: number;
+	    framesB: number;
+	    onlyA: ComparedID[];
+	    onlyB: ComparedID[];
+	    differences: IDDifference[];
+	    same: number;
+	    warnings: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CaptureComparison(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.framesA = source["framesA"];
+	        this.framesB = source["framesB"];
+	        this.onlyA = this.convertValues(source["onlyA"], ComparedID);
+	        this.onlyB = this.convertValues(source["onlyB"], ComparedID);
+	        this.differences = this.convertValues(source["differences"], IDDifference);
+	        this.same = source["same"];
+	        this.warnings = source["warnings"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CaptureFilter {
+	    interface: string;
+	    network: string;
+	    ids: CANFilter[];
+	    direction: string;
+	    data: string;
+	    tag: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CaptureFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.network = source["network"];
+	        this.ids = this.convertValues(source["ids"], CANFilter);
+	        this.direction = source["direction"];
+	        this.data = source["data"];
+	        this.tag = source["tag"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CaptureImport {
+	    frames: Record<string, number>;
+	    warnings?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CaptureImport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.frames = source["frames"];
+	        this.warnings = source["warnings"];
+	    }
+	}
+	export class CapturedFrame {
+	    seq: number;
+	    // Go type: time
+	    timestamp: any;
+	    interface: string;
+	    network: string;
+	    direction: string;
+	    id: number;
+	    extended: boolean;
+	    remote: boolean;
+	    error: boolean;
+	    fd: boolean;
+	    brs: boolean;
+	    esi: boolean;
+	    dlc: number;
+	    data: number[];
+	    cycle?: number;
+	    group?: string;
+	    comment?: string;
+	    tag?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CapturedFrame(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.seq = source["seq"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.interface = source["interface"];
+	        this.network = source["network"];
+	        this.direction = source["direction"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.remote = source["remote"];
+	        this.error = source["error"];
+	        this.fd = source["fd"];
+	        this.brs = source["brs"];
+	        this.esi = source["esi"];
+	        this.dlc = source["dlc"];
+	        this.data = source["data"];
+	        this.cycle = source["cycle"];
+	        this.group = source["group"];
+	        this.comment = source["comment"];
+	        this.tag = source["tag"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CapturePage {
+	    total: number;
+	    offset: number;
+	    frames: CapturedFrame[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CapturePage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.total = source["total"];
+	        this.offset = source["offset"];
+	        this.frames = this.convertValues(source["frames"], CapturedFrame);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CaptureRecovery {
+	    path: string;
+	    chunks: number;
+	    frames: number;
+	    indexed: number;
+	    // Go type: time
+	    first: any;
+	    // Go type: time
+	    last: any;
+	    truncatedBytes: number;
+	    torn?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CaptureRecovery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.chunks = source["chunks"];
+	        this.frames = source["frames"];
+	        this.indexed = source["indexed"];
+	        this.first = this.convertValues(source["first"], null);
+	        this.last = this.convertValues(source["last"], null);
+	        this.truncatedBytes = source["truncatedBytes"];
+	        this.torn = source["torn"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CaptureSpillOptions {
+	    enabled: boolean;
+	    memoryMB: number;
+	    dir: string;
+	    diskMB: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CaptureSpillOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.memoryMB = source["memoryMB"];
+	        this.dir = source["dir"];
+	        this.diskMB = source["diskMB"];
+	    }
+	}
+	export class CaptureStatus {
+	    size: number;
+	    count: number;
+	    spilling: boolean;
+	    spillDir?: string;
+	    spilled: number;
+	    spillBytes: number;
+	    spillError?: string;
+	    paused: boolean;
+	    skipped: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CaptureStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.size = source["size"];
+	        this.count = source["count"];
+	        this.spilling = source["spilling"];
+	        this.spillDir = source["spillDir"];
+	        this.spilled = source["spilled"];
+	        this.spillBytes = source["spillBytes"];
+	        this.spillError = source["spillError"];
+	        this.paused = source["paused"];
+	        this.skipped = source["skipped"];
+	    }
+	}
+	
+	
+	export class CyclicFrameInfo {
+	    handle: number;
+	    interface: string;
+	    id: number;
+	    extended: boolean;
+	    fd: boolean;
+	    data: number[];
+	    periodMs: number;
+	    kernel: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new CyclicFrameInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.interface = source["interface"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.fd = source["fd"];
+	        this.data = source["data"];
+	        this.periodMs = source["periodMs"];
+	        this.kernel = source["kernel"];
+	    }
+	}
+	export class DBCInfo {
+	    path: string;
+	    version: string;
+	    messages: number;
+	    signals: number;
+	    modified: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new DBCInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.version = source["version"];
+	        this.messages = source["messages"];
+	        this.signals = source["signals"];
+	        this.modified = source["modified"];
+	    }
+	}
+	export class ValueLabel {
+	    value: number;
+	    label: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ValueLabel(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.value = source["value"];
+	        this.label = source["label"];
+	    }
+	}
+	export class DBCSignal {
+	    name: string;
+	    startBit: number;
+	    length: number;
+	    bigEndian: boolean;
+	    signed: boolean;
+	    valueType: string;
+	    scale: number;
+	    offset: number;
+	    min: number;
+	    max: number;
+	    unit: string;
+	    description: string;
+	    receivers: string[];
+	    values: ValueLabel[];
+	    multiplexer: boolean;
+	    multiplexed: boolean;
+	    multiplexerValue: number;
+	    multiplexerSwitch: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DBCSignal(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.startBit = source["startBit"];
+	        this.length = source["length"];
+	        this.bigEndian = source["bigEndian"];
+	        this.signed = source["signed"];
+	        this.valueType = source["valueType"];
+	        this.scale = source["scale"];
+	        this.offset = source["offset"];
+	        this.min = source["min"];
+	        this.max = source["max"];
+	        this.unit = source["unit"];
+	        this.description = source["description"];
+	        this.receivers = source["receivers"];
+	        this.values = this.convertValues(source["values"], ValueLabel);
+	        this.multiplexer = source["multiplexer"];
+	        this.multiplexed = source["multiplexed"];
+	        this.multiplexerValue = source["multiplexerValue"];
+	        this.multiplexerSwitch = source["multiplexerSwitch"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DBCMessage {
+	    name: string;
+	    id: number;
+	    extended: boolean;
+	    length: number;
+	    sender: string;
+	    description: string;
+	    cycleTimeMs: number;
+	    signals: DBCSignal[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DBCMessage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.length = source["length"];
+	        this.sender = source["sender"];
+	        this.description = source["description"];
+	        this.cycleTimeMs = source["cycleTimeMs"];
+	        this.signals = this.convertValues(source["signals"], DBCSignal);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class DIDValue {
+	    name: string;
+	    value: number;
+	    numeric: boolean;
+	    text?: string;
+	    unit?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DIDValue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.value = source["value"];
+	        this.numeric = source["numeric"];
+	        this.text = source["text"];
+	        this.unit = source["unit"];
+	    }
+	}
+	export class DoIPOptions {
+	    sourceAddress: number;
+	    targetAddress: number;
+	    activationType: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DoIPOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sourceAddress = source["sourceAddress"];
+	        this.targetAddress = source["targetAddress"];
+	        this.activationType = source["activationType"];
+	    }
+	}
+	export class DoIPConnectionInfo {
+	    handle: number;
+	    address: string;
+	    options: DoIPOptions;
+	    entityAddress: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DoIPConnectionInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.address = source["address"];
+	        this.options = this.convertValues(source["options"], DoIPOptions);
+	        this.entityAddress = source["entityAddress"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class IsoTPOptions {
+	    extended: boolean;
+	    addressing?: string;
+	    txAddress: number;
+	    rxAddress: number;
+	    fd: boolean;
+	    brs: boolean;
+	    frameLength: number;
+	    blockSize: number;
+	    stminUs: number;
+	    overrideStmin: boolean;
+	    txStminUs: number;
+	    maxWaitFrames: number;
+	    maxMessageSize: number;
+	    padding: boolean;
+	    paddingByte: number;
+	    timeoutMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new IsoTPOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.extended = source["extended"];
+	        this.addressing = source["addressing"];
+	        this.txAddress = source["txAddress"];
+	        this.rxAddress = source["rxAddress"];
+	        this.fd = source["fd"];
+	        this.brs = source["brs"];
+	        this.frameLength = source["frameLength"];
+	        this.blockSize = source["blockSize"];
+	        this.stminUs = source["stminUs"];
+	        this.overrideStmin = source["overrideStmin"];
+	        this.txStminUs = source["txStminUs"];
+	        this.maxWaitFrames = source["maxWaitFrames"];
+	        this.maxMessageSize = source["maxMessageSize"];
+	        this.padding = source["padding"];
+	        this.paddingByte = source["paddingByte"];
+	        this.timeoutMs = source["timeoutMs"];
+	    }
+	}
+	export class IsoTPChannelInfo {
+	    handle: number;
+	    interface: string;
+	    txId: number;
+	    rxId: number;
+	    options: IsoTPOptions;
+	    server: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new IsoTPChannelInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.interface = source["interface"];
+	        this.txId = source["txId"];
+	        this.rxId = source["rxId"];
+	        this.options = this.convertValues(source["options"], IsoTPOptions);
+	        this.server = source["server"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DiagChannel {
+	    handle: number;
+	    isotp?: IsoTPChannelInfo;
+	    doip?: DoIPConnectionInfo;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiagChannel(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.isotp = this.convertValues(source["isotp"], IsoTPChannelInfo);
+	        this.doip = this.convertValues(source["doip"], DoIPConnectionInfo);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DiagDID {
+	    id: number;
+	    name: string;
+	    data: number[];
+	    text?: string;
+	    values?: DIDValue[];
+	    decodeError?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiagDID(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.data = source["data"];
+	        this.text = source["text"];
+	        this.values = this.convertValues(source["values"], DIDValue);
+	        this.decodeError = source["decodeError"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class OBDParameter {
+	    pid: number;
+	    name: string;
+	    unit: string;
+	    value: number;
+	    known: boolean;
+	    raw: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new OBDParameter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pid = source["pid"];
+	        this.name = source["name"];
+	        this.unit = source["unit"];
+	        this.value = source["value"];
+	        this.known = source["known"];
+	        this.raw = source["raw"];
+	    }
+	}
+	export class UDSDTC {
+	    code: number;
+	    name: string;
+	    description?: string;
+	    failureType?: string;
+	    status: number;
+	    statusFlags: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new UDSDTC(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.code = source["code"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.failureType = source["failureType"];
+	        this.status = source["status"];
+	        this.statusFlags = source["statusFlags"];
+	    }
+	}
+	export class DiagExchange {
+	    seq: number;
+	    handle?: number;
+	    interface?: string;
+	    protocol: string;
+	    service: number;
+	    serviceName: string;
+	    request: number[];
+	    response: number[];
+	    // Go type: time
+	    sent: any;
+	    durationMs: number;
+	    pending?: number;
+	    nrc?: number;
+	    nrcName?: string;
+	    error?: string;
+	    did?: DiagDID;
+	    dtcs?: UDSDTC[];
+	    obd?: OBDParameter;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiagExchange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.seq = source["seq"];
+	        this.handle = source["handle"];
+	        this.interface = source["interface"];
+	        this.protocol = source["protocol"];
+	        this.service = source["service"];
+	        this.serviceName = source["serviceName"];
+	        this.request = source["request"];
+	        this.response = source["response"];
+	        this.sent = this.convertValues(source["sent"], null);
+	        this.durationMs = source["durationMs"];
+	        this.pending = source["pending"];
+	        this.nrc = source["nrc"];
+	        this.nrcName = source["nrcName"];
+	        this.error = source["error"];
+	        this.did = this.convertValues(source["did"], DiagDID);
+	        this.dtcs = this.convertValues(source["dtcs"], UDSDTC);
+	        this.obd = this.convertValues(source["obd"], OBDParameter);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DiagRecordingStatus {
+	    active: boolean;
+	    title: string;
+	    // Go type: time
+	    started: any;
+	    exchanges: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiagRecordingStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.active = source["active"];
+	        this.title = source["title"];
+	        this.started = this.convertValues(source["started"], null);
+	        this.exchanges = source["exchanges"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DiagReport {
+	    version: number;
+	    title: string;
+	    // Go type: time
+	    started: any;
+	    // Go type: time
+	    stopped: any;
+	    channels: DiagChannel[];
+	    exchanges: DiagExchange[];
+	    negative: number;
+	    failed: number;
+	    dropped: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiagReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.title = source["title"];
+	        this.started = this.convertValues(source["started"], null);
+	        this.stopped = this.convertValues(source["stopped"], null);
+	        this.channels = this.convertValues(source["channels"], DiagChannel);
+	        this.exchanges = this.convertValues(source["exchanges"], DiagExchange);
+	        this.negative = source["negative"];
+	        this.failed = source["failed"];
+	        this.dropped = source["dropped"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DissectField {
+	    name: string;
+	    value: string;
+	    unit?: string;
+	    children?: DissectField[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DissectField(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.value = source["value"];
+	        this.unit = source["unit"];
+	        this.children = this.convertValues(source["children"], DissectField);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DissectResult {
+	    dissector: string;
+	    fields: DissectField[];
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DissectResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.dissector = source["dissector"];
+	        this.fields = this.convertValues(source["fields"], DissectField);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DissectorRange {
+	    from: number;
+	    to: number;
+	    extended: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new DissectorRange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.extended = source["extended"];
+	    }
+	}
+	export class DissectorInfo {
+	    name: string;
+	    source: string;
+	    ranges: DissectorRange[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DissectorInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.source = source["source"];
+	        this.ranges = this.convertValues(source["ranges"], DissectorRange);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class DoIPEntity {
+	    address: string;
+	    vin: string;
+	    logicalAddress: number;
+	    eid: string;
+	    gid: string;
+	    furtherAction: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DoIPEntity(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.address = source["address"];
+	        this.vin = source["vin"];
+	        this.logicalAddress = source["logicalAddress"];
+	        this.eid = source["eid"];
+	        this.gid = source["gid"];
+	        this.furtherAction = source["furtherAction"];
+	    }
+	}
+	
+	export class E2EProtection {
+	    interface: string;
+	    id: number;
+	    extended: boolean;
+	    profile: string;
+	    dataId: number;
+	    dataIdMode?: string;
+	    dataIdList?: number[];
+	    crcOffset?: number;
+	    counterOffset?: number;
+	    dataIdNibbleOffset?: number;
+	    offset?: number;
+	    maxDeltaCounter: number;
+	    timeoutMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new E2EProtection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.profile = source["profile"];
+	        this.dataId = source["dataId"];
+	        this.dataIdMode = source["dataIdMode"];
+	        this.dataIdList = source["dataIdList"];
+	        this.crcOffset = source["crcOffset"];
+	        this.counterOffset = source["counterOffset"];
+	        this.dataIdNibbleOffset = source["dataIdNibbleOffset"];
+	        this.offset = source["offset"];
+	        this.maxDeltaCounter = source["maxDeltaCounter"];
+	        this.timeoutMs = source["timeoutMs"];
+	    }
+	}
+	export class E2EStatus {
+	    interface: string;
+	    id: number;
+	    extended: boolean;
+	    message?: string;
+	    profile: string;
+	    frames: number;
+	    crcErrors: number;
+	    lost: number;
+	    repeated: number;
+	    wrongSequence: number;
+	    invalid: number;
+	    timeouts: number;
+	    status: string;
+	    // Go type: time
+	    last: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new E2EStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.message = source["message"];
+	        this.profile = source["profile"];
+	        this.frames = source["frames"];
+	        this.crcErrors = source["crcErrors"];
+	        this.lost = source["lost"];
+	        this.repeated = source["repeated"];
+	        this.wrongSequence = source["wrongSequence"];
+	        this.invalid = source["invalid"];
+	        this.timeouts = source["timeouts"];
+	        this.status = source["status"];
+	        this.last = this.convertValues(source["last"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class EDSInfo {
+	    interface: string;
+	    node: number;
+	    file: string;
+	    device: string;
+	    vendor: string;
+	    objects: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new EDSInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.node = source["node"];
+	        this.file = source["file"];
+	        this.device = source["device"];
+	        this.vendor = source["vendor"];
+	        this.objects = source["objects"];
+	    }
+	}
+	export class GPSMotion {
+	    heading?: number;
+	    pitch?: number;
+	    roll?: number;
+	    accX?: number;
+	    accY?: number;
+	    accZ?: number;
+	    gyroX?: number;
+	    gyroY?: number;
+	    gyroZ?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GPSMotion(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.heading = source["heading"];
+	        this.pitch = source["pitch"];
+	        this.roll = source["roll"];
+	        this.accX = source["accX"];
+	        this.accY = source["accY"];
+	        this.accZ = source["accZ"];
+	        this.gyroX = source["gyroX"];
+	        this.gyroY = source["gyroY"];
+	        this.gyroZ = source["gyroZ"];
+	    }
+	}
+	export class GPSPosition {
+	    fix: string;
+	    latitude?: number;
+	    longitude?: number;
+	    altitude?: number;
+	    speed?: number;
+	    course?: number;
+	    satellites: number;
+	    hdop?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GPSPosition(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.fix = source["fix"];
+	        this.latitude = source["latitude"];
+	        this.longitude = source["longitude"];
+	        this.altitude = source["altitude"];
+	        this.speed = source["speed"];
+	        this.course = source["course"];
+	        this.satellites = source["satellites"];
+	        this.hdop = source["hdop"];
+	    }
+	}
+	export class GPSSample {
+	    // Go type: time
+	    timestamp: any;
+	    interface: string;
+	    // Go type: time
+	    gpsTime?: any;
+	    position?: GPSPosition;
+	    motion?: GPSMotion;
+	
+	    static createFrom(source: any = {}) {
+	        return new GPSSample(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.interface = source["interface"];
+	        this.gpsTime = this.convertValues(source["gpsTime"], null);
+	        this.position = this.convertValues(source["position"], GPSPosition);
+	        this.motion = this.convertValues(source["motion"], GPSMotion);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class GPSStatus {
+	    running: boolean;
+	    connected: boolean;
+	    source: string;
+	    interface: string;
+	    reconnects: number;
+	    samples: number;
+	    last?: GPSSample;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GPSStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.running = source["running"];
+	        this.connected = source["connected"];
+	        this.source = source["source"];
+	        this.interface = source["interface"];
+	        this.reconnects = source["reconnects"];
+	        this.samples = source["samples"];
+	        this.last = this.convertValues(source["last"], GPSSample);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class InputBridgeStatus {
+	    running: boolean;
+	    path: string;
+	    interface: string;
+	    lines: number;
+	    sent: number;
+	    errors: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new InputBridgeStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.running = source["running"];
+	        this.path = source["path"];
+	        this.interface = source["interface"];
+	        this.lines = source["lines"];
+	        this.sent = source["sent"];
+	        this.errors = source["errors"];
+	        this.error = source["error"];
+	    }
+	}
+	export class MQTTStatus {
+	    running: boolean;
+	    connected: boolean;
+	    broker: string;
+	    reconnects: number;
+	    published: number;
+	    dropped: number;
+	    injected: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MQTTStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.running = source["running"];
+	        this.connected = source["connected"];
+	        this.broker = source["broker"];
+	        this.reconnects = source["reconnects"];
+	        this.published = source["published"];
+	        this.dropped = source["dropped"];
+	        this.injected = source["injected"];
+	        this.error = source["error"];
+	    }
+	}
+	export class EventClientInfo {
+	    remote: string;
+	    subscriptions: string[];
+	    maxRate: number;
+	    dropOldest: boolean;
+	    queue: number;
+	    queued: number;
+	    sent: number;
+	    dropped: number;
+	    limited: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new EventClientInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.remote = source["remote"];
+	        this.subscriptions = source["subscriptions"];
+	        this.maxRate = source["maxRate"];
+	        this.dropOldest = source["dropOldest"];
+	        this.queue = source["queue"];
+	        this.queued = source["queued"];
+	        this.sent = source["sent"];
+	        this.dropped = source["dropped"];
+	        this.limited = source["limited"];
+	    }
+	}
+	export class EventServerStatus {
+	    running: boolean;
+	    url: string;
+	    clients: EventClientInfo[];
+	
+	    static createFrom(source: any = {}) {
+	        return new EventServerStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.running = source["running"];
+	        this.url = source["url"];
+	        this.clients = this.convertValues(source["clients"], EventClientInfo);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RESTStatus {
+	    running: boolean;
+	    url: string;
+	    requests: number;
+	    frames: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RESTStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.running = source["running"];
+	        this.url = source["url"];
+	        this.requests = source["requests"];
+	        this.frames = source["frames"];
+	    }
+	}
+	export class XCPSessionInfo {
+	    handle: number;
+	    interface: string;
+	    cro: number;
+	    dto: number;
+	    extended: boolean;
+	    calPag: boolean;
+	    daq: boolean;
+	    pgm: boolean;
+	    bigEndian: boolean;
+	    addressGranularity: number;
+	    maxCto: number;
+	    maxDto: number;
+	    protocolVersion: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new XCPSessionInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.interface = source["interface"];
+	        this.cro = source["cro"];
+	        this.dto = source["dto"];
+	        this.extended = source["extended"];
+	        this.calPag = source["calPag"];
+	        this.daq = source["daq"];
+	        this.pgm = source["pgm"];
+	        this.bigEndian = source["bigEndian"];
+	        this.addressGranularity = source["addressGranularity"];
+	        this.maxCto = source["maxCto"];
+	        this.maxDto = source["maxDto"];
+	        this.protocolVersion = source["protocolVersion"];
+	    }
+	}
+	export class ScriptInfo {
+	    handle: number;
+	    name: string;
+	    path: string;
+	    interface: string;
+	    frames: number;
+	    dropped: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScriptInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.name = source["name"];
+	        this.path = source["path"];
+	        this.interface = source["interface"];
+	        this.frames = source["frames"];
+	        this.dropped = source["dropped"];
+	        this.error = source["error"];
+	    }
+	}
+	export class FrameSubscription {
+	    channel: string;
+	    interfaces: string[];
+	    ids: CANFilter[];
+	    groups: string[];
+	    direction: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FrameSubscription(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.channel = source["channel"];
+	        this.interfaces = source["interfaces"];
+	        this.ids = this.convertValues(source["ids"], CANFilter);
+	        this.groups = source["groups"];
+	        this.direction = source["direction"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FrameBatchOptions {
+	    enabled: boolean;
+	    intervalMs: number;
+	    maxFrames: number;
+	    bufferSize: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FrameBatchOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.intervalMs = source["intervalMs"];
+	        this.maxFrames = source["maxFrames"];
+	        this.bufferSize = source["bufferSize"];
+	    }
+	}
+	export class TriggerStatus {
+	    state: string;
+	    buffered: number;
+	    captures: number;
+	    lastFile: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TriggerStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.state = source["state"];
+	        this.buffered = source["buffered"];
+	        this.captures = source["captures"];
+	        this.lastFile = source["lastFile"];
+	        this.error = source["error"];
+	    }
+	}
+	export class SignalRecordingStatus {
+	    active: boolean;
+	    kind: string;
+	    url: string;
+	    written: number;
+	    pending: number;
+	    dropped: number;
+	    retries: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SignalRecordingStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.active = source["active"];
+	        this.kind = source["kind"];
+	        this.url = source["url"];
+	        this.written = source["written"];
+	        this.pending = source["pending"];
+	        this.dropped = source["dropped"];
+	        this.retries = source["retries"];
+	        this.error = source["error"];
+	    }
+	}
+	export class LoggingStatus {
+	    active: boolean;
+	    path: string;
+	    includeTx: boolean;
+	    frames: number;
+	    format: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LoggingStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.active = source["active"];
+	        this.path = source["path"];
+	        this.includeTx = source["includeTx"];
+	        this.frames = source["frames"];
+	        this.format = source["format"];
+	    }
+	}
+	export class ReplayStatus {
+	    state: string;
+	    path: string;
+	    interface: string;
+	    speed: number;
+	    loop: boolean;
+	    position: number;
+	    total: number;
+	    elapsedMs: number;
+	    durationMs: number;
+	    pass: number;
+	    errors: number;
+	    warnings?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ReplayStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.state = source["state"];
+	        this.path = source["path"];
+	        this.interface = source["interface"];
+	        this.speed = source["speed"];
+	        this.loop = source["loop"];
+	        this.position = source["position"];
+	        this.total = source["total"];
+	        this.elapsedMs = source["elapsedMs"];
+	        this.durationMs = source["durationMs"];
+	        this.pass = source["pass"];
+	        this.errors = source["errors"];
+	        this.warnings = source["warnings"];
+	    }
+	}
+	export class ResponderRuleStatus {
+	    name: string;
+	    hits: number;
+	    errors: number;
+	    limit: number;
+	    service?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ResponderRuleStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.hits = source["hits"];
+	        this.errors = source["errors"];
+	        this.limit = source["limit"];
+	        this.service = source["service"];
+	    }
+	}
+	export class ResponderStatus {
+	    profile: string;
+	    path: string;
+	    enabled: boolean;
+	    rules: ResponderRuleStatus[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ResponderStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.profile = source["profile"];
+	        this.path = source["path"];
+	        this.enabled = source["enabled"];
+	        this.rules = this.convertValues(source["rules"], ResponderRuleStatus);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SequenceInfo {
+	    name: string;
+	    description?: string;
+	    interface?: string;
+	    steps: number;
+	    running: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SequenceInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.interface = source["interface"];
+	        this.steps = source["steps"];
+	        this.running = source["running"];
+	    }
+	}
+	export class GapTransmitRule {
+	    name: string;
+	    interface: string;
+	    triggerId: number;
+	    triggerExtended: boolean;
+	    delayUs: number;
+	    txInterface: string;
+	    id: number;
+	    extended: boolean;
+	    fd: boolean;
+	    brs: boolean;
+	    data: number[];
+	    limit: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GapTransmitRule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.interface = source["interface"];
+	        this.triggerId = source["triggerId"];
+	        this.triggerExtended = source["triggerExtended"];
+	        this.delayUs = source["delayUs"];
+	        this.txInterface = source["txInterface"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.fd = source["fd"];
+	        this.brs = source["brs"];
+	        this.data = source["data"];
+	        this.limit = source["limit"];
+	    }
+	}
+	export class GapTransmitInfo {
+	    handle: number;
+	    rule: GapTransmitRule;
+	    sent: number;
+	    missed: number;
+	    errors: number;
+	    maxLateUs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GapTransmitInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.rule = this.convertValues(source["rule"], GapTransmitRule);
+	        this.sent = source["sent"];
+	        this.missed = source["missed"];
+	        this.errors = source["errors"];
+	        this.maxLateUs = source["maxLateUs"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class GeneratorStatus {
+	    handle: number;
+	    interface: string;
+	    mode: string;
+	    running: boolean;
+	    sent: number;
+	    errors: number;
+	    rate: number;
+	    achievedRate: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GeneratorStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.interface = source["interface"];
+	        this.mode = source["mode"];
+	        this.running = source["running"];
+	        this.sent = source["sent"];
+	        this.errors = source["errors"];
+	        this.rate = source["rate"];
+	        this.achievedRate = source["achievedRate"];
+	    }
+	}
+	export class TxQueueStatus {
+	    interface: string;
+	    depth: number;
+	    maxFramesPerSec: number;
+	    pending: number;
+	    sent: number;
+	    failed: number;
+	    overflows: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TxQueueStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.depth = source["depth"];
+	        this.maxFramesPerSec = source["maxFramesPerSec"];
+	        this.pending = source["pending"];
+	        this.sent = source["sent"];
+	        this.failed = source["failed"];
+	        this.overflows = source["overflows"];
+	    }
+	}
+	export class TimestampStatus {
+	    interface: string;
+	    mode: string;
+	    clockOffsetUs: number;
+	    synced: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TimestampStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.mode = source["mode"];
+	        this.clockOffsetUs = source["clockOffsetUs"];
+	        this.synced = source["synced"];
+	    }
+	}
+	export class SocketOptions {
+	    loopback: boolean;
+	    receiveOwn: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SocketOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.loopback = source["loopback"];
+	        this.receiveOwn = source["receiveOwn"];
+	    }
+	}
+	export class SessionState {
+	    interface: string;
+	    options: CANOptions;
+	    autoReconnect: boolean;
+	    reconnecting: boolean;
+	    filters: CANFilter[];
+	    socketOptions: SocketOptions;
+	    timestamp: TimestampStatus;
+	    bus: BusState;
+	    stats: CANStats;
+	    txQueue?: TxQueueStatus;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionState(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.options = this.convertValues(source["options"], CANOptions);
+	        this.autoReconnect = source["autoReconnect"];
+	        this.reconnecting = source["reconnecting"];
+	        this.filters = this.convertValues(source["filters"], CANFilter);
+	        this.socketOptions = this.convertValues(source["socketOptions"], SocketOptions);
+	        this.timestamp = this.convertValues(source["timestamp"], TimestampStatus);
+	        this.bus = this.convertValues(source["bus"], BusState);
+	        this.stats = this.convertValues(source["stats"], CANStats);
+	        this.txQueue = this.convertValues(source["txQueue"], TxQueueStatus);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class EngineState {
+	    // Go type: time
+	    timestamp: any;
+	    sessions: SessionState[];
+	    cyclic: CyclicFrameInfo[];
+	    generators: GeneratorStatus[];
+	    gapTransmits: GapTransmitInfo[];
+	    sequences: SequenceInfo[];
+	    responder: ResponderStatus;
+	    replay: ReplayStatus;
+	    logging: LoggingStatus;
+	    pcap: LoggingStatus;
+	    mdf: LoggingStatus;
+	    signalRecording: SignalRecordingStatus;
+	    trigger: TriggerStatus;
+	    capture: CaptureStatus;
+	    batching: FrameBatchOptions;
+	    subscriptions: FrameSubscription[];
+	    globalFrameChannel: boolean;
+	    processors: string[];
+	    scripts: ScriptInfo[];
+	    dissectors: DissectorInfo[];
+	    isotpChannels: IsoTPChannelInfo[];
+	    xcpSessions: XCPSessionInfo[];
+	    rest: RESTStatus;
+	    eventServer: EventServerStatus;
+	    mqtt: MQTTStatus;
+	    inputBridge: InputBridgeStatus;
+	    gps: GPSStatus;
+	
+	    static createFrom(source: any = {}) {
+	        return new EngineState(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.sessions = this.convertValues(source["sessions"], SessionState);
+	        this.cyclic = this.convertValues(source["cyclic"], CyclicFrameInfo);
+	        this.generators = this.convertValues(source["generators"], GeneratorStatus);
+	        this.gapTransmits = this.convertValues(source["gapTransmits"], GapTransmitInfo);
+	        this.sequences = this.convertValues(source["sequences"], SequenceInfo);
+	        this.responder = this.convertValues(source["responder"], ResponderStatus);
+	        this.replay = this.convertValues(source["replay"], ReplayStatus);
+	        this.logging = this.convertValues(source["logging"], LoggingStatus);
+	        this.pcap = this.convertValues(source["pcap"], LoggingStatus);
+	        this.mdf = this.convertValues(source["mdf"], LoggingStatus);
+	        this.signalRecording = this.convertValues(source["signalRecording"], SignalRecordingStatus);
+	        this.trigger = this.convertValues(source["trigger"], TriggerStatus);
+	        this.capture = this.convertValues(source["capture"], CaptureStatus);
+	        this.batching = this.convertValues(source["batching"], FrameBatchOptions);
+	        this.subscriptions = this.convertValues(source["subscriptions"], FrameSubscription);
+	        this.globalFrameChannel = source["globalFrameChannel"];
+	        this.processors = source["processors"];
+	        this.scripts = this.convertValues(source["scripts"], ScriptInfo);
+	        this.dissectors = this.convertValues(source["dissectors"], DissectorInfo);
+	        this.isotpChannels = this.convertValues(source["isotpChannels"], IsoTPChannelInfo);
+	        this.xcpSessions = this.convertValues(source["xcpSessions"], XCPSessionInfo);
+	        this.rest = this.convertValues(source["rest"], RESTStatus);
+	        this.eventServer = this.convertValues(source["eventServer"], EventServerStatus);
+	        this.mqtt = this.convertValues(source["mqtt"], MQTTStatus);
+	        this.inputBridge = this.convertValues(source["inputBridge"], InputBridgeStatus);
+	        this.gps = this.convertValues(source["gps"], GPSStatus);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class EventServerOptions {
+	    address: string;
+	    port: number;
+	    token: string;
+	    origins: string[];
+	    hosts: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new EventServerOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.address = source["address"];
+	        this.port = source["port"];
+	        this.token = source["token"];
+	        this.origins = source["origins"];
+	        this.hosts = source["hosts"];
+	    }
+	}
+	
+	export class FaultCapability {
+	    fault: string;
+	    supported: boolean;
+	    reason?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FaultCapability(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.fault = source["fault"];
+	        this.supported = source["supported"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class FaultCapabilities {
+	    interface: string;
+	    kind: string;
+	    driver: string;
+	    faults: FaultCapability[];
+	    errorKinds: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FaultCapabilities(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.kind = source["kind"];
+	        this.driver = source["driver"];
+	        this.faults = this.convertValues(source["faults"], FaultCapability);
+	        this.errorKinds = source["errorKinds"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class FaultResult {
+	    interface: string;
+	    bitrate: number;
+	    sent: number;
+	    failed: number;
+	    state: string;
+	    txErrors: number;
+	    rxErrors: number;
+	    hasCounters: boolean;
+	    busOff: boolean;
+	    durationMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FaultResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.bitrate = source["bitrate"];
+	        this.sent = source["sent"];
+	        this.failed = source["failed"];
+	        this.state = source["state"];
+	        this.txErrors = source["txErrors"];
+	        this.rxErrors = source["rxErrors"];
+	        this.hasCounters = source["hasCounters"];
+	        this.busOff = source["busOff"];
+	        this.durationMs = source["durationMs"];
+	    }
+	}
+	export class FlashOptions {
+	    path: string;
+	    address: number;
+	    session: number;
+	    securityLevel: number;
+	    eraseRoutine: number;
+	    checkRoutine: number;
+	    retries: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FlashOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.address = source["address"];
+	        this.session = source["session"];
+	        this.securityLevel = source["securityLevel"];
+	        this.eraseRoutine = source["eraseRoutine"];
+	        this.checkRoutine = source["checkRoutine"];
+	        this.retries = source["retries"];
+	    }
+	}
+	export class FlashProgress {
+	    handle: number;
+	    path: string;
+	    state: string;
+	    stage: string;
+	    sent: number;
+	    total: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FlashProgress(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.path = source["path"];
+	        this.state = source["state"];
+	        this.stage = source["stage"];
+	        this.sent = source["sent"];
+	        this.total = source["total"];
+	        this.error = source["error"];
+	    }
+	}
+	
+	export class IDRange {
+	    from: number;
+	    to: number;
+	    extended: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new IDRange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.extended = source["extended"];
+	    }
+	}
+	export class FrameGroup {
+	    name: string;
+	    color: string;
+	    ranges: IDRange[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FrameGroup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.color = source["color"];
+	        this.ranges = this.convertValues(source["ranges"], IDRange);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class FuzzConfig {
+	    interface: string;
+	    targets: fuzz.Target[];
+	    strategies: string[];
+	    fd: boolean;
+	    brs: boolean;
+	    maxBitFlips: number;
+	    seed: number;
+	    rate: number;
+	    count: number;
+	    history: number;
+	    stopOnFinding: boolean;
+	    errorFrames: boolean;
+	    watchId: number;
+	    watchExtended: boolean;
+	    silenceMs: number;
+	    udsHandle: number;
+	    dtcStatusMask: number;
+	    dtcPollMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FuzzConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.targets = this.convertValues(source["targets"], fuzz.Target);
+	        this.strategies = source["strategies"];
+	        this.fd = source["fd"];
+	        this.brs = source["brs"];
+	        this.maxBitFlips = source["maxBitFlips"];
+	        this.seed = source["seed"];
+	        this.rate = source["rate"];
+	        this.count = source["count"];
+	        this.history = source["history"];
+	        this.stopOnFinding = source["stopOnFinding"];
+	        this.errorFrames = source["errorFrames"];
+	        this.watchId = source["watchId"];
+	        this.watchExtended = source["watchExtended"];
+	        this.silenceMs = source["silenceMs"];
+	        this.udsHandle = source["udsHandle"];
+	        this.dtcStatusMask = source["dtcStatusMask"];
+	        this.dtcPollMs = source["dtcPollMs"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FuzzFrame {
+	    seq: number;
+	    // Go type: time
+	    time: any;
+	    id: number;
+	    extended: boolean;
+	    fd: boolean;
+	    brs: boolean;
+	    data: number[];
+	    strategy: string;
+	    detail: string;
+	    candump: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FuzzFrame(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.seq = source["seq"];
+	        this.time = this.convertValues(source["time"], null);
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.fd = source["fd"];
+	        this.brs = source["brs"];
+	        this.data = source["data"];
+	        this.strategy = source["strategy"];
+	        this.detail = source["detail"];
+	        this.candump = source["candump"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FuzzFinding {
+	    handle: number;
+	    interface: string;
+	    kind: string;
+	    // Go type: time
+	    time: any;
+	    detail: string;
+	    seed: number;
+	    frames: FuzzFrame[];
+	    dtcs?: UDSDTC[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FuzzFinding(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.interface = source["interface"];
+	        this.kind = source["kind"];
+	        this.time = this.convertValues(source["time"], null);
+	        this.detail = source["detail"];
+	        this.seed = source["seed"];
+	        this.frames = this.convertValues(source["frames"], FuzzFrame);
+	        this.dtcs = this.convertValues(source["dtcs"], UDSDTC);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class FuzzStatus {
+	    handle: number;
+	    interface: string;
+	    running: boolean;
+	    seed: number;
+	    sent: number;
+	    errors: number;
+	    findings: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FuzzStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.interface = source["interface"];
+	        this.running = source["running"];
+	        this.seed = source["seed"];
+	        this.sent = source["sent"];
+	        this.errors = source["errors"];
+	        this.findings = source["findings"];
+	    }
+	}
+	
+	export class GPSOptions {
+	    source: string;
+	    interface: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new GPSOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = source["source"];
+	        this.interface = source["interface"];
+	    }
+	}
+	
+	
+	
+	
+	
+	export class GatewayLatency {
+	    sourceInterface: string;
+	    destinationInterface: string;
+	    sourceId: number;
+	    destinationId: number;
+	    pairs: number;
+	    unmatchedSource: number;
+	    unmatchedDestination: number;
+	    minUs: number;
+	    meanUs: number;
+	    p50Us: number;
+	    p99Us: number;
+	    maxUs: number;
+	    jitterUs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GatewayLatency(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sourceInterface = source["sourceInterface"];
+	        this.destinationInterface = source["destinationInterface"];
+	        this.sourceId = source["sourceId"];
+	        this.destinationId = source["destinationId"];
+	        this.pairs = source["pairs"];
+	        this.unmatchedSource = source["unmatchedSource"];
+	        this.unmatchedDestination = source["unmatchedDestination"];
+	        this.minUs = source["minUs"];
+	        this.meanUs = source["meanUs"];
+	        this.p50Us = source["p50Us"];
+	        this.p99Us = source["p99Us"];
+	        this.maxUs = source["maxUs"];
+	        this.jitterUs = source["jitterUs"];
+	    }
+	}
+	export class GeneratorConfig {
+	    interface: string;
+	    mode: string;
+	    extended: boolean;
+	    fd: boolean;
+	    brs: boolean;
+	    id: number;
+	    minId: number;
+	    maxId: number;
+	    length: number;
+	    randomLength: boolean;
+	    data: number[];
+	    counterOffset: number;
+	    counterBytes: number;
+	    seed: number;
+	    rate: number;
+	    count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GeneratorConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.mode = source["mode"];
+	        this.extended = source["extended"];
+	        this.fd = source["fd"];
+	        this.brs = source["brs"];
+	        this.id = source["id"];
+	        this.minId = source["minId"];
+	        this.maxId = source["maxId"];
+	        this.length = source["length"];
+	        this.randomLength = source["randomLength"];
+	        this.data = source["data"];
+	        this.counterOffset = source["counterOffset"];
+	        this.counterBytes = source["counterBytes"];
+	        this.seed = source["seed"];
+	        this.rate = source["rate"];
+	        this.count = source["count"];
+	    }
+	}
+	
+	export class IDConflictEvent {
+	    // Go type: time
+	    timestamp: any;
+	    interface: string;
+	    id: number;
+	    extended: boolean;
+	    handle: number;
+	    count: number;
+	    // Go type: time
+	    firstSeen: any;
+	    data: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new IDConflictEvent(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.interface = source["interface"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.handle = source["handle"];
+	        this.count = source["count"];
+	        this.firstSeen = this.convertValues(source["firstSeen"], null);
+	        this.data = source["data"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class InputBridgeOptions {
+	    path: string;
+	    interface: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new InputBridgeOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.interface = source["interface"];
+	    }
+	}
+	
+	
+	
+	export class JournalEntry {
+	    // Go type: time
+	    time: any;
+	    action: string;
+	    interface?: string;
+	    options?: CANOptions;
+	    id?: number;
+	    extended?: boolean;
+	    fd?: boolean;
+	    brs?: boolean;
+	    data?: number[];
+	    filters?: CANFilter[];
+	    periodMs?: number;
+	    handle?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new JournalEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = this.convertValues(source["time"], null);
+	        this.action = source["action"];
+	        this.interface = source["interface"];
+	        this.options = this.convertValues(source["options"], CANOptions);
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.fd = source["fd"];
+	        this.brs = source["brs"];
+	        this.data = source["data"];
+	        this.filters = this.convertValues(source["filters"], CANFilter);
+	        this.periodMs = source["periodMs"];
+	        this.handle = source["handle"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LDFInfo {
+	    path: string;
+	    protocolVersion: string;
+	    bitrate: number;
+	    master: string;
+	    slaves: string[];
+	    frames: number;
+	    signals: number;
+	    scheduleTables: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LDFInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.protocolVersion = source["protocolVersion"];
+	        this.bitrate = source["bitrate"];
+	        this.master = source["master"];
+	        this.slaves = source["slaves"];
+	        this.frames = source["frames"];
+	        this.signals = source["signals"];
+	        this.scheduleTables = source["scheduleTables"];
+	    }
+	}
+	export class LINScheduleStatus {
+	    interface: string;
+	    table: string;
+	    cycles: number;
+	    errors: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LINScheduleStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.table = source["table"];
+	        this.cycles = source["cycles"];
+	        this.errors = source["errors"];
+	    }
+	}
+	export class LatencyBucket {
+	    upToUs: number;
+	    count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LatencyBucket(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.upToUs = source["upToUs"];
+	        this.count = source["count"];
+	    }
+	}
+	
+	export class MQTTOptions {
+	    broker: string;
+	    clientId: string;
+	    username: string;
+	    password: string;
+	    interfaces: string[];
+	    publishFrames: boolean;
+	    frameTopic: string;
+	    publishSignals: boolean;
+	    signalTopic: string;
+	    retain: boolean;
+	    injectTopic: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MQTTOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.broker = source["broker"];
+	        this.clientId = source["clientId"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.interfaces = source["interfaces"];
+	        this.publishFrames = source["publishFrames"];
+	        this.frameTopic = source["frameTopic"];
+	        this.publishSignals = source["publishSignals"];
+	        this.signalTopic = source["signalTopic"];
+	        this.retain = source["retain"];
+	        this.injectTopic = source["injectTopic"];
+	    }
+	}
+	
+	export class MessageTiming {
+	    id: number;
+	    extended: boolean;
+	    class: string;
+	    count: number;
+	    periodMs: number;
+	    jitterMs: number;
+	    minIntervalMs: number;
+	    maxIntervalMs: number;
+	    late: number;
+	    missed: number;
+	    timeouts: number;
+	    // Go type: time
+	    last: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new MessageTiming(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.class = source["class"];
+	        this.count = source["count"];
+	        this.periodMs = source["periodMs"];
+	        this.jitterMs = source["jitterMs"];
+	        this.minIntervalMs = source["minIntervalMs"];
+	        this.maxIntervalMs = source["maxIntervalMs"];
+	        this.late = source["late"];
+	        this.missed = source["missed"];
+	        this.timeouts = source["timeouts"];
+	        this.last = this.convertValues(source["last"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class NMNodeInfo {
+	    node: number;
+	    state: string;
+	    // Go type: time
+	    awakeSince: any;
+	    // Go type: time
+	    lastSeen: any;
+	    messages: number;
+	    wakeups: number;
+	    flags: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new NMNodeInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.node = source["node"];
+	        this.state = source["state"];
+	        this.awakeSince = this.convertValues(source["awakeSince"], null);
+	        this.lastSeen = this.convertValues(source["lastSeen"], null);
+	        this.messages = source["messages"];
+	        this.wakeups = source["wakeups"];
+	        this.flags = source["flags"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class NMOptions {
+	    protocol: string;
+	    from: number;
+	    to: number;
+	    extended: boolean;
+	    nodeIdPosition: string;
+	    cbvPosition: string;
+	    timeoutMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new NMOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.protocol = source["protocol"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.extended = source["extended"];
+	        this.nodeIdPosition = source["nodeIdPosition"];
+	        this.cbvPosition = source["cbvPosition"];
+	        this.timeoutMs = source["timeoutMs"];
+	    }
+	}
+	export class OBDDTC {
+	    code: number;
+	    name: string;
+	    description?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new OBDDTC(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.code = source["code"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	    }
+	}
+	export class OBDFreezeFrame {
+	    handle: number;
+	    frame: number;
+	    dtc: string;
+	    description?: string;
+	    parameters: OBDParameter[];
+	
+	    static createFrom(source: any = {}) {
+	        return new OBDFreezeFrame(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.frame = source["frame"];
+	        this.dtc = source["dtc"];
+	        this.description = source["description"];
+	        this.parameters = this.convertValues(source["parameters"], OBDParameter);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class OBDMonitorTest {
+	    mid: number;
+	    monitor: string;
+	    tid: number;
+	    test: string;
+	    uasid: number;
+	    unit: string;
+	    value: number;
+	    min: number;
+	    max: number;
+	    passed: boolean;
+	    scaled: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new OBDMonitorTest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mid = source["mid"];
+	        this.monitor = source["monitor"];
+	        this.tid = source["tid"];
+	        this.test = source["test"];
+	        this.uasid = source["uasid"];
+	        this.unit = source["unit"];
+	        this.value = source["value"];
+	        this.min = source["min"];
+	        this.max = source["max"];
+	        this.passed = source["passed"];
+	        this.scaled = source["scaled"];
+	    }
+	}
+	export class OBDPIDEvent {
+	    // Go type: time
+	    timestamp: any;
+	    interface: string;
+	    ecu: number;
+	    pid: number;
+	    name: string;
+	    unit: string;
+	    value: number;
+	    known: boolean;
+	    raw: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new OBDPIDEvent(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.interface = source["interface"];
+	        this.ecu = source["ecu"];
+	        this.pid = source["pid"];
+	        this.name = source["name"];
+	        this.unit = source["unit"];
+	        this.value = source["value"];
+	        this.known = source["known"];
+	        this.raw = source["raw"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class OBDPIDInfo {
+	    pid: number;
+	    name: string;
+	    unit: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new OBDPIDInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pid = source["pid"];
+	        this.name = source["name"];
+	        this.unit = source["unit"];
+	    }
+	}
+	
+	export class OBDTestResults {
+	    handle: number;
+	    tests: OBDMonitorTest[];
+	
+	    static createFrom(source: any = {}) {
+	        return new OBDTestResults(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.tests = this.convertValues(source["tests"], OBDMonitorTest);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class OBDVehicleInfo {
+	    handle: number;
+	    vin: string;
+	    calibrationIds: string[];
+	    cvns: string[];
+	    ecuName: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new OBDVehicleInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.vin = source["vin"];
+	        this.calibrationIds = source["calibrationIds"];
+	        this.cvns = source["cvns"];
+	        this.ecuName = source["ecuName"];
+	    }
+	}
+	export class ODXParamInfo {
+	    name: string;
+	    type: string;
+	    bitLength: number;
+	    unit?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ODXParamInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	        this.bitLength = source["bitLength"];
+	        this.unit = source["unit"];
+	    }
+	}
+	export class ODXDIDInfo {
+	    id: number;
+	    name: string;
+	    description?: string;
+	    layer: string;
+	    params: ODXParamInfo[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ODXDIDInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.layer = source["layer"];
+	        this.params = this.convertValues(source["params"], ODXParamInfo);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ODXSegment {
+	    name: string;
+	    address: number;
+	    size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ODXSegment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.address = source["address"];
+	        this.size = source["size"];
+	    }
+	}
+	export class ODXDataBlock {
+	    name: string;
+	    segments: ODXSegment[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ODXDataBlock(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.segments = this.convertValues(source["segments"], ODXSegment);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ODXFlashSession {
+	    name: string;
+	    dataBlocks: ODXDataBlock[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ODXFlashSession(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.dataBlocks = this.convertValues(source["dataBlocks"], ODXDataBlock);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ODXInfo {
+	    path: string;
+	    layers: string[];
+	    dids: number;
+	    flashSessions: ODXFlashSession[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ODXInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.layers = source["layers"];
+	        this.dids = source["dids"];
+	        this.flashSessions = this.convertValues(source["flashSessions"], ODXFlashSession);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class OverviewEntry {
+	    interface: string;
+	    id: number;
+	    extended: boolean;
+	    fd: boolean;
+	    remote: boolean;
+	    data: number[];
+	    count: number;
+	    // Go type: time
+	    last: any;
+	    periodMs: number;
+	    changed: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new OverviewEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.fd = source["fd"];
+	        this.remote = source["remote"];
+	        this.data = source["data"];
+	        this.count = source["count"];
+	        this.last = this.convertValues(source["last"], null);
+	        this.periodMs = source["periodMs"];
+	        this.changed = source["changed"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class OverviewOptions {
+	    enabled: boolean;
+	    intervalMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new OverviewOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.intervalMs = source["intervalMs"];
+	    }
+	}
+	export class PlotPoint {
+	    timeMs: number;
+	    min: number;
+	    max: number;
+	    avg: number;
+	    count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlotPoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timeMs = source["timeMs"];
+	        this.min = source["min"];
+	        this.max = source["max"];
+	        this.avg = source["avg"];
+	        this.count = source["count"];
+	    }
+	}
+	export class PlotSignal {
+	    interface: string;
+	    message: string;
+	    signal: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlotSignal(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.message = source["message"];
+	        this.signal = source["signal"];
+	    }
+	}
+	export class PlotQuery {
+	    signals: PlotSignal[];
+	    fromMs: number;
+	    toMs: number;
+	    maxPoints: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlotQuery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.signals = this.convertValues(source["signals"], PlotSignal);
+	        this.fromMs = source["fromMs"];
+	        this.toMs = source["toMs"];
+	        this.maxPoints = source["maxPoints"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PlotSeries {
+	    interface: string;
+	    message: string;
+	    signal: string;
+	    unit: string;
+	    bucketMs: number;
+	    points: PlotPoint[];
+	
+	    static createFrom(source: any = {}) {
+	        return new PlotSeries(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.message = source["message"];
+	        this.signal = source["signal"];
+	        this.unit = source["unit"];
+	        this.bucketMs = source["bucketMs"];
+	        this.points = this.convertValues(source["points"], PlotPoint);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PlotSeriesInfo {
+	    interface: string;
+	    message: string;
+	    signal: string;
+	    unit: string;
+	    firstMs: number;
+	    lastMs: number;
+	    samples: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PlotSeriesInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.message = source["message"];
+	        this.signal = source["signal"];
+	        this.unit = source["unit"];
+	        this.firstMs = source["firstMs"];
+	        this.lastMs = source["lastMs"];
+	        this.samples = source["samples"];
+	    }
+	}
+	
+	export class ProfileCyclicFrame {
+	    interface: string;
+	    id: number;
+	    extended: boolean;
+	    data: number[];
+	    periodMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProfileCyclicFrame(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.data = source["data"];
+	        this.periodMs = source["periodMs"];
+	    }
+	}
+	export class ProfileInfo {
+	    name: string;
+	    path: string;
+	    // Go type: time
+	    savedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProfileInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.path = source["path"];
+	        this.savedAt = this.convertValues(source["savedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProfileInterface {
+	    name: string;
+	    fd: boolean;
+	    readOnly: boolean;
+	    bitrate: number;
+	    dataBitrate: number;
+	    samplePoint: number;
+	    filters: CANFilter[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProfileInterface(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.fd = source["fd"];
+	        this.readOnly = source["readOnly"];
+	        this.bitrate = source["bitrate"];
+	        this.dataBitrate = source["dataBitrate"];
+	        this.samplePoint = source["samplePoint"];
+	        this.filters = this.convertValues(source["filters"], CANFilter);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TXPanelItem {
+	    label: string;
+	    interface: string;
+	    message: string;
+	    signals: Record<string, number>;
+	    id: number;
+	    extended: boolean;
+	    fd: boolean;
+	    data: number[];
+	    cycleMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TXPanelItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.label = source["label"];
+	        this.interface = source["interface"];
+	        this.message = source["message"];
+	        this.signals = source["signals"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.fd = source["fd"];
+	        this.data = source["data"];
+	        this.cycleMs = source["cycleMs"];
+	    }
+	}
+	export class TXPanel {
+	    name: string;
+	    items: TXPanelItem[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TXPanel(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.items = this.convertValues(source["items"], TXPanelItem);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TXChecksum {
+	    byte: number;
+	    algorithm: string;
+	    seed?: number;
+	    from: number;
+	    to: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TXChecksum(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.byte = source["byte"];
+	        this.algorithm = source["algorithm"];
+	        this.seed = source["seed"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	    }
+	}
+	export class TXCounter {
+	    byte: number;
+	    mask: number;
+	    min: number;
+	    max: number;
+	    step: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TXCounter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.byte = source["byte"];
+	        this.mask = source["mask"];
+	        this.min = source["min"];
+	        this.max = source["max"];
+	        this.step = source["step"];
+	    }
+	}
+	export class TXProcessor {
+	    id: number;
+	    extended: boolean;
+	    counter?: TXCounter;
+	    checksum?: TXChecksum;
+	
+	    static createFrom(source: any = {}) {
+	        return new TXProcessor(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.counter = this.convertValues(source["counter"], TXCounter);
+	        this.checksum = this.convertValues(source["checksum"], TXChecksum);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SessionProfile {
+	    name: string;
+	    // Go type: time
+	    savedAt: any;
+	    interfaces: ProfileInterface[];
+	    dbcs: string[];
+	    cyclicFrames: ProfileCyclicFrame[];
+	    txProcessors: TXProcessor[];
+	    responder: string;
+	    groups: FrameGroup[];
+	    txPanels: TXPanel[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionProfile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.savedAt = this.convertValues(source["savedAt"], null);
+	        this.interfaces = this.convertValues(source["interfaces"], ProfileInterface);
+	        this.dbcs = source["dbcs"];
+	        this.cyclicFrames = this.convertValues(source["cyclicFrames"], ProfileCyclicFrame);
+	        this.txProcessors = this.convertValues(source["txProcessors"], TXProcessor);
+	        this.responder = source["responder"];
+	        this.groups = this.convertValues(source["groups"], FrameGroup);
+	        this.txPanels = this.convertValues(source["txPanels"], TXPanel);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProfileLoadResult {
+	    profile: SessionProfile;
+	    warnings: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ProfileLoadResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.profile = this.convertValues(source["profile"], SessionProfile);
+	        this.warnings = source["warnings"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RESTOptions {
+	    address: string;
+	    port: number;
+	    token: string;
+	    origins: string[];
+	    hosts: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RESTOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.address = source["address"];
+	        this.port = source["port"];
+	        this.token = source["token"];
+	        this.origins = source["origins"];
+	        this.hosts = source["hosts"];
+	    }
+	}
+	
+	export class RemoteStatus {
+	    interface: string;
+	    connected: boolean;
+	    latencyMs: number;
+	    reconnects: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RemoteStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.connected = source["connected"];
+	        this.latencyMs = source["latencyMs"];
+	        this.reconnects = source["reconnects"];
+	        this.error = source["error"];
+	    }
+	}
+	
+	
+	
+	
+	export class SelfTestResult {
+	    sender: string;
+	    receiver: string;
+	    sent: number;
+	    received: number;
+	    lost: number;
+	    duplicates: number;
+	    outOfOrder: number;
+	    durationMs: number;
+	    txFramesPerSecond: number;
+	    rxFramesPerSecond: number;
+	    latencyMinUs: number;
+	    latencyMeanUs: number;
+	    latencyP50Us: number;
+	    latencyP90Us: number;
+	    latencyP99Us: number;
+	    latencyMaxUs: number;
+	    histogram: LatencyBucket[];
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SelfTestResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sender = source["sender"];
+	        this.receiver = source["receiver"];
+	        this.sent = source["sent"];
+	        this.received = source["received"];
+	        this.lost = source["lost"];
+	        this.duplicates = source["duplicates"];
+	        this.outOfOrder = source["outOfOrder"];
+	        this.durationMs = source["durationMs"];
+	        this.txFramesPerSecond = source["txFramesPerSecond"];
+	        this.rxFramesPerSecond = source["rxFramesPerSecond"];
+	        this.latencyMinUs = source["latencyMinUs"];
+	        this.latencyMeanUs = source["latencyMeanUs"];
+	        this.latencyP50Us = source["latencyP50Us"];
+	        this.latencyP90Us = source["latencyP90Us"];
+	        this.latencyP99Us = source["latencyP99Us"];
+	        this.latencyMaxUs = source["latencyMaxUs"];
+	        this.histogram = this.convertValues(source["histogram"], LatencyBucket);
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SendOptions {
+	    fd: boolean;
+	    brs: boolean;
+	    validateOnly: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SendOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.fd = source["fd"];
+	        this.brs = source["brs"];
+	        this.validateOnly = source["validateOnly"];
+	    }
+	}
+	
+	export class SequenceOptions {
+	    validateOnly: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SequenceOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.validateOnly = source["validateOnly"];
+	    }
+	}
+	
+	export class SessionReplayResult {
+	    actions: number;
+	    failures: string[];
+	    stopped: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionReplayResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.actions = source["actions"];
+	        this.failures = source["failures"];
+	        this.stopped = source["stopped"];
+	    }
+	}
+	
+	export class SignalRecordingOptions {
+	    kind: string;
+	    url: string;
+	    org: string;
+	    bucket: string;
+	    token: string;
+	    database: string;
+	    username: string;
+	    password: string;
+	    measurement: string;
+	    table: string;
+	    timescale: boolean;
+	    interfaces: string[];
+	    batchSize: number;
+	    flushIntervalMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SignalRecordingOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.url = source["url"];
+	        this.org = source["org"];
+	        this.bucket = source["bucket"];
+	        this.token = source["token"];
+	        this.database = source["database"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.measurement = source["measurement"];
+	        this.table = source["table"];
+	        this.timescale = source["timescale"];
+	        this.interfaces = source["interfaces"];
+	        this.batchSize = source["batchSize"];
+	        this.flushIntervalMs = source["flushIntervalMs"];
+	    }
+	}
+	
+	
+	
+	
+	
+	
+	
+	export class TXWarning {
+	    step?: number;
+	    interface: string;
+	    id: number;
+	    extended: boolean;
+	    kind: string;
+	    message?: string;
+	    signal?: string;
+	    value: number;
+	    min: number;
+	    max: number;
+	    text: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TXWarning(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.step = source["step"];
+	        this.interface = source["interface"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.kind = source["kind"];
+	        this.message = source["message"];
+	        this.signal = source["signal"];
+	        this.value = source["value"];
+	        this.min = source["min"];
+	        this.max = source["max"];
+	        this.text = source["text"];
+	    }
+	}
+	export class TimeRange {
+	    startMs: number;
+	    endMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TimeRange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.startMs = source["startMs"];
+	        this.endMs = source["endMs"];
+	    }
+	}
+	
+	export class TransportInfo {
+	    name: string;
+	    prefix: string;
+	    example: string;
+	    fd: boolean;
+	    lin: boolean;
+	    available: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TransportInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.prefix = source["prefix"];
+	        this.example = source["example"];
+	        this.fd = source["fd"];
+	        this.lin = source["lin"];
+	        this.available = source["available"];
+	    }
+	}
+	export class TriggerOptions {
+	    interface: string;
+	    errorFrame: boolean;
+	    ids: CANFilter[];
+	    data: string;
+	    preTriggerMs: number;
+	    postTriggerMs: number;
+	    path: string;
+	    includeTx: boolean;
+	    rearm: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TriggerOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.errorFrame = source["errorFrame"];
+	        this.ids = this.convertValues(source["ids"], CANFilter);
+	        this.data = source["data"];
+	        this.preTriggerMs = source["preTriggerMs"];
+	        this.postTriggerMs = source["postTriggerMs"];
+	        this.path = source["path"];
+	        this.includeTx = source["includeTx"];
+	        this.rearm = source["rearm"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class TxHistoryEntry {
+	    index: number;
+	    // Go type: time
+	    timestamp: any;
+	    interface: string;
+	    id: number;
+	    extended: boolean;
+	    fd: boolean;
+	    brs: boolean;
+	    data: number[];
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TxHistoryEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.interface = source["interface"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.fd = source["fd"];
+	        this.brs = source["brs"];
+	        this.data = source["data"];
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class TxTemplate {
+	    message: string;
+	    id: number;
+	    extended: boolean;
+	    data: number[];
+	    signals: candb.Value[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TxTemplate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.message = source["message"];
+	        this.id = source["id"];
+	        this.extended = source["extended"];
+	        this.data = source["data"];
+	        this.signals = this.convertValues(source["signals"], candb.Value);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class UDSKeepAliveOptions {
+	    enabled: boolean;
+	    periodMs: number;
+	    respond: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new UDSKeepAliveOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.periodMs = source["periodMs"];
+	        this.respond = source["respond"];
+	    }
+	}
+	export class UDSKeepAliveStatus {
+	    handle: number;
+	    options: UDSKeepAliveOptions;
+	    active: boolean;
+	    session: number;
+	    sent: number;
+	    errors: number;
+	    lastError?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new UDSKeepAliveStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.options = this.convertValues(source["options"], UDSKeepAliveOptions);
+	        this.active = source["active"];
+	        this.session = source["session"];
+	        this.sent = source["sent"];
+	        this.errors = source["errors"];
+	        this.lastError = source["lastError"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UDSMemoryDump {
+	    handle: number;
+	    address: number;
+	    write: boolean;
+	    data: number[];
+	    hexdump: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new UDSMemoryDump(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.handle = source["handle"];
+	        this.address = source["address"];
+	        this.write = source["write"];
+	        this.data = source["data"];
+	        this.hexdump = source["hexdump"];
+	    }
+	}
+	export class UDSMemoryFormat {
+	    addressBytes: number;
+	    sizeBytes: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new UDSMemoryFormat(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.addressBytes = source["addressBytes"];
+	        this.sizeBytes = source["sizeBytes"];
+	    }
+	}
+	export class UDSResponse {
+	    service: number;
+	    data: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new UDSResponse(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.service = source["service"];
+	        this.data = source["data"];
+	    }
+	}
+	export class UDSSessionTiming {
+	    p2Ms: number;
+	    p2StarMs: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new UDSSessionTiming(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.p2Ms = source["p2Ms"];
+	        this.p2StarMs = source["p2StarMs"];
+	    }
+	}
+	
+	export class XCPDAQEntry {
+	    name: string;
+	    address: number;
+	    extension: number;
+	    size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new XCPDAQEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.address = source["address"];
+	        this.extension = source["extension"];
+	        this.size = source["size"];
+	    }
+	}
+	export class XCPDAQList {
+	    event: number;
+	    prescaler: number;
+	    priority: number;
+	    entries: XCPDAQEntry[];
+	
+	    static createFrom(source: any = {}) {
+	        return new XCPDAQList(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.event = source["event"];
+	        this.prescaler = source["prescaler"];
+	        this.priority = source["priority"];
+	        this.entries = this.convertValues(source["entries"], XCPDAQEntry);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
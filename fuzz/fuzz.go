@@ -0,0 +1,199 @@
+// Package fuzz mutates the payloads of known CAN frames to test how the ECUs
+// receiving them cope with malformed input.
+package fuzz
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"canproject/canbus"
+)
+
+// Strategies of a Config.
+const (
+	// StrategyBitFlip flips one to MaxBitFlips random bits of the payload.
+	StrategyBitFlip = "bitflip"
+	// StrategyBoundary sets a random byte, or a random 16 or 32 bit big endian
+	// field, to a boundary value: the lowest, the highest, the sign limits or
+	// one off them.
+	StrategyBoundary = "boundary"
+	// StrategyLength sends the payload with another length, any of the CAN FD
+	// lengths for the FD frames, padding with zeros.
+	StrategyLength = "length"
+	// StrategyRandom replaces the payload with random bytes of the same length.
+	StrategyRandom = "random"
+)
+
+// Strategies lists the strategies in the order they are described.
+var Strategies = []string{StrategyBitFlip, StrategyBoundary, StrategyLength, StrategyRandom}
+
+// Target is a frame to fuzz: the mutations start from its payload each time.
+type Target struct {
+	ID       uint32 `json:"id"`
+	Extended bool   `json:"extended"`
+	Data     []byte `json:"data"`
+}
+
+// Config describes the mutated frames.
+type Config struct {
+	Targets []Target `json:"targets"`
+	// Strategies are the mutations picked at random for each frame, all of
+	// them when empty.
+	Strategies []string `json:"strategies"`
+	// FD sends CAN FD frames, BRS with the bit rate switch.
+	FD  bool `json:"fd"`
+	BRS bool `json:"brs"`
+	// MaxBitFlips bounds the bits flipped by StrategyBitFlip, 0 for 1.
+	MaxBitFlips int `json:"maxBitFlips"`
+	// Seed seeds the mutations, so a run can be reproduced. 0 picks a random seed.
+	Seed int64 `json:"seed"`
+}
+
+// Mutation is a frame produced by a Fuzzer.
+type Mutation struct {
+	Frame canbus.Frame
+	// Target is the index of the target mutated.
+	Target   int
+	Strategy string
+	// Detail describes the mutation, eg "flipped bits 3, 17".
+	Detail string
+}
+
+// Fuzzer returns the mutations of a Config. It is not safe for concurrent use.
+type Fuzzer struct {
+	cfg  Config
+	max  int
+	seed int64
+	rng  *rand.Rand
+}
+
+// New validates cfg and returns its fuzzer.
+func New(cfg Config) (*Fuzzer, error) {
+	if len(cfg.Targets) == 0 {
+		return nil, errors.New("no frame to fuzz")
+	}
+	max := canbus.MaxDataLength
+	if cfg.FD {
+		max = canbus.MaxFDDataLength
+	}
+	for i, t := range cfg.Targets {
+		if len(t.Data) > max {
+			return nil, fmt.Errorf("target %d: the data is longer than %d bytes", i, max)
+		}
+		if err := (&canbus.Frame{ID: t.ID, IsExtended: t.Extended}).Validate(); err != nil {
+			return nil, fmt.Errorf("target %d: %w", i, err)
+		}
+	}
+	if len(cfg.Strategies) == 0 {
+		cfg.Strategies = Strategies
+	}
+	for _, s := range cfg.Strategies {
+		switch s {
+		case StrategyBitFlip, StrategyBoundary, StrategyLength, StrategyRandom:
+		default:
+			return nil, fmt.Errorf("unknown strategy %q, want %s, %s, %s or %s", s, StrategyBitFlip, StrategyBoundary, StrategyLength, StrategyRandom)
+		}
+	}
+	if cfg.MaxBitFlips < 0 || cfg.MaxBitFlips > 8*max {
+		return nil, fmt.Errorf("max bit flips must be within 0..%d (got %d)", 8*max, cfg.MaxBitFlips)
+	}
+	if cfg.MaxBitFlips == 0 {
+		cfg.MaxBitFlips = 1
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	return &Fuzzer{cfg: cfg, max: max, seed: seed, rng: rand.New(rand.NewSource(seed))}, nil
+}
+
+// Seed returns the seed of the mutations, the one picked when Config.Seed is 0.
+func (z *Fuzzer) Seed() int64 {
+	return z.seed
+}
+
+// Next returns the next mutation.
+func (z *Fuzzer) Next() Mutation {
+	cfg := &z.cfg
+	m := Mutation{
+		Target:   z.rng.Intn(len(cfg.Targets)),
+		Strategy: cfg.Strategies[z.rng.Intn(len(cfg.Strategies))],
+	}
+	t := &cfg.Targets[m.Target]
+	f := &m.Frame
+	f.ID, f.IsExtended = t.ID, t.Extended
+	f.IsFD, f.BRS = cfg.FD, cfg.FD && cfg.BRS
+	n := copy(f.Data[:], t.Data)
+	if n == 0 && m.Strategy != StrategyLength {
+		// an empty payload has nothing to mutate but its length
+		m.Strategy = StrategyLength
+	}
+
+	switch m.Strategy {
+	case StrategyBitFlip:
+		m.Detail = z.flipBits(f.Data[:n])
+	case StrategyBoundary:
+		m.Detail = z.boundary(f.Data[:n])
+	case StrategyLength:
+		n = z.length(n)
+		m.Detail = fmt.Sprintf("length %d instead of %d", n, len(t.Data))
+	case StrategyRandom:
+		z.rng.Read(f.Data[:n])
+		m.Detail = "random payload"
+	}
+	f.Length = uint8(n)
+	if cfg.FD {
+		f.Length = uint8(canbus.PaddedLength(n))
+	}
+	return m
+}
+
+// flipBits flips distinct random bits of data.
+func (z *Fuzzer) flipBits(data []byte) string {
+	n := 1 + z.rng.Intn(min(z.cfg.MaxBitFlips, 8*len(data)))
+	detail := "flipped bit"
+	if n > 1 {
+		detail += "s"
+	}
+	for i, bit := range z.rng.Perm(8 * len(data))[:n] {
+		data[bit/8] ^= 0x80 >> (bit % 8)
+		if i > 0 {
+			detail += ","
+		}
+		detail += fmt.Sprintf(" %d", bit)
+	}
+	return detail
+}
+
+// boundary writes a boundary value in a random field of data.
+func (z *Fuzzer) boundary(data []byte) string {
+	size := 1
+	switch {
+	case len(data) >= 4 && z.rng.Intn(3) == 0:
+		size = 4
+	case len(data) >= 2 && z.rng.Intn(2) == 0:
+		size = 2
+	}
+	off := z.rng.Intn(len(data) - size + 1)
+	top := uint64(1)<<(8*size) - 1
+	values := [...]uint64{0, 1, top>>1 - 1, top >> 1, top>>1 + 1, top>>1 + 2, top - 1, top}
+	value := values[z.rng.Intn(len(values))]
+	for i := 0; i < size; i++ {
+		data[off+i] = byte(value >> (8 * (size - 1 - i)))
+	}
+	return fmt.Sprintf("%d bit 0x%0*X at byte %d", 8*size, 2*size, value, off)
+}
+
+// length returns another length than n: any up to 8 bytes for classic CAN,
+// any of the CAN FD lengths for CAN FD.
+func (z *Fuzzer) length(n int) int {
+	var lengths []int
+	for dlc := uint8(0); dlc <= 15; dlc++ {
+		l := int(canbus.DLCToLength(dlc))
+		if l <= z.max && l != n {
+			lengths = append(lengths, l)
+		}
+	}
+	return lengths[z.rng.Intn(len(lengths))]
+}
@@ -0,0 +1,505 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"canproject/canbus"
+	"canproject/fuzz"
+	"canproject/uds"
+)
+
+const (
+	// maxFuzzFindings bounds the findings kept, the oldest are dropped.
+	maxFuzzFindings = 1000
+	// fuzzErrorHoldoff is the time after an error frame finding during which the
+	// next error frames, usually of the same burst, are not reported.
+	fuzzErrorHoldoff = time.Second
+)
+
+// FuzzConfig configures a fuzzer started with StartFuzzer.
+type FuzzConfig struct {
+	Interface string `json:"interface"`
+	fuzz.Config
+	// Rate is the frames sent per second.
+	Rate int `json:"rate"`
+	// Count stops the fuzzer after that many frames, 0 for no limit.
+	Count int `json:"count"`
+	// History is the number of frames sent before a finding that it records,
+	// 0 for 32.
+	History int `json:"history"`
+	// StopOnFinding stops the fuzzer at its first finding.
+	StopOnFinding bool `json:"stopOnFinding"`
+
+	// ErrorFrames reports the error frames received on Interface.
+	ErrorFrames bool `json:"errorFrames"`
+	// SilenceMs reports when no frame WatchID was received for that long, 0
+	// not to watch any ID.
+	WatchID       uint32 `json:"watchId"`
+	WatchExtended bool   `json:"watchExtended"`
+	SilenceMs     int    `json:"silenceMs"`
+	// UDSHandle is an ISO-TP channel or DoIP connection whose DTCs matching
+	// DTCStatusMask (0 for 0xFF) are read every DTCPollMs (0 for 1000),
+	// reporting the new ones and the requests without answer. 0 not to read
+	// any.
+	UDSHandle     int   `json:"udsHandle"`
+	DTCStatusMask uint8 `json:"dtcStatusMask"`
+	DTCPollMs     int   `json:"dtcPollMs"`
+}
+
+// FuzzFrame is a frame sent by a fuzzer.
+type FuzzFrame struct {
+	// Seq is the number of the frame in the run, from 1.
+	Seq      uint64    `json:"seq"`
+	Time     time.Time `json:"time"`
+	ID       uint32    `json:"id"`
+	Extended bool      `json:"extended"`
+	FD       bool      `json:"fd"`
+	BRS      bool      `json:"brs"`
+	Data     []uint32  `json:"data"`
+	Strategy string    `json:"strategy"`
+	Detail   string    `json:"detail"`
+	// Candump is the frame in candump log format, eg "123#DEADBEEF".
+	Candump string `json:"candump"`
+}
+
+// FuzzFinding is an anomaly seen while fuzzing, emitted on "fuzz:finding".
+type FuzzFinding struct {
+	Handle    int    `json:"handle"`
+	Interface string `json:"interface"`
+	// Kind is "error-frame", "silence", "dtc" or "no-response" (a DTC read
+	// failed after the earlier ones succeeded).
+	Kind   string    `json:"kind"`
+	Time   time.Time `json:"time"`
+	Detail string    `json:"detail"`
+	// Seed reproduces the run with the same configuration.
+	Seed int64 `json:"seed"`
+	// Frames are the last frames sent before the finding, the latest last.
+	Frames []FuzzFrame `json:"frames"`
+	DTCs   []UDSDTC    `json:"dtcs,omitempty"`
+}
+
+// FuzzStatus is the progress of a fuzzer, emitted on "fuzz:status" every
+// second and when it stops.
+type FuzzStatus struct {
+	Handle    int    `json:"handle"`
+	Interface string `json:"interface"`
+	Running   bool   `json:"running"`
+	Seed      int64  `json:"seed"`
+	Sent      uint64 `json:"sent"`
+	// Errors counts the frames that could not be sent.
+	Errors   uint64 `json:"errors"`
+	Findings uint64 `json:"findings"`
+}
+
+type canFuzzer struct {
+	handle int
+	cfg    FuzzConfig
+	fz     *fuzz.Fuzzer
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	sent     atomic.Uint64
+	errors   atomic.Uint64
+	findings atomic.Uint64
+
+	// mu guards the frames sent last and the monitor states.
+	mu        sync.Mutex
+	history   []FuzzFrame
+	next      int
+	lastSeen  time.Time
+	silent    bool
+	lastError time.Time
+}
+
+// StartFuzzer sends mutations of the Targets frames on a started interface at
+// cfg.Rate frames per second and returns a handle for StopFuzzer. The
+// strategies are "bitflip", "boundary" (boundary values of 8, 16 and 32 bit
+// fields), "length" (every other length, the CAN FD ones with FD) and
+// "random". The error frames, the silence of WatchID and the new DTCs of
+// UDSHandle are reported as findings with the frames sent just before them.
+func (a *App) StartFuzzer(cfg FuzzConfig) (int, error) {
+	cfg.Interface = strings.TrimSpace(cfg.Interface)
+	if cfg.Rate < 1 || cfg.Rate > 1000 {
+		return 0, fmt.Errorf("rate must be within 1..1000 frames/s (got %d)", cfg.Rate)
+	}
+	if cfg.Count < 0 {
+		return 0, fmt.Errorf("count must be >= 0 (got %d)", cfg.Count)
+	}
+	if cfg.History < 0 || cfg.History > 1000 {
+		return 0, fmt.Errorf("history must be within 0..1000 frames (got %d)", cfg.History)
+	}
+	if cfg.History == 0 {
+		cfg.History = 32
+	}
+	if cfg.SilenceMs < 0 {
+		return 0, fmt.Errorf("silence must be >= 0 ms (got %d)", cfg.SilenceMs)
+	}
+	if cfg.SilenceMs > 0 {
+		if err := (&canbus.Frame{ID: cfg.WatchID, IsExtended: cfg.WatchExtended}).Validate(); err != nil {
+			return 0, fmt.Errorf("watch ID: %w", err)
+		}
+	}
+	if cfg.DTCStatusMask == 0 {
+		cfg.DTCStatusMask = 0xff
+	}
+	if cfg.DTCPollMs == 0 {
+		cfg.DTCPollMs = 1000
+	}
+	if cfg.DTCPollMs < 100 {
+		return 0, fmt.Errorf("DTC poll period must be >= 100 ms (got %d)", cfg.DTCPollMs)
+	}
+	if cfg.UDSHandle != 0 {
+		if _, err := a.udsClient(cfg.UDSHandle); err != nil {
+			return 0, err
+		}
+	}
+	fz, err := fuzz.New(cfg.Config)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := a.txConn(cfg.Interface, cfg.FD); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	z := &canFuzzer{cfg: cfg, fz: fz, cancel: cancel, done: make(chan struct{}), lastSeen: time.Now()}
+	a.fuzzMu.Lock()
+	a.nextFuzzer++
+	z.handle = a.nextFuzzer
+	if a.fuzzers == nil {
+		a.fuzzers = make(map[int]*canFuzzer)
+	}
+	a.fuzzers[z.handle] = z
+	a.fuzzMu.Unlock()
+
+	go a.fuzzLoop(ctx, z)
+	return z.handle, nil
+}
+
+// StopFuzzer stops a fuzzer started with StartFuzzer.
+func (a *App) StopFuzzer(handle int) error {
+	a.fuzzMu.Lock()
+	z := a.fuzzers[handle]
+	a.fuzzMu.Unlock()
+	if z == nil {
+		return fmt.Errorf("no fuzzer with handle %d", handle)
+	}
+	z.cancel()
+	<-z.done
+	return nil
+}
+
+// ListFuzzers returns the running fuzzers ordered by handle.
+func (a *App) ListFuzzers() []FuzzStatus {
+	a.fuzzMu.Lock()
+	defer a.fuzzMu.Unlock()
+
+	infos := make([]FuzzStatus, 0, len(a.fuzzers))
+	for _, z := range a.fuzzers {
+		infos = append(infos, z.status(true))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Handle < infos[j].Handle
+	})
+	return infos
+}
+
+// GetFuzzFindings returns the findings of the fuzzers, the oldest first. They
+// are kept after the fuzzers stop, until ClearFuzzFindings.
+func (a *App) GetFuzzFindings() []FuzzFinding {
+	a.fuzzMu.Lock()
+	defer a.fuzzMu.Unlock()
+	return append([]FuzzFinding{}, a.fuzzFindings...)
+}
+
+// ClearFuzzFindings removes the findings of the fuzzers.
+func (a *App) ClearFuzzFindings() {
+	a.fuzzMu.Lock()
+	a.fuzzFindings = nil
+	a.fuzzMu.Unlock()
+}
+
+// ExportFuzzFindings writes the findings of the fuzzers to a JSON file and
+// returns their count.
+func (a *App) ExportFuzzFindings(path string) (int, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return 0, errors.New("findings path is empty")
+	}
+	findings := a.GetFuzzFindings()
+	data, err := json.MarshalIndent(findings, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return 0, err
+	}
+	return len(findings), nil
+}
+
+// stopFuzzers stops every fuzzer on iface.
+func (a *App) stopFuzzers(iface string) {
+	a.fuzzMu.Lock()
+	var fuzzers []*canFuzzer
+	for _, z := range a.fuzzers {
+		if z.cfg.Interface == iface {
+			fuzzers = append(fuzzers, z)
+		}
+	}
+	a.fuzzMu.Unlock()
+
+	for _, z := range fuzzers {
+		z.cancel()
+		<-z.done
+	}
+}
+
+// monitorFuzz watches the frames received on iface for the fuzzers running
+// on it: the error frames and the frames of the watched IDs.
+func (a *App) monitorFuzz(iface string, ts time.Time, f *canbus.Frame) {
+	a.fuzzMu.Lock()
+	if len(a.fuzzers) == 0 {
+		a.fuzzMu.Unlock()
+		return
+	}
+	var fuzzers []*canFuzzer
+	for _, z := range a.fuzzers {
+		if z.cfg.Interface == iface {
+			fuzzers = append(fuzzers, z)
+		}
+	}
+	a.fuzzMu.Unlock()
+
+	now := time.Now()
+	for _, z := range fuzzers {
+		switch {
+		case f.IsError && z.cfg.ErrorFrames:
+			z.mu.Lock()
+			report := now.Sub(z.lastError) >= fuzzErrorHoldoff
+			if report {
+				z.lastError = now
+			}
+			z.mu.Unlock()
+			if report {
+				ef := f.ErrorFrame()
+				a.reportFuzzFinding(z, "error-frame", ts, fmt.Sprintf("error frame: class=%s controller=%s protocol=%s location=%s transceiver=%s",
+					ef.ErrorClass,
+					ef.ControllerError,
+					ef.ProtocolError,
+					ef.ProtocolViolationErrorLocation,
+					ef.TransceiverError,
+				), nil)
+			}
+		case !f.IsError && z.cfg.SilenceMs > 0 && f.ID == z.cfg.WatchID && f.IsExtended == z.cfg.WatchExtended:
+			z.mu.Lock()
+			z.lastSeen, z.silent = now, false
+			z.mu.Unlock()
+		}
+	}
+}
+
+func (a *App) fuzzLoop(ctx context.Context, z *canFuzzer) {
+	var polling sync.WaitGroup
+	defer func() {
+		z.cancel()
+		polling.Wait()
+		a.fuzzMu.Lock()
+		delete(a.fuzzers, z.handle)
+		a.fuzzMu.Unlock()
+		a.emit("fuzz:status", z.status(false))
+		close(z.done)
+	}()
+
+	if z.cfg.UDSHandle != 0 {
+		polling.Add(1)
+		go func() {
+			defer polling.Done()
+			a.pollFuzzDTCs(ctx, z)
+		}()
+	}
+
+	ticker := time.NewTicker(time.Second / time.Duration(z.cfg.Rate))
+	defer ticker.Stop()
+	silence := time.Duration(z.cfg.SilenceMs) * time.Millisecond
+	report := time.Now()
+	failing := false
+	for {
+		if z.cfg.Count > 0 && z.sent.Load() >= uint64(z.cfg.Count) {
+			return
+		}
+		m := z.fz.Next()
+		now := time.Now()
+		err := a.send(z.cfg.Interface, m.Frame)
+		if err != nil {
+			z.errors.Add(1)
+			// report the first failure of a run only, the next frames would repeat it
+			if !failing {
+				a.emitError(fmt.Errorf("fuzzer %d: %w", z.handle, err))
+			}
+		} else {
+			z.sent.Add(1)
+			z.record(now, &m)
+		}
+		failing = err != nil
+
+		if silence > 0 {
+			z.mu.Lock()
+			quiet := !z.silent && now.Sub(z.lastSeen) >= silence
+			if quiet {
+				z.silent = true
+			}
+			z.mu.Unlock()
+			if quiet {
+				a.reportFuzzFinding(z, "silence", now, fmt.Sprintf("no frame 0x%X for %d ms", z.cfg.WatchID, z.cfg.SilenceMs), nil)
+			}
+		}
+
+		if now.Sub(report) >= time.Second {
+			report = now
+			a.emit("fuzz:status", z.status(true))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollFuzzDTCs reads the DTCs of the UDS handle of z until ctx is done,
+// reporting the codes not read before and the reads failing after a success.
+func (a *App) pollFuzzDTCs(ctx context.Context, z *canFuzzer) {
+	ticker := time.NewTicker(time.Duration(z.cfg.DTCPollMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	var known map[uint32]bool
+	responding := false
+	for {
+		c, err := a.udsClient(z.cfg.UDSHandle)
+		var dtcs []uds.DTC
+		if err == nil {
+			rctx, cancel := context.WithTimeout(ctx, udsRequestTimeout)
+			dtcs, err = c.ReadDTCs(rctx, z.cfg.DTCStatusMask)
+			cancel()
+		}
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			if responding {
+				a.reportFuzzFinding(z, "no-response", time.Now(), fmt.Sprintf("reading the DTCs of handle %d: %v", z.cfg.UDSHandle, err), nil)
+			}
+			responding = false
+		case known == nil:
+			// the DTCs present before the fuzzing are not findings
+			known = make(map[uint32]bool)
+			for _, d := range dtcs {
+				known[d.Code] = true
+			}
+			responding = true
+		default:
+			var fresh []uds.DTC
+			for _, d := range dtcs {
+				if !known[d.Code] {
+					known[d.Code] = true
+					fresh = append(fresh, d)
+				}
+			}
+			responding = true
+			if len(fresh) > 0 {
+				described := a.describeDTCs(fresh)
+				names := make([]string, len(described))
+				for i, d := range described {
+					names[i] = d.Name
+				}
+				a.reportFuzzFinding(z, "dtc", time.Now(), "new DTCs "+strings.Join(names, ", "), described)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reportFuzzFinding records a finding of z with the frames it sent last.
+func (a *App) reportFuzzFinding(z *canFuzzer, kind string, ts time.Time, detail string, dtcs []UDSDTC) {
+	finding := FuzzFinding{
+		Handle:    z.handle,
+		Interface: z.cfg.Interface,
+		Kind:      kind,
+		Time:      ts,
+		Detail:    detail,
+		Seed:      z.fz.Seed(),
+		Frames:    z.lastFrames(),
+		DTCs:      dtcs,
+	}
+	z.findings.Add(1)
+	a.fuzzMu.Lock()
+	if len(a.fuzzFindings) >= maxFuzzFindings {
+		a.fuzzFindings = a.fuzzFindings[1:]
+	}
+	a.fuzzFindings = append(a.fuzzFindings, finding)
+	a.fuzzMu.Unlock()
+	a.emit("fuzz:finding", finding)
+	if z.cfg.StopOnFinding {
+		z.cancel()
+	}
+}
+
+// record keeps a frame sent in the history of z.
+func (z *canFuzzer) record(ts time.Time, m *fuzz.Mutation) {
+	fr := FuzzFrame{
+		Seq:      z.sent.Load(),
+		Time:     ts,
+		ID:       m.Frame.ID,
+		Extended: m.Frame.IsExtended,
+		FD:       m.Frame.IsFD,
+		BRS:      m.Frame.BRS,
+		Data:     dataWords(m.Frame.Payload()),
+		Strategy: m.Strategy,
+		Detail:   m.Detail,
+		Candump:  m.Frame.String(),
+	}
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if len(z.history) < z.cfg.History {
+		z.history = append(z.history, fr)
+		return
+	}
+	z.history[z.next] = fr
+	z.next = (z.next + 1) % len(z.history)
+}
+
+// lastFrames returns the history of z, the latest frame last.
+func (z *canFuzzer) lastFrames() []FuzzFrame {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	out := make([]FuzzFrame, 0, len(z.history))
+	out = append(out, z.history[z.next:]...)
+	return append(out, z.history[:z.next]...)
+}
+
+func (z *canFuzzer) status(running bool) FuzzStatus {
+	return FuzzStatus{
+		Handle:    z.handle,
+		Interface: z.cfg.Interface,
+		Running:   running,
+		Seed:      z.fz.Seed(),
+		Sent:      z.sent.Load(),
+		Errors:    z.errors.Load(),
+		Findings:  z.findings.Load(),
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"canproject/canbus"
+)
+
+const (
+	// gapQueue is the number of transmissions a gap rule holds while it waits
+	// for the delay of the previous ones, more triggers are missed.
+	gapQueue = 64
+	// gapSpin is the end of the delay waited for by spinning instead of a timer,
+	// which wakes up too late for sub-millisecond gaps.
+	gapSpin = 200 * time.Microsecond
+)
+
+// GapTransmitRule transmits a frame a fixed delay after every received frame
+// with a given ID, eg 2 ms after every 0x123, to answer or overwrite a frame on a
+// test bench.
+type GapTransmitRule struct {
+	Name string `json:"name"`
+	// Interface is the interface of the triggering frames, empty for all.
+	Interface       string `json:"interface"`
+	TriggerID       uint32 `json:"triggerId"`
+	TriggerExtended bool   `json:"triggerExtended"`
+	// DelayUs is the gap in microseconds between the reception of the triggering
+	// frame, as stamped by the kernel when it reports it, and the transmission.
+	DelayUs int `json:"delayUs"`
+	// TxInterface is the interface the frame is sent on, empty for the interface
+	// of the triggering frame.
+	TxInterface string   `json:"txInterface"`
+	ID          uint32   `json:"id"`
+	Extended    bool     `json:"extended"`
+	FD          bool     `json:"fd"`
+	BRS         bool     `json:"brs"`
+	Data        []uint32 `json:"data"`
+	// Limit stops the rule after that many transmissions, 0 for no limit.
+	Limit int `json:"limit"`
+}
+
+// GapTransmitInfo describes a rule added with AddGapTransmit.
+type GapTransmitInfo struct {
+	Handle int             `json:"handle"`
+	Rule   GapTransmitRule `json:"rule"`
+	Sent   uint64          `json:"sent"`
+	// Missed counts the triggers skipped because too many transmissions were
+	// waiting, Errors the failed transmissions.
+	Missed uint64 `json:"missed"`
+	Errors uint64 `json:"errors"`
+	// MaxLateUs is the largest delay in microseconds between the scheduled and
+	// the actual transmission time.
+	MaxLateUs float64 `json:"maxLateUs"`
+}
+
+type gapRule struct {
+	handle int
+	rule   GapTransmitRule
+	frame  canbus.Frame
+	delay  time.Duration
+	// due receives the transmission times, the sender goroutine runs until ctx is done.
+	due    chan gapDue
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	triggers atomic.Uint64
+	sent     atomic.Uint64
+	missed   atomic.Uint64
+	errors   atomic.Uint64
+	maxLate  atomic.Int64
+}
+
+type gapDue struct {
+	at    time.Time
+	iface string
+}
+
+// AddGapTransmit registers a gap transmit rule and returns a handle for
+// RemoveGapTransmit. The triggers are detected first thing in the receive
+// pipeline, and the frame is sent by a goroutine of the rule at the reception
+// time plus the delay.
+func (a *App) AddGapTransmit(rule GapTransmitRule) (int, error) {
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.Interface = strings.TrimSpace(rule.Interface)
+	rule.TxInterface = strings.TrimSpace(rule.TxInterface)
+	if rule.DelayUs < 0 {
+		return 0, fmt.Errorf("delay must be >= 0 (got %d us)", rule.DelayUs)
+	}
+	if rule.Limit < 0 {
+		return 0, fmt.Errorf("limit must be >= 0 (got %d)", rule.Limit)
+	}
+	if err := (&canbus.Frame{ID: rule.TriggerID, IsExtended: rule.TriggerExtended}).Validate(); err != nil {
+		return 0, fmt.Errorf("trigger: %w", err)
+	}
+	data := make([]byte, len(rule.Data))
+	for i, b := range rule.Data {
+		if b > 0xFF {
+			return 0, fmt.Errorf("data byte %d out of range: %d", i, b)
+		}
+		data[i] = byte(b)
+	}
+	f, err := newFrame(rule.ID, data, rule.Extended, rule.FD || len(data) > canbus.MaxDataLength, rule.BRS)
+	if err != nil {
+		return 0, err
+	}
+	rule.Data = append([]uint32(nil), rule.Data...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &gapRule{
+		rule:   rule,
+		frame:  f,
+		delay:  time.Duration(rule.DelayUs) * time.Microsecond,
+		due:    make(chan gapDue, gapQueue),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	a.gapMu.Lock()
+	defer a.gapMu.Unlock()
+	a.nextGap++
+	r.handle = a.nextGap
+	var rules []*gapRule
+	if old := a.gapRules.Load(); old != nil {
+		rules = slices.Clone(*old)
+	}
+	rules = append(rules, r)
+	a.gapRules.Store(&rules)
+	go a.gapLoop(r)
+	return r.handle, nil
+}
+
+// RemoveGapTransmit removes a gap transmit rule, the transmissions it has not
+// sent yet are discarded.
+func (a *App) RemoveGapTransmit(handle int) error {
+	a.gapMu.Lock()
+	old := a.gapRules.Load()
+	var r *gapRule
+	if old != nil {
+		if i := slices.IndexFunc(*old, func(r *gapRule) bool { return r.handle == handle }); i >= 0 {
+			r = (*old)[i]
+			rules := slices.Delete(slices.Clone(*old), i, i+1)
+			a.gapRules.Store(&rules)
+		}
+	}
+	a.gapMu.Unlock()
+
+	if r == nil {
+		return fmt.Errorf("no gap transmit rule with handle %d", handle)
+	}
+	r.stop()
+	return nil
+}
+
+// ClearGapTransmits removes all the gap transmit rules.
+func (a *App) ClearGapTransmits() {
+	a.gapMu.Lock()
+	old := a.gapRules.Swap(nil)
+	a.gapMu.Unlock()
+
+	if old != nil {
+		for _, r := range *old {
+			r.stop()
+		}
+	}
+}
+
+// ListGapTransmits returns the gap transmit rules by handle with their counters.
+func (a *App) ListGapTransmits() []GapTransmitInfo {
+	infos := []GapTransmitInfo{}
+	if rules := a.gapRules.Load(); rules != nil {
+		for _, r := range *rules {
+			infos = append(infos, GapTransmitInfo{
+				Handle:    r.handle,
+				Rule:      r.rule,
+				Sent:      r.sent.Load(),
+				Missed:    r.missed.Load(),
+				Errors:    r.errors.Load(),
+				MaxLateUs: float64(r.maxLate.Load()) / float64(time.Microsecond),
+			})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Handle < infos[j].Handle })
+	return infos
+}
+
+// dispatchGap schedules the transmissions of the rules triggered by a received frame.
+func (a *App) dispatchGap(iface string, info canbus.RxInfo, f *canbus.Frame) {
+	rules := a.gapRules.Load()
+	if rules == nil {
+		return
+	}
+	// hardware timestamps are in the clock of the controller, not the one of the timers
+	at := info.Time
+	if info.Source == canbus.TimestampHardware {
+		at = time.Now()
+	}
+	for _, r := range *rules {
+		rule := &r.rule
+		if f.ID != rule.TriggerID || f.IsExtended != rule.TriggerExtended ||
+			(rule.Interface != "" && iface != rule.Interface) {
+			continue
+		}
+		if rule.Limit > 0 && r.triggers.Add(1) > uint64(rule.Limit) {
+			continue
+		}
+		select {
+		case r.due <- gapDue{at: at.Add(r.delay), iface: iface}:
+		default:
+			r.missed.Add(1)
+		}
+	}
+}
+
+// gapLoop sends the frame of r at the scheduled times until r is stopped.
+func (a *App) gapLoop(r *gapRule) {
+	defer close(r.done)
+
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	for {
+		var due gapDue
+		select {
+		case <-r.ctx.Done():
+			return
+		case due = <-r.due:
+		}
+		if d := time.Until(due.at) - gapSpin; d > 0 {
+			timer.Reset(d)
+			select {
+			case <-r.ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+		for time.Now().Before(due.at) {
+			// spin the last gapSpin
+		}
+
+		iface := r.rule.TxInterface
+		if iface == "" {
+			iface = due.iface
+		}
+		err := a.send(iface, r.frame)
+		if late := int64(time.Since(due.at)); late > r.maxLate.Load() {
+			r.maxLate.Store(late)
+		}
+		if err != nil {
+			// report the first failure only, later transmissions usually fail the same way
+			if r.errors.Add(1) == 1 {
+				a.emitError(fmt.Errorf("gap transmit %d: %w", r.handle, err))
+			}
+			continue
+		}
+		r.sent.Add(1)
+	}
+}
+
+func (r *gapRule) stop() {
+	r.cancel()
+	<-r.done
+}
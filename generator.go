@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"canproject/generator"
+)
+
+// generatorTick is the pacing period of the generators: the frames due are sent in a burst every tick.
+const generatorTick = time.Millisecond
+
+// GeneratorConfig configures a traffic generator started with StartGenerator.
+type GeneratorConfig struct {
+	Interface string `json:"interface"`
+	generator.Config
+	// Rate is the target frames per second.
+	Rate int `json:"rate"`
+	// Count stops the generator after that many frames, 0 for no limit.
+	Count int `json:"count"`
+}
+
+// GeneratorStatus is the progress of a generator, emitted on "can:generator" every
+// second and when it stops.
+type GeneratorStatus struct {
+	Handle    int    `json:"handle"`
+	Interface string `json:"interface"`
+	Mode      string `json:"mode"`
+	Running   bool   `json:"running"`
+	Sent      uint64 `json:"sent"`
+	// Errors counts the frames that could not be sent, eg with a full TX queue.
+	Errors uint64 `json:"errors"`
+	// Rate is the target rate, AchievedRate the frames sent over the last second.
+	Rate         int    `json:"rate"`
+	AchievedRate uint64 `json:"achievedRate"`
+}
+
+type trafficGenerator struct {
+	handle int
+	cfg    GeneratorConfig
+	gen    *generator.Generator
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	sent   atomic.Uint64
+	errors atomic.Uint64
+	// achieved is the number of frames sent over the last second.
+	achieved atomic.Uint64
+}
+
+// StartGenerator sends synthetic traffic on a started interface at cfg.Rate frames
+// per second and returns a handle for StopGenerator. The modes are "counter" (a
+// fixed ID with an incrementing counter in the payload), "random" (random IDs and
+// payloads) and "fuzz" (IDs swept in order with random payloads).
+func (a *App) StartGenerator(cfg GeneratorConfig) (int, error) {
+	cfg.Interface = strings.TrimSpace(cfg.Interface)
+	if cfg.Rate < 1 || cfg.Rate > 1000000 {
+		return 0, fmt.Errorf("rate must be within 1..1000000 frames/s (got %d)", cfg.Rate)
+	}
+	if cfg.Count < 0 {
+		return 0, fmt.Errorf("count must be >= 0 (got %d)", cfg.Count)
+	}
+	gen, err := generator.New(cfg.Config)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := a.txConn(cfg.Interface, cfg.FD); err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &trafficGenerator{cfg: cfg, gen: gen, cancel: cancel, done: make(chan struct{})}
+	a.genMu.Lock()
+	a.nextGenerator++
+	g.handle = a.nextGenerator
+	if a.generators == nil {
+		a.generators = make(map[int]*trafficGenerator)
+	}
+	a.generators[g.handle] = g
+	a.genMu.Unlock()
+
+	go a.generatorLoop(ctx, g)
+	return g.handle, nil
+}
+
+// StopGenerator stops a generator started with StartGenerator.
+func (a *App) StopGenerator(handle int) error {
+	a.genMu.Lock()
+	g := a.generators[handle]
+	a.genMu.Unlock()
+	if g == nil {
+		return fmt.Errorf("no generator with handle %d", handle)
+	}
+	g.cancel()
+	<-g.done
+	return nil
+}
+
+// ListGenerators returns the running generators ordered by handle.
+func (a *App) ListGenerators() []GeneratorStatus {
+	a.genMu.Lock()
+	defer a.genMu.Unlock()
+
+	infos := make([]GeneratorStatus, 0, len(a.generators))
+	for _, g := range a.generators {
+		infos = append(infos, g.status(true))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Handle < infos[j].Handle
+	})
+	return infos
+}
+
+// stopGenerators stops every generator on iface.
+func (a *App) stopGenerators(iface string) {
+	a.genMu.Lock()
+	var gens []*trafficGenerator
+	for _, g := range a.generators {
+		if g.cfg.Interface == iface {
+			gens = append(gens, g)
+		}
+	}
+	a.genMu.Unlock()
+
+	for _, g := range gens {
+		g.cancel()
+		<-g.done
+	}
+}
+
+func (a *App) generatorLoop(ctx context.Context, g *trafficGenerator) {
+	defer func() {
+		a.genMu.Lock()
+		delete(a.generators, g.handle)
+		a.genMu.Unlock()
+		a.emit("can:generator", g.status(false))
+		close(g.done)
+	}()
+
+	ticker := time.NewTicker(generatorTick)
+	defer ticker.Stop()
+
+	rate := uint64(g.cfg.Rate)
+	start := time.Now()
+	report, lastSent := start, uint64(0)
+	var scheduled uint64
+	failing := false
+	for {
+		now := time.Now()
+		// the frames due since the start are sent in a burst, catching up after a
+		// late tick but not after a long stall such as a suspend
+		due := uint64(now.Sub(start).Seconds() * float64(rate))
+		if due > scheduled+rate {
+			scheduled = due - rate
+		}
+		for ; scheduled < due; scheduled++ {
+			if g.cfg.Count > 0 && g.sent.Load() >= uint64(g.cfg.Count) {
+				return
+			}
+			err := a.send(g.cfg.Interface, g.gen.Next())
+			if err != nil {
+				g.errors.Add(1)
+				// report the first failure of a run only, the next frames would repeat it
+				if !failing {
+					a.emitError(fmt.Errorf("generator %d: %w", g.handle, err))
+				}
+			} else {
+				g.sent.Add(1)
+			}
+			failing = err != nil
+		}
+		if g.cfg.Count > 0 && g.sent.Load() >= uint64(g.cfg.Count) {
+			return
+		}
+
+		if d := now.Sub(report); d >= time.Second {
+			sent := g.sent.Load()
+			g.achieved.Store(uint64(float64(sent-lastSent)/d.Seconds() + 0.5))
+			report, lastSent = now, sent
+			a.emit("can:generator", g.status(true))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *trafficGenerator) status(running bool) GeneratorStatus {
+	return GeneratorStatus{
+		Handle:       g.handle,
+		Interface:    g.cfg.Interface,
+		Mode:         g.cfg.Mode,
+		Running:      running,
+		Sent:         g.sent.Load(),
+		Errors:       g.errors.Load(),
+		Rate:         g.cfg.Rate,
+		AchievedRate: g.achieved.Load(),
+	}
+}
@@ -0,0 +1,136 @@
+// Package generator produces synthetic CAN traffic for load and fuzz testing.
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"canproject/canbus"
+)
+
+// Modes of a Config.
+const (
+	// ModeCounter sends a fixed ID whose payload carries an incrementing counter.
+	ModeCounter = "counter"
+	// ModeRandom sends random IDs within MinID..MaxID with random payloads.
+	ModeRandom = "random"
+	// ModeFuzz sweeps the IDs MinID..MaxID in order with random payloads, wrapping around.
+	ModeFuzz = "fuzz"
+)
+
+// Config describes the generated frames.
+type Config struct {
+	Mode     string `json:"mode"`
+	Extended bool   `json:"extended"`
+	// FD sends CAN FD frames, BRS with the bit rate switch.
+	FD  bool `json:"fd"`
+	BRS bool `json:"brs"`
+	// ID is the ID of the counter mode.
+	ID uint32 `json:"id"`
+	// MinID and MaxID bound the IDs of the random and fuzz modes, MaxID 0 for the
+	// highest ID (0x7FF or 0x1FFFFFFF).
+	MinID uint32 `json:"minId"`
+	MaxID uint32 `json:"maxId"`
+	// Length is the payload length; RandomLength picks a random length up to it
+	// in the random and fuzz modes.
+	Length       int  `json:"length"`
+	RandomLength bool `json:"randomLength"`
+	// Data is the payload of the counter mode before the counter is written.
+	Data []byte `json:"data"`
+	// CounterOffset and CounterBytes locate the big endian counter in the payload of
+	// the counter mode, CounterBytes 0 for the whole payload up to 8 bytes.
+	CounterOffset int `json:"counterOffset"`
+	CounterBytes  int `json:"counterBytes"`
+	// Seed seeds the random payloads, so a run can be reproduced. 0 picks a random seed.
+	Seed int64 `json:"seed"`
+}
+
+// Generator returns the frames of a Config. It is not safe for concurrent use.
+type Generator struct {
+	cfg     Config
+	max     int
+	rng     *rand.Rand
+	counter uint64
+	next    uint32
+}
+
+// New validates cfg and returns its generator.
+func New(cfg Config) (*Generator, error) {
+	max := canbus.MaxDataLength
+	if cfg.FD {
+		max = canbus.MaxFDDataLength
+	}
+	if cfg.Length < 0 || cfg.Length > max {
+		return nil, fmt.Errorf("length must be within 0..%d (got %d)", max, cfg.Length)
+	}
+	top := uint32(0x7ff)
+	if cfg.Extended {
+		top = 0x1fffffff
+	}
+	switch cfg.Mode {
+	case ModeCounter:
+		if len(cfg.Data) > cfg.Length {
+			return nil, fmt.Errorf("data is longer than the length %d", cfg.Length)
+		}
+		if cfg.CounterBytes == 0 {
+			cfg.CounterBytes = min(cfg.Length-cfg.CounterOffset, 8)
+		}
+		if cfg.CounterOffset < 0 || cfg.CounterBytes < 1 || cfg.CounterBytes > 8 || cfg.CounterOffset+cfg.CounterBytes > cfg.Length {
+			return nil, errors.New("the counter must be 1 to 8 bytes within the payload")
+		}
+		if err := (&canbus.Frame{ID: cfg.ID, IsExtended: cfg.Extended}).Validate(); err != nil {
+			return nil, err
+		}
+	case ModeRandom, ModeFuzz:
+		if cfg.MaxID == 0 {
+			cfg.MaxID = top
+		}
+		if cfg.MinID > cfg.MaxID || cfg.MaxID > top {
+			return nil, fmt.Errorf("the ID range 0x%X..0x%X is invalid", cfg.MinID, cfg.MaxID)
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode %q, want %s, %s or %s", cfg.Mode, ModeCounter, ModeRandom, ModeFuzz)
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = rand.Int63()
+	}
+	return &Generator{cfg: cfg, max: max, rng: rand.New(rand.NewSource(seed)), next: cfg.MinID}, nil
+}
+
+// Next returns the next frame.
+func (g *Generator) Next() canbus.Frame {
+	cfg := &g.cfg
+	f := canbus.Frame{IsExtended: cfg.Extended, IsFD: cfg.FD, BRS: cfg.FD && cfg.BRS}
+	n := cfg.Length
+	switch cfg.Mode {
+	case ModeCounter:
+		f.ID = cfg.ID
+		copy(f.Data[:], cfg.Data)
+		for i := 0; i < cfg.CounterBytes; i++ {
+			f.Data[cfg.CounterOffset+i] = byte(g.counter >> (8 * (cfg.CounterBytes - 1 - i)))
+		}
+		g.counter++
+	case ModeRandom, ModeFuzz:
+		if cfg.Mode == ModeRandom {
+			f.ID = cfg.MinID + uint32(g.rng.Int63n(int64(cfg.MaxID-cfg.MinID)+1))
+		} else {
+			f.ID = g.next
+			if g.next == cfg.MaxID {
+				g.next = cfg.MinID
+			} else {
+				g.next++
+			}
+		}
+		if cfg.RandomLength {
+			n = g.rng.Intn(n + 1)
+		}
+		g.rng.Read(f.Data[:n])
+	}
+	f.Length = uint8(n)
+	if cfg.FD {
+		f.Length = uint8(canbus.PaddedLength(n))
+	}
+	return f
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+	"canproject/candb"
+	"canproject/gps"
+)
+
+const (
+	// defaultGPSInterface names the GPS records of the capture buffer.
+	defaultGPSInterface = "gps"
+	// gpsMessage is the message of the GPS samples in the decoded exports.
+	gpsMessage = "GPS"
+
+	gpsMinBackoff = time.Second
+	gpsMaxBackoff = 30 * time.Second
+)
+
+// GPSOptions configures the receiver started with StartGPS.
+type GPSOptions struct {
+	// Source is the URL of the receiver: "nmea:///dev/ttyACM0?baud=9600" for
+	// a serial NMEA 0183 receiver, "nmea+tcp://host:port" for NMEA sentences
+	// served over TCP, eg by a phone app, or "gpsd://localhost:2947".
+	Source string `json:"source"`
+	// Interface names the samples in the capture buffer, "gps" when empty.
+	Interface string `json:"interface"`
+}
+
+// GPSPosition is a position of the receiver; the values it does not know are
+// null.
+type GPSPosition struct {
+	// Fix is "none", "2d" or "3d".
+	Fix       string   `json:"fix"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	// Altitude is in m, Speed in m/s and Course in degrees from the true north.
+	Altitude   *float64 `json:"altitude"`
+	Speed      *float64 `json:"speed"`
+	Course     *float64 `json:"course"`
+	Satellites int      `json:"satellites"`
+	HDOP       *float64 `json:"hdop"`
+}
+
+// GPSMotion is an attitude or inertial report of the IMU of the receiver; the
+// values it does not know are null.
+type GPSMotion struct {
+	// Heading, Pitch and Roll are in degrees.
+	Heading *float64 `json:"heading"`
+	Pitch   *float64 `json:"pitch"`
+	Roll    *float64 `json:"roll"`
+	// AccX, AccY and AccZ are in m/s², GyroX, GyroY and GyroZ in degrees/s.
+	AccX  *float64 `json:"accX"`
+	AccY  *float64 `json:"accY"`
+	AccZ  *float64 `json:"accZ"`
+	GyroX *float64 `json:"gyroX"`
+	GyroY *float64 `json:"gyroY"`
+	GyroZ *float64 `json:"gyroZ"`
+}
+
+// GPSSample is a sample of the receiver, emitted on "gps:sample".
+type GPSSample struct {
+	// Timestamp is the reception time, on the clock of the CAN frames.
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	// GPSTime is the time of the sample by the receiver.
+	GPSTime  *time.Time   `json:"gpsTime,omitempty"`
+	Position *GPSPosition `json:"position,omitempty"`
+	Motion   *GPSMotion   `json:"motion,omitempty"`
+}
+
+// GPSStatus describes the GPS receiver, it is emitted on "gps:status" when the
+// receiver is lost or found again.
+type GPSStatus struct {
+	Running    bool   `json:"running"`
+	Connected  bool   `json:"connected"`
+	Source     string `json:"source"`
+	Interface  string `json:"interface"`
+	Reconnects int    `json:"reconnects"`
+	Samples    uint64 `json:"samples"`
+	// Last is the last sample received.
+	Last  *GPSSample `json:"last,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+type gpsReceiver struct {
+	source string
+	iface  string
+	ctx    context.Context
+	stop   context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	src    gps.Source
+	status GPSStatus
+}
+
+// StartGPS connects to a GPS receiver and keeps its samples in the capture
+// buffer with the CAN frames, with the network "gps", timestamped on
+// reception. The decoded exports of the capture have them as the message
+// "GPS", with the signals Latitude, Longitude, Altitude, Speed, Course and the
+// IMU values the receiver reports, so the signals of a drive can be mapped. The
+// receiver is reconnected when it is lost, until StopGPS.
+func (a *App) StartGPS(opts GPSOptions) (GPSStatus, error) {
+	opts.Source, opts.Interface = strings.TrimSpace(opts.Source), strings.TrimSpace(opts.Interface)
+	if opts.Source == "" {
+		return GPSStatus{}, errors.New("GPS source is empty")
+	}
+	if opts.Interface == "" {
+		opts.Interface = defaultGPSInterface
+	}
+
+	a.gpsMu.Lock()
+	defer a.gpsMu.Unlock()
+	if a.gps != nil {
+		return GPSStatus{}, fmt.Errorf("GPS already reading %s", a.gps.source)
+	}
+	src, err := gps.Open(opts.Source)
+	if err != nil {
+		return GPSStatus{}, fmt.Errorf("GPS %s: %w", opts.Source, err)
+	}
+	r := &gpsReceiver{
+		source: opts.Source,
+		iface:  opts.Interface,
+		done:   make(chan struct{}),
+		src:    src,
+		status: GPSStatus{Running: true, Connected: true, Source: opts.Source, Interface: opts.Interface},
+	}
+	r.ctx, r.stop = context.WithCancel(context.Background())
+	a.gps = r
+	go a.gpsLoop(r, src)
+	return r.snapshot(), nil
+}
+
+// StopGPS disconnects the GPS receiver. Its samples stay in the capture buffer.
+func (a *App) StopGPS() (GPSStatus, error) {
+	a.gpsMu.Lock()
+	r := a.gps
+	a.gps = nil
+	a.gpsMu.Unlock()
+	if r == nil {
+		return GPSStatus{}, errors.New("GPS not started")
+	}
+	r.mu.Lock()
+	r.stop()
+	_ = r.src.Close()
+	r.mu.Unlock()
+	<-r.done
+	r.count(func(s *GPSStatus) { s.Running, s.Connected = false, false })
+	return r.snapshot(), nil
+}
+
+// GetGPSStatus returns the state of the GPS receiver.
+func (a *App) GetGPSStatus() GPSStatus {
+	a.gpsMu.Lock()
+	defer a.gpsMu.Unlock()
+	if a.gps == nil {
+		return GPSStatus{}
+	}
+	return a.gps.snapshot()
+}
+
+// gpsLoop reads the samples of the receiver and reconnects it with backoff
+// until StopGPS.
+func (a *App) gpsLoop(r *gpsReceiver, src gps.Source) {
+	defer close(r.done)
+	for {
+		err := a.readGPS(r, src)
+		if r.ctx.Err() != nil {
+			return
+		}
+		_ = src.Close()
+		r.count(func(s *GPSStatus) { s.Connected, s.Error = false, err.Error() })
+		a.emit("gps:status", r.snapshot())
+
+		for backoff := gpsMinBackoff; ; backoff = min(2*backoff, gpsMaxBackoff) {
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if src, err = gps.Open(r.source); err == nil {
+				break
+			}
+			r.count(func(s *GPSStatus) { s.Error = err.Error() })
+		}
+		r.mu.Lock()
+		if r.ctx.Err() != nil {
+			// StopGPS ran while connecting and did not see src
+			r.mu.Unlock()
+			_ = src.Close()
+			return
+		}
+		r.src = src
+		r.mu.Unlock()
+		r.count(func(s *GPSStatus) { s.Connected, s.Error = true, ""; s.Reconnects++ })
+		a.emit("gps:status", r.snapshot())
+	}
+}
+
+// readGPS keeps and emits the samples of src until it fails.
+func (a *App) readGPS(r *gpsReceiver, src gps.Source) error {
+	for {
+		s, err := src.Read()
+		if err != nil {
+			return err
+		}
+		ts := time.Now()
+		payload, err := s.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		a.capture.AddPacket(ts, r.iface, &canbus.Packet{Network: canbus.NetworkGPS, Payload: payload}, false)
+		ev := gpsSample(ts, r.iface, &s)
+		r.count(func(st *GPSStatus) { st.Samples++; st.Last = &ev })
+		a.emit("gps:sample", ev)
+	}
+}
+
+func (r *gpsReceiver) count(update func(*GPSStatus)) {
+	r.mu.Lock()
+	update(&r.status)
+	r.mu.Unlock()
+}
+
+func (r *gpsReceiver) snapshot() GPSStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+func gpsSample(ts time.Time, iface string, s *gps.Sample) GPSSample {
+	ev := GPSSample{Timestamp: ts, Interface: iface}
+	if !s.Time.IsZero() {
+		t := s.Time
+		ev.GPSTime = &t
+	}
+	if p := s.Position; p != nil {
+		ev.Position = &GPSPosition{
+			Fix:        p.Fix.String(),
+			Latitude:   known(p.Latitude),
+			Longitude:  known(p.Longitude),
+			Altitude:   known(p.Altitude),
+			Speed:      known(p.Speed),
+			Course:     known(p.Course),
+			Satellites: p.Satellites,
+			HDOP:       known(p.HDOP),
+		}
+	}
+	if m := s.Motion; m != nil {
+		ev.Motion = &GPSMotion{
+			Heading: known(m.Heading),
+			Pitch:   known(m.Pitch),
+			Roll:    known(m.Roll),
+			AccX:    known(m.AccX),
+			AccY:    known(m.AccY),
+			AccZ:    known(m.AccZ),
+			GyroX:   known(m.GyroX),
+			GyroY:   known(m.GyroY),
+			GyroZ:   known(m.GyroZ),
+		}
+	}
+	return ev
+}
+
+// known is &v, nil for NaN.
+func known(v float64) *float64 {
+	if math.IsNaN(v) {
+		return nil
+	}
+	return &v
+}
+
+// decodeGPS returns the values of an encoded GPS sample as the signals of the
+// message "GPS"; the unknown ones are left out.
+func decodeGPS(payload []byte) (string, []candb.Value) {
+	var s gps.Sample
+	if s.UnmarshalBinary(payload) != nil {
+		return "", nil
+	}
+	var values []candb.Value
+	add := func(name string, v float64, unit, label string) {
+		if !math.IsNaN(v) {
+			values = append(values, candb.Value{Name: name, Raw: v, Physical: v, Unit: unit, Label: label})
+		}
+	}
+	if p := s.Position; p != nil {
+		add("Fix", float64(p.Fix), "", p.Fix.String())
+		if p.Fix != gps.FixNone {
+			add("Latitude", p.Latitude, "deg", "")
+			add("Longitude", p.Longitude, "deg", "")
+			add("Altitude", p.Altitude, "m", "")
+			add("Speed", p.Speed, "m/s", "")
+			add("Course", p.Course, "deg", "")
+		}
+		add("Satellites", float64(p.Satellites), "", "")
+		add("HDOP", p.HDOP, "", "")
+	}
+	if m := s.Motion; m != nil {
+		add("Heading", m.Heading, "deg", "")
+		add("Pitch", m.Pitch, "deg", "")
+		add("Roll", m.Roll, "deg", "")
+		add("AccX", m.AccX, "m/s²", "")
+		add("AccY", m.AccY, "m/s²", "")
+		add("AccZ", m.AccZ, "m/s²", "")
+		add("GyroX", m.GyroX, "deg/s", "")
+		add("GyroY", m.GyroY, "deg/s", "")
+		add("GyroZ", m.GyroZ, "deg/s", "")
+	}
+	return gpsMessage, values
+}
@@ -0,0 +1,225 @@
+// Package gps reads the position and the motion of a vehicle from an external
+// receiver, the NMEA 0183 sentences of a serial or TCP GPS receiver or the
+// reports of gpsd, so they can be timestamped with the CAN traffic of drive
+// logs.
+package gps
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+const (
+	// DefaultBaudRate is the baud rate of the serial receivers, the one of
+	// NMEA 0183.
+	DefaultBaudRate = 4800
+	// DefaultGPSDAddress is the address of gpsd in a gpsd URL without host.
+	DefaultGPSDAddress = "localhost:2947"
+
+	dialTimeout = 5 * time.Second
+)
+
+// Fix is the quality of a position.
+type Fix uint8
+
+// Fixes of the positions.
+const (
+	FixNone Fix = iota
+	Fix2D
+	Fix3D
+)
+
+func (f Fix) String() string {
+	switch f {
+	case Fix2D:
+		return "2d"
+	case Fix3D:
+		return "3d"
+	}
+	return "none"
+}
+
+// Position is a position report. The values the receiver does not know are
+// NaN.
+type Position struct {
+	Fix Fix
+	// Latitude and Longitude are in degrees, north and east positive.
+	Latitude  float64
+	Longitude float64
+	// Altitude is in m above the mean sea level.
+	Altitude float64
+	// Speed is the speed over ground in m/s, Course the track in degrees from
+	// the true north.
+	Speed  float64
+	Course float64
+	// Satellites is the number of satellites used, HDOP the horizontal
+	// dilution of precision.
+	Satellites int
+	HDOP       float64
+}
+
+// Motion is an attitude or inertial report of an IMU. The values the receiver
+// does not know are NaN.
+type Motion struct {
+	// Heading, Pitch and Roll are in degrees.
+	Heading float64
+	Pitch   float64
+	Roll    float64
+	// AccX, AccY and AccZ are the accelerations in m/s², GyroX, GyroY and
+	// GyroZ the angular rates in degrees/s.
+	AccX  float64
+	AccY  float64
+	AccZ  float64
+	GyroX float64
+	GyroY float64
+	GyroZ float64
+}
+
+// Sample is a report of the receiver, a position or a motion.
+type Sample struct {
+	// Time is the time of the report by the receiver, zero when unknown.
+	Time     time.Time
+	Position *Position
+	Motion   *Motion
+}
+
+// Source reads the samples of a receiver.
+type Source interface {
+	// Read returns the next sample. It fails when the receiver is lost or the
+	// source closed.
+	Read() (Sample, error)
+	Close() error
+}
+
+// Open opens the receiver of rawURL:
+//
+//	nmea:///dev/ttyACM0?baud=9600   NMEA 0183 sentences of a serial receiver
+//	nmea+tcp://phone:10110          NMEA 0183 sentences of a TCP server
+//	gpsd://localhost:2947           gpsd, the default address when the host is empty
+func Open(rawURL string) (Source, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "nmea":
+		port := u.Host + u.Path
+		if port == "" {
+			return nil, fmt.Errorf("invalid GPS URL %q, want nmea:///dev/ttyACM0?baud=9600", rawURL)
+		}
+		baud := DefaultBaudRate
+		if v := u.Query().Get("baud"); v != "" {
+			if baud, err = strconv.Atoi(v); err != nil || baud <= 0 {
+				return nil, fmt.Errorf("invalid baud rate in GPS URL %q", rawURL)
+			}
+		}
+		p, err := serial.Open(port, &serial.Mode{BaudRate: baud})
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", port, err)
+		}
+		return NewNMEASource(p), nil
+	case "nmea+tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid GPS URL %q, want nmea+tcp://host:port", rawURL)
+		}
+		c, err := net.DialTimeout("tcp", u.Host, dialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return NewNMEASource(c), nil
+	case "gpsd":
+		addr := u.Host
+		if addr == "" {
+			addr = DefaultGPSDAddress
+		} else if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(addr, "2947")
+		}
+		return DialGPSD(addr)
+	}
+	return nil, fmt.Errorf("unknown GPS URL scheme %q, want nmea, nmea+tcp or gpsd", u.Scheme)
+}
+
+// Sample encodings, the first byte of MarshalBinary.
+const (
+	flagPosition = 0x01
+	flagMotion   = 0x02
+)
+
+// MarshalBinary encodes s in a compact form for the capture buffer: a flags
+// byte, the time in Unix nanoseconds and the values of the position and of the
+// motion, little endian.
+func (s *Sample) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 9, 9+2+6*8+9*8)
+	var ns int64
+	if !s.Time.IsZero() {
+		ns = s.Time.UnixNano()
+	}
+	binary.LittleEndian.PutUint64(b[1:9], uint64(ns))
+	if p := s.Position; p != nil {
+		b[0] |= flagPosition
+		b = append(b, byte(p.Fix), byte(min(max(p.Satellites, 0), 255)))
+		b = appendFloats(b, p.Latitude, p.Longitude, p.Altitude, p.Speed, p.Course, p.HDOP)
+	}
+	if m := s.Motion; m != nil {
+		b[0] |= flagMotion
+		b = appendFloats(b, m.Heading, m.Pitch, m.Roll, m.AccX, m.AccY, m.AccZ, m.GyroX, m.GyroY, m.GyroZ)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary decodes a sample encoded by MarshalBinary.
+func (s *Sample) UnmarshalBinary(b []byte) error {
+	errShort := errors.New("truncated GPS sample")
+	if len(b) < 9 {
+		return errShort
+	}
+	*s = Sample{}
+	flags := b[0]
+	if ns := int64(binary.LittleEndian.Uint64(b[1:9])); ns != 0 {
+		s.Time = time.Unix(0, ns).UTC()
+	}
+	b = b[9:]
+	if flags&flagPosition != 0 {
+		if len(b) < 2+6*8 {
+			return errShort
+		}
+		p := &Position{Fix: Fix(b[0]), Satellites: int(b[1])}
+		b = readFloats(b[2:], &p.Latitude, &p.Longitude, &p.Altitude, &p.Speed, &p.Course, &p.HDOP)
+		s.Position = p
+	}
+	if flags&flagMotion != 0 {
+		if len(b) < 9*8 {
+			return errShort
+		}
+		m := &Motion{}
+		b = readFloats(b, &m.Heading, &m.Pitch, &m.Roll, &m.AccX, &m.AccY, &m.AccZ, &m.GyroX, &m.GyroY, &m.GyroZ)
+		s.Motion = m
+	}
+	if len(b) != 0 {
+		return fmt.Errorf("GPS sample with %d trailing bytes", len(b))
+	}
+	return nil
+}
+
+func appendFloats(b []byte, values ...float64) []byte {
+	for _, v := range values {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	}
+	return b
+}
+
+func readFloats(b []byte, values ...*float64) []byte {
+	for _, v := range values {
+		*v = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		b = b[8:]
+	}
+	return b
+}
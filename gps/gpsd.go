@@ -0,0 +1,158 @@
+package gps
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"time"
+)
+
+// gpsdWatch asks gpsd to stream its reports as JSON.
+const gpsdWatch = `?WATCH={"enable":true,"json":true}` + "\n"
+
+// gpsdReport holds the fields of the TPV, SKY, ATT and IMU reports used.
+type gpsdReport struct {
+	Class string `json:"class"`
+	Time  string `json:"time"`
+	// TPV
+	Mode   int      `json:"mode"`
+	Lat    *float64 `json:"lat"`
+	Lon    *float64 `json:"lon"`
+	Alt    *float64 `json:"alt"`
+	AltMSL *float64 `json:"altMSL"`
+	Speed  *float64 `json:"speed"`
+	Track  *float64 `json:"track"`
+	// SKY
+	HDOP       *float64 `json:"hdop"`
+	USat       *int     `json:"uSat"`
+	Satellites []struct {
+		Used bool `json:"used"`
+	} `json:"satellites"`
+	// ATT and IMU
+	Heading *float64 `json:"heading"`
+	Pitch   *float64 `json:"pitch"`
+	Roll    *float64 `json:"roll"`
+	AccX    *float64 `json:"acc_x"`
+	AccY    *float64 `json:"acc_y"`
+	AccZ    *float64 `json:"acc_z"`
+	GyroX   *float64 `json:"gyro_x"`
+	GyroY   *float64 `json:"gyro_y"`
+	GyroZ   *float64 `json:"gyro_z"`
+}
+
+// GPSD is a client of gpsd. It returns a position per TPV report, with the
+// satellites and the HDOP of the last SKY report, and a motion per ATT or IMU
+// report.
+type GPSD struct {
+	conn net.Conn
+	sc   *bufio.Scanner
+
+	satellites int
+	hdop       float64
+}
+
+// DialGPSD connects to the gpsd of addr, eg "localhost:2947", and starts its
+// reports.
+func DialGPSD(addr string) (*GPSD, error) {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(dialTimeout))
+	if _, err := conn.Write([]byte(gpsdWatch)); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("gpsd %s: %w", addr, err)
+	}
+	_ = conn.SetWriteDeadline(time.Time{})
+	return &GPSD{conn: conn, sc: bufio.NewScanner(conn), hdop: math.NaN()}, nil
+}
+
+// Read returns the next report.
+func (g *GPSD) Read() (Sample, error) {
+	for g.sc.Scan() {
+		var r gpsdReport
+		if json.Unmarshal(g.sc.Bytes(), &r) != nil {
+			continue
+		}
+		if s, ok := g.sample(&r); ok {
+			return s, nil
+		}
+	}
+	if err := g.sc.Err(); err != nil {
+		return Sample{}, err
+	}
+	return Sample{}, errors.New("gpsd closed the connection")
+}
+
+func (g *GPSD) sample(r *gpsdReport) (Sample, bool) {
+	var s Sample
+	if t, err := time.Parse(time.RFC3339Nano, r.Time); err == nil {
+		s.Time = t
+	}
+	switch r.Class {
+	case "SKY":
+		g.hdop = value(r.HDOP)
+		if r.USat != nil {
+			g.satellites = *r.USat
+		} else if r.Satellites != nil {
+			g.satellites = 0
+			for _, sat := range r.Satellites {
+				if sat.Used {
+					g.satellites++
+				}
+			}
+		}
+		return s, false
+	case "TPV":
+		// mode 0 is unknown, 1 no fix
+		fix := FixNone
+		if r.Mode >= 2 {
+			fix = Fix(r.Mode - 1)
+		}
+		alt := r.AltMSL
+		if alt == nil {
+			alt = r.Alt
+		}
+		s.Position = &Position{
+			Fix:        fix,
+			Latitude:   value(r.Lat),
+			Longitude:  value(r.Lon),
+			Altitude:   value(alt),
+			Speed:      value(r.Speed),
+			Course:     value(r.Track),
+			Satellites: g.satellites,
+			HDOP:       g.hdop,
+		}
+		return s, true
+	case "ATT", "IMU":
+		s.Motion = &Motion{
+			Heading: value(r.Heading),
+			Pitch:   value(r.Pitch),
+			Roll:    value(r.Roll),
+			AccX:    value(r.AccX),
+			AccY:    value(r.AccY),
+			AccZ:    value(r.AccZ),
+			GyroX:   value(r.GyroX),
+			GyroY:   value(r.GyroY),
+			GyroZ:   value(r.GyroZ),
+		}
+		return s, true
+	}
+	return s, false
+}
+
+// Close closes the connection, Read fails.
+func (g *GPSD) Close() error {
+	return g.conn.Close()
+}
+
+// value is *v, NaN when the report lacks it.
+func value(v *float64) float64 {
+	if v == nil {
+		return math.NaN()
+	}
+	return *v
+}
@@ -0,0 +1,169 @@
+package gps
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// knot is a knot in m/s.
+const knot = 1852.0 / 3600
+
+// NMEA assembles the positions of NMEA 0183 sentences of any talker: a sample
+// per RMC sentence, completed with the altitude, the satellites and the HDOP of
+// the GGA sentence of the same time. The other sentences are ignored.
+type NMEA struct {
+	gga     Position
+	ggaTime string
+}
+
+// Parse parses a sentence, eg "$GPRMC,...*hh", and returns the sample it
+// completes. The sentences without checksum are accepted, a wrong checksum
+// fails.
+func (n *NMEA) Parse(line string) (Sample, bool, error) {
+	fields, err := nmeaFields(strings.TrimSpace(line))
+	if err != nil || len(fields[0]) < 3 {
+		return Sample{}, false, err
+	}
+	switch fields[0][len(fields[0])-3:] {
+	case "GGA":
+		return Sample{}, false, n.parseGGA(fields)
+	case "RMC":
+		return n.parseRMC(fields)
+	}
+	return Sample{}, false, nil
+}
+
+// parseGGA keeps the fix of
+//
+//	$GPGGA,hhmmss.ss,llll.ll,a,yyyyy.yy,a,q,nn,h.h,a.a,M,g.g,M,,*hh
+func (n *NMEA) parseGGA(f []string) error {
+	if len(f) < 10 {
+		return fmt.Errorf("GGA sentence with %d fields", len(f))
+	}
+	q, _ := strconv.Atoi(f[6])
+	sats, _ := strconv.Atoi(f[7])
+	n.ggaTime = f[1]
+	n.gga = Position{Satellites: sats, HDOP: nmeaFloat(f[8]), Altitude: nmeaFloat(f[9])}
+	if q == 0 {
+		n.gga.Fix = FixNone
+	} else if math.IsNaN(n.gga.Altitude) {
+		n.gga.Fix = Fix2D
+	} else {
+		n.gga.Fix = Fix3D
+	}
+	return nil
+}
+
+// parseRMC returns the sample of
+//
+//	$GPRMC,hhmmss.ss,A,llll.ll,a,yyyyy.yy,a,x.x,x.x,ddmmyy,x.x,a*hh
+func (n *NMEA) parseRMC(f []string) (Sample, bool, error) {
+	if len(f) < 10 {
+		return Sample{}, false, fmt.Errorf("RMC sentence with %d fields", len(f))
+	}
+	p := &Position{
+		Latitude:  nmeaCoordinate(f[3], f[4], "S"),
+		Longitude: nmeaCoordinate(f[5], f[6], "W"),
+		Altitude:  math.NaN(),
+		Speed:     nmeaFloat(f[7]) * knot,
+		Course:    nmeaFloat(f[8]),
+		HDOP:      math.NaN(),
+	}
+	if f[2] == "A" {
+		p.Fix = Fix2D
+	}
+	if n.ggaTime != "" && n.ggaTime == f[1] {
+		if p.Fix != FixNone {
+			p.Fix = max(p.Fix, n.gga.Fix)
+		}
+		p.Altitude, p.Satellites, p.HDOP = n.gga.Altitude, n.gga.Satellites, n.gga.HDOP
+	}
+	s := Sample{Position: p}
+	if t, err := time.Parse("020106 150405.999999999", f[9]+" "+f[1]); err == nil {
+		s.Time = t
+	}
+	return s, true, nil
+}
+
+// nmeaFields splits a sentence after checking its checksum.
+func nmeaFields(line string) ([]string, error) {
+	if !strings.HasPrefix(line, "$") && !strings.HasPrefix(line, "!") {
+		return nil, fmt.Errorf("invalid NMEA sentence %q", line)
+	}
+	body := line[1:]
+	if i := strings.LastIndexByte(body, '*'); i >= 0 {
+		want, err := strconv.ParseUint(body[i+1:], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NMEA checksum in %q", line)
+		}
+		body = body[:i]
+		var sum byte
+		for j := 0; j < len(body); j++ {
+			sum ^= body[j]
+		}
+		if sum != byte(want) {
+			return nil, fmt.Errorf("NMEA checksum %02X of %q, want %02X", sum, line, want)
+		}
+	}
+	return strings.Split(body, ","), nil
+}
+
+func nmeaFloat(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return v
+}
+
+// nmeaCoordinate converts a (d)ddmm.mmmm coordinate to degrees, negative in
+// the hemisphere neg.
+func nmeaCoordinate(s, hemisphere, neg string) float64 {
+	v := nmeaFloat(s)
+	if math.IsNaN(v) {
+		return v
+	}
+	deg := math.Trunc(v / 100)
+	deg += (v - deg*100) / 60
+	if hemisphere == neg {
+		deg = -deg
+	}
+	return deg
+}
+
+// NMEASource reads the NMEA 0183 sentences of a connection to a receiver.
+type NMEASource struct {
+	rc   io.ReadCloser
+	sc   *bufio.Scanner
+	nmea NMEA
+}
+
+// NewNMEASource returns the source of the sentences read from rc.
+func NewNMEASource(rc io.ReadCloser) *NMEASource {
+	return &NMEASource{rc: rc, sc: bufio.NewScanner(rc)}
+}
+
+// Read returns the next position; the invalid sentences are skipped, the
+// receivers send some when they start.
+func (s *NMEASource) Read() (Sample, error) {
+	for s.sc.Scan() {
+		if sample, ok, err := s.nmea.Parse(s.sc.Text()); err == nil && ok {
+			return sample, nil
+		}
+	}
+	if err := s.sc.Err(); err != nil {
+		return Sample{}, err
+	}
+	return Sample{}, errors.New("NMEA receiver closed")
+}
+
+// Close closes the connection, Read fails.
+func (s *NMEASource) Close() error {
+	return s.rc.Close()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"canproject/canbus"
+)
+
+// FrameGroup is a named set of CAN IDs, eg "powertrain" for 0x100-0x1FF. Every
+// frame emitted, buffered, exported and counted carries the name of its group.
+type FrameGroup struct {
+	Name string `json:"name"`
+	// Color is the CSS color the frontend shows the frames of the group with.
+	Color  string    `json:"color"`
+	Ranges []IDRange `json:"ranges"`
+}
+
+// IDRange is a range of CAN IDs, bounds included.
+type IDRange struct {
+	From     uint32 `json:"from"`
+	To       uint32 `json:"to"`
+	Extended bool   `json:"extended"`
+}
+
+// CANGroupStats are the counters of the IDs of a frame group in a "can:stats" event.
+type CANGroupStats struct {
+	Group string  `json:"group"`
+	Count uint64  `json:"count"`
+	Bytes uint64  `json:"bytes"`
+	Rate  float64 `json:"rate"`
+}
+
+// SetFrameGroups replaces the frame groups. A frame belongs to the first group
+// with a range containing its ID, to none when no range does.
+func (a *App) SetFrameGroups(groups []FrameGroup) error {
+	names := make(map[string]bool, len(groups))
+	gs := make([]FrameGroup, len(groups))
+	for i, g := range groups {
+		g.Name = strings.TrimSpace(g.Name)
+		g.Color = strings.TrimSpace(g.Color)
+		if g.Name == "" {
+			return fmt.Errorf("frame group %d has no name", i+1)
+		}
+		if names[g.Name] {
+			return fmt.Errorf("duplicate frame group %s", g.Name)
+		}
+		names[g.Name] = true
+		if len(g.Ranges) == 0 {
+			return fmt.Errorf("frame group %s has no ID range", g.Name)
+		}
+		for _, r := range g.Ranges {
+			if r.From > r.To {
+				return fmt.Errorf("frame group %s: range 0x%X-0x%X is empty", g.Name, r.From, r.To)
+			}
+			if err := (&canbus.Frame{ID: r.To, IsExtended: r.Extended}).Validate(); err != nil {
+				return fmt.Errorf("frame group %s: %w", g.Name, err)
+			}
+		}
+		g.Ranges = append([]IDRange(nil), g.Ranges...)
+		gs[i] = g
+	}
+	a.groups.Store(&gs)
+	return nil
+}
+
+// GetFrameGroups returns the frame groups set with SetFrameGroups.
+func (a *App) GetFrameGroups() []FrameGroup {
+	gs := a.groups.Load()
+	if gs == nil {
+		return []FrameGroup{}
+	}
+	return append([]FrameGroup{}, *gs...)
+}
+
+// frameGroup returns the name of the group of a CAN ID, empty if none.
+func (a *App) frameGroup(id uint32, extended bool) string {
+	gs := a.groups.Load()
+	if gs == nil {
+		return ""
+	}
+	for _, g := range *gs {
+		for _, r := range g.Ranges {
+			if r.Extended == extended && id >= r.From && id <= r.To {
+				return g.Name
+			}
+		}
+	}
+	return ""
+}
+
+// groupStats sums the counters of the IDs of s by group, in the order of the groups.
+func (a *App) groupStats(s *CANStats) []CANGroupStats {
+	gs := a.groups.Load()
+	if gs == nil {
+		return []CANGroupStats{}
+	}
+	stats := make([]CANGroupStats, len(*gs))
+	index := make(map[string]int, len(*gs))
+	for i, g := range *gs {
+		stats[i].Group = g.Name
+		index[g.Name] = i
+	}
+	for i := range s.IDs {
+		id := &s.IDs[i]
+		id.Group = a.frameGroup(id.ID, id.Extended)
+		if j, ok := index[id.Group]; ok {
+			stats[j].Count += id.Count
+			stats[j].Bytes += id.Bytes
+			stats[j].Rate += id.Rate
+		}
+	}
+	return stats
+}
@@ -0,0 +1,437 @@
+// Package gsusb drives candleLight and other USB CAN adapters implementing the
+// gs_usb protocol (the one of the Linux gs_usb driver) from user space, so they
+// can be used where SocketCAN is not available, eg on Windows and macOS.
+//
+// The USB access uses gousb, which needs libusb and cgo; it is compiled in with
+// the gsusb build tag. Without it Dial reports that the backend is not available.
+package gsusb
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"canproject/canbus"
+)
+
+// USB IDs of the adapters known to speak gs_usb.
+var KnownDevices = []struct{ Vendor, Product uint16 }{
+	{0x1d50, 0x606f}, // candleLight, CANable with candleLight firmware
+	{0x1209, 0x2323}, // Candle USB2CAN
+	{0x1cd2, 0x606f}, // CES CANext FD
+	{0x16d0, 0x10b8}, // ABE CANdebugger FD
+	{0x16d0, 0x0f30}, // Xylanta SAINT3
+}
+
+// gs_usb control requests.
+const (
+	requestHostFormat     = 0
+	requestBitTiming      = 1
+	requestMode           = 2
+	requestBitTimingConst = 4
+	requestDeviceConfig   = 5
+)
+
+const (
+	hostFormat = 0x0000beef
+
+	modeReset = 0
+	modeStart = 1
+
+	flagListenOnly = 1 << 0
+
+	// rxEchoID marks received frames, other echo IDs are the echoes of sent frames.
+	rxEchoID = 0xffffffff
+	// hostFrameSize is the size of a classic struct gs_host_frame without timestamp.
+	hostFrameSize = 20
+
+	// DefaultBitrate is the CAN bitrate used when the URL does not set one.
+	DefaultBitrate = 500000
+	// DefaultSamplePoint is the sample point used for the bit timing, in percent.
+	DefaultSamplePoint = 87.5
+
+	receiveBacklog = 1024
+	controlTimeout = time.Second
+)
+
+// can_id flags shared with SocketCAN.
+const (
+	idFlagExtended = 0x80000000
+	idFlagRemote   = 0x40000000
+	idFlagError    = 0x20000000
+	idMaskExtended = 0x1fffffff
+	idMaskStandard = 0x7ff
+)
+
+// ErrNotAvailable is returned by Dial when the backend was built without USB support.
+var ErrNotAvailable = errors.New("gs_usb support is not compiled in (build with -tags gsusb)")
+
+// Config describes how an adapter is opened.
+type Config struct {
+	// Device is the index of the adapter among the connected gs_usb adapters.
+	Device int
+	// Channel is the CAN channel of multi-channel adapters.
+	Channel int
+	Bitrate int
+	// SamplePoint is the sample point in percent, zero for DefaultSamplePoint.
+	SamplePoint float64
+	ListenOnly  bool
+}
+
+// IsURL reports whether name is a gs_usb URL such as "gsusb://0?bitrate=500000".
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "gsusb://")
+}
+
+// ParseURL decodes "gsusb://<device index>" with the optional query parameters
+// channel, bitrate, samplepoint and listen.
+func ParseURL(rawURL string) (Config, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Config{}, err
+	}
+	if u.Scheme != "gsusb" {
+		return Config{}, fmt.Errorf("invalid gs_usb URL %q, want gsusb://0?bitrate=500000", rawURL)
+	}
+	cfg := Config{Bitrate: DefaultBitrate, SamplePoint: DefaultSamplePoint}
+	if u.Host != "" {
+		if cfg.Device, err = strconv.Atoi(u.Host); err != nil || cfg.Device < 0 {
+			return Config{}, fmt.Errorf("invalid device index in gs_usb URL %q", rawURL)
+		}
+	}
+	q := u.Query()
+	for key, dst := range map[string]*int{"channel": &cfg.Channel, "bitrate": &cfg.Bitrate} {
+		if v := q.Get(key); v != "" {
+			if *dst, err = strconv.Atoi(v); err != nil {
+				return Config{}, fmt.Errorf("invalid %s in gs_usb URL %q", key, rawURL)
+			}
+		}
+	}
+	if v := q.Get("samplepoint"); v != "" {
+		if cfg.SamplePoint, err = strconv.ParseFloat(v, 64); err != nil {
+			return Config{}, fmt.Errorf("invalid samplepoint in gs_usb URL %q", rawURL)
+		}
+	}
+	if v := q.Get("listen"); v != "" {
+		if cfg.ListenOnly, err = strconv.ParseBool(v); err != nil {
+			return Config{}, fmt.Errorf("invalid listen in gs_usb URL %q", rawURL)
+		}
+	}
+	return cfg, nil
+}
+
+// usbDevice is the USB access the protocol needs, implemented with gousb.
+type usbDevice interface {
+	// controlIn and controlOut perform vendor requests on the interface.
+	controlIn(request uint8, value uint16, data []byte) (int, error)
+	controlOut(request uint8, value uint16, data []byte) (int, error)
+	readBulk(ctx context.Context, buf []byte) (int, error)
+	writeBulk(ctx context.Context, buf []byte) (int, error)
+	close() error
+}
+
+// BitTimingConst are the bit timing limits reported by the adapter.
+type BitTimingConst struct {
+	Features uint32
+	ClockHz  uint32
+	Tseg1Min uint32
+	Tseg1Max uint32
+	Tseg2Min uint32
+	Tseg2Max uint32
+	SJWMax   uint32
+	BRPMin   uint32
+	BRPMax   uint32
+	BRPInc   uint32
+}
+
+// BitTiming is a struct gs_device_bittiming.
+type BitTiming struct {
+	PropSeg   uint32
+	PhaseSeg1 uint32
+	PhaseSeg2 uint32
+	SJW       uint32
+	BRP       uint32
+}
+
+// CalcBitTiming returns the bit timing of bitrate closest to samplePoint (percent),
+// using the smallest prescaler that divides the clock exactly.
+func CalcBitTiming(c BitTimingConst, bitrate int, samplePoint float64) (BitTiming, error) {
+	if bitrate <= 0 {
+		return BitTiming{}, fmt.Errorf("gs_usb: invalid bitrate %d", bitrate)
+	}
+	if c.BRPInc == 0 {
+		c.BRPInc = 1
+	}
+	best, bestErr := BitTiming{}, -1.0
+	for brp := max(c.BRPMin, 1); brp <= c.BRPMax; brp += c.BRPInc {
+		if uint64(c.ClockHz)%(uint64(brp)*uint64(bitrate)) != 0 {
+			continue
+		}
+		tq := c.ClockHz / (brp * uint32(bitrate))
+		if tq < 1+c.Tseg1Min+c.Tseg2Min || tq > 1+c.Tseg1Max+c.Tseg2Max {
+			continue
+		}
+		tseg1 := uint32(float64(tq)*samplePoint/100+0.5) - 1
+		tseg1 = min(max(tseg1, c.Tseg1Min), c.Tseg1Max)
+		tseg2 := tq - 1 - tseg1
+		if tseg2 < c.Tseg2Min {
+			tseg2 = c.Tseg2Min
+			tseg1 = tq - 1 - tseg2
+		}
+		if tseg2 > c.Tseg2Max || tseg1 < c.Tseg1Min || tseg1 > c.Tseg1Max {
+			continue
+		}
+		sp := 100 * float64(1+tseg1) / float64(tq)
+		if e := abs(sp - samplePoint); bestErr < 0 || e < bestErr {
+			best = BitTiming{
+				PropSeg:   1,
+				PhaseSeg1: tseg1 - 1,
+				PhaseSeg2: tseg2,
+				SJW:       min(tseg2, max(c.SJWMax, 1)),
+				BRP:       brp,
+			}
+			bestErr = e
+		}
+	}
+	if bestErr < 0 {
+		return BitTiming{}, fmt.Errorf("gs_usb: no bit timing for %d bit/s with a %d Hz clock", bitrate, c.ClockHz)
+	}
+	return best, nil
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// Conn is an open channel of a gs_usb adapter. It implements canbus.Bus.
+type Conn struct {
+	dev     usbDevice
+	channel uint8
+
+	frames chan canbus.Frame
+	cancel context.CancelFunc
+	done   chan struct{}
+	once   sync.Once
+	closed atomic.Bool
+	echoID atomic.Uint32
+
+	mu      sync.Mutex
+	filters []canbus.Filter
+	readErr error
+}
+
+var _ canbus.Bus = (*Conn)(nil)
+
+// Dial opens the adapter and channel of cfg and starts the CAN controller.
+func Dial(cfg Config) (*Conn, error) {
+	dev, err := openDevice(cfg.Device)
+	if err != nil {
+		return nil, err
+	}
+	c, err := open(dev, cfg)
+	if err != nil {
+		_ = dev.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func open(dev usbDevice, cfg Config) (*Conn, error) {
+	le := binary.LittleEndian
+	if cfg.SamplePoint == 0 {
+		cfg.SamplePoint = DefaultSamplePoint
+	}
+	ch := uint16(cfg.Channel)
+
+	if _, err := dev.controlOut(requestHostFormat, 1, le.AppendUint32(nil, hostFormat)); err != nil {
+		return nil, fmt.Errorf("gs_usb: set host format: %w", err)
+	}
+	devCfg := make([]byte, 12)
+	if _, err := dev.controlIn(requestDeviceConfig, 1, devCfg); err != nil {
+		return nil, fmt.Errorf("gs_usb: read device config: %w", err)
+	}
+	// icount is the number of channels minus one
+	if channels := int(devCfg[3]) + 1; cfg.Channel < 0 || cfg.Channel >= channels {
+		return nil, fmt.Errorf("gs_usb: channel %d out of range, the adapter has %d", cfg.Channel, channels)
+	}
+
+	raw := make([]byte, 40)
+	if _, err := dev.controlIn(requestBitTimingConst, ch, raw); err != nil {
+		return nil, fmt.Errorf("gs_usb: read bit timing limits: %w", err)
+	}
+	var btc BitTimingConst
+	for i, dst := range []*uint32{&btc.Features, &btc.ClockHz, &btc.Tseg1Min, &btc.Tseg1Max, &btc.Tseg2Min,
+		&btc.Tseg2Max, &btc.SJWMax, &btc.BRPMin, &btc.BRPMax, &btc.BRPInc} {
+		*dst = le.Uint32(raw[4*i:])
+	}
+	bt, err := CalcBitTiming(btc, cfg.Bitrate, cfg.SamplePoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// reset the channel in case a previous session did not stop it
+	_, _ = dev.controlOut(requestMode, ch, le.AppendUint32(le.AppendUint32(nil, modeReset), 0))
+	var timing []byte
+	for _, v := range []uint32{bt.PropSeg, bt.PhaseSeg1, bt.PhaseSeg2, bt.SJW, bt.BRP} {
+		timing = le.AppendUint32(timing, v)
+	}
+	if _, err := dev.controlOut(requestBitTiming, ch, timing); err != nil {
+		return nil, fmt.Errorf("gs_usb: set bit timing: %w", err)
+	}
+	var flags uint32
+	if cfg.ListenOnly {
+		flags |= flagListenOnly
+	}
+	if _, err := dev.controlOut(requestMode, ch, le.AppendUint32(le.AppendUint32(nil, modeStart), flags)); err != nil {
+		return nil, fmt.Errorf("gs_usb: start channel: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Conn{
+		dev:     dev,
+		channel: uint8(cfg.Channel),
+		frames:  make(chan canbus.Frame, receiveBacklog),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(ctx)
+	return c, nil
+}
+
+func (c *Conn) readLoop(ctx context.Context) {
+	defer close(c.done)
+
+	buf := make([]byte, 512)
+	for {
+		n, err := c.dev.readBulk(ctx, buf)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.mu.Lock()
+				c.readErr = err
+				c.mu.Unlock()
+			}
+			return
+		}
+		for b := buf[:n]; len(b) >= hostFrameSize; b = b[hostFrameSize:] {
+			f, ok := c.decode(b[:hostFrameSize])
+			if !ok {
+				continue
+			}
+			select {
+			case c.frames <- f:
+			default:
+				// the application does not keep up, drop the frame
+			}
+		}
+	}
+}
+
+// decode converts a received struct gs_host_frame. Echoes of sent frames are skipped.
+func (c *Conn) decode(b []byte) (canbus.Frame, bool) {
+	le := binary.LittleEndian
+	if le.Uint32(b[0:4]) != rxEchoID || b[9] != c.channel {
+		return canbus.Frame{}, false
+	}
+	id := le.Uint32(b[4:8])
+	f := canbus.Frame{
+		IsExtended: id&idFlagExtended != 0,
+		IsRemote:   id&idFlagRemote != 0,
+		IsError:    id&idFlagError != 0,
+		Length:     min(b[8], canbus.MaxDataLength),
+	}
+	switch {
+	case f.IsError:
+		f.IsExtended = false
+		f.ID = id & idMaskExtended
+		f.Length = canbus.MaxDataLength
+	case f.IsExtended:
+		f.ID = id & idMaskExtended
+	default:
+		f.ID = id & idMaskStandard
+	}
+	copy(f.Data[:canbus.MaxDataLength], b[12:20])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return f, f.IsError || canbus.MatchAny(c.filters, &f)
+}
+
+// ReadFrame blocks until the next frame is received.
+func (c *Conn) ReadFrame() (canbus.Frame, error) {
+	select {
+	case f := <-c.frames:
+		return f, nil
+	case <-c.done:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.readErr != nil && !c.closed.Load() {
+			return canbus.Frame{}, fmt.Errorf("gs_usb: %w", c.readErr)
+		}
+		return canbus.Frame{}, net.ErrClosed
+	}
+}
+
+// WriteFrame transmits a classic frame.
+func (c *Conn) WriteFrame(ctx context.Context, f canbus.Frame) error {
+	if f.IsFD {
+		return errors.New("gs_usb: CAN FD frames are not supported")
+	}
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	if c.closed.Load() {
+		return net.ErrClosed
+	}
+	id := f.ID
+	if f.IsExtended {
+		id |= idFlagExtended
+	}
+	if f.IsRemote {
+		id |= idFlagRemote
+	}
+	le := binary.LittleEndian
+	b := make([]byte, hostFrameSize)
+	// echo IDs only need to differ from rxEchoID, the echoes are skipped
+	le.PutUint32(b[0:4], c.echoID.Add(1)%rxEchoID)
+	le.PutUint32(b[4:8], id)
+	b[8] = f.Length
+	b[9] = c.channel
+	copy(b[12:20], f.Data[:canbus.MaxDataLength])
+	if _, err := c.dev.writeBulk(ctx, b); err != nil {
+		return fmt.Errorf("gs_usb: write: %w", err)
+	}
+	return nil
+}
+
+// SetFilters filters the received frames on the host side.
+func (c *Conn) SetFilters(filters []canbus.Filter) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filters = append([]canbus.Filter{}, filters...)
+	return nil
+}
+
+// Close stops the CAN channel and releases the adapter.
+func (c *Conn) Close() error {
+	var err error
+	c.once.Do(func() {
+		c.closed.Store(true)
+		le := binary.LittleEndian
+		_, _ = c.dev.controlOut(requestMode, uint16(c.channel), le.AppendUint32(le.AppendUint32(nil, modeReset), 0))
+		c.cancel()
+		<-c.done
+		err = c.dev.close()
+	})
+	return err
+}
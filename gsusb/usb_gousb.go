@@ -0,0 +1,105 @@
+//go:build gsusb
+
+package gsusb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/gousb"
+)
+
+// Available reports whether the backend was built with USB support.
+const Available = true
+
+const (
+	interfaceNumber = 0
+	bulkIn          = 1
+	bulkOut         = 2
+)
+
+type gousbDevice struct {
+	ctx  *gousb.Context
+	dev  *gousb.Device
+	cfg  *gousb.Config
+	intf *gousb.Interface
+	in   *gousb.InEndpoint
+	out  *gousb.OutEndpoint
+}
+
+// openDevice opens the index-th connected gs_usb adapter.
+func openDevice(index int) (usbDevice, error) {
+	ctx := gousb.NewContext()
+	n := 0
+	devs, err := ctx.OpenDevices(func(desc *gousb.DeviceDesc) bool {
+		for _, d := range KnownDevices {
+			if uint16(desc.Vendor) == d.Vendor && uint16(desc.Product) == d.Product {
+				n++
+				return n-1 == index
+			}
+		}
+		return false
+	})
+	for i, d := range devs {
+		if i > 0 || err != nil {
+			_ = d.Close()
+		}
+	}
+	if err != nil {
+		_ = ctx.Close()
+		return nil, fmt.Errorf("gs_usb: open device: %w", err)
+	}
+	if len(devs) == 0 {
+		_ = ctx.Close()
+		return nil, fmt.Errorf("gs_usb: no adapter #%d (%d found)", index, n)
+	}
+
+	d := &gousbDevice{ctx: ctx, dev: devs[0]}
+	fail := func(op string, err error) (usbDevice, error) {
+		_ = d.close()
+		return nil, fmt.Errorf("gs_usb: %s: %w", op, err)
+	}
+	_ = d.dev.SetAutoDetach(true)
+	d.dev.ControlTimeout = controlTimeout
+	if d.cfg, err = d.dev.Config(1); err != nil {
+		return fail("select configuration", err)
+	}
+	if d.intf, err = d.cfg.Interface(interfaceNumber, 0); err != nil {
+		return fail("claim interface", err)
+	}
+	if d.in, err = d.intf.InEndpoint(bulkIn); err != nil {
+		return fail("open IN endpoint", err)
+	}
+	if d.out, err = d.intf.OutEndpoint(bulkOut); err != nil {
+		return fail("open OUT endpoint", err)
+	}
+	return d, nil
+}
+
+func (d *gousbDevice) controlIn(request uint8, value uint16, data []byte) (int, error) {
+	return d.dev.Control(gousb.ControlIn|gousb.ControlVendor|gousb.ControlInterface, request, value, interfaceNumber, data)
+}
+
+func (d *gousbDevice) controlOut(request uint8, value uint16, data []byte) (int, error) {
+	return d.dev.Control(gousb.ControlOut|gousb.ControlVendor|gousb.ControlInterface, request, value, interfaceNumber, data)
+}
+
+func (d *gousbDevice) readBulk(ctx context.Context, buf []byte) (int, error) {
+	return d.in.ReadContext(ctx, buf)
+}
+
+func (d *gousbDevice) writeBulk(ctx context.Context, buf []byte) (int, error) {
+	return d.out.WriteContext(ctx, buf)
+}
+
+func (d *gousbDevice) close() error {
+	if d.intf != nil {
+		d.intf.Close()
+	}
+	if d.cfg != nil {
+		_ = d.cfg.Close()
+	}
+	err := d.dev.Close()
+	_ = d.ctx.Close()
+	return err
+}
@@ -0,0 +1,10 @@
+//go:build !gsusb
+
+package gsusb
+
+// Available reports whether the backend was built with USB support.
+const Available = false
+
+func openDevice(int) (usbDevice, error) {
+	return nil, ErrNotAvailable
+}
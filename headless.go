@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"canproject/apiserver"
+)
+
+// headlessConfig configures the headless mode selected with -headless.
+type headlessConfig struct {
+	listen  string
+	token   string
+	origins []string
+	hosts   []string
+}
+
+// parseHeadless reports whether args select the headless mode, eg
+//
+//	canproject -headless -listen 127.0.0.1:8765 -token secret
+//
+// Other arguments are left to Wails.
+func parseHeadless(args []string) (headlessConfig, bool, error) {
+	fs := flag.NewFlagSet("canproject", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	headless := fs.Bool("headless", false, "run without the UI and serve the API over WebSocket")
+	listen := fs.String("listen", "127.0.0.1:8765", "address of the WebSocket API")
+	token := fs.String("token", "", "token the API clients must send")
+	origins := fs.String("origins", "", "comma separated browser origins allowed to connect, * for any")
+	hosts := fs.String("hosts", "", "comma separated host names accepted besides localhost and the server addresses")
+	err := fs.Parse(args)
+	if !*headless {
+		return headlessConfig{}, false, nil
+	}
+	if err != nil {
+		return headlessConfig{}, true, err
+	}
+	cfg := headlessConfig{listen: *listen, token: *token}
+	for _, o := range strings.Split(*origins, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			cfg.origins = append(cfg.origins, o)
+		}
+	}
+	for _, h := range strings.Split(*hosts, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			cfg.hosts = append(cfg.hosts, h)
+		}
+	}
+	return cfg, true, nil
+}
+
+// runHeadless runs the app without the UI until interrupted. The bound methods
+// are served over WebSocket on /ws and the events are pushed to the subscribed
+// clients, see package apiserver; the REST API of StartRESTServer is served too.
+func runHeadless(a *App, cfg headlessConfig) error {
+	srv := apiserver.New(a, apiserver.Options{Token: cfg.token, Origins: cfg.origins, Hosts: cfg.hosts})
+	a.addSink(srv)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	a.start(ctx)
+
+	mux := http.NewServeMux()
+	mux.Handle("/ws", srv)
+	mux.Handle("/", a.restHandler(RESTOptions{Token: cfg.token, Origins: cfg.origins, Hosts: cfg.hosts}))
+	httpSrv := &http.Server{Addr: cfg.listen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = httpSrv.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("serving the API on ws://%s/ws", cfg.listen)
+	err := httpSrv.ListenAndServe()
+	a.shutdown(ctx)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return fmt.Errorf("headless server: %w", err)
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+	"canproject/canlog"
+)
+
+// InputBridgeOptions configures the bridge started with StartInputBridge.
+type InputBridgeOptions struct {
+	// Path is the named pipe (created with mkfifo) or file to read, "-" for
+	// the standard input of the app.
+	Path string `json:"path"`
+	// Interface sends all the frames on this interface. When empty, the lines
+	// must name their interface as in candump logs.
+	Interface string `json:"interface"`
+}
+
+// InputBridgeStatus describes the input bridge, it is emitted on
+// "can:inputbridge" when the bridge stops by itself, at the end of its file or
+// of the standard input.
+type InputBridgeStatus struct {
+	Running   bool   `json:"running"`
+	Path      string `json:"path"`
+	Interface string `json:"interface"`
+	// Lines counts the frame lines read, Sent the frames queued for
+	// transmission and Errors the lines that could not be parsed or queued.
+	Lines  uint64 `json:"lines"`
+	Sent   uint64 `json:"sent"`
+	Errors uint64 `json:"errors"`
+	// Error is the last error, of a line or of the input.
+	Error string `json:"error,omitempty"`
+}
+
+type inputBridge struct {
+	iface string
+	f     *os.File
+	stdin bool
+	ctx   context.Context
+	stop  context.CancelFunc
+	done  chan struct{}
+
+	mu     sync.Mutex
+	status InputBridgeStatus
+}
+
+// StartInputBridge reads frames from a named pipe, a file or the standard input
+// and transmits them, so shell scripts and can-utils pipelines can drive the
+// bus through the app, eg
+//
+//	mkfifo /tmp/can-in
+//	candump -L can1 > /tmp/can-in
+//	echo "123#DEADBEEF" > /tmp/can-in
+//
+// A line is a candump log line, "(1700000000.000000) can0 123#DEADBEEF", or a
+// cansend frame, "can0 123#DEADBEEF" or "123#DEADBEEF"; blank lines and lines
+// starting with '#' are skipped. The frames go through the TX queue of their
+// interface, so its rate limit applies and they are logged and reported on
+// "can:tx"; the bridge waits while the queue is full. A named pipe stays open
+// when its writers close it, for the next ones.
+func (a *App) StartInputBridge(opts InputBridgeOptions) (InputBridgeStatus, error) {
+	opts.Path, opts.Interface = strings.TrimSpace(opts.Path), strings.TrimSpace(opts.Interface)
+	if opts.Path == "" {
+		return InputBridgeStatus{}, errors.New("input bridge without path")
+	}
+
+	a.bridgeMu.Lock()
+	defer a.bridgeMu.Unlock()
+	if a.bridge != nil {
+		return InputBridgeStatus{}, fmt.Errorf("input bridge already reading %s", a.bridge.snapshot().Path)
+	}
+	b := &inputBridge{
+		iface:  opts.Interface,
+		done:   make(chan struct{}),
+		status: InputBridgeStatus{Running: true, Path: opts.Path, Interface: opts.Interface},
+	}
+	if opts.Path == "-" {
+		b.f, b.stdin = os.Stdin, true
+		_ = os.Stdin.SetReadDeadline(time.Time{})
+	} else {
+		fi, err := os.Stat(opts.Path)
+		if err != nil {
+			return InputBridgeStatus{}, err
+		}
+		// a pipe opened for writing too never reads the end of file, and opening
+		// it does not wait for a writer
+		flag := os.O_RDONLY
+		if fi.Mode()&os.ModeNamedPipe != 0 {
+			flag = os.O_RDWR
+		}
+		if b.f, err = os.OpenFile(opts.Path, flag, 0); err != nil {
+			return InputBridgeStatus{}, err
+		}
+	}
+	b.ctx, b.stop = context.WithCancel(context.Background())
+	a.bridge = b
+	go a.inputLoop(b)
+	return b.snapshot(), nil
+}
+
+// StopInputBridge stops the input bridge. The frames it queued are still sent.
+func (a *App) StopInputBridge() (InputBridgeStatus, error) {
+	a.bridgeMu.Lock()
+	b := a.bridge
+	a.bridge = nil
+	a.bridgeMu.Unlock()
+	if b == nil {
+		return InputBridgeStatus{}, errors.New("input bridge not started")
+	}
+	b.stop()
+	if b.stdin {
+		// the standard input is left open for a next bridge; when it cannot
+		// be interrupted, the loop ends at the next line
+		if os.Stdin.SetReadDeadline(time.Now()) == nil {
+			<-b.done
+		}
+	} else {
+		_ = b.f.Close()
+		<-b.done
+	}
+	b.mu.Lock()
+	b.status.Running = false
+	b.mu.Unlock()
+	return b.snapshot(), nil
+}
+
+// GetInputBridgeStatus returns the state of the input bridge.
+func (a *App) GetInputBridgeStatus() InputBridgeStatus {
+	a.bridgeMu.Lock()
+	defer a.bridgeMu.Unlock()
+	if a.bridge == nil {
+		return InputBridgeStatus{}
+	}
+	return a.bridge.snapshot()
+}
+
+// inputLoop sends the frames of the lines of the bridge until its input ends or
+// the bridge is stopped.
+func (a *App) inputLoop(b *inputBridge) {
+	defer close(b.done)
+
+	sc := bufio.NewScanner(b.f)
+	for sc.Scan() {
+		if b.ctx.Err() != nil {
+			return
+		}
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		b.count(func(s *InputBridgeStatus) { s.Lines++ })
+		err := a.inputLine(b, line)
+		if b.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			err = fmt.Errorf("input bridge: %q: %w", line, err)
+			b.count(func(s *InputBridgeStatus) { s.Errors++; s.Error = err.Error() })
+			a.emitError(err)
+			continue
+		}
+		b.count(func(s *InputBridgeStatus) { s.Sent++ })
+	}
+	if b.ctx.Err() != nil {
+		return
+	}
+	// the input ended by itself
+	err := sc.Err()
+	b.count(func(s *InputBridgeStatus) {
+		s.Running = false
+		if err != nil {
+			s.Error = err.Error()
+		}
+	})
+	if !b.stdin {
+		_ = b.f.Close()
+	}
+	a.bridgeMu.Lock()
+	if a.bridge == b {
+		a.bridge = nil
+	}
+	a.bridgeMu.Unlock()
+	a.emit("can:inputbridge", b.snapshot())
+}
+
+// inputLine queues the frame of a line on its interface.
+func (a *App) inputLine(b *inputBridge, line string) error {
+	var iface, frame string
+	switch fields := strings.Fields(line); len(fields) {
+	case 1:
+		frame = fields[0]
+	case 2:
+		iface, frame = fields[0], fields[1]
+	default:
+		rec, err := canlog.ParseCandumpLine(line)
+		if err != nil {
+			return err
+		}
+		return a.inputFrame(b, rec.Interface, rec.Frame)
+	}
+	var f canbus.Frame
+	if err := f.UnmarshalString(frame); err != nil {
+		return err
+	}
+	return a.inputFrame(b, iface, f)
+}
+
+func (a *App) inputFrame(b *inputBridge, iface string, f canbus.Frame) error {
+	if b.iface != "" {
+		iface = b.iface
+	}
+	if iface == "" {
+		return errors.New("no interface, set one for the bridge")
+	}
+	if f.IsError {
+		return errors.New("error frames cannot be sent")
+	}
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	_, err := a.queueFrameWait(b.ctx, iface, f)
+	return err
+}
+
+func (b *inputBridge) count(update func(*InputBridgeStatus)) {
+	b.mu.Lock()
+	update(&b.status)
+	b.mu.Unlock()
+}
+
+func (b *inputBridge) snapshot() InputBridgeStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"canproject/canbus"
+)
+
+// CANInterfaceInfo describes a SocketCAN interface of the system.
+type CANInterfaceInfo struct {
+	Name string `json:"name"`
+	// State is "DOWN" for interfaces that are down, the controller state (eg "ERROR-ACTIVE",
+	// "BUS-OFF") for hardware interfaces that are up and "UP" for virtual ones.
+	State  string `json:"state"`
+	Up     bool   `json:"up"`
+	Kind   string `json:"kind"`
+	Driver string `json:"driver"`
+	// Bitrate and SamplePoint are zero for interfaces without a CAN controller.
+	Bitrate     uint32  `json:"bitrate"`
+	SamplePoint float64 `json:"samplePoint"`
+	DataBitrate uint32  `json:"dataBitrate"`
+	FD          bool    `json:"fd"`
+	// XL is true for the interfaces configured for CAN XL (mtu 76 to 2060).
+	// XLError tells why their CAN XL frames cannot be received, empty when
+	// they can.
+	XL        bool   `json:"xl"`
+	XLError   string `json:"xlError,omitempty"`
+	RestartMs uint32 `json:"restartMs"`
+	// Started is true when the interface is opened by the app.
+	Started bool `json:"started"`
+}
+
+// ListCANInterfaces returns the SocketCAN interfaces of the system (can0, vcan0, slcan0, ...)
+// ordered by name.
+func (a *App) ListCANInterfaces() ([]CANInterfaceInfo, error) {
+	links, err := canbus.Links()
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	infos := make([]CANInterfaceInfo, len(links))
+	for i, l := range links {
+		infos[i] = interfaceInfo(&l)
+		_, infos[i].Started = a.sessions[l.Name]
+	}
+	return infos, nil
+}
+
+func interfaceInfo(l *canbus.Link) CANInterfaceInfo {
+	info := CANInterfaceInfo{
+		Name:        l.Name,
+		State:       "DOWN",
+		Up:          l.Up,
+		Kind:        l.Kind,
+		Driver:      l.Driver,
+		Bitrate:     l.Bitrate,
+		SamplePoint: l.SamplePoint,
+		DataBitrate: l.DataBitrate,
+		FD:          l.FD(),
+		XL:          l.XL(),
+		RestartMs:   l.RestartMs,
+	}
+	if info.XL {
+		if err := canbus.CheckXL(); err != nil {
+			info.XLError = err.Error()
+		}
+	}
+	switch {
+	case !l.Up:
+	case l.HasController:
+		info.State = l.State.String()
+	default:
+		info.State = "UP"
+	}
+	return info
+}
+
+// ConfigureInterface programs the bit timing of a CAN controller like
+// "ip link set <iface> type can bitrate ... dbitrate ... sample-point ... restart-ms ..."
+// and brings the link up. dbitrate > 0 enables CAN FD, samplePoint is a fraction
+// (eg 0.875) or 0 for the driver default and restartMs 0 disables the automatic
+// bus-off restart. A started interface is stopped while it is configured and
+// started again afterwards. It requires CAP_NET_ADMIN.
+func (a *App) ConfigureInterface(iface string, bitrate uint32, dbitrate uint32, samplePoint float64, restartMs uint32) error {
+	iface = strings.TrimSpace(iface)
+	if samplePoint < 0 || samplePoint >= 1 {
+		return fmt.Errorf("sample point must be a fraction within 0..1 (got %g)", samplePoint)
+	}
+	cfg := canbus.LinkConfig{
+		Bitrate:     bitrate,
+		SamplePoint: samplePoint * 100,
+		DataBitrate: dbitrate,
+		RestartMs:   restartMs,
+	}
+	return a.reconfigure(iface, dbitrate > 0, func() error {
+		return canbus.ConfigureLink(iface, cfg)
+	})
+}
+
+// SetInterfaceUp brings an interface up or down like "ip link set <iface> up|down".
+// Bringing a started interface down stops it. It requires CAP_NET_ADMIN.
+func (a *App) SetInterfaceUp(iface string, up bool) error {
+	iface = strings.TrimSpace(iface)
+	if up {
+		return canbus.SetLinkUp(iface, true)
+	}
+	if err := a.StopCAN(iface); err != nil {
+		return err
+	}
+	return canbus.SetLinkUp(iface, false)
+}
+
+// reconfigure runs configure with iface stopped and starts it again afterwards
+// if it was started, with CAN FD as configured.
+func (a *App) reconfigure(iface string, fd bool, configure func() error) error {
+	a.mu.Lock()
+	_, started := a.sessions[iface]
+	a.mu.Unlock()
+
+	if started {
+		if err := a.StopCAN(iface); err != nil {
+			return err
+		}
+	}
+	if err := configure(); err != nil {
+		return err
+	}
+	if started {
+		return a.StartCANWithOptions(iface, CANOptions{FD: fd})
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/isotp"
+	"canproject/uds"
+)
+
+// isotpSendTimeout bounds a whole SendIsoTP call.
+const isotpSendTimeout = 10 * time.Second
+
+// IsoTPOptions configures an ISO-TP channel opened with OpenIsoTP.
+type IsoTPOptions struct {
+	// Extended selects 29-bit CAN IDs.
+	Extended bool `json:"extended"`
+	// Addressing is "normal" (the default), "extended" or "mixed". With the
+	// extended and mixed addressings the frames sent start with TxAddress (the
+	// target address or the address extension), the frames received with RxAddress.
+	Addressing string `json:"addressing,omitempty"`
+	TxAddress  uint8  `json:"txAddress"`
+	RxAddress  uint8  `json:"rxAddress"`
+	// FD sends CAN FD frames of FrameLength bytes (0 for 64), with bit rate
+	// switching when BRS is set.
+	FD          bool `json:"fd"`
+	BRS         bool `json:"brs"`
+	FrameLength int  `json:"frameLength"`
+	// BlockSize is the number of consecutive frames the peer may send per flow control, 0 for no limit.
+	BlockSize int `json:"blockSize"`
+	// STminUs is the separation time between consecutive frames requested from the peer, in microseconds.
+	STminUs int `json:"stminUs"`
+	// OverrideSTmin ignores the separation time requested by the peer and
+	// separates the consecutive frames sent by TxSTminUs instead.
+	OverrideSTmin bool `json:"overrideStmin"`
+	TxSTminUs     int  `json:"txStminUs"`
+	// MaxWaitFrames is the number of flow control wait frames accepted in a row (WFTmax), 0 for 10.
+	MaxWaitFrames int `json:"maxWaitFrames"`
+	// MaxMessageSize is the length of the largest message received, longer
+	// ones are refused with an overflow flow control, 0 for 1 MiB.
+	MaxMessageSize int `json:"maxMessageSize"`
+	// Padding fills every frame up to 8 bytes with PaddingByte.
+	Padding     bool  `json:"padding"`
+	PaddingByte uint8 `json:"paddingByte"`
+	// TimeoutMs is the flow control / consecutive frame timeout, 0 for 1000 ms.
+	TimeoutMs int `json:"timeoutMs"`
+}
+
+// IsoTPChannelInfo describes an open ISO-TP channel.
+type IsoTPChannelInfo struct {
+	Handle    int          `json:"handle"`
+	Interface string       `json:"interface"`
+	TxID      uint32       `json:"txId"`
+	RxID      uint32       `json:"rxId"`
+	Options   IsoTPOptions `json:"options"`
+	// Server is set for the channels opened with OpenIsoTPServer.
+	Server bool `json:"server"`
+}
+
+// IsoTPMessageEvent is a reassembled ISO-TP message emitted on "can:isotp".
+type IsoTPMessageEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Handle    int       `json:"handle"`
+	Interface string    `json:"interface"`
+	TxID      uint32    `json:"txId"`
+	RxID      uint32    `json:"rxId"`
+	Data      []uint32  `json:"data"`
+}
+
+type isotpChannel struct {
+	info IsoTPChannelInfo
+	ch   *isotp.Channel
+	// uds answers the diagnostic requests sent on the channel, nil for a server.
+	uds *uds.Client
+}
+
+// OpenIsoTP opens an ISO-TP channel on a started interface that sends on txID and
+// receives on rxID. Reassembled messages are emitted on "can:isotp".
+func (a *App) OpenIsoTP(iface string, txID uint32, rxID uint32, opts IsoTPOptions) (int, error) {
+	return a.openIsoTP(iface, txID, rxID, opts, false)
+}
+
+// openIsoTP opens a client channel, or a server channel answering the
+// messages it receives with serveIsoTP.
+func (a *App) openIsoTP(iface string, txID uint32, rxID uint32, opts IsoTPOptions, server bool) (int, error) {
+	iface = strings.TrimSpace(iface)
+	if _, err := a.txConn(iface, opts.FD); err != nil {
+		return 0, err
+	}
+	if opts.BlockSize < 0 || opts.BlockSize > 0xff {
+		return 0, fmt.Errorf("block size must be within 0..255 (got %d)", opts.BlockSize)
+	}
+	addressing, err := isotp.ParseAddressing(opts.Addressing)
+	if err != nil {
+		return 0, err
+	}
+	if n := opts.FrameLength; n != 0 && (!opts.FD || n < canbus.MaxDataLength || n > canbus.MaxFDDataLength || canbus.PaddedLength(n) != n) {
+		return 0, fmt.Errorf("invalid ISO-TP frame length %d, want a CAN FD length of 8..64 bytes", n)
+	}
+	if opts.STminUs < 0 || opts.TxSTminUs < 0 || opts.MaxWaitFrames < 0 || opts.TimeoutMs < 0 || opts.MaxMessageSize < 0 {
+		return 0, fmt.Errorf("ISO-TP times, wait frames and message size must be >= 0")
+	}
+
+	a.isotpMu.Lock()
+	defer a.isotpMu.Unlock()
+	for _, c := range a.isotpChannels {
+		o := c.ch.Config()
+		if c.info.Interface == iface && o.RxID == rxID && o.Extended == opts.Extended &&
+			(o.Addressing == isotp.AddressingNormal || addressing == isotp.AddressingNormal || o.RxAddress == opts.RxAddress) {
+			return 0, fmt.Errorf("an ISO-TP channel already receives on 0x%X on %s", rxID, iface)
+		}
+	}
+
+	a.nextIsoTP++
+	c := &isotpChannel{
+		info: IsoTPChannelInfo{
+			Handle:    a.nextIsoTP,
+			Interface: iface,
+			TxID:      txID,
+			RxID:      rxID,
+			Options:   opts,
+			Server:    server,
+		},
+	}
+	c.ch = isotp.NewChannel(isotp.Config{
+		TxID:           txID,
+		RxID:           rxID,
+		Extended:       opts.Extended,
+		Addressing:     addressing,
+		TxAddress:      opts.TxAddress,
+		RxAddress:      opts.RxAddress,
+		FD:             opts.FD,
+		BRS:            opts.BRS,
+		FrameLength:    opts.FrameLength,
+		BlockSize:      uint8(opts.BlockSize),
+		STmin:          time.Duration(opts.STminUs) * time.Microsecond,
+		OverrideSTmin:  opts.OverrideSTmin,
+		TxSTmin:        time.Duration(opts.TxSTminUs) * time.Microsecond,
+		MaxWaitFrames:  opts.MaxWaitFrames,
+		MaxMessageSize: opts.MaxMessageSize,
+		Padding:        opts.Padding,
+		PaddingByte:    opts.PaddingByte,
+		Timeout:        time.Duration(opts.TimeoutMs) * time.Millisecond,
+		OnMessage: func(data []byte) {
+			if server {
+				// the response waits for the flow control of the tester
+				go a.serveIsoTP(c, data)
+			} else {
+				c.uds.HandleMessage(data)
+			}
+			a.emit("can:isotp", IsoTPMessageEvent{
+				Timestamp: time.Now(),
+				Handle:    c.info.Handle,
+				Interface: iface,
+				TxID:      txID,
+				RxID:      rxID,
+				Data:      dataWords(data),
+			})
+		},
+		OnError: func(err error) {
+			a.emitError(fmt.Errorf("ISO-TP 0x%X/0x%X: %w", txID, rxID, err))
+		},
+	}, func(f canbus.Frame) error {
+		return a.transmit(iface, f)
+	})
+	if !server {
+		c.uds = uds.NewClient(c.ch)
+		c.uds.Observe(a.observeUDS(c.info.Handle))
+	}
+	a.isotpChannels[c.info.Handle] = c
+	return c.info.Handle, nil
+}
+
+// SendIsoTP sends a message on an ISO-TP channel, segmenting it as needed.
+// It returns once the last frame is sent.
+func (a *App) SendIsoTP(handle int, data []byte) error {
+	c, err := a.isotpChannel(handle)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), isotpSendTimeout)
+	defer cancel()
+	return c.ch.Send(ctx, data)
+}
+
+// CloseIsoTP closes a channel opened with OpenIsoTP.
+func (a *App) CloseIsoTP(handle int) error {
+	a.isotpMu.Lock()
+	defer a.isotpMu.Unlock()
+
+	if _, ok := a.isotpChannels[handle]; !ok {
+		return fmt.Errorf("no ISO-TP channel with handle %d", handle)
+	}
+	delete(a.isotpChannels, handle)
+	return nil
+}
+
+// ListIsoTPChannels returns the open ISO-TP channels ordered by handle.
+func (a *App) ListIsoTPChannels() []IsoTPChannelInfo {
+	a.isotpMu.Lock()
+	defer a.isotpMu.Unlock()
+
+	infos := make([]IsoTPChannelInfo, 0, len(a.isotpChannels))
+	for _, c := range a.isotpChannels {
+		infos = append(infos, c.info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Handle < infos[j].Handle
+	})
+	return infos
+}
+
+func (a *App) isotpChannel(handle int) (*isotpChannel, error) {
+	a.isotpMu.Lock()
+	defer a.isotpMu.Unlock()
+
+	c := a.isotpChannels[handle]
+	if c == nil {
+		return nil, fmt.Errorf("no ISO-TP channel with handle %d", handle)
+	}
+	return c, nil
+}
+
+// dispatchIsoTP hands a received frame to the ISO-TP channels of iface.
+func (a *App) dispatchIsoTP(iface string, f *canbus.Frame) {
+	a.isotpMu.Lock()
+	var channels []*isotp.Channel
+	for _, c := range a.isotpChannels {
+		if c.info.Interface == iface {
+			channels = append(channels, c.ch)
+		}
+	}
+	a.isotpMu.Unlock()
+
+	for _, ch := range channels {
+		if ch.HandleFrame(*f) {
+			return
+		}
+	}
+}
+
+// closeIsoTPChannels closes every ISO-TP channel on iface.
+func (a *App) closeIsoTPChannels(iface string) {
+	a.isotpMu.Lock()
+	defer a.isotpMu.Unlock()
+
+	for handle, c := range a.isotpChannels {
+		if c.info.Interface == iface {
+			delete(a.isotpChannels, handle)
+		}
+	}
+}
@@ -0,0 +1,563 @@
+// Package isotp implements the ISO 15765-2 (ISO-TP) transport protocol on top
+// of CAN frames, for payloads that do not fit into a single frame.
+//
+// A Channel is a pair of CAN IDs: frames are sent on TxID and the peer answers
+// on RxID. Received frames are fed to the channel with HandleFrame; reassembled
+// messages are delivered to Config.OnMessage. Frames are addressed by their ID
+// alone (normal addressing) or also by their first byte (extended and mixed
+// addressing), and are classic CAN frames or CAN FD frames of up to 64 bytes.
+package isotp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+)
+
+// protocol control information types (high nibble of the first byte).
+const (
+	pciSingle      = 0x0
+	pciFirst       = 0x1
+	pciConsecutive = 0x2
+	pciFlowControl = 0x3
+)
+
+// flow status values of a flow control frame.
+const (
+	flowContinue = 0x0
+	flowWait     = 0x1
+	flowOverflow = 0x2
+)
+
+const (
+	// classicFrameLength is the CAN frame size used for padding.
+	classicFrameLength = 8
+	// maxShortLength is the largest length that fits the 12-bit first frame length.
+	maxShortLength = 0xfff
+
+	defaultTimeout        = time.Second
+	defaultMaxWaitFrames  = 10
+	defaultMaxMessageSize = 1 << 20
+)
+
+// ErrTimeout is returned when the peer does not answer in time.
+var ErrTimeout = errors.New("isotp: timeout")
+
+// Addressing is how the frames of a channel are addressed.
+type Addressing int
+
+// The addressing formats.
+const (
+	// AddressingNormal addresses the frames by their CAN ID only.
+	AddressingNormal Addressing = iota
+	// AddressingExtended starts every frame with the target address.
+	AddressingExtended
+	// AddressingMixed starts every frame with the address extension.
+	AddressingMixed
+)
+
+// ParseAddressing parses "normal", "extended" or "mixed", empty for normal.
+func ParseAddressing(s string) (Addressing, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "normal":
+		return AddressingNormal, nil
+	case "extended":
+		return AddressingExtended, nil
+	case "mixed":
+		return AddressingMixed, nil
+	}
+	return 0, fmt.Errorf("unknown ISO-TP addressing %q, want normal, extended or mixed", s)
+}
+
+func (a Addressing) String() string {
+	switch a {
+	case AddressingExtended:
+		return "extended"
+	case AddressingMixed:
+		return "mixed"
+	}
+	return "normal"
+}
+
+// Config describes an ISO-TP channel.
+type Config struct {
+	// TxID is the CAN ID frames are sent on.
+	TxID uint32
+	// RxID is the CAN ID of frames sent by the peer.
+	RxID uint32
+	// Extended selects 29-bit CAN IDs.
+	Extended bool
+	// Addressing selects the addressing format. With the extended and mixed
+	// formats every frame sent starts with TxAddress (the target address or the
+	// address extension) and only the frames received starting with RxAddress
+	// belong to the channel.
+	Addressing Addressing
+	TxAddress  byte
+	RxAddress  byte
+	// FD sends CAN FD frames of FrameLength bytes (0 for 64), with bit rate
+	// switching when BRS is set.
+	FD          bool
+	BRS         bool
+	FrameLength int
+	// BlockSize is the number of consecutive frames the peer may send before
+	// waiting for the next flow control frame. Zero means no limit.
+	BlockSize uint8
+	// STmin is the minimum separation time between consecutive frames requested from the peer.
+	STmin time.Duration
+	// OverrideSTmin ignores the separation time requested by the peer and
+	// separates the consecutive frames sent by TxSTmin instead.
+	OverrideSTmin bool
+	TxSTmin       time.Duration
+	// Padding fills frames up to 8 bytes with PaddingByte. CAN FD frames are
+	// always padded up to a valid length.
+	Padding     bool
+	PaddingByte byte
+	// Timeout is the N_Bs/N_Cr timeout waiting for flow control or consecutive frames.
+	// Zero means one second.
+	Timeout time.Duration
+	// MaxWaitFrames is the number of flow control WAIT frames accepted in a row. Zero means 10.
+	MaxWaitFrames int
+	// MaxMessageSize is the length of the largest message received, longer
+	// first frames are answered with an overflow flow control. Zero means 1 MiB.
+	MaxMessageSize int
+
+	// OnMessage is called with every reassembled message. It runs on the goroutine
+	// calling HandleFrame and must not block.
+	OnMessage func(data []byte)
+	// OnError is called for reception errors such as lost consecutive frames.
+	OnError func(err error)
+}
+
+// SendFunc transmits a CAN frame.
+type SendFunc func(f canbus.Frame) error
+
+// Channel is an ISO-TP connection between two CAN IDs.
+type Channel struct {
+	cfg  Config
+	send SendFunc
+	// txDL is the length of the frames sent, addrLen the address byte
+	// preceding the protocol control information.
+	txDL    int
+	addrLen int
+
+	// txMu serializes Send calls.
+	txMu sync.Mutex
+	// fc receives flow control frames while a transmission waits for them.
+	fc chan []byte
+
+	mu      sync.Mutex
+	rx      []byte
+	rxLen   int
+	rxSN    uint8
+	rxBlock int
+	rxTimer *time.Timer
+}
+
+// NewChannel returns a channel that transmits frames with send.
+func NewChannel(cfg Config, send SendFunc) *Channel {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxWaitFrames <= 0 {
+		cfg.MaxWaitFrames = defaultMaxWaitFrames
+	}
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = defaultMaxMessageSize
+	}
+	c := &Channel{
+		cfg:  cfg,
+		send: send,
+		fc:   make(chan []byte, 1),
+		txDL: classicFrameLength,
+	}
+	if cfg.FD {
+		c.txDL = canbus.MaxFDDataLength
+		if cfg.FrameLength > classicFrameLength {
+			c.txDL = canbus.PaddedLength(cfg.FrameLength)
+		}
+	}
+	if cfg.Addressing != AddressingNormal {
+		c.addrLen = 1
+	}
+	return c
+}
+
+// Config returns the configuration of the channel.
+func (c *Channel) Config() Config {
+	return c.cfg
+}
+
+// HandleFrame processes a received frame. It reports whether the frame belongs to the channel.
+func (c *Channel) HandleFrame(f canbus.Frame) bool {
+	if f.ID != c.cfg.RxID || f.IsExtended != c.cfg.Extended || f.IsRemote || f.IsError {
+		return false
+	}
+	data := f.Payload()
+	if c.addrLen > 0 {
+		if len(data) == 0 || data[0] != c.cfg.RxAddress {
+			return false
+		}
+		data = data[1:]
+	}
+	if len(data) == 0 {
+		return true
+	}
+	switch data[0] >> 4 {
+	case pciSingle:
+		c.handleSingle(data, len(f.Payload()) > classicFrameLength)
+	case pciFirst:
+		c.handleFirst(data)
+	case pciConsecutive:
+		c.handleConsecutive(data)
+	case pciFlowControl:
+		// only the latest flow control frame matters to the transmitter
+		select {
+		case c.fc <- append([]byte(nil), data...):
+		default:
+			select {
+			case <-c.fc:
+			default:
+			}
+			c.fc <- append([]byte(nil), data...)
+		}
+	}
+	return true
+}
+
+func (c *Channel) handleSingle(data []byte, fd bool) {
+	n := int(data[0] & 0x0f)
+	payload := data[1:]
+	if n == 0 && fd {
+		// CAN FD single frame with escape sequence
+		n = int(data[1])
+		payload = data[2:]
+	}
+	if n == 0 || n > len(payload) {
+		c.fail(fmt.Errorf("isotp: invalid single frame length %d", n))
+		return
+	}
+	c.mu.Lock()
+	if c.rx != nil {
+		c.resetRx()
+		c.mu.Unlock()
+		c.fail(errors.New("isotp: reception interrupted by a single frame"))
+	} else {
+		c.mu.Unlock()
+	}
+	c.deliver(append([]byte(nil), payload[:n]...))
+}
+
+func (c *Channel) handleFirst(data []byte) {
+	if len(data) < 2 {
+		c.fail(errors.New("isotp: short first frame"))
+		return
+	}
+	n := int(data[0]&0x0f)<<8 | int(data[1])
+	payload := data[2:]
+	if n == 0 {
+		if len(data) < 6 {
+			c.fail(errors.New("isotp: short first frame"))
+			return
+		}
+		n = int(data[2])<<24 | int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+		payload = data[6:]
+	}
+	if n <= len(payload) {
+		c.fail(fmt.Errorf("isotp: first frame length %d fits a single frame", n))
+		return
+	}
+	if n > c.cfg.MaxMessageSize {
+		c.mu.Lock()
+		interrupted := c.rx != nil
+		c.resetRx()
+		c.mu.Unlock()
+		if interrupted {
+			c.fail(errors.New("isotp: reception interrupted by a new first frame"))
+		}
+		if err := c.sendFlowControl(flowOverflow); err != nil {
+			c.fail(err)
+		}
+		c.fail(fmt.Errorf("isotp: first frame length %d exceeds the maximum message size %d", n, c.cfg.MaxMessageSize))
+		return
+	}
+
+	c.mu.Lock()
+	interrupted := c.rx != nil
+	c.resetRx()
+	c.rx = make([]byte, 0, n)
+	c.rx = append(c.rx, payload...)
+	c.rxLen = n
+	c.rxSN = 1
+	c.rxBlock = 0
+	c.armRxTimer()
+	c.mu.Unlock()
+
+	if interrupted {
+		c.fail(errors.New("isotp: reception interrupted by a new first frame"))
+	}
+	if err := c.sendFlowControl(flowContinue); err != nil {
+		c.fail(err)
+	}
+}
+
+func (c *Channel) handleConsecutive(data []byte) {
+	c.mu.Lock()
+	if c.rx == nil {
+		c.mu.Unlock()
+		return // not ours or a late frame of an aborted message
+	}
+	sn := data[0] & 0x0f
+	if sn != c.rxSN {
+		want := c.rxSN
+		c.resetRx()
+		c.mu.Unlock()
+		c.fail(fmt.Errorf("isotp: wrong sequence number %d, expected %d", sn, want))
+		return
+	}
+	c.rxSN = (c.rxSN + 1) & 0x0f
+	remaining := c.rxLen - len(c.rx)
+	payload := data[1:]
+	if len(payload) > remaining {
+		payload = payload[:remaining]
+	}
+	c.rx = append(c.rx, payload...)
+	if len(c.rx) == c.rxLen {
+		msg := c.rx
+		c.resetRx()
+		c.mu.Unlock()
+		c.deliver(msg)
+		return
+	}
+	c.rxBlock++
+	needFC := c.cfg.BlockSize > 0 && c.rxBlock == int(c.cfg.BlockSize)
+	if needFC {
+		c.rxBlock = 0
+	}
+	c.armRxTimer()
+	c.mu.Unlock()
+
+	if needFC {
+		if err := c.sendFlowControl(flowContinue); err != nil {
+			c.fail(err)
+		}
+	}
+}
+
+// resetRx drops any partial message. c.mu must be held.
+func (c *Channel) resetRx() {
+	if c.rxTimer != nil {
+		c.rxTimer.Stop()
+		c.rxTimer = nil
+	}
+	c.rx = nil
+	c.rxLen = 0
+}
+
+// armRxTimer (re)starts the N_Cr timeout. c.mu must be held.
+func (c *Channel) armRxTimer() {
+	if c.rxTimer != nil {
+		c.rxTimer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(c.cfg.Timeout, func() {
+		c.mu.Lock()
+		if c.rxTimer != timer {
+			c.mu.Unlock()
+			return
+		}
+		c.resetRx()
+		c.mu.Unlock()
+		c.fail(fmt.Errorf("%w waiting for consecutive frame", ErrTimeout))
+	})
+	c.rxTimer = timer
+}
+
+func (c *Channel) sendFlowControl(status byte) error {
+	return c.sendFrame([]byte{pciFlowControl<<4 | status, c.cfg.BlockSize, EncodeSTmin(c.cfg.STmin)})
+}
+
+// Send transmits a message, segmenting it as needed. It blocks until the last
+// frame is sent or the peer's flow control fails.
+func (c *Channel) Send(ctx context.Context, data []byte) error {
+	if len(data) == 0 {
+		return errors.New("isotp: empty message")
+	}
+	c.txMu.Lock()
+	defer c.txMu.Unlock()
+
+	// room is the data of a frame after the address
+	room := c.txDL - c.addrLen
+	if len(data) <= classicFrameLength-1-c.addrLen {
+		return c.sendFrame(append([]byte{byte(len(data))}, data...))
+	}
+	if c.txDL > classicFrameLength && len(data) <= room-2 {
+		// CAN FD single frame with escape sequence
+		return c.sendFrame(append([]byte{0, byte(len(data))}, data...))
+	}
+
+	// drop flow control frames left over from a previous transmission
+	select {
+	case <-c.fc:
+	default:
+	}
+
+	var first []byte
+	if len(data) <= maxShortLength {
+		first = []byte{pciFirst<<4 | byte(len(data)>>8), byte(len(data))}
+	} else {
+		n := uint32(len(data))
+		first = []byte{pciFirst << 4, 0, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}
+	}
+	sent := room - len(first)
+	if err := c.sendFrame(append(first, data[:sent]...)); err != nil {
+		return err
+	}
+
+	sn := byte(1)
+	for sent < len(data) {
+		bs, stmin, err := c.waitFlowControl(ctx)
+		if err != nil {
+			return err
+		}
+		if c.cfg.OverrideSTmin {
+			stmin = c.cfg.TxSTmin
+		}
+		for block := 0; sent < len(data) && (bs == 0 || block < bs); block++ {
+			if block > 0 {
+				if err := sleep(ctx, stmin); err != nil {
+					return err
+				}
+			}
+			end := sent + room - 1
+			if end > len(data) {
+				end = len(data)
+			}
+			if err := c.sendFrame(append([]byte{pciConsecutive<<4 | sn}, data[sent:end]...)); err != nil {
+				return err
+			}
+			sent = end
+			sn = (sn + 1) & 0x0f
+		}
+	}
+	return nil
+}
+
+// waitFlowControl waits for a CTS flow control frame and returns its block size and separation time.
+func (c *Channel) waitFlowControl(ctx context.Context) (int, time.Duration, error) {
+	for waits := 0; ; {
+		timer := time.NewTimer(c.cfg.Timeout)
+		var fc []byte
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, 0, ctx.Err()
+		case <-timer.C:
+			return 0, 0, fmt.Errorf("%w waiting for flow control", ErrTimeout)
+		case fc = <-c.fc:
+			timer.Stop()
+		}
+		if len(fc) < 3 {
+			return 0, 0, errors.New("isotp: short flow control frame")
+		}
+		switch fc[0] & 0x0f {
+		case flowContinue:
+			return int(fc[1]), DecodeSTmin(fc[2]), nil
+		case flowWait:
+			waits++
+			if waits > c.cfg.MaxWaitFrames {
+				return 0, 0, fmt.Errorf("isotp: more than %d flow control wait frames", c.cfg.MaxWaitFrames)
+			}
+		case flowOverflow:
+			return 0, 0, errors.New("isotp: receiver overflow")
+		default:
+			return 0, 0, fmt.Errorf("isotp: invalid flow status %d", fc[0]&0x0f)
+		}
+	}
+}
+
+func (c *Channel) sendFrame(payload []byte) error {
+	f := canbus.Frame{
+		ID:         c.cfg.TxID,
+		IsExtended: c.cfg.Extended,
+		IsFD:       c.cfg.FD,
+		BRS:        c.cfg.FD && c.cfg.BRS,
+	}
+	n := 0
+	if c.addrLen > 0 {
+		f.Data[0] = c.cfg.TxAddress
+		n = 1
+	}
+	n += copy(f.Data[n:], payload)
+	length := n
+	if c.cfg.Padding && length < classicFrameLength {
+		length = classicFrameLength
+	}
+	if c.cfg.FD {
+		length = canbus.PaddedLength(length)
+	}
+	for i := n; i < length; i++ {
+		f.Data[i] = c.cfg.PaddingByte
+	}
+	f.Length = uint8(length)
+	return c.send(f)
+}
+
+func (c *Channel) deliver(msg []byte) {
+	if c.cfg.OnMessage != nil {
+		c.cfg.OnMessage(msg)
+	}
+}
+
+func (c *Channel) fail(err error) {
+	if c.cfg.OnError != nil {
+		c.cfg.OnError(err)
+	}
+}
+
+// EncodeSTmin encodes a separation time into the STmin byte of a flow control frame.
+func EncodeSTmin(d time.Duration) byte {
+	switch {
+	case d <= 0:
+		return 0
+	case d < 900*time.Microsecond:
+		n := (d + 99*time.Microsecond) / (100 * time.Microsecond)
+		return 0xf0 + byte(n)
+	case d < time.Millisecond:
+		return 0xf9
+	case d > 127*time.Millisecond:
+		return 127
+	default:
+		return byte(d / time.Millisecond)
+	}
+}
+
+// DecodeSTmin decodes the STmin byte of a flow control frame. Reserved values map to 127 ms.
+func DecodeSTmin(b byte) time.Duration {
+	switch {
+	case b <= 0x7f:
+		return time.Duration(b) * time.Millisecond
+	case b >= 0xf1 && b <= 0xf9:
+		return time.Duration(b-0xf0) * 100 * time.Microsecond
+	default:
+		return 127 * time.Millisecond
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
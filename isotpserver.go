@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// OpenIsoTPServer opens an ISO-TP channel on a started interface that receives
+// the requests of a tester on rxID and answers on txID, to simulate a
+// diagnostic ECU. The reassembled requests are emitted on "can:isotp" and
+// answered by the first service of the responder profile matching them, or
+// else by the first script whose on_isotp function returns a response.
+// Requests nobody answers are left unanswered. The handle is closed with
+// CloseIsoTP.
+func (a *App) OpenIsoTPServer(iface string, txID uint32, rxID uint32, opts IsoTPOptions) (int, error) {
+	return a.openIsoTP(iface, txID, rxID, opts, true)
+}
+
+// serveIsoTP answers a message received by a server channel.
+func (a *App) serveIsoTP(c *isotpChannel, data []byte) {
+	iface, rxID, txID := c.info.Interface, c.info.RxID, c.info.TxID
+	if r := a.responder.Load(); r != nil && !a.responderOff.Load() {
+		if sv := r.profile.MatchMessage(iface, rxID, data); sv != nil {
+			if sleepCtx(r.ctx, sv.Delay()) != nil {
+				return
+			}
+			if err := respondIsoTP(c, sv.Response); err != nil {
+				// report the first failure only, like the frame rules
+				if sv.CountError(); sv.Errors() == 1 {
+					a.emitError(fmt.Errorf("responder %s: %w", sv.Name, err))
+				}
+			}
+			return
+		}
+	}
+
+	a.scriptMu.Lock()
+	scripts := a.scripts
+	a.scriptMu.Unlock()
+
+	now := time.Now()
+	for _, s := range scripts {
+		if (s.iface != "" && s.iface != iface) || s.err.Load() != nil {
+			continue
+		}
+		resp, err := s.script.HandleMessage(iface, now, rxID, txID, data)
+		if err != nil {
+			msg := err.Error()
+			s.err.Store(&msg)
+			a.emitError(fmt.Errorf("script %s stopped: %w", s.name, err))
+			continue
+		}
+		if resp != nil {
+			if err := respondIsoTP(c, resp); err != nil {
+				a.emitError(fmt.Errorf("script %s: %w", s.name, err))
+			}
+			return
+		}
+	}
+}
+
+// respondIsoTP sends a response on a server channel.
+func respondIsoTP(c *isotpChannel, resp []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), isotpSendTimeout)
+	defer cancel()
+	return c.ch.Send(ctx, resp)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/j1939"
+)
+
+// J1939Event is a J1939 parameter group emitted on "can:j1939".
+type J1939Event struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Interface   string    `json:"interface"`
+	Priority    uint8     `json:"priority"`
+	PGN         uint32    `json:"pgn"`
+	Acronym     string    `json:"acronym"`
+	Name        string    `json:"name"`
+	Source      uint8     `json:"source"`
+	Destination uint8     `json:"destination"`
+	Data        []uint32  `json:"data"`
+	// Multipacket is true for messages reassembled from TP.BAM or TP.CM transfers.
+	Multipacket bool `json:"multipacket"`
+}
+
+// SetJ1939Decoding enables or disables J1939 decoding of the 29-bit frames received on
+// a started interface. Decoded parameter groups, including reassembled transport
+// protocol messages, are emitted on "can:j1939".
+func (a *App) SetJ1939Decoding(iface string, enabled bool) error {
+	iface = strings.TrimSpace(iface)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sess := a.sessions[iface]
+	if sess == nil || sess.conn == nil {
+		return fmt.Errorf("CAN not started on %s", iface)
+	}
+	if !enabled {
+		sess.j1939.Store(nil)
+	} else if sess.j1939.Load() == nil {
+		sess.j1939.Store(j1939.NewReassembler())
+	}
+	return nil
+}
+
+// SendPGN sends a J1939 parameter group with the 29-bit ID built from priority, pgn,
+// source and destination. The destination is only used by PDU1 (destination specific)
+// PGNs. Messages longer than 8 bytes are broadcast with TP.BAM, which takes 50 ms per
+// 7 bytes of data.
+func (a *App) SendPGN(iface string, priority uint8, pgn uint32, source uint8, destination uint8, data []byte) error {
+	iface = strings.TrimSpace(iface)
+	h := j1939.Header{Priority: priority, PGN: pgn, Source: source, Destination: destination}
+	if len(data) <= canbus.MaxDataLength {
+		f, err := h.Frame(data)
+		if err != nil {
+			return err
+		}
+		return a.transmit(iface, f)
+	}
+
+	if j1939.IsPDU1(pgn) && destination != j1939.GlobalAddress {
+		return fmt.Errorf("%d bytes to address %d need a TP.CM connection, only broadcasts are supported", len(data), destination)
+	}
+	frames, err := j1939.BAMFrames(h, data)
+	if err != nil {
+		return err
+	}
+	for i, f := range frames {
+		if i > 0 {
+			time.Sleep(j1939.BAMInterval)
+		}
+		if err := a.transmit(iface, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dispatchJ1939 decodes a frame received on a session with J1939 decoding enabled.
+func (a *App) dispatchJ1939(sess *canSession, ts time.Time, f *canbus.Frame) {
+	r := sess.j1939.Load()
+	if r == nil {
+		return
+	}
+	msg, ok := r.Handle(ts, f)
+	if !ok {
+		return
+	}
+	ev := J1939Event{
+		Timestamp:   ts,
+		Interface:   sess.iface,
+		Priority:    msg.Priority,
+		PGN:         msg.PGN,
+		Acronym:     j1939.PGNName(msg.PGN),
+		Source:      msg.Source,
+		Destination: msg.Destination,
+		Data:        dataWords(msg.Data),
+		Multipacket: msg.Multipacket,
+	}
+	if info, ok := j1939.LookupPGN(msg.PGN); ok {
+		ev.Name = info.Name
+	}
+	a.emit("can:j1939", ev)
+}
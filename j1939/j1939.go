@@ -0,0 +1,93 @@
+// Package j1939 decodes SAE J1939 identifiers and reassembles the messages of
+// the J1939-21 transport protocol (TP.BAM broadcasts and TP.CM RTS/CTS
+// connections).
+package j1939
+
+import (
+	"fmt"
+
+	"canproject/canbus"
+)
+
+const (
+	// GlobalAddress is the destination address of broadcast messages.
+	GlobalAddress = 0xff
+	// MaxPriority is the lowest priority (highest value) of a J1939 message.
+	MaxPriority = 7
+	// pdu2Threshold is the first PDU format value of PDU2 (broadcast) PGNs.
+	pdu2Threshold = 0xf0
+)
+
+// Header is the content of a 29-bit J1939 identifier.
+type Header struct {
+	Priority uint8
+	// PGN is the parameter group number. For PDU1 PGNs the PS byte (destination) is zero.
+	PGN         uint32
+	Source      uint8
+	Destination uint8
+}
+
+// ParseID decodes a 29-bit CAN identifier.
+func ParseID(id uint32) Header {
+	h := Header{
+		Priority: uint8(id>>26) & 0x7,
+		Source:   uint8(id),
+	}
+	pf := uint8(id >> 16)
+	ps := uint8(id >> 8)
+	dp := (id >> 16) & 0x300
+	if pf < pdu2Threshold {
+		h.PGN = dp<<8 | uint32(pf)<<8
+		h.Destination = ps
+	} else {
+		h.PGN = dp<<8 | uint32(pf)<<8 | uint32(ps)
+		h.Destination = GlobalAddress
+	}
+	return h
+}
+
+// IsPDU1 reports whether pgn is destination specific.
+func IsPDU1(pgn uint32) bool {
+	return uint8(pgn>>8) < pdu2Threshold
+}
+
+// ID returns the 29-bit CAN identifier of the header.
+func (h Header) ID() (uint32, error) {
+	if h.Priority > MaxPriority {
+		return 0, fmt.Errorf("j1939: priority must be within 0..7 (got %d)", h.Priority)
+	}
+	if h.PGN > 0x3ffff {
+		return 0, fmt.Errorf("j1939: PGN 0x%X does not fit in 18 bits", h.PGN)
+	}
+	id := uint32(h.Priority)<<26 | h.PGN<<8 | uint32(h.Source)
+	if IsPDU1(h.PGN) {
+		if h.PGN&0xff != 0 {
+			return 0, fmt.Errorf("j1939: PDU1 PGN 0x%X must have a zero PS byte", h.PGN)
+		}
+		id |= uint32(h.Destination) << 8
+	}
+	return id, nil
+}
+
+// Frame returns a single-frame message with the header.
+func (h Header) Frame(data []byte) (canbus.Frame, error) {
+	if len(data) > canbus.MaxDataLength {
+		return canbus.Frame{}, fmt.Errorf("j1939: %d bytes do not fit in a single frame", len(data))
+	}
+	id, err := h.ID()
+	if err != nil {
+		return canbus.Frame{}, err
+	}
+	f := canbus.Frame{ID: id, IsExtended: true, Length: uint8(len(data))}
+	copy(f.Data[:], data)
+	return f, nil
+}
+
+// Message is a J1939 parameter group, either a single frame or a reassembled
+// transport protocol message.
+type Message struct {
+	Header
+	Data []byte
+	// Multipacket is true for messages reassembled from the transport protocol.
+	Multipacket bool
+}
@@ -0,0 +1,73 @@
+package j1939
+
+import "fmt"
+
+// PGNInfo names a parameter group.
+type PGNInfo struct {
+	PGN     uint32
+	Acronym string
+	Name    string
+}
+
+// Well-known PGNs.
+const (
+	PGNAcknowledgment uint32 = 0xe800
+	PGNRequest        uint32 = 0xea00
+	PGNTransferDT     uint32 = 0xeb00
+	PGNTransferCM     uint32 = 0xec00
+	PGNAddressClaimed uint32 = 0xee00
+)
+
+var pgnNames = map[uint32]PGNInfo{}
+
+func init() {
+	for _, p := range []PGNInfo{
+		{0x0000, "TSC1", "Torque/Speed Control 1"},
+		{PGNAcknowledgment, "ACKM", "Acknowledgment"},
+		{PGNRequest, "RQST", "Request"},
+		{PGNTransferDT, "TP.DT", "Transport Protocol - Data Transfer"},
+		{PGNTransferCM, "TP.CM", "Transport Protocol - Connection Management"},
+		{PGNAddressClaimed, "AC", "Address Claimed"},
+		{0xf001, "EBC1", "Electronic Brake Controller 1"},
+		{0xf002, "ETC1", "Electronic Transmission Controller 1"},
+		{0xf003, "EEC2", "Electronic Engine Controller 2"},
+		{0xf004, "EEC1", "Electronic Engine Controller 1"},
+		{0xf005, "ETC2", "Electronic Transmission Controller 2"},
+		{0xfe6c, "TCO1", "Tachograph"},
+		{0xfebf, "EBC2", "Wheel Speed Information"},
+		{0xfec1, "VDHR", "High Resolution Vehicle Distance"},
+		{0xfeca, "DM1", "Active Diagnostic Trouble Codes"},
+		{0xfecb, "DM2", "Previously Active Diagnostic Trouble Codes"},
+		{0xfeda, "SOFT", "Software Identification"},
+		{0xfee0, "VD", "Vehicle Distance"},
+		{0xfee4, "SHUTDN", "Shutdown"},
+		{0xfee5, "HOURS", "Engine Hours, Revolutions"},
+		{0xfee6, "TD", "Time/Date"},
+		{0xfee9, "LFC", "Fuel Consumption (Liquid)"},
+		{0xfeec, "VI", "Vehicle Identification"},
+		{0xfeee, "ET1", "Engine Temperature 1"},
+		{0xfeef, "EFL/P1", "Engine Fluid Level/Pressure 1"},
+		{0xfef1, "CCVS1", "Cruise Control/Vehicle Speed 1"},
+		{0xfef2, "LFE1", "Fuel Economy (Liquid)"},
+		{0xfef5, "AMB", "Ambient Conditions"},
+		{0xfef6, "IC1", "Inlet/Exhaust Conditions 1"},
+		{0xfef7, "VEP1", "Vehicle Electrical Power 1"},
+		{0xfefc, "DD", "Dash Display"},
+	} {
+		pgnNames[p.PGN] = p
+	}
+}
+
+// LookupPGN returns the name of a PGN from the built-in database.
+func LookupPGN(pgn uint32) (PGNInfo, bool) {
+	p, ok := pgnNames[pgn]
+	return p, ok
+}
+
+// PGNName returns the acronym of a PGN, or its number when it is unknown.
+func PGNName(pgn uint32) string {
+	if p, ok := pgnNames[pgn]; ok {
+		return p.Acronym
+	}
+	return fmt.Sprintf("PGN %d", pgn)
+}
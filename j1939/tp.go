@@ -0,0 +1,175 @@
+package j1939
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"canproject/canbus"
+)
+
+// TP.CM control bytes.
+const (
+	cmRTS   = 0x10
+	cmCTS   = 0x11
+	cmEOMA  = 0x13
+	cmBAM   = 0x20
+	cmAbort = 0xff
+)
+
+const (
+	// MaxTransportLength is the largest message of the transport protocol.
+	MaxTransportLength = 1785
+	// packetLength is the number of message bytes per TP.DT frame.
+	packetLength = 7
+	// T1 is the maximum time between two data packets of a transfer.
+	T1 = 750 * time.Millisecond
+	// BAMInterval is the gap between the packets of a broadcast sent by BAMFrames.
+	BAMInterval = 50 * time.Millisecond
+)
+
+type sessionKey struct {
+	source, destination uint8
+}
+
+type session struct {
+	priority uint8
+	pgn      uint32
+	size     int
+	packets  int
+	next     int
+	data     []byte
+	last     time.Time
+}
+
+// Reassembler rebuilds the messages of the transport protocol from the frames
+// observed on the bus. Connections (RTS/CTS) are followed passively, the
+// Reassembler never answers them. It is not safe for concurrent use.
+type Reassembler struct {
+	sessions map[sessionKey]*session
+}
+
+// NewReassembler returns an empty Reassembler.
+func NewReassembler() *Reassembler {
+	return &Reassembler{sessions: make(map[sessionKey]*session)}
+}
+
+// Handle processes a received frame received at ts. It returns the message of
+// single-frame parameter groups and of completed transfers; transport protocol
+// frames themselves are consumed.
+func (r *Reassembler) Handle(ts time.Time, f *canbus.Frame) (Message, bool) {
+	if !f.IsExtended || f.IsRemote || f.IsError {
+		return Message{}, false
+	}
+	h := ParseID(f.ID)
+	data := f.Payload()
+	switch h.PGN {
+	case PGNTransferCM:
+		r.handleCM(ts, h, data)
+		return Message{}, false
+	case PGNTransferDT:
+		return r.handleDT(ts, h, data)
+	}
+	return Message{Header: h, Data: append([]byte(nil), data...)}, true
+}
+
+func (r *Reassembler) handleCM(ts time.Time, h Header, data []byte) {
+	if len(data) < 8 {
+		return
+	}
+	key := sessionKey{h.Source, h.Destination}
+	switch data[0] {
+	case cmRTS, cmBAM:
+		if data[0] == cmBAM && h.Destination != GlobalAddress {
+			return
+		}
+		size := int(binary.LittleEndian.Uint16(data[1:3]))
+		packets := int(data[3])
+		if size <= canbus.MaxDataLength || size > MaxTransportLength || packets != (size+packetLength-1)/packetLength {
+			delete(r.sessions, key)
+			return
+		}
+		r.sessions[key] = &session{
+			priority: h.Priority,
+			pgn:      uint32(data[5]) | uint32(data[6])<<8 | uint32(data[7])<<16,
+			size:     size,
+			packets:  packets,
+			next:     1,
+			data:     make([]byte, 0, packets*packetLength),
+			last:     ts,
+		}
+	case cmAbort:
+		delete(r.sessions, key)
+		// the responder aborts a connection towards the originator
+		delete(r.sessions, sessionKey{h.Destination, h.Source})
+	case cmCTS, cmEOMA:
+		// sent by the responder of a connection, nothing to reassemble
+	}
+}
+
+func (r *Reassembler) handleDT(ts time.Time, h Header, data []byte) (Message, bool) {
+	key := sessionKey{h.Source, h.Destination}
+	s := r.sessions[key]
+	if s == nil || len(data) < 1 {
+		return Message{}, false
+	}
+	if ts.Sub(s.last) > T1 || int(data[0]) != s.next {
+		// lost or late packet, the transfer cannot complete
+		delete(r.sessions, key)
+		return Message{}, false
+	}
+	s.last = ts
+	s.next++
+	s.data = append(s.data, data[1:]...)
+	if s.next <= s.packets {
+		return Message{}, false
+	}
+	delete(r.sessions, key)
+	if len(s.data) < s.size {
+		return Message{}, false
+	}
+	return Message{
+		Header: Header{
+			Priority:    s.priority,
+			PGN:         s.pgn,
+			Source:      h.Source,
+			Destination: h.Destination,
+		},
+		Data:        s.data[:s.size],
+		Multipacket: true,
+	}, true
+}
+
+// BAMFrames returns the frames broadcasting a message of 9 to 1785 bytes with
+// TP.BAM: the announcement followed by the data packets, which must be sent
+// BAMInterval apart. The destination of h is ignored.
+func BAMFrames(h Header, data []byte) ([]canbus.Frame, error) {
+	if len(data) <= canbus.MaxDataLength || len(data) > MaxTransportLength {
+		return nil, errors.New("j1939: BAM messages must be 9 to 1785 bytes long")
+	}
+	if h.PGN > 0x3ffff {
+		return nil, errors.New("j1939: PGN does not fit in 18 bits")
+	}
+	packets := (len(data) + packetLength - 1) / packetLength
+
+	cm := Header{Priority: h.Priority, PGN: PGNTransferCM, Source: h.Source, Destination: GlobalAddress}
+	announce := []byte{cmBAM, byte(len(data)), byte(len(data) >> 8), byte(packets), 0xff,
+		byte(h.PGN), byte(h.PGN >> 8), byte(h.PGN >> 16)}
+	first, err := cm.Frame(announce)
+	if err != nil {
+		return nil, err
+	}
+	frames := []canbus.Frame{first}
+
+	dt := Header{Priority: h.Priority, PGN: PGNTransferDT, Source: h.Source, Destination: GlobalAddress}
+	for i := 0; i < packets; i++ {
+		packet := []byte{byte(i + 1), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+		copy(packet[1:], data[i*packetLength:min(len(data), (i+1)*packetLength)])
+		f, err := dt.Frame(packet)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, f)
+	}
+	return frames, nil
+}
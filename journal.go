@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+)
+
+const (
+	// maxJournalEntries bounds the session journal, the oldest actions are dropped.
+	maxJournalEntries = 100000
+	// journalVersion is the format version of ExportSession files.
+	journalVersion = 1
+)
+
+// Journaled actions.
+const (
+	actionStartCAN         = "StartCAN"
+	actionStopCAN          = "StopCAN"
+	actionSendFrame        = "SendFrame"
+	actionSetFilters       = "SetFilters"
+	actionStartCyclicFrame = "StartCyclicFrame"
+	actionStopCyclicFrame  = "StopCyclicFrame"
+)
+
+// JournalEntry is an action of the session journal. Action is "StartCAN",
+// "StopCAN", "SendFrame" (the frames sent from the UI, the REST API and the
+// MQTT bridge), "SetFilters" (no filters for ClearFilters), "StartCyclicFrame"
+// or "StopCyclicFrame"; the fields it uses are set.
+type JournalEntry struct {
+	Time      time.Time   `json:"time"`
+	Action    string      `json:"action"`
+	Interface string      `json:"interface,omitempty"`
+	Options   *CANOptions `json:"options,omitempty"`
+	ID        uint32      `json:"id,omitempty"`
+	Extended  bool        `json:"extended,omitempty"`
+	FD        bool        `json:"fd,omitempty"`
+	BRS       bool        `json:"brs,omitempty"`
+	Data      []uint32    `json:"data,omitempty"`
+	Filters   []CANFilter `json:"filters,omitempty"`
+	PeriodMs  int         `json:"periodMs,omitempty"`
+	// Handle is the handle of the cyclic transmission.
+	Handle int `json:"handle,omitempty"`
+}
+
+// sessionFile is the document of ExportSession.
+type sessionFile struct {
+	Version  int            `json:"version"`
+	Exported time.Time      `json:"exported"`
+	Entries  []JournalEntry `json:"entries"`
+}
+
+// SessionReplayResult is the result of ReplaySession.
+type SessionReplayResult struct {
+	Actions int `json:"actions"`
+	// Failures are the actions that failed, the replay goes on after them.
+	Failures []string `json:"failures"`
+	// Stopped is set when StopSessionReplay ended the replay early.
+	Stopped bool `json:"stopped"`
+}
+
+// GetSessionJournal returns the actions performed since the app started or the
+// last ClearSessionJournal, oldest first.
+func (a *App) GetSessionJournal() []JournalEntry {
+	a.journalMu.Lock()
+	defer a.journalMu.Unlock()
+	return append([]JournalEntry{}, a.journal...)
+}
+
+// ClearSessionJournal forgets the journaled actions.
+func (a *App) ClearSessionJournal() {
+	a.journalMu.Lock()
+	a.journal = nil
+	a.journalMu.Unlock()
+}
+
+// ExportSession writes the session journal to path as JSON, for ReplaySession.
+func (a *App) ExportSession(path string) (int, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return 0, errors.New("session path is empty")
+	}
+	doc := sessionFile{Version: journalVersion, Exported: time.Now(), Entries: a.GetSessionJournal()}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return 0, err
+	}
+	return len(doc.Entries), nil
+}
+
+// ReplaySession performs the actions of a session exported with ExportSession
+// again, with the time between them as recorded, and returns when all were
+// performed or StopSessionReplay was called. The cyclic transmissions stopped
+// by the session are those the replay started.
+func (a *App) ReplaySession(path string) (SessionReplayResult, error) {
+	data, err := os.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return SessionReplayResult{}, err
+	}
+	var doc sessionFile
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return SessionReplayResult{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if doc.Version != journalVersion {
+		return SessionReplayResult{}, fmt.Errorf("%s: unsupported session version %d", path, doc.Version)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.journalMu.Lock()
+	if a.journalReplay != nil {
+		a.journalMu.Unlock()
+		cancel()
+		return SessionReplayResult{}, errors.New("a session replay is already running")
+	}
+	a.journalReplay = cancel
+	a.journalMu.Unlock()
+	defer func() {
+		a.journalMu.Lock()
+		a.journalReplay = nil
+		a.journalMu.Unlock()
+		cancel()
+	}()
+
+	res := SessionReplayResult{Failures: []string{}}
+	handles := make(map[int]int)
+	start := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+	for i := range doc.Entries {
+		e := &doc.Entries[i]
+		timer.Reset(time.Until(start.Add(e.Time.Sub(doc.Entries[0].Time))))
+		select {
+		case <-ctx.Done():
+			res.Stopped = true
+			return res, nil
+		case <-timer.C:
+		}
+		res.Actions++
+		if err := a.replayAction(e, handles); err != nil {
+			action := e.Action
+			if e.Interface != "" {
+				action += " " + e.Interface
+			}
+			res.Failures = append(res.Failures, fmt.Sprintf("%s %s: %v", e.Time.Format("15:04:05.000"), action, err))
+		}
+	}
+	return res, nil
+}
+
+// StopSessionReplay ends the running ReplaySession.
+func (a *App) StopSessionReplay() {
+	a.journalMu.Lock()
+	defer a.journalMu.Unlock()
+	if a.journalReplay != nil {
+		a.journalReplay()
+	}
+}
+
+// replayAction performs a journaled action, handles mapping the handles of the
+// session to those of the replay.
+func (a *App) replayAction(e *JournalEntry, handles map[int]int) error {
+	data := make([]byte, len(e.Data))
+	for i, b := range e.Data {
+		data[i] = byte(b)
+	}
+	switch e.Action {
+	case actionStartCAN:
+		var opts CANOptions
+		if e.Options != nil {
+			opts = *e.Options
+		}
+		return a.StartCANWithOptions(e.Interface, opts)
+	case actionStopCAN:
+		return a.StopCAN(e.Interface)
+	case actionSendFrame:
+		return a.sendFrame(e.Interface, e.ID, data, e.Extended, e.FD, e.BRS)
+	case actionSetFilters:
+		if len(e.Filters) == 0 {
+			return a.ClearFilters(e.Interface)
+		}
+		return a.SetFilters(e.Interface, e.Filters)
+	case actionStartCyclicFrame:
+		handle, err := a.StartCyclicFrame(e.Interface, e.ID, data, e.Extended, e.PeriodMs)
+		if err == nil {
+			handles[e.Handle] = handle
+		}
+		return err
+	case actionStopCyclicFrame:
+		handle, ok := handles[e.Handle]
+		if !ok {
+			return fmt.Errorf("cyclic frame %d was not started by the session", e.Handle)
+		}
+		delete(handles, e.Handle)
+		return a.StopCyclicFrame(handle)
+	}
+	return fmt.Errorf("unknown action %q", e.Action)
+}
+
+// record appends an action to the session journal.
+func (a *App) record(e JournalEntry) {
+	e.Time = time.Now()
+	a.journalMu.Lock()
+	defer a.journalMu.Unlock()
+	if len(a.journal) >= maxJournalEntries {
+		a.journal = append(a.journal[:0], a.journal[len(a.journal)-maxJournalEntries+1:]...)
+	}
+	a.journal = append(a.journal, e)
+}
+
+// recordFrame journals a frame sent from the UI or the APIs.
+func (a *App) recordFrame(iface string, f *canbus.Frame) {
+	a.record(JournalEntry{
+		Action:    actionSendFrame,
+		Interface: iface,
+		ID:        f.ID,
+		Extended:  f.IsExtended,
+		FD:        f.IsFD,
+		BRS:       f.BRS,
+		Data:      dataWords(f.Payload()),
+	})
+}
@@ -0,0 +1,10 @@
+//go:build !kvaser || !windows
+
+package kvaser
+
+// Available reports whether the backend was built with CANlib support.
+const Available = false
+
+func loadAPI() (api, error) {
+	return nil, ErrNotAvailable
+}
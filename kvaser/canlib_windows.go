@@ -0,0 +1,94 @@
+//go:build kvaser && windows
+
+package kvaser
+
+import (
+	"fmt"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// Available reports whether the backend was built with CANlib support.
+const Available = true
+
+var (
+	canlib                  = syscall.NewLazyDLL("canlib32.dll")
+	procInitializeLibrary   = canlib.NewProc("canInitializeLibrary")
+	procOpenChannel         = canlib.NewProc("canOpenChannel")
+	procSetBusParams        = canlib.NewProc("canSetBusParams")
+	procSetBusOutputControl = canlib.NewProc("canSetBusOutputControl")
+	procBusOn               = canlib.NewProc("canBusOn")
+	procBusOff              = canlib.NewProc("canBusOff")
+	procClose               = canlib.NewProc("canClose")
+	procRead                = canlib.NewProc("canRead")
+	procWrite               = canlib.NewProc("canWrite")
+	procErrorText           = canlib.NewProc("canGetErrorText")
+
+	initOnce sync.Once
+)
+
+// dllAPI calls canlib32.dll.
+type dllAPI struct{}
+
+func loadAPI() (api, error) {
+	if err := canlib.Load(); err != nil {
+		return nil, fmt.Errorf("kvaser: %w (are the Kvaser drivers installed?)", err)
+	}
+	initOnce.Do(func() { _, _, _ = procInitializeLibrary.Call() })
+	return dllAPI{}, nil
+}
+
+func (dllAPI) openChannel(channel int, flags int32) int32 {
+	r, _, _ := procOpenChannel.Call(uintptr(channel), uintptr(flags))
+	return int32(r)
+}
+
+func (dllAPI) setBusParams(handle int32, freq int32) int32 {
+	// the segments and the sampling are those of the predefined bitrate
+	r, _, _ := procSetBusParams.Call(uintptr(handle), uintptr(freq), 0, 0, 0, 0, 0)
+	return int32(r)
+}
+
+func (dllAPI) setBusOutputControl(handle int32, driver int32) int32 {
+	r, _, _ := procSetBusOutputControl.Call(uintptr(handle), uintptr(driver))
+	return int32(r)
+}
+
+func (dllAPI) busOn(handle int32) int32 {
+	r, _, _ := procBusOn.Call(uintptr(handle))
+	return int32(r)
+}
+
+func (dllAPI) busOff(handle int32) int32 {
+	r, _, _ := procBusOff.Call(uintptr(handle))
+	return int32(r)
+}
+
+func (dllAPI) close(handle int32) int32 {
+	r, _, _ := procClose.Call(uintptr(handle))
+	return int32(r)
+}
+
+func (dllAPI) read(handle int32, id *int32, data *[8]byte, dlc, flags *uint32, time *uint32) int32 {
+	r, _, _ := procRead.Call(uintptr(handle), uintptr(unsafe.Pointer(id)), uintptr(unsafe.Pointer(data)),
+		uintptr(unsafe.Pointer(dlc)), uintptr(unsafe.Pointer(flags)), uintptr(unsafe.Pointer(time)))
+	return int32(r)
+}
+
+func (dllAPI) write(handle int32, id int32, data *[8]byte, dlc, flags uint32) int32 {
+	r, _, _ := procWrite.Call(uintptr(handle), uintptr(id), uintptr(unsafe.Pointer(data)), uintptr(dlc), uintptr(flags))
+	return int32(r)
+}
+
+func (dllAPI) errorText(status int32) string {
+	var buf [256]byte
+	if r, _, _ := procErrorText.Call(uintptr(status), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf))); int32(r) != statusOK {
+		return fmt.Sprintf("error %d", status)
+	}
+	n := 0
+	for n < len(buf) && buf[n] != 0 {
+		n++
+	}
+	return string(buf[:n])
+}
@@ -0,0 +1,314 @@
+// Package kvaser drives Kvaser CAN adapters through the Kvaser CANlib driver
+// API, so they can be used on the Windows lab PCs where SocketCAN is not
+// available. On Linux the Kvaser adapters have SocketCAN drivers (kvaser_usb,
+// kvaser_pci) and are used as can0, can1...
+//
+// canlib32.dll is loaded at run time, without cgo; the backend is compiled in
+// with the kvaser build tag on Windows. Otherwise Dial reports that it is not
+// available.
+package kvaser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"canproject/canbus"
+)
+
+const (
+	// DefaultBitrate is the CAN bitrate used when the URL does not set one.
+	DefaultBitrate = 500000
+
+	receiveBacklog = 1024
+	// pollInterval is the wait between two reads of an empty receive queue.
+	pollInterval = time.Millisecond
+)
+
+// CANlib message flags.
+const (
+	msgRTR        = 0x0001
+	msgStandard   = 0x0002
+	msgExtended   = 0x0004
+	msgErrorFrame = 0x0020
+	msgTxAck      = 0x0040
+)
+
+// CANlib status codes and options.
+const (
+	statusOK    = 0
+	statusNoMsg = -2
+
+	openAcceptVirtual = 0x0020
+
+	driverSilent = 1
+	driverNormal = 4
+)
+
+// bitrates maps the CAN bitrates to the predefined canBITRATE_xxx parameters.
+var bitrates = map[int]int32{
+	1000000: -1, 500000: -2, 250000: -3, 125000: -4, 100000: -5,
+	62500: -6, 50000: -7, 83333: -8, 10000: -9,
+}
+
+// ErrNotAvailable is returned by Dial when the backend is not compiled in.
+var ErrNotAvailable = errors.New("Kvaser CANlib support is not compiled in (build for Windows with -tags kvaser)")
+
+// Config describes how an adapter is opened.
+type Config struct {
+	// Channel is the CANlib channel number, counting the channels of all the
+	// connected adapters.
+	Channel int
+	Bitrate int
+	// Virtual accepts the virtual channels of the Kvaser driver.
+	Virtual    bool
+	ListenOnly bool
+}
+
+// api is the part of CANlib the backend uses, returning canStatus codes.
+type api interface {
+	openChannel(channel int, flags int32) int32
+	setBusParams(handle int32, freq int32) int32
+	setBusOutputControl(handle int32, driver int32) int32
+	busOn(handle int32) int32
+	busOff(handle int32) int32
+	close(handle int32) int32
+	read(handle int32, id *int32, data *[8]byte, dlc, flags *uint32, time *uint32) int32
+	write(handle int32, id int32, data *[8]byte, dlc, flags uint32) int32
+	errorText(status int32) string
+}
+
+// IsURL reports whether name is a Kvaser URL such as "kvaser://0?bitrate=500000".
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "kvaser://")
+}
+
+// ParseURL decodes "kvaser://<channel>" with the optional query parameters
+// bitrate, virtual and listen.
+func ParseURL(rawURL string) (Config, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Config{}, err
+	}
+	if u.Scheme != "kvaser" {
+		return Config{}, fmt.Errorf("invalid Kvaser URL %q, want kvaser://0?bitrate=500000", rawURL)
+	}
+	cfg := Config{Bitrate: DefaultBitrate}
+	if u.Host != "" {
+		if cfg.Channel, err = strconv.Atoi(u.Host); err != nil || cfg.Channel < 0 {
+			return Config{}, fmt.Errorf("invalid channel in Kvaser URL %q", rawURL)
+		}
+	}
+	q := u.Query()
+	if v := q.Get("bitrate"); v != "" {
+		if cfg.Bitrate, err = strconv.Atoi(v); err != nil {
+			return Config{}, fmt.Errorf("invalid bitrate in Kvaser URL %q", rawURL)
+		}
+	}
+	for key, dst := range map[string]*bool{"virtual": &cfg.Virtual, "listen": &cfg.ListenOnly} {
+		if v := q.Get(key); v != "" {
+			if *dst, err = strconv.ParseBool(v); err != nil {
+				return Config{}, fmt.Errorf("invalid %s in Kvaser URL %q", key, rawURL)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// Conn is an open CANlib channel. It implements canbus.Bus.
+type Conn struct {
+	api    api
+	handle int32
+
+	frames chan canbus.Frame
+	done   chan struct{}
+	once   sync.Once
+	closed atomic.Bool
+
+	mu      sync.Mutex
+	filters []canbus.Filter
+	readErr error
+	// apiMu serializes the calls on the handle, which CANlib does not allow
+	// concurrently.
+	apiMu sync.Mutex
+}
+
+var _ canbus.Bus = (*Conn)(nil)
+
+// Dial opens the channel of cfg and goes bus on.
+func Dial(cfg Config) (*Conn, error) {
+	a, err := loadAPI()
+	if err != nil {
+		return nil, err
+	}
+	return open(a, cfg)
+}
+
+func open(a api, cfg Config) (*Conn, error) {
+	freq, ok := bitrates[cfg.Bitrate]
+	if !ok {
+		return nil, fmt.Errorf("kvaser: unsupported bitrate %d", cfg.Bitrate)
+	}
+	var flags int32
+	if cfg.Virtual {
+		flags |= openAcceptVirtual
+	}
+	h := a.openChannel(cfg.Channel, flags)
+	if h < 0 {
+		return nil, fmt.Errorf("kvaser: open channel %d: %s", cfg.Channel, a.errorText(h))
+	}
+	fail := func(op string, st int32) (*Conn, error) {
+		_ = a.close(h)
+		return nil, fmt.Errorf("kvaser: %s: %s", op, a.errorText(st))
+	}
+	if st := a.setBusParams(h, freq); st != statusOK {
+		return fail("set bitrate", st)
+	}
+	driver := int32(driverNormal)
+	if cfg.ListenOnly {
+		driver = driverSilent
+	}
+	if st := a.setBusOutputControl(h, driver); st != statusOK {
+		return fail("set output control", st)
+	}
+	if st := a.busOn(h); st != statusOK {
+		return fail("bus on", st)
+	}
+	c := &Conn{
+		api:    a,
+		handle: h,
+		frames: make(chan canbus.Frame, receiveBacklog),
+		done:   make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// readLoop polls the receive queue of the driver until the channel is closed.
+func (c *Conn) readLoop() {
+	defer close(c.done)
+
+	var data [8]byte
+	var id int32
+	var dlc, flags, ts uint32
+	for !c.closed.Load() {
+		c.apiMu.Lock()
+		st := c.api.read(c.handle, &id, &data, &dlc, &flags, &ts)
+		c.apiMu.Unlock()
+		switch {
+		case st == statusNoMsg:
+			time.Sleep(pollInterval)
+			continue
+		case st != statusOK:
+			if !c.closed.Load() {
+				c.mu.Lock()
+				c.readErr = errors.New(c.api.errorText(st))
+				c.mu.Unlock()
+			}
+			return
+		}
+		f, ok := c.decode(uint32(id), data, dlc, flags)
+		if !ok {
+			continue
+		}
+		select {
+		case c.frames <- f:
+		default:
+			// the application does not keep up, drop the frame
+		}
+	}
+}
+
+func (c *Conn) decode(id uint32, data [8]byte, dlc, flags uint32) (canbus.Frame, bool) {
+	// error frames have no SocketCAN error class, transmit acknowledges are
+	// the frames sent on the handle
+	if flags&(msgErrorFrame|msgTxAck) != 0 {
+		return canbus.Frame{}, false
+	}
+	f := canbus.Frame{
+		ID:         id,
+		IsExtended: flags&msgExtended != 0,
+		IsRemote:   flags&msgRTR != 0,
+		Length:     uint8(min(dlc, canbus.MaxDataLength)),
+	}
+	copy(f.Data[:], data[:f.Length])
+	if f.Validate() != nil {
+		return canbus.Frame{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return f, canbus.MatchAny(c.filters, &f)
+}
+
+// ReadFrame blocks until the next frame is received.
+func (c *Conn) ReadFrame() (canbus.Frame, error) {
+	select {
+	case f := <-c.frames:
+		return f, nil
+	case <-c.done:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.readErr != nil && !c.closed.Load() {
+			return canbus.Frame{}, fmt.Errorf("kvaser: %w", c.readErr)
+		}
+		return canbus.Frame{}, net.ErrClosed
+	}
+}
+
+// WriteFrame transmits a classic frame.
+func (c *Conn) WriteFrame(_ context.Context, f canbus.Frame) error {
+	if f.IsFD {
+		return errors.New("kvaser: CAN FD frames are not supported")
+	}
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	if c.closed.Load() {
+		return net.ErrClosed
+	}
+	flags := uint32(msgStandard)
+	if f.IsExtended {
+		flags = msgExtended
+	}
+	if f.IsRemote {
+		flags |= msgRTR
+	}
+	var data [8]byte
+	copy(data[:], f.Payload())
+	c.apiMu.Lock()
+	st := c.api.write(c.handle, int32(f.ID), &data, uint32(f.Length), flags)
+	c.apiMu.Unlock()
+	if st != statusOK {
+		return fmt.Errorf("kvaser: write: %s", c.api.errorText(st))
+	}
+	return nil
+}
+
+// SetFilters filters the received frames on the host side.
+func (c *Conn) SetFilters(filters []canbus.Filter) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filters = append([]canbus.Filter{}, filters...)
+	return nil
+}
+
+// Close goes bus off and closes the channel.
+func (c *Conn) Close() error {
+	var err error
+	c.once.Do(func() {
+		c.closed.Store(true)
+		<-c.done
+		_ = c.api.busOff(c.handle)
+		if st := c.api.close(c.handle); st != statusOK {
+			err = fmt.Errorf("kvaser: close: %s", c.api.errorText(st))
+		}
+	})
+	return err
+}
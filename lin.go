@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/candb"
+	"canproject/lin"
+)
+
+// LDFInfo describes an LDF loaded with LoadLDF.
+type LDFInfo struct {
+	Path            string   `json:"path"`
+	ProtocolVersion string   `json:"protocolVersion"`
+	Bitrate         int      `json:"bitrate"`
+	Master          string   `json:"master"`
+	Slaves          []string `json:"slaves"`
+	Frames          int      `json:"frames"`
+	Signals         int      `json:"signals"`
+	// ScheduleTables are the names of the schedule tables.
+	ScheduleTables []string `json:"scheduleTables"`
+}
+
+// LINScheduleStatus describes a schedule table run with StartLINSchedule.
+type LINScheduleStatus struct {
+	Interface string `json:"interface"`
+	Table     string `json:"table"`
+	// Cycles is the number of times the table ran, Errors the slots that failed,
+	// eg a slave frame without response.
+	Cycles uint64 `json:"cycles"`
+	Errors uint64 `json:"errors"`
+}
+
+// linCluster is a loaded LDF and the database its frames are decoded with.
+type linCluster struct {
+	ldf *lin.LDF
+	db  *candb.Database
+}
+
+type linSchedule struct {
+	iface  string
+	table  *lin.ScheduleTable
+	ldf    *lin.LDF
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// cycles and errors are updated under linMu.
+	cycles uint64
+	errors uint64
+}
+
+// isLINInterface reports whether iface is a LIN bus: a serial LIN adapter
+// (lin://) or an sllin SocketCAN interface.
+func isLINInterface(iface string) bool {
+	return strings.HasPrefix(iface, "lin://") || strings.HasPrefix(iface, "sllin")
+}
+
+// LoadLDF loads a LIN description file. The frames received on the LIN
+// interfaces are decoded with it instead of the CAN databases, and its schedule
+// tables can be run with StartLINSchedule. An LDF loaded from the same path is
+// replaced.
+func (a *App) LoadLDF(path string) (LDFInfo, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return LDFInfo{}, fmt.Errorf("LDF path is empty")
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	ldf, err := lin.LoadLDF(path)
+	if err != nil {
+		return LDFInfo{}, err
+	}
+	db, err := ldf.Database()
+	if err != nil {
+		return LDFInfo{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	a.linMu.Lock()
+	defer a.linMu.Unlock()
+	c := &linCluster{ldf: ldf, db: db}
+	for i, old := range a.linClusters {
+		if old.ldf.SourceFile == path {
+			a.linClusters[i] = c
+			return ldfInfo(ldf), nil
+		}
+	}
+	a.linClusters = append(a.linClusters, c)
+	return ldfInfo(ldf), nil
+}
+
+// UnloadLDF removes an LDF loaded with LoadLDF. The schedules running its
+// tables keep running.
+func (a *App) UnloadLDF(path string) error {
+	if abs, err := filepath.Abs(strings.TrimSpace(path)); err == nil {
+		path = abs
+	}
+
+	a.linMu.Lock()
+	defer a.linMu.Unlock()
+	for i, c := range a.linClusters {
+		if c.ldf.SourceFile == path {
+			a.linClusters = append(a.linClusters[:i], a.linClusters[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no LDF loaded from %s", path)
+}
+
+// ListLDFs returns the loaded LDFs.
+func (a *App) ListLDFs() []LDFInfo {
+	a.linMu.Lock()
+	defer a.linMu.Unlock()
+
+	infos := make([]LDFInfo, len(a.linClusters))
+	for i, c := range a.linClusters {
+		infos[i] = ldfInfo(c.ldf)
+	}
+	return infos
+}
+
+// SetLINFrameData sets the payload the schedules send for a frame published by
+// the master, instead of the initial values of its signals.
+func (a *App) SetLINFrameData(frame string, data []byte) error {
+	frame = strings.TrimSpace(frame)
+	a.linMu.Lock()
+	defer a.linMu.Unlock()
+
+	for _, c := range a.linClusters {
+		if f, ok := c.ldf.Frame(frame); ok {
+			if len(data) != f.Length {
+				return fmt.Errorf("frame %s has %d bytes, got %d", frame, f.Length, len(data))
+			}
+			if a.linData == nil {
+				a.linData = make(map[string][]byte)
+			}
+			a.linData[frame] = append([]byte(nil), data...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no LIN frame %s in the loaded LDFs", frame)
+}
+
+// StartLINSchedule runs a schedule table of the loaded LDFs in a loop on a
+// started LIN interface, replacing the table running there: the frames the
+// master publishes are sent with their data, the headers of the frames the
+// slaves publish are sent for them to answer. The node configuration slots
+// are skipped.
+func (a *App) StartLINSchedule(iface, table string) error {
+	iface = strings.TrimSpace(iface)
+	table = strings.TrimSpace(table)
+	if !isLINInterface(iface) {
+		return fmt.Errorf("%s is not a LIN interface", iface)
+	}
+	if _, err := a.session(iface); err != nil {
+		return err
+	}
+
+	a.linMu.Lock()
+	var ldf *lin.LDF
+	var t *lin.ScheduleTable
+	for _, c := range a.linClusters {
+		if st, ok := c.ldf.ScheduleTable(table); ok {
+			ldf, t = c.ldf, st
+			break
+		}
+	}
+	a.linMu.Unlock()
+	if t == nil {
+		return fmt.Errorf("no schedule table %s in the loaded LDFs", table)
+	}
+	var cycle time.Duration
+	for _, e := range t.Entries {
+		cycle += e.Delay
+	}
+	if cycle <= 0 {
+		return fmt.Errorf("schedule table %s has no slot", table)
+	}
+
+	a.StopLINSchedule(iface)
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &linSchedule{iface: iface, table: t, ldf: ldf, cancel: cancel, done: make(chan struct{})}
+	a.linMu.Lock()
+	if a.linSchedules == nil {
+		a.linSchedules = make(map[string]*linSchedule)
+	}
+	a.linSchedules[iface] = s
+	a.linMu.Unlock()
+	go a.linScheduleLoop(ctx, s)
+	return nil
+}
+
+// StopLINSchedule stops the schedule table running on iface, if any.
+func (a *App) StopLINSchedule(iface string) {
+	iface = strings.TrimSpace(iface)
+	a.linMu.Lock()
+	s := a.linSchedules[iface]
+	delete(a.linSchedules, iface)
+	a.linMu.Unlock()
+
+	if s != nil {
+		s.cancel()
+		<-s.done
+	}
+}
+
+// ListLINSchedules returns the running schedule tables by interface.
+func (a *App) ListLINSchedules() []LINScheduleStatus {
+	a.linMu.Lock()
+	defer a.linMu.Unlock()
+
+	list := make([]LINScheduleStatus, 0, len(a.linSchedules))
+	for _, s := range a.linSchedules {
+		list = append(list, LINScheduleStatus{Interface: s.iface, Table: s.table.Name, Cycles: s.cycles, Errors: s.errors})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Interface < list[j].Interface })
+	return list
+}
+
+// linScheduleLoop runs the slots of s at the times of the table, so that a slot
+// running late does not shift the next ones.
+func (a *App) linScheduleLoop(ctx context.Context, s *linSchedule) {
+	defer close(s.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	next := time.Now()
+	failing := false
+	for {
+		for _, e := range s.table.Entries {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+			next = next.Add(e.Delay)
+			timer.Reset(time.Until(next))
+
+			f, ok := a.linSlot(s.ldf, e.Frame)
+			if !ok {
+				continue
+			}
+			err := a.send(s.iface, f)
+			if err != nil {
+				a.linMu.Lock()
+				s.errors++
+				a.linMu.Unlock()
+			}
+			// report the first failure of a run only, the next cycles would repeat it
+			if err != nil && !failing && ctx.Err() == nil {
+				a.emitError(fmt.Errorf("LIN schedule %s, frame %s: %w", s.table.Name, e.Frame, err))
+			}
+			failing = err != nil
+		}
+		a.linMu.Lock()
+		s.cycles++
+		a.linMu.Unlock()
+	}
+}
+
+// linSlot returns the frame a schedule slot sends, ok is false for the node
+// configuration slots.
+func (a *App) linSlot(ldf *lin.LDF, name string) (canbus.Frame, bool) {
+	lf, ok := ldf.Frame(name)
+	if !ok {
+		return canbus.Frame{}, false
+	}
+	f := canbus.Frame{ID: uint32(lf.ID), Length: uint8(lf.Length)}
+	if lf.Publisher == "" || lf.Publisher != ldf.Master {
+		f.IsRemote = true
+		return f, true
+	}
+	a.linMu.Lock()
+	data := a.linData[name]
+	a.linMu.Unlock()
+	if data == nil {
+		data = lf.InitialData()
+	}
+	copy(f.Data[:], data)
+	return f, true
+}
+
+// lookupLINMessage returns the LDF frame of a LIN ID.
+func (a *App) lookupLINMessage(id uint32) (*candb.Message, bool) {
+	a.linMu.Lock()
+	defer a.linMu.Unlock()
+
+	for _, c := range a.linClusters {
+		if m, ok := c.db.Message(id, false); ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+func ldfInfo(ldf *lin.LDF) LDFInfo {
+	info := LDFInfo{
+		Path:            ldf.SourceFile,
+		ProtocolVersion: ldf.ProtocolVersion,
+		Bitrate:         ldf.Bitrate,
+		Master:          ldf.Master,
+		Slaves:          append([]string{}, ldf.Slaves...),
+		Frames:          len(ldf.Frames),
+		Signals:         len(ldf.Signals),
+		ScheduleTables:  []string{},
+	}
+	for _, t := range ldf.ScheduleTables {
+		info.ScheduleTables = append(info.ScheduleTables, t.Name)
+	}
+	return info
+}
@@ -0,0 +1,716 @@
+package lin
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"canproject/candb"
+)
+
+// LDF is a LIN description file: the nodes, signals, frames and schedule
+// tables of a LIN cluster.
+type LDF struct {
+	// SourceFile is the path the LDF was loaded from.
+	SourceFile      string
+	ProtocolVersion string
+	// Bitrate is the LIN speed in bit/s.
+	Bitrate int
+	// Master is the master node, TimeBase the unit of its schedule delays and
+	// Jitter its scheduling jitter.
+	Master   string
+	TimeBase time.Duration
+	Jitter   time.Duration
+	Slaves   []string
+	Signals  []*Signal
+	Frames   []*Frame
+	// ScheduleTables are the schedules the master runs.
+	ScheduleTables []*ScheduleTable
+	// Encodings are the signal encoding types by name.
+	Encodings map[string]*Encoding
+}
+
+// Signal is a signal of the LDF.
+type Signal struct {
+	Name string
+	// Size is the size in bits, up to 64 and a multiple of 8 for byte array signals.
+	Size int
+	// Init is the initial value, InitArray the one of a byte array signal.
+	Init      uint64
+	InitArray []byte
+	Publisher string
+	// Subscribers are the nodes receiving the signal.
+	Subscribers []string
+	// Encoding is the encoding type of the signal (Signal_representation), nil
+	// for raw values.
+	Encoding *Encoding
+	// Diagnostic is set for the signals of the diagnostic frames.
+	Diagnostic bool
+}
+
+// Frame is an unconditional or diagnostic frame of the LDF.
+type Frame struct {
+	Name      string
+	ID        uint8
+	Publisher string
+	// Length is the payload length in bytes.
+	Length  int
+	Signals []FrameSignal
+}
+
+// FrameSignal places a signal in a frame at a bit offset.
+type FrameSignal struct {
+	Signal *Signal
+	Offset int
+}
+
+// ScheduleTable is a schedule of frame slots the master runs in a loop.
+type ScheduleTable struct {
+	Name    string
+	Entries []ScheduleEntry
+}
+
+// ScheduleEntry is a slot of a schedule table.
+type ScheduleEntry struct {
+	// Frame is the frame of the slot, or the command, eg AssignNAD {LSM}, for
+	// the node configuration slots.
+	Frame string
+	// Delay is the length of the slot.
+	Delay time.Duration
+}
+
+// Encoding is a signal encoding type.
+type Encoding struct {
+	Name     string
+	Logical  []LogicalValue
+	Physical []PhysicalRange
+}
+
+// LogicalValue is a label for a raw value.
+type LogicalValue struct {
+	Value uint64
+	Text  string
+}
+
+// PhysicalRange is the scaling of the raw values from Min to Max.
+type PhysicalRange struct {
+	Min, Max      uint64
+	Scale, Offset float64
+	Unit          string
+}
+
+// LoadLDF reads and parses an LDF file.
+func LoadLDF(path string) (*LDF, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ldf, err := ParseLDF(path, data)
+	if err != nil {
+		return nil, err
+	}
+	return ldf, nil
+}
+
+// ParseLDF parses the content of an LDF file, filename is used in errors and as
+// SourceFile. The unconditional and diagnostic frames, their signals and
+// encodings and the schedule tables are read; the sporadic and event triggered
+// frames and the node attributes are ignored.
+func ParseLDF(filename string, data []byte) (*LDF, error) {
+	toks, err := ldfTokens(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	p := &ldfParser{toks: toks}
+	root, err := p.block(false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	ldf := &LDF{SourceFile: filename, Bitrate: DefaultBitrate, Encodings: map[string]*Encoding{}}
+	if err := ldf.build(root); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return ldf, nil
+}
+
+// Frame returns the frame with the given name.
+func (l *LDF) Frame(name string) (*Frame, bool) {
+	for _, f := range l.Frames {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// FrameByID returns the frame with the given ID.
+func (l *LDF) FrameByID(id uint8) (*Frame, bool) {
+	for _, f := range l.Frames {
+		if f.ID == id {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// ScheduleTable returns the schedule table with the given name.
+func (l *LDF) ScheduleTable(name string) (*ScheduleTable, bool) {
+	for _, t := range l.ScheduleTables {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Checksum returns the checksum model of the frames: classic for LIN 1.x,
+// enhanced otherwise.
+func (l *LDF) Checksum() ChecksumModel {
+	if strings.HasPrefix(l.ProtocolVersion, "1.") {
+		return ChecksumClassic
+	}
+	return ChecksumEnhanced
+}
+
+// InitialData returns the payload of f with the initial values of its signals.
+func (f *Frame) InitialData() []byte {
+	data := make([]byte, f.Length)
+	for _, fs := range f.Signals {
+		s := fs.Signal
+		if s.InitArray != nil {
+			for i, b := range s.InitArray {
+				if start := fs.Offset/8 + i; start < len(data) {
+					data[start] = b
+				}
+			}
+			continue
+		}
+		for bit := 0; bit < s.Size; bit++ {
+			pos := fs.Offset + bit
+			if pos/8 < len(data) && s.Init>>bit&1 != 0 {
+				data[pos/8] |= 1 << (pos % 8)
+			}
+		}
+	}
+	return data
+}
+
+// Database returns the frames of the LDF as a signal database, to decode the
+// frames received on a LIN interface: the messages have the LIN IDs and the
+// little-endian signals of the frames.
+func (l *LDF) Database() (*candb.Database, error) {
+	db := candb.NewDatabase(l.SourceFile)
+	db.Version = l.ProtocolVersion
+	if l.Master != "" {
+		db.Nodes = append(db.Nodes, l.Master)
+	}
+	db.Nodes = append(db.Nodes, l.Slaves...)
+	for _, f := range l.Frames {
+		m := &candb.Message{Name: f.Name, ID: uint32(f.ID), Length: f.Length, Sender: f.Publisher}
+		for _, fs := range f.Signals {
+			s := fs.Signal
+			cs := &candb.Signal{
+				Name:      s.Name,
+				Start:     fs.Offset,
+				Length:    s.Size,
+				Scale:     1,
+				Max:       math.Pow(2, float64(s.Size)) - 1,
+				Receivers: append([]string(nil), s.Subscribers...),
+			}
+			if e := s.Encoding; e != nil {
+				if len(e.Physical) > 0 {
+					r := e.Physical[0]
+					cs.Scale, cs.Offset, cs.Unit = r.Scale, r.Offset, r.Unit
+					cs.Min, cs.Max = float64(r.Min)*r.Scale+r.Offset, float64(r.Max)*r.Scale+r.Offset
+				}
+				for _, lv := range e.Logical {
+					cs.ValueDescriptions = append(cs.ValueDescriptions, candb.ValueDescription{Value: int64(lv.Value), Description: lv.Text})
+				}
+			}
+			m.Signals = append(m.Signals, cs)
+		}
+		if err := db.SetMessage("", m); err != nil {
+			return nil, fmt.Errorf("frame %s: %w", f.Name, err)
+		}
+	}
+	return db, nil
+}
+
+// ldfStatement is a statement of an LDF, its tokens up to ; and its block
+// when it has one.
+type ldfStatement struct {
+	toks  []string
+	block []*ldfStatement
+	line  int
+}
+
+type ldfParser struct {
+	toks []ldfToken
+	pos  int
+}
+
+type ldfToken struct {
+	text string
+	line int
+	// str is set for string literals.
+	str bool
+}
+
+// ldfTokens splits an LDF into words, numbers, strings and punctuation,
+// dropping the comments.
+func ldfTokens(s string) ([]ldfToken, error) {
+	var toks []ldfToken
+	line := 1
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(s[i:], "//"):
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(s[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"':
+			end := strings.IndexByte(s[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			toks = append(toks, ldfToken{text: s[i+1 : i+1+end], line: line, str: true})
+			line += strings.Count(s[i+1:i+1+end], "\n")
+			i += end + 2
+		case strings.IndexByte("{};,:=", c) >= 0:
+			toks = append(toks, ldfToken{text: s[i : i+1], line: line})
+			i++
+		default:
+			j := i
+			for j < len(s) && !unicode.IsSpace(rune(s[j])) && strings.IndexByte("{};,:=\"", s[j]) < 0 &&
+				!strings.HasPrefix(s[j:], "//") && !strings.HasPrefix(s[j:], "/*") {
+				j++
+			}
+			toks = append(toks, ldfToken{text: s[i:j], line: line})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+// block parses statements up to the closing brace, or the end of the file at
+// the top level.
+func (p *ldfParser) block(nested bool) ([]*ldfStatement, error) {
+	var stmts []*ldfStatement
+	for {
+		if p.pos >= len(p.toks) {
+			if nested {
+				return nil, errors.New("unexpected end of file, missing }")
+			}
+			return stmts, nil
+		}
+		if p.toks[p.pos].text == "}" && !p.toks[p.pos].str {
+			if !nested {
+				return nil, fmt.Errorf("line %d: unexpected }", p.toks[p.pos].line)
+			}
+			p.pos++
+			return stmts, nil
+		}
+		st, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, st)
+	}
+}
+
+// statement parses the tokens of a statement up to ; or its block. A brace
+// list without ; such as {0, 0} or {LSM} is a single token.
+func (p *ldfParser) statement() (*ldfStatement, error) {
+	st := &ldfStatement{line: p.toks[p.pos].line}
+	for p.pos < len(p.toks) {
+		t := p.toks[p.pos]
+		if t.str {
+			st.toks = append(st.toks, "\""+t.text)
+			p.pos++
+			continue
+		}
+		switch t.text {
+		case ";":
+			p.pos++
+			return st, nil
+		case "}":
+			// a last statement without ;
+			return st, nil
+		case "{":
+			if list, n, ok := p.inlineList(); ok {
+				st.toks = append(st.toks, list)
+				p.pos += n
+				continue
+			}
+			p.pos++
+			block, err := p.block(true)
+			if err != nil {
+				return nil, err
+			}
+			st.block = block
+			if p.pos < len(p.toks) && p.toks[p.pos].text == ";" {
+				p.pos++
+			}
+			return st, nil
+		default:
+			st.toks = append(st.toks, t.text)
+			p.pos++
+		}
+	}
+	return st, nil
+}
+
+// inlineList returns the brace list at the current token as "{a,b}" and its
+// number of tokens, ok is false for a block.
+func (p *ldfParser) inlineList() (string, int, bool) {
+	var b strings.Builder
+	b.WriteString("{")
+	for i := p.pos + 1; i < len(p.toks); i++ {
+		t := p.toks[i]
+		if t.str {
+			return "", 0, false
+		}
+		switch t.text {
+		case "}":
+			if i == p.pos+1 {
+				// an empty block
+				return "", 0, false
+			}
+			b.WriteString("}")
+			return b.String(), i - p.pos + 1, true
+		case ";", "{":
+			return "", 0, false
+		}
+		b.WriteString(t.text)
+	}
+	return "", 0, false
+}
+
+// fields splits the tokens of a statement at the commas.
+func (st *ldfStatement) fields(toks []string) [][]string {
+	fields := [][]string{nil}
+	for _, t := range toks {
+		if t == "," {
+			fields = append(fields, nil)
+			continue
+		}
+		fields[len(fields)-1] = append(fields[len(fields)-1], t)
+	}
+	return fields
+}
+
+// head splits a "name: a, b" statement into its name and the fields after :.
+func (st *ldfStatement) head() (string, [][]string, bool) {
+	if len(st.toks) < 2 || st.toks[1] != ":" {
+		return "", nil, false
+	}
+	return st.toks[0], st.fields(st.toks[2:]), true
+}
+
+func (st *ldfStatement) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", st.line, fmt.Sprintf(format, args...))
+}
+
+func (l *LDF) build(root []*ldfStatement) error {
+	signals := map[string]*Signal{}
+	sections := map[string]*ldfStatement{}
+	for _, st := range root {
+		if len(st.toks) == 0 {
+			continue
+		}
+		if st.block != nil || len(st.toks) == 1 {
+			sections[st.toks[0]] = st
+			continue
+		}
+		if len(st.toks) >= 3 && st.toks[1] == "=" {
+			value := strings.TrimPrefix(st.toks[2], "\"")
+			switch st.toks[0] {
+			case "LIN_protocol_version":
+				l.ProtocolVersion = value
+			case "LIN_speed":
+				kbps, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return st.errorf("invalid LIN_speed %q", value)
+				}
+				l.Bitrate = int(math.Round(kbps * 1000))
+			}
+		}
+	}
+	if _, ok := sections["LIN_description_file"]; !ok {
+		return errors.New("not an LDF file, LIN_description_file is missing")
+	}
+
+	if st := sections["Nodes"]; st != nil {
+		for _, n := range st.block {
+			name, fields, ok := n.head()
+			if !ok {
+				continue
+			}
+			switch name {
+			case "Master":
+				if len(fields) < 1 || len(fields[0]) != 1 {
+					return n.errorf("invalid Master")
+				}
+				l.Master = fields[0][0]
+				if len(fields) > 1 {
+					l.TimeBase, _ = ldfDuration(fields[1])
+				}
+				if len(fields) > 2 {
+					l.Jitter, _ = ldfDuration(fields[2])
+				}
+			case "Slaves":
+				for _, f := range fields {
+					if len(f) == 1 {
+						l.Slaves = append(l.Slaves, f[0])
+					}
+				}
+			}
+		}
+	}
+
+	for _, section := range []string{"Signals", "Diagnostic_signals"} {
+		st := sections[section]
+		if st == nil {
+			continue
+		}
+		for _, n := range st.block {
+			name, fields, ok := n.head()
+			if !ok || len(fields) < 2 {
+				return n.errorf("invalid signal")
+			}
+			s := &Signal{Name: name, Diagnostic: section == "Diagnostic_signals"}
+			size, err := ldfInt(fields[0])
+			if err != nil || size < 1 || size > 64 {
+				return n.errorf("signal %s: invalid size", name)
+			}
+			s.Size = int(size)
+			if len(fields[1]) == 1 && strings.HasPrefix(fields[1][0], "{") {
+				for _, v := range strings.Split(strings.Trim(fields[1][0], "{}"), ",") {
+					b, err := strconv.ParseUint(v, 0, 8)
+					if err != nil {
+						return n.errorf("signal %s: invalid initial value", name)
+					}
+					s.InitArray = append(s.InitArray, byte(b))
+				}
+			} else if s.Init, err = ldfInt(fields[1]); err != nil {
+				return n.errorf("signal %s: invalid initial value", name)
+			}
+			if len(fields) > 2 && len(fields[2]) == 1 {
+				s.Publisher = fields[2][0]
+			}
+			for _, f := range fields[min(3, len(fields)):] {
+				if len(f) == 1 {
+					s.Subscribers = append(s.Subscribers, f[0])
+				}
+			}
+			signals[name] = s
+			l.Signals = append(l.Signals, s)
+		}
+	}
+
+	for _, section := range []string{"Frames", "Diagnostic_frames"} {
+		st := sections[section]
+		if st == nil {
+			continue
+		}
+		for _, n := range st.block {
+			f, err := l.frame(n, signals, section == "Diagnostic_frames")
+			if err != nil {
+				return err
+			}
+			l.Frames = append(l.Frames, f)
+		}
+	}
+
+	if st := sections["Signal_encoding_types"]; st != nil {
+		for _, n := range st.block {
+			if len(n.toks) != 1 {
+				return n.errorf("invalid signal encoding type")
+			}
+			e, err := ldfEncoding(n)
+			if err != nil {
+				return err
+			}
+			l.Encodings[e.Name] = e
+		}
+	}
+	if st := sections["Signal_representation"]; st != nil {
+		for _, n := range st.block {
+			name, fields, ok := n.head()
+			e := l.Encodings[name]
+			if !ok || e == nil {
+				return n.errorf("unknown signal encoding type %s", name)
+			}
+			for _, f := range fields {
+				if len(f) == 1 && signals[f[0]] != nil {
+					signals[f[0]].Encoding = e
+				}
+			}
+		}
+	}
+
+	if st := sections["Schedule_tables"]; st != nil {
+		for _, n := range st.block {
+			if len(n.toks) != 1 {
+				return n.errorf("invalid schedule table")
+			}
+			t := &ScheduleTable{Name: n.toks[0]}
+			for _, e := range n.block {
+				i := indexOf(e.toks, "delay")
+				if i < 1 {
+					return e.errorf("schedule table %s: entry without delay", t.Name)
+				}
+				d, err := ldfDuration(e.toks[i+1:])
+				if err != nil {
+					return e.errorf("schedule table %s: %v", t.Name, err)
+				}
+				t.Entries = append(t.Entries, ScheduleEntry{Frame: strings.Join(e.toks[:i], " "), Delay: d})
+			}
+			l.ScheduleTables = append(l.ScheduleTables, t)
+		}
+	}
+	return nil
+}
+
+// frame parses "name: id, publisher, length { signal, offset; }" or, for the
+// diagnostic frames, "name: id { signal, offset; }".
+func (l *LDF) frame(st *ldfStatement, signals map[string]*Signal, diagnostic bool) (*Frame, error) {
+	name, fields, ok := st.head()
+	if !ok || len(fields) < 1 {
+		return nil, st.errorf("invalid frame")
+	}
+	id, err := ldfInt(fields[0])
+	if err != nil || id > MaxID {
+		return nil, st.errorf("frame %s: invalid ID", name)
+	}
+	f := &Frame{Name: name, ID: uint8(id), Length: 8}
+	if diagnostic {
+		f.Publisher = l.Master
+		if id == SlaveResponseID {
+			f.Publisher = ""
+		}
+	} else {
+		if len(fields) < 3 || len(fields[1]) != 1 {
+			return nil, st.errorf("frame %s: want ID, publisher and length", name)
+		}
+		f.Publisher = fields[1][0]
+		n, err := ldfInt(fields[2])
+		if err != nil || n < 1 || n > 8 {
+			return nil, st.errorf("frame %s: invalid length", name)
+		}
+		f.Length = int(n)
+	}
+	for _, n := range st.block {
+		fs := n.fields(n.toks)
+		if len(fs) != 2 || len(fs[0]) != 1 {
+			return nil, n.errorf("frame %s: invalid signal", name)
+		}
+		s := signals[fs[0][0]]
+		if s == nil {
+			return nil, n.errorf("frame %s: unknown signal %s", name, fs[0][0])
+		}
+		offset, err := ldfInt(fs[1])
+		if err != nil || int(offset)+s.Size > 8*f.Length {
+			return nil, n.errorf("frame %s: signal %s does not fit", name, s.Name)
+		}
+		f.Signals = append(f.Signals, FrameSignal{Signal: s, Offset: int(offset)})
+	}
+	return f, nil
+}
+
+func ldfEncoding(st *ldfStatement) (*Encoding, error) {
+	e := &Encoding{Name: st.toks[0]}
+	for _, n := range st.block {
+		fs := n.fields(n.toks)
+		if len(fs[0]) != 1 {
+			return nil, n.errorf("encoding %s: invalid value", e.Name)
+		}
+		switch fs[0][0] {
+		case "logical_value":
+			if len(fs) < 2 {
+				return nil, n.errorf("encoding %s: invalid logical value", e.Name)
+			}
+			v, err := ldfInt(fs[1])
+			if err != nil {
+				return nil, n.errorf("encoding %s: invalid logical value", e.Name)
+			}
+			lv := LogicalValue{Value: v}
+			if len(fs) > 2 && len(fs[2]) == 1 {
+				lv.Text = strings.TrimPrefix(fs[2][0], "\"")
+			}
+			e.Logical = append(e.Logical, lv)
+		case "physical_value":
+			if len(fs) < 5 {
+				return nil, n.errorf("encoding %s: invalid physical value", e.Name)
+			}
+			var r PhysicalRange
+			var errs [4]error
+			r.Min, errs[0] = ldfInt(fs[1])
+			r.Max, errs[1] = ldfInt(fs[2])
+			r.Scale, errs[2] = ldfFloat(fs[3])
+			r.Offset, errs[3] = ldfFloat(fs[4])
+			if err := errors.Join(errs[:]...); err != nil {
+				return nil, n.errorf("encoding %s: invalid physical value", e.Name)
+			}
+			if len(fs) > 5 && len(fs[5]) == 1 {
+				r.Unit = strings.TrimPrefix(fs[5][0], "\"")
+			}
+			e.Physical = append(e.Physical, r)
+		case "bcd_value", "ascii_value":
+		default:
+			return nil, n.errorf("encoding %s: unknown value %s", e.Name, fs[0][0])
+		}
+	}
+	return e, nil
+}
+
+// ldfInt parses a decimal or 0x hex integer field.
+func ldfInt(field []string) (uint64, error) {
+	if len(field) != 1 {
+		return 0, errors.New("want a number")
+	}
+	return strconv.ParseUint(field[0], 0, 64)
+}
+
+func ldfFloat(field []string) (float64, error) {
+	if len(field) != 1 {
+		return 0, errors.New("want a number")
+	}
+	return strconv.ParseFloat(field[0], 64)
+}
+
+// ldfDuration parses "5 ms" or "5ms".
+func ldfDuration(toks []string) (time.Duration, error) {
+	s := strings.Join(toks, "")
+	ms, err := strconv.ParseFloat(strings.TrimSuffix(s, "ms"), 64)
+	if err != nil || !strings.HasSuffix(s, "ms") || ms < 0 {
+		return 0, fmt.Errorf("invalid duration %q, want ms", s)
+	}
+	return time.Duration(ms * float64(time.Millisecond)), nil
+}
+
+func indexOf(toks []string, s string) int {
+	for i, t := range toks {
+		if t == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,93 @@
+// Package lin handles LIN bus frames: the protected identifiers and checksums
+// of the LIN specification, the LDF description files with their schedule
+// tables, and serial LIN adapters.
+//
+// LIN frames are carried as canbus.Frame values, as the sllin SocketCAN driver
+// does: the frame ID is the LIN ID (0-63) and a remote frame is a header alone,
+// which the slave publishing the frame answers.
+package lin
+
+import (
+	"fmt"
+
+	"canproject/canbus"
+)
+
+const (
+	// MaxID is the largest LIN frame ID.
+	MaxID = 0x3F
+	// MasterRequestID and SlaveResponseID are the IDs of the diagnostic frames,
+	// whose checksum is always classic.
+	MasterRequestID = 0x3C
+	SlaveResponseID = 0x3D
+	// DefaultBitrate is the usual LIN bitrate.
+	DefaultBitrate = 19200
+)
+
+// ChecksumModel is how the checksum of a frame is computed.
+type ChecksumModel int
+
+const (
+	// ChecksumEnhanced is the LIN 2.x checksum over the protected ID and the data.
+	ChecksumEnhanced ChecksumModel = iota
+	// ChecksumClassic is the LIN 1.x checksum over the data only.
+	ChecksumClassic
+)
+
+// String returns "enhanced" or "classic".
+func (m ChecksumModel) String() string {
+	if m == ChecksumClassic {
+		return "classic"
+	}
+	return "enhanced"
+}
+
+// ParseChecksumModel parses "enhanced" or "classic".
+func ParseChecksumModel(s string) (ChecksumModel, error) {
+	switch s {
+	case "enhanced", "":
+		return ChecksumEnhanced, nil
+	case "classic":
+		return ChecksumClassic, nil
+	}
+	return 0, fmt.Errorf("lin: unknown checksum model %q, want enhanced or classic", s)
+}
+
+// PID returns the protected identifier of a frame ID: the ID with its two
+// parity bits.
+func PID(id uint8) uint8 {
+	id &= MaxID
+	bit := func(n uint) uint8 { return id >> n & 1 }
+	p0 := bit(0) ^ bit(1) ^ bit(2) ^ bit(4)
+	p1 := ^(bit(1) ^ bit(3) ^ bit(4) ^ bit(5)) & 1
+	return id | p0<<6 | p1<<7
+}
+
+// Checksum returns the checksum of a frame: the inverted eight bit sum with
+// carry of the data, and of the protected ID for the enhanced model. The
+// diagnostic frames always use the classic model.
+func Checksum(model ChecksumModel, id uint8, data []byte) uint8 {
+	var sum uint16
+	if model == ChecksumEnhanced && id != MasterRequestID && id != SlaveResponseID {
+		sum = uint16(PID(id))
+	}
+	for _, b := range data {
+		sum += uint16(b)
+		if sum > 0xFF {
+			sum -= 0xFF
+		}
+	}
+	return ^uint8(sum)
+}
+
+// Validate checks that f is a LIN frame: a standard ID up to MaxID and at
+// most 8 data bytes.
+func Validate(f *canbus.Frame) error {
+	if f.IsExtended || f.IsFD || f.ID > MaxID {
+		return fmt.Errorf("lin: frame %s is not a LIN frame, want an ID up to 0x3F", f)
+	}
+	if f.Length == 0 || f.Length > 8 {
+		return fmt.Errorf("lin: frame %s must have 1 to 8 data bytes", f)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package lin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+
+	"canproject/canbus"
+)
+
+const (
+	// breakBits is the length of the break field, in bit times.
+	breakBits = 13
+	// responseMargin is the LIN response time tolerance, 40% over the nominal
+	// frame time; adapterLatency covers the serial adapter buffering.
+	responseMargin = 1.4
+	adapterLatency = 10 * time.Millisecond
+	receiveBacklog = 256
+)
+
+// Config describes how a serial LIN adapter is opened: a LIN transceiver
+// behind a UART, the app being the master of the cluster.
+type Config struct {
+	// Port is the serial port, eg /dev/ttyUSB0 or COM3.
+	Port string
+	// Bitrate is the LIN speed, which is the baud rate of the port.
+	Bitrate  int
+	Checksum ChecksumModel
+}
+
+// ParseURL decodes "lin:///dev/ttyUSB0" or "lin://COM3" with the optional query
+// parameters bitrate and checksum (enhanced or classic).
+func ParseURL(rawURL string) (Config, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg := Config{Port: u.Host + u.Path, Bitrate: DefaultBitrate}
+	if u.Scheme != "lin" || cfg.Port == "" {
+		return Config{}, fmt.Errorf("invalid LIN URL %q, want lin:///dev/ttyUSB0?bitrate=19200", rawURL)
+	}
+	q := u.Query()
+	if v := q.Get("bitrate"); v != "" {
+		if cfg.Bitrate, err = strconv.Atoi(v); err != nil || cfg.Bitrate < 1000 || cfg.Bitrate > 20000 {
+			return Config{}, fmt.Errorf("invalid bitrate in LIN URL %q, want 1000 to 20000", rawURL)
+		}
+	}
+	if cfg.Checksum, err = ParseChecksumModel(strings.ToLower(q.Get("checksum"))); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// breaker is the port of a Conn, which sends the break fields.
+type breaker interface {
+	io.ReadWriteCloser
+	Break(time.Duration) error
+}
+
+// Conn is the master of a LIN cluster on a serial adapter. It implements
+// canbus.Bus: writing a frame sends its header and data, writing a remote frame
+// sends the header alone and the response of the slave is received with
+// ReadFrame. The frames are sent one at a time, each waiting for its response.
+type Conn struct {
+	port     breaker
+	bitrate  int
+	checksum ChecksumModel
+
+	// bytes are the bytes read from the bus, including the echo of the ones written.
+	bytes  chan byte
+	frames chan canbus.Frame
+	closed chan struct{}
+	once   sync.Once
+	// readErr is set when the serial port failed, before closed is closed.
+	readErr error
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	filters []canbus.Filter
+}
+
+var _ canbus.Bus = (*Conn)(nil)
+
+// Dial opens the serial port of cfg.
+func Dial(cfg Config) (*Conn, error) {
+	port, err := serial.Open(cfg.Port, &serial.Mode{BaudRate: cfg.Bitrate})
+	if err != nil {
+		return nil, fmt.Errorf("lin: open %s: %w", cfg.Port, err)
+	}
+	return Open(port, cfg.Bitrate, cfg.Checksum), nil
+}
+
+// Open returns the master of the cluster connected through port. port is
+// closed with the Conn.
+func Open(port breaker, bitrate int, checksum ChecksumModel) *Conn {
+	c := &Conn{
+		port:     port,
+		bitrate:  bitrate,
+		checksum: checksum,
+		bytes:    make(chan byte, 1024),
+		frames:   make(chan canbus.Frame, receiveBacklog),
+		closed:   make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+func (c *Conn) readLoop() {
+	buf := make([]byte, 64)
+	for {
+		n, err := c.port.Read(buf)
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+		for _, b := range buf[:n] {
+			select {
+			case c.bytes <- b:
+			default:
+				// bytes nobody waits for, outside of a frame
+			}
+		}
+	}
+}
+
+// ReadFrame blocks until the next slave response is received.
+func (c *Conn) ReadFrame() (canbus.Frame, error) {
+	select {
+	case f := <-c.frames:
+		return f, nil
+	case <-c.closed:
+		return canbus.Frame{}, c.closeErr()
+	}
+}
+
+// WriteFrame sends the header of f, followed by its data unless f is a remote
+// frame whose Length is the length of the response to wait for.
+func (c *Conn) WriteFrame(ctx context.Context, f canbus.Frame) error {
+	if err := Validate(&f); err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	select {
+	case <-c.closed:
+		return c.closeErr()
+	default:
+	}
+	c.drain()
+	id := uint8(f.ID)
+	bit := time.Second / time.Duration(c.bitrate)
+	if err := c.port.Break(breakBits * bit); err != nil {
+		return fmt.Errorf("lin: send break: %w", err)
+	}
+	msg := []byte{0x55, PID(id)}
+	if !f.IsRemote {
+		msg = append(msg, f.Payload()...)
+		msg = append(msg, Checksum(c.checksum, id, f.Payload()))
+	}
+	if _, err := c.port.Write(msg); err != nil {
+		return fmt.Errorf("lin: write: %w", err)
+	}
+
+	// the transceiver echoes the frame, then the slave answers a header
+	want := len(msg)
+	if f.IsRemote {
+		want += int(f.Length) + 1
+	}
+	// nominal frame time: 34 bits of header and 10 bits per byte of response
+	nominal := time.Duration(34+10*(int(f.Length)+1)) * bit
+	timeout := time.Duration(float64(nominal)*responseMargin) + adapterLatency
+	if d, ok := ctx.Deadline(); ok && time.Until(d) < timeout {
+		timeout = time.Until(d)
+	}
+	got, err := c.receive(msg, want, timeout)
+	if err != nil {
+		return err
+	}
+	if !f.IsRemote {
+		return nil
+	}
+	resp := got[len(msg):]
+	data, sum := resp[:len(resp)-1], resp[len(resp)-1]
+	if Checksum(c.checksum, id, data) != sum {
+		return fmt.Errorf("lin: frame 0x%02X: checksum error", id)
+	}
+	rx := canbus.Frame{ID: f.ID, Length: f.Length}
+	copy(rx.Data[:], data)
+	if c.accept(&rx) {
+		select {
+		case c.frames <- rx:
+		default:
+			// the application does not keep up, drop the frame
+		}
+	}
+	return nil
+}
+
+// receive reads the echo of msg and the bytes after it, up to want bytes. The
+// break reads as zero bytes before the echo, they are skipped.
+func (c *Conn) receive(msg []byte, want int, timeout time.Duration) ([]byte, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	got := make([]byte, 0, want)
+	for len(got) < want {
+		select {
+		case b := <-c.bytes:
+			if len(got) == 0 && b != msg[0] {
+				continue
+			}
+			got = append(got, b)
+			if n := len(got); n <= len(msg) && b != msg[n-1] {
+				return nil, fmt.Errorf("lin: frame 0x%02X: bus collision, read back 0x%02X for 0x%02X", msg[1]&MaxID, b, msg[n-1])
+			}
+		case <-timer.C:
+			if len(got) <= len(msg) {
+				if len(got) < len(msg) {
+					return nil, errors.New("lin: no echo from the transceiver")
+				}
+				return nil, fmt.Errorf("lin: frame 0x%02X: no response", msg[1]&MaxID)
+			}
+			return nil, fmt.Errorf("lin: frame 0x%02X: incomplete response", msg[1]&MaxID)
+		case <-c.closed:
+			return nil, c.closeErr()
+		}
+	}
+	return got, nil
+}
+
+// drain discards the bytes received since the last frame.
+func (c *Conn) drain() {
+	for {
+		select {
+		case <-c.bytes:
+		default:
+			return
+		}
+	}
+}
+
+// SetFilters filters the received frames on the host side.
+func (c *Conn) SetFilters(filters []canbus.Filter) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filters = append([]canbus.Filter{}, filters...)
+	return nil
+}
+
+func (c *Conn) accept(f *canbus.Frame) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return canbus.MatchAny(c.filters, f)
+}
+
+// Close closes the serial port.
+func (c *Conn) Close() error {
+	c.shutdown(nil)
+	return c.port.Close()
+}
+
+func (c *Conn) shutdown(err error) {
+	c.once.Do(func() {
+		c.readErr = err
+		close(c.closed)
+	})
+}
+
+// closeErr is the error of operations on a closed connection.
+func (c *Conn) closeErr() error {
+	if c.readErr != nil && !errors.Is(c.readErr, io.EOF) {
+		return fmt.Errorf("lin: %w", c.readErr)
+	}
+	return net.ErrClosed
+}
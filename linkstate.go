@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+)
+
+// reconnectMinDelay and reconnectMaxDelay bound the backoff between reconnection attempts.
+const (
+	reconnectMinDelay = 250 * time.Millisecond
+	reconnectMaxDelay = 10 * time.Second
+)
+
+// LinkEvent is a change of an interface, emitted on "can:link".
+type LinkEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	// State is "up", "down" or "removed" when the kernel reports a change of a
+	// SocketCAN interface; for a started interface it is "lost" when its connection
+	// failed, "reconnecting" before each reconnection attempt and "reconnected"
+	// once the session is restored.
+	State string `json:"state"`
+	// Running is true for an interface which is up and operational, a bus-off
+	// controller is not.
+	Running bool `json:"running"`
+	// Attempt and DelayMs are the number and the delay of a reconnection attempt.
+	Attempt int `json:"attempt,omitempty"`
+	DelayMs int `json:"delayMs,omitempty"`
+	// Error is the failure which lost the connection or of the previous attempt.
+	Error string `json:"error,omitempty"`
+}
+
+// linkState is the last reported state of a SocketCAN interface.
+type linkState struct {
+	up, running bool
+}
+
+// SetAutoReconnect enables or disables the reconnection of a started interface
+// when its connection fails, eg when the interface goes down or its USB adapter is
+// unplugged. The session is kept while reconnecting, with backoff, and its filters,
+// socket options and cyclic frames are restored once the interface is back.
+func (a *App) SetAutoReconnect(iface string, enabled bool) error {
+	sess, err := a.session(iface)
+	if err != nil {
+		return err
+	}
+	sess.reconnect.Store(enabled)
+	return nil
+}
+
+// watchLinks emits the changes of the SocketCAN interfaces on "can:link" until ctx is done.
+func (a *App) watchLinks(ctx context.Context) {
+	links, err := canbus.Links()
+	if err != nil {
+		// no SocketCAN on this system
+		return
+	}
+	a.linkMu.Lock()
+	a.linkStates = make(map[string]linkState, len(links))
+	for _, l := range links {
+		a.linkStates[l.Name] = linkState{up: l.Up, running: l.Running}
+	}
+	a.linkMu.Unlock()
+
+	err = canbus.WatchLinks(ctx, a.linkChanged)
+	if err != nil && ctx.Err() == nil {
+		a.emitError(fmt.Errorf("link monitor: %w", err))
+	}
+}
+
+func (a *App) linkChanged(l canbus.Link, removed bool) {
+	st := linkState{up: l.Up, running: l.Running}
+	a.linkMu.Lock()
+	prev, known := a.linkStates[l.Name]
+	if removed {
+		delete(a.linkStates, l.Name)
+	} else {
+		a.linkStates[l.Name] = st
+	}
+	a.linkMu.Unlock()
+	if !removed && known && prev == st {
+		// the kernel also notifies changes of other attributes
+		return
+	}
+
+	ev := LinkEvent{Timestamp: time.Now(), Interface: l.Name, State: "down", Running: st.running}
+	switch {
+	case removed:
+		ev.State, ev.Running = "removed", false
+	case st.up:
+		ev.State = "up"
+	}
+	a.emit("can:link", ev)
+	if removed || !known {
+		a.emitInterfaces()
+	}
+
+	if st.up && st.running && !removed {
+		a.mu.Lock()
+		sess := a.sessions[l.Name]
+		a.mu.Unlock()
+		if sess != nil {
+			// wake up a pending reconnection
+			select {
+			case sess.linkUp <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// reconnect replaces the failed connection of sess, retrying with backoff until it
+// succeeds or the session is stopped. It reports whether the session was restored.
+func (a *App) reconnect(sess *canSession, cause error) bool {
+	a.emit("can:link", LinkEvent{Timestamp: time.Now(), Interface: sess.iface, State: "lost", Error: cause.Error()})
+
+	sess.reconnecting.Store(true)
+	defer sess.reconnecting.Store(false)
+	delay := reconnectMinDelay
+	lastErr := cause
+	for attempt := 1; ; attempt++ {
+		a.emit("can:link", LinkEvent{
+			Timestamp: time.Now(),
+			Interface: sess.iface,
+			State:     "reconnecting",
+			Attempt:   attempt,
+			DelayMs:   int(delay / time.Millisecond),
+			Error:     lastErr.Error(),
+		})
+		timer := time.NewTimer(delay)
+		select {
+		case <-sess.ctx.Done():
+			timer.Stop()
+			return false
+		case <-sess.linkUp:
+			timer.Stop()
+		case <-timer.C:
+		}
+		delay = min(2*delay, reconnectMaxDelay)
+
+		conn, err := a.redial(sess)
+		if err != nil {
+			if sess.ctx.Err() != nil {
+				return false
+			}
+			lastErr = err
+			continue
+		}
+
+		a.mu.Lock()
+		old := sess.conn
+		sess.conn = conn
+		a.mu.Unlock()
+		_ = old.Close()
+		if sess.ctx.Err() != nil {
+			// stopped while dialing, stopSession may have closed the old connection only
+			_ = conn.Close()
+			return false
+		}
+		a.restartCyclicFrames(sess.iface)
+
+		sess.reconnecting.Store(false)
+		l, _ := canbus.LinkByName(sess.iface)
+		a.emit("can:link", LinkEvent{Timestamp: time.Now(), Interface: sess.iface, State: "reconnected", Running: l.Running, Attempt: attempt})
+		return true
+	}
+}
+
+// redial opens the interface of sess again with its filters and socket options.
+func (a *App) redial(sess *canSession) (canbus.Bus, error) {
+	if !strings.Contains(sess.iface, "://") {
+		// a SocketCAN socket can be bound to an interface which is down, wait for it
+		l, err := canbus.LinkByName(sess.iface)
+		if err != nil {
+			return nil, err
+		}
+		if !l.Up {
+			return nil, fmt.Errorf("%s is down", sess.iface)
+		}
+	}
+	conn, err := a.dialBus(sess.ctx, sess.iface, sess.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	filters, opts := sess.filters, sess.sockOpts
+	a.mu.Unlock()
+	if err := restoreConn(conn, filters, opts); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// restoreConn applies the filters and socket options of a session to a new connection.
+func restoreConn(conn canbus.Bus, filters []CANFilter, opts SocketOptions) error {
+	if filters != nil {
+		raw := make([]canbus.Filter, len(filters))
+		for i, f := range filters {
+			raw[i] = canbus.Filter{ID: f.ID, Mask: f.Mask, Extended: f.Extended, Invert: f.Invert}
+		}
+		if err := conn.SetFilters(raw); err != nil {
+			return fmt.Errorf("restore filters: %w", err)
+		}
+	}
+	if opts == defaultSocketOptions {
+		return nil
+	}
+	so, ok := conn.(socketOptioner)
+	if !ok {
+		return errors.New("restore socket options: not a SocketCAN interface")
+	}
+	if err := so.SetLoopback(opts.Loopback); err != nil {
+		return fmt.Errorf("restore socket options: %w", err)
+	}
+	if err := so.SetReceiveOwnMessages(opts.ReceiveOwn); err != nil {
+		return fmt.Errorf("restore socket options: %w", err)
+	}
+	return nil
+}
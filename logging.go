@@ -0,0 +1,313 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+	"canproject/canlog"
+)
+
+// logFlushInterval bounds how much of a log is lost if the app dies.
+const logFlushInterval = time.Second
+
+// errFrameSkipped is returned by trace writers for frames their format does not record.
+var errFrameSkipped = errors.New("frame not recorded")
+
+// traceWriter writes frames in a trace file format.
+type traceWriter interface {
+	WriteFrame(ts time.Time, iface string, f canbus.Frame, tx bool) error
+	Flush() error
+}
+
+// candumpTrace writes candump logs, which do not record the direction.
+type candumpTrace struct {
+	*canlog.CandumpWriter
+}
+
+func (c candumpTrace) WriteFrame(ts time.Time, iface string, f canbus.Frame, _ bool) error {
+	return c.CandumpWriter.WriteFrame(ts, iface, f)
+}
+
+type frameLogger struct {
+	path      string
+	includeTx bool
+	format    string
+
+	mu     sync.Mutex
+	f      *os.File
+	w      traceWriter
+	frames int
+	err    error
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// LoggingStatus describes the active log file.
+type LoggingStatus struct {
+	Active    bool   `json:"active"`
+	Path      string `json:"path"`
+	IncludeTx bool   `json:"includeTx"`
+	Frames    int    `json:"frames"`
+	// Format is "candump", "asc", "blf" or "chunked", "pcapng" for pcap
+	// captures and "mdf4" for MDF recordings.
+	Format string `json:"format"`
+}
+
+// StartLogging writes every received frame of all started interfaces to path in
+// candump log format, so captures can be replayed with canplayer. includeTx also
+// logs the frames sent by the app. Paths ending in .asc or .blf are written in
+// the Vector ASC or BLF format instead, for CANoe and CANalyzer, and paths
+// ending in .ccap in the crash-safe chunked format of package canlog: it is
+// synced to the disk every second with its index (the path plus ".idx"), and
+// RecoverCapture salvages it after a crash.
+func (a *App) StartLogging(path string, includeTx bool) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return errors.New("log path is empty")
+	}
+
+	a.logMu.Lock()
+	defer a.logMu.Unlock()
+	if a.logger != nil {
+		return fmt.Errorf("already logging to %s", a.logger.path)
+	}
+
+	format := logFormat(path)
+	l, err := newFrameLogger(path, format, includeTx, func(f *os.File) (traceWriter, error) {
+		return openTrace(format, f)
+	})
+	if err != nil {
+		return err
+	}
+	a.logger = l
+	return nil
+}
+
+// StopLogging flushes and closes the log file started with StartLogging.
+func (a *App) StopLogging() (LoggingStatus, error) {
+	a.logMu.Lock()
+	l := a.logger
+	a.logger = nil
+	a.logMu.Unlock()
+
+	if l == nil {
+		return LoggingStatus{}, nil
+	}
+	status := l.status()
+	return status, l.close()
+}
+
+// GetLoggingStatus returns the state of the log file started with StartLogging.
+func (a *App) GetLoggingStatus() LoggingStatus {
+	a.logMu.Lock()
+	l := a.logger
+	a.logMu.Unlock()
+
+	if l == nil {
+		return LoggingStatus{}
+	}
+	return l.status()
+}
+
+// CaptureRecovery is the result of RecoverCapture.
+type CaptureRecovery struct {
+	Path string `json:"path"`
+	// Chunks counts the complete chunks kept and Frames their frames, Indexed
+	// the chunks the index listed before the recovery.
+	Chunks  int `json:"chunks"`
+	Frames  int `json:"frames"`
+	Indexed int `json:"indexed"`
+	// First and Last are the timestamps of the first and the last frame kept.
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+	// TruncatedBytes is the size of the incomplete tail removed, Torn tells
+	// why it was.
+	TruncatedBytes int64  `json:"truncatedBytes"`
+	Torn           string `json:"torn,omitempty"`
+}
+
+// RecoverCapture repairs a chunked capture (.ccap) cut by a crash of the app or
+// of the machine: the frames up to its last complete chunk are kept, the
+// incomplete tail is removed and the index is rebuilt. The capture can then be
+// imported with ImportCapture or replayed.
+func (a *App) RecoverCapture(path string) (CaptureRecovery, error) {
+	path = strings.TrimSpace(path)
+	if logFormat(path) != "chunked" {
+		return CaptureRecovery{}, fmt.Errorf("%s is not a chunked capture, want a .ccap path", path)
+	}
+	a.logMu.Lock()
+	logging := a.logger != nil && a.logger.path == path
+	a.logMu.Unlock()
+	if logging {
+		return CaptureRecovery{}, fmt.Errorf("%s is being logged to", path)
+	}
+
+	rec, err := canlog.RecoverChunked(path)
+	if err != nil {
+		return CaptureRecovery{}, err
+	}
+	res := CaptureRecovery{
+		Path:           path,
+		Chunks:         len(rec.Chunks),
+		Frames:         len(rec.Records),
+		Indexed:        rec.Indexed,
+		TruncatedBytes: rec.Truncated,
+		Torn:           rec.Torn,
+	}
+	if n := len(rec.Records); n > 0 {
+		res.First, res.Last = rec.Records[0].Timestamp, rec.Records[n-1].Timestamp
+	}
+	return res, nil
+}
+
+// logFrame keeps a frame in the capture buffer and appends it to the active log,
+// capture, recording and trigger, if any.
+func (a *App) logFrame(iface string, ts time.Time, f *canbus.Frame, tx bool) {
+	a.capture.Add(ts, iface, f, tx)
+	if t := a.trigger.Load(); t != nil {
+		t.add(ts, iface, f, tx)
+	}
+
+	a.logMu.Lock()
+	loggers := [...]*frameLogger{a.logger, a.pcap, a.mdf}
+	a.logMu.Unlock()
+
+	for _, l := range loggers {
+		if l == nil || (tx && !l.includeTx) {
+			continue
+		}
+		if err := l.write(iface, ts, f, tx); err != nil {
+			a.emitError(fmt.Errorf("log %s: %w", l.path, err))
+		}
+	}
+}
+
+// logFormat returns the trace format of path from its extension.
+func logFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".asc":
+		return "asc"
+	case ".blf":
+		return "blf"
+	case ".ccap":
+		return "chunked"
+	default:
+		return "candump"
+	}
+}
+
+// openTrace returns a trace writer of format (see logFormat) on f.
+func openTrace(format string, f *os.File) (traceWriter, error) {
+	switch format {
+	case "asc":
+		return canlog.NewASCWriter(f), nil
+	case "blf":
+		return canlog.NewBLFWriter(f)
+	case "chunked":
+		return canlog.NewChunkedWriter(f)
+	default:
+		return candumpTrace{canlog.NewCandumpWriter(f)}, nil
+	}
+}
+
+// newFrameLogger creates the file path and a trace writer of format on it.
+func newFrameLogger(path, format string, includeTx bool, open func(*os.File) (traceWriter, error)) (*frameLogger, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := open(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	l := &frameLogger{
+		path:      path,
+		includeTx: includeTx,
+		format:    format,
+		f:         f,
+		w:         w,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go l.flushLoop()
+	return l, nil
+}
+
+// write reports only the first write error, later frames are dropped silently.
+func (l *frameLogger) write(iface string, ts time.Time, f *canbus.Frame, tx bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return nil
+	}
+	if err := l.w.WriteFrame(ts, iface, *f, tx); err == errFrameSkipped {
+		return nil
+	} else if err != nil {
+		l.err = err
+		return err
+	}
+	l.frames++
+	return nil
+}
+
+func (l *frameLogger) flushLoop() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(logFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			l.mu.Lock()
+			if l.err == nil {
+				l.err = l.w.Flush()
+			}
+			l.mu.Unlock()
+		}
+	}
+}
+
+func (l *frameLogger) close() error {
+	close(l.stop)
+	<-l.done
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	err := l.err
+	// formats with a trailer write it on Close
+	finish := l.w.Flush
+	if c, ok := l.w.(interface{ Close() error }); ok {
+		finish = c.Close
+	}
+	if ferr := finish(); err == nil {
+		err = ferr
+	}
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (l *frameLogger) status() LoggingStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return LoggingStatus{
+		Active:    true,
+		Path:      l.path,
+		IncludeTx: l.includeTx,
+		Frames:    l.frames,
+		Format:    l.format,
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -15,6 +16,17 @@ func main() {
 	// Create an instance of the app structure
 	app := NewApp()
 
+	if cfg, headless, err := parseHeadless(os.Args[1:]); headless {
+		if err == nil {
+			err = runHeadless(app, cfg)
+		}
+		if err != nil {
+			println("Error:", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create application with options
 	err := wails.Run(&options.App{
 		Title:  "canproject",
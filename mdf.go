@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/candb"
+	"canproject/mdf"
+)
+
+type messageKey struct {
+	id       uint32
+	extended bool
+}
+
+// mdfTrace records the signals of the received messages of the loaded databases.
+type mdfTrace struct {
+	*mdf.Writer
+	iface    string
+	start    time.Time
+	messages map[messageKey]mdfMessage
+}
+
+type mdfMessage struct {
+	group int
+	msg   *candb.Message
+}
+
+// StartMDFRecording writes the decoded signals of the frames received on iface, or on
+// all interfaces when iface is empty, to an MDF4 file with a channel group per message
+// of the loaded databases. Frames of unknown messages are not recorded.
+func (a *App) StartMDFRecording(path string, iface string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return errors.New("recording path is empty")
+	}
+
+	a.dbMu.RLock()
+	var groups []mdf.Group
+	messages := make(map[messageKey]mdfMessage)
+	for _, db := range a.databases {
+		for _, m := range db.Messages {
+			key := messageKey{m.ID, m.IsExtended}
+			// the first database defining an ID decodes it, as for "can:signals"
+			if _, ok := messages[key]; ok {
+				continue
+			}
+			messages[key] = mdfMessage{group: len(groups), msg: m}
+			g := mdf.Group{Name: m.Name, Comment: m.Description}
+			for _, s := range m.Signals {
+				g.Channels = append(g.Channels, mdf.Channel{Name: s.Name, Unit: s.Unit})
+			}
+			groups = append(groups, g)
+		}
+	}
+	a.dbMu.RUnlock()
+	if len(groups) == 0 {
+		return errors.New("no DBC loaded: MDF recordings hold decoded signals")
+	}
+
+	a.logMu.Lock()
+	defer a.logMu.Unlock()
+	if a.mdf != nil {
+		return fmt.Errorf("already recording to %s", a.mdf.path)
+	}
+
+	l, err := newFrameLogger(path, "mdf4", false, func(f *os.File) (traceWriter, error) {
+		start := time.Now()
+		w, err := mdf.NewWriter(f, start, "CanSocket", groups)
+		if err != nil {
+			return nil, err
+		}
+		return &mdfTrace{Writer: w, iface: strings.TrimSpace(iface), start: start, messages: messages}, nil
+	})
+	if err != nil {
+		return err
+	}
+	a.mdf = l
+	return nil
+}
+
+// StopMDFRecording finalizes and closes the recording started with StartMDFRecording.
+func (a *App) StopMDFRecording() (LoggingStatus, error) {
+	a.logMu.Lock()
+	l := a.mdf
+	a.mdf = nil
+	a.logMu.Unlock()
+
+	if l == nil {
+		return LoggingStatus{}, nil
+	}
+	status := l.status()
+	return status, l.close()
+}
+
+// GetMDFStatus returns the state of the recording started with StartMDFRecording.
+func (a *App) GetMDFStatus() LoggingStatus {
+	a.logMu.Lock()
+	l := a.mdf
+	a.logMu.Unlock()
+
+	if l == nil {
+		return LoggingStatus{}
+	}
+	return l.status()
+}
+
+func (t *mdfTrace) WriteFrame(ts time.Time, iface string, f canbus.Frame, _ bool) error {
+	if f.IsError || f.IsRemote || (t.iface != "" && iface != t.iface) {
+		return errFrameSkipped
+	}
+	m, ok := t.messages[messageKey{f.ID, f.IsExtended}]
+	if !ok {
+		return errFrameSkipped
+	}
+	values := make([]float64, len(m.msg.Signals))
+	valid := make([]bool, len(m.msg.Signals))
+	payload := f.Payload()
+	for i, s := range m.msg.Signals {
+		if !m.msg.Present(s, payload) {
+			continue
+		}
+		if v, ok := s.Decode(payload); ok {
+			values[i], valid[i] = v.Physical, true
+		}
+	}
+	return t.Write(m.group, ts.Sub(t.start), values, valid)
+}
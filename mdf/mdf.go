@@ -0,0 +1,337 @@
+// Package mdf writes ASAM MDF 4.1 measurement files, as read by CANape,
+// vSignalyzer and asammdf.
+//
+// The file has one unsorted data group whose channel groups hold the records of
+// one CAN message each: a float64 time master channel in seconds followed by a
+// float64 channel per signal, with invalidation bits for the signals absent from
+// a record. Records are streamed into a single DT block whose length and the
+// cycle counters are written by Close; until then the file is marked unfinalized
+// so readers can recover an interrupted measurement.
+package mdf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	idBlockSize = 64
+	// recordIDSize is the size of the record ID preceding each record of the unsorted data group.
+	recordIDSize = 2
+
+	// id_unfin_flags: update of the cycle counters and of the length of the last DT block required.
+	unfinCycleCounters = 1 << 0
+	unfinDTLength      = 1 << 2
+
+	channelTypeMaster = 2
+	syncTypeTime      = 1
+	dataTypeRealLE    = 4
+	channelFlagInval  = 1 << 1
+
+	sourceTypeBus = 2
+	busTypeCAN    = 2
+
+	hdTimeFlagOffsetsValid = 1 << 1
+)
+
+// Group describes the records of a channel group, eg the signals of a CAN message.
+type Group struct {
+	// Name is the acquisition name of the group.
+	Name     string
+	Comment  string
+	Channels []Channel
+}
+
+// Channel is a signal of a group.
+type Channel struct {
+	Name string
+	Unit string
+}
+
+// Writer streams records to an MDF file.
+type Writer struct {
+	ws     io.WriteSeeker
+	w      *bufio.Writer
+	start  time.Time
+	groups []groupState
+
+	dtAddr uint64
+	dtSize uint64
+	rec    []byte
+}
+
+type groupState struct {
+	addr     uint64 // address of the CG block
+	channels int
+	cycles   uint64
+}
+
+// NewWriter writes the header and the channel descriptions of groups to w.
+// The times passed to Write are relative to start.
+func NewWriter(w io.WriteSeeker, start time.Time, tool string, groups []Group) (*Writer, error) {
+	if len(groups) == 0 {
+		return nil, errors.New("mdf: no channel groups")
+	}
+	if len(groups) >= 1<<(8*recordIDSize) {
+		return nil, fmt.Errorf("mdf: too many channel groups (%d)", len(groups))
+	}
+
+	b := &blocks{}
+	hd := b.add("HD", 6, hdData(start))
+	fh := b.add("FH", 2, fhData(start))
+	b.link(hd, 1, fh)
+	b.link(fh, 1, b.md(fmt.Sprintf("<FHcomment><TX>created</TX><tool_id>%s</tool_id><tool_vendor>%s</tool_vendor><tool_version>1.0</tool_version></FHcomment>", xmlEscape(tool), xmlEscape(tool))))
+
+	dg := b.add("DG", 4, append([]byte{recordIDSize}, make([]byte, 7)...))
+	b.link(hd, 0, dg)
+	si := b.add("SI", 3, []byte{sourceTypeBus, busTypeCAN, 0, 0, 0, 0, 0, 0})
+	b.link(si, 0, b.tx("CAN"))
+
+	states := make([]groupState, len(groups))
+	var prevCG uint64
+	for gi, g := range groups {
+		inval := (len(g.Channels) + 7) / 8
+		cg := b.add("CG", 6, cgData(uint64(gi+1), uint32(8*(1+len(g.Channels))), uint32(inval)))
+		if prevCG == 0 {
+			b.link(dg, 1, cg)
+		} else {
+			b.link(prevCG, 0, cg)
+		}
+		prevCG = cg
+		b.link(cg, 2, b.tx(g.Name))
+		b.link(cg, 3, si)
+		if g.Comment != "" {
+			b.link(cg, 5, b.tx(g.Comment))
+		}
+
+		// the time master channel, then the signals
+		cn := b.add("CN", 8, cnData(channelTypeMaster, syncTypeTime, 0, 0))
+		b.link(cn, 2, b.tx("time"))
+		b.link(cn, 6, b.tx("s"))
+		b.link(cg, 1, cn)
+		prev := cn
+		for ci, c := range g.Channels {
+			cn := b.add("CN", 8, cnData(0, 0, uint32(8*(1+ci)), uint32(ci)))
+			b.link(cn, 2, b.tx(c.Name))
+			if c.Unit != "" {
+				b.link(cn, 6, b.tx(c.Unit))
+			}
+			b.link(prev, 0, cn)
+			prev = cn
+		}
+		states[gi] = groupState{addr: cg, channels: len(g.Channels)}
+	}
+
+	dt := b.add("DT", 0, nil)
+	b.link(dg, 2, dt)
+
+	if _, err := w.Write(idBlock(true)); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b.b); err != nil {
+		return nil, err
+	}
+	return &Writer{
+		ws:     w,
+		w:      bufio.NewWriter(w),
+		start:  start,
+		groups: states,
+		dtAddr: dt,
+	}, nil
+}
+
+// Write appends a record to group (an index of the groups of NewWriter) at t after
+// the start. values holds a value per channel; the channels with valid set to false,
+// or beyond valid, are marked invalid.
+func (w *Writer) Write(group int, t time.Duration, values []float64, valid []bool) error {
+	if group < 0 || group >= len(w.groups) {
+		return fmt.Errorf("mdf: invalid channel group %d", group)
+	}
+	g := &w.groups[group]
+	if len(values) != g.channels {
+		return fmt.Errorf("mdf: %d values for %d channels", len(values), g.channels)
+	}
+
+	le := binary.LittleEndian
+	rec := le.AppendUint16(w.rec[:0], uint16(group+1))
+	rec = le.AppendUint64(rec, math.Float64bits(t.Seconds()))
+	for _, v := range values {
+		rec = le.AppendUint64(rec, math.Float64bits(v))
+	}
+	inval := make([]byte, (g.channels+7)/8)
+	for i := range values {
+		if i >= len(valid) || !valid[i] {
+			inval[i/8] |= 1 << (i % 8)
+		}
+	}
+	rec = append(rec, inval...)
+	w.rec = rec
+
+	if _, err := w.w.Write(rec); err != nil {
+		return err
+	}
+	w.dtSize += uint64(len(rec))
+	g.cycles++
+	return nil
+}
+
+// Flush writes buffered records to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Close writes the buffered records, the DT block length and the cycle counters
+// and marks the file finalized. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	le := binary.LittleEndian
+	patch := func(addr uint64, b []byte) error {
+		if _, err := w.ws.Seek(int64(addr), io.SeekStart); err != nil {
+			return err
+		}
+		_, err := w.ws.Write(b)
+		return err
+	}
+	// block length at offset 8 of the header
+	if err := patch(w.dtAddr+8, le.AppendUint64(nil, 24+w.dtSize)); err != nil {
+		return err
+	}
+	for _, g := range w.groups {
+		// cg_cycle_count follows the header, 6 links and cg_record_id
+		if err := patch(g.addr+24+6*8+8, le.AppendUint64(nil, g.cycles)); err != nil {
+			return err
+		}
+	}
+	if err := patch(0, idBlock(false)); err != nil {
+		return err
+	}
+	_, err := w.ws.Seek(0, io.SeekEnd)
+	return err
+}
+
+// idBlock returns the identification block; unfinished files get the unfinalized marks.
+func idBlock(unfinished bool) []byte {
+	b := make([]byte, idBlockSize)
+	copy(b[0:], "MDF     ")
+	if unfinished {
+		copy(b[0:], "UnFinMF ")
+	}
+	copy(b[8:], "4.10    ")
+	copy(b[16:], "CanSockt")
+	binary.LittleEndian.PutUint16(b[28:], 410)
+	if unfinished {
+		binary.LittleEndian.PutUint16(b[60:], unfinCycleCounters|unfinDTLength)
+	}
+	return b
+}
+
+func hdData(start time.Time) []byte {
+	le := binary.LittleEndian
+	_, offset := start.Zone()
+	d := le.AppendUint64(nil, uint64(start.UnixNano()))
+	d = le.AppendUint16(d, uint16(int16(offset/60)))
+	d = le.AppendUint16(d, 0) // DST offset, included in the zone offset
+	d = append(d, hdTimeFlagOffsetsValid, 0, 0, 0)
+	d = le.AppendUint64(d, 0) // start angle
+	d = le.AppendUint64(d, 0) // start distance
+	return d
+}
+
+func fhData(t time.Time) []byte {
+	le := binary.LittleEndian
+	_, offset := t.Zone()
+	d := le.AppendUint64(nil, uint64(t.UnixNano()))
+	d = le.AppendUint16(d, uint16(int16(offset/60)))
+	d = le.AppendUint16(d, 0)
+	return append(d, hdTimeFlagOffsetsValid, 0, 0, 0)
+}
+
+func cgData(recordID uint64, dataBytes, invalBytes uint32) []byte {
+	le := binary.LittleEndian
+	d := le.AppendUint64(nil, recordID)
+	d = le.AppendUint64(d, 0) // cycle count, written by Close
+	d = le.AppendUint16(d, 0) // flags
+	d = le.AppendUint16(d, 0) // path separator
+	d = le.AppendUint32(d, 0)
+	d = le.AppendUint32(d, dataBytes)
+	return le.AppendUint32(d, invalBytes)
+}
+
+// cnData describes a float64 channel at byteOffset of the record.
+func cnData(channelType, syncType uint8, byteOffset, invalBit uint32) []byte {
+	le := binary.LittleEndian
+	var flags uint32
+	if channelType != channelTypeMaster {
+		flags = channelFlagInval
+	}
+	d := []byte{channelType, syncType, dataTypeRealLE, 0}
+	d = le.AppendUint32(d, byteOffset)
+	d = le.AppendUint32(d, 64) // bit count
+	d = le.AppendUint32(d, flags)
+	d = le.AppendUint32(d, invalBit)
+	d = append(d, 0, 0, 0, 0) // precision, attachment count
+	// value range and limits are not used
+	return append(d, make([]byte, 6*8)...)
+}
+
+// blocks lays out the blocks following the identification block.
+type blocks struct {
+	b     []byte
+	texts map[string]uint64
+}
+
+// add appends a block with nlinks nil links and returns its address. The data
+// is zero padded so blocks start on 8 byte boundaries.
+func (bs *blocks) add(id string, nlinks int, data []byte) uint64 {
+	le := binary.LittleEndian
+	addr := uint64(idBlockSize + len(bs.b))
+	for len(data)%8 != 0 {
+		data = append(data, 0)
+	}
+	length := uint64(24 + 8*nlinks + len(data))
+	bs.b = append(bs.b, "##"+id...)
+	bs.b = append(bs.b, 0, 0, 0, 0)
+	bs.b = le.AppendUint64(bs.b, length)
+	bs.b = le.AppendUint64(bs.b, uint64(nlinks))
+	bs.b = append(bs.b, make([]byte, 8*nlinks)...)
+	bs.b = append(bs.b, data...)
+	return addr
+}
+
+// link sets link i of the block at addr.
+func (bs *blocks) link(addr uint64, i int, target uint64) {
+	off := int(addr) - idBlockSize + 24 + 8*i
+	binary.LittleEndian.PutUint64(bs.b[off:], target)
+}
+
+// tx returns a TX block holding s, shared by equal strings.
+func (bs *blocks) tx(s string) uint64 {
+	if addr, ok := bs.texts[s]; ok {
+		return addr
+	}
+	if bs.texts == nil {
+		bs.texts = make(map[string]uint64)
+	}
+	addr := bs.add("TX", 0, append([]byte(s), 0))
+	bs.texts[s] = addr
+	return addr
+}
+
+func (bs *blocks) md(xml string) uint64 {
+	return bs.add("MD", 0, append([]byte(xml), 0))
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
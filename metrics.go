@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// metricFamily is a metric of the Prometheus text format with its samples.
+type metricFamily struct {
+	name    string
+	kind    string
+	help    string
+	samples []metricSample
+}
+
+type metricSample struct {
+	labels []string // name, value pairs
+	value  float64
+}
+
+// busStates are the values of the cansocket_bus_state metric.
+var busStates = []string{"ERROR-ACTIVE", "ERROR-WARNING", "ERROR-PASSIVE", "BUS-OFF", "STOPPED", "SLEEPING"}
+
+// restMetrics serves GET /metrics in the Prometheus text format, so bench setups
+// can be scraped and alerted on:
+//
+//	cansocket_rx_frames_total, cansocket_tx_frames_total, cansocket_bytes_total and
+//	cansocket_error_frames_total count the traffic of every started interface and
+//	cansocket_kernel_dropped_frames_total the frames the kernel dropped before they
+//	were read;
+//	cansocket_frames_per_second and cansocket_bus_load_ratio are the rates of the
+//	last "can:stats" window; cansocket_bus_state, cansocket_bus_errors and
+//	cansocket_bus_off_total describe the controller; cansocket_tx_queue_* the TX
+//	queues; cansocket_dropped_total counts the events, publications and points lost
+//	by the frame batching, the API clients, the MQTT bridge and the signal recording.
+func (a *App) restMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, a.metrics())
+}
+
+// metrics collects the metrics of the app.
+func (a *App) metrics() []metricFamily {
+	rx := metricFamily{name: "cansocket_rx_frames_total", kind: "counter", help: "Frames received."}
+	tx := metricFamily{name: "cansocket_tx_frames_total", kind: "counter", help: "Frames transmitted by the app."}
+	bytes := metricFamily{name: "cansocket_bytes_total", kind: "counter", help: "Payload bytes received and transmitted."}
+	errs := metricFamily{name: "cansocket_error_frames_total", kind: "counter", help: "Error frames received."}
+	rate := metricFamily{name: "cansocket_frames_per_second", kind: "gauge", help: "Frame rate of the last statistics window."}
+	load := metricFamily{name: "cansocket_bus_load_ratio", kind: "gauge", help: "Estimated bus load of the last statistics window, 0 to 1."}
+	state := metricFamily{name: "cansocket_bus_state", kind: "gauge", help: "Controller state, 1 for the current one."}
+	busErrs := metricFamily{name: "cansocket_bus_errors", kind: "gauge", help: "Transmit and receive error counters of the controller."}
+	busOffs := metricFamily{name: "cansocket_bus_off_total", kind: "counter", help: "Bus-off events."}
+	qPending := metricFamily{name: "cansocket_tx_queue_pending", kind: "gauge", help: "Frames waiting in the TX queue."}
+	qDepth := metricFamily{name: "cansocket_tx_queue_depth", kind: "gauge", help: "Capacity of the TX queue."}
+	qOverflows := metricFamily{name: "cansocket_tx_queue_overflows_total", kind: "counter", help: "Frames rejected because the TX queue was full."}
+	qFailed := metricFamily{name: "cansocket_tx_queue_failed_total", kind: "counter", help: "Queued frames which could not be written."}
+	kernelDrops := metricFamily{name: "cansocket_kernel_dropped_frames_total", kind: "counter", help: "Received frames dropped by the kernel because the socket receive queue was full."}
+
+	a.mu.Lock()
+	sessions := make([]*canSession, 0, len(a.sessions))
+	queues := make(map[*canSession]*txQueue)
+	for _, sess := range a.sessions {
+		sessions = append(sessions, sess)
+		if sess.txq != nil {
+			queues[sess] = sess.txq
+		}
+	}
+	a.mu.Unlock()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].iface < sessions[j].iface })
+
+	for _, sess := range sessions {
+		iface := []string{"interface", sess.iface}
+		t := sess.stats.Totals()
+		rx.add(float64(t.TotalFrames-t.TxFrames), iface...)
+		tx.add(float64(t.TxFrames), iface...)
+		bytes.add(float64(t.TotalBytes), iface...)
+		errs.add(float64(t.TotalErrors), iface...)
+		kernelDrops.add(float64(sess.kernelDrops.Load()), iface...)
+		if s := sess.lastStats.Load(); s != nil {
+			rate.add(s.FramesPerSec, iface...)
+			load.add(s.BusLoad/100, iface...)
+		}
+
+		bs := sess.bus.snapshot(sess.iface)
+		for _, name := range busStates {
+			v := 0.0
+			if name == bs.State {
+				v = 1
+			}
+			state.add(v, "interface", sess.iface, "state", name)
+		}
+		if bs.HasCounters {
+			busErrs.add(float64(bs.TxErrors), "interface", sess.iface, "direction", "tx")
+			busErrs.add(float64(bs.RxErrors), "interface", sess.iface, "direction", "rx")
+		}
+		busOffs.add(float64(bs.BusOffCount), iface...)
+
+		if q := queues[sess]; q != nil {
+			qs := q.status()
+			qPending.add(float64(qs.Pending), iface...)
+			qDepth.add(float64(qs.Depth), iface...)
+			qOverflows.add(float64(qs.Overflows), iface...)
+			qFailed.add(float64(qs.Failed), iface...)
+		}
+	}
+
+	dropped := metricFamily{name: "cansocket_dropped_total", kind: "counter", help: "Events, publications and points lost because a consumer did not keep up."}
+	if b := a.batcher.Load(); b != nil {
+		b.mu.Lock()
+		dropped.add(float64(b.totalDropped), "source", "batching")
+		b.mu.Unlock()
+	}
+	if sinks := a.sinks.Load(); sinks != nil {
+		var n uint64
+		counted := false
+		for _, s := range *sinks {
+			if s, ok := s.(interface{ Dropped() uint64 }); ok {
+				n, counted = n+s.Dropped(), true
+			}
+		}
+		if counted {
+			dropped.add(float64(n), "source", "api")
+		}
+	}
+	if s := a.GetMQTTStatus(); s.Running {
+		dropped.add(float64(s.Dropped), "source", "mqtt")
+	}
+	if s := a.GetSignalRecordingStatus(); s.Active {
+		dropped.add(float64(s.Dropped), "source", "signal_recording")
+	}
+
+	started := metricFamily{name: "cansocket_interfaces_started", kind: "gauge", help: "Started interfaces."}
+	started.add(float64(len(sessions)))
+	goroutines := metricFamily{name: "cansocket_goroutines", kind: "gauge", help: "Goroutines of the app."}
+	goroutines.add(float64(runtime.NumGoroutine()))
+
+	return []metricFamily{rx, tx, bytes, errs, rate, load, state, busErrs, busOffs,
+		qPending, qDepth, qOverflows, qFailed, kernelDrops, dropped, started, goroutines}
+}
+
+func (m *metricFamily) add(value float64, labels ...string) {
+	m.samples = append(m.samples, metricSample{labels: labels, value: value})
+}
+
+// writeMetrics writes the families in the Prometheus text exposition format,
+// skipping the ones without samples.
+func writeMetrics(w io.Writer, families []metricFamily) {
+	var b strings.Builder
+	for _, m := range families {
+		if len(m.samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, s := range m.samples {
+			b.WriteString(m.name)
+			if len(s.labels) > 0 {
+				b.WriteByte('{')
+				for i := 0; i+1 < len(s.labels); i += 2 {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					b.WriteString(s.labels[i] + `="` + labelEscaper.Replace(s.labels[i+1]) + `"`)
+				}
+				b.WriteByte('}')
+			}
+			b.WriteByte(' ')
+			b.WriteString(metricValue(s.value))
+			b.WriteByte('\n')
+		}
+	}
+	_, _ = io.WriteString(w, b.String())
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func metricValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
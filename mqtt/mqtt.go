@@ -0,0 +1,508 @@
+// Package mqtt is a minimal MQTT 3.1.1 client: it publishes and subscribes with
+// QoS 0, keeps the connection alive and reconnects with backoff when it drops,
+// subscribing again to its topics.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPort and DefaultTLSPort are the ports of a broker URL without a port.
+	DefaultPort    = "1883"
+	DefaultTLSPort = "8883"
+	// DefaultKeepAlive is the keep alive interval when Options.KeepAlive is zero.
+	DefaultKeepAlive = 30 * time.Second
+
+	dialTimeout = 5 * time.Second
+	minBackoff  = 500 * time.Millisecond
+	maxBackoff  = 30 * time.Second
+)
+
+// Control packet types.
+const (
+	packetConnect     = 1
+	packetConnAck     = 2
+	packetPublish     = 3
+	packetPubAck      = 4
+	packetSubscribe   = 8
+	packetUnsubscribe = 10
+	packetPingReq     = 12
+	packetDisconnect  = 14
+)
+
+// ErrNotConnected is returned by Publish while the client reconnects.
+var ErrNotConnected = errors.New("mqtt: not connected")
+
+// Options configures a Client.
+type Options struct {
+	// ClientID identifies the client to the broker, the broker assigns one when empty.
+	ClientID string
+	Username string
+	Password string
+	// KeepAlive is the interval the connection is checked at, DefaultKeepAlive when zero.
+	KeepAlive time.Duration
+	// OnStatus, if set, is called when the connection is lost or restored. It must not block.
+	OnStatus func(Status)
+}
+
+// Status is the state of the connection to the broker.
+type Status struct {
+	Connected  bool
+	Reconnects int
+	// Err is the error that dropped the connection, if any.
+	Err error
+}
+
+// Message is a received publication.
+type Message struct {
+	Topic   string
+	Payload []byte
+}
+
+// Client is a connection to a broker. It is safe for concurrent use.
+type Client struct {
+	addr string
+	tls  *tls.Config
+	opts Options
+
+	closed chan struct{}
+	once   sync.Once
+	// writeMu serializes the packets written to the broker.
+	writeMu sync.Mutex
+
+	mu       sync.Mutex
+	conn     net.Conn
+	status   Status
+	handlers map[string]func(Message)
+	packetID uint16
+}
+
+// ParseURL returns the address of a broker URL such as "tcp://host:1883" or
+// "mqtts://host", and the TLS configuration of the mqtts, ssl and tls schemes.
+func ParseURL(raw string) (string, *tls.Config, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid broker URL %q: %w", raw, err)
+	}
+	var tlsConf *tls.Config
+	port := DefaultPort
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "mqtts", "ssl", "tls":
+		tlsConf = &tls.Config{ServerName: u.Hostname()}
+		port = DefaultTLSPort
+	default:
+		return "", nil, fmt.Errorf("invalid broker URL %q: want tcp://, mqtt:// or mqtts://", raw)
+	}
+	if u.Hostname() == "" {
+		return "", nil, fmt.Errorf("invalid broker URL %q: no host", raw)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), tlsConf, nil
+}
+
+// Dial connects to the broker of rawURL. The client reconnects by itself until Close.
+func Dial(ctx context.Context, rawURL string, opts Options) (*Client, error) {
+	addr, tlsConf, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = DefaultKeepAlive
+	}
+	c := &Client{
+		addr:     addr,
+		tls:      tlsConf,
+		opts:     opts,
+		closed:   make(chan struct{}),
+		handlers: make(map[string]func(Message)),
+	}
+	conn, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.conn = conn
+	c.status = Status{Connected: true}
+	c.mu.Unlock()
+	go c.run(conn)
+	return c, nil
+}
+
+// Publish sends payload to topic with QoS 0.
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	body := appendString(nil, topic)
+	body = append(body, payload...)
+	flags := byte(0)
+	if retain {
+		flags = 1
+	}
+	return c.write(packetPublish<<4|flags, body)
+}
+
+// Subscribe calls handler with the messages published to the topics matching
+// filter, which can hold the + and # wildcards. The handler of a filter replaces
+// the previous one; it runs on the receiving goroutine and must not block.
+func (c *Client) Subscribe(filter string, handler func(Message)) error {
+	c.mu.Lock()
+	c.handlers[filter] = handler
+	body := c.subscribePacket(filter)
+	c.mu.Unlock()
+	return c.write(packetSubscribe<<4|2, body)
+}
+
+// Unsubscribe removes the handler of filter.
+func (c *Client) Unsubscribe(filter string) error {
+	c.mu.Lock()
+	delete(c.handlers, filter)
+	id := c.nextPacketID()
+	c.mu.Unlock()
+	body := binary.BigEndian.AppendUint16(nil, id)
+	return c.write(packetUnsubscribe<<4|2, appendString(body, filter))
+}
+
+// Status returns the state of the connection.
+func (c *Client) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+// Close disconnects from the broker.
+func (c *Client) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+		_ = c.write(packetDisconnect<<4, nil)
+		c.mu.Lock()
+		if c.conn != nil {
+			_ = c.conn.Close()
+		}
+		c.mu.Unlock()
+	})
+	return nil
+}
+
+// connect dials the broker and sends CONNECT and the subscriptions.
+func (c *Client) connect(ctx context.Context) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	if c.tls != nil {
+		tc := tls.Client(conn, c.tls)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		conn = tc
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	if err := writePacket(conn, packetConnect<<4, c.connectPacket()); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	header, body, err := readPacket(bufio.NewReader(conn))
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("mqtt: connect: %w", err)
+	}
+	if header>>4 != packetConnAck || len(body) < 2 {
+		_ = conn.Close()
+		return nil, errors.New("mqtt: connect: unexpected answer")
+	}
+	if code := body[1]; code != 0 {
+		_ = conn.Close()
+		return nil, fmt.Errorf("mqtt: connection refused: %s", connAckReason(code))
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	c.mu.Lock()
+	var subs [][]byte
+	for filter := range c.handlers {
+		subs = append(subs, c.subscribePacket(filter))
+	}
+	c.mu.Unlock()
+	for _, body := range subs {
+		if err := writePacket(conn, packetSubscribe<<4|2, body); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c *Client) connectPacket() []byte {
+	body := appendString(nil, "MQTT")
+	// protocol level 4 (3.1.1), clean session
+	flags := byte(0x02)
+	if c.opts.Username != "" {
+		flags |= 0x80
+	}
+	if c.opts.Password != "" {
+		flags |= 0x40
+	}
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(c.opts.KeepAlive/time.Second))
+	body = appendString(body, c.opts.ClientID)
+	if c.opts.Username != "" {
+		body = appendString(body, c.opts.Username)
+	}
+	if c.opts.Password != "" {
+		body = appendString(body, c.opts.Password)
+	}
+	return body
+}
+
+// subscribePacket returns the body of a SUBSCRIBE packet, mu must be held.
+func (c *Client) subscribePacket(filter string) []byte {
+	body := binary.BigEndian.AppendUint16(nil, c.nextPacketID())
+	body = appendString(body, filter)
+	return append(body, 0)
+}
+
+// nextPacketID returns a non-zero packet identifier, mu must be held.
+func (c *Client) nextPacketID() uint16 {
+	c.packetID++
+	if c.packetID == 0 {
+		c.packetID = 1
+	}
+	return c.packetID
+}
+
+// run serves conn and the next connections until Close.
+func (c *Client) run(conn net.Conn) {
+	backoff := minBackoff
+	for {
+		err := c.serve(conn)
+		select {
+		case <-c.closed:
+			return
+		default:
+		}
+		c.setStatus(func(s *Status) { s.Connected, s.Err = false, err })
+
+		for {
+			select {
+			case <-c.closed:
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(2*backoff, maxBackoff)
+			var derr error
+			if conn, derr = c.connect(context.Background()); derr == nil {
+				break
+			}
+			c.setStatus(func(s *Status) { s.Err = derr })
+		}
+		backoff = minBackoff
+		c.mu.Lock()
+		c.conn = conn
+		c.mu.Unlock()
+		select {
+		case <-c.closed:
+			// Close ran while connecting and did not see conn
+			_ = conn.Close()
+			return
+		default:
+		}
+		c.setStatus(func(s *Status) { s.Connected, s.Err = true, nil; s.Reconnects++ })
+	}
+}
+
+// serve reads the packets of conn and pings the broker until the connection fails.
+func (c *Client) serve(conn net.Conn) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(c.opts.KeepAlive / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := c.write(packetPingReq<<4, nil); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		// the broker answers the pings, a silent connection is dead
+		_ = conn.SetReadDeadline(time.Now().Add(c.opts.KeepAlive * 3 / 2))
+		header, body, err := readPacket(r)
+		if err != nil {
+			_ = conn.Close()
+			c.mu.Lock()
+			if c.conn == conn {
+				c.conn = nil
+			}
+			c.mu.Unlock()
+			return err
+		}
+		if header>>4 == packetPublish {
+			c.handlePublish(header, body)
+		}
+	}
+}
+
+func (c *Client) handlePublish(header byte, body []byte) {
+	if len(body) < 2 {
+		return
+	}
+	n := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+n {
+		return
+	}
+	topic := string(body[2 : 2+n])
+	rest := body[2+n:]
+	if qos := header >> 1 & 3; qos > 0 {
+		if len(rest) < 2 {
+			return
+		}
+		id := rest[:2]
+		rest = rest[2:]
+		if qos == 1 {
+			_ = c.write(packetPubAck<<4, append([]byte(nil), id...))
+		}
+	}
+
+	c.mu.Lock()
+	var matched []func(Message)
+	for filter, h := range c.handlers {
+		if Match(filter, topic) {
+			matched = append(matched, h)
+		}
+	}
+	c.mu.Unlock()
+	for _, h := range matched {
+		h(Message{Topic: topic, Payload: rest})
+	}
+}
+
+func (c *Client) write(header byte, body []byte) error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return ErrNotConnected
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_ = conn.SetWriteDeadline(time.Now().Add(dialTimeout))
+	return writePacket(conn, header, body)
+}
+
+func (c *Client) setStatus(update func(*Status)) {
+	c.mu.Lock()
+	update(&c.status)
+	s := c.status
+	c.mu.Unlock()
+	if c.opts.OnStatus != nil {
+		c.opts.OnStatus(s)
+	}
+}
+
+// Match reports whether topic matches filter, which can hold the + (one level)
+// and # (all remaining levels) wildcards.
+func Match(filter, topic string) bool {
+	fs := strings.Split(filter, "/")
+	ts := strings.Split(topic, "/")
+	for i, f := range fs {
+		if f == "#" {
+			return true
+		}
+		if i >= len(ts) || (f != "+" && f != ts[i]) {
+			return false
+		}
+	}
+	return len(fs) == len(ts)
+}
+
+func writePacket(w io.Writer, header byte, body []byte) error {
+	pkt := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		pkt = append(pkt, b)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(pkt, body...))
+	return err
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, mult := 0, 1
+	for i := 0; ; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n += int(b&0x7f) * mult
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, errors.New("mqtt: malformed remaining length")
+		}
+		mult *= 128
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func connAckReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad user name or password"
+	case 5:
+		return "not authorized"
+	default:
+		return fmt.Sprintf("code %d", code)
+	}
+}
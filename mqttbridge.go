@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+	"canproject/mqtt"
+)
+
+const (
+	defaultFrameTopic  = "can/{interface}/frames/{id}"
+	defaultSignalTopic = "can/{interface}/{message}/{signal}"
+	// mqttQueue is the number of publications queued for the broker, the next
+	// ones are dropped while it is full.
+	mqttQueue = 4096
+)
+
+// MQTTOptions configures the bridge started with StartMQTTBridge.
+type MQTTOptions struct {
+	// Broker is the broker URL, eg "tcp://localhost:1883" or "mqtts://broker:8883".
+	Broker   string `json:"broker"`
+	ClientID string `json:"clientId"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Interfaces limits the bridge to these interfaces, all when empty.
+	Interfaces []string `json:"interfaces"`
+	// PublishFrames publishes the received frames as "can:frame" JSON to FrameTopic,
+	// "can/{interface}/frames/{id}" when empty.
+	PublishFrames bool   `json:"publishFrames"`
+	FrameTopic    string `json:"frameTopic"`
+	// PublishSignals publishes the signals decoded with the loaded databases to
+	// SignalTopic, "can/{interface}/{message}/{signal}" when empty.
+	PublishSignals bool   `json:"publishSignals"`
+	SignalTopic    string `json:"signalTopic"`
+	// Retain asks the broker to keep the last publication of every topic.
+	Retain bool `json:"retain"`
+	// InjectTopic, if set, is subscribed to: the frames published there are sent,
+	// in the format of the REST API POST /frames.
+	InjectTopic string `json:"injectTopic"`
+}
+
+// MQTTStatus describes the MQTT bridge, it is emitted on "can:mqtt" when the
+// connection to the broker drops or is restored.
+type MQTTStatus struct {
+	Running    bool   `json:"running"`
+	Connected  bool   `json:"connected"`
+	Broker     string `json:"broker"`
+	Reconnects int    `json:"reconnects"`
+	// Published counts the publications sent, Dropped the ones lost while the
+	// broker was unreachable or slow, Injected the frames sent from InjectTopic.
+	Published uint64 `json:"published"`
+	Dropped   uint64 `json:"dropped"`
+	Injected  uint64 `json:"injected"`
+	Error     string `json:"error,omitempty"`
+}
+
+// mqttSignal is the payload of a signal publication.
+type mqttSignal struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+	Raw       float64   `json:"raw"`
+	Unit      string    `json:"unit,omitempty"`
+	Label     string    `json:"label,omitempty"`
+}
+
+type mqttPublication struct {
+	topic   string
+	payload []byte
+}
+
+type mqttBridge struct {
+	opts   MQTTOptions
+	client *mqtt.Client
+	queue  chan mqttPublication
+	done   chan struct{}
+
+	mu     sync.Mutex
+	status MQTTStatus
+}
+
+// StartMQTTBridge connects to an MQTT broker and publishes the received frames
+// and/or their decoded signals. The topics can use the placeholders {interface},
+// {id} (hex), {message} and {signal}, which are replaced for every publication;
+// "/", "+" and "#" in the replaced values are changed to "_".
+func (a *App) StartMQTTBridge(opts MQTTOptions) (MQTTStatus, error) {
+	opts.Broker = strings.TrimSpace(opts.Broker)
+	if opts.Broker == "" {
+		return MQTTStatus{}, errors.New("MQTT broker is empty")
+	}
+	if !opts.PublishFrames && !opts.PublishSignals && opts.InjectTopic == "" {
+		return MQTTStatus{}, errors.New("nothing to bridge: enable frames, signals or an inject topic")
+	}
+	if opts.FrameTopic == "" {
+		opts.FrameTopic = defaultFrameTopic
+	}
+	if opts.SignalTopic == "" {
+		opts.SignalTopic = defaultSignalTopic
+	}
+	if _, _, err := mqtt.ParseURL(opts.Broker); err != nil {
+		return MQTTStatus{}, err
+	}
+
+	a.mqttMu.Lock()
+	defer a.mqttMu.Unlock()
+	if a.mqtt != nil {
+		return MQTTStatus{}, fmt.Errorf("MQTT bridge already connected to %s", a.mqtt.opts.Broker)
+	}
+	b := &mqttBridge{
+		opts:   opts,
+		queue:  make(chan mqttPublication, mqttQueue),
+		done:   make(chan struct{}),
+		status: MQTTStatus{Running: true, Connected: true, Broker: opts.Broker},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mqtt.Dial(ctx, opts.Broker, mqtt.Options{
+		ClientID: opts.ClientID,
+		Username: opts.Username,
+		Password: opts.Password,
+		OnStatus: func(s mqtt.Status) {
+			a.emit("can:mqtt", b.update(s))
+		},
+	})
+	if err != nil {
+		return MQTTStatus{}, fmt.Errorf("MQTT broker %s: %w", opts.Broker, err)
+	}
+	b.client = client
+	if opts.InjectTopic != "" {
+		if err := client.Subscribe(opts.InjectTopic, func(m mqtt.Message) { a.injectMQTT(b, m) }); err != nil {
+			_ = client.Close()
+			return MQTTStatus{}, fmt.Errorf("MQTT subscribe %s: %w", opts.InjectTopic, err)
+		}
+	}
+	if opts.PublishFrames || opts.PublishSignals {
+		if err := a.rxPipeline.register(frameProcessor{"mqtt", func(rx *rxFrame) bool {
+			b.publish(rx)
+			return true
+		}}, "isotp"); err != nil {
+			_ = client.Close()
+			return MQTTStatus{}, err
+		}
+	}
+	a.mqtt = b
+	go b.writeLoop()
+	return b.snapshot(), nil
+}
+
+// StopMQTTBridge disconnects the MQTT bridge.
+func (a *App) StopMQTTBridge() error {
+	a.mqttMu.Lock()
+	b := a.mqtt
+	a.mqtt = nil
+	a.mqttMu.Unlock()
+	if b == nil {
+		return nil
+	}
+	if b.opts.PublishFrames || b.opts.PublishSignals {
+		_ = a.rxPipeline.remove("mqtt")
+	}
+	close(b.done)
+	return b.client.Close()
+}
+
+// GetMQTTStatus returns the state of the MQTT bridge.
+func (a *App) GetMQTTStatus() MQTTStatus {
+	a.mqttMu.Lock()
+	defer a.mqttMu.Unlock()
+	if a.mqtt == nil {
+		return MQTTStatus{}
+	}
+	return a.mqtt.snapshot()
+}
+
+// publish queues the publications of a received frame. It runs in the receive
+// pipeline after "signals", so it sees the frame as shown and its decoded signals.
+func (b *mqttBridge) publish(rx *rxFrame) {
+	if rx.hidden || (len(b.opts.Interfaces) > 0 && !slices.Contains(b.opts.Interfaces, rx.sess.iface)) {
+		return
+	}
+	id := fmt.Sprintf("%03X", rx.shown.ID)
+	if rx.shown.IsExtended {
+		id = fmt.Sprintf("%08X", rx.shown.ID)
+	}
+	if b.opts.PublishFrames {
+		if payload, err := json.Marshal(frameEvent(rx.sess.iface, rx.info, &rx.shown, false)); err == nil {
+			b.enqueue(mqttTopic(b.opts.FrameTopic, rx.sess.iface, id, "", ""), payload)
+		}
+	}
+	if b.opts.PublishSignals && rx.decoded {
+		for _, v := range rx.signals.Signals {
+			payload, err := json.Marshal(mqttSignal{
+				Timestamp: rx.signals.Timestamp,
+				Value:     v.Physical,
+				Raw:       v.Raw,
+				Unit:      v.Unit,
+				Label:     v.Label,
+			})
+			if err == nil {
+				b.enqueue(mqttTopic(b.opts.SignalTopic, rx.sess.iface, id, rx.signals.Message, v.Name), payload)
+			}
+		}
+	}
+}
+
+func (b *mqttBridge) enqueue(topic string, payload []byte) {
+	select {
+	case b.queue <- mqttPublication{topic, payload}:
+	default:
+		b.count(func(s *MQTTStatus) { s.Dropped++ })
+	}
+}
+
+// writeLoop publishes the queued publications, so a slow broker does not hold
+// the receive loops.
+func (b *mqttBridge) writeLoop() {
+	for {
+		select {
+		case <-b.done:
+			return
+		case p := <-b.queue:
+			if err := b.client.Publish(p.topic, p.payload, b.opts.Retain); err != nil {
+				b.count(func(s *MQTTStatus) { s.Dropped++ })
+			} else {
+				b.count(func(s *MQTTStatus) { s.Published++ })
+			}
+		}
+	}
+}
+
+// injectMQTT sends the frames of a message published to the inject topic.
+func (a *App) injectMQTT(b *mqttBridge, m mqtt.Message) {
+	var frames []restFrame
+	if trimmed := strings.TrimSpace(string(m.Payload)); strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(m.Payload, &frames); err != nil {
+			a.emitError(fmt.Errorf("MQTT %s: %w", m.Topic, err))
+			return
+		}
+	} else {
+		var f restFrame
+		if err := json.Unmarshal(m.Payload, &f); err != nil {
+			a.emitError(fmt.Errorf("MQTT %s: %w", m.Topic, err))
+			return
+		}
+		frames = []restFrame{f}
+	}
+	for i, rf := range frames {
+		data, err := restData(rf.Data)
+		if err == nil {
+			err = a.sendFrame(rf.Interface, rf.ID, data, rf.Extended, rf.FD || len(data) > canbus.MaxDataLength, rf.BRS)
+		}
+		if err != nil {
+			a.emitError(fmt.Errorf("MQTT %s: frame %d: %w", m.Topic, i+1, err))
+			return
+		}
+		b.count(func(s *MQTTStatus) { s.Injected++ })
+	}
+}
+
+// update records the connection state of the client and returns the status.
+func (b *mqttBridge) update(s mqtt.Status) MQTTStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.status.Connected, b.status.Reconnects = s.Connected, s.Reconnects
+	b.status.Error = ""
+	if s.Err != nil {
+		b.status.Error = s.Err.Error()
+	}
+	return b.status
+}
+
+func (b *mqttBridge) count(update func(*MQTTStatus)) {
+	b.mu.Lock()
+	update(&b.status)
+	b.mu.Unlock()
+}
+
+func (b *mqttBridge) snapshot() MQTTStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status
+}
+
+var mqttTopicEscaper = strings.NewReplacer("/", "_", "+", "_", "#", "_")
+
+// mqttTopic fills the placeholders of a topic template.
+func mqttTopic(template, iface, id, message, signal string) string {
+	return strings.NewReplacer(
+		"{interface}", mqttTopicEscaper.Replace(iface),
+		"{id}", id,
+		"{message}", mqttTopicEscaper.Replace(message),
+		"{signal}", mqttTopicEscaper.Replace(signal),
+	).Replace(template)
+}
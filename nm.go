@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/nm"
+)
+
+// NMOptions describe the network management of a bus for SetNMDecoding.
+type NMOptions struct {
+	// Protocol is "autosar" (CanNm) or "osek".
+	Protocol string `json:"protocol"`
+	// From and To bound the IDs of the NM messages, both 0 for 0x500-0x5FF
+	// (AUTOSAR) or 0x400-0x4FF (OSEK).
+	From     uint32 `json:"from"`
+	To       uint32 `json:"to"`
+	Extended bool   `json:"extended"`
+	// NodeIDPosition and CBVPosition are "byte0", "byte1" or "off", the
+	// positions of the source node ID and of the control bit vector in AUTOSAR
+	// messages; empty for byte 0 and byte 1.
+	NodeIDPosition string `json:"nodeIdPosition"`
+	CBVPosition    string `json:"cbvPosition"`
+	// TimeoutMs is the time without NM message after which a node is asleep, 0
+	// for 2000.
+	TimeoutMs int `json:"timeoutMs"`
+}
+
+// NMEvent is a decoded NM message emitted on "nm:message".
+type NMEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	ID        uint32    `json:"id"`
+	Node      uint8     `json:"node"`
+	// CBV is the control bit vector of AUTOSAR messages, Opcode and Destination
+	// the opcode and the addressed node of OSEK messages.
+	CBV         uint8 `json:"cbv"`
+	Opcode      uint8 `json:"opcode"`
+	Destination uint8 `json:"destination"`
+	// Flags name the bits set in the CBV or the opcode, eg
+	// "repeat-message-request", "active-wakeup" or "sleep-indication".
+	Flags    []string `json:"flags"`
+	UserData []uint32 `json:"userData"`
+}
+
+// NMNodeEvent is emitted on "nm:node" when the state of a node changes.
+type NMNodeEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	Node      uint8     `json:"node"`
+	// State is "awake", "ready-to-sleep", "limp-home" or "asleep", Previous
+	// "asleep" for the first message of a node.
+	State    string `json:"state"`
+	Previous string `json:"previous"`
+	// Wakeup is set when the node woke up the network, its message being the
+	// first while all the nodes were asleep.
+	Wakeup bool `json:"wakeup"`
+	// Awake are the nodes keeping the network awake after the change, the
+	// nodes not asleep.
+	Awake []uint8 `json:"awake"`
+}
+
+// NMNodeInfo is a node seen on a bus with NM decoding enabled.
+type NMNodeInfo struct {
+	Node       uint8     `json:"node"`
+	State      string    `json:"state"`
+	AwakeSince time.Time `json:"awakeSince"`
+	LastSeen   time.Time `json:"lastSeen"`
+	Messages   uint64    `json:"messages"`
+	// Wakeups counts the times the node woke up the network.
+	Wakeups uint64 `json:"wakeups"`
+	// Flags are the flags of the last message of the node.
+	Flags []string `json:"flags"`
+}
+
+// SetNMDecoding enables or disables the decoding of the AUTOSAR or OSEK network
+// management messages received on a started interface. Decoded messages are
+// emitted on "nm:message" and the changes of the nodes, awake, ready to sleep
+// or asleep once silent for the NM timeout, on "nm:node". Enabling it again
+// forgets the nodes seen.
+func (a *App) SetNMDecoding(iface string, enabled bool, opts NMOptions) error {
+	iface = strings.TrimSpace(iface)
+	var tracker *nm.Tracker
+	if enabled {
+		t, err := nm.NewTracker(nm.Config{
+			Protocol:       nm.Protocol(strings.ToLower(strings.TrimSpace(opts.Protocol))),
+			From:           opts.From,
+			To:             opts.To,
+			Extended:       opts.Extended,
+			NodeIDPosition: nm.Position(strings.ToLower(strings.TrimSpace(opts.NodeIDPosition))),
+			CBVPosition:    nm.Position(strings.ToLower(strings.TrimSpace(opts.CBVPosition))),
+			Timeout:        time.Duration(opts.TimeoutMs) * time.Millisecond,
+		})
+		if err != nil {
+			return err
+		}
+		tracker = t
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	sess := a.sessions[iface]
+	if sess == nil || sess.conn == nil {
+		return fmt.Errorf("CAN not started on %s", iface)
+	}
+	sess.nm.Store(tracker)
+	return nil
+}
+
+// GetNMNodes returns the nodes that sent an NM message since NM decoding was
+// enabled on iface, sorted by node ID.
+func (a *App) GetNMNodes(iface string) ([]NMNodeInfo, error) {
+	sess, err := a.session(iface)
+	if err != nil {
+		return nil, err
+	}
+	tracker := sess.nm.Load()
+	if tracker == nil {
+		return nil, fmt.Errorf("NM decoding is not enabled on %s", sess.iface)
+	}
+	protocol := tracker.Config().Protocol
+	nodes := tracker.Nodes()
+	infos := make([]NMNodeInfo, len(nodes))
+	for i := range nodes {
+		n := &nodes[i]
+		infos[i] = NMNodeInfo{
+			Node:       n.ID,
+			State:      string(n.State),
+			AwakeSince: n.AwakeSince,
+			LastSeen:   n.LastSeen,
+			Messages:   n.Messages,
+			Wakeups:    n.Wakeups,
+			Flags:      n.Last.Flags(protocol),
+		}
+	}
+	return infos, nil
+}
+
+// dispatchNM decodes the NM messages of a session with NM decoding enabled.
+func (a *App) dispatchNM(sess *canSession, ts time.Time, f *canbus.Frame) {
+	tracker := sess.nm.Load()
+	if tracker == nil {
+		return
+	}
+	m, change, ok := tracker.Add(ts, f)
+	if !ok {
+		return
+	}
+	a.emit("nm:message", NMEvent{
+		Timestamp:   ts,
+		Interface:   sess.iface,
+		ID:          f.ID,
+		Node:        m.Node,
+		CBV:         m.CBV,
+		Opcode:      m.Opcode,
+		Destination: m.Destination,
+		Flags:       m.Flags(tracker.Config().Protocol),
+		UserData:    dataWords(m.UserData),
+	})
+	if change != nil {
+		a.emitNMChanges(sess.iface, tracker, []nm.Change{*change})
+	}
+}
+
+// checkNM reports the nodes of a session which went asleep. It runs with the
+// timing checks.
+func (a *App) checkNM(sess *canSession, now time.Time) {
+	if tracker := sess.nm.Load(); tracker != nil {
+		a.emitNMChanges(sess.iface, tracker, tracker.Check(now))
+	}
+}
+
+func (a *App) emitNMChanges(iface string, tracker *nm.Tracker, changes []nm.Change) {
+	if len(changes) == 0 {
+		return
+	}
+	awake := []uint8{}
+	for _, n := range tracker.Nodes() {
+		if n.State != nm.StateAsleep {
+			awake = append(awake, n.ID)
+		}
+	}
+	for _, c := range changes {
+		a.emit("nm:node", NMNodeEvent{
+			Timestamp: c.Time,
+			Interface: iface,
+			Node:      c.Node,
+			State:     string(c.State),
+			Previous:  string(c.Previous),
+			Wakeup:    c.Wakeup,
+			Awake:     awake,
+		})
+	}
+}
@@ -0,0 +1,383 @@
+// Package nm decodes the network management messages of AUTOSAR CanNm and
+// OSEK NM and tracks from them the state of the nodes of a network: awake while
+// they send NM messages, ready to sleep when they signal it, and asleep once
+// silent for the NM timeout. The node whose message starts a network that was
+// asleep is recorded as its wake-up source.
+package nm
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+)
+
+// Protocol is the network management protocol of a network.
+type Protocol string
+
+// Protocols.
+const (
+	AUTOSAR Protocol = "autosar"
+	OSEK    Protocol = "osek"
+)
+
+// Position is the position of the node ID or of the control bit vector in
+// AUTOSAR NM messages, as configured with CanNmPduNidPosition and
+// CanNmPduCbvPosition.
+type Position string
+
+// Positions. The default is byte 0 for the node ID and byte 1 for the control
+// bit vector.
+const (
+	PositionDefault Position = ""
+	PositionByte0   Position = "byte0"
+	PositionByte1   Position = "byte1"
+	PositionOff     Position = "off"
+)
+
+// Bits of the control bit vector of AUTOSAR NM messages.
+const (
+	CBVRepeatMessageRequest  = 0x01
+	CBVPNShutdownRequest     = 0x02
+	CBVCoordinatorSleepReady = 0x08
+	CBVActiveWakeup          = 0x10
+	CBVPNLearning            = 0x20
+	CBVPNInformation         = 0x40
+)
+
+// Bits of the opcode of OSEK NM messages.
+const (
+	OpAlive            = 0x01
+	OpRing             = 0x02
+	OpLimpHome         = 0x04
+	OpSleepIndication  = 0x10
+	OpSleepAcknowledge = 0x20
+)
+
+// DefaultTimeout is the time without NM message after which a node is
+// considered asleep when Config.Timeout is 0.
+const DefaultTimeout = 2 * time.Second
+
+// Default ID ranges of the NM messages.
+const (
+	DefaultAUTOSARFrom = 0x500
+	DefaultAUTOSARTo   = 0x5ff
+	DefaultOSEKFrom    = 0x400
+	DefaultOSEKTo      = 0x4ff
+)
+
+// Config selects the NM messages of a network and their layout.
+type Config struct {
+	Protocol Protocol
+	// From and To bound the IDs of the NM messages, both 0 for the default
+	// range of the protocol. The node ID of OSEK messages, and of AUTOSAR
+	// messages without node ID byte, is the offset of the ID in the range.
+	From, To uint32
+	Extended bool
+	// NodeIDPosition and CBVPosition place the node ID and the control bit
+	// vector in AUTOSAR messages.
+	NodeIDPosition Position
+	CBVPosition    Position
+	// Timeout is the time without NM message after which a node is asleep, 0
+	// for DefaultTimeout.
+	Timeout time.Duration
+}
+
+// Message is a decoded NM message.
+type Message struct {
+	// Node is the ID of the sending node.
+	Node uint8
+	// CBV is the control bit vector of AUTOSAR messages, 0 without one.
+	CBV uint8
+	// Opcode is the opcode of OSEK messages and Destination the node the
+	// message is addressed to, the logical successor in the ring.
+	Opcode      uint8
+	Destination uint8
+	// UserData are the bytes after the NM fields.
+	UserData []byte
+}
+
+// State is the state of a node.
+type State string
+
+// Node states.
+const (
+	// StateAwake nodes send NM messages and keep the network awake.
+	StateAwake State = "awake"
+	// StateReadyToSleep nodes send NM messages with the coordinator sleep
+	// ready bit (AUTOSAR) or a sleep indication (OSEK).
+	StateReadyToSleep State = "ready-to-sleep"
+	// StateLimpHome nodes send OSEK limp home messages.
+	StateLimpHome State = "limp-home"
+	// StateAsleep nodes sent no NM message for the timeout.
+	StateAsleep State = "asleep"
+)
+
+// Node is the state of a node of a tracked network.
+type Node struct {
+	ID    uint8
+	State State
+	// AwakeSince is the time of the first message since the node was asleep.
+	AwakeSince time.Time
+	LastSeen   time.Time
+	Messages   uint64
+	// Wakeups counts the times the node woke up the network.
+	Wakeups uint64
+	// Last is the last message of the node.
+	Last Message
+}
+
+// Change is a change of the state of a node.
+type Change struct {
+	Time     time.Time
+	Node     uint8
+	State    State
+	Previous State
+	// Wakeup is set when the message of the node is the first one while all the
+	// nodes were asleep, or since the network is tracked.
+	Wakeup bool
+}
+
+// Decode decodes f if it is an NM message of the network.
+func (c *Config) Decode(f *canbus.Frame) (Message, bool) {
+	from, to := c.idRange()
+	if f.IsError || f.IsRemote || f.IsExtended != c.Extended || f.ID < from || f.ID > to {
+		return Message{}, false
+	}
+	data := f.Payload()
+	offset := uint8(f.ID - from)
+	if c.Protocol == OSEK {
+		if len(data) < 2 {
+			return Message{}, false
+		}
+		return Message{Node: offset, Destination: data[0], Opcode: data[1], UserData: append([]byte(nil), data[2:]...)}, true
+	}
+
+	m := Message{Node: offset}
+	used := 0
+	if i, ok := c.NodeIDPosition.byteIndex(0); ok {
+		if len(data) <= i {
+			return Message{}, false
+		}
+		m.Node = data[i]
+		used = max(used, i+1)
+	}
+	if i, ok := c.CBVPosition.byteIndex(1); ok {
+		if len(data) <= i {
+			return Message{}, false
+		}
+		m.CBV = data[i]
+		used = max(used, i+1)
+	}
+	m.UserData = append([]byte(nil), data[used:]...)
+	return m, true
+}
+
+// SleepReady reports whether the message signals that its node is ready to
+// sleep.
+func (m *Message) SleepReady(p Protocol) bool {
+	if p == OSEK {
+		return m.Opcode&(OpSleepIndication|OpSleepAcknowledge) != 0
+	}
+	return m.CBV&CBVCoordinatorSleepReady != 0
+}
+
+// Flags returns the names of the bits set in the control bit vector or the
+// opcode of the message, eg "repeat-message-request" or "sleep-indication".
+func (m *Message) Flags(p Protocol) []string {
+	bits, names := m.CBV, cbvNames
+	if p == OSEK {
+		bits, names = m.Opcode, opcodeNames
+	}
+	flags := []string{}
+	for _, n := range names {
+		if bits&n.bit != 0 {
+			flags = append(flags, n.name)
+		}
+	}
+	return flags
+}
+
+type bitName struct {
+	bit  uint8
+	name string
+}
+
+var cbvNames = []bitName{
+	{CBVRepeatMessageRequest, "repeat-message-request"},
+	{CBVPNShutdownRequest, "pn-shutdown-request"},
+	{CBVCoordinatorSleepReady, "coordinator-sleep-ready"},
+	{CBVActiveWakeup, "active-wakeup"},
+	{CBVPNLearning, "pn-learning"},
+	{CBVPNInformation, "pn-information"},
+}
+
+var opcodeNames = []bitName{
+	{OpAlive, "alive"},
+	{OpRing, "ring"},
+	{OpLimpHome, "limp-home"},
+	{OpSleepIndication, "sleep-indication"},
+	{OpSleepAcknowledge, "sleep-acknowledge"},
+}
+
+// Tracker tracks the nodes of a network from its NM messages. It is safe for
+// concurrent use.
+type Tracker struct {
+	cfg Config
+
+	mu    sync.Mutex
+	nodes map[uint8]*Node
+}
+
+// NewTracker returns a tracker of the network described by cfg.
+func NewTracker(cfg Config) (*Tracker, error) {
+	switch cfg.Protocol {
+	case AUTOSAR, OSEK:
+	default:
+		return nil, fmt.Errorf("unknown NM protocol %q", cfg.Protocol)
+	}
+	if cfg.From == 0 && cfg.To == 0 {
+		cfg.From, cfg.To = cfg.idRange()
+	}
+	limit := uint32(0x7ff)
+	if cfg.Extended {
+		limit = 0x1fffffff
+	}
+	if cfg.To < cfg.From || cfg.To > limit {
+		return nil, fmt.Errorf("invalid NM ID range 0x%X-0x%X", cfg.From, cfg.To)
+	}
+	for _, p := range []Position{cfg.NodeIDPosition, cfg.CBVPosition} {
+		switch p {
+		case PositionDefault, PositionByte0, PositionByte1, PositionOff:
+		default:
+			return nil, fmt.Errorf("unknown NM PDU position %q", p)
+		}
+	}
+	nid, hasNID := cfg.NodeIDPosition.byteIndex(0)
+	cbv, hasCBV := cfg.CBVPosition.byteIndex(1)
+	if cfg.Protocol == AUTOSAR && hasNID && hasCBV && nid == cbv {
+		return nil, fmt.Errorf("NM node ID and control bit vector both at byte %d", nid)
+	}
+	if (cfg.Protocol == OSEK || !hasNID) && cfg.To-cfg.From > 0xff {
+		return nil, fmt.Errorf("NM ID range 0x%X-0x%X has more than 256 IDs, the node IDs are taken from the IDs", cfg.From, cfg.To)
+	}
+	if cfg.Timeout < 0 {
+		return nil, fmt.Errorf("NM timeout must be >= 0 (got %s)", cfg.Timeout)
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &Tracker{cfg: cfg, nodes: make(map[uint8]*Node)}, nil
+}
+
+// Config returns the configuration of the tracker, with the defaults set.
+func (t *Tracker) Config() Config {
+	return t.cfg
+}
+
+// Add decodes a received frame and updates the state of its node. It returns
+// the decoded message, and the change of the node when its state changed.
+func (t *Tracker) Add(ts time.Time, f *canbus.Frame) (Message, *Change, bool) {
+	m, ok := t.cfg.Decode(f)
+	if !ok {
+		return Message{}, nil, false
+	}
+	state := StateAwake
+	switch {
+	case t.cfg.Protocol == OSEK && m.Opcode&OpLimpHome != 0:
+		state = StateLimpHome
+	case m.SleepReady(t.cfg.Protocol):
+		state = StateReadyToSleep
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	wakeup := t.asleep()
+	n := t.nodes[m.Node]
+	if n == nil {
+		n = &Node{ID: m.Node, State: StateAsleep}
+		t.nodes[m.Node] = n
+	}
+	prev := n.State
+	if prev == StateAsleep {
+		n.AwakeSince = ts
+	}
+	if wakeup {
+		n.Wakeups++
+	}
+	n.State, n.LastSeen, n.Last = state, ts, m
+	n.Messages++
+	if prev == state {
+		return m, nil, true
+	}
+	return m, &Change{Time: ts, Node: m.Node, State: state, Previous: prev, Wakeup: wakeup}, true
+}
+
+// Check marks asleep the nodes which sent no NM message for the timeout before
+// now and returns their changes.
+func (t *Tracker) Check(now time.Time) []Change {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var changes []Change
+	for _, n := range t.nodes {
+		if n.State == StateAsleep || now.Sub(n.LastSeen) < t.cfg.Timeout {
+			continue
+		}
+		changes = append(changes, Change{Time: now, Node: n.ID, State: StateAsleep, Previous: n.State})
+		n.State = StateAsleep
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Node < changes[j].Node })
+	return changes
+}
+
+// Nodes returns the nodes seen on the network, sorted by ID.
+func (t *Tracker) Nodes() []Node {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	nodes := make([]Node, 0, len(t.nodes))
+	for _, n := range t.nodes {
+		nodes = append(nodes, *n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+	return nodes
+}
+
+// asleep reports whether all the nodes are asleep.
+func (t *Tracker) asleep() bool {
+	for _, n := range t.nodes {
+		if n.State != StateAsleep {
+			return false
+		}
+	}
+	return true
+}
+
+// idRange returns the range of the NM IDs, the default range of the protocol
+// when none is set.
+func (c *Config) idRange() (uint32, uint32) {
+	if c.From != 0 || c.To != 0 {
+		return c.From, c.To
+	}
+	if c.Protocol == OSEK {
+		return DefaultOSEKFrom, DefaultOSEKTo
+	}
+	return DefaultAUTOSARFrom, DefaultAUTOSARTo
+}
+
+// byteIndex returns the byte of the position, def for the default position,
+// and false when off.
+func (p Position) byteIndex(def int) (int, bool) {
+	switch p {
+	case PositionByte0:
+		return 0, true
+	case PositionByte1:
+		return 1, true
+	case PositionOff:
+		return -1, false
+	}
+	return def, true
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/nmea2000"
+)
+
+// NMEA2000Event is an NMEA 2000 message emitted on "can:nmea2000".
+type NMEA2000Event struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Interface   string    `json:"interface"`
+	Priority    uint8     `json:"priority"`
+	PGN         uint32    `json:"pgn"`
+	Name        string    `json:"name"`
+	Source      uint8     `json:"source"`
+	Destination uint8     `json:"destination"`
+	// Fields are the decoded fields of known PGNs, unavailable values are left out.
+	Fields []NMEA2000Field `json:"fields"`
+	Data   []uint32        `json:"data"`
+}
+
+// NMEA2000Field is a decoded field of an NMEA 2000 message.
+type NMEA2000Field struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// SetNMEA2000Decoding enables or disables NMEA 2000 decoding of the 29-bit frames
+// received on a started interface. Fast-packet and transport protocol messages are
+// reassembled and the fields of common PGNs (position, heading, depth, wind, engine
+// and battery parameters) are decoded and emitted on "can:nmea2000".
+func (a *App) SetNMEA2000Decoding(iface string, enabled bool) error {
+	iface = strings.TrimSpace(iface)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sess := a.sessions[iface]
+	if sess == nil || sess.conn == nil {
+		return fmt.Errorf("CAN not started on %s", iface)
+	}
+	if !enabled {
+		sess.nmea2000.Store(nil)
+	} else if sess.nmea2000.Load() == nil {
+		sess.nmea2000.Store(nmea2000.NewDecoder())
+	}
+	return nil
+}
+
+// dispatchNMEA2000 decodes a frame received on a session with NMEA 2000 decoding enabled.
+func (a *App) dispatchNMEA2000(sess *canSession, ts time.Time, f *canbus.Frame) {
+	d := sess.nmea2000.Load()
+	if d == nil {
+		return
+	}
+	msg, ok := d.Handle(ts, f)
+	if !ok {
+		return
+	}
+	ev := NMEA2000Event{
+		Timestamp:   ts,
+		Interface:   sess.iface,
+		Priority:    msg.Priority,
+		PGN:         msg.PGN,
+		Name:        msg.Name,
+		Source:      msg.Source,
+		Destination: msg.Destination,
+		Fields:      make([]NMEA2000Field, len(msg.Fields)),
+		Data:        dataWords(msg.Data),
+	}
+	for i, fd := range msg.Fields {
+		ev.Fields[i] = NMEA2000Field{Name: fd.Name, Value: fd.Value, Unit: fd.Unit}
+	}
+	a.emit("can:nmea2000", ev)
+}
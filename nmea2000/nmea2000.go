@@ -0,0 +1,121 @@
+// Package nmea2000 decodes NMEA 2000 marine networks. NMEA 2000 uses the J1939
+// identifiers and transport protocol and adds fast-packet messages, which carry up
+// to 223 bytes in a series of frames without handshake.
+package nmea2000
+
+import (
+	"time"
+
+	"canproject/canbus"
+	"canproject/j1939"
+)
+
+const (
+	// MaxFastPacketLength is the largest fast-packet message.
+	MaxFastPacketLength = 223
+	// fastPacketTimeout discards a fast-packet message whose next frame does not come in time.
+	fastPacketTimeout = 750 * time.Millisecond
+)
+
+// Message is a decoded NMEA 2000 message.
+type Message struct {
+	j1939.Header
+	Data []byte
+	// Name is the name of a known PGN, empty otherwise.
+	Name string
+	// Fields are the decoded fields of a known PGN, without the unavailable ones.
+	Fields []Field
+}
+
+type fastKey struct {
+	source uint8
+	pgn    uint32
+}
+
+type fastPacket struct {
+	seq  uint8
+	next uint8
+	size int
+	data []byte
+	last time.Time
+}
+
+// Decoder reassembles and decodes the messages of a bus. It is not safe for
+// concurrent use.
+type Decoder struct {
+	tp   *j1939.Reassembler
+	fast map[fastKey]*fastPacket
+}
+
+// NewDecoder returns an empty decoder.
+func NewDecoder() *Decoder {
+	return &Decoder{tp: j1939.NewReassembler(), fast: make(map[fastKey]*fastPacket)}
+}
+
+// Handle processes a frame received at ts. It returns single-frame messages and
+// completed fast-packet and transport protocol messages.
+func (d *Decoder) Handle(ts time.Time, f *canbus.Frame) (Message, bool) {
+	if !f.IsExtended || f.IsRemote || f.IsError {
+		return Message{}, false
+	}
+	h := j1939.ParseID(f.ID)
+	if IsFastPacket(h.PGN) {
+		data, ok := d.handleFast(ts, h, f.Payload())
+		if !ok {
+			return Message{}, false
+		}
+		return Decode(h, data), true
+	}
+	msg, ok := d.tp.Handle(ts, f)
+	if !ok {
+		return Message{}, false
+	}
+	return Decode(msg.Header, msg.Data), true
+}
+
+// handleFast adds a frame of a fast-packet message. The first frame holds a
+// sequence counter and frame index 0 in the first byte, the length in the second
+// and 6 data bytes; the next frames hold the counter and their index and 7 bytes.
+func (d *Decoder) handleFast(ts time.Time, h j1939.Header, data []byte) ([]byte, bool) {
+	if len(data) < 2 {
+		return nil, false
+	}
+	key := fastKey{h.Source, h.PGN}
+	seq, index := data[0]>>5, data[0]&0x1f
+	if index == 0 {
+		size := int(data[1])
+		if size > MaxFastPacketLength {
+			delete(d.fast, key)
+			return nil, false
+		}
+		if size <= len(data)-2 {
+			delete(d.fast, key)
+			return append([]byte(nil), data[2:2+size]...), true
+		}
+		d.fast[key] = &fastPacket{
+			seq:  seq,
+			next: 1,
+			size: size,
+			data: append(make([]byte, 0, size), data[2:]...),
+			last: ts,
+		}
+		return nil, false
+	}
+
+	p := d.fast[key]
+	if p == nil || p.seq != seq || p.next != index || ts.Sub(p.last) > fastPacketTimeout {
+		// a lost or out of order frame invalidates the message
+		if p != nil && p.seq == seq {
+			delete(d.fast, key)
+		}
+		return nil, false
+	}
+	p.data = append(p.data, data[1:min(len(data), 1+p.size-len(p.data))]...)
+	p.next++
+	p.last = ts
+	if len(p.data) < p.size {
+		return nil, false
+	}
+	delete(d.fast, key)
+	return p.data, true
+}
@@ -0,0 +1,231 @@
+package nmea2000
+
+import (
+	"math"
+
+	"canproject/j1939"
+)
+
+// Field is a decoded field of a message.
+type Field struct {
+	Name  string
+	Value float64
+	Unit  string
+}
+
+// fieldDef describes a little-endian bit field. Fields without a name are reserved.
+type fieldDef struct {
+	name   string
+	bits   int
+	signed bool
+	scale  float64
+	offset float64
+	unit   string
+}
+
+// PGNInfo describes a known PGN.
+type PGNInfo struct {
+	PGN  uint32
+	Name string
+	// FastPacket is true for PGNs sent as fast-packet messages.
+	FastPacket bool
+	fields     []fieldDef
+}
+
+const (
+	// radians per 1e-4 unit converted to degrees
+	angle    = 1e-4 * 180 / math.Pi
+	kelvin   = -273.15
+	sidField = "SID"
+)
+
+func sid() fieldDef                           { return fieldDef{name: sidField, bits: 8, scale: 1} }
+func reserved(bits int) fieldDef              { return fieldDef{bits: bits} }
+func unsigned(name string, bits int) fieldDef { return fieldDef{name: name, bits: bits, scale: 1} }
+func deg(name string, signed bool) fieldDef {
+	return fieldDef{name: name, bits: 16, signed: signed, scale: angle, unit: "deg"}
+}
+func temp(name string, scale float64) fieldDef {
+	return fieldDef{name: name, bits: 16, scale: scale, offset: kelvin, unit: "degC"}
+}
+
+var pgns = map[uint32]*PGNInfo{}
+
+// fastPacketPGNs are sent as fast-packet messages, including PGNs without a decoder.
+var fastPacketPGNs = map[uint32]bool{}
+
+func init() {
+	for _, p := range []PGNInfo{
+		{PGN: 126992, Name: "System Time", fields: []fieldDef{
+			sid(), unsigned("Source", 4), reserved(4),
+			{name: "Date", bits: 16, scale: 1, unit: "days"},
+			{name: "Time", bits: 32, scale: 1e-4, unit: "s"},
+		}},
+		{PGN: 127250, Name: "Vessel Heading", fields: []fieldDef{
+			sid(), deg("Heading", false), deg("Deviation", true), deg("Variation", true), unsigned("Reference", 2),
+		}},
+		{PGN: 127251, Name: "Rate of Turn", fields: []fieldDef{
+			sid(), {name: "Rate", bits: 32, signed: true, scale: 3.125e-8 * 180 / math.Pi, unit: "deg/s"},
+		}},
+		{PGN: 127257, Name: "Attitude", fields: []fieldDef{
+			sid(), deg("Yaw", true), deg("Pitch", true), deg("Roll", true),
+		}},
+		{PGN: 127488, Name: "Engine Parameters, Rapid Update", fields: []fieldDef{
+			unsigned("Instance", 8),
+			{name: "Speed", bits: 16, scale: 0.25, unit: "rpm"},
+			{name: "Boost Pressure", bits: 16, scale: 0.1, unit: "kPa"},
+			{name: "Tilt/Trim", bits: 8, signed: true, scale: 1, unit: "%"},
+		}},
+		{PGN: 127489, Name: "Engine Parameters, Dynamic", FastPacket: true, fields: []fieldDef{
+			unsigned("Instance", 8),
+			{name: "Oil Pressure", bits: 16, scale: 0.1, unit: "kPa"},
+			temp("Oil Temperature", 0.1),
+			temp("Temperature", 0.01),
+			{name: "Alternator Potential", bits: 16, signed: true, scale: 0.01, unit: "V"},
+			{name: "Fuel Rate", bits: 16, signed: true, scale: 0.1, unit: "L/h"},
+			{name: "Total Engine Hours", bits: 32, scale: 1.0 / 3600, unit: "h"},
+			{name: "Coolant Pressure", bits: 16, scale: 0.1, unit: "kPa"},
+			{name: "Fuel Pressure", bits: 16, scale: 1, unit: "kPa"},
+			reserved(8),
+			unsigned("Discrete Status 1", 16),
+			unsigned("Discrete Status 2", 16),
+			{name: "Engine Load", bits: 8, signed: true, scale: 1, unit: "%"},
+			{name: "Engine Torque", bits: 8, signed: true, scale: 1, unit: "%"},
+		}},
+		{PGN: 127505, Name: "Fluid Level", fields: []fieldDef{
+			unsigned("Instance", 4), unsigned("Type", 4),
+			{name: "Level", bits: 16, signed: true, scale: 0.004, unit: "%"},
+			{name: "Capacity", bits: 32, scale: 0.1, unit: "L"},
+		}},
+		{PGN: 127508, Name: "Battery Status", fields: []fieldDef{
+			unsigned("Instance", 8),
+			{name: "Voltage", bits: 16, signed: true, scale: 0.01, unit: "V"},
+			{name: "Current", bits: 16, signed: true, scale: 0.1, unit: "A"},
+			temp("Temperature", 0.01),
+			sid(),
+		}},
+		{PGN: 128259, Name: "Speed", fields: []fieldDef{
+			sid(),
+			{name: "Speed Water Referenced", bits: 16, scale: 0.01, unit: "m/s"},
+			{name: "Speed Ground Referenced", bits: 16, scale: 0.01, unit: "m/s"},
+		}},
+		{PGN: 128267, Name: "Water Depth", fields: []fieldDef{
+			sid(),
+			{name: "Depth", bits: 32, scale: 0.01, unit: "m"},
+			{name: "Offset", bits: 16, signed: true, scale: 0.001, unit: "m"},
+			{name: "Range", bits: 8, scale: 10, unit: "m"},
+		}},
+		{PGN: 129025, Name: "Position, Rapid Update", fields: []fieldDef{
+			{name: "Latitude", bits: 32, signed: true, scale: 1e-7, unit: "deg"},
+			{name: "Longitude", bits: 32, signed: true, scale: 1e-7, unit: "deg"},
+		}},
+		{PGN: 129026, Name: "COG & SOG, Rapid Update", fields: []fieldDef{
+			sid(), unsigned("COG Reference", 2), reserved(6), deg("COG", false),
+			{name: "SOG", bits: 16, scale: 0.01, unit: "m/s"},
+		}},
+		{PGN: 129029, Name: "GNSS Position Data", FastPacket: true, fields: []fieldDef{
+			sid(),
+			{name: "Date", bits: 16, scale: 1, unit: "days"},
+			{name: "Time", bits: 32, scale: 1e-4, unit: "s"},
+			{name: "Latitude", bits: 64, signed: true, scale: 1e-16, unit: "deg"},
+			{name: "Longitude", bits: 64, signed: true, scale: 1e-16, unit: "deg"},
+			{name: "Altitude", bits: 64, signed: true, scale: 1e-6, unit: "m"},
+			unsigned("GNSS Type", 4), unsigned("Method", 4), unsigned("Integrity", 2), reserved(6),
+			unsigned("Satellites", 8),
+			{name: "HDOP", bits: 16, signed: true, scale: 0.01},
+			{name: "PDOP", bits: 16, signed: true, scale: 0.01},
+			{name: "Geoidal Separation", bits: 32, signed: true, scale: 0.01, unit: "m"},
+		}},
+		{PGN: 130306, Name: "Wind Data", fields: []fieldDef{
+			sid(),
+			{name: "Wind Speed", bits: 16, scale: 0.01, unit: "m/s"},
+			deg("Wind Angle", false),
+			unsigned("Reference", 3),
+		}},
+		{PGN: 130310, Name: "Environmental Parameters", fields: []fieldDef{
+			sid(), temp("Water Temperature", 0.01), temp("Outside Ambient Air Temperature", 0.01),
+			{name: "Atmospheric Pressure", bits: 16, scale: 0.1, unit: "kPa"},
+		}},
+		{PGN: 130312, Name: "Temperature", fields: []fieldDef{
+			sid(), unsigned("Instance", 8), unsigned("Source", 8), temp("Actual Temperature", 0.01), temp("Set Temperature", 0.01),
+		}},
+	} {
+		pgns[p.PGN] = &p
+		if p.FastPacket {
+			fastPacketPGNs[p.PGN] = true
+		}
+	}
+	for _, pgn := range []uint32{
+		126208, 126464, 126720, 126996, 126998, 127233, 127237, 127496, 127497, 127498,
+		127503, 127506, 128275, 129038, 129039, 129040, 129041, 129044, 129045, 129284,
+		129285, 129301, 129302, 129540, 129793, 129794, 129795, 129797, 129798, 129809,
+		129810, 130074, 130323, 130577,
+	} {
+		fastPacketPGNs[pgn] = true
+	}
+}
+
+// LookupPGN returns the description of a known PGN.
+func LookupPGN(pgn uint32) (PGNInfo, bool) {
+	p, ok := pgns[pgn]
+	if !ok {
+		return PGNInfo{}, false
+	}
+	return *p, true
+}
+
+// IsFastPacket reports whether pgn is sent as fast-packet messages.
+func IsFastPacket(pgn uint32) bool {
+	return fastPacketPGNs[pgn]
+}
+
+// Decode decodes the fields of a message with header h. Unknown PGNs get no fields.
+func Decode(h j1939.Header, data []byte) Message {
+	m := Message{Header: h, Data: append([]byte(nil), data...)}
+	p, ok := pgns[h.PGN]
+	if !ok {
+		return m
+	}
+	m.Name = p.Name
+	bit := 0
+	for _, fd := range p.fields {
+		start := bit
+		bit += fd.bits
+		if bit > 8*len(data) {
+			break
+		}
+		if fd.name == "" {
+			continue
+		}
+		raw, ok := extract(data, start, fd.bits, fd.signed)
+		if !ok {
+			continue
+		}
+		m.Fields = append(m.Fields, Field{Name: fd.name, Value: raw*fd.scale + fd.offset, Unit: fd.unit})
+	}
+	return m
+}
+
+// extract reads a little-endian bit field. It reports false for the "not available"
+// values: all bits set for unsigned fields of 2 bits or more, the largest positive
+// value for signed fields.
+func extract(data []byte, start, bits int, signed bool) (float64, bool) {
+	var v uint64
+	for i := 0; i < bits; i++ {
+		pos := start + i
+		if data[pos/8]>>(pos%8)&1 != 0 {
+			v |= 1 << i
+		}
+	}
+	if signed {
+		if v == 1<<(bits-1)-1 {
+			return 0, false
+		}
+		shift := 64 - bits
+		return float64(int64(v<<shift) >> shift), true
+	}
+	if bits >= 2 && v == 1<<bits-1 {
+		return 0, false
+	}
+	return float64(v), true
+}
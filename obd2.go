@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/obd2"
+)
+
+// obdResponseTimeout is how long a mode 01 request waits for the first ECU to answer.
+const obdResponseTimeout = 250 * time.Millisecond
+
+// OBDPIDInfo describes a mode 01 PID with a known scaling.
+type OBDPIDInfo struct {
+	PID  uint8  `json:"pid"`
+	Name string `json:"name"`
+	Unit string `json:"unit"`
+}
+
+// OBDPIDEvent is a mode 01 response emitted on "obd:pid".
+type OBDPIDEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	// ECU is the CAN ID the ECU answered on (0x7E8..0x7EF).
+	ECU  uint32 `json:"ecu"`
+	PID  uint8  `json:"pid"`
+	Name string `json:"name"`
+	Unit string `json:"unit"`
+	// Value is the scaled value, Known is false for PIDs without a known scaling.
+	Value float64  `json:"value"`
+	Known bool     `json:"known"`
+	Raw   []uint32 `json:"raw"`
+}
+
+type obdWaiter struct {
+	iface string
+	pid   byte
+	ch    chan OBDPIDEvent
+}
+
+type obdPoller struct {
+	iface  string
+	pids   []byte
+	period time.Duration
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// OBDKnownPIDs returns the mode 01 PIDs the app can scale.
+func (a *App) OBDKnownPIDs() []OBDPIDInfo {
+	pids := obd2.PIDs()
+	infos := make([]OBDPIDInfo, len(pids))
+	for i, p := range pids {
+		infos[i] = OBDPIDInfo{PID: p.PID, Name: p.Name, Unit: p.Unit}
+	}
+	return infos
+}
+
+// ReadOBDPID sends a mode 01 request for pid on 0x7DF and returns the first answer.
+// Answers of every ECU are also emitted on "obd:pid".
+func (a *App) ReadOBDPID(iface string, pid uint8) (OBDPIDEvent, error) {
+	return a.requestOBD(strings.TrimSpace(iface), pid, a.transmit)
+}
+
+// QueryOBDSupportedPIDs reads the "PIDs supported" bitmaps of the first ECU that answers
+// and returns the mode 01 PIDs it supports.
+func (a *App) QueryOBDSupportedPIDs(iface string) ([]uint8, error) {
+	iface = strings.TrimSpace(iface)
+	supported := []uint8{}
+	for base := 0; base <= 0xe0; base += 0x20 {
+		ev, err := a.requestOBD(iface, byte(base), a.transmit)
+		if err != nil {
+			if base > 0 && errors.Is(err, context.DeadlineExceeded) {
+				break
+			}
+			return nil, err
+		}
+		pids := obd2.SupportedPIDs(byte(base), wordsBytes(ev.Raw))
+		next := false
+		for _, pid := range pids {
+			if obd2.IsSupportedPIDsRequest(pid) {
+				next = true
+			} else {
+				supported = append(supported, pid)
+			}
+		}
+		if !next {
+			break
+		}
+	}
+	return supported, nil
+}
+
+// StartOBDPolling requests the given PIDs one after the other every periodMs milliseconds
+// on a started interface. Answers are emitted on "obd:pid".
+func (a *App) StartOBDPolling(iface string, pids []uint8, periodMs int) error {
+	iface = strings.TrimSpace(iface)
+	if len(pids) == 0 {
+		return errors.New("no PIDs to poll")
+	}
+	if periodMs < 1 {
+		return fmt.Errorf("period must be >= 1 ms (got %d)", periodMs)
+	}
+	if _, err := a.txConn(iface, false); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &obdPoller{
+		iface:  iface,
+		pids:   append([]byte(nil), pids...),
+		period: time.Duration(periodMs) * time.Millisecond,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	a.obdMu.Lock()
+	if _, ok := a.obdPollers[iface]; ok {
+		a.obdMu.Unlock()
+		cancel()
+		return fmt.Errorf("OBD polling already running on %s", iface)
+	}
+	a.obdPollers[iface] = p
+	a.obdMu.Unlock()
+
+	go a.obdPollLoop(ctx, p)
+	return nil
+}
+
+// StopOBDPolling stops the polling started with StartOBDPolling on iface.
+func (a *App) StopOBDPolling(iface string) error {
+	a.stopOBDPolling(strings.TrimSpace(iface))
+	return nil
+}
+
+func (a *App) stopOBDPolling(iface string) {
+	a.obdMu.Lock()
+	p := a.obdPollers[iface]
+	delete(a.obdPollers, iface)
+	a.obdMu.Unlock()
+
+	if p != nil {
+		p.cancel()
+		<-p.done
+	}
+}
+
+func (a *App) obdPollLoop(ctx context.Context, p *obdPoller) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.period)
+	defer ticker.Stop()
+
+	failing := false
+	for {
+		for _, pid := range p.pids {
+			_, err := a.requestOBD(p.iface, pid, a.send)
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				// ECUs do not answer unsupported PIDs
+				continue
+			}
+			// report the first failure of a run only, the next cycles would repeat it
+			if err != nil && !failing {
+				a.emitError(fmt.Errorf("OBD polling on %s: %w", p.iface, err))
+			}
+			failing = err != nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// requestOBD sends a mode 01 request with send and waits for the first answer.
+func (a *App) requestOBD(iface string, pid byte, send func(string, canbus.Frame) error) (OBDPIDEvent, error) {
+	w := &obdWaiter{iface: iface, pid: pid, ch: make(chan OBDPIDEvent, 1)}
+	a.obdMu.Lock()
+	a.obdWaiters[w] = struct{}{}
+	a.obdMu.Unlock()
+	defer func() {
+		a.obdMu.Lock()
+		delete(a.obdWaiters, w)
+		a.obdMu.Unlock()
+	}()
+
+	sent := time.Now()
+	if err := send(iface, obd2.RequestFrame(pid)); err != nil {
+		a.recordOBD(iface, pid, sent, time.Time{}, OBDPIDEvent{}, err)
+		return OBDPIDEvent{}, err
+	}
+	timer := time.NewTimer(obdResponseTimeout)
+	defer timer.Stop()
+	select {
+	case ev := <-w.ch:
+		a.recordOBD(iface, pid, sent, time.Now(), ev, nil)
+		return ev, nil
+	case <-timer.C:
+		err := fmt.Errorf("%s: no answer: %w", obd2.Name(pid), context.DeadlineExceeded)
+		a.recordOBD(iface, pid, sent, time.Time{}, OBDPIDEvent{}, err)
+		return OBDPIDEvent{}, err
+	}
+}
+
+// dispatchOBD emits mode 01 responses received on iface and wakes up the pending requests.
+func (a *App) dispatchOBD(iface string, ts time.Time, f *canbus.Frame) {
+	resp, ok := obd2.ParseResponse(f)
+	if !ok {
+		return
+	}
+	ev := OBDPIDEvent{
+		Timestamp: ts,
+		Interface: iface,
+		ECU:       resp.ECU,
+		PID:       resp.PID,
+		Name:      obd2.Name(resp.PID),
+		Raw:       dataWords(resp.Data),
+	}
+	if v, err := obd2.Decode(resp.PID, resp.Data); err == nil {
+		ev.Name, ev.Unit, ev.Value, ev.Known = v.Name, v.Unit, v.Value, true
+	} else if !errors.Is(err, obd2.ErrUnknownPID) {
+		return
+	}
+
+	a.obdMu.Lock()
+	for w := range a.obdWaiters {
+		if w.iface == iface && w.pid == resp.PID {
+			select {
+			case w.ch <- ev:
+			default:
+			}
+		}
+	}
+	a.obdMu.Unlock()
+
+	// bitmaps of the "PIDs supported" requests are not live data
+	if !obd2.IsSupportedPIDsRequest(resp.PID) {
+		a.emit("obd:pid", ev)
+	}
+}
+
+// wordsBytes narrows event payload words back to bytes.
+func wordsBytes(w []uint32) []byte {
+	b := make([]byte, len(w))
+	for i, v := range w {
+		b[i] = byte(v)
+	}
+	return b
+}
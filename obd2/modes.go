@@ -0,0 +1,246 @@
+package obd2
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Services beyond the current data, sent on a physical ISO-TP channel as their
+// responses take several frames.
+const (
+	// ServiceFreezeFrame is mode 02, the data stored when a DTC was set.
+	ServiceFreezeFrame = 0x02
+	// ServiceTestResults is mode 06, the results of the on-board monitoring tests.
+	ServiceTestResults = 0x06
+	// ServiceVehicleInfo is mode 09, the VIN and the calibration of the ECU.
+	ServiceVehicleInfo = 0x09
+
+	// PIDFreezeFrameDTC is the mode 02 PID of the DTC that stored the frame.
+	PIDFreezeFrameDTC = 0x02
+)
+
+// Mode 09 info types.
+const (
+	InfoVIN            = 0x02
+	InfoCalibrationIDs = 0x04
+	InfoCVNs           = 0x06
+	InfoECUName        = 0x0a
+)
+
+// FreezeFrameData checks a mode 02 response, including its service byte, to
+// the request of pid in frame and returns the data of the PID.
+func FreezeFrameData(resp []byte, pid, frame byte) ([]byte, error) {
+	if len(resp) < 3 || resp[0] != ServiceFreezeFrame+positiveOffset || resp[1] != pid || resp[2] != frame {
+		return nil, fmt.Errorf("obd2: malformed freeze frame response % X to PID 0x%02X", resp, pid)
+	}
+	return resp[3:], nil
+}
+
+// TestResult is a mode 06 test result in the CAN format.
+type TestResult struct {
+	MID byte
+	TID byte
+	// UASID is the unit and scaling of Value, Min and Max; Known is false
+	// for the unknown ones, whose values are left unscaled.
+	UASID           byte
+	Unit            string
+	Value, Min, Max float64
+	Known           bool
+}
+
+// Passed reports whether the value of a test is within its limits.
+func (r *TestResult) Passed() bool {
+	return r.Value >= r.Min && r.Value <= r.Max
+}
+
+// ParseTestResults decodes a mode 06 response, including its service byte:
+// 9 byte records of monitor ID, test ID, unit and scaling ID, test value,
+// minimum and maximum limits. It returns the monitor ID of the first record.
+func ParseTestResults(resp []byte) (byte, []TestResult, error) {
+	if len(resp) < 10 || resp[0] != ServiceTestResults+positiveOffset || (len(resp)-1)%9 != 0 {
+		return 0, nil, fmt.Errorf("obd2: malformed test results response % X", resp)
+	}
+	results := make([]TestResult, 0, (len(resp)-1)/9)
+	for rec := resp[1:]; len(rec) >= 9; rec = rec[9:] {
+		r := TestResult{MID: rec[0], TID: rec[1], UASID: rec[2]}
+		var s scaling
+		s, r.Known = scalings[r.UASID]
+		if !r.Known {
+			s = scaling{factor: 1}
+		}
+		r.Unit = s.unit
+		r.Value = s.apply(binary.BigEndian.Uint16(rec[3:]), r.UASID)
+		r.Min = s.apply(binary.BigEndian.Uint16(rec[5:]), r.UASID)
+		r.Max = s.apply(binary.BigEndian.Uint16(rec[7:]), r.UASID)
+		results = append(results, r)
+	}
+	return resp[1], results, nil
+}
+
+// scaling is a unit and scaling ID of SAE J1979 appendix E.
+type scaling struct {
+	unit           string
+	factor, offset float64
+}
+
+// apply scales a raw value, signed for the unit and scaling IDs from 0x80.
+func (s scaling) apply(raw uint16, uasid byte) float64 {
+	v := float64(raw)
+	if uasid >= 0x80 {
+		v = float64(int16(raw))
+	}
+	return v*s.factor + s.offset
+}
+
+var scalings = map[byte]scaling{
+	0x01: {factor: 1},
+	0x02: {factor: 0.1},
+	0x03: {factor: 0.01},
+	0x04: {factor: 0.001},
+	0x05: {factor: 0.0000305},
+	0x06: {factor: 0.000305},
+	0x07: {unit: "rpm", factor: 0.25},
+	0x08: {unit: "km/h", factor: 0.01},
+	0x09: {unit: "km/h", factor: 1},
+	0x0a: {unit: "mV", factor: 0.122},
+	0x0b: {unit: "V", factor: 0.001},
+	0x0c: {unit: "V", factor: 0.01},
+	0x0d: {unit: "mA", factor: 0.00390625},
+	0x0e: {unit: "A", factor: 0.001},
+	0x0f: {unit: "A", factor: 0.01},
+	0x10: {unit: "ms", factor: 1},
+	0x11: {unit: "ms", factor: 100},
+	0x12: {unit: "s", factor: 1},
+	0x13: {unit: "mΩ", factor: 1},
+	0x14: {unit: "Ω", factor: 1},
+	0x15: {unit: "kΩ", factor: 1},
+	0x16: {unit: "°C", factor: 0.1, offset: -40},
+	0x17: {unit: "kPa", factor: 0.01},
+	0x1a: {unit: "kPa", factor: 1},
+	0x1b: {unit: "kPa", factor: 10},
+	0x1c: {unit: "°", factor: 0.01},
+	0x1d: {unit: "°", factor: 0.5},
+	0x1e: {factor: 0.0000305},
+	0x24: {unit: "counts", factor: 1},
+	0x25: {unit: "km", factor: 1},
+	0x27: {unit: "g/s", factor: 0.01},
+	0x28: {unit: "g/s", factor: 1},
+	0x2f: {unit: "%", factor: 0.01},
+	0x81: {factor: 1},
+	0x82: {factor: 0.1},
+	0x83: {factor: 0.01},
+	0x84: {factor: 0.001},
+	0x8a: {unit: "mV", factor: 0.122},
+	0x8b: {unit: "V", factor: 0.001},
+	0x8c: {unit: "V", factor: 0.01},
+	0x8e: {unit: "A", factor: 0.001},
+	0x90: {unit: "ms", factor: 1},
+	0x96: {unit: "°C", factor: 0.1},
+	0x9c: {unit: "°", factor: 0.01},
+	0xaf: {unit: "%", factor: 0.01},
+	0xfc: {unit: "kPa", factor: 0.01},
+	0xfd: {unit: "kPa", factor: 0.001},
+	0xfe: {unit: "Pa", factor: 0.25},
+}
+
+// MonitorName returns the name of a mode 06 monitor ID.
+func MonitorName(mid byte) string {
+	bank := func(first byte) string { return fmt.Sprintf("bank %d", mid-first+1) }
+	sensor := func(first byte) string {
+		n := mid - first
+		return fmt.Sprintf("bank %d sensor %d", n/4+1, n%4+1)
+	}
+	switch {
+	case IsSupportedPIDsRequest(mid):
+		return fmt.Sprintf("Monitor IDs supported 0x%02X-0x%02X", mid+1, mid+0x20)
+	case mid <= 0x10:
+		return "Oxygen sensor monitor " + sensor(0x01)
+	case mid >= 0x21 && mid <= 0x24:
+		return "Catalyst monitor " + bank(0x21)
+	case mid >= 0x31 && mid <= 0x34:
+		return "EGR monitor " + bank(0x31)
+	case mid >= 0x35 && mid <= 0x38:
+		return "VVT monitor " + bank(0x35)
+	case mid == 0x39:
+		return "EVAP monitor (cap off / 0.150\")"
+	case mid == 0x3a:
+		return "EVAP monitor (0.090\")"
+	case mid == 0x3b:
+		return "EVAP monitor (0.040\")"
+	case mid == 0x3c:
+		return "EVAP monitor (0.020\")"
+	case mid == 0x3d:
+		return "Purge flow monitor"
+	case mid >= 0x41 && mid <= 0x50:
+		return "Oxygen sensor heater monitor " + sensor(0x41)
+	case mid >= 0x61 && mid <= 0x64:
+		return "Heated catalyst monitor " + bank(0x61)
+	case mid >= 0x71 && mid <= 0x74:
+		return fmt.Sprintf("Secondary air monitor %d", mid-0x70)
+	case mid >= 0x81 && mid <= 0x84:
+		return "Fuel system monitor " + bank(0x81)
+	case mid >= 0x85 && mid <= 0x86:
+		return "Boost pressure control monitor " + bank(0x85)
+	case mid >= 0x90 && mid <= 0x91:
+		return "NOx adsorber monitor " + bank(0x90)
+	case mid >= 0x98 && mid <= 0x99:
+		return "NOx catalyst monitor " + bank(0x98)
+	case mid == 0xa1:
+		return "Misfire monitor general data"
+	case mid >= 0xa2 && mid <= 0xad:
+		return fmt.Sprintf("Misfire cylinder %d data", mid-0xa1)
+	case mid >= 0xb0 && mid <= 0xb1:
+		return "PM filter monitor " + bank(0xb0)
+	}
+	return fmt.Sprintf("Monitor 0x%02X", mid)
+}
+
+var testNames = map[byte]string{
+	0x01: "Rich to lean sensor threshold voltage",
+	0x02: "Lean to rich sensor threshold voltage",
+	0x03: "Low sensor voltage for switch time calculation",
+	0x04: "High sensor voltage for switch time calculation",
+	0x05: "Rich to lean sensor switch time",
+	0x06: "Lean to rich sensor switch time",
+	0x07: "Minimum sensor voltage for test cycle",
+	0x08: "Maximum sensor voltage for test cycle",
+	0x09: "Time between sensor transitions",
+	0x0a: "Sensor period",
+	0x0b: "EWMA misfire counts for the last 10 driving cycles",
+	0x0c: "Misfire counts for the last or current driving cycle",
+}
+
+// TestName returns the name of a standard mode 06 test ID; the IDs from 0x80
+// are defined by the manufacturer.
+func TestName(tid byte) string {
+	if n, ok := testNames[tid]; ok {
+		return n
+	}
+	if tid >= 0x80 {
+		return fmt.Sprintf("Manufacturer test 0x%02X", tid)
+	}
+	return fmt.Sprintf("Test 0x%02X", tid)
+}
+
+// VehicleInfo checks a mode 09 response, including its service byte, to the
+// request of infoType and returns the number of data items and their data.
+func VehicleInfo(resp []byte, infoType byte) (int, []byte, error) {
+	if len(resp) < 3 || resp[0] != ServiceVehicleInfo+positiveOffset || resp[1] != infoType {
+		return 0, nil, fmt.Errorf("obd2: malformed vehicle info response % X to info type 0x%02X", resp, infoType)
+	}
+	return int(resp[2]), resp[3:], nil
+}
+
+// InfoStrings splits the data of a mode 09 response into n items of size
+// bytes, as text without the padding.
+func InfoStrings(data []byte, n, size int) ([]string, error) {
+	if len(data) < n*size {
+		return nil, fmt.Errorf("obd2: %d items of %d bytes in %d bytes", n, size, len(data))
+	}
+	out := make([]string, n)
+	for i := range out {
+		out[i] = strings.TrimRight(string(data[i*size:(i+1)*size]), "\x00 ")
+	}
+	return out, nil
+}
@@ -0,0 +1,186 @@
+// Package obd2 implements OBD-II (SAE J1979 / ISO 15765-4) mode 01 requests
+// and the standard scaling of the current data PIDs, and decodes the freeze
+// frames (mode 02), test results (mode 06) and vehicle information (mode 09).
+//
+// Requests are sent as single frames on the functional address 0x7DF; every
+// emission-related ECU answers on its own response ID in 0x7E8..0x7EF.
+package obd2
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"canproject/canbus"
+)
+
+const (
+	// FunctionalID is the 11-bit functional request ID every OBD ECU listens to.
+	FunctionalID = 0x7df
+	// FirstResponseID and LastResponseID bound the physical response IDs of the ECUs.
+	FirstResponseID = 0x7e8
+	LastResponseID  = 0x7ef
+
+	// ServiceCurrentData is mode 01, show current data.
+	ServiceCurrentData = 0x01
+	// positiveOffset is added to the service to form the response service.
+	positiveOffset = 0x40
+	// paddingByte fills request frames to 8 bytes as required by ISO 15765-4.
+	paddingByte = 0x55
+)
+
+// ErrUnknownPID is returned by Decode for PIDs without a known scaling.
+var ErrUnknownPID = errors.New("obd2: unknown PID")
+
+// PID describes a mode 01 parameter.
+type PID struct {
+	PID  byte
+	Name string
+	Unit string
+	// Length is the number of data bytes of the response.
+	Length int
+
+	decode func(d []byte) float64
+}
+
+// Value is a decoded mode 01 response.
+type Value struct {
+	PID   byte
+	Name  string
+	Unit  string
+	Value float64
+}
+
+// Response is a mode 01 response frame.
+type Response struct {
+	// ECU is the CAN ID the ECU answered on.
+	ECU  uint32
+	PID  byte
+	Data []byte
+}
+
+func pct(d []byte) float64       { return float64(d[0]) * 100 / 255 }
+func temp(d []byte) float64      { return float64(d[0]) - 40 }
+func trim(d []byte) float64      { return (float64(d[0]) - 128) * 100 / 128 }
+func byteValue(d []byte) float64 { return float64(d[0]) }
+func word(d []byte) float64      { return float64(uint16(d[0])<<8 | uint16(d[1])) }
+
+var pids = map[byte]*PID{}
+
+func init() {
+	for _, p := range []*PID{
+		{PID: 0x04, Name: "Calculated engine load", Unit: "%", Length: 1, decode: pct},
+		{PID: 0x05, Name: "Engine coolant temperature", Unit: "°C", Length: 1, decode: temp},
+		{PID: 0x06, Name: "Short term fuel trim bank 1", Unit: "%", Length: 1, decode: trim},
+		{PID: 0x07, Name: "Long term fuel trim bank 1", Unit: "%", Length: 1, decode: trim},
+		{PID: 0x08, Name: "Short term fuel trim bank 2", Unit: "%", Length: 1, decode: trim},
+		{PID: 0x09, Name: "Long term fuel trim bank 2", Unit: "%", Length: 1, decode: trim},
+		{PID: 0x0a, Name: "Fuel pressure", Unit: "kPa", Length: 1, decode: func(d []byte) float64 { return 3 * float64(d[0]) }},
+		{PID: 0x0b, Name: "Intake manifold absolute pressure", Unit: "kPa", Length: 1, decode: byteValue},
+		{PID: 0x0c, Name: "Engine speed", Unit: "rpm", Length: 2, decode: func(d []byte) float64 { return word(d) / 4 }},
+		{PID: 0x0d, Name: "Vehicle speed", Unit: "km/h", Length: 1, decode: byteValue},
+		{PID: 0x0e, Name: "Timing advance", Unit: "°", Length: 1, decode: func(d []byte) float64 { return float64(d[0])/2 - 64 }},
+		{PID: 0x0f, Name: "Intake air temperature", Unit: "°C", Length: 1, decode: temp},
+		{PID: 0x10, Name: "Mass air flow rate", Unit: "g/s", Length: 2, decode: func(d []byte) float64 { return word(d) / 100 }},
+		{PID: 0x11, Name: "Throttle position", Unit: "%", Length: 1, decode: pct},
+		{PID: 0x1f, Name: "Run time since engine start", Unit: "s", Length: 2, decode: word},
+		{PID: 0x21, Name: "Distance traveled with MIL on", Unit: "km", Length: 2, decode: word},
+		{PID: 0x2c, Name: "Commanded EGR", Unit: "%", Length: 1, decode: pct},
+		{PID: 0x2f, Name: "Fuel tank level input", Unit: "%", Length: 1, decode: pct},
+		{PID: 0x31, Name: "Distance traveled since codes cleared", Unit: "km", Length: 2, decode: word},
+		{PID: 0x33, Name: "Absolute barometric pressure", Unit: "kPa", Length: 1, decode: byteValue},
+		{PID: 0x42, Name: "Control module voltage", Unit: "V", Length: 2, decode: func(d []byte) float64 { return word(d) / 1000 }},
+		{PID: 0x45, Name: "Relative throttle position", Unit: "%", Length: 1, decode: pct},
+		{PID: 0x46, Name: "Ambient air temperature", Unit: "°C", Length: 1, decode: temp},
+		{PID: 0x5c, Name: "Engine oil temperature", Unit: "°C", Length: 1, decode: temp},
+		{PID: 0x5e, Name: "Engine fuel rate", Unit: "L/h", Length: 2, decode: func(d []byte) float64 { return word(d) / 20 }},
+	} {
+		pids[p.PID] = p
+	}
+}
+
+// Lookup returns the description of a PID.
+func Lookup(pid byte) (PID, bool) {
+	p, ok := pids[pid]
+	if !ok {
+		return PID{}, false
+	}
+	return *p, true
+}
+
+// PIDs returns the PIDs with a known scaling ordered by number.
+func PIDs() []PID {
+	out := make([]PID, 0, len(pids))
+	for _, p := range pids {
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].PID < out[j].PID
+	})
+	return out
+}
+
+// Name returns the name of a PID, or its number when it is unknown.
+func Name(pid byte) string {
+	if p, ok := pids[pid]; ok {
+		return p.Name
+	}
+	return fmt.Sprintf("PID 0x%02X", pid)
+}
+
+// IsSupportedPIDsRequest reports whether pid is one of the "PIDs supported" bitmaps 0x00, 0x20, 0x40, ...
+func IsSupportedPIDsRequest(pid byte) bool {
+	return pid%0x20 == 0
+}
+
+// RequestFrame returns the functional mode 01 request for pid.
+func RequestFrame(pid byte) canbus.Frame {
+	f := canbus.Frame{ID: FunctionalID, Length: 8}
+	copy(f.Data[:], []byte{0x02, ServiceCurrentData, pid, paddingByte, paddingByte, paddingByte, paddingByte, paddingByte})
+	return f
+}
+
+// ParseResponse extracts a mode 01 response from a received frame.
+// It reports false for frames that are not single-frame mode 01 responses.
+func ParseResponse(f *canbus.Frame) (Response, bool) {
+	if f.IsExtended || f.IsRemote || f.IsError || f.ID < FirstResponseID || f.ID > LastResponseID {
+		return Response{}, false
+	}
+	data := f.Payload()
+	if len(data) < 3 || data[0]>>4 != 0 {
+		return Response{}, false
+	}
+	n := int(data[0] & 0x0f)
+	if n < 2 || n > len(data)-1 || data[1] != ServiceCurrentData+positiveOffset {
+		return Response{}, false
+	}
+	return Response{
+		ECU:  f.ID,
+		PID:  data[2],
+		Data: append([]byte(nil), data[3:1+n]...),
+	}, true
+}
+
+// Decode applies the standard scaling of pid to its response data.
+func Decode(pid byte, data []byte) (Value, error) {
+	p, ok := pids[pid]
+	if !ok {
+		return Value{PID: pid, Name: Name(pid)}, ErrUnknownPID
+	}
+	if len(data) < p.Length {
+		return Value{}, fmt.Errorf("obd2: %s response too short (%d bytes)", p.Name, len(data))
+	}
+	return Value{PID: pid, Name: p.Name, Unit: p.Unit, Value: p.decode(data)}, nil
+}
+
+// SupportedPIDs decodes the bitmap answered to a "PIDs supported" request for base
+// (0x00, 0x20, ...) and returns the supported PIDs in base+1..base+0x20.
+func SupportedPIDs(base byte, data []byte) []byte {
+	var out []byte
+	for i := 0; i < 32 && i/8 < len(data); i++ {
+		if data[i/8]&(0x80>>(i%8)) != 0 {
+			out = append(out, base+byte(i)+1)
+		}
+	}
+	return out
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"canproject/dtc"
+	"canproject/obd2"
+	"canproject/uds"
+)
+
+// OBDParameter is a PID value of a freeze frame.
+type OBDParameter struct {
+	PID  uint8  `json:"pid"`
+	Name string `json:"name"`
+	Unit string `json:"unit"`
+	// Value is the scaled value, Known is false for PIDs without a known scaling.
+	Value float64  `json:"value"`
+	Known bool     `json:"known"`
+	Raw   []uint32 `json:"raw"`
+}
+
+// OBDFreezeFrame is a freeze frame read with OBDReadFreezeFrame, emitted on "obd:freezeframe".
+type OBDFreezeFrame struct {
+	Handle int   `json:"handle"`
+	Frame  uint8 `json:"frame"`
+	// DTC is the code that stored the frame, eg "P0301".
+	DTC         string         `json:"dtc"`
+	Description string         `json:"description,omitempty"`
+	Parameters  []OBDParameter `json:"parameters"`
+}
+
+// OBDMonitorTest is an on-board monitoring test result read with OBDReadTestResults.
+type OBDMonitorTest struct {
+	MID     uint8  `json:"mid"`
+	Monitor string `json:"monitor"`
+	TID     uint8  `json:"tid"`
+	Test    string `json:"test"`
+	// UASID is the unit and scaling ID; Scaled is false when it is unknown and
+	// the values are raw.
+	UASID  uint8   `json:"uasid"`
+	Unit   string  `json:"unit"`
+	Value  float64 `json:"value"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Passed bool    `json:"passed"`
+	Scaled bool    `json:"scaled"`
+}
+
+// OBDTestResults are the mode 06 results of an ECU, emitted on "obd:testresults".
+type OBDTestResults struct {
+	Handle int              `json:"handle"`
+	Tests  []OBDMonitorTest `json:"tests"`
+}
+
+// OBDVehicleInfo is the mode 09 information of an ECU read with
+// OBDReadVehicleInfo, emitted on "obd:vehicleinfo". The items the ECU does not
+// support are empty.
+type OBDVehicleInfo struct {
+	Handle         int      `json:"handle"`
+	VIN            string   `json:"vin"`
+	CalibrationIDs []string `json:"calibrationIds"`
+	// CVNs are the calibration verification numbers in hex.
+	CVNs    []string `json:"cvns"`
+	ECUName string   `json:"ecuName"`
+}
+
+// OBDReadFreezeFrame reads a freeze frame (usually 0) of an ECU with mode 02 on
+// an ISO-TP channel opened with OpenIsoTP (eg 0x7E0/0x7E8): the DTC that stored
+// it and the values of the PIDs it holds.
+func (a *App) OBDReadFreezeFrame(handle int, frame uint8) (OBDFreezeFrame, error) {
+	resp, err := a.obdRequest(handle, obd2.ServiceFreezeFrame, obd2.PIDFreezeFrameDTC, frame)
+	if err != nil {
+		return OBDFreezeFrame{}, err
+	}
+	data, err := obd2.FreezeFrameData(resp, obd2.PIDFreezeFrameDTC, frame)
+	if err != nil {
+		return OBDFreezeFrame{}, err
+	}
+	if len(data) < 2 || data[0] == 0 && data[1] == 0 {
+		return OBDFreezeFrame{}, fmt.Errorf("no freeze frame %d stored", frame)
+	}
+	code := uint16(data[0])<<8 | uint16(data[1])
+	ff := OBDFreezeFrame{Handle: handle, Frame: frame, DTC: dtc.Format(code), Parameters: []OBDParameter{}}
+	ff.Description = a.dtcDatabase.Load().Describe(ff.DTC)
+
+	pids, err := a.obdSupported(handle, obd2.ServiceFreezeFrame, frame)
+	if err != nil {
+		return OBDFreezeFrame{}, err
+	}
+	for _, pid := range pids {
+		if pid == obd2.PIDFreezeFrameDTC {
+			continue
+		}
+		resp, err := a.obdRequest(handle, obd2.ServiceFreezeFrame, pid, frame)
+		if unsupported(err) {
+			continue
+		}
+		if err != nil {
+			return OBDFreezeFrame{}, err
+		}
+		data, err := obd2.FreezeFrameData(resp, pid, frame)
+		if err != nil {
+			return OBDFreezeFrame{}, err
+		}
+		p := OBDParameter{PID: pid, Name: obd2.Name(pid), Raw: dataWords(data)}
+		if v, err := obd2.Decode(pid, data); err == nil {
+			p.Name, p.Unit, p.Value, p.Known = v.Name, v.Unit, v.Value, true
+		}
+		ff.Parameters = append(ff.Parameters, p)
+	}
+	a.emit("obd:freezeframe", ff)
+	return ff, nil
+}
+
+// OBDReadTestResults reads the results of the on-board monitoring tests of an
+// ECU with mode 06 on an ISO-TP channel, for every monitor ID it supports. The
+// standard test IDs are named and the values scaled with their unit and
+// scaling ID; a test passed when its value is within its limits.
+func (a *App) OBDReadTestResults(handle int) (OBDTestResults, error) {
+	mids, err := a.obdSupported(handle, obd2.ServiceTestResults)
+	if err != nil {
+		return OBDTestResults{}, err
+	}
+	res := OBDTestResults{Handle: handle, Tests: []OBDMonitorTest{}}
+	for _, mid := range mids {
+		resp, err := a.obdRequest(handle, obd2.ServiceTestResults, mid)
+		if unsupported(err) {
+			continue
+		}
+		if err != nil {
+			return OBDTestResults{}, err
+		}
+		_, tests, err := obd2.ParseTestResults(resp)
+		if err != nil {
+			return OBDTestResults{}, err
+		}
+		for _, t := range tests {
+			res.Tests = append(res.Tests, OBDMonitorTest{
+				MID:     t.MID,
+				Monitor: obd2.MonitorName(t.MID),
+				TID:     t.TID,
+				Test:    obd2.TestName(t.TID),
+				UASID:   t.UASID,
+				Unit:    t.Unit,
+				Value:   t.Value,
+				Min:     t.Min,
+				Max:     t.Max,
+				Passed:  t.Passed(),
+				Scaled:  t.Known,
+			})
+		}
+	}
+	a.emit("obd:testresults", res)
+	return res, nil
+}
+
+// OBDReadVehicleInfo reads the VIN, calibration IDs, calibration verification
+// numbers and ECU name of an ECU with mode 09 on an ISO-TP channel.
+func (a *App) OBDReadVehicleInfo(handle int) (OBDVehicleInfo, error) {
+	types, err := a.obdSupported(handle, obd2.ServiceVehicleInfo)
+	if err != nil {
+		return OBDVehicleInfo{}, err
+	}
+	info := OBDVehicleInfo{Handle: handle, CalibrationIDs: []string{}, CVNs: []string{}}
+	for _, t := range types {
+		if t != obd2.InfoVIN && t != obd2.InfoCalibrationIDs && t != obd2.InfoCVNs && t != obd2.InfoECUName {
+			continue
+		}
+		resp, err := a.obdRequest(handle, obd2.ServiceVehicleInfo, t)
+		if unsupported(err) {
+			continue
+		}
+		if err != nil {
+			return OBDVehicleInfo{}, err
+		}
+		n, data, err := obd2.VehicleInfo(resp, t)
+		if err != nil {
+			return OBDVehicleInfo{}, err
+		}
+		switch t {
+		case obd2.InfoVIN:
+			var vin []string
+			if vin, err = obd2.InfoStrings(data, 1, 17); err == nil {
+				info.VIN = vin[0]
+			}
+		case obd2.InfoCalibrationIDs:
+			info.CalibrationIDs, err = obd2.InfoStrings(data, n, 16)
+		case obd2.InfoCVNs:
+			if len(data) < 4*n {
+				err = fmt.Errorf("%d CVNs in %d bytes", n, len(data))
+				break
+			}
+			for i := 0; i < n; i++ {
+				info.CVNs = append(info.CVNs, strings.ToUpper(hex.EncodeToString(data[4*i:4*i+4])))
+			}
+		case obd2.InfoECUName:
+			var name []string
+			if name, err = obd2.InfoStrings(data, 1, len(data)); err == nil {
+				info.ECUName = name[0]
+			}
+		}
+		if err != nil {
+			return OBDVehicleInfo{}, fmt.Errorf("info type 0x%02X: %w", t, err)
+		}
+	}
+	a.emit("obd:vehicleinfo", info)
+	return info, nil
+}
+
+// obdRequest sends an OBD request on an ISO-TP channel and returns the
+// positive response.
+func (a *App) obdRequest(handle int, req ...byte) ([]byte, error) {
+	var resp []byte
+	err := a.withUDS(handle, func(ctx context.Context, c *uds.Client) (err error) {
+		resp, err = c.Request(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// obdSupported reads the "supported" bitmaps of a mode, with the bytes of
+// extra after the base of each request, and returns the IDs supported.
+func (a *App) obdSupported(handle int, mode byte, extra ...byte) ([]byte, error) {
+	supported := []byte{}
+	for base := 0; base <= 0xe0; base += 0x20 {
+		req := append([]byte{mode, byte(base)}, extra...)
+		resp, err := a.obdRequest(handle, req...)
+		if base > 0 && unsupported(err) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(resp) < len(req)+4 {
+			return nil, fmt.Errorf("malformed mode %02X response % X", mode, resp)
+		}
+		next := false
+		for _, id := range obd2.SupportedPIDs(byte(base), resp[len(req):len(req)+4]) {
+			if obd2.IsSupportedPIDsRequest(id) {
+				next = true
+			} else {
+				supported = append(supported, id)
+			}
+		}
+		if !next {
+			break
+		}
+	}
+	return supported, nil
+}
+
+// unsupported reports whether err is the answer of an ECU to an item it does
+// not support: a negative response, or none.
+func unsupported(err error) bool {
+	var nrc *uds.NegativeResponseError
+	return errors.As(err, &nrc) || errors.Is(err, uds.ErrTimeout)
+}
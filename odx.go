@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"canproject/odx"
+	"canproject/uds"
+)
+
+// ODXInfo describes the diagnostic description loaded with LoadODX.
+type ODXInfo struct {
+	// Path is empty when none is loaded.
+	Path          string            `json:"path"`
+	Layers        []string          `json:"layers"`
+	DIDs          int               `json:"dids"`
+	FlashSessions []ODXFlashSession `json:"flashSessions"`
+}
+
+// ODXFlashSession is a flash session of an ODX-F file.
+type ODXFlashSession struct {
+	Name       string         `json:"name"`
+	DataBlocks []ODXDataBlock `json:"dataBlocks"`
+}
+
+// ODXDataBlock is a data block of a flash session and its memory segments.
+type ODXDataBlock struct {
+	Name     string       `json:"name"`
+	Segments []ODXSegment `json:"segments"`
+}
+
+// ODXSegment is a memory range of a data block.
+type ODXSegment struct {
+	Name    string `json:"name"`
+	Address uint64 `json:"address"`
+	Size    uint64 `json:"size"`
+}
+
+// ODXDIDInfo is a data identifier of the loaded diagnostic description.
+type ODXDIDInfo struct {
+	ID          uint16         `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Layer       string         `json:"layer"`
+	Params      []ODXParamInfo `json:"params"`
+}
+
+// ODXParamInfo is a value of the record of a DID.
+type ODXParamInfo struct {
+	Name string `json:"name"`
+	// Type is the coded type, eg "A_UINT32", BitLength 0 for the values up
+	// to the end of the record.
+	Type      string `json:"type"`
+	BitLength int    `json:"bitLength"`
+	Unit      string `json:"unit,omitempty"`
+}
+
+// DIDValue is a value of a DID decoded with the description of LoadODX.
+type DIDValue struct {
+	Name string `json:"name"`
+	// Value is the physical value when Numeric is set, Text the text of the
+	// strings, byte fields (in hex) and text tables otherwise.
+	Value   float64 `json:"value"`
+	Numeric bool    `json:"numeric"`
+	Text    string  `json:"text,omitempty"`
+	Unit    string  `json:"unit,omitempty"`
+}
+
+type odxDescription struct {
+	path string
+	db   *odx.Database
+}
+
+// LoadODX loads the DIDs of an ODX diagnostic description, an ODX-D file or
+// a PDX package, so the DIDs read with UDSReadDID and recorded in the
+// diagnostic reports are named and decoded into engineering values. The flash
+// sessions of its ODX-F files are listed. It replaces the description loaded
+// before.
+func (a *App) LoadODX(path string) (ODXInfo, error) {
+	path = strings.TrimSpace(path)
+	db, err := odx.Load(path)
+	if err != nil {
+		return ODXInfo{}, fmt.Errorf("%s: %w", path, err)
+	}
+	d := &odxDescription{path: path, db: db}
+	a.odx.Store(d)
+	return d.info(), nil
+}
+
+// UnloadODX removes the diagnostic description of LoadODX.
+func (a *App) UnloadODX() {
+	a.odx.Store(nil)
+}
+
+// GetODXInfo returns the diagnostic description loaded with LoadODX.
+func (a *App) GetODXInfo() ODXInfo {
+	if d := a.odx.Load(); d != nil {
+		return d.info()
+	}
+	return ODXInfo{Layers: []string{}, FlashSessions: []ODXFlashSession{}}
+}
+
+// ListODXDIDs returns the DIDs of the loaded diagnostic description ordered
+// by identifier.
+func (a *App) ListODXDIDs() []ODXDIDInfo {
+	out := []ODXDIDInfo{}
+	d := a.odx.Load()
+	if d == nil {
+		return out
+	}
+	for _, did := range d.db.SortedDIDs() {
+		info := ODXDIDInfo{
+			ID:          did.ID,
+			Name:        did.Name,
+			Description: did.Description,
+			Layer:       did.Layer,
+			Params:      []ODXParamInfo{},
+		}
+		for _, p := range did.Params {
+			info.Params = append(info.Params, ODXParamInfo{Name: p.Name, Type: p.Type, BitLength: p.BitLength, Unit: p.Unit})
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+// UDSReadDID reads a data identifier like UDSReadDataByIdentifier and decodes
+// it with the description of LoadODX.
+func (a *App) UDSReadDID(handle int, did uint16) (DiagDID, error) {
+	var data []byte
+	err := a.withUDS(handle, func(ctx context.Context, c *uds.Client) (err error) {
+		data, err = c.ReadDataByIdentifier(ctx, did)
+		return err
+	})
+	if err != nil {
+		return DiagDID{}, err
+	}
+	return a.decodeDID(did, data), nil
+}
+
+// decodeDID names and decodes the record of a DID.
+func (a *App) decodeDID(id uint16, data []byte) DiagDID {
+	d := DiagDID{ID: id, Name: uds.DIDName(id), Data: dataWords(data)}
+	if text := strings.TrimRight(string(data), "\x00 "); text != "" && printable(text) {
+		d.Text = text
+	}
+	desc := a.odx.Load()
+	if desc == nil {
+		return d
+	}
+	did := desc.db.DID(id)
+	if did == nil {
+		return d
+	}
+	d.Name = did.Name
+	values, err := did.Decode(data)
+	for _, v := range values {
+		d.Values = append(d.Values, DIDValue{Name: v.Name, Value: v.Value, Numeric: v.Numeric, Text: v.Text, Unit: v.Unit})
+	}
+	if err != nil {
+		d.DecodeError = err.Error()
+	}
+	return d
+}
+
+func (d *odxDescription) info() ODXInfo {
+	info := ODXInfo{
+		Path:          d.path,
+		Layers:        append([]string{}, d.db.Layers...),
+		DIDs:          len(d.db.DIDs),
+		FlashSessions: []ODXFlashSession{},
+	}
+	for _, s := range d.db.Flash {
+		fs := ODXFlashSession{Name: s.Name, DataBlocks: []ODXDataBlock{}}
+		for _, b := range s.DataBlocks {
+			db := ODXDataBlock{Name: b.Name, Segments: []ODXSegment{}}
+			for _, seg := range b.Segments {
+				db.Segments = append(db.Segments, ODXSegment(seg))
+			}
+			fs.DataBlocks = append(fs.DataBlocks, db)
+		}
+		info.FlashSessions = append(info.FlashSessions, fs)
+	}
+	return info
+}
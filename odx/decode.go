@@ -0,0 +1,222 @@
+package odx
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Value is a decoded parameter of a DID.
+type Value struct {
+	Name string
+	// Value is the physical value of the numeric parameters, Text the value of
+	// the strings and byte fields (in hex) and the text of the text tables.
+	Value   float64
+	Numeric bool
+	Text    string
+	Unit    string
+}
+
+// Decode decodes the record of d read with ReadDataByIdentifier, the data
+// after the DID.
+func (d *DID) Decode(data []byte) ([]Value, error) {
+	values := make([]Value, 0, len(d.Params))
+	for i := range d.Params {
+		v, err := d.Params[i].decode(data)
+		if err != nil {
+			return values, fmt.Errorf("%s: %w", d.Params[i].Name, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (p *Param) decode(data []byte) (Value, error) {
+	v := Value{Name: p.Name, Unit: p.Unit}
+	if p.Offset < 0 || p.Offset > len(data) {
+		return v, fmt.Errorf("offset %d beyond the %d bytes of the record", p.Offset, len(data))
+	}
+	field := data[p.Offset:]
+	if p.BitLength > 0 {
+		n := (p.BitOffset + p.BitLength + 7) / 8
+		if n > len(field) {
+			return v, fmt.Errorf("%d bits at offset %d beyond the %d bytes of the record", p.BitLength, p.Offset, len(data))
+		}
+		field = field[:n]
+	}
+
+	switch p.Type {
+	case "A_ASCIISTRING", "A_UTF8STRING":
+		v.Text = strings.TrimRight(string(field), "\x00")
+		return v, nil
+	case "A_UNICODE2STRING":
+		u := make([]uint16, len(field)/2)
+		for i := range u {
+			u[i] = uint16(field[2*i])<<8 | uint16(field[2*i+1])
+		}
+		v.Text = strings.TrimRight(string(utf16.Decode(u)), "\x00")
+		return v, nil
+	case "A_BYTEFIELD":
+		v.Text = strings.ToUpper(hex.EncodeToString(field))
+		return v, nil
+	}
+
+	if p.BitLength == 0 || p.BitLength > 64 {
+		return v, fmt.Errorf("unsupported %d bit %s", p.BitLength, p.Type)
+	}
+	// BIT-POSITION counts from the least significant bit of the field
+	raw := bitsFromLSB(field, p.BitOffset, p.BitLength)
+
+	var x float64
+	switch p.Type {
+	case "A_INT32":
+		x = float64(int64(raw<<(64-p.BitLength)) >> (64 - p.BitLength))
+	case "A_FLOAT32":
+		x = float64(math.Float32frombits(uint32(raw)))
+	case "A_FLOAT64":
+		x = math.Float64frombits(raw)
+	default:
+		x = float64(raw)
+	}
+	v.Value, v.Numeric = x, true
+	if text, ok := p.compu.text(x); ok {
+		v.Text, v.Numeric = text, false
+	} else {
+		v.Value = p.compu.phys(x)
+	}
+	return v, nil
+}
+
+// bitsFromLSB extracts the n bits of the big-endian field starting bit bits
+// above its least significant bit.
+func bitsFromLSB(field []byte, bit, n int) uint64 {
+	var raw uint64
+	for _, b := range field {
+		raw = raw<<8 | uint64(b)
+	}
+	raw >>= bit
+	if n < 64 {
+		raw &= 1<<n - 1
+	}
+	return raw
+}
+
+// compu converts coded values to physical ones.
+type compu struct {
+	category string
+	scales   []scale
+}
+
+type scale struct {
+	lower, upper         float64
+	hasLower, hasUpper   bool
+	openLower, openUpper bool
+	num, den             []float64
+	text                 string
+	hasConst             bool
+	constant             float64
+}
+
+func newCompu(m *compuMethod) compu {
+	c := compu{category: m.Category}
+	for _, s := range m.Scales {
+		sc := scale{num: s.Num, den: s.Den, text: s.Text}
+		if s.Lower != nil {
+			sc.lower, sc.hasLower = parseLimit(s.Lower.Value)
+			sc.openLower = s.Lower.Interval == "OPEN"
+			sc.hasLower = sc.hasLower && s.Lower.Interval != "INFINITE"
+		}
+		if s.Upper != nil {
+			sc.upper, sc.hasUpper = parseLimit(s.Upper.Value)
+			sc.openUpper = s.Upper.Interval == "OPEN"
+			sc.hasUpper = sc.hasUpper && s.Upper.Interval != "INFINITE"
+		}
+		if s.Const != nil {
+			sc.constant, sc.hasConst = *s.Const, true
+		}
+		c.scales = append(c.scales, sc)
+	}
+	return c
+}
+
+func parseLimit(s string) (float64, bool) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return v, err == nil
+}
+
+func (s *scale) contains(x float64) bool {
+	switch {
+	case s.hasLower && (x < s.lower || s.openLower && x == s.lower):
+		return false
+	case s.hasUpper && (x > s.upper || s.openUpper && x == s.upper):
+		return false
+	case !s.hasUpper && s.hasLower && len(s.num) == 0 && s.text != "":
+		// a text table entry without upper limit is a single value
+		return x == s.lower
+	}
+	return true
+}
+
+// scale returns the scale of x, the first one for the methods of one scale.
+func (c *compu) scale(x float64) *scale {
+	switch c.category {
+	case "LINEAR", "RAT-FUNC":
+		if len(c.scales) > 0 {
+			return &c.scales[0]
+		}
+		return nil
+	}
+	for i := range c.scales {
+		if c.scales[i].contains(x) {
+			return &c.scales[i]
+		}
+	}
+	return nil
+}
+
+// text returns the text of x for the text tables.
+func (c *compu) text(x float64) (string, bool) {
+	if c.category != "TEXTTABLE" {
+		return "", false
+	}
+	if s := c.scale(x); s != nil {
+		return s.text, true
+	}
+	return "", false
+}
+
+// phys returns the physical value of x, x itself for the identical and the
+// unsupported methods.
+func (c *compu) phys(x float64) float64 {
+	switch c.category {
+	case "LINEAR", "SCALE-LINEAR", "RAT-FUNC", "SCALE-RAT-FUNC":
+	default:
+		return x
+	}
+	s := c.scale(x)
+	switch {
+	case s == nil:
+		return x
+	case s.hasConst && len(s.num) == 0:
+		return s.constant
+	case len(s.num) == 0:
+		return x
+	}
+	den := 1.0
+	if len(s.den) > 0 {
+		den = poly(s.den, x)
+	}
+	return poly(s.num, x) / den
+}
+
+// poly evaluates the polynomial of coefficients k, lowest degree first.
+func poly(k []float64, x float64) float64 {
+	v := 0.0
+	for i := len(k) - 1; i >= 0; i-- {
+		v = v*x + k[i]
+	}
+	return v
+}
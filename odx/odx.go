@@ -0,0 +1,494 @@
+// Package odx reads the data identifiers of ASAM MCD-2D (ODX) diagnostic
+// descriptions: ODX-D files, PDX packages of ODX files and, for their flash
+// sessions, ODX-F files.
+//
+// The ReadDataByIdentifier services of the diagnostic layers give the DIDs,
+// the parameters of their positive responses and the data object properties
+// of the parameters give how the values are coded and converted to physical
+// values (identical, linear, scale-linear, rational and text table compu
+// methods). References are resolved across all the files loaded together;
+// the services of the ECU variants override those of their base variants.
+package odx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// readDataByIdentifier is the SID of the services describing DIDs.
+const readDataByIdentifier = 0x22
+
+// Database is the content of the ODX files loaded with Load.
+type Database struct {
+	// Layers are the short names of the diagnostic layers.
+	Layers []string
+	DIDs   map[uint16]*DID
+	Flash  []FlashSession
+}
+
+// DID is a data identifier read with a ReadDataByIdentifier service.
+type DID struct {
+	ID uint16
+	// Name is the short name of the service, Description its long name.
+	Name        string
+	Description string
+	// Layer is the diagnostic layer of the service.
+	Layer  string
+	Params []Param
+}
+
+// Param is a value of the record of a DID.
+type Param struct {
+	Name string
+	// Offset and BitOffset locate the value in the record after the DID.
+	Offset    int
+	BitOffset int
+	// Type is the base data type of the coded value, eg "A_UINT32", and
+	// BitLength its size, 0 for values up to the end of the record.
+	Type      string
+	BitLength int
+	// Unit is the display name of the physical unit.
+	Unit  string
+	compu compu
+}
+
+// FlashSession is a session of an ODX-F file.
+type FlashSession struct {
+	Name       string
+	DataBlocks []DataBlock
+}
+
+// DataBlock is a data block of a flash session.
+type DataBlock struct {
+	Name     string
+	Segments []Segment
+}
+
+// Segment is a memory range of a data block.
+type Segment struct {
+	Name    string
+	Address uint64
+	Size    uint64
+}
+
+// Load reads an ODX file (.odx, .odx-d, .odx-f ...) or a PDX package, a zip
+// archive of ODX files.
+func Load(path string) (*Database, error) {
+	var docs [][]byte
+	if strings.EqualFold(filepath.Ext(path), ".pdx") {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if !strings.HasPrefix(strings.ToLower(filepath.Ext(f.Name)), ".odx") {
+				continue
+			}
+			doc, err := readZipFile(f)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name, err)
+			}
+			docs = append(docs, doc)
+		}
+		if len(docs) == 0 {
+			return nil, fmt.Errorf("%s: no ODX file in the package", path)
+		}
+	} else {
+		doc, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return Parse(docs...)
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// Parse reads ODX documents, all references between them are resolved.
+func Parse(docs ...[]byte) (*Database, error) {
+	var files []odxFile
+	for i, doc := range docs {
+		var f odxFile
+		if err := xml.NewDecoder(bytes.NewReader(doc)).Decode(&f); err != nil {
+			return nil, fmt.Errorf("odx: document %d: %w", i+1, err)
+		}
+		files = append(files, f)
+	}
+
+	p := parser{
+		messages:   make(map[string]*message),
+		dops:       make(map[string]*dop),
+		structures: make(map[string]*structure),
+		units:      make(map[string]*unit),
+		blocks:     make(map[string]*dataBlock),
+	}
+	var layers []*layer
+	for i := range files {
+		c := files[i].Container
+		if c == nil {
+			continue
+		}
+		// in inheritance order, so the later services override
+		for _, group := range [][]layer{c.ECUSharedData, c.Protocols, c.FunctionalGroups, c.BaseVariants, c.ECUVariants} {
+			for j := range group {
+				layers = append(layers, &group[j])
+			}
+		}
+	}
+	for _, l := range layers {
+		p.index(l)
+	}
+	for i := range files {
+		if fl := files[i].Flash; fl != nil {
+			for j := range fl.ECUMems {
+				blocks := fl.ECUMems[j].DataBlocks
+				for k := range blocks {
+					p.blocks[blocks[k].ID] = &blocks[k]
+				}
+			}
+		}
+	}
+
+	db := &Database{DIDs: make(map[uint16]*DID)}
+	for _, l := range layers {
+		db.Layers = append(db.Layers, l.ShortName)
+		for _, s := range l.Services {
+			if d, ok := p.did(l, &s); ok {
+				db.DIDs[d.ID] = d
+			}
+		}
+	}
+	for i := range files {
+		if fl := files[i].Flash; fl != nil {
+			db.Flash = append(db.Flash, p.flashSessions(fl)...)
+		}
+	}
+	return db, nil
+}
+
+// DID returns the description of a data identifier, nil if it is unknown.
+func (db *Database) DID(id uint16) *DID {
+	if db == nil {
+		return nil
+	}
+	return db.DIDs[id]
+}
+
+// SortedDIDs returns the DIDs ordered by identifier.
+func (db *Database) SortedDIDs() []*DID {
+	if db == nil {
+		return nil
+	}
+	dids := make([]*DID, 0, len(db.DIDs))
+	for _, d := range db.DIDs {
+		dids = append(dids, d)
+	}
+	sort.Slice(dids, func(i, j int) bool { return dids[i].ID < dids[j].ID })
+	return dids
+}
+
+type parser struct {
+	messages   map[string]*message
+	dops       map[string]*dop
+	structures map[string]*structure
+	units      map[string]*unit
+	blocks     map[string]*dataBlock
+}
+
+func (p *parser) index(l *layer) {
+	for _, msgs := range [][]message{l.Requests, l.PosResponses} {
+		for i := range msgs {
+			p.messages[msgs[i].ID] = &msgs[i]
+		}
+	}
+	d := &l.Dictionary
+	for i := range d.DOPs {
+		p.dops[d.DOPs[i].ID] = &d.DOPs[i]
+	}
+	for i := range d.Structures {
+		p.structures[d.Structures[i].ID] = &d.Structures[i]
+	}
+	for i := range d.Units {
+		p.units[d.Units[i].ID] = &d.Units[i]
+	}
+}
+
+// did returns the DID read by s, if it is a ReadDataByIdentifier service.
+func (p *parser) did(l *layer, s *service) (*DID, bool) {
+	req := p.messages[s.Request.IDRef]
+	if req == nil || len(s.PosResponses) == 0 {
+		return nil, false
+	}
+	var sid, id uint64
+	var haveSID, haveID bool
+	for _, prm := range req.Params {
+		if prm.Type != "CODED-CONST" || prm.BytePosition == nil {
+			continue
+		}
+		v, err := parseUint(prm.CodedValue)
+		if err != nil {
+			continue
+		}
+		switch *prm.BytePosition {
+		case 0:
+			sid, haveSID = v, true
+		case 1:
+			id, haveID = v, prm.Coded == nil || prm.Coded.BitLength == 16
+		}
+	}
+	if !haveSID || sid != readDataByIdentifier || !haveID || id > 0xffff {
+		return nil, false
+	}
+	resp := p.messages[s.PosResponses[0].IDRef]
+	if resp == nil {
+		return nil, false
+	}
+	d := &DID{ID: uint16(id), Name: s.ShortName, Description: s.LongName, Layer: l.ShortName}
+	// the record follows the SID and the DID
+	d.Params = p.params(resp.Params, "", -3, 3)
+	return d, true
+}
+
+// params flattens the VALUE parameters of a message or a structure at base,
+// the structures they reference included; pos is where a parameter without
+// BYTE-POSITION starts.
+func (p *parser) params(params []param, prefix string, base, pos int) []Param {
+	var out []Param
+	for _, prm := range params {
+		if prm.BytePosition != nil {
+			pos = *prm.BytePosition
+		}
+		size := 0
+		switch prm.Type {
+		case "VALUE":
+			name := prefix + prm.ShortName
+			if prm.DOPRef == nil {
+				continue
+			}
+			if st := p.structures[prm.DOPRef.IDRef]; st != nil {
+				sub := p.params(st.Params, name+".", base+pos, 0)
+				out = append(out, sub...)
+				if st.ByteSize != nil {
+					size = *st.ByteSize
+				}
+				break
+			}
+			dp := p.dops[prm.DOPRef.IDRef]
+			if dp == nil {
+				continue
+			}
+			v := Param{
+				Name:      name,
+				Offset:    base + pos,
+				BitOffset: prm.BitPosition,
+				Type:      dp.Coded.BaseDataType,
+				BitLength: dp.Coded.BitLength,
+				compu:     newCompu(&dp.Compu),
+			}
+			if dp.Coded.Type == "MIN-MAX-LENGTH-TYPE" {
+				v.BitLength = 0
+			}
+			if dp.UnitRef != nil {
+				if u := p.units[dp.UnitRef.IDRef]; u != nil {
+					v.Unit = u.DisplayName
+				}
+			}
+			out = append(out, v)
+			size = (v.BitOffset + v.BitLength + 7) / 8
+		case "CODED-CONST", "RESERVED", "MATCHING-REQUEST-PARAM":
+			if prm.Coded != nil {
+				size = (prm.BitPosition + prm.Coded.BitLength + 7) / 8
+			}
+			if prm.ByteLength != 0 {
+				size = prm.ByteLength
+			}
+		}
+		pos += size
+	}
+	return out
+}
+
+func (p *parser) flashSessions(fl *flash) []FlashSession {
+	var out []FlashSession
+	for _, mem := range fl.ECUMems {
+		for _, s := range mem.Sessions {
+			fs := FlashSession{Name: s.ShortName}
+			for _, ref := range s.DataBlockRefs {
+				b := p.blocks[ref.IDRef]
+				if b == nil {
+					continue
+				}
+				db := DataBlock{Name: b.ShortName}
+				for _, seg := range b.Segments {
+					addr, _ := strconv.ParseUint(strings.TrimSpace(seg.Start), 16, 64)
+					size := seg.Size
+					if size == 0 && seg.End != "" {
+						if end, err := strconv.ParseUint(strings.TrimSpace(seg.End), 16, 64); err == nil && end >= addr {
+							size = end - addr + 1
+						}
+					}
+					db.Segments = append(db.Segments, Segment{Name: seg.ShortName, Address: addr, Size: size})
+				}
+				fs.DataBlocks = append(fs.DataBlocks, db)
+			}
+			out = append(out, fs)
+		}
+	}
+	return out
+}
+
+// parseUint parses a coded value, decimal as in ODX or hex with 0x.
+func parseUint(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	if h, ok := strings.CutPrefix(strings.ToLower(s), "0x"); ok {
+		return strconv.ParseUint(h, 16, 64)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// The XML model, limited to what the DIDs and the flash sessions need.
+
+type odxFile struct {
+	Container *layerContainer `xml:"DIAG-LAYER-CONTAINER"`
+	Flash     *flash          `xml:"FLASH"`
+}
+
+type layerContainer struct {
+	ShortName        string  `xml:"SHORT-NAME"`
+	Protocols        []layer `xml:"PROTOCOLS>PROTOCOL"`
+	FunctionalGroups []layer `xml:"FUNCTIONAL-GROUPS>FUNCTIONAL-GROUP"`
+	BaseVariants     []layer `xml:"BASE-VARIANTS>BASE-VARIANT"`
+	ECUVariants      []layer `xml:"ECU-VARIANTS>ECU-VARIANT"`
+	ECUSharedData    []layer `xml:"ECU-SHARED-DATAS>ECU-SHARED-DATA"`
+}
+
+type layer struct {
+	ID           string     `xml:"ID,attr"`
+	ShortName    string     `xml:"SHORT-NAME"`
+	Services     []service  `xml:"DIAG-COMMS>DIAG-SERVICE"`
+	Requests     []message  `xml:"REQUESTS>REQUEST"`
+	PosResponses []message  `xml:"POS-RESPONSES>POS-RESPONSE"`
+	Dictionary   dictionary `xml:"DIAG-DATA-DICTIONARY-SPEC"`
+}
+
+type ref struct {
+	IDRef string `xml:"ID-REF,attr"`
+}
+
+type service struct {
+	ShortName    string `xml:"SHORT-NAME"`
+	LongName     string `xml:"LONG-NAME"`
+	Request      ref    `xml:"REQUEST-REF"`
+	PosResponses []ref  `xml:"POS-RESPONSE-REFS>POS-RESPONSE-REF"`
+}
+
+type message struct {
+	ID     string  `xml:"ID,attr"`
+	Params []param `xml:"PARAMS>PARAM"`
+}
+
+type param struct {
+	// Type is the xsi:type, eg "CODED-CONST" or "VALUE".
+	Type         string     `xml:"type,attr"`
+	ShortName    string     `xml:"SHORT-NAME"`
+	BytePosition *int       `xml:"BYTE-POSITION"`
+	BitPosition  int        `xml:"BIT-POSITION"`
+	CodedValue   string     `xml:"CODED-VALUE"`
+	Coded        *codedType `xml:"DIAG-CODED-TYPE"`
+	DOPRef       *ref       `xml:"DOP-REF"`
+	// ByteLength is the length of a MATCHING-REQUEST-PARAM.
+	ByteLength int `xml:"BYTE-LENGTH"`
+}
+
+type codedType struct {
+	Type         string `xml:"type,attr"`
+	BaseDataType string `xml:"BASE-DATA-TYPE,attr"`
+	BitLength    int    `xml:"BIT-LENGTH"`
+}
+
+type dictionary struct {
+	DOPs       []dop       `xml:"DATA-OBJECT-PROPS>DATA-OBJECT-PROP"`
+	Structures []structure `xml:"STRUCTURES>STRUCTURE"`
+	Units      []unit      `xml:"UNIT-SPEC>UNITS>UNIT"`
+}
+
+type dop struct {
+	ID      string      `xml:"ID,attr"`
+	Compu   compuMethod `xml:"COMPU-METHOD"`
+	Coded   codedType   `xml:"DIAG-CODED-TYPE"`
+	UnitRef *ref        `xml:"UNIT-REF"`
+}
+
+type compuMethod struct {
+	Category string       `xml:"CATEGORY"`
+	Scales   []compuScale `xml:"COMPU-INTERNAL-TO-PHYS>COMPU-SCALES>COMPU-SCALE"`
+}
+
+type compuScale struct {
+	Lower *limit    `xml:"LOWER-LIMIT"`
+	Upper *limit    `xml:"UPPER-LIMIT"`
+	Text  string    `xml:"COMPU-CONST>VT"`
+	Const *float64  `xml:"COMPU-CONST>V"`
+	Num   []float64 `xml:"COMPU-RATIONAL-COEFFS>COMPU-NUMERATOR>V"`
+	Den   []float64 `xml:"COMPU-RATIONAL-COEFFS>COMPU-DENOMINATOR>V"`
+}
+
+type limit struct {
+	Value    string `xml:",chardata"`
+	Interval string `xml:"INTERVAL-TYPE,attr"`
+}
+
+type structure struct {
+	ID       string  `xml:"ID,attr"`
+	ByteSize *int    `xml:"BYTE-SIZE"`
+	Params   []param `xml:"PARAMS>PARAM"`
+}
+
+type unit struct {
+	ID          string `xml:"ID,attr"`
+	DisplayName string `xml:"DISPLAY-NAME"`
+}
+
+type flash struct {
+	ECUMems []ecuMem `xml:"ECU-MEMS>ECU-MEM"`
+}
+
+type ecuMem struct {
+	Sessions   []flashSession `xml:"MEM>SESSIONS>SESSION"`
+	DataBlocks []dataBlock    `xml:"MEM>DATABLOCKS>DATABLOCK"`
+}
+
+type flashSession struct {
+	ShortName     string `xml:"SHORT-NAME"`
+	DataBlockRefs []ref  `xml:"DATABLOCK-REFS>DATABLOCK-REF"`
+}
+
+type dataBlock struct {
+	ID        string    `xml:"ID,attr"`
+	ShortName string    `xml:"SHORT-NAME"`
+	Segments  []segment `xml:"SEGMENTS>SEGMENT"`
+}
+
+type segment struct {
+	ShortName string `xml:"SHORT-NAME"`
+	Start     string `xml:"SOURCE-START-ADDRESS"`
+	End       string `xml:"SOURCE-END-ADDRESS"`
+	Size      uint64 `xml:"UNCOMPRESSED-SIZE"`
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"canproject/canbus"
+	"canproject/overview"
+)
+
+const defaultOverviewInterval = 200 * time.Millisecond
+
+// OverviewOptions configures the per-ID overview set with SetOverview.
+type OverviewOptions struct {
+	// Enabled aggregates received frames by ID and emits the changes on "can:overview".
+	Enabled bool `json:"enabled"`
+	// IntervalMs is the period of the "can:overview" events, 0 for 200 ms.
+	IntervalMs int `json:"intervalMs"`
+}
+
+// OverviewEntry is the state of a CAN ID in the overview.
+type OverviewEntry struct {
+	Interface string    `json:"interface"`
+	ID        uint32    `json:"id"`
+	Extended  bool      `json:"extended"`
+	FD        bool      `json:"fd"`
+	Remote    bool      `json:"remote"`
+	Data      []uint32  `json:"data"`
+	Count     uint64    `json:"count"`
+	Last      time.Time `json:"last"`
+	// PeriodMs is the time between the last two frames in milliseconds.
+	PeriodMs float64 `json:"periodMs"`
+	// Changed has bit n%32 of word n/32 set when byte n changed since the previous
+	// event, so changed bytes can be highlighted. It is empty in GetOverview.
+	Changed []uint32 `json:"changed"`
+}
+
+// OverviewEvent is emitted on "can:overview" with the IDs received since the previous event.
+type OverviewEvent struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Entries   []OverviewEntry `json:"entries"`
+}
+
+// idOverview is the tracker and emit loop of an enabled overview.
+type idOverview struct {
+	opts    OverviewOptions
+	tracker *overview.Tracker
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// SetOverview enables or disables the overview mode. When enabled the latest payload,
+// count and period of every received ID are kept in the backend and only the IDs that
+// were received since the previous event are emitted on "can:overview", which keeps
+// the frontend responsive on dense buses. Enabling it again starts from scratch.
+func (a *App) SetOverview(opts OverviewOptions) error {
+	if opts.IntervalMs < 0 {
+		return fmt.Errorf("overview interval must be >= 0")
+	}
+	if opts.IntervalMs == 0 {
+		opts.IntervalMs = int(defaultOverviewInterval / time.Millisecond)
+	}
+
+	a.overviewMu.Lock()
+	defer a.overviewMu.Unlock()
+
+	var o *idOverview
+	if opts.Enabled {
+		o = &idOverview{
+			opts:    opts,
+			tracker: overview.NewTracker(),
+			stop:    make(chan struct{}),
+			done:    make(chan struct{}),
+		}
+		go a.overviewLoop(o)
+	}
+	if old := a.overview.Swap(o); old != nil {
+		close(old.stop)
+		<-old.done
+	}
+	return nil
+}
+
+// GetOverviewOptions returns the overview options, Enabled is false when the overview is off.
+func (a *App) GetOverviewOptions() OverviewOptions {
+	if o := a.overview.Load(); o != nil {
+		return o.opts
+	}
+	return OverviewOptions{}
+}
+
+// GetOverview returns all the IDs of the overview, eg to fill the table when it is
+// shown. It fails when the overview is off.
+func (a *App) GetOverview() ([]OverviewEntry, error) {
+	o := a.overview.Load()
+	if o == nil {
+		return nil, fmt.Errorf("overview is not enabled")
+	}
+	return overviewEntries(o.tracker.Snapshot(), false), nil
+}
+
+// ResetOverview drops the IDs of the overview.
+func (a *App) ResetOverview() {
+	if o := a.overview.Load(); o != nil {
+		o.tracker.Reset()
+	}
+}
+
+// trackOverview adds a received frame to the overview, if enabled.
+func (a *App) trackOverview(iface string, ts time.Time, f *canbus.Frame) {
+	if o := a.overview.Load(); o != nil {
+		o.tracker.Add(iface, ts, f)
+	}
+}
+
+func (a *App) overviewLoop(o *idOverview) {
+	defer close(o.done)
+
+	ticker := time.NewTicker(time.Duration(o.opts.IntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-o.stop:
+			return
+		case now := <-ticker.C:
+			if entries := o.tracker.Updates(); len(entries) > 0 {
+				a.emit("can:overview", OverviewEvent{Timestamp: now, Entries: overviewEntries(entries, true)})
+			}
+		}
+	}
+}
+
+func overviewEntries(entries []overview.Entry, changes bool) []OverviewEntry {
+	out := make([]OverviewEntry, len(entries))
+	for i, e := range entries {
+		out[i] = OverviewEntry{
+			Interface: e.Interface,
+			ID:        e.ID,
+			Extended:  e.Extended,
+			FD:        e.FD,
+			Remote:    e.Remote,
+			Data:      dataWords(e.Data),
+			Count:     e.Count,
+			Last:      e.Last,
+			PeriodMs:  milliseconds(e.Period),
+			Changed:   []uint32{},
+		}
+		if changes {
+			out[i].Changed = e.Changed[:(len(e.Data)+31)/32]
+		}
+	}
+	return out
+}
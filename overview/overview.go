@@ -0,0 +1,147 @@
+// Package overview aggregates received frames by CAN ID, like cansniffer or the
+// SavvyCAN overview: the latest payload, the frame count and the period of every
+// ID, with the bytes that changed since the previous update.
+package overview
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+)
+
+// Entry is the state of one CAN ID of an interface.
+type Entry struct {
+	Interface string
+	ID        uint32
+	Extended  bool
+	FD        bool
+	Remote    bool
+	Data      []byte
+	Count     uint64
+	// Last is the time of the latest frame.
+	Last time.Time
+	// Period is the time between the last two frames, zero after the first one.
+	Period time.Duration
+	// Changed has bit n set when byte n differs from the previous update or did not
+	// exist then.
+	Changed [2]uint32
+}
+
+type key struct {
+	iface    string
+	id       uint32
+	extended bool
+}
+
+type state struct {
+	Entry
+	// sent is the payload of the previous update.
+	sent  []byte
+	dirty bool
+	// new is true until the entry was part of an update.
+	new bool
+}
+
+// Tracker aggregates frames by ID. It is safe for concurrent use.
+type Tracker struct {
+	mu      sync.Mutex
+	entries map[key]*state
+}
+
+// NewTracker returns an empty tracker.
+func NewTracker() *Tracker {
+	return &Tracker{entries: make(map[key]*state)}
+}
+
+// Add records a frame received on iface at ts. Error frames are ignored.
+func (t *Tracker) Add(iface string, ts time.Time, f *canbus.Frame) {
+	if f.IsError {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := key{iface, f.ID, f.IsExtended}
+	s := t.entries[k]
+	if s == nil {
+		s = &state{Entry: Entry{Interface: iface, ID: f.ID, Extended: f.IsExtended}, new: true}
+		t.entries[k] = s
+	} else {
+		s.Period = ts.Sub(s.Last)
+	}
+	s.FD, s.Remote = f.IsFD, f.IsRemote
+	s.Data = append(s.Data[:0], f.Payload()...)
+	s.Count++
+	s.Last = ts
+	s.dirty = true
+}
+
+// Updates returns the entries that received frames since the previous call, sorted
+// by interface and ID, with Changed relative to the payload of the previous update.
+func (t *Tracker) Updates() []Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out []Entry
+	for _, s := range t.entries {
+		if !s.dirty {
+			continue
+		}
+		e := s.Entry
+		e.Data = append([]byte(nil), s.Data...)
+		e.Changed = changed(s.sent, s.Data, s.new)
+		out = append(out, e)
+		s.sent = append(s.sent[:0], s.Data...)
+		s.dirty, s.new = false, false
+	}
+	sortEntries(out)
+	return out
+}
+
+// Snapshot returns all the entries without marking them as sent, Changed is unset.
+func (t *Tracker) Snapshot() []Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make([]Entry, 0, len(t.entries))
+	for _, s := range t.entries {
+		e := s.Entry
+		e.Data = append([]byte(nil), s.Data...)
+		e.Changed = [2]uint32{}
+		out = append(out, e)
+	}
+	sortEntries(out)
+	return out
+}
+
+// Reset drops all the entries.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	clear(t.entries)
+}
+
+func changed(prev, data []byte, all bool) [2]uint32 {
+	var mask [2]uint32
+	for i, b := range data {
+		if all || i >= len(prev) || prev[i] != b {
+			mask[i/32] |= 1 << (i % 32)
+		}
+	}
+	return mask
+}
+
+func sortEntries(entries []Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := &entries[i], &entries[j]
+		if a.Interface != b.Interface {
+			return a.Interface < b.Interface
+		}
+		if a.Extended != b.Extended {
+			return !a.Extended
+		}
+		return a.ID < b.ID
+	})
+}
@@ -0,0 +1,370 @@
+// Package parquet writes Apache Parquet files with a flat schema of required
+// or optional columns, for the tools of data analysis (pandas, DuckDB,
+// Spark ...). Values are PLAIN encoded, one gzip compressed data page per
+// column chunk, in row groups of RowGroupSize rows.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// RowGroupSize is the number of rows buffered before a row group is written.
+const RowGroupSize = 128 * 1024
+
+const magic = "PAR1"
+
+// Type is the type of a column.
+type Type int
+
+// The column types and the Go values of Writer.Write.
+const (
+	// Boolean columns take bool values.
+	Boolean Type = iota
+	// Int32 columns take int32 values.
+	Int32
+	// Int64 columns take int64 values.
+	Int64
+	// Double columns take float64 values.
+	Double
+	// String columns take UTF-8 string values.
+	String
+	// Timestamp columns take time.Time values, stored in microseconds since the
+	// epoch in UTC.
+	Timestamp
+)
+
+// physical types, converted types and enums of the Parquet format.
+const (
+	physBoolean   = 0
+	physInt32     = 1
+	physInt64     = 2
+	physDouble    = 5
+	physByteArray = 6
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecGzip = 2
+
+	pageData = 0
+)
+
+// Column describes a column of a file.
+type Column struct {
+	Name string
+	Type Type
+	// Optional columns take nil values.
+	Optional bool
+}
+
+func (c *Column) physical() int32 {
+	switch c.Type {
+	case Boolean:
+		return physBoolean
+	case Int32:
+		return physInt32
+	case Int64, Timestamp:
+		return physInt64
+	case Double:
+		return physDouble
+	}
+	return physByteArray
+}
+
+// column buffers the values of a column in the current row group.
+type column struct {
+	Column
+	values []byte
+	bools  []bool
+	// defined are the definition levels of an optional column.
+	defined []bool
+}
+
+type chunkMeta struct {
+	offset             int64
+	uncompressed, size int64
+	values             int64
+}
+
+type rowGroupMeta struct {
+	chunks []chunkMeta
+	rows   int64
+	bytes  int64
+}
+
+// Writer writes a Parquet file.
+type Writer struct {
+	w       io.Writer
+	offset  int64
+	columns []column
+	rows    int
+	groups  []rowGroupMeta
+	total   int64
+	err     error
+}
+
+// NewWriter writes the header of a file with the columns cols to w.
+func NewWriter(w io.Writer, cols []Column) (*Writer, error) {
+	if len(cols) == 0 {
+		return nil, errors.New("parquet: no columns")
+	}
+	pw := &Writer{w: w}
+	names := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		if c.Name == "" || names[c.Name] {
+			return nil, fmt.Errorf("parquet: empty or duplicate column name %q", c.Name)
+		}
+		names[c.Name] = true
+		pw.columns = append(pw.columns, column{Column: c})
+	}
+	if err := pw.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// Write adds a row, one value per column of the type of the column, nil for
+// the missing values of optional columns.
+func (pw *Writer) Write(row ...any) error {
+	if pw.err != nil {
+		return pw.err
+	}
+	if len(row) != len(pw.columns) {
+		return fmt.Errorf("parquet: row of %d values for %d columns", len(row), len(pw.columns))
+	}
+	for i, v := range row {
+		if c := &pw.columns[i]; !c.valid(v) {
+			return fmt.Errorf("parquet: %T value for column %s", v, c.Name)
+		}
+	}
+	for i, v := range row {
+		pw.columns[i].add(v)
+	}
+	pw.rows++
+	if pw.rows >= RowGroupSize {
+		return pw.flush()
+	}
+	return nil
+}
+
+// Close writes the buffered rows and the footer. It does not close the
+// underlying writer.
+func (pw *Writer) Close() error {
+	if err := pw.flush(); err != nil {
+		return err
+	}
+	footer := pw.footer()
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(footer)))
+	return pw.write(append(footer, magic...))
+}
+
+// valid reports whether v is a value of the column.
+func (c *column) valid(v any) bool {
+	var ok bool
+	switch c.Type {
+	case Boolean:
+		_, ok = v.(bool)
+	case Int32:
+		_, ok = v.(int32)
+	case Int64:
+		_, ok = v.(int64)
+	case Double:
+		_, ok = v.(float64)
+	case String:
+		_, ok = v.(string)
+	case Timestamp:
+		_, ok = v.(time.Time)
+	}
+	return ok || (v == nil && c.Optional)
+}
+
+// add buffers a valid value.
+func (c *column) add(v any) {
+	if c.Optional {
+		c.defined = append(c.defined, v != nil)
+	}
+	switch v := v.(type) {
+	case bool:
+		c.bools = append(c.bools, v)
+	case int32:
+		c.values = binary.LittleEndian.AppendUint32(c.values, uint32(v))
+	case int64:
+		c.values = binary.LittleEndian.AppendUint64(c.values, uint64(v))
+	case float64:
+		c.values = appendDouble(c.values, v)
+	case string:
+		c.values = binary.LittleEndian.AppendUint32(c.values, uint32(len(v)))
+		c.values = append(c.values, v...)
+	case time.Time:
+		c.values = binary.LittleEndian.AppendUint64(c.values, uint64(v.UnixMicro()))
+	}
+}
+
+// flush writes the buffered rows as a row group.
+func (pw *Writer) flush() error {
+	if pw.err != nil || pw.rows == 0 {
+		return pw.err
+	}
+	g := rowGroupMeta{rows: int64(pw.rows)}
+	for i := range pw.columns {
+		c := &pw.columns[i]
+		page := c.page()
+		var z bytes.Buffer
+		zw := gzip.NewWriter(&z)
+		_, _ = zw.Write(page)
+		_ = zw.Close()
+
+		var h encoder
+		h.begin()
+		h.i32(1, pageData)
+		h.i32(2, int32(len(page)))
+		h.i32(3, int32(z.Len()))
+		h.structField(5)
+		h.i32(1, int32(pw.rows))
+		h.i32(2, encodingPlain)
+		h.i32(3, encodingRLE)
+		h.i32(4, encodingRLE)
+		h.end()
+		h.end()
+
+		chunk := chunkMeta{
+			offset:       pw.offset,
+			uncompressed: int64(len(h.buf) + len(page)),
+			size:         int64(len(h.buf) + z.Len()),
+			values:       int64(pw.rows),
+		}
+		if err := pw.write(h.buf); err != nil {
+			return err
+		}
+		if err := pw.write(z.Bytes()); err != nil {
+			return err
+		}
+		g.chunks = append(g.chunks, chunk)
+		g.bytes += chunk.uncompressed
+		c.values, c.bools, c.defined = c.values[:0], c.bools[:0], c.defined[:0]
+	}
+	pw.groups = append(pw.groups, g)
+	pw.total += int64(pw.rows)
+	pw.rows = 0
+	return nil
+}
+
+// page returns the data of the page of a column: the definition levels of an
+// optional column, prefixed with their length, then the values.
+func (c *column) page() []byte {
+	var page []byte
+	if c.Optional {
+		levels := bitPacked(c.defined)
+		page = binary.LittleEndian.AppendUint32(page, uint32(len(levels)))
+		page = append(page, levels...)
+	}
+	if c.Type == Boolean {
+		packed := make([]byte, (len(c.bools)+7)/8)
+		for i, b := range c.bools {
+			if b {
+				packed[i/8] |= 1 << (i % 8)
+			}
+		}
+		return append(page, packed...)
+	}
+	return append(page, c.values...)
+}
+
+// bitPacked encodes levels of bit width 1 as a single bit-packed run of the
+// RLE/bit-packing hybrid encoding.
+func bitPacked(levels []bool) []byte {
+	groups := (len(levels) + 7) / 8
+	b := binary.AppendUvarint(nil, uint64(groups)<<1|1)
+	packed := make([]byte, groups)
+	for i, l := range levels {
+		if l {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return append(b, packed...)
+}
+
+// footer encodes the FileMetaData.
+func (pw *Writer) footer() []byte {
+	var e encoder
+	e.begin()
+	e.i32(1, 1)
+	e.list(2, tStruct, len(pw.columns)+1)
+	e.begin()
+	e.string(4, "schema")
+	e.i32(5, int32(len(pw.columns)))
+	e.end()
+	for i := range pw.columns {
+		c := &pw.columns[i]
+		e.begin()
+		e.i32(1, c.physical())
+		rep := int32(repetitionRequired)
+		if c.Optional {
+			rep = repetitionOptional
+		}
+		e.i32(3, rep)
+		e.string(4, c.Name)
+		switch c.Type {
+		case String:
+			e.i32(6, convertedUTF8)
+		case Timestamp:
+			e.i32(6, convertedTimestampMicros)
+		}
+		e.end()
+	}
+	e.i64(3, pw.total)
+	e.list(4, tStruct, len(pw.groups))
+	for _, g := range pw.groups {
+		e.begin()
+		e.list(1, tStruct, len(g.chunks))
+		for i, ch := range g.chunks {
+			c := &pw.columns[i]
+			e.begin()
+			e.i64(2, ch.offset)
+			e.structField(3)
+			e.i32(1, c.physical())
+			e.list(2, tI32, 2)
+			e.elemI32(encodingPlain)
+			e.elemI32(encodingRLE)
+			e.list(3, tBinary, 1)
+			e.elemString(c.Name)
+			e.i32(4, codecGzip)
+			e.i64(5, ch.values)
+			e.i64(6, ch.uncompressed)
+			e.i64(7, ch.size)
+			e.i64(9, ch.offset)
+			e.end()
+			e.end()
+		}
+		e.i64(2, g.bytes)
+		e.i64(3, g.rows)
+		e.end()
+	}
+	e.string(6, "canproject")
+	e.end()
+	return e.buf
+}
+
+func (pw *Writer) write(b []byte) error {
+	if pw.err != nil {
+		return pw.err
+	}
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	if err != nil {
+		pw.err = err
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Thrift compact protocol types.
+const (
+	tI32    = 5
+	tI64    = 6
+	tBinary = 8
+	tList   = 9
+	tStruct = 12
+)
+
+// encoder writes Thrift compact protocol structs, the encoding of the Parquet
+// metadata. Fields are written in increasing id order within a struct.
+type encoder struct {
+	buf  []byte
+	last []int16
+}
+
+func (e *encoder) field(id int16, typ byte) {
+	last := e.last[len(e.last)-1]
+	if d := id - last; d > 0 && d <= 15 {
+		e.buf = append(e.buf, byte(d)<<4|typ)
+	} else {
+		e.buf = append(e.buf, typ)
+		e.varint(int64(id))
+	}
+	e.last[len(e.last)-1] = id
+}
+
+func (e *encoder) varint(v int64) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(v<<1^v>>63))
+}
+
+func (e *encoder) begin() { e.last = append(e.last, 0) }
+
+func (e *encoder) end() {
+	e.buf = append(e.buf, 0)
+	e.last = e.last[:len(e.last)-1]
+}
+
+func (e *encoder) i32(id int16, v int32) {
+	e.field(id, tI32)
+	e.varint(int64(v))
+}
+
+func (e *encoder) i64(id int16, v int64) {
+	e.field(id, tI64)
+	e.varint(v)
+}
+
+func (e *encoder) string(id int16, s string) {
+	e.field(id, tBinary)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// list starts a list field of n elements of type typ.
+func (e *encoder) list(id int16, typ byte, n int) {
+	e.field(id, tList)
+	if n < 15 {
+		e.buf = append(e.buf, byte(n)<<4|typ)
+	} else {
+		e.buf = append(e.buf, 0xf0|typ)
+		e.buf = binary.AppendUvarint(e.buf, uint64(n))
+	}
+}
+
+// elemI32 writes an i32 element of a list.
+func (e *encoder) elemI32(v int32) { e.varint(int64(v)) }
+
+// elemString writes a string element of a list.
+func (e *encoder) elemString(s string) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// structField starts a struct field, closed with end.
+func (e *encoder) structField(id int16) {
+	e.field(id, tStruct)
+	e.begin()
+}
+
+func appendDouble(b []byte, v float64) []byte {
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+}
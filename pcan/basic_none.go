@@ -0,0 +1,10 @@
+//go:build !pcan || !windows
+
+package pcan
+
+// Available reports whether the backend was built with PCAN-Basic support.
+const Available = false
+
+func loadAPI() (api, error) {
+	return nil, ErrNotAvailable
+}
@@ -0,0 +1,65 @@
+//go:build pcan && windows
+
+package pcan
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// Available reports whether the backend was built with PCAN-Basic support.
+const Available = true
+
+var (
+	basic            = syscall.NewLazyDLL("PCANBasic.dll")
+	procInitialize   = basic.NewProc("CAN_Initialize")
+	procUninitialize = basic.NewProc("CAN_Uninitialize")
+	procRead         = basic.NewProc("CAN_Read")
+	procWrite        = basic.NewProc("CAN_Write")
+	procErrorText    = basic.NewProc("CAN_GetErrorText")
+)
+
+// dllAPI calls PCANBasic.dll.
+type dllAPI struct{}
+
+func loadAPI() (api, error) {
+	if err := basic.Load(); err != nil {
+		return nil, fmt.Errorf("pcan: %w (is the PCAN-Basic driver installed?)", err)
+	}
+	return dllAPI{}, nil
+}
+
+func (dllAPI) initialize(channel, btr0btr1 uint16) uint32 {
+	// the hardware type, I/O port and interrupt are for the non plug and play adapters
+	r, _, _ := procInitialize.Call(uintptr(channel), uintptr(btr0btr1), 0, 0, 0)
+	return uint32(r)
+}
+
+func (dllAPI) uninitialize(channel uint16) uint32 {
+	r, _, _ := procUninitialize.Call(uintptr(channel))
+	return uint32(r)
+}
+
+func (dllAPI) read(channel uint16, msg *message, ts *timestamp) uint32 {
+	r, _, _ := procRead.Call(uintptr(channel), uintptr(unsafe.Pointer(msg)), uintptr(unsafe.Pointer(ts)))
+	return uint32(r)
+}
+
+func (dllAPI) write(channel uint16, msg *message) uint32 {
+	r, _, _ := procWrite.Call(uintptr(channel), uintptr(unsafe.Pointer(msg)))
+	return uint32(r)
+}
+
+func (dllAPI) errorText(status uint32) string {
+	var buf [256]byte
+	// language 0x09 is English
+	if r, _, _ := procErrorText.Call(uintptr(status), 0x09, uintptr(unsafe.Pointer(&buf[0]))); r != statusOK {
+		return fmt.Sprintf("error 0x%X", status)
+	}
+	n := 0
+	for n < len(buf) && buf[n] != 0 {
+		n++
+	}
+	return string(buf[:n])
+}
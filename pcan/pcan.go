@@ -0,0 +1,320 @@
+// Package pcan drives PEAK-System PCAN adapters through the PCAN-Basic driver
+// API, so they can be used on the Windows lab PCs where SocketCAN is not
+// available. On Linux the PCAN adapters have SocketCAN drivers (peak_usb,
+// peak_pci) and are used as can0, can1...
+//
+// PCANBasic.dll is loaded at run time, without cgo; the backend is compiled in
+// with the pcan build tag on Windows. Otherwise Dial reports that it is not
+// available.
+package pcan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"canproject/canbus"
+)
+
+const (
+	// DefaultBitrate is the CAN bitrate used when the URL does not set one.
+	DefaultBitrate = 500000
+
+	receiveBacklog = 1024
+	// pollInterval is the wait between two reads of an empty receive queue.
+	pollInterval = time.Millisecond
+)
+
+// PCAN-Basic message types.
+const (
+	msgStandard = 0x00
+	msgRTR      = 0x01
+	msgExtended = 0x02
+	msgError    = 0x40
+	msgStatus   = 0x80
+)
+
+// PCAN-Basic status codes.
+const (
+	statusOK         = 0x00000
+	statusBusLight   = 0x00004
+	statusBusHeavy   = 0x00008
+	statusBusOff     = 0x00010
+	statusQRcvEmpty  = 0x00020
+	statusBusPassive = 0x40000
+	// statusBusErrors are the bus states reported by reads, which do not stop them.
+	statusBusErrors = statusBusLight | statusBusHeavy | statusBusOff | statusBusPassive
+)
+
+// bitrates maps the CAN bitrates to the BTR0BTR1 codes of PCAN-Basic.
+var bitrates = map[int]uint16{
+	1000000: 0x0014, 800000: 0x0016, 500000: 0x001C, 250000: 0x011C, 125000: 0x031C,
+	100000: 0x432F, 95000: 0xC34E, 83000: 0x852B, 50000: 0x472F, 47000: 0x1414,
+	33000: 0x8B2F, 20000: 0x532F, 10000: 0x672F, 5000: 0x7F7F,
+}
+
+// ErrNotAvailable is returned by Dial when the backend is not compiled in.
+var ErrNotAvailable = errors.New("PCAN-Basic support is not compiled in (build for Windows with -tags pcan)")
+
+// Config describes how an adapter is opened.
+type Config struct {
+	// Channel is the PCAN-Basic channel handle, eg 0x51 for PCAN_USBBUS1.
+	Channel uint16
+	Bitrate int
+}
+
+// message is TPCANMsg.
+type message struct {
+	ID      uint32
+	MsgType uint8
+	Len     uint8
+	Data    [8]byte
+}
+
+// timestamp is TPCANTimestamp.
+type timestamp struct {
+	Millis         uint32
+	MillisOverflow uint16
+	Micros         uint16
+}
+
+// api is the part of PCAN-Basic the backend uses, returning TPCANStatus codes.
+type api interface {
+	initialize(channel, btr0btr1 uint16) uint32
+	uninitialize(channel uint16) uint32
+	read(channel uint16, msg *message, ts *timestamp) uint32
+	write(channel uint16, msg *message) uint32
+	errorText(status uint32) string
+}
+
+// IsURL reports whether name is a PCAN URL such as "pcan://usb1?bitrate=500000".
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "pcan://")
+}
+
+// ParseURL decodes "pcan://usb1", "pcan://pci2" or "pcan://lan1" (the bus and the
+// channel number from 1 to 16) with the optional query parameter bitrate.
+func ParseURL(rawURL string) (Config, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Config{}, err
+	}
+	invalid := fmt.Errorf("invalid PCAN URL %q, want pcan://usb1?bitrate=500000", rawURL)
+	if u.Scheme != "pcan" {
+		return Config{}, invalid
+	}
+	cfg := Config{Bitrate: DefaultBitrate}
+	if cfg.Channel, err = channelHandle(strings.ToLower(u.Host)); err != nil {
+		return Config{}, fmt.Errorf("%w: %v", invalid, err)
+	}
+	if v := u.Query().Get("bitrate"); v != "" {
+		if cfg.Bitrate, err = strconv.Atoi(v); err != nil {
+			return Config{}, fmt.Errorf("invalid bitrate in PCAN URL %q", rawURL)
+		}
+	}
+	return cfg, nil
+}
+
+// channelHandle returns the TPCANHandle of usb<n>, pci<n> or lan<n>.
+func channelHandle(name string) (uint16, error) {
+	for _, bus := range []struct {
+		prefix    string
+		low, high uint16
+	}{
+		// PCAN_USBBUS1-8 and 9-16, PCAN_PCIBUS1-8 and 9-16, PCAN_LANBUS1-16
+		{"usb", 0x51, 0x509},
+		{"pci", 0x41, 0x409},
+		{"lan", 0x801, 0x809},
+	} {
+		if !strings.HasPrefix(name, bus.prefix) {
+			continue
+		}
+		n, err := strconv.Atoi(name[len(bus.prefix):])
+		if err != nil || n < 1 || n > 16 {
+			return 0, fmt.Errorf("channel %q out of range 1-16", name)
+		}
+		if n <= 8 {
+			return bus.low + uint16(n-1), nil
+		}
+		return bus.high + uint16(n-9), nil
+	}
+	return 0, fmt.Errorf("unknown channel %q, want usb<n>, pci<n> or lan<n>", name)
+}
+
+// Conn is an open PCAN channel. It implements canbus.Bus.
+type Conn struct {
+	api     api
+	channel uint16
+
+	frames chan canbus.Frame
+	done   chan struct{}
+	once   sync.Once
+	closed atomic.Bool
+
+	mu      sync.Mutex
+	filters []canbus.Filter
+	readErr error
+	// apiMu serializes the calls into the driver.
+	apiMu sync.Mutex
+}
+
+var _ canbus.Bus = (*Conn)(nil)
+
+// Dial initializes the channel of cfg at its bitrate.
+func Dial(cfg Config) (*Conn, error) {
+	a, err := loadAPI()
+	if err != nil {
+		return nil, err
+	}
+	return open(a, cfg)
+}
+
+func open(a api, cfg Config) (*Conn, error) {
+	btr, ok := bitrates[cfg.Bitrate]
+	if !ok {
+		return nil, fmt.Errorf("pcan: unsupported bitrate %d", cfg.Bitrate)
+	}
+	if st := a.initialize(cfg.Channel, btr); st != statusOK {
+		return nil, fmt.Errorf("pcan: initialize channel 0x%X: %s", cfg.Channel, a.errorText(st))
+	}
+	c := &Conn{
+		api:     a,
+		channel: cfg.Channel,
+		frames:  make(chan canbus.Frame, receiveBacklog),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// readLoop polls the receive queue of the driver until the channel is closed.
+func (c *Conn) readLoop() {
+	defer close(c.done)
+
+	var msg message
+	var ts timestamp
+	for !c.closed.Load() {
+		c.apiMu.Lock()
+		st := c.api.read(c.channel, &msg, &ts)
+		c.apiMu.Unlock()
+		switch {
+		case st == statusQRcvEmpty:
+			time.Sleep(pollInterval)
+			continue
+		case st&^statusBusErrors != statusOK:
+			if !c.closed.Load() {
+				c.mu.Lock()
+				c.readErr = errors.New(c.api.errorText(st))
+				c.mu.Unlock()
+			}
+			return
+		case st != statusOK:
+			// a bus error state, the reads go on
+			continue
+		}
+		f, ok := c.decode(&msg)
+		if !ok {
+			continue
+		}
+		select {
+		case c.frames <- f:
+		default:
+			// the application does not keep up, drop the frame
+		}
+	}
+}
+
+func (c *Conn) decode(msg *message) (canbus.Frame, bool) {
+	if msg.MsgType&msgStatus != 0 {
+		return canbus.Frame{}, false
+	}
+	f := canbus.Frame{
+		ID:         msg.ID,
+		IsExtended: msg.MsgType&msgExtended != 0,
+		IsRemote:   msg.MsgType&msgRTR != 0,
+		IsError:    msg.MsgType&msgError != 0,
+		Length:     min(msg.Len, canbus.MaxDataLength),
+	}
+	copy(f.Data[:], msg.Data[:f.Length])
+	if f.IsError {
+		// the error frames of PCAN-Basic have no SocketCAN error class
+		return canbus.Frame{}, false
+	}
+	if f.Validate() != nil {
+		return canbus.Frame{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return f, canbus.MatchAny(c.filters, &f)
+}
+
+// ReadFrame blocks until the next frame is received.
+func (c *Conn) ReadFrame() (canbus.Frame, error) {
+	select {
+	case f := <-c.frames:
+		return f, nil
+	case <-c.done:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.readErr != nil && !c.closed.Load() {
+			return canbus.Frame{}, fmt.Errorf("pcan: %w", c.readErr)
+		}
+		return canbus.Frame{}, net.ErrClosed
+	}
+}
+
+// WriteFrame transmits a classic frame.
+func (c *Conn) WriteFrame(_ context.Context, f canbus.Frame) error {
+	if f.IsFD {
+		return errors.New("pcan: CAN FD frames are not supported")
+	}
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	if c.closed.Load() {
+		return net.ErrClosed
+	}
+	msg := message{ID: f.ID, MsgType: msgStandard, Len: f.Length}
+	if f.IsExtended {
+		msg.MsgType |= msgExtended
+	}
+	if f.IsRemote {
+		msg.MsgType |= msgRTR
+	}
+	copy(msg.Data[:], f.Payload())
+	c.apiMu.Lock()
+	st := c.api.write(c.channel, &msg)
+	c.apiMu.Unlock()
+	if st != statusOK {
+		return fmt.Errorf("pcan: write: %s", c.api.errorText(st))
+	}
+	return nil
+}
+
+// SetFilters filters the received frames on the host side.
+func (c *Conn) SetFilters(filters []canbus.Filter) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filters = append([]canbus.Filter{}, filters...)
+	return nil
+}
+
+// Close uninitializes the channel.
+func (c *Conn) Close() error {
+	var err error
+	c.once.Do(func() {
+		c.closed.Store(true)
+		<-c.done
+		if st := c.api.uninitialize(c.channel); st != statusOK {
+			err = fmt.Errorf("pcan: uninitialize: %s", c.api.errorText(st))
+		}
+	})
+	return err
+}
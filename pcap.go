@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"canproject/canlog"
+)
+
+// StartPcapCapture writes the frames of all started interfaces, received and sent,
+// including error frames, to a pcapng file that can be opened in Wireshark.
+func (a *App) StartPcapCapture(path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return errors.New("capture path is empty")
+	}
+
+	a.logMu.Lock()
+	defer a.logMu.Unlock()
+	if a.pcap != nil {
+		return fmt.Errorf("already capturing to %s", a.pcap.path)
+	}
+
+	l, err := newFrameLogger(path, "pcapng", true, func(f *os.File) (traceWriter, error) {
+		return canlog.NewPcapngWriter(f)
+	})
+	if err != nil {
+		return err
+	}
+	a.pcap = l
+	return nil
+}
+
+// StopPcapCapture flushes and closes the capture started with StartPcapCapture.
+func (a *App) StopPcapCapture() (LoggingStatus, error) {
+	a.logMu.Lock()
+	l := a.pcap
+	a.pcap = nil
+	a.logMu.Unlock()
+
+	if l == nil {
+		return LoggingStatus{}, nil
+	}
+	status := l.status()
+	return status, l.close()
+}
+
+// GetPcapStatus returns the state of the capture started with StartPcapCapture.
+func (a *App) GetPcapStatus() LoggingStatus {
+	a.logMu.Lock()
+	l := a.pcap
+	a.logMu.Unlock()
+
+	if l == nil {
+		return LoggingStatus{}
+	}
+	return l.status()
+}
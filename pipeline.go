@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"canproject/canbus"
+)
+
+// rxFrame is a received frame passing through the receive pipeline. It is reused
+// for the next frame of the session, processors must not keep it.
+type rxFrame struct {
+	sess *canSession
+	info canbus.RxInfo
+	// frame is the frame as received, the protocol decoders see it.
+	frame canbus.Frame
+	// shown is the frame emitted on "can:frame" and decoded to signals, which the
+	// scripts can change; hidden is set when a script dropped it.
+	shown  canbus.Frame
+	hidden bool
+	// signals are the signals decoded from shown by "signals", when decoded is set.
+	signals CANSignalsEvent
+	decoded bool
+}
+
+// frameProcessor is a stage of the receive pipeline. process returns false to stop
+// the frame there.
+type frameProcessor struct {
+	name    string
+	process func(rx *rxFrame) bool
+}
+
+// framePipeline is the ordered list of processors every received frame goes
+// through. Changes replace the list, so they can be made while frames flow.
+type framePipeline struct {
+	mu         sync.Mutex
+	processors atomic.Pointer[[]frameProcessor]
+}
+
+// newRxPipeline returns the receive pipeline of the app. "selftest" comes first
+// to take the self-test frames out, then "xl" takes out the CAN XL frames, which
+// the other stages do not decode, and "gap" schedules the gap transmissions
+// with the least latency. Error frames stop at
+// "errors", after being logged and counted; "scripts" decides what the stages
+// showing the frame see.
+func (a *App) newRxPipeline() *framePipeline {
+	p := &framePipeline{}
+	for _, proc := range []frameProcessor{
+		{"selftest", func(rx *rxFrame) bool {
+			return !a.receiveSelfTest(rx.sess.iface, rx.info, &rx.frame)
+		}},
+		{"xl", func(rx *rxFrame) bool {
+			if rx.frame.XL == nil {
+				return true
+			}
+			a.receiveXL(rx.sess.iface, rx.info.Time, rx.frame.XL)
+			return false
+		}},
+		{"gap", func(rx *rxFrame) bool {
+			if !rx.frame.IsError {
+				a.dispatchGap(rx.sess.iface, rx.info, &rx.frame)
+			}
+			return true
+		}},
+		{"log", func(rx *rxFrame) bool {
+			a.logFrame(rx.sess.iface, rx.info.Time, &rx.frame, false)
+			return true
+		}},
+		{"stats", func(rx *rxFrame) bool {
+			rx.sess.stats.Add(rx.info.Time, &rx.frame, false)
+			return true
+		}},
+		{"overview", func(rx *rxFrame) bool {
+			a.trackOverview(rx.sess.iface, rx.info.Time, &rx.frame)
+			return true
+		}},
+		{"fuzz", func(rx *rxFrame) bool {
+			a.monitorFuzz(rx.sess.iface, rx.info.Time, &rx.frame)
+			return true
+		}},
+		{"errors", func(rx *rxFrame) bool {
+			if !rx.frame.IsError {
+				return true
+			}
+			a.reportErrorFrame(rx.sess, rx.info.Time, &rx.frame)
+			return false
+		}},
+		{"alerts", func(rx *rxFrame) bool {
+			a.checkAlerts(rx.sess.iface, rx.info.Time, &rx.frame)
+			return true
+		}},
+		{"conflicts", func(rx *rxFrame) bool {
+			a.checkConflict(rx.sess.iface, rx.info.Time, &rx.frame)
+			return true
+		}},
+		{"e2e", func(rx *rxFrame) bool {
+			a.checkE2E(rx.sess.iface, rx.info.Time, &rx.frame)
+			return true
+		}},
+		{"timing", func(rx *rxFrame) bool {
+			a.trackTiming(rx.sess, rx.info.Time, &rx.frame)
+			return true
+		}},
+		{"scripts", func(rx *rxFrame) bool {
+			shown, keep := a.runScripts(rx.sess.iface, rx.info.Time, &rx.frame)
+			rx.shown, rx.hidden = shown, !keep
+			return true
+		}},
+		{"emit", func(rx *rxFrame) bool {
+			if !rx.hidden {
+				a.emitFrame(frameEvent(rx.sess.iface, rx.info, &rx.shown, false))
+			}
+			return true
+		}},
+		{"signals", func(rx *rxFrame) bool {
+			if rx.hidden {
+				return true
+			}
+			if rx.signals, rx.decoded = a.decodeSignals(rx.sess.iface, rx.info.Time, &rx.shown); rx.decoded {
+				a.emit("can:signals", rx.signals)
+			}
+			return true
+		}},
+		{"isotp", func(rx *rxFrame) bool {
+			a.dispatchIsoTP(rx.sess.iface, &rx.frame)
+			return true
+		}},
+		{"obd", func(rx *rxFrame) bool {
+			a.dispatchOBD(rx.sess.iface, rx.info.Time, &rx.frame)
+			return true
+		}},
+		{"j1939", func(rx *rxFrame) bool {
+			a.dispatchJ1939(rx.sess, rx.info.Time, &rx.frame)
+			return true
+		}},
+		{"canopen", func(rx *rxFrame) bool {
+			a.dispatchCANopen(rx.sess, rx.info.Time, &rx.frame)
+			return true
+		}},
+		{"nm", func(rx *rxFrame) bool {
+			a.dispatchNM(rx.sess, rx.info.Time, &rx.frame)
+			return true
+		}},
+		{"nmea2000", func(rx *rxFrame) bool {
+			a.dispatchNMEA2000(rx.sess, rx.info.Time, &rx.frame)
+			return true
+		}},
+		{"xcp", func(rx *rxFrame) bool {
+			a.dispatchXCP(rx.sess.iface, &rx.frame)
+			return true
+		}},
+		{"sequences", func(rx *rxFrame) bool {
+			a.dispatchSequences(rx.sess.iface, &rx.frame)
+			return true
+		}},
+		{"responder", func(rx *rxFrame) bool {
+			a.dispatchResponder(rx.sess.iface, &rx.frame)
+			return true
+		}},
+	} {
+		_ = p.register(proc, "")
+	}
+	return p
+}
+
+// ListFrameProcessors returns the names of the receive pipeline stages in the
+// order received frames go through them.
+func (a *App) ListFrameProcessors() []string {
+	return a.rxPipeline.names()
+}
+
+// run passes rx through the processors until one stops it.
+func (p *framePipeline) run(rx *rxFrame) {
+	procs := p.processors.Load()
+	if procs == nil {
+		return
+	}
+	for i := range *procs {
+		if !(*procs)[i].process(rx) {
+			return
+		}
+	}
+}
+
+// register inserts a processor before the one named before, or at the end when
+// before is empty.
+func (p *framePipeline) register(proc frameProcessor, before string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	procs := p.list()
+	if p.index(procs, proc.name) >= 0 {
+		return fmt.Errorf("frame processor %q already registered", proc.name)
+	}
+	return p.insert(procs, proc, before)
+}
+
+// remove deletes a processor.
+func (p *framePipeline) remove(name string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	procs := p.list()
+	i := p.index(procs, name)
+	if i < 0 {
+		return fmt.Errorf("no frame processor %q", name)
+	}
+	procs = append(procs[:i], procs[i+1:]...)
+	p.processors.Store(&procs)
+	return nil
+}
+
+// move reorders a processor before the one named before, or to the end when
+// before is empty.
+func (p *framePipeline) move(name, before string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	procs := p.list()
+	i := p.index(procs, name)
+	if i < 0 {
+		return fmt.Errorf("no frame processor %q", name)
+	}
+	if name == before {
+		return nil
+	}
+	proc := procs[i]
+	return p.insert(append(procs[:i], procs[i+1:]...), proc, before)
+}
+
+// insert stores procs with proc inserted before the processor named before.
+func (p *framePipeline) insert(procs []frameProcessor, proc frameProcessor, before string) error {
+	i := len(procs)
+	if before != "" {
+		if i = p.index(procs, before); i < 0 {
+			return fmt.Errorf("no frame processor %q", before)
+		}
+	}
+	procs = append(procs[:i:i], append([]frameProcessor{proc}, procs[i:]...)...)
+	p.processors.Store(&procs)
+	return nil
+}
+
+func (p *framePipeline) names() []string {
+	procs := p.list()
+	names := make([]string, len(procs))
+	for i := range procs {
+		names[i] = procs[i].name
+	}
+	return names
+}
+
+// list returns a copy of the processors.
+func (p *framePipeline) list() []frameProcessor {
+	if procs := p.processors.Load(); procs != nil {
+		return append([]frameProcessor(nil), *procs...)
+	}
+	return nil
+}
+
+func (p *framePipeline) index(procs []frameProcessor, name string) int {
+	for i := range procs {
+		if procs[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"canproject/plot"
+)
+
+// PlotSignal names a signal whose history is kept for plotting.
+type PlotSignal struct {
+	// Interface restricts the signal to an interface, empty for all.
+	Interface string `json:"interface"`
+	Message   string `json:"message"`
+	Signal    string `json:"signal"`
+}
+
+// PlotSeriesInfo describes the history of a tracked signal.
+type PlotSeriesInfo struct {
+	PlotSignal
+	Unit string `json:"unit"`
+	// FirstMs and LastMs are the times of the first and last samples, in Unix
+	// milliseconds.
+	FirstMs float64 `json:"firstMs"`
+	LastMs  float64 `json:"lastMs"`
+	Samples uint64  `json:"samples"`
+}
+
+// PlotQuery selects the window of QueryPlot.
+type PlotQuery struct {
+	Signals []PlotSignal `json:"signals"`
+	// FromMs and ToMs bound the window in Unix milliseconds, 0 for the first
+	// and the last sample of each signal.
+	FromMs float64 `json:"fromMs"`
+	ToMs   float64 `json:"toMs"`
+	// MaxPoints bounds the points of each signal, 0 for 1000.
+	MaxPoints int `json:"maxPoints"`
+}
+
+// PlotPoint aggregates the samples of a bucket, or is a sample.
+type PlotPoint struct {
+	// TimeMs is the start of the bucket in Unix milliseconds.
+	TimeMs float64 `json:"timeMs"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Avg    float64 `json:"avg"`
+	Count  int     `json:"count"`
+}
+
+// PlotSeries is the window of a signal returned by QueryPlot.
+type PlotSeries struct {
+	PlotSignal
+	Unit string `json:"unit"`
+	// BucketMs is the width of the points, 0 when they are the samples.
+	BucketMs float64     `json:"bucketMs"`
+	Points   []PlotPoint `json:"points"`
+}
+
+type plotSeries struct {
+	series *plot.Series
+	// unit is the unit of the last sample.
+	unit atomic.Pointer[string]
+}
+
+// TrackPlotSignals keeps the history of signals decoded with the loaded
+// databases, for QueryPlot. The latest 10000 samples of each are kept as
+// received, and all of them in min/max/avg buckets of 10 ms to 1 min.
+// Tracking a signal again keeps its history.
+func (a *App) TrackPlotSignals(signals []PlotSignal) error {
+	a.plotMu.Lock()
+	defer a.plotMu.Unlock()
+
+	plots := map[PlotSignal]*plotSeries{}
+	if p := a.plots.Load(); p != nil {
+		plots = maps.Clone(*p)
+	}
+	for _, s := range signals {
+		s = normalizePlotSignal(s)
+		if s.Message == "" || s.Signal == "" {
+			return fmt.Errorf("plot signal without message or signal name")
+		}
+		if plots[s] == nil {
+			plots[s] = &plotSeries{series: plot.NewSeries(plot.DefaultConfig())}
+		}
+	}
+	if a.plots.Load() == nil && len(plots) > 0 {
+		if err := a.rxPipeline.register(frameProcessor{"plot", func(rx *rxFrame) bool {
+			a.recordPlot(rx)
+			return true
+		}}, "isotp"); err != nil {
+			return err
+		}
+	}
+	a.plots.Store(&plots)
+	return nil
+}
+
+// UntrackPlotSignals drops the history of signals, or of all of them when
+// signals is empty.
+func (a *App) UntrackPlotSignals(signals []PlotSignal) {
+	a.plotMu.Lock()
+	defer a.plotMu.Unlock()
+
+	p := a.plots.Load()
+	if p == nil {
+		return
+	}
+	plots := map[PlotSignal]*plotSeries{}
+	if len(signals) > 0 {
+		plots = maps.Clone(*p)
+		for _, s := range signals {
+			delete(plots, normalizePlotSignal(s))
+		}
+	}
+	if len(plots) == 0 {
+		_ = a.rxPipeline.remove("plot")
+		a.plots.Store(nil)
+		return
+	}
+	a.plots.Store(&plots)
+}
+
+// ListPlotSignals returns the tracked signals and the span of their history.
+func (a *App) ListPlotSignals() []PlotSeriesInfo {
+	infos := []PlotSeriesInfo{}
+	p := a.plots.Load()
+	if p == nil {
+		return infos
+	}
+	for s, ps := range *p {
+		first, last, n := ps.series.Span()
+		info := PlotSeriesInfo{PlotSignal: s, Unit: ps.unitName(), Samples: n}
+		if n > 0 {
+			info.FirstMs, info.LastMs = unixMillis(first), unixMillis(last)
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Interface != infos[j].Interface {
+			return infos[i].Interface < infos[j].Interface
+		}
+		if infos[i].Message != infos[j].Message {
+			return infos[i].Message < infos[j].Message
+		}
+		return infos[i].Signal < infos[j].Signal
+	})
+	return infos
+}
+
+// QueryPlot returns a window of the history of tracked signals, at most
+// q.MaxPoints points each: the samples when there are few enough, else min,
+// max and average buckets as wide as needed.
+func (a *App) QueryPlot(q PlotQuery) ([]PlotSeries, error) {
+	if q.MaxPoints < 0 {
+		return nil, fmt.Errorf("max points must be >= 0 (got %d)", q.MaxPoints)
+	}
+	if q.FromMs > 0 && q.ToMs > 0 && q.ToMs < q.FromMs {
+		return nil, fmt.Errorf("plot window ends before it starts")
+	}
+	p := a.plots.Load()
+	out := make([]PlotSeries, 0, len(q.Signals))
+	for _, s := range q.Signals {
+		s = normalizePlotSignal(s)
+		var ps *plotSeries
+		if p != nil {
+			ps = (*p)[s]
+		}
+		if ps == nil {
+			return nil, fmt.Errorf("signal %s.%s is not tracked", s.Message, s.Signal)
+		}
+		first, last, _ := ps.series.Span()
+		from, to := first, last
+		if q.FromMs > 0 {
+			from = fromUnixMillis(q.FromMs)
+		}
+		if q.ToMs > 0 {
+			to = fromUnixMillis(q.ToMs)
+		}
+		width, buckets := ps.series.Query(from, to, q.MaxPoints)
+		series := PlotSeries{
+			PlotSignal: s,
+			Unit:       ps.unitName(),
+			BucketMs:   milliseconds(width),
+			Points:     make([]PlotPoint, len(buckets)),
+		}
+		for i := range buckets {
+			b := &buckets[i]
+			series.Points[i] = PlotPoint{
+				TimeMs: float64(b.Start) / 1e6,
+				Min:    b.Min,
+				Max:    b.Max,
+				Avg:    b.Avg(),
+				Count:  b.Count,
+			}
+		}
+		out = append(out, series)
+	}
+	return out, nil
+}
+
+// recordPlot adds the decoded signals of a received frame to their histories.
+// It runs in the receive pipeline after "signals".
+func (a *App) recordPlot(rx *rxFrame) {
+	p := a.plots.Load()
+	if p == nil || !rx.decoded {
+		return
+	}
+	for i := range rx.signals.Signals {
+		v := &rx.signals.Signals[i]
+		for _, iface := range []string{rx.sess.iface, ""} {
+			if ps := (*p)[PlotSignal{Interface: iface, Message: rx.signals.Message, Signal: v.Name}]; ps != nil {
+				ps.series.Add(rx.signals.Timestamp, v.Physical)
+				if u := ps.unit.Load(); u == nil || *u != v.Unit {
+					unit := v.Unit
+					ps.unit.Store(&unit)
+				}
+			}
+		}
+	}
+}
+
+func (ps *plotSeries) unitName() string {
+	if u := ps.unit.Load(); u != nil {
+		return *u
+	}
+	return ""
+}
+
+func normalizePlotSignal(s PlotSignal) PlotSignal {
+	return PlotSignal{
+		Interface: strings.TrimSpace(s.Interface),
+		Message:   strings.TrimSpace(s.Message),
+		Signal:    strings.TrimSpace(s.Signal),
+	}
+}
+
+func unixMillis(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e6
+}
+
+func fromUnixMillis(ms float64) time.Time {
+	return time.Unix(0, int64(ms*1e6))
+}
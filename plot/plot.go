@@ -0,0 +1,246 @@
+// Package plot keeps the history of signals for plotting: every Series stores
+// its latest samples as received and aggregates all of them into min/max/avg
+// buckets at several resolutions, so a window of hours is served from a few
+// hundred buckets instead of every sample.
+package plot
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRawCapacity is the number of samples kept as received.
+	DefaultRawCapacity = 10000
+	// DefaultCapacity is the number of buckets kept per resolution.
+	DefaultCapacity = 20000
+	// DefaultMaxPoints is the number of points of a query without limit.
+	DefaultMaxPoints = 1000
+)
+
+// DefaultResolutions are the bucket widths, from 200 s of history at 10 ms to
+// about two weeks at one minute with the default capacity.
+var DefaultResolutions = []time.Duration{
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+	time.Minute,
+}
+
+// Config sizes a Series.
+type Config struct {
+	RawCapacity int
+	// Resolutions are the bucket widths, finest first, kept Capacity buckets each.
+	Resolutions []time.Duration
+	Capacity    int
+}
+
+// DefaultConfig returns the default sizes.
+func DefaultConfig() Config {
+	return Config{RawCapacity: DefaultRawCapacity, Resolutions: DefaultResolutions, Capacity: DefaultCapacity}
+}
+
+// Bucket aggregates the samples from Start (Unix nanoseconds) over the width of
+// its resolution. A raw sample is a bucket of one.
+type Bucket struct {
+	Start    int64
+	Min, Max float64
+	Sum      float64
+	Count    int
+}
+
+// Avg returns the mean of the samples of the bucket.
+func (b *Bucket) Avg() float64 {
+	if b.Count == 0 {
+		return 0
+	}
+	return b.Sum / float64(b.Count)
+}
+
+func (b *Bucket) merge(o *Bucket) {
+	if b.Count == 0 {
+		*b = *o
+		return
+	}
+	b.Min = min(b.Min, o.Min)
+	b.Max = max(b.Max, o.Max)
+	b.Sum += o.Sum
+	b.Count += o.Count
+}
+
+// level is the history at one resolution.
+type level struct {
+	width   int64
+	buckets ring
+}
+
+// Series is the history of a signal. It is safe for concurrent use.
+type Series struct {
+	mu     sync.Mutex
+	raw    ring
+	levels []level
+	total  uint64
+	first  int64
+	last   int64
+}
+
+// NewSeries returns an empty series sized by cfg.
+func NewSeries(cfg Config) *Series {
+	if cfg.RawCapacity <= 0 {
+		cfg.RawCapacity = DefaultRawCapacity
+	}
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = DefaultCapacity
+	}
+	if len(cfg.Resolutions) == 0 {
+		cfg.Resolutions = DefaultResolutions
+	}
+	s := &Series{raw: ring{capacity: cfg.RawCapacity}}
+	for _, r := range cfg.Resolutions {
+		s.levels = append(s.levels, level{width: int64(r), buckets: ring{capacity: cfg.Capacity}})
+	}
+	return s
+}
+
+// Add records a sample. Samples older than the last one are recorded at the
+// time of the last one, the buckets only grow forward.
+func (s *Series) Add(t time.Time, v float64) {
+	ns := t.UnixNano()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.total == 0 {
+		s.first = ns
+	} else if ns < s.last {
+		ns = s.last
+	}
+	s.last = ns
+	s.total++
+	sample := Bucket{Start: ns, Min: v, Max: v, Sum: v, Count: 1}
+	s.raw.push(sample)
+	for i := range s.levels {
+		l := &s.levels[i]
+		start := ns - ns%l.width
+		if n := l.buckets.len(); n > 0 && l.buckets.at(n-1).Start == start {
+			l.buckets.at(n - 1).merge(&sample)
+			continue
+		}
+		b := sample
+		b.Start = start
+		l.buckets.push(b)
+	}
+}
+
+// Span returns the times of the first and last samples and their number.
+func (s *Series) Span() (first, last time.Time, samples uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.total == 0 {
+		return time.Time{}, time.Time{}, 0
+	}
+	return time.Unix(0, s.first), time.Unix(0, s.last), s.total
+}
+
+// Query returns at most maxPoints buckets (DefaultMaxPoints when 0) covering
+// the samples from from to to, and their width: the raw samples and a zero
+// width when they are few enough, else the buckets of the widest resolution
+// fine enough merged to a width that fits.
+func (s *Series) Query(from, to time.Time, maxPoints int) (time.Duration, []Bucket) {
+	if maxPoints <= 0 {
+		maxPoints = DefaultMaxPoints
+	}
+	f, t := from.UnixNano(), to.UnixNano()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.total == 0 || t < f {
+		return 0, []Bucket{}
+	}
+
+	if lo, hi := s.raw.window(f, t); s.raw.covers(f) && hi-lo <= maxPoints {
+		out := make([]Bucket, 0, hi-lo)
+		for i := lo; i < hi; i++ {
+			out = append(out, *s.raw.at(i))
+		}
+		return 0, out
+	}
+
+	// the widest resolution covering from not wider than the target, else the
+	// finest covering from, else the widest
+	target := (t - f) / int64(maxPoints)
+	pick := -1
+	for i := range s.levels {
+		l := &s.levels[i]
+		if !l.buckets.covers(f) {
+			continue
+		}
+		if pick < 0 || l.width <= target {
+			pick = i
+		}
+	}
+	if pick < 0 {
+		pick = len(s.levels) - 1
+	}
+	l := &s.levels[pick]
+	width := max(l.width, (target+l.width-1)/l.width*l.width)
+	// the buckets are aligned on their width, the first may start before from
+	for (t-(f-f%width))/width+1 > int64(maxPoints) {
+		width += l.width
+	}
+
+	lo, hi := l.buckets.window(f-f%l.width, t)
+	out := make([]Bucket, 0, maxPoints)
+	for i := lo; i < hi; i++ {
+		b := l.buckets.at(i)
+		start := b.Start - b.Start%width
+		if n := len(out); n > 0 && out[n-1].Start == start {
+			out[n-1].merge(b)
+			continue
+		}
+		m := *b
+		m.Start = start
+		out = append(out, m)
+	}
+	return time.Duration(width), out
+}
+
+// ring is a ring buffer of buckets ordered by Start.
+type ring struct {
+	items    []Bucket
+	head     int
+	capacity int
+	// dropped is set once the oldest items are overwritten.
+	dropped bool
+}
+
+func (r *ring) len() int { return len(r.items) }
+
+// at returns the i-th oldest item.
+func (r *ring) at(i int) *Bucket {
+	return &r.items[(r.head+i)%len(r.items)]
+}
+
+func (r *ring) push(b Bucket) {
+	if len(r.items) < r.capacity {
+		r.items = append(r.items, b)
+		return
+	}
+	r.items[r.head] = b
+	r.head = (r.head + 1) % len(r.items)
+	r.dropped = true
+}
+
+// covers reports whether the ring holds every item from t on.
+func (r *ring) covers(t int64) bool {
+	return !r.dropped || (len(r.items) > 0 && r.at(0).Start <= t)
+}
+
+// window returns the indexes of the first item starting at or after from and
+// of the first one after to.
+func (r *ring) window(from, to int64) (int, int) {
+	n := len(r.items)
+	lo := sort.Search(n, func(i int) bool { return r.at(i).Start >= from })
+	hi := sort.Search(n, func(i int) bool { return r.at(i).Start > to })
+	return lo, hi
+}
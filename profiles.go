@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+)
+
+// profileDirName is the directory of the session profiles in the user config directory.
+const profileDirName = "CanSocket/profiles"
+
+// SessionProfile is a saved test bench setup.
+type SessionProfile struct {
+	Name    string    `json:"name"`
+	SavedAt time.Time `json:"savedAt"`
+	// Interfaces are the started interfaces.
+	Interfaces []ProfileInterface `json:"interfaces"`
+	// DBCs are the paths of the loaded databases.
+	DBCs         []string             `json:"dbcs"`
+	CyclicFrames []ProfileCyclicFrame `json:"cyclicFrames"`
+	// TXProcessors update the counters and checksums of the cyclic frames.
+	TXProcessors []TXProcessor `json:"txProcessors"`
+	// Responder is the path of the loaded responder profile, empty if none.
+	Responder string `json:"responder"`
+	// Groups are the frame groups.
+	Groups []FrameGroup `json:"groups"`
+	// TXPanels are the TX panels.
+	TXPanels []TXPanel `json:"txPanels"`
+}
+
+// ProfileInterface is a started interface of a SessionProfile.
+type ProfileInterface struct {
+	Name     string `json:"name"`
+	FD       bool   `json:"fd"`
+	ReadOnly bool   `json:"readOnly"`
+	// Bitrate, DataBitrate and SamplePoint (a fraction) are the bit timing of
+	// SocketCAN controllers, zero when unknown.
+	Bitrate     uint32      `json:"bitrate"`
+	DataBitrate uint32      `json:"dataBitrate"`
+	SamplePoint float64     `json:"samplePoint"`
+	Filters     []CANFilter `json:"filters"`
+}
+
+// ProfileCyclicFrame is a cyclic transmission of a SessionProfile.
+type ProfileCyclicFrame struct {
+	Interface string   `json:"interface"`
+	ID        uint32   `json:"id"`
+	Extended  bool     `json:"extended"`
+	Data      []uint32 `json:"data"`
+	PeriodMs  int      `json:"periodMs"`
+}
+
+// ProfileInfo describes a saved profile.
+type ProfileInfo struct {
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	SavedAt time.Time `json:"savedAt"`
+}
+
+// ProfileLoadResult is the result of LoadProfile.
+type ProfileLoadResult struct {
+	Profile SessionProfile `json:"profile"`
+	// Warnings are the parts of the profile that could not be restored.
+	Warnings []string `json:"warnings"`
+}
+
+// SaveProfile saves the started interfaces with their bit timing and filters, the
+// loaded DBCs, the cyclic frames, the responder profile, the frame groups and the
+// TX panels as name in the config directory, replacing a profile of the same name.
+func (a *App) SaveProfile(name string) (ProfileInfo, error) {
+	path, err := profilePath(name)
+	if err != nil {
+		return ProfileInfo{}, err
+	}
+	p := a.currentProfile()
+	p.Name = strings.TrimSpace(name)
+	p.SavedAt = time.Now()
+
+	doc, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return ProfileInfo{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return ProfileInfo{}, err
+	}
+	if err := os.WriteFile(path, append(doc, '\n'), 0o644); err != nil {
+		return ProfileInfo{}, err
+	}
+	return ProfileInfo{Name: p.Name, Path: path, SavedAt: p.SavedAt}, nil
+}
+
+// LoadProfile restores a profile saved with SaveProfile on top of the current setup:
+// interfaces that are not started are configured and started, databases that are not
+// loaded are loaded, the cyclic frames are started, the responder profile and the
+// frame groups replace the current ones and the TX panels replace the panels of the
+// same name. What cannot be restored is reported in the warnings.
+func (a *App) LoadProfile(name string) (ProfileLoadResult, error) {
+	path, err := profilePath(name)
+	if err != nil {
+		return ProfileLoadResult{}, err
+	}
+	doc, err := os.ReadFile(path)
+	if err != nil {
+		return ProfileLoadResult{}, err
+	}
+	var p SessionProfile
+	if err := json.Unmarshal(doc, &p); err != nil {
+		return ProfileLoadResult{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	res := ProfileLoadResult{Profile: p, Warnings: []string{}}
+	warn := func(format string, args ...any) {
+		res.Warnings = append(res.Warnings, fmt.Sprintf(format, args...))
+	}
+	active := make(map[string]bool)
+	for _, iface := range a.ActiveInterfaces() {
+		active[iface] = true
+	}
+	for _, pi := range p.Interfaces {
+		if !active[pi.Name] {
+			if pi.Bitrate > 0 {
+				if err := a.restoreBitTiming(pi); err != nil {
+					warn("configure %s: %v", pi.Name, err)
+				}
+			}
+			if err := a.StartCANWithOptions(pi.Name, CANOptions{FD: pi.FD, ReadOnly: pi.ReadOnly}); err != nil {
+				warn("start %s: %v", pi.Name, err)
+				continue
+			}
+		}
+		if len(pi.Filters) > 0 {
+			if err := a.SetFilters(pi.Name, pi.Filters); err != nil {
+				warn("filters of %s: %v", pi.Name, err)
+			}
+		}
+	}
+
+	loaded := make(map[string]bool)
+	for _, info := range a.LoadedDBCs() {
+		loaded[info.Path] = true
+	}
+	for _, path := range p.DBCs {
+		if loaded[path] {
+			continue
+		}
+		if _, err := a.LoadDBC(path); err != nil {
+			warn("load %s: %v", path, err)
+		}
+	}
+
+	for _, tp := range p.TXProcessors {
+		if err := a.SetTXProcessor(tp); err != nil {
+			warn("TX processor 0x%X: %v", tp.ID, err)
+		}
+	}
+	for _, c := range p.CyclicFrames {
+		data := make([]byte, len(c.Data))
+		for i, b := range c.Data {
+			data[i] = byte(b)
+		}
+		if _, err := a.StartCyclicFrame(c.Interface, c.ID, data, c.Extended, c.PeriodMs); err != nil {
+			warn("cyclic frame 0x%X on %s: %v", c.ID, c.Interface, err)
+		}
+	}
+
+	if p.Responder != "" {
+		if _, err := a.LoadResponderProfile(p.Responder); err != nil {
+			warn("responder: %v", err)
+		}
+	}
+	if len(p.Groups) > 0 {
+		if err := a.SetFrameGroups(p.Groups); err != nil {
+			warn("frame groups: %v", err)
+		}
+	}
+	for _, panel := range p.TXPanels {
+		if err := a.SaveTXPanel(panel); err != nil {
+			warn("%v", err)
+		}
+	}
+	return res, nil
+}
+
+// ListProfiles returns the saved profiles by name.
+func (a *App) ListProfiles() ([]ProfileInfo, error) {
+	dir, err := profileDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []ProfileInfo{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	infos := []ProfileInfo{}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		doc, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var p SessionProfile
+		if json.Unmarshal(doc, &p) != nil {
+			continue
+		}
+		infos = append(infos, ProfileInfo{
+			Name:    strings.TrimSuffix(e.Name(), ".json"),
+			Path:    path,
+			SavedAt: p.SavedAt,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// currentProfile captures the current setup.
+func (a *App) currentProfile() SessionProfile {
+	p := SessionProfile{
+		Interfaces:   []ProfileInterface{},
+		DBCs:         []string{},
+		CyclicFrames: []ProfileCyclicFrame{},
+	}
+	a.mu.Lock()
+	for name, sess := range a.sessions {
+		if sess.conn == nil {
+			continue
+		}
+		p.Interfaces = append(p.Interfaces, ProfileInterface{
+			Name:     name,
+			FD:       sess.fd,
+			ReadOnly: sess.opts.ReadOnly,
+			Filters:  append([]CANFilter{}, sess.filters...),
+		})
+	}
+	a.mu.Unlock()
+	for i := range p.Interfaces {
+		pi := &p.Interfaces[i]
+		if l, err := canbus.LinkByName(pi.Name); err == nil && l.HasController {
+			pi.Bitrate, pi.DataBitrate, pi.SamplePoint = l.Bitrate, l.DataBitrate, l.SamplePoint/100
+		}
+	}
+	sort.Slice(p.Interfaces, func(i, j int) bool { return p.Interfaces[i].Name < p.Interfaces[j].Name })
+
+	for _, info := range a.LoadedDBCs() {
+		p.DBCs = append(p.DBCs, info.Path)
+	}
+	for _, c := range a.ListCyclicFrames() {
+		p.CyclicFrames = append(p.CyclicFrames, ProfileCyclicFrame{
+			Interface: c.Interface,
+			ID:        c.ID,
+			Extended:  c.Extended,
+			Data:      c.Data,
+			PeriodMs:  c.PeriodMs,
+		})
+	}
+	p.TXProcessors = a.ListTXProcessors()
+	if r := a.responder.Load(); r != nil {
+		p.Responder = r.path
+	}
+	p.Groups = a.GetFrameGroups()
+	p.TXPanels = a.ListTXPanels()
+	return p
+}
+
+// restoreBitTiming configures the saved bit timing of a SocketCAN controller unless
+// it is already set.
+func (a *App) restoreBitTiming(pi ProfileInterface) error {
+	l, err := canbus.LinkByName(pi.Name)
+	if err != nil || !l.HasController {
+		return nil
+	}
+	if l.Up && l.Bitrate == pi.Bitrate && l.DataBitrate == pi.DataBitrate {
+		return nil
+	}
+	return a.ConfigureInterface(pi.Name, pi.Bitrate, pi.DataBitrate, pi.SamplePoint, 0)
+}
+
+func profileDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, filepath.FromSlash(profileDirName)), nil
+}
+
+// profilePath returns the file of the profile name.
+func profilePath(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\:`) {
+		return "", fmt.Errorf("invalid profile name %q", name)
+	}
+	dir, err := profileDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".json"), nil
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"canproject/canlog"
+)
+
+// replayProgressInterval throttles "can:replay" progress events.
+const replayProgressInterval = 100 * time.Millisecond
+
+// Replay states reported in ReplayStatus.State.
+const (
+	replayPlaying  = "playing"
+	replayPaused   = "paused"
+	replayFinished = "finished"
+	replayStopped  = "stopped"
+)
+
+// ReplayStatus is the playback position of a log replay, emitted on "can:replay".
+type ReplayStatus struct {
+	State     string  `json:"state"`
+	Path      string  `json:"path"`
+	Interface string  `json:"interface"`
+	Speed     float64 `json:"speed"`
+	Loop      bool    `json:"loop"`
+	// Position is the index of the next frame to send, Total the number of frames in the log.
+	Position int `json:"position"`
+	Total    int `json:"total"`
+	// ElapsedMs is the log time of the current position, DurationMs the log time of the last frame.
+	ElapsedMs  int64 `json:"elapsedMs"`
+	DurationMs int64 `json:"durationMs"`
+	Pass       int   `json:"pass"`
+	Errors     int   `json:"errors"`
+	// Warnings lists the records of the trace that could not be replayed.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type replayer struct {
+	iface   string
+	path    string
+	records []canlog.Record
+	// warnings are the records of the trace that were skipped.
+	warnings []string
+	speed    float64
+	loop     bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	// wake interrupts a pending wait when the pause state changes.
+	wake chan struct{}
+
+	mu       sync.Mutex
+	paused   bool
+	position int
+	pass     int
+	errors   int
+	state    string
+}
+
+// ReplayLog parses a candump log, a Vector ASC (.asc) or BLF (.blf) trace or a chunked
+// capture (.ccap) and retransmits its frames on a started interface with the original
+// inter-frame timing divided by speedFactor (2 plays twice as fast). With loop the log
+// restarts when it ends. Progress is emitted on "can:replay"; trace records that cannot
+// be replayed, such as the frames of other networks than CAN, are skipped and listed in
+// its warnings.
+func (a *App) ReplayLog(iface string, path string, speedFactor float64, loop bool) error {
+	iface = strings.TrimSpace(iface)
+	if speedFactor <= 0 {
+		return fmt.Errorf("speed factor must be > 0 (got %g)", speedFactor)
+	}
+	if _, err := a.txConn(iface, false); err != nil {
+		return err
+	}
+
+	records, warnings, err := readTrace(path)
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("%s contains no frames", path)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &replayer{
+		iface:    iface,
+		path:     path,
+		records:  records,
+		warnings: warnings,
+		speed:    speedFactor,
+		loop:     loop,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		wake:     make(chan struct{}, 1),
+		state:    replayPlaying,
+	}
+
+	a.replayMu.Lock()
+	if a.replay != nil {
+		a.replayMu.Unlock()
+		cancel()
+		return errors.New("a replay is already running")
+	}
+	a.replay = r
+	a.replayMu.Unlock()
+
+	go a.replayLoop(ctx, r)
+	return nil
+}
+
+// PauseReplay pauses the running replay at its current position.
+func (a *App) PauseReplay() error {
+	return a.setReplayPaused(true)
+}
+
+// ResumeReplay continues a paused replay.
+func (a *App) ResumeReplay() error {
+	return a.setReplayPaused(false)
+}
+
+// StopReplay aborts the running replay.
+func (a *App) StopReplay() error {
+	a.replayMu.Lock()
+	r := a.replay
+	a.replayMu.Unlock()
+
+	if r == nil {
+		return nil
+	}
+	r.cancel()
+	<-r.done
+	return nil
+}
+
+// GetReplayStatus returns the position of the running replay.
+func (a *App) GetReplayStatus() ReplayStatus {
+	a.replayMu.Lock()
+	r := a.replay
+	a.replayMu.Unlock()
+
+	if r == nil {
+		return ReplayStatus{}
+	}
+	return r.status()
+}
+
+func (a *App) setReplayPaused(paused bool) error {
+	a.replayMu.Lock()
+	r := a.replay
+	a.replayMu.Unlock()
+
+	if r == nil {
+		return errors.New("no replay running")
+	}
+	r.mu.Lock()
+	r.paused = paused
+	r.state = replayPlaying
+	if paused {
+		r.state = replayPaused
+	}
+	r.mu.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	a.emit("can:replay", r.status())
+	return nil
+}
+
+// stopReplayOn aborts the running replay if it transmits on iface.
+func (a *App) stopReplayOn(iface string) {
+	a.replayMu.Lock()
+	r := a.replay
+	a.replayMu.Unlock()
+
+	if r != nil && r.iface == iface {
+		_ = a.StopReplay()
+	}
+}
+
+func (a *App) replayLoop(ctx context.Context, r *replayer) {
+	defer func() {
+		r.mu.Lock()
+		if ctx.Err() != nil {
+			r.state = replayStopped
+		} else {
+			r.state = replayFinished
+		}
+		r.mu.Unlock()
+		a.emit("can:replay", r.status())
+
+		a.replayMu.Lock()
+		if a.replay == r {
+			a.replay = nil
+		}
+		a.replayMu.Unlock()
+		r.cancel()
+		close(r.done)
+	}()
+
+	a.emit("can:replay", r.status())
+	first := r.records[0].Timestamp
+	lastProgress := time.Now()
+	for pass := 1; ; pass++ {
+		r.mu.Lock()
+		r.pass = pass
+		r.mu.Unlock()
+
+		start := time.Now()
+		for i := range r.records {
+			rec := &r.records[i]
+			offset := time.Duration(float64(rec.Timestamp.Sub(first)) / r.speed)
+			if !r.waitUntil(ctx, &start, offset) {
+				return
+			}
+
+			var err error
+			if !rec.Frame.IsError {
+				err = a.send(r.iface, rec.Frame)
+			}
+			r.mu.Lock()
+			r.position = i + 1
+			if err != nil {
+				r.errors++
+				// report the first failure only, a log usually fails the same way for every frame
+				if r.errors == 1 {
+					a.emitError(fmt.Errorf("replay %s: %w", r.path, err))
+				}
+			}
+			r.mu.Unlock()
+
+			if now := time.Now(); now.Sub(lastProgress) >= replayProgressInterval {
+				lastProgress = now
+				a.emit("can:replay", r.status())
+			}
+		}
+		if !r.loop {
+			return
+		}
+	}
+}
+
+// waitUntil blocks until offset after *start, or while the replay is paused.
+// Time spent paused shifts *start so the timing continues where it stopped.
+// It returns false when the replay is cancelled.
+func (r *replayer) waitUntil(ctx context.Context, start *time.Time, offset time.Duration) bool {
+	var pausedAt time.Time
+	for {
+		r.mu.Lock()
+		paused := r.paused
+		r.mu.Unlock()
+
+		if paused {
+			if pausedAt.IsZero() {
+				pausedAt = time.Now()
+			}
+			select {
+			case <-ctx.Done():
+				return false
+			case <-r.wake:
+			}
+			continue
+		}
+		if !pausedAt.IsZero() {
+			*start = start.Add(time.Since(pausedAt))
+			pausedAt = time.Time{}
+		}
+
+		wait := time.Until(start.Add(offset))
+		if wait <= 0 {
+			return ctx.Err() == nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-r.wake:
+			timer.Stop()
+		case <-timer.C:
+			return true
+		}
+	}
+}
+
+func (r *replayer) status() ReplayStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	first := r.records[0].Timestamp
+	pos := r.position
+	if pos >= len(r.records) {
+		pos = len(r.records) - 1
+	}
+	return ReplayStatus{
+		State:      r.state,
+		Path:       r.path,
+		Interface:  r.iface,
+		Speed:      r.speed,
+		Loop:       r.loop,
+		Position:   r.position,
+		Total:      len(r.records),
+		ElapsedMs:  r.records[pos].Timestamp.Sub(first).Milliseconds(),
+		DurationMs: r.records[len(r.records)-1].Timestamp.Sub(first).Milliseconds(),
+		Pass:       r.pass,
+		Errors:     r.errors,
+		Warnings:   r.warnings,
+	}
+}
+
+// readTrace reads the CAN frames of a trace file, see readTraceFile, and the
+// warnings about the records that were skipped.
+func readTrace(path string) ([]canlog.Record, []string, error) {
+	records, warnings, err := readTraceFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	frames := records[:0]
+	for _, r := range records {
+		if r.Packet == nil {
+			frames = append(frames, r)
+		}
+	}
+	if n := len(records) - len(frames); n > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d frames of other networks than CAN skipped", n))
+	}
+	return frames, warnings, nil
+}
+
+// readTraceFile reads the records of a candump log, or of a Vector ASC or BLF
+// trace or a chunked capture for .asc, .blf and .ccap paths, and the warnings
+// about the records that were skipped.
+func readTraceFile(path string) ([]canlog.Record, []string, error) {
+	path = strings.TrimSpace(path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".asc":
+		return canlog.ReadASC(f)
+	case ".blf":
+		return canlog.ReadBLF(f)
+	case ".ccap":
+		return canlog.ReadChunked(f)
+	}
+	records, err := canlog.ReadCandump(f)
+	return records, nil, err
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/responder"
+)
+
+// ResponderStatus describes the loaded ECU simulation profile.
+type ResponderStatus struct {
+	Profile string                `json:"profile"`
+	Path    string                `json:"path"`
+	Enabled bool                  `json:"enabled"`
+	Rules   []ResponderRuleStatus `json:"rules"`
+}
+
+// ResponderRuleStatus holds the counters of a responder rule or service.
+type ResponderRuleStatus struct {
+	Name   string `json:"name"`
+	Hits   uint64 `json:"hits"`
+	Errors uint64 `json:"errors"`
+	Limit  int    `json:"limit"`
+	// Service is set for the services answering the ISO-TP servers.
+	Service bool `json:"service,omitempty"`
+}
+
+type ecuResponder struct {
+	path    string
+	profile *responder.Profile
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// LoadResponderProfile loads an ECU simulation profile from a JSON or YAML file and
+// enables it: received frames matching a rule are answered with its replies, and
+// the messages of the ISO-TP servers opened with OpenIsoTPServer by its services.
+// The profile replaces the one loaded before.
+func (a *App) LoadResponderProfile(path string) (ResponderStatus, error) {
+	path = strings.TrimSpace(path)
+	doc, err := os.ReadFile(path)
+	if err != nil {
+		return ResponderStatus{}, err
+	}
+	p, err := responder.Parse(doc)
+	if err != nil {
+		return ResponderStatus{}, fmt.Errorf("%s: %w", path, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &ecuResponder{path: path, profile: p, ctx: ctx, cancel: cancel}
+
+	a.responderOff.Store(false)
+	if old := a.responder.Swap(r); old != nil {
+		old.cancel()
+	}
+	return r.status(true), nil
+}
+
+// UnloadResponderProfile disables the ECU simulation and cancels the pending replies.
+func (a *App) UnloadResponderProfile() {
+	if old := a.responder.Swap(nil); old != nil {
+		old.cancel()
+	}
+}
+
+// SetResponderEnabled pauses or resumes the loaded profile.
+func (a *App) SetResponderEnabled(enabled bool) error {
+	r := a.responder.Load()
+	if r == nil {
+		return errors.New("no responder profile loaded")
+	}
+	a.responderOff.Store(!enabled)
+	return nil
+}
+
+// GetResponderStatus returns the loaded profile and the counters of its rules.
+func (a *App) GetResponderStatus() ResponderStatus {
+	r := a.responder.Load()
+	if r == nil {
+		return ResponderStatus{Rules: []ResponderRuleStatus{}}
+	}
+	return r.status(!a.responderOff.Load())
+}
+
+// ResetResponderCounters zeroes the rule counters, re-arming the rules with a limit.
+func (a *App) ResetResponderCounters() {
+	if r := a.responder.Load(); r != nil {
+		r.profile.ResetCounters()
+	}
+}
+
+// dispatchResponder answers a received frame with the replies of the matching rules.
+func (a *App) dispatchResponder(iface string, f *canbus.Frame) {
+	r := a.responder.Load()
+	if r == nil || a.responderOff.Load() {
+		return
+	}
+	for _, rule := range r.profile.Match(iface, f) {
+		ifaces, frames := rule.Frames(iface)
+		reply := func() {
+			if r.ctx.Err() != nil {
+				return
+			}
+			for i := range frames {
+				if err := a.send(ifaces[i], frames[i]); err != nil {
+					// report the first failure only, later requests usually fail the same way
+					if rule.CountError(); rule.Errors() == 1 {
+						a.emitError(fmt.Errorf("responder %s: %w", rule.Name, err))
+					}
+				}
+			}
+		}
+		if d := rule.Delay(); d > 0 {
+			time.AfterFunc(d, reply)
+		} else {
+			reply()
+		}
+	}
+}
+
+func (r *ecuResponder) status(enabled bool) ResponderStatus {
+	s := ResponderStatus{
+		Profile: r.profile.Name,
+		Path:    r.path,
+		Enabled: enabled,
+		Rules:   make([]ResponderRuleStatus, len(r.profile.Rules), len(r.profile.Rules)+len(r.profile.Services)),
+	}
+	for i := range r.profile.Rules {
+		rule := &r.profile.Rules[i]
+		s.Rules[i] = ResponderRuleStatus{
+			Name:   rule.Name,
+			Hits:   rule.Hits(),
+			Errors: rule.Errors(),
+			Limit:  rule.Limit,
+		}
+	}
+	for i := range r.profile.Services {
+		sv := &r.profile.Services[i]
+		s.Rules = append(s.Rules, ResponderRuleStatus{
+			Name:    sv.Name,
+			Hits:    sv.Hits(),
+			Errors:  sv.Errors(),
+			Limit:   sv.Limit,
+			Service: true,
+		})
+	}
+	return s
+}
@@ -0,0 +1,281 @@
+// Package responder simulates ECUs: rules answer received request frames with
+// reply frames, optionally after a delay. Profiles are loaded from JSON or YAML:
+//
+//	name: engine ECU
+//	interface: can0
+//	rules:
+//	  - name: extended session
+//	    request: {id: 0x7e0, data: "02 10 03"}
+//	    delayMs: 5
+//	    replies:
+//	      - {id: 0x7e8, data: "06 50 03 00 32 01 f4 00"}
+//
+// Services answer the reassembled requests received by the ISO-TP servers, for
+// diagnostic messages that do not fit into a frame:
+//
+//	services:
+//	  - name: read VIN
+//	    rxId: 0x7e0
+//	    request: "22 f1 90"
+//	    response: "62 f1 90 57 56 57 5a 5a 5a 31 4a 5a 58 57 30 30 30 30 30 31"
+//
+// IDs and data use the notation of the sequence package: numbers or hex strings.
+// The data of a request is a prefix of the payload.
+package responder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"canproject/canbus"
+	"canproject/sequence"
+)
+
+// Profile is a named set of rules.
+type Profile struct {
+	Name string `json:"name"`
+	// Interface restricts the rules that do not name one, empty for all interfaces.
+	Interface string `json:"interface,omitempty"`
+	Rules     []Rule `json:"rules"`
+	// Services answer the messages of the ISO-TP servers.
+	Services []Service `json:"services,omitempty"`
+}
+
+// Rule answers the frames matching Request with Replies.
+type Rule struct {
+	Name      string  `json:"name,omitempty"`
+	Interface string  `json:"interface,omitempty"`
+	Request   Request `json:"request"`
+	Replies   []Reply `json:"replies"`
+	// DelayMs delays the replies after the request.
+	DelayMs int `json:"delayMs,omitempty"`
+	// Limit stops the rule after that many matches, 0 for no limit.
+	Limit int `json:"limit,omitempty"`
+
+	hits   atomic.Uint64
+	errors atomic.Uint64
+}
+
+// Request selects the frames a rule answers.
+type Request struct {
+	ID sequence.ID `json:"id"`
+	// Mask selects the ID bits compared, all bits when not set.
+	Mask     *sequence.ID  `json:"mask,omitempty"`
+	Extended bool          `json:"extended,omitempty"`
+	Data     sequence.Data `json:"data,omitempty"`
+}
+
+// Service answers the ISO-TP messages starting with Request with Response.
+type Service struct {
+	Name      string `json:"name,omitempty"`
+	Interface string `json:"interface,omitempty"`
+	// RxID restricts the service to the servers receiving on that ID.
+	RxID     *sequence.ID  `json:"rxId,omitempty"`
+	Request  sequence.Data `json:"request"`
+	Response sequence.Data `json:"response"`
+	// DelayMs delays the response after the request.
+	DelayMs int `json:"delayMs,omitempty"`
+	// Limit stops the service after that many matches, 0 for no limit.
+	Limit int `json:"limit,omitempty"`
+
+	hits   atomic.Uint64
+	errors atomic.Uint64
+}
+
+// Reply is a frame sent in answer to a request. Without interface it is sent
+// on the interface the request was received on.
+type Reply = sequence.Send
+
+// Parse decodes a JSON or YAML profile.
+func Parse(doc []byte) (*Profile, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(doc, &v); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	p := &Profile{}
+	if err := json.Unmarshal(b, p); err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate checks the rules of the profile.
+func (p *Profile) Validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return errors.New("profile without name")
+	}
+	if len(p.Rules) == 0 && len(p.Services) == 0 {
+		return fmt.Errorf("profile %q has no rules", p.Name)
+	}
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if len(r.Replies) == 0 {
+			return fmt.Errorf("%s: no replies", r.Name)
+		}
+		for j := range r.Replies {
+			if _, err := r.Replies[j].Frame(); err != nil {
+				return fmt.Errorf("%s: reply %d: %w", r.Name, j+1, err)
+			}
+		}
+		if r.DelayMs < 0 || r.Limit < 0 {
+			return fmt.Errorf("%s: delay and limit must be >= 0", r.Name)
+		}
+	}
+	for i := range p.Services {
+		sv := &p.Services[i]
+		if sv.Name == "" {
+			sv.Name = fmt.Sprintf("service %d", i+1)
+		}
+		if len(sv.Request) == 0 || len(sv.Response) == 0 {
+			return fmt.Errorf("%s: request and response must not be empty", sv.Name)
+		}
+		if sv.DelayMs < 0 || sv.Limit < 0 {
+			return fmt.Errorf("%s: delay and limit must be >= 0", sv.Name)
+		}
+	}
+	return nil
+}
+
+// MatchMessage returns the first service answering the ISO-TP message data,
+// received on rxID on iface, and counts its hit. It returns nil when none does.
+func (p *Profile) MatchMessage(iface string, rxID uint32, data []byte) *Service {
+	for i := range p.Services {
+		sv := &p.Services[i]
+		if si := sv.iface(p); si != "" && si != iface {
+			continue
+		}
+		if sv.RxID != nil && uint32(*sv.RxID) != rxID {
+			continue
+		}
+		if len(sv.Request) > len(data) || string(data[:len(sv.Request)]) != string(sv.Request) {
+			continue
+		}
+		if n := sv.hits.Add(1); sv.Limit > 0 && n > uint64(sv.Limit) {
+			sv.hits.Add(^uint64(0))
+			continue
+		}
+		return sv
+	}
+	return nil
+}
+
+// Match returns the rules answering f, received on iface, and counts their hits.
+func (p *Profile) Match(iface string, f *canbus.Frame) []*Rule {
+	var rules []*Rule
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if ri := r.iface(p); ri != "" && ri != iface {
+			continue
+		}
+		if !r.Request.match(f) {
+			continue
+		}
+		if n := r.hits.Add(1); r.Limit > 0 && n > uint64(r.Limit) {
+			r.hits.Add(^uint64(0))
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// Frames returns the replies of the rule to a request received on iface, with
+// the interface each is sent on.
+func (r *Rule) Frames(iface string) ([]string, []canbus.Frame) {
+	ifaces := make([]string, len(r.Replies))
+	frames := make([]canbus.Frame, len(r.Replies))
+	for i := range r.Replies {
+		// the replies were validated by Parse
+		frames[i], _ = r.Replies[i].Frame()
+		ifaces[i] = iface
+		if ri := strings.TrimSpace(r.Replies[i].Interface); ri != "" {
+			ifaces[i] = ri
+		}
+	}
+	return ifaces, frames
+}
+
+// Delay returns the delay of the replies.
+func (r *Rule) Delay() time.Duration {
+	return time.Duration(r.DelayMs) * time.Millisecond
+}
+
+// Hits returns the number of requests the rule answered.
+func (r *Rule) Hits() uint64 { return r.hits.Load() }
+
+// Errors returns the number of replies that could not be sent.
+func (r *Rule) Errors() uint64 { return r.errors.Load() }
+
+// CountError records a reply that could not be sent.
+func (r *Rule) CountError() { r.errors.Add(1) }
+
+// Delay returns the delay of the response.
+func (s *Service) Delay() time.Duration {
+	return time.Duration(s.DelayMs) * time.Millisecond
+}
+
+// Hits returns the number of messages the service answered.
+func (s *Service) Hits() uint64 { return s.hits.Load() }
+
+// Errors returns the number of responses that could not be sent.
+func (s *Service) Errors() uint64 { return s.errors.Load() }
+
+// CountError records a response that could not be sent.
+func (s *Service) CountError() { s.errors.Add(1) }
+
+// ResetCounters zeroes the hits and errors of every rule and service,
+// re-arming the limited ones.
+func (p *Profile) ResetCounters() {
+	for i := range p.Rules {
+		p.Rules[i].hits.Store(0)
+		p.Rules[i].errors.Store(0)
+	}
+	for i := range p.Services {
+		p.Services[i].hits.Store(0)
+		p.Services[i].errors.Store(0)
+	}
+}
+
+func (r *Rule) iface(p *Profile) string {
+	if ri := strings.TrimSpace(r.Interface); ri != "" {
+		return ri
+	}
+	return strings.TrimSpace(p.Interface)
+}
+
+func (s *Service) iface(p *Profile) string {
+	if si := strings.TrimSpace(s.Interface); si != "" {
+		return si
+	}
+	return strings.TrimSpace(p.Interface)
+}
+
+func (q *Request) match(f *canbus.Frame) bool {
+	if f.IsError || f.IsRemote || f.IsExtended != q.Extended {
+		return false
+	}
+	mask := ^uint32(0)
+	if q.Mask != nil {
+		mask = uint32(*q.Mask)
+	}
+	if f.ID&mask != uint32(q.ID)&mask {
+		return false
+	}
+	return len(q.Data) <= int(f.Length) && string(f.Data[:len(q.Data)]) == string(q.Data)
+}
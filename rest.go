@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"canproject/apiserver"
+	"canproject/canbus"
+)
+
+// defaultRESTPort is the port of the REST API when RESTOptions.Port is 0.
+const defaultRESTPort = 8766
+
+// maxRESTBody bounds the size of a POST /frames request.
+const maxRESTBody = 1 << 20
+
+// RESTOptions configures the REST API started with StartRESTServer.
+type RESTOptions struct {
+	// Address is the address to listen on, 127.0.0.1 when empty; "0.0.0.0" makes
+	// the API reachable from other hosts.
+	Address string `json:"address"`
+	// Port is the TCP port, 0 for 8766.
+	Port int `json:"port"`
+	// Token, if set, must be sent by the clients as "Authorization: Bearer <token>".
+	Token string `json:"token"`
+	// Origins are the browser origins allowed besides the one of the API, "*"
+	// for any.
+	Origins []string `json:"origins"`
+	// Hosts are the host names accepted besides "localhost" and the IP
+	// addresses of the server, eg the name of the machine when Address is
+	// "0.0.0.0".
+	Hosts []string `json:"hosts"`
+}
+
+// RESTStatus describes the REST API.
+type RESTStatus struct {
+	Running bool `json:"running"`
+	// URL is the base URL of the API while it runs.
+	URL string `json:"url"`
+	// Requests and Frames count the requests served and the frames injected.
+	Requests uint64 `json:"requests"`
+	Frames   uint64 `json:"frames"`
+}
+
+// restFrame is a frame of a POST /frames request.
+type restFrame struct {
+	Interface string `json:"interface"`
+	ID        uint32 `json:"id"`
+	// Data is a list of bytes or a hex string like "01 02 03".
+	Data     json.RawMessage `json:"data"`
+	Extended bool            `json:"extended"`
+	FD       bool            `json:"fd"`
+	BRS      bool            `json:"brs"`
+}
+
+type restServer struct {
+	srv    *http.Server
+	url    string
+	status RESTStatus
+}
+
+// StartRESTServer starts a small HTTP API so scripts and tools can use the started
+// interfaces through the app:
+//
+//	POST /frames  sends a frame or a list of frames, eg
+//	              curl -H 'Content-Type: application/json' \
+//	                -d '{"interface":"can0","id":291,"data":"11 22"}' localhost:8766/frames
+//	GET  /stats   returns the statistics of the started interfaces, ?interface=can0 for one
+//	GET  /metrics returns the counters of the app in the Prometheus text format
+//	GET  /state   returns the whole state of the app, see GetEngineState
+//
+// The sent frames are logged and captured like the frames sent from the UI.
+// POST requests must be JSON, so browsers preflight them, and the requests of
+// web pages are accepted from opts.Origins only; the Host must be localhost,
+// an address of the server or one of opts.Hosts, against DNS rebinding.
+func (a *App) StartRESTServer(opts RESTOptions) (RESTStatus, error) {
+	if opts.Port < 0 || opts.Port > 65535 {
+		return RESTStatus{}, fmt.Errorf("invalid port %d", opts.Port)
+	}
+	addr := strings.TrimSpace(opts.Address)
+	if addr == "" {
+		addr = "127.0.0.1"
+	}
+	port := opts.Port
+	if port == 0 {
+		port = defaultRESTPort
+	}
+
+	a.restMu.Lock()
+	defer a.restMu.Unlock()
+	if a.rest != nil {
+		return RESTStatus{}, fmt.Errorf("REST API already running on %s", a.rest.url)
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(addr, strconv.Itoa(port)))
+	if err != nil {
+		return RESTStatus{}, err
+	}
+	rs := &restServer{url: "http://" + ln.Addr().String()}
+	rs.srv = &http.Server{Handler: a.restHandler(opts), ReadHeaderTimeout: 10 * time.Second}
+	a.rest = rs
+	go func() {
+		if err := rs.srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			a.emitError(fmt.Errorf("REST API: %w", err))
+		}
+	}()
+	return a.restStatus(), nil
+}
+
+// StopRESTServer stops the REST API.
+func (a *App) StopRESTServer() error {
+	a.restMu.Lock()
+	rs := a.rest
+	a.rest = nil
+	a.restMu.Unlock()
+	if rs == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return rs.srv.Shutdown(ctx)
+}
+
+// GetRESTStatus returns the state of the REST API.
+func (a *App) GetRESTStatus() RESTStatus {
+	a.restMu.Lock()
+	defer a.restMu.Unlock()
+	return a.restStatus()
+}
+
+// restStatus returns the status of the REST API, restMu must be held.
+func (a *App) restStatus() RESTStatus {
+	if a.rest == nil {
+		return RESTStatus{}
+	}
+	s := a.rest.status
+	s.Running, s.URL = true, a.rest.url
+	return s
+}
+
+// restHandler serves the REST API, opts.Token empty accepts every client.
+func (a *App) restHandler(opts RESTOptions) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /frames", a.restSendFrames)
+	mux.HandleFunc("GET /stats", a.restStats)
+	mux.HandleFunc("GET /metrics", a.restMetrics)
+	mux.HandleFunc("GET /state", a.restState)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !apiserver.CheckHost(r, opts.Hosts) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "invalid host"})
+			return
+		}
+		if !apiserver.CheckOrigin(r, opts.Origins) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "origin not allowed"})
+			return
+		}
+		if opts.Token != "" && !apiserver.CheckToken(r, opts.Token, false) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+			return
+		}
+		a.restMu.Lock()
+		if a.rest != nil {
+			a.rest.status.Requests++
+		}
+		a.restMu.Unlock()
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (a *App) restSendFrames(w http.ResponseWriter, r *http.Request) {
+	// a web page can send text/plain or form posts cross-site without a preflight
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mt != "application/json" {
+		writeJSON(w, http.StatusUnsupportedMediaType, map[string]string{"error": "Content-Type must be application/json"})
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, maxRESTBody)
+	var raw json.RawMessage
+	if err := json.NewDecoder(body).Decode(&raw); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	var frames []restFrame
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(raw, &frames); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	} else {
+		var f restFrame
+		if err := json.Unmarshal(raw, &f); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		frames = []restFrame{f}
+	}
+
+	sent := 0
+	for i, rf := range frames {
+		data, err := restData(rf.Data)
+		if err == nil {
+			err = a.sendFrame(rf.Interface, rf.ID, data, rf.Extended, rf.FD || len(data) > canbus.MaxDataLength, rf.BRS)
+		}
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]any{"error": fmt.Sprintf("frame %d: %v", i+1, err), "sent": sent})
+			a.countRESTFrames(sent)
+			return
+		}
+		sent++
+	}
+	a.countRESTFrames(sent)
+	writeJSON(w, http.StatusOK, map[string]int{"sent": sent})
+}
+
+func (a *App) restStats(w http.ResponseWriter, r *http.Request) {
+	if iface := r.URL.Query().Get("interface"); iface != "" {
+		s, err := a.GetStats(iface)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, s)
+		return
+	}
+	stats := []CANStats{}
+	for _, iface := range a.ActiveInterfaces() {
+		if s, err := a.GetStats(iface); err == nil {
+			stats = append(stats, s)
+		}
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (a *App) restState(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, a.GetEngineState())
+}
+
+func (a *App) countRESTFrames(n int) {
+	a.restMu.Lock()
+	if a.rest != nil {
+		a.rest.status.Frames += uint64(n)
+	}
+	a.restMu.Unlock()
+}
+
+// restData decodes the data of a restFrame, a list of bytes or a hex string.
+func restData(raw json.RawMessage) ([]byte, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		p, err := parseDataPattern(s)
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, len(p))
+		for i, b := range p {
+			if b < 0 {
+				return nil, fmt.Errorf("invalid data %q: wildcards are not allowed", s)
+			}
+			data[i] = byte(b)
+		}
+		return data, nil
+	}
+	var bytes []int
+	if err := json.Unmarshal(raw, &bytes); err != nil {
+		return nil, errors.New("data must be a list of bytes or a hex string")
+	}
+	data := make([]byte, len(bytes))
+	for i, b := range bytes {
+		if b < 0 || b > 255 {
+			return nil, fmt.Errorf("data[%d] = %d is not a byte", i, b)
+		}
+		data[i] = byte(b)
+	}
+	return data, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
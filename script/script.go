@@ -0,0 +1,311 @@
+// Package script runs Lua scripts on received CAN frames, to transform or filter
+// them or to answer them from Go without a round trip through the frontend.
+//
+// A script defines a global on_frame function which is called for every frame:
+//
+//	function on_frame(f)
+//	  if f.id == 0x7e0 and f.data[2] == 0x3e then
+//	    can.send(f.iface, 0x7e8, {0x02, 0x7e, 0x00})
+//	  end
+//	  if f.id == 0x100 then return false end -- hide the frame
+//	end
+//
+// The frame table has the fields iface, time (Unix seconds), id, extended, remote,
+// fd, brs and data (a list of bytes). on_frame returns nothing to keep the frame,
+// false to drop it or a frame table to replace it. The can module provides
+// can.send(iface, id, data [, extended]), can.log(...) and can.now().
+//
+// A script can also simulate a diagnostic ECU with a global on_isotp function,
+// called with the messages reassembled by the ISO-TP servers. It returns the
+// response as a list of bytes, or nothing to leave the message unanswered:
+//
+//	function on_isotp(m)
+//	  if m.data[1] == 0x3e then return {0x7e, 0x00} end
+//	end
+//
+// The message table has the fields iface, time, rx and tx (the CAN IDs the server
+// receives and answers on) and data. A script defines on_frame, on_isotp or both.
+//
+// Scripts only get the base, table, string and math libraries.
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"canproject/canbus"
+)
+
+// CallTimeout bounds the run time of one on_frame or on_isotp call.
+const CallTimeout = 100 * time.Millisecond
+
+// Host is what scripts can act on.
+type Host interface {
+	// Send transmits a frame on a started interface.
+	Send(iface string, f canbus.Frame) error
+	// Log reports a message of can.log.
+	Log(msg string)
+}
+
+// Script is a loaded Lua script. It is safe for concurrent use, calls are serialized.
+type Script struct {
+	mu      sync.Mutex
+	L       *lua.LState
+	onFrame *lua.LFunction
+	onISOTP *lua.LFunction
+}
+
+// Load compiles and runs the top level of the script src, name is the chunk name of error messages.
+func Load(name, src string, host Host) (*Script, error) {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	// the base library can load code from files
+	for _, fn := range []string{"dofile", "loadfile", "require"} {
+		L.SetGlobal(fn, lua.LNil)
+	}
+	L.SetGlobal("can", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"send": func(L *lua.LState) int { return luaSend(L, host) },
+		"log": func(L *lua.LState) int {
+			parts := make([]string, L.GetTop())
+			for i := range parts {
+				parts[i] = L.ToStringMeta(L.Get(i + 1)).String()
+			}
+			host.Log(strings.Join(parts, " "))
+			return 0
+		},
+		"now": func(L *lua.LState) int {
+			L.Push(lua.LNumber(float64(time.Now().UnixNano()) / 1e9))
+			return 1
+		},
+	}))
+
+	fn, err := L.Load(strings.NewReader(src), name)
+	if err != nil {
+		L.Close()
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), CallTimeout)
+	defer cancel()
+	L.SetContext(ctx)
+	L.Push(fn)
+	err = L.PCall(0, 0, nil)
+	L.RemoveContext()
+	if err != nil {
+		L.Close()
+		return nil, err
+	}
+	onFrame, _ := L.GetGlobal("on_frame").(*lua.LFunction)
+	onISOTP, _ := L.GetGlobal("on_isotp").(*lua.LFunction)
+	if onFrame == nil && onISOTP == nil {
+		L.Close()
+		return nil, fmt.Errorf("%s: no on_frame or on_isotp function", name)
+	}
+	return &Script{L: L, onFrame: onFrame, onISOTP: onISOTP}, nil
+}
+
+// HandleFrame calls on_frame with a frame received on iface at ts. It returns the
+// frame to show, which is f unless the script replaced it, and false when the
+// script dropped it.
+func (s *Script) HandleFrame(iface string, ts time.Time, f *canbus.Frame) (canbus.Frame, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.L == nil {
+		return *f, true, errors.New("script is closed")
+	}
+	if s.onFrame == nil {
+		return *f, true, nil
+	}
+
+	L := s.L
+	ctx, cancel := context.WithTimeout(context.Background(), CallTimeout)
+	defer cancel()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	if err := L.CallByParam(lua.P{Fn: s.onFrame, NRet: 1, Protect: true}, frameTable(L, iface, ts, f)); err != nil {
+		return *f, true, err
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+	switch ret := ret.(type) {
+	case *lua.LNilType:
+		return *f, true, nil
+	case lua.LBool:
+		return *f, bool(ret), nil
+	case *lua.LTable:
+		out, err := tableFrame(ret, f)
+		if err != nil {
+			return *f, true, fmt.Errorf("on_frame result: %w", err)
+		}
+		return out, true, nil
+	default:
+		return *f, true, fmt.Errorf("on_frame returned a %s, want nothing, a boolean or a frame", ret.Type())
+	}
+}
+
+// HandleMessage calls on_isotp with a message received at ts by the ISO-TP
+// server receiving on rxID and answering on txID on iface. It returns the
+// response, nil when the script does not answer.
+func (s *Script) HandleMessage(iface string, ts time.Time, rxID, txID uint32, data []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.L == nil {
+		return nil, errors.New("script is closed")
+	}
+	if s.onISOTP == nil {
+		return nil, nil
+	}
+
+	L := s.L
+	ctx, cancel := context.WithTimeout(context.Background(), CallTimeout)
+	defer cancel()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	m := L.CreateTable(0, 5)
+	m.RawSetString("iface", lua.LString(iface))
+	m.RawSetString("time", lua.LNumber(float64(ts.UnixNano())/1e9))
+	m.RawSetString("rx", lua.LNumber(rxID))
+	m.RawSetString("tx", lua.LNumber(txID))
+	t := L.CreateTable(len(data), 0)
+	for _, b := range data {
+		t.Append(lua.LNumber(b))
+	}
+	m.RawSetString("data", t)
+	if err := L.CallByParam(lua.P{Fn: s.onISOTP, NRet: 1, Protect: true}, m); err != nil {
+		return nil, err
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+	switch ret := ret.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case *lua.LTable:
+		resp := make([]byte, ret.Len())
+		for i := range resp {
+			v, ok := ret.RawGetInt(i + 1).(lua.LNumber)
+			if !ok || v < 0 || v > 255 {
+				return nil, fmt.Errorf("on_isotp result: data[%d] is not a byte", i+1)
+			}
+			resp[i] = byte(v)
+		}
+		if len(resp) == 0 {
+			return nil, nil
+		}
+		return resp, nil
+	default:
+		return nil, fmt.Errorf("on_isotp returned a %s, want nothing or a list of bytes", ret.Type())
+	}
+}
+
+// Close releases the Lua state.
+func (s *Script) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.L != nil {
+		s.L.Close()
+		s.L = nil
+	}
+}
+
+func luaSend(L *lua.LState, host Host) int {
+	iface := L.CheckString(1)
+	id := L.CheckInt64(2)
+	data := L.CheckTable(3)
+	extended := L.OptBool(4, false)
+
+	f := canbus.Frame{ID: uint32(id), IsExtended: extended}
+	if err := setData(&f, data); err != nil {
+		L.ArgError(3, err.Error())
+		return 0
+	}
+	if err := f.Validate(); err != nil {
+		L.RaiseError("can.send: %v", err)
+		return 0
+	}
+	if err := host.Send(iface, f); err != nil {
+		L.RaiseError("can.send: %v", err)
+	}
+	return 0
+}
+
+func frameTable(L *lua.LState, iface string, ts time.Time, f *canbus.Frame) *lua.LTable {
+	t := L.CreateTable(0, 8)
+	t.RawSetString("iface", lua.LString(iface))
+	t.RawSetString("time", lua.LNumber(float64(ts.UnixNano())/1e9))
+	t.RawSetString("id", lua.LNumber(f.ID))
+	t.RawSetString("extended", lua.LBool(f.IsExtended))
+	t.RawSetString("remote", lua.LBool(f.IsRemote))
+	t.RawSetString("fd", lua.LBool(f.IsFD))
+	t.RawSetString("brs", lua.LBool(f.BRS))
+	payload := f.Payload()
+	data := L.CreateTable(len(payload), 0)
+	for _, b := range payload {
+		data.Append(lua.LNumber(b))
+	}
+	t.RawSetString("data", data)
+	return t
+}
+
+// tableFrame converts a frame table, the fields it does not set are copied from f.
+func tableFrame(t *lua.LTable, f *canbus.Frame) (canbus.Frame, error) {
+	out := *f
+	if v, ok := t.RawGetString("id").(lua.LNumber); ok {
+		out.ID = uint32(v)
+	}
+	for field, dst := range map[string]*bool{"extended": &out.IsExtended, "remote": &out.IsRemote, "fd": &out.IsFD, "brs": &out.BRS} {
+		if v, ok := t.RawGetString(field).(lua.LBool); ok {
+			*dst = bool(v)
+		}
+	}
+	if data, ok := t.RawGetString("data").(*lua.LTable); ok {
+		if err := setData(&out, data); err != nil {
+			return canbus.Frame{}, err
+		}
+	}
+	if err := out.Validate(); err != nil {
+		return canbus.Frame{}, err
+	}
+	return out, nil
+}
+
+func setData(f *canbus.Frame, data *lua.LTable) error {
+	n := data.Len()
+	if n > len(f.Data) {
+		return fmt.Errorf("payload of %d bytes is too long", n)
+	}
+	f.Data = [len(f.Data)]byte{}
+	for i := 1; i <= n; i++ {
+		v, ok := data.RawGetInt(i).(lua.LNumber)
+		if !ok || v < 0 || v > 255 {
+			return fmt.Errorf("data[%d] is not a byte", i)
+		}
+		f.Data[i-1] = byte(v)
+	}
+	f.Length = uint8(n)
+	if n > canbus.MaxDataLength {
+		f.IsFD = true
+	}
+	if f.IsFD {
+		f.Length = uint8(canbus.PaddedLength(n))
+	}
+	return nil
+}
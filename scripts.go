@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"canproject/canbus"
+	"canproject/script"
+)
+
+// ScriptInfo describes a loaded frame script.
+type ScriptInfo struct {
+	Handle int    `json:"handle"`
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	// Interface is the interface whose frames the script sees, empty for all.
+	Interface string `json:"interface"`
+	Frames    uint64 `json:"frames"`
+	Dropped   uint64 `json:"dropped"`
+	// Error is the error that stopped the script.
+	Error string `json:"error,omitempty"`
+}
+
+// ScriptLogEvent is a can.log message of a script, emitted on "script:log".
+type ScriptLogEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Handle    int       `json:"handle"`
+	Name      string    `json:"name"`
+	Message   string    `json:"message"`
+}
+
+type frameScript struct {
+	handle  int
+	name    string
+	path    string
+	iface   string
+	script  *script.Script
+	frames  atomic.Uint64
+	dropped atomic.Uint64
+	// err is set when the script failed, it is not called anymore.
+	err atomic.Pointer[string]
+}
+
+// scriptHost gives a script access to the started interfaces.
+type scriptHost struct {
+	a *App
+	s *frameScript
+}
+
+func (h scriptHost) Send(iface string, f canbus.Frame) error {
+	return h.a.send(iface, f)
+}
+
+func (h scriptHost) Log(msg string) {
+	h.a.emit("script:log", ScriptLogEvent{
+		Timestamp: time.Now(),
+		Handle:    h.s.handle,
+		Name:      h.s.name,
+		Message:   msg,
+	})
+}
+
+// LoadScript loads a Lua script whose on_frame function is called for every frame
+// received on iface, or on all interfaces when iface is empty, and returns a handle
+// for UnloadScript. on_frame can transform or drop the frame shown by "can:frame"
+// and the signal decoding, and send frames; the protocol decoders see the frames
+// as received. Scripts run in the load order. A script defining on_isotp answers
+// the messages of the ISO-TP servers opened with OpenIsoTPServer.
+func (a *App) LoadScript(path string, iface string) (int, error) {
+	path = strings.TrimSpace(path)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	fs := &frameScript{
+		name:  filepath.Base(path),
+		path:  path,
+		iface: strings.TrimSpace(iface),
+	}
+	s, err := script.Load(fs.name, string(src), scriptHost{a: a, s: fs})
+	if err != nil {
+		return 0, err
+	}
+	fs.script = s
+
+	a.scriptMu.Lock()
+	a.nextScript++
+	fs.handle = a.nextScript
+	a.scripts = append(a.scripts, fs)
+	a.scriptMu.Unlock()
+	return fs.handle, nil
+}
+
+// UnloadScript removes a loaded script.
+func (a *App) UnloadScript(handle int) error {
+	a.scriptMu.Lock()
+	var fs *frameScript
+	for i, s := range a.scripts {
+		if s.handle == handle {
+			fs = s
+			a.scripts = append(a.scripts[:i:i], a.scripts[i+1:]...)
+			break
+		}
+	}
+	a.scriptMu.Unlock()
+
+	if fs == nil {
+		return fmt.Errorf("no script with handle %d", handle)
+	}
+	fs.script.Close()
+	return nil
+}
+
+// ListScripts returns the loaded scripts by handle.
+func (a *App) ListScripts() []ScriptInfo {
+	a.scriptMu.Lock()
+	scripts := a.scripts
+	a.scriptMu.Unlock()
+
+	infos := make([]ScriptInfo, len(scripts))
+	for i, s := range scripts {
+		infos[i] = ScriptInfo{
+			Handle:    s.handle,
+			Name:      s.name,
+			Path:      s.path,
+			Interface: s.iface,
+			Frames:    s.frames.Load(),
+			Dropped:   s.dropped.Load(),
+		}
+		if err := s.err.Load(); err != nil {
+			infos[i].Error = *err
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Handle < infos[j].Handle })
+	return infos
+}
+
+// runScripts passes a received frame through the scripts of iface. It returns the
+// frame to show and false when a script dropped it.
+func (a *App) runScripts(iface string, ts time.Time, f *canbus.Frame) (canbus.Frame, bool) {
+	a.scriptMu.Lock()
+	scripts := a.scripts
+	a.scriptMu.Unlock()
+
+	shown := *f
+	for _, s := range scripts {
+		if (s.iface != "" && s.iface != iface) || s.err.Load() != nil {
+			continue
+		}
+		s.frames.Add(1)
+		out, keep, err := s.script.HandleFrame(iface, ts, &shown)
+		if err != nil {
+			// a failing script usually fails for every frame, stop it
+			msg := err.Error()
+			s.err.Store(&msg)
+			a.emitError(fmt.Errorf("script %s stopped: %w", s.name, err))
+			continue
+		}
+		if !keep {
+			s.dropped.Add(1)
+			return shown, false
+		}
+		shown = out
+	}
+	return shown, true
+}
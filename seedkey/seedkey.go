@@ -0,0 +1,233 @@
+// Package seedkey computes the keys of UDS SecurityAccess seeds with pluggable
+// algorithms: a Lua function or an external program.
+//
+// A Lua algorithm defines a global key function which gets the security level and
+// the seed as a list of bytes and returns the key the same way:
+//
+//	function key(level, seed)
+//	  local k = {}
+//	  for i, b in ipairs(seed) do k[i] = bit.bxor(b, 0x5a) end
+//	  return k
+//	end
+//
+// Lua 5.1 has no bitwise operators, the bit module provides band, bor, bxor, bnot,
+// lshift and rshift on 32-bit unsigned integers.
+//
+// An external program is run with the level and the seed in hex as arguments, eg
+// "keygen 1 1A2B3C4D", and prints the key in hex on its standard output. Vendor
+// seed-key libraries can be wrapped this way.
+package seedkey
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Timeout bounds the computation of one key.
+const Timeout = 5 * time.Second
+
+// Algorithm computes the key of a seed for a security level.
+type Algorithm interface {
+	Key(level byte, seed []byte) ([]byte, error)
+	Close()
+}
+
+// Load returns the algorithm of path: a Lua script when it ends with .lua,
+// an external program otherwise.
+func Load(path string) (Algorithm, error) {
+	if strings.EqualFold(filepath.Ext(path), ".lua") {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return LoadScript(filepath.Base(path), string(src))
+	}
+	prog, err := exec.LookPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{Path: prog}, nil
+}
+
+// Program is an algorithm run as an external program.
+type Program struct {
+	Path string
+}
+
+// Key runs the program with level and seed and parses the key it prints.
+func (p *Program) Key(level byte, seed []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.Path, strconv.Itoa(int(level)), strings.ToUpper(hex.EncodeToString(seed)))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.Join(strings.Fields(string(out)), ""))
+	if err != nil {
+		return nil, fmt.Errorf("key output: %w", err)
+	}
+	if len(key) == 0 {
+		return nil, errors.New("program printed no key")
+	}
+	return key, nil
+}
+
+// Close does nothing, a program runs once per key.
+func (p *Program) Close() {}
+
+// Script is an algorithm defined by the key function of a Lua script.
+// It is safe for concurrent use, calls are serialized.
+type Script struct {
+	mu  sync.Mutex
+	L   *lua.LState
+	key *lua.LFunction
+}
+
+// LoadScript compiles and runs the top level of the script src, name is the chunk
+// name of error messages.
+func LoadScript(name, src string) (*Script, error) {
+	L := newState()
+	fn, err := L.Load(strings.NewReader(src), name)
+	if err != nil {
+		L.Close()
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	L.SetContext(ctx)
+	L.Push(fn)
+	err = L.PCall(0, 0, nil)
+	L.RemoveContext()
+	if err != nil {
+		L.Close()
+		return nil, err
+	}
+	key, ok := L.GetGlobal("key").(*lua.LFunction)
+	if !ok {
+		L.Close()
+		return nil, fmt.Errorf("%s: no key function", name)
+	}
+	return &Script{L: L, key: key}, nil
+}
+
+// Key calls the key function of the script.
+func (s *Script) Key(level byte, seed []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.L == nil {
+		return nil, errors.New("script is closed")
+	}
+
+	L := s.L
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	t := L.CreateTable(len(seed), 0)
+	for _, b := range seed {
+		t.Append(lua.LNumber(b))
+	}
+	if err := L.CallByParam(lua.P{Fn: s.key, NRet: 1, Protect: true}, lua.LNumber(level), t); err != nil {
+		return nil, err
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+	list, ok := ret.(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("key returned a %s, want a list of bytes", ret.Type())
+	}
+	key := make([]byte, list.Len())
+	for i := range key {
+		v, ok := list.RawGetInt(i + 1).(lua.LNumber)
+		if !ok || v < 0 || v > 255 {
+			return nil, fmt.Errorf("key[%d] is not a byte", i+1)
+		}
+		key[i] = byte(v)
+	}
+	if len(key) == 0 {
+		return nil, errors.New("key returned an empty list")
+	}
+	return key, nil
+}
+
+// Close releases the Lua state.
+func (s *Script) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.L != nil {
+		s.L.Close()
+		s.L = nil
+	}
+}
+
+// newState returns a Lua state with the base, table, string and math libraries
+// and the bit module.
+func newState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	// the base library can load code from files
+	for _, fn := range []string{"dofile", "loadfile", "require"} {
+		L.SetGlobal(fn, lua.LNil)
+	}
+
+	u32 := func(L *lua.LState, n int) uint32 { return uint32(int64(L.CheckNumber(n))) }
+	fold := func(op func(a, b uint32) uint32) lua.LGFunction {
+		return func(L *lua.LState) int {
+			v := u32(L, 1)
+			for i := 2; i <= L.GetTop(); i++ {
+				v = op(v, u32(L, i))
+			}
+			L.Push(lua.LNumber(v))
+			return 1
+		}
+	}
+	L.SetGlobal("bit", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"band": fold(func(a, b uint32) uint32 { return a & b }),
+		"bor":  fold(func(a, b uint32) uint32 { return a | b }),
+		"bxor": fold(func(a, b uint32) uint32 { return a ^ b }),
+		"bnot": func(L *lua.LState) int {
+			L.Push(lua.LNumber(^u32(L, 1)))
+			return 1
+		},
+		"lshift": func(L *lua.LState) int {
+			L.Push(lua.LNumber(u32(L, 1) << (u32(L, 2) & 31)))
+			return 1
+		},
+		"rshift": func(L *lua.LState) int {
+			L.Push(lua.LNumber(u32(L, 1) >> (u32(L, 2) & 31)))
+			return 1
+		},
+	}))
+	return L
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"canproject/canbus"
+)
+
+const (
+	// selfTestFrames is the number of frames SelfTest sends.
+	selfTestFrames = 10000
+	// selfTestID is the extended ID of the test frames, unlikely to be used on
+	// a vehicle bus.
+	selfTestID = 0x1fffff00
+	// selfTestSettle is how long SelfTest waits for more frames once none was
+	// received for that long after the last one was sent.
+	selfTestSettle = time.Second
+)
+
+// SelfTestResult is the result of SelfTest.
+type SelfTestResult struct {
+	Sender   string `json:"sender"`
+	Receiver string `json:"receiver"`
+	Sent     int    `json:"sent"`
+	Received int    `json:"received"`
+	Lost     int    `json:"lost"`
+	// Duplicates are frames received more than once, OutOfOrder the ones
+	// received after a frame sent later.
+	Duplicates int `json:"duplicates"`
+	OutOfOrder int `json:"outOfOrder"`
+	// DurationMs is the time to send the frames, the rates are over it.
+	DurationMs        float64 `json:"durationMs"`
+	TxFramesPerSecond float64 `json:"txFramesPerSecond"`
+	RxFramesPerSecond float64 `json:"rxFramesPerSecond"`
+	// The latencies are from the write of a frame to its reception.
+	LatencyMinUs  float64         `json:"latencyMinUs"`
+	LatencyMeanUs float64         `json:"latencyMeanUs"`
+	LatencyP50Us  float64         `json:"latencyP50Us"`
+	LatencyP90Us  float64         `json:"latencyP90Us"`
+	LatencyP99Us  float64         `json:"latencyP99Us"`
+	LatencyMaxUs  float64         `json:"latencyMaxUs"`
+	Histogram     []LatencyBucket `json:"histogram"`
+	// Error is why the sender stopped early, empty if all the frames were sent.
+	Error string `json:"error,omitempty"`
+}
+
+// LatencyBucket counts the frames received with a latency up to UpToUs, and
+// above the previous bucket. The last bucket has UpToUs 0 and counts the rest.
+type LatencyBucket struct {
+	UpToUs float64 `json:"upToUs"`
+	Count  int     `json:"count"`
+}
+
+// selfTestRun is the self-test in progress.
+type selfTestRun struct {
+	receiver string
+	nonce    uint32
+	// sent are the write times of the frames by sequence number, in Unix
+	// nanoseconds, 0 until written.
+	sent []atomic.Int64
+
+	mu         sync.Mutex
+	seen       []bool
+	latencies  []time.Duration
+	duplicates int
+	outOfOrder int
+	highest    int
+	lastRx     time.Time
+}
+
+// SelfTest sends 10000 frames as fast as possible from the started interface
+// ifaceA and receives them on ifaceB, measuring the achieved throughput, the
+// latency distribution and the frames lost. ifaceA and ifaceB may be the same
+// SocketCAN interface, eg a vcan, the frames being received back through the
+// kernel loopback. The test frames use the extended ID 0x1FFFFF00 and are
+// neither logged, captured nor emitted.
+func (a *App) SelfTest(ifaceA, ifaceB string) (SelfTestResult, error) {
+	ifaceA, ifaceB = strings.TrimSpace(ifaceA), strings.TrimSpace(ifaceB)
+	tx, err := a.txConn(ifaceA, false)
+	if err != nil {
+		return SelfTestResult{}, err
+	}
+	if _, err := a.session(ifaceB); err != nil {
+		return SelfTestResult{}, err
+	}
+	var nonce [4]byte
+	_, _ = rand.Read(nonce[:])
+	run := &selfTestRun{
+		receiver:  ifaceB,
+		nonce:     binary.BigEndian.Uint32(nonce[:]),
+		sent:      make([]atomic.Int64, selfTestFrames),
+		seen:      make([]bool, selfTestFrames),
+		latencies: make([]time.Duration, 0, selfTestFrames),
+		highest:   -1,
+	}
+	if !a.selfTest.CompareAndSwap(nil, run) {
+		return SelfTestResult{}, errors.New("a self-test is already running")
+	}
+	defer a.selfTest.Store(nil)
+
+	if ifaceA == ifaceB {
+		// the session does not receive the frames it sends, a second socket does
+		rx, err := canbus.Dial(ifaceB)
+		if err != nil {
+			return SelfTestResult{}, fmt.Errorf("a self-test on one interface needs a SocketCAN interface: %w", err)
+		}
+		done := make(chan struct{})
+		go run.readLoop(rx, done)
+		defer func() {
+			_ = rx.Close()
+			<-done
+		}()
+	}
+
+	res := SelfTestResult{Sender: ifaceA, Receiver: ifaceB}
+	start := time.Now()
+	for seq := 0; seq < selfTestFrames; seq++ {
+		f := canbus.Frame{ID: selfTestID, IsExtended: true, Length: 8}
+		binary.BigEndian.PutUint32(f.Data[0:], uint32(seq))
+		binary.BigEndian.PutUint32(f.Data[4:], run.nonce)
+		run.sent[seq].Store(time.Now().UnixNano())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err := tx.WriteFrame(ctx, f)
+		cancel()
+		if err != nil {
+			run.sent[seq].Store(0)
+			res.Error = err.Error()
+			break
+		}
+		res.Sent++
+	}
+	elapsed := time.Since(start)
+	run.settle()
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	res.Received = len(run.latencies)
+	res.Lost = res.Sent - res.Received
+	res.Duplicates, res.OutOfOrder = run.duplicates, run.outOfOrder
+	res.DurationMs = milliseconds(elapsed)
+	if s := elapsed.Seconds(); s > 0 {
+		res.TxFramesPerSecond = float64(res.Sent) / s
+		res.RxFramesPerSecond = float64(res.Received) / s
+	}
+	latencyStats(&res, run.latencies)
+	return res, nil
+}
+
+// settle waits until all the frames sent were received, or none was for
+// selfTestSettle.
+func (r *selfTestRun) settle() {
+	last := time.Now()
+	for {
+		r.mu.Lock()
+		if r.lastRx.After(last) {
+			last = r.lastRx
+		}
+		complete := len(r.latencies) >= len(r.sent)
+		r.mu.Unlock()
+		if complete || time.Since(last) >= selfTestSettle {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// readLoop records the frames read from conn until it is closed.
+func (r *selfTestRun) readLoop(conn canbus.Bus, done chan struct{}) {
+	defer close(done)
+	frames := make([]canbus.Frame, canbus.MaxBatch)
+	infos := make([]canbus.RxInfo, canbus.MaxBatch)
+	for {
+		n, err := readFrames(conn, frames, infos)
+		if err != nil {
+			return
+		}
+		for i := 0; i < n; i++ {
+			r.record(infos[i], &frames[i])
+		}
+	}
+}
+
+// receiveSelfTest records a frame of the running self-test received on iface,
+// and reports whether f is one, to keep it out of the rest of the pipeline.
+func (a *App) receiveSelfTest(iface string, info canbus.RxInfo, f *canbus.Frame) bool {
+	run := a.selfTest.Load()
+	if run == nil || !run.match(f) {
+		return false
+	}
+	if iface == run.receiver && !info.Own {
+		run.record(info, f)
+	}
+	return true
+}
+
+func (r *selfTestRun) match(f *canbus.Frame) bool {
+	return f.ID == selfTestID && f.IsExtended && f.Length == 8 &&
+		binary.BigEndian.Uint32(f.Data[4:]) == r.nonce
+}
+
+func (r *selfTestRun) record(info canbus.RxInfo, f *canbus.Frame) {
+	if !r.match(f) {
+		return
+	}
+	seq := int(binary.BigEndian.Uint32(f.Data[0:]))
+	if seq >= len(r.sent) {
+		return
+	}
+	now := time.Now()
+	at := info.Time
+	if info.Source == canbus.TimestampHardware || at.IsZero() {
+		// the controller clock is not the one of the write times
+		at = now
+	}
+	latency := at.Sub(time.Unix(0, r.sent[seq].Load()))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastRx = now
+	if r.seen[seq] {
+		r.duplicates++
+		return
+	}
+	r.seen[seq] = true
+	if seq < r.highest {
+		r.outOfOrder++
+	}
+	r.highest = max(r.highest, seq)
+	r.latencies = append(r.latencies, max(latency, 0))
+}
+
+// latencyStats fills the latency statistics and histogram of res.
+func latencyStats(res *SelfTestResult, latencies []time.Duration) {
+	res.Histogram = []LatencyBucket{}
+	if len(latencies) == 0 {
+		return
+	}
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+	us := func(d time.Duration) float64 { return float64(d) / float64(time.Microsecond) }
+	pct := func(p float64) float64 { return us(sorted[int(p*float64(len(sorted)-1))]) }
+	var sum time.Duration
+	for _, l := range sorted {
+		sum += l
+	}
+	res.LatencyMinUs, res.LatencyMaxUs = us(sorted[0]), us(sorted[len(sorted)-1])
+	res.LatencyMeanUs = us(sum / time.Duration(len(sorted)))
+	res.LatencyP50Us, res.LatencyP90Us, res.LatencyP99Us = pct(0.5), pct(0.9), pct(0.99)
+
+	// buckets doubling from 50 µs to 102.4 ms
+	i := 0
+	for limit := 50 * time.Microsecond; limit <= 102400*time.Microsecond; limit *= 2 {
+		b := LatencyBucket{UpToUs: us(limit)}
+		for ; i < len(sorted) && sorted[i] <= limit; i++ {
+			b.Count++
+		}
+		res.Histogram = append(res.Histogram, b)
+	}
+	res.Histogram = append(res.Histogram, LatencyBucket{Count: len(sorted) - i})
+}
@@ -0,0 +1,162 @@
+package sequence
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+)
+
+// TimeoutError is returned by Run when a wait step which is not optional times out.
+type TimeoutError struct {
+	Step int
+	Wait *Wait
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("step %d: no frame %s within %s", e.Step, formatID(uint32(e.Wait.ID), e.Wait.Extended), e.Wait.Timeout())
+}
+
+// Runner executes sequences. The received frames must be passed to HandleFrame
+// for the wait steps.
+type Runner struct {
+	// Send transmits a frame on an interface.
+	Send func(iface string, f canbus.Frame) error
+	// Progress, if set, is called before each step with its number, 1 for the first
+	// step of the sequence in file order and the same number for each repetition.
+	Progress func(step int, st *Step)
+
+	mu      sync.Mutex
+	pending *pendingWait
+}
+
+type pendingWait struct {
+	iface   string
+	wait    *Wait
+	matched chan struct{}
+}
+
+// HandleFrame completes the running wait step if f, received on iface, matches it.
+func (r *Runner) HandleFrame(iface string, f *canbus.Frame) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p := r.pending; p != nil && p.iface == iface && p.wait.Match(f) {
+		close(p.matched)
+		r.pending = nil
+	}
+}
+
+// Run executes the steps of s until they are done, one fails or ctx is cancelled.
+func (r *Runner) Run(ctx context.Context, s *Sequence) error {
+	_, err := r.run(ctx, s, s.Steps, 1)
+	return err
+}
+
+func (r *Runner) run(ctx context.Context, s *Sequence, steps []Step, n int) (int, error) {
+	var armed *pendingWait
+	defer func() { r.disarm(armed) }()
+
+	for i := range steps {
+		st := &steps[i]
+		if st.Steps != nil {
+			end := n + (&Sequence{Steps: st.Steps}).Len()
+			for pass := 0; st.Repeat < 0 || pass < max(st.Repeat, 1); pass++ {
+				if _, err := r.run(ctx, s, st.Steps, n); err != nil {
+					return 0, err
+				}
+			}
+			n = end
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		if r.Progress != nil {
+			r.Progress(n, st)
+		}
+		var err error
+		switch {
+		case st.Send != nil:
+			// the response to a frame can be received before Send returns:
+			// watch for the frame of a following wait step before sending
+			if i+1 < len(steps) && steps[i+1].Wait != nil {
+				armed = r.arm(s, steps[i+1].Wait)
+			}
+			err = r.send(s, st.Send)
+		case st.Wait != nil:
+			p := armed
+			if p == nil {
+				p = r.arm(s, st.Wait)
+			}
+			armed = nil
+			err = r.wait(ctx, p, n)
+		default:
+			err = sleep(ctx, time.Duration(st.DelayMs)*time.Millisecond)
+		}
+		if err != nil {
+			if _, ok := err.(*TimeoutError); ok || ctx.Err() != nil {
+				return 0, err
+			}
+			return 0, fmt.Errorf("step %d: %w", n, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (r *Runner) send(s *Sequence, snd *Send) error {
+	f, err := snd.Frame()
+	if err != nil {
+		return err
+	}
+	return r.Send(s.iface(snd.Interface), f)
+}
+
+func (r *Runner) arm(s *Sequence, w *Wait) *pendingWait {
+	p := &pendingWait{iface: s.iface(w.Interface), wait: w, matched: make(chan struct{})}
+	r.mu.Lock()
+	r.pending = p
+	r.mu.Unlock()
+	return p
+}
+
+func (r *Runner) disarm(p *pendingWait) {
+	r.mu.Lock()
+	if p != nil && r.pending == p {
+		r.pending = nil
+	}
+	r.mu.Unlock()
+}
+
+func (r *Runner) wait(ctx context.Context, p *pendingWait, step int) error {
+	defer r.disarm(p)
+
+	w := p.wait
+	timer := time.NewTimer(w.Timeout())
+	defer timer.Stop()
+	select {
+	case <-p.matched:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		if w.Optional {
+			return nil
+		}
+		return &TimeoutError{Step: step, Wait: w}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
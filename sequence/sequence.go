@@ -0,0 +1,352 @@
+// Package sequence defines scripted transmit sequences: frames to send, delays,
+// waits for received frames and repeated blocks, loaded from JSON or YAML.
+//
+// A sequence file holds one sequence or a list of them:
+//
+//	name: unlock
+//	interface: can0
+//	steps:
+//	  - send: {id: 0x7e0, data: "02 10 03"}
+//	  - wait: {id: 0x7e8, timeoutMs: 500}
+//	  - delayMs: 100
+//	  - repeat: 3
+//	    steps:
+//	      - send: {id: 0x7e0, data: [2, 0x3e, 0]}
+//	      - delayMs: 1000
+//
+// IDs are numbers or hex strings ("7e8" or "0x7e8"), data is a list of bytes or
+// a hex string where spaces are ignored.
+package sequence
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"canproject/canbus"
+)
+
+// DefaultWaitTimeout is the timeout of wait steps that do not set one.
+const DefaultWaitTimeout = time.Second
+
+// Sequence is a named list of steps.
+type Sequence struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Interface is used by the steps that do not name one.
+	Interface string `json:"interface,omitempty"`
+	Steps     []Step `json:"steps"`
+}
+
+// Step is one action of a sequence; exactly one of Send, Wait, DelayMs or Steps is set.
+type Step struct {
+	Send    *Send `json:"send,omitempty"`
+	Wait    *Wait `json:"wait,omitempty"`
+	DelayMs int   `json:"delayMs,omitempty"`
+	// Steps are run Repeat times (once when Repeat is not set, until stopped when it is -1).
+	Repeat int    `json:"repeat,omitempty"`
+	Steps  []Step `json:"steps,omitempty"`
+}
+
+// Send transmits a frame.
+type Send struct {
+	Interface string `json:"interface,omitempty"`
+	ID        ID     `json:"id"`
+	Extended  bool   `json:"extended,omitempty"`
+	FD        bool   `json:"fd,omitempty"`
+	BRS       bool   `json:"brs,omitempty"`
+	Data      Data   `json:"data,omitempty"`
+}
+
+// Wait blocks until a matching frame is received.
+type Wait struct {
+	Interface string `json:"interface,omitempty"`
+	ID        ID     `json:"id"`
+	// Mask selects the ID bits compared, all bits when not set.
+	Mask     *ID  `json:"mask,omitempty"`
+	Extended bool `json:"extended,omitempty"`
+	// Data must be a prefix of the payload of the frame.
+	Data      Data `json:"data,omitempty"`
+	TimeoutMs int  `json:"timeoutMs,omitempty"`
+	// Optional continues the sequence when the timeout expires instead of failing it.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// ID is a CAN ID, a JSON number or hex string.
+type ID uint32
+
+// UnmarshalJSON accepts numbers and hex strings with an optional 0x prefix.
+func (id *ID) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n uint32
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("invalid ID %s", b)
+		}
+		*id = ID(n)
+		return nil
+	}
+	s = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "0x")
+	n, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return fmt.Errorf("invalid ID %q", s)
+	}
+	*id = ID(n)
+	return nil
+}
+
+// Data is a frame payload, a JSON list of bytes or a hex string.
+type Data []byte
+
+// UnmarshalJSON accepts lists of numbers and hex strings where spaces are ignored.
+func (d *Data) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var bytes []uint8
+		if err := json.Unmarshal(b, &bytes); err != nil {
+			return fmt.Errorf("invalid data %s", b)
+		}
+		*d = bytes
+		return nil
+	}
+	s = strings.Join(strings.Fields(s), "")
+	bytes, err := hex.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("invalid data %q", s)
+	}
+	*d = bytes
+	return nil
+}
+
+// MarshalJSON writes the payload as a list of numbers rather than base64.
+func (d Data) MarshalJSON() ([]byte, error) {
+	words := make([]uint32, len(d))
+	for i, v := range d {
+		words[i] = uint32(v)
+	}
+	return json.Marshal(words)
+}
+
+// Parse decodes the sequences of a JSON or YAML document.
+func Parse(doc []byte) ([]*Sequence, error) {
+	// YAML is a superset of JSON: decode it generically and use the JSON
+	// decoding of the types for both formats
+	var v interface{}
+	if err := yaml.Unmarshal(doc, &v); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var seqs []*Sequence
+	if _, ok := v.([]interface{}); ok {
+		err = json.Unmarshal(b, &seqs)
+	} else {
+		seqs = []*Sequence{{}}
+		err = json.Unmarshal(b, seqs[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, s := range seqs {
+		if err := s.Validate(); err != nil {
+			return nil, err
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("duplicate sequence %q", s.Name)
+		}
+		names[s.Name] = true
+	}
+	return seqs, nil
+}
+
+// Validate checks the steps of the sequence and that every step has an interface.
+func (s *Sequence) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("sequence without name")
+	}
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("sequence %q has no steps", s.Name)
+	}
+	n := 0
+	if err := s.validate(s.Steps, &n); err != nil {
+		return fmt.Errorf("sequence %q: %w", s.Name, err)
+	}
+	return nil
+}
+
+func (s *Sequence) validate(steps []Step, n *int) error {
+	for i := range steps {
+		st := &steps[i]
+		kinds := 0
+		for _, set := range []bool{st.Send != nil, st.Wait != nil, st.DelayMs != 0, st.Steps != nil} {
+			if set {
+				kinds++
+			}
+		}
+		if st.Steps == nil {
+			*n++
+		}
+		if kinds != 1 {
+			return fmt.Errorf("step %d: want exactly one of send, wait, delayMs or steps", *n)
+		}
+		switch {
+		case st.Send != nil:
+			if s.iface(st.Send.Interface) == "" {
+				return fmt.Errorf("step %d: no interface", *n)
+			}
+			if _, err := st.Send.Frame(); err != nil {
+				return fmt.Errorf("step %d: %w", *n, err)
+			}
+		case st.Wait != nil:
+			if s.iface(st.Wait.Interface) == "" {
+				return fmt.Errorf("step %d: no interface", *n)
+			}
+			if st.Wait.TimeoutMs < 0 {
+				return fmt.Errorf("step %d: negative timeout", *n)
+			}
+		case st.DelayMs < 0:
+			return fmt.Errorf("step %d: negative delay", *n)
+		case st.Steps != nil:
+			if st.Repeat < -1 {
+				return fmt.Errorf("repeat must be >= -1 (got %d)", st.Repeat)
+			}
+			if err := s.validate(st.Steps, n); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Len returns the number of send, wait and delay steps, counting repeated blocks once.
+func (s *Sequence) Len() int {
+	var count func([]Step) int
+	count = func(steps []Step) int {
+		n := 0
+		for i := range steps {
+			if steps[i].Steps != nil {
+				n += count(steps[i].Steps)
+			} else {
+				n++
+			}
+		}
+		return n
+	}
+	return count(s.Steps)
+}
+
+// SendStep is a send step of a sequence, see Sends.
+type SendStep struct {
+	// Step is the number of the step, as reported by Runner.Progress.
+	Step      int
+	Interface string
+	Frame     canbus.Frame
+}
+
+// Sends returns the frames of the send steps of a validated sequence in file
+// order, those of repeated blocks once.
+func (s *Sequence) Sends() []SendStep {
+	var sends []SendStep
+	n := 0
+	var walk func([]Step)
+	walk = func(steps []Step) {
+		for i := range steps {
+			st := &steps[i]
+			if st.Steps != nil {
+				walk(st.Steps)
+				continue
+			}
+			n++
+			if st.Send != nil {
+				if f, err := st.Send.Frame(); err == nil {
+					sends = append(sends, SendStep{Step: n, Interface: s.iface(st.Send.Interface), Frame: f})
+				}
+			}
+		}
+	}
+	walk(s.Steps)
+	return sends
+}
+
+func (s *Sequence) iface(name string) string {
+	if name = strings.TrimSpace(name); name != "" {
+		return name
+	}
+	return strings.TrimSpace(s.Interface)
+}
+
+// Frame returns the frame to transmit.
+func (s *Send) Frame() (canbus.Frame, error) {
+	f := canbus.Frame{
+		ID:         uint32(s.ID),
+		Length:     uint8(len(s.Data)),
+		IsExtended: s.Extended,
+		IsFD:       s.FD || len(s.Data) > canbus.MaxDataLength,
+		BRS:        s.BRS,
+	}
+	if f.IsFD {
+		f.Length = uint8(canbus.PaddedLength(len(s.Data)))
+	}
+	if len(s.Data) > len(f.Data) {
+		return canbus.Frame{}, fmt.Errorf("payload of %d bytes is too long", len(s.Data))
+	}
+	copy(f.Data[:], s.Data)
+	if err := f.Validate(); err != nil {
+		return canbus.Frame{}, err
+	}
+	return f, nil
+}
+
+// Match reports whether f is the frame waited for.
+func (w *Wait) Match(f *canbus.Frame) bool {
+	if f.IsError || f.IsExtended != w.Extended {
+		return false
+	}
+	mask := ^uint32(0)
+	if w.Mask != nil {
+		mask = uint32(*w.Mask)
+	}
+	if f.ID&mask != uint32(w.ID)&mask {
+		return false
+	}
+	return len(w.Data) <= int(f.Length) && string(f.Data[:len(w.Data)]) == string(w.Data)
+}
+
+// Timeout returns the timeout of the wait.
+func (w *Wait) Timeout() time.Duration {
+	if w.TimeoutMs == 0 {
+		return DefaultWaitTimeout
+	}
+	return time.Duration(w.TimeoutMs) * time.Millisecond
+}
+
+// String describes the step for progress reports, eg "send 7E0#021003".
+func (st *Step) String() string {
+	switch {
+	case st.Send != nil:
+		f, _ := st.Send.Frame()
+		return "send " + f.String()
+	case st.Wait != nil:
+		return fmt.Sprintf("wait for %s within %s", formatID(uint32(st.Wait.ID), st.Wait.Extended), st.Wait.Timeout())
+	case st.Steps != nil:
+		return fmt.Sprintf("repeat %d", st.Repeat)
+	default:
+		return fmt.Sprintf("delay %s", time.Duration(st.DelayMs)*time.Millisecond)
+	}
+}
+
+func formatID(id uint32, extended bool) string {
+	if extended {
+		return fmt.Sprintf("%08X", id)
+	}
+	return fmt.Sprintf("%03X", id)
+}
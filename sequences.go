@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"canproject/canbus"
+	"canproject/sequence"
+)
+
+// Sequence states reported in SequenceProgress.State.
+const (
+	sequenceRunning  = "running"
+	sequenceFinished = "finished"
+	sequenceFailed   = "failed"
+	sequenceStopped  = "stopped"
+)
+
+// SequenceInfo describes a loaded transmit sequence.
+type SequenceInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Interface   string `json:"interface,omitempty"`
+	Steps       int    `json:"steps"`
+	Running     bool   `json:"running"`
+}
+
+// SequenceProgress is emitted on "can:sequence" before each step and when a run ends.
+type SequenceProgress struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	// Step is the number of the current step, Steps the number of steps of the sequence.
+	Step  int `json:"step"`
+	Steps int `json:"steps"`
+	// Description describes the current step, eg "wait for 7E8 within 500ms".
+	Description string `json:"description"`
+	Error       string `json:"error,omitempty"`
+}
+
+type sequenceRun struct {
+	seq    *sequence.Sequence
+	runner *sequence.Runner
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu          sync.Mutex
+	step        int
+	description string
+}
+
+// LoadSequences reads the transmit sequences of a JSON or YAML file. Loaded
+// sequences replace the ones of the same name that are not running.
+func (a *App) LoadSequences(path string) ([]SequenceInfo, error) {
+	doc, err := os.ReadFile(strings.TrimSpace(path))
+	if err != nil {
+		return nil, err
+	}
+	seqs, err := sequence.Parse(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	a.seqMu.Lock()
+	defer a.seqMu.Unlock()
+	for _, s := range seqs {
+		if a.seqRuns[s.Name] != nil {
+			return nil, fmt.Errorf("sequence %q is running", s.Name)
+		}
+	}
+	if a.sequences == nil {
+		a.sequences = make(map[string]*sequence.Sequence)
+	}
+	infos := make([]SequenceInfo, len(seqs))
+	for i, s := range seqs {
+		a.sequences[s.Name] = s
+		infos[i] = sequenceInfo(s, false)
+	}
+	return infos, nil
+}
+
+// ListSequences returns the loaded sequences sorted by name.
+func (a *App) ListSequences() []SequenceInfo {
+	a.seqMu.Lock()
+	defer a.seqMu.Unlock()
+
+	infos := make([]SequenceInfo, 0, len(a.sequences))
+	for name, s := range a.sequences {
+		infos = append(infos, sequenceInfo(s, a.seqRuns[name] != nil))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// RunSequence starts a loaded sequence in the background. Its progress is
+// emitted on "can:sequence".
+func (a *App) RunSequence(name string) error {
+	a.seqMu.Lock()
+	s := a.sequences[name]
+	if s == nil {
+		a.seqMu.Unlock()
+		return fmt.Errorf("no sequence %q", name)
+	}
+	if a.seqRuns[name] != nil {
+		a.seqMu.Unlock()
+		return fmt.Errorf("sequence %q is already running", name)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &sequenceRun{
+		seq:    s,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	run.runner = &sequence.Runner{
+		Send: a.send,
+		Progress: func(step int, st *sequence.Step) {
+			run.mu.Lock()
+			run.step, run.description = step, st.String()
+			run.mu.Unlock()
+			a.emit("can:sequence", run.progress(sequenceRunning, nil))
+		},
+	}
+	if a.seqRuns == nil {
+		a.seqRuns = make(map[string]*sequenceRun)
+	}
+	a.seqRuns[name] = run
+	a.seqMu.Unlock()
+
+	go a.sequenceLoop(ctx, run)
+	return nil
+}
+
+// StopSequence aborts a running sequence.
+func (a *App) StopSequence(name string) error {
+	a.seqMu.Lock()
+	run := a.seqRuns[name]
+	a.seqMu.Unlock()
+
+	if run == nil {
+		return nil
+	}
+	run.cancel()
+	<-run.done
+	return nil
+}
+
+func (a *App) sequenceLoop(ctx context.Context, run *sequenceRun) {
+	err := run.runner.Run(ctx, run.seq)
+
+	a.seqMu.Lock()
+	delete(a.seqRuns, run.seq.Name)
+	a.seqMu.Unlock()
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		a.emit("can:sequence", run.progress(sequenceStopped, nil))
+	case err != nil:
+		a.emit("can:sequence", run.progress(sequenceFailed, err))
+		a.emitError(fmt.Errorf("sequence %s: %w", run.seq.Name, err))
+	default:
+		a.emit("can:sequence", run.progress(sequenceFinished, nil))
+	}
+	run.cancel()
+	close(run.done)
+}
+
+// dispatchSequences passes a received frame to the wait steps of the running sequences.
+func (a *App) dispatchSequences(iface string, f *canbus.Frame) {
+	a.seqMu.Lock()
+	defer a.seqMu.Unlock()
+	for _, run := range a.seqRuns {
+		run.runner.HandleFrame(iface, f)
+	}
+}
+
+func (run *sequenceRun) progress(state string, err error) SequenceProgress {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	p := SequenceProgress{
+		Name:        run.seq.Name,
+		State:       state,
+		Step:        run.step,
+		Steps:       run.seq.Len(),
+		Description: run.description,
+	}
+	if err != nil {
+		p.Error = err.Error()
+	}
+	return p
+}
+
+func sequenceInfo(s *sequence.Sequence, running bool) SequenceInfo {
+	return SequenceInfo{
+		Name:        s.Name,
+		Description: s.Description,
+		Interface:   s.Interface,
+		Steps:       s.Len(),
+		Running:     running,
+	}
+}
@@ -0,0 +1,352 @@
+// Package slcan drives CAN adapters speaking the LAWICEL serial line CAN (SLCAN)
+// ASCII protocol, such as CANable and USBtin, over a serial port.
+package slcan
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+
+	"canproject/canbus"
+)
+
+const (
+	// DefaultBaudRate is the serial baud rate used when the URL does not set one.
+	DefaultBaudRate = 115200
+	// DefaultBitrate is the CAN bitrate used when the URL does not set one.
+	DefaultBitrate = 500000
+
+	ackTimeout     = time.Second
+	receiveBacklog = 1024
+)
+
+// bitrates maps the CAN bitrates to the S0..S8 setup commands.
+var bitrates = map[int]int{
+	10000: 0, 20000: 1, 50000: 2, 100000: 3, 125000: 4,
+	250000: 5, 500000: 6, 800000: 7, 1000000: 8,
+}
+
+// Config describes how an adapter is opened.
+type Config struct {
+	// Port is the serial port, eg /dev/ttyACM0 or COM3.
+	Port     string
+	BaudRate int
+	// Bitrate is the CAN bitrate, one of the nine standard SLCAN bitrates.
+	Bitrate int
+	// ListenOnly opens the channel without acknowledging frames (L instead of O).
+	ListenOnly bool
+}
+
+// IsURL reports whether name is an SLCAN URL such as "slcan:///dev/ttyACM0?bitrate=500000".
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "slcan://")
+}
+
+// ParseURL decodes "slcan:///dev/ttyACM0" or "slcan://COM3" with the optional
+// query parameters bitrate, baud and listen.
+func ParseURL(rawURL string) (Config, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg := Config{
+		Port:     u.Host + u.Path,
+		BaudRate: DefaultBaudRate,
+		Bitrate:  DefaultBitrate,
+	}
+	if u.Scheme != "slcan" || cfg.Port == "" {
+		return Config{}, fmt.Errorf("invalid SLCAN URL %q, want slcan:///dev/ttyACM0?bitrate=500000", rawURL)
+	}
+	q := u.Query()
+	for key, dst := range map[string]*int{"bitrate": &cfg.Bitrate, "baud": &cfg.BaudRate} {
+		if v := q.Get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid %s in SLCAN URL %q", key, rawURL)
+			}
+			*dst = n
+		}
+	}
+	if v := q.Get("listen"); v != "" {
+		cfg.ListenOnly, err = strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid listen in SLCAN URL %q", rawURL)
+		}
+	}
+	return cfg, nil
+}
+
+// Conn is an open SLCAN channel. It implements canbus.Bus.
+type Conn struct {
+	rw io.ReadWriteCloser
+
+	frames chan canbus.Frame
+	// acks receives the answers to commands: true for CR, false for BEL.
+	acks   chan bool
+	closed chan struct{}
+	once   sync.Once
+	// readErr is set when the serial port failed, before closed is closed.
+	readErr error
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	filters []canbus.Filter
+}
+
+var _ canbus.Bus = (*Conn)(nil)
+
+// Dial opens the serial port of cfg and opens the CAN channel.
+func Dial(cfg Config) (*Conn, error) {
+	code, ok := bitrates[cfg.Bitrate]
+	if !ok {
+		return nil, fmt.Errorf("slcan: unsupported bitrate %d", cfg.Bitrate)
+	}
+	port, err := serial.Open(cfg.Port, &serial.Mode{BaudRate: cfg.BaudRate})
+	if err != nil {
+		return nil, fmt.Errorf("slcan: open %s: %w", cfg.Port, err)
+	}
+	c, err := Open(port, code, cfg.ListenOnly)
+	if err != nil {
+		return nil, fmt.Errorf("slcan %s: %w", cfg.Port, err)
+	}
+	return c, nil
+}
+
+// Open sets up an adapter connected through rw with the S<code> bitrate command and
+// opens the channel. rw is closed with the Conn.
+func Open(rw io.ReadWriteCloser, bitrateCode int, listenOnly bool) (*Conn, error) {
+	c := &Conn{
+		rw:     rw,
+		frames: make(chan canbus.Frame, receiveBacklog),
+		acks:   make(chan bool, 8),
+		closed: make(chan struct{}),
+	}
+	go c.readLoop()
+
+	// flush a partial command and close a channel left open by a previous session
+	_ = c.write("\r\r\r")
+	time.Sleep(50 * time.Millisecond)
+	c.drainAcks()
+	_ = c.command("C")
+	c.drainAcks()
+
+	open := "O"
+	if listenOnly {
+		open = "L"
+	}
+	for _, cmd := range []string{"S" + strconv.Itoa(bitrateCode), open} {
+		if err := c.command(cmd); err != nil {
+			_ = c.rw.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// command sends a command and waits for its answer.
+func (c *Conn) command(cmd string) error {
+	if err := c.write(cmd + "\r"); err != nil {
+		return err
+	}
+	timer := time.NewTimer(ackTimeout)
+	defer timer.Stop()
+	select {
+	case ok := <-c.acks:
+		if !ok {
+			return fmt.Errorf("command %s rejected", cmd)
+		}
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("no answer to command %s", cmd)
+	case <-c.closed:
+		return c.closeErr()
+	}
+}
+
+func (c *Conn) drainAcks() {
+	for {
+		select {
+		case <-c.acks:
+		default:
+			return
+		}
+	}
+}
+
+func (c *Conn) write(s string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := io.WriteString(c.rw, s)
+	return err
+}
+
+func (c *Conn) readLoop() {
+	r := bufio.NewReader(c.rw)
+	var line []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+		switch b {
+		case '\r', '\a':
+			c.handleLine(string(line), b == '\r')
+			line = line[:0]
+		default:
+			if len(line) < 64 {
+				line = append(line, b)
+			}
+		}
+	}
+}
+
+// handleLine processes a line terminated by CR (ok) or BEL.
+func (c *Conn) handleLine(line string, ok bool) {
+	if line != "" {
+		switch line[0] {
+		case 't', 'T', 'r', 'R':
+			if f, err := parseFrame(line); err == nil && c.accept(&f) {
+				select {
+				case c.frames <- f:
+				default:
+					// the application does not keep up, drop the frame
+				}
+			}
+			return
+		case 'z', 'Z':
+			// transmit acknowledge of adapters answering t/T with z/Z
+			return
+		}
+	}
+	select {
+	case c.acks <- ok:
+	default:
+	}
+}
+
+// parseFrame decodes tiiildd.., Tiiiiiiiildd.., riiil and Riiiiiiiil.
+func parseFrame(line string) (canbus.Frame, error) {
+	var f canbus.Frame
+	idLen := 3
+	switch line[0] {
+	case 'T':
+		f.IsExtended, idLen = true, 8
+	case 'r':
+		f.IsRemote = true
+	case 'R':
+		f.IsRemote, f.IsExtended, idLen = true, true, 8
+	}
+	if len(line) < 1+idLen+1 {
+		return canbus.Frame{}, errors.New("slcan: short frame")
+	}
+	id, err := strconv.ParseUint(line[1:1+idLen], 16, 32)
+	if err != nil {
+		return canbus.Frame{}, err
+	}
+	f.ID = uint32(id)
+	dlc := line[1+idLen] - '0'
+	if dlc > canbus.MaxDataLength {
+		return canbus.Frame{}, errors.New("slcan: invalid DLC")
+	}
+	f.Length = dlc
+	if !f.IsRemote {
+		data := line[2+idLen:]
+		if len(data) < 2*int(dlc) {
+			return canbus.Frame{}, errors.New("slcan: short frame data")
+		}
+		if _, err := hex.Decode(f.Data[:dlc], []byte(data[:2*int(dlc)])); err != nil {
+			return canbus.Frame{}, err
+		}
+	}
+	return f, f.Validate()
+}
+
+// ReadFrame blocks until the next frame is received.
+func (c *Conn) ReadFrame() (canbus.Frame, error) {
+	select {
+	case f := <-c.frames:
+		return f, nil
+	case <-c.closed:
+		return canbus.Frame{}, c.closeErr()
+	}
+}
+
+// WriteFrame transmits a classic frame.
+func (c *Conn) WriteFrame(ctx context.Context, f canbus.Frame) error {
+	if f.IsFD {
+		return errors.New("slcan: CAN FD frames are not supported")
+	}
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	var cmd string
+	switch {
+	case f.IsRemote && f.IsExtended:
+		cmd = fmt.Sprintf("R%08X%d", f.ID, f.Length)
+	case f.IsRemote:
+		cmd = fmt.Sprintf("r%03X%d", f.ID, f.Length)
+	case f.IsExtended:
+		cmd = fmt.Sprintf("T%08X%d%X", f.ID, f.Length, f.Payload())
+	default:
+		cmd = fmt.Sprintf("t%03X%d%X", f.ID, f.Length, f.Payload())
+	}
+	select {
+	case <-c.closed:
+		return c.closeErr()
+	default:
+	}
+	return c.write(cmd + "\r")
+}
+
+// SetFilters filters the received frames on the host side.
+func (c *Conn) SetFilters(filters []canbus.Filter) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filters = append([]canbus.Filter{}, filters...)
+	return nil
+}
+
+func (c *Conn) accept(f *canbus.Frame) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return canbus.MatchAny(c.filters, f)
+}
+
+// Close closes the CAN channel and the serial port.
+func (c *Conn) Close() error {
+	select {
+	case <-c.closed:
+	default:
+		_ = c.write("C\r")
+	}
+	c.shutdown(nil)
+	return c.rw.Close()
+}
+
+func (c *Conn) shutdown(err error) {
+	c.once.Do(func() {
+		c.readErr = err
+		close(c.closed)
+	})
+}
+
+// closeErr is the error of operations on a closed connection.
+func (c *Conn) closeErr() error {
+	if c.readErr != nil && !errors.Is(c.readErr, io.EOF) {
+		return fmt.Errorf("slcan: %w", c.readErr)
+	}
+	return net.ErrClosed
+}
@@ -0,0 +1,414 @@
+// Package socketcand is a client of the socketcand ASCII protocol, which exposes the
+// SocketCAN interfaces of a remote host (eg a Raspberry Pi) over TCP.
+//
+// The client opens the bus in raw mode, reconnects when the connection drops and
+// measures the round trip time with echo commands.
+package socketcand
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+)
+
+const (
+	// DefaultPort is the TCP port socketcand listens on.
+	DefaultPort = "29536"
+
+	dialTimeout    = 5 * time.Second
+	echoInterval   = 2 * time.Second
+	minBackoff     = 500 * time.Millisecond
+	maxBackoff     = 10 * time.Second
+	receiveBacklog = 1024
+)
+
+// Status is the state of the connection to the server.
+type Status struct {
+	Connected bool
+	// Latency is the last echo round trip time.
+	Latency    time.Duration
+	Reconnects int
+	// Err is the error that dropped the connection, if any.
+	Err error
+}
+
+// Option configures a Conn opened with Dial.
+type Option func(*Conn)
+
+// WithStatusHandler returns an Option which calls fn when the connection drops,
+// is restored or a new latency is measured. fn must not block.
+func WithStatusHandler(fn func(Status)) Option {
+	return func(c *Conn) {
+		c.onStatus = fn
+	}
+}
+
+// Conn is a connection to a bus of a socketcand server. It implements canbus.Bus.
+type Conn struct {
+	addr     string
+	bus      string
+	onStatus func(Status)
+
+	frames chan canbus.Frame
+	closed chan struct{}
+	once   sync.Once
+	// writeMu serializes the commands written to the server.
+	writeMu sync.Mutex
+
+	mu         sync.Mutex
+	conn       net.Conn
+	status     Status
+	echoSentAt time.Time
+	filters    []canbus.Filter
+}
+
+var _ canbus.Bus = (*Conn)(nil)
+
+// IsURL reports whether name is a socketcand URL such as "tcp://host:port/can0".
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "tcp://")
+}
+
+// ParseURL splits a "tcp://host[:port]/bus" URL into the server address and the remote bus name.
+func ParseURL(rawURL string) (address, bus string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", err
+	}
+	if u.Scheme != "tcp" || u.Host == "" {
+		return "", "", fmt.Errorf("invalid socketcand URL %q, want tcp://host:port/bus", rawURL)
+	}
+	bus = strings.Trim(u.Path, "/")
+	if bus == "" || strings.ContainsAny(bus, "/ <>") {
+		return "", "", fmt.Errorf("invalid bus name in socketcand URL %q", rawURL)
+	}
+	address = u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), DefaultPort)
+	}
+	return address, bus, nil
+}
+
+// Dial connects to the socketcand server of a "tcp://host:port/bus" URL and opens
+// the bus in raw mode. Once connected, the connection is restored in the background
+// when it drops; frames sent meanwhile fail.
+func Dial(ctx context.Context, rawURL string, opts ...Option) (*Conn, error) {
+	address, bus, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{
+		addr:   address,
+		bus:    bus,
+		frames: make(chan canbus.Frame, receiveBacklog),
+		closed: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	conn, r, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.conn = conn
+	c.status.Connected = true
+	c.mu.Unlock()
+
+	go c.run(conn, r)
+	go c.echoLoop()
+	return c, nil
+}
+
+// connect dials the server and performs the raw mode handshake.
+func (c *Conn) connect(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	d := net.Dialer{Timeout: dialTimeout}
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	fail := func(err error) (net.Conn, *bufio.Reader, error) {
+		_ = conn.Close()
+		return nil, nil, fmt.Errorf("socketcand %s: %w", c.addr, err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(dialTimeout))
+	r := bufio.NewReader(conn)
+	if err := expect(r, "hi"); err != nil {
+		return fail(err)
+	}
+	for _, cmd := range []string{"open " + c.bus, "rawmode"} {
+		if _, err := conn.Write([]byte("< " + cmd + " >")); err != nil {
+			return fail(err)
+		}
+		if err := expect(r, "ok"); err != nil {
+			return fail(fmt.Errorf("%s: %w", cmd, err))
+		}
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, r, nil
+}
+
+// expect reads the next element and checks its command.
+func expect(r *bufio.Reader, cmd string) error {
+	fields, err := readElement(r)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 || fields[0] != cmd {
+		return fmt.Errorf("unexpected answer < %s >", strings.Join(fields, " "))
+	}
+	return nil
+}
+
+// readElement reads a "< ... >" element and returns its fields.
+func readElement(r *bufio.Reader) ([]string, error) {
+	for {
+		s, err := r.ReadString('>')
+		if err != nil {
+			return nil, err
+		}
+		start := strings.IndexByte(s, '<')
+		if start < 0 {
+			continue
+		}
+		return strings.Fields(s[start+1 : len(s)-1]), nil
+	}
+}
+
+// run receives frames and restores the connection until Close is called.
+func (c *Conn) run(conn net.Conn, r *bufio.Reader) {
+	for {
+		err := c.receive(r)
+		_ = conn.Close()
+		if c.isClosed() {
+			return
+		}
+		c.setStatus(func(s *Status) {
+			s.Connected = false
+			s.Err = err
+		})
+
+		backoff := minBackoff
+		for {
+			select {
+			case <-c.closed:
+				return
+			case <-time.After(backoff):
+			}
+			var rerr error
+			conn, r, rerr = c.connect(context.Background())
+			if rerr == nil {
+				break
+			}
+			backoff = min(2*backoff, maxBackoff)
+		}
+
+		c.mu.Lock()
+		c.conn = conn
+		c.mu.Unlock()
+		if c.isClosed() {
+			_ = conn.Close()
+			return
+		}
+		c.setStatus(func(s *Status) {
+			s.Connected = true
+			s.Err = nil
+			s.Reconnects++
+		})
+	}
+}
+
+// receive reads elements until the connection fails.
+func (c *Conn) receive(r *bufio.Reader) error {
+	for {
+		fields, err := readElement(r)
+		if err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "frame":
+			f, err := parseFrame(fields[1:])
+			if err != nil || !c.accept(&f) {
+				continue
+			}
+			select {
+			case c.frames <- f:
+			default:
+				// the application does not keep up, drop the frame rather than the connection
+			}
+		case "echo":
+			c.mu.Lock()
+			sent := c.echoSentAt
+			c.echoSentAt = time.Time{}
+			c.mu.Unlock()
+			if !sent.IsZero() {
+				latency := time.Since(sent)
+				c.setStatus(func(s *Status) { s.Latency = latency })
+			}
+		}
+	}
+}
+
+// parseFrame decodes the fields of a "< frame ID secs.usecs DATA >" element.
+func parseFrame(fields []string) (canbus.Frame, error) {
+	if len(fields) < 2 {
+		return canbus.Frame{}, errors.New("short frame element")
+	}
+	id, err := strconv.ParseUint(fields[0], 16, 32)
+	if err != nil {
+		return canbus.Frame{}, err
+	}
+	f := canbus.Frame{ID: uint32(id), IsExtended: len(fields[0]) > 3}
+	if len(fields) > 2 {
+		data := strings.Join(fields[2:], "")
+		if len(data) > 2*canbus.MaxDataLength {
+			return canbus.Frame{}, errors.New("frame data too long")
+		}
+		n, err := hex.Decode(f.Data[:], []byte(data))
+		if err != nil {
+			return canbus.Frame{}, err
+		}
+		f.Length = uint8(n)
+	}
+	return f, f.Validate()
+}
+
+func (c *Conn) accept(f *canbus.Frame) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return canbus.MatchAny(c.filters, f)
+}
+
+func (c *Conn) echoLoop() {
+	ticker := time.NewTicker(echoInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+		}
+		c.mu.Lock()
+		c.echoSentAt = time.Now()
+		c.mu.Unlock()
+		_ = c.command(context.Background(), "echo")
+	}
+}
+
+func (c *Conn) setStatus(fn func(s *Status)) {
+	c.mu.Lock()
+	fn(&c.status)
+	s := c.status
+	c.mu.Unlock()
+
+	if c.onStatus != nil {
+		c.onStatus(s)
+	}
+}
+
+// Status returns the state of the connection.
+func (c *Conn) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
+
+// ReadFrame blocks until the next frame is received.
+func (c *Conn) ReadFrame() (canbus.Frame, error) {
+	select {
+	case f := <-c.frames:
+		return f, nil
+	case <-c.closed:
+		return canbus.Frame{}, net.ErrClosed
+	}
+}
+
+// WriteFrame sends a classic frame with the send command.
+func (c *Conn) WriteFrame(ctx context.Context, f canbus.Frame) error {
+	if f.IsFD {
+		return errors.New("socketcand: CAN FD frames are not supported")
+	}
+	if f.IsRemote {
+		return errors.New("socketcand: remote frames are not supported")
+	}
+	id := fmt.Sprintf("%03X", f.ID)
+	if f.IsExtended {
+		id = fmt.Sprintf("%08X", f.ID)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "send %s %d", id, f.Length)
+	for _, v := range f.Payload() {
+		fmt.Fprintf(&b, " %02X", v)
+	}
+	return c.command(ctx, b.String())
+}
+
+// SetFilters filters the received frames on the client side; raw mode has no server side filters.
+func (c *Conn) SetFilters(filters []canbus.Filter) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.filters = append([]canbus.Filter{}, filters...)
+	return nil
+}
+
+func (c *Conn) command(ctx context.Context, cmd string) error {
+	c.mu.Lock()
+	conn := c.conn
+	connected := c.status.Connected
+	c.mu.Unlock()
+
+	if c.isClosed() {
+		return net.ErrClosed
+	}
+	if !connected || conn == nil {
+		return fmt.Errorf("socketcand %s: not connected", c.addr)
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(dialTimeout)
+	}
+	_ = conn.SetWriteDeadline(deadline)
+	_, err := conn.Write([]byte("< " + cmd + " >"))
+	return err
+}
+
+// Close closes the connection to the server.
+func (c *Conn) Close() error {
+	c.once.Do(func() {
+		close(c.closed)
+		c.mu.Lock()
+		conn := c.conn
+		c.mu.Unlock()
+		if conn != nil {
+			_ = conn.Close()
+		}
+	})
+	return nil
+}
+
+func (c *Conn) isClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SocketOptions are the SocketCAN socket options of a started interface.
+type SocketOptions struct {
+	// Loopback lets the other applications of the host see the frames sent by the
+	// app (CAN_RAW_LOOPBACK). It is enabled by default.
+	Loopback bool `json:"loopback"`
+	// ReceiveOwn receives the frames sent by the app back once they are transmitted
+	// (CAN_RAW_RECV_OWN_MSGS), they are emitted on "can:frame" with direction "tx"
+	// and the reception timestamp of the bus. It requires Loopback.
+	ReceiveOwn bool `json:"receiveOwn"`
+}
+
+// defaultSocketOptions are the options of a new socket.
+var defaultSocketOptions = SocketOptions{Loopback: true}
+
+// socketOptioner is implemented by the SocketCAN connection.
+type socketOptioner interface {
+	SetLoopback(enabled bool) error
+	SetReceiveOwnMessages(enabled bool) error
+}
+
+// SetSocketOptions sets the socket options of a started SocketCAN interface.
+func (a *App) SetSocketOptions(iface string, opts SocketOptions) error {
+	iface = strings.TrimSpace(iface)
+	if opts.ReceiveOwn && !opts.Loopback {
+		return errors.New("receiving own messages requires the loopback")
+	}
+	a.mu.Lock()
+	sess := a.sessions[iface]
+	a.mu.Unlock()
+
+	if sess == nil || sess.conn == nil {
+		return fmt.Errorf("CAN not started on %s", iface)
+	}
+	conn, ok := sess.conn.(socketOptioner)
+	if !ok {
+		return fmt.Errorf("%s is not a SocketCAN interface, it has no socket options", iface)
+	}
+	if err := conn.SetLoopback(opts.Loopback); err != nil {
+		return err
+	}
+	if err := conn.SetReceiveOwnMessages(opts.ReceiveOwn); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	sess.sockOpts = opts
+	a.mu.Unlock()
+	return nil
+}
+
+// GetSocketOptions returns the socket options of a started interface.
+func (a *App) GetSocketOptions(iface string) (SocketOptions, error) {
+	iface = strings.TrimSpace(iface)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sess := a.sessions[iface]
+	if sess == nil {
+		return SocketOptions{}, fmt.Errorf("CAN not started on %s", iface)
+	}
+	return sess.sockOpts, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/canstats"
+)
+
+// statsInterval is the period of the "can:stats" events.
+const statsInterval = time.Second
+
+// CANStats are the traffic statistics of an interface emitted on "can:stats".
+type CANStats struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	// Bitrate is the nominal bitrate the bus load is computed for.
+	Bitrate           uint32  `json:"bitrate"`
+	FramesPerSec      float64 `json:"framesPerSec"`
+	BytesPerSec       float64 `json:"bytesPerSec"`
+	ErrorFramesPerSec float64 `json:"errorFramesPerSec"`
+	// BusLoad is the estimated bus load in percent.
+	BusLoad     float64 `json:"busLoad"`
+	TotalFrames uint64  `json:"totalFrames"`
+	TotalBytes  uint64  `json:"totalBytes"`
+	TotalErrors uint64  `json:"totalErrors"`
+	TxFrames    uint64  `json:"txFrames"`
+	// KernelDrops is the number of frames the kernel dropped because the app did
+	// not read them fast enough, since the interface was started.
+	KernelDrops uint64       `json:"kernelDrops"`
+	IDs         []CANIDStats `json:"ids"`
+	// Groups are the counters of the frame groups, whose IDs are tagged in IDs.
+	Groups []CANGroupStats `json:"groups"`
+}
+
+// CANIDStats are the counters of a single CAN ID.
+type CANIDStats struct {
+	ID       uint32  `json:"id"`
+	Extended bool    `json:"extended"`
+	Count    uint64  `json:"count"`
+	Bytes    uint64  `json:"bytes"`
+	Rate     float64 `json:"rate"`
+	// MinIntervalMs, AvgIntervalMs and MaxIntervalMs are inter-arrival times in milliseconds.
+	MinIntervalMs float64 `json:"minIntervalMs"`
+	AvgIntervalMs float64 `json:"avgIntervalMs"`
+	MaxIntervalMs float64 `json:"maxIntervalMs"`
+	Group         string  `json:"group,omitempty"`
+}
+
+// GetStats returns the statistics of a started interface as of the last "can:stats" event.
+func (a *App) GetStats(iface string) (CANStats, error) {
+	sess, err := a.session(iface)
+	if err != nil {
+		return CANStats{}, err
+	}
+	if s := sess.lastStats.Load(); s != nil {
+		return *s, nil
+	}
+	return CANStats{Interface: sess.iface, IDs: []CANIDStats{}, Groups: []CANGroupStats{}}, nil
+}
+
+// ResetStats clears the statistics of a started interface.
+func (a *App) ResetStats(iface string) error {
+	sess, err := a.session(iface)
+	if err != nil {
+		return err
+	}
+	sess.stats.Reset()
+	sess.lastStats.Store(nil)
+	return nil
+}
+
+// session returns the started session of iface.
+func (a *App) session(iface string) (*canSession, error) {
+	iface = strings.TrimSpace(iface)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sess := a.sessions[iface]
+	if sess == nil || sess.conn == nil {
+		return nil, fmt.Errorf("CAN not started on %s", iface)
+	}
+	return sess, nil
+}
+
+// newStatsCollector returns a collector using the bitrate configured on iface.
+func newStatsCollector(iface string) *canstats.Collector {
+	var bitrate, dbitrate uint32
+	if l, err := canbus.LinkByName(iface); err == nil {
+		bitrate, dbitrate = l.Bitrate, l.DataBitrate
+	}
+	return canstats.NewCollector(bitrate, dbitrate)
+}
+
+func (a *App) statsLoop(sess *canSession) {
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	var drops uint64
+	for {
+		select {
+		case <-sess.ctx.Done():
+			return
+		case now := <-ticker.C:
+			s := statsEvent(sess.iface, sess.stats.Snapshot(now))
+			s.Groups = a.groupStats(&s)
+			s.KernelDrops = sess.kernelDrops.Load()
+			if s.KernelDrops > drops {
+				a.emitError(fmt.Errorf("%s: the kernel dropped %d received frames, the receive queue overflowed",
+					sess.iface, s.KernelDrops-drops))
+				drops = s.KernelDrops
+			}
+			sess.lastStats.Store(&s)
+			a.emit("can:stats", s)
+		}
+	}
+}
+
+// countTx adds a transmitted frame to the statistics of iface.
+func (a *App) countTx(iface string, ts time.Time, f *canbus.Frame) {
+	a.mu.Lock()
+	sess := a.sessions[iface]
+	a.mu.Unlock()
+
+	if sess != nil && sess.stats != nil {
+		sess.stats.Add(ts, f, true)
+	}
+}
+
+func statsEvent(iface string, s canstats.Snapshot) CANStats {
+	ev := CANStats{
+		Timestamp:         s.Timestamp,
+		Interface:         iface,
+		Bitrate:           s.Bitrate,
+		FramesPerSec:      s.FramesPerSec,
+		BytesPerSec:       s.BytesPerSec,
+		ErrorFramesPerSec: s.ErrorFramesPerSec,
+		BusLoad:           s.BusLoad,
+		TotalFrames:       s.TotalFrames,
+		TotalBytes:        s.TotalBytes,
+		TotalErrors:       s.TotalErrors,
+		TxFrames:          s.TxFrames,
+		IDs:               make([]CANIDStats, len(s.IDs)),
+	}
+	for i, id := range s.IDs {
+		ev.IDs[i] = CANIDStats{
+			ID:            id.ID,
+			Extended:      id.Extended,
+			Count:         id.Count,
+			Bytes:         id.Bytes,
+			Rate:          id.Rate,
+			MinIntervalMs: milliseconds(id.MinInterval),
+			AvgIntervalMs: milliseconds(id.AvgInterval),
+			MaxIntervalMs: milliseconds(id.MaxInterval),
+		}
+	}
+	return ev
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
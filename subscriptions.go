@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"canproject/canbus"
+)
+
+// FrameSubscription selects the frames emitted on an event channel of their
+// own, so a view of a single interface or ID range only receives its frames.
+// The frames are CANFrameEvent payloads, or CANFramesEvent batches holding the
+// matching frames of each batch when batching is enabled.
+type FrameSubscription struct {
+	// Channel is the event name, by default "can:frame:<interface>" for a single
+	// interface and "can:frame:sub<N>" otherwise.
+	Channel string `json:"channel"`
+	// Interfaces, IDs and Groups select the frames of the given interfaces,
+	// matching any of the filters and in any of the frame groups; empty selects all.
+	Interfaces []string    `json:"interfaces"`
+	IDs        []CANFilter `json:"ids"`
+	Groups     []string    `json:"groups"`
+	// Direction is "rx" or "tx" for the received or sent frames only, empty for both.
+	Direction string `json:"direction"`
+}
+
+type frameSub struct {
+	FrameSubscription
+	filters []canbus.Filter
+}
+
+// frameChannels are the subscriptions of SubscribeFrames, replaced as a whole.
+type frameChannels struct {
+	// noGlobal is set when the frames are only emitted to the subscriptions.
+	noGlobal bool
+	subs     []*frameSub
+}
+
+// SubscribeFrames emits the frames matching sub on sub.Channel, in addition to
+// "can:frame". It returns the subscription with its channel name.
+func (a *App) SubscribeFrames(sub FrameSubscription) (FrameSubscription, error) {
+	sub.Channel = strings.TrimSpace(sub.Channel)
+	sub.Direction = strings.ToLower(strings.TrimSpace(sub.Direction))
+	if sub.Direction != "" && sub.Direction != "rx" && sub.Direction != "tx" {
+		return FrameSubscription{}, fmt.Errorf("unknown direction %q, want rx, tx or empty", sub.Direction)
+	}
+	sub.Interfaces = trimAll(sub.Interfaces)
+	sub.Groups = trimAll(sub.Groups)
+	s := &frameSub{FrameSubscription: sub}
+	if len(sub.IDs) > 0 {
+		sub.IDs = append([]CANFilter(nil), sub.IDs...)
+		for _, f := range sub.IDs {
+			s.filters = append(s.filters, canbus.Filter{ID: f.ID, Mask: f.Mask, Extended: f.Extended, Invert: f.Invert})
+		}
+	}
+
+	a.frameChanMu.Lock()
+	defer a.frameChanMu.Unlock()
+	old := a.frameChans.Load()
+	if old == nil {
+		old = &frameChannels{}
+	}
+	if sub.Channel == "" {
+		if len(sub.Interfaces) == 1 {
+			sub.Channel = "can:frame:" + sub.Interfaces[0]
+		} else {
+			a.nextFrameSub++
+			sub.Channel = fmt.Sprintf("can:frame:sub%d", a.nextFrameSub)
+		}
+	}
+	if sub.Channel == "can:frame" || sub.Channel == "can:frames" {
+		return FrameSubscription{}, fmt.Errorf("channel %s is the channel of all frames", sub.Channel)
+	}
+	for _, o := range old.subs {
+		if o.Channel == sub.Channel {
+			return FrameSubscription{}, fmt.Errorf("channel %s is already subscribed", sub.Channel)
+		}
+	}
+	s.FrameSubscription = sub
+	a.frameChans.Store(&frameChannels{noGlobal: old.noGlobal, subs: append(slices.Clip(old.subs), s)})
+	return sub, nil
+}
+
+// UnsubscribeFrames removes the subscription of channel.
+func (a *App) UnsubscribeFrames(channel string) error {
+	channel = strings.TrimSpace(channel)
+
+	a.frameChanMu.Lock()
+	defer a.frameChanMu.Unlock()
+	old := a.frameChans.Load()
+	if old == nil {
+		return fmt.Errorf("channel %s is not subscribed", channel)
+	}
+	i := slices.IndexFunc(old.subs, func(s *frameSub) bool { return s.Channel == channel })
+	if i < 0 {
+		return fmt.Errorf("channel %s is not subscribed", channel)
+	}
+	a.frameChans.Store(&frameChannels{noGlobal: old.noGlobal, subs: slices.Delete(slices.Clone(old.subs), i, i+1)})
+	return nil
+}
+
+// GetFrameSubscriptions returns the subscriptions of SubscribeFrames.
+func (a *App) GetFrameSubscriptions() []FrameSubscription {
+	subs := []FrameSubscription{}
+	if c := a.frameChans.Load(); c != nil {
+		for _, s := range c.subs {
+			subs = append(subs, s.FrameSubscription)
+		}
+	}
+	return subs
+}
+
+// SetGlobalFrameChannel turns the emission of every frame on "can:frame" and
+// "can:frames" on or off, it is on by default. With it off and no subscription
+// no frame is emitted.
+func (a *App) SetGlobalFrameChannel(enabled bool) {
+	a.frameChanMu.Lock()
+	defer a.frameChanMu.Unlock()
+	var subs []*frameSub
+	if old := a.frameChans.Load(); old != nil {
+		subs = old.subs
+	}
+	if enabled && len(subs) == 0 {
+		a.frameChans.Store(nil)
+		return
+	}
+	a.frameChans.Store(&frameChannels{noGlobal: !enabled, subs: subs})
+}
+
+// GetGlobalFrameChannel reports whether every frame is emitted on "can:frame".
+func (a *App) GetGlobalFrameChannel() bool {
+	c := a.frameChans.Load()
+	return c == nil || !c.noGlobal
+}
+
+func (s *frameSub) match(ev *CANFrameEvent) bool {
+	if len(s.Interfaces) > 0 && !slices.Contains(s.Interfaces, ev.Interface) {
+		return false
+	}
+	if s.Direction != "" && ev.Direction != s.Direction {
+		return false
+	}
+	if len(s.Groups) > 0 && !slices.Contains(s.Groups, ev.Group) {
+		return false
+	}
+	return canbus.MatchAny(s.filters, &canbus.Frame{ID: ev.ID, IsExtended: ev.Extended})
+}
+
+// emitSubscribed emits a frame to the matching subscriptions.
+func (a *App) emitSubscribed(c *frameChannels, ev *CANFrameEvent) {
+	for _, s := range c.subs {
+		if s.match(ev) {
+			a.emit(s.Channel, *ev)
+		}
+	}
+}
+
+// emitBatch emits a batch on "can:frames" and its matching frames to the subscriptions.
+func (a *App) emitBatch(ev CANFramesEvent) {
+	c := a.frameChans.Load()
+	if c == nil || !c.noGlobal {
+		a.emit("can:frames", ev)
+	}
+	if c == nil {
+		return
+	}
+	for _, s := range c.subs {
+		sub := CANFramesEvent{Dropped: ev.Dropped, TotalDropped: ev.TotalDropped}
+		for i := range ev.Frames {
+			if s.match(&ev.Frames[i]) {
+				sub.Frames = append(sub.Frames, ev.Frames[i])
+			}
+		}
+		if len(sub.Frames) > 0 || sub.Dropped > 0 {
+			if sub.Frames == nil {
+				sub.Frames = []CANFrameEvent{}
+			}
+			a.emit(s.Channel, sub)
+		}
+	}
+}
+
+// trimAll returns the non-empty trimmed strings of ss.
+func trimAll(ss []string) []string {
+	var out []string
+	for _, s := range ss {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"canproject/uds"
+)
+
+const (
+	// defaultKeepAlivePeriod is the TesterPresent period when
+	// UDSKeepAliveOptions.PeriodMs is 0.
+	defaultKeepAlivePeriod = 2 * time.Second
+	// maxKeepAlivePeriod stays below the S3 timeout of the servers, 5 s.
+	maxKeepAlivePeriod = 4500 * time.Millisecond
+	minKeepAlivePeriod = 50 * time.Millisecond
+)
+
+// UDSKeepAliveOptions configures the TesterPresent keep-alive of SetUDSKeepAlive.
+type UDSKeepAliveOptions struct {
+	Enabled bool `json:"enabled"`
+	// PeriodMs is the longest time without a request before a TesterPresent
+	// is sent, 2000 when 0 and below the 5 s S3 timeout of the ECU.
+	PeriodMs int `json:"periodMs"`
+	// Respond asks for the positive response (0x3E 0x00) and checks it,
+	// instead of suppressing it (0x3E 0x80).
+	Respond bool `json:"respond"`
+}
+
+// UDSKeepAliveStatus is the state of the keep-alive of a handle, emitted on
+// "uds:keepalive" when it starts or stops sending and on errors.
+type UDSKeepAliveStatus struct {
+	Handle  int                 `json:"handle"`
+	Options UDSKeepAliveOptions `json:"options"`
+	// Active is set while the ECU is in Session, a non-default session.
+	Active    bool   `json:"active"`
+	Session   uint8  `json:"session"`
+	Sent      int    `json:"sent"`
+	Errors    int    `json:"errors"`
+	LastError string `json:"lastError,omitempty"`
+}
+
+type keepAlive struct {
+	handle int
+	opts   UDSKeepAliveOptions
+	client *uds.Client
+	period time.Duration
+
+	mu      sync.Mutex
+	active  bool
+	session uint8
+	sent    int
+	errors  int
+	lastErr string
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// SetUDSKeepAlive keeps the diagnostic sessions of an ISO-TP channel or a DoIP
+// connection alive: while a request of the handle switched the ECU to a
+// non-default session with DiagnosticSessionControl, a TesterPresent is sent
+// whenever no request was sent for the period, so the ECU does not fall back
+// to the default session in the middle of a long operation. It stops sending
+// when the ECU goes back to the default session or is reset, and stops when
+// the handle is closed. Disabled options stop it.
+func (a *App) SetUDSKeepAlive(handle int, opts UDSKeepAliveOptions) (UDSKeepAliveStatus, error) {
+	period := defaultKeepAlivePeriod
+	if opts.PeriodMs != 0 {
+		period = time.Duration(opts.PeriodMs) * time.Millisecond
+	}
+	if opts.Enabled && (period < minKeepAlivePeriod || period > maxKeepAlivePeriod) {
+		return UDSKeepAliveStatus{}, fmt.Errorf("keep-alive period must be within %d..%d ms (got %d)",
+			minKeepAlivePeriod.Milliseconds(), maxKeepAlivePeriod.Milliseconds(), opts.PeriodMs)
+	}
+	c, err := a.udsClient(handle)
+	if err != nil {
+		return UDSKeepAliveStatus{}, err
+	}
+
+	var k *keepAlive
+	if opts.Enabled {
+		session := c.Session()
+		k = &keepAlive{
+			handle:  handle,
+			opts:    opts,
+			client:  c,
+			period:  period,
+			session: session,
+			active:  session != uds.DefaultSession,
+			stop:    make(chan struct{}),
+			done:    make(chan struct{}),
+		}
+	}
+	a.keepAliveMu.Lock()
+	old := a.keepAlives[handle]
+	delete(a.keepAlives, handle)
+	if k != nil {
+		if a.keepAlives == nil {
+			a.keepAlives = make(map[int]*keepAlive)
+		}
+		a.keepAlives[handle] = k
+	}
+	a.keepAliveMu.Unlock()
+	// the loop of old takes keepAliveMu when its handle is closed
+	if old != nil {
+		old.close()
+	}
+	if k == nil {
+		return UDSKeepAliveStatus{Handle: handle, Options: opts}, nil
+	}
+	go a.keepAliveLoop(k)
+	return k.status(), nil
+}
+
+// GetUDSKeepAlive returns the state of the keep-alive of a handle.
+func (a *App) GetUDSKeepAlive(handle int) UDSKeepAliveStatus {
+	a.keepAliveMu.Lock()
+	k := a.keepAlives[handle]
+	a.keepAliveMu.Unlock()
+	if k == nil {
+		return UDSKeepAliveStatus{Handle: handle}
+	}
+	return k.status()
+}
+
+// stopKeepAlives stops every keep-alive.
+func (a *App) stopKeepAlives() {
+	a.keepAliveMu.Lock()
+	keepAlives := a.keepAlives
+	a.keepAlives = nil
+	a.keepAliveMu.Unlock()
+
+	for _, k := range keepAlives {
+		k.close()
+	}
+}
+
+func (a *App) keepAliveLoop(k *keepAlive) {
+	defer close(k.done)
+
+	timer := time.NewTimer(k.period)
+	defer timer.Stop()
+	for {
+		select {
+		case <-k.stop:
+			return
+		case <-timer.C:
+		}
+		if c, err := a.udsClient(k.handle); err != nil || c != k.client {
+			// the handle was closed
+			a.keepAliveMu.Lock()
+			if a.keepAlives[k.handle] == k {
+				delete(a.keepAlives, k.handle)
+			}
+			a.keepAliveMu.Unlock()
+			return
+		}
+
+		wait := k.period
+		session := k.client.Session()
+		active := session != uds.DefaultSession
+		if k.setSession(session, active) {
+			a.emit("uds:keepalive", k.status())
+		}
+		if idle := k.client.Idle(); active && idle < k.period {
+			wait = k.period - idle
+		} else if active {
+			ctx, cancel := context.WithTimeout(context.Background(), udsRequestTimeout)
+			sent, err := k.client.KeepAlive(ctx, !k.opts.Respond)
+			cancel()
+			if k.record(sent, err) {
+				a.emit("uds:keepalive", k.status())
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+func (k *keepAlive) close() {
+	close(k.stop)
+	<-k.done
+}
+
+// setSession reports whether the keep-alive started or stopped sending.
+func (k *keepAlive) setSession(session uint8, active bool) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	changed := active != k.active
+	k.session, k.active = session, active
+	return changed
+}
+
+// record counts a TesterPresent and reports whether it failed.
+func (k *keepAlive) record(sent bool, err error) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err != nil {
+		k.errors++
+		k.lastErr = err.Error()
+		return true
+	}
+	if sent {
+		k.sent++
+	}
+	return false
+}
+
+func (k *keepAlive) status() UDSKeepAliveStatus {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return UDSKeepAliveStatus{
+		Handle:    k.handle,
+		Options:   k.opts,
+		Active:    k.active,
+		Session:   k.session,
+		Sent:      k.sent,
+		Errors:    k.errors,
+		LastError: k.lastErr,
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/capture"
+)
+
+// Timestamp modes of SetTimestampMode.
+const (
+	// timestampSynced maps the hardware timestamps onto the system clock, the
+	// default: all the interfaces share the time base of the kernel timestamps.
+	timestampSynced int32 = iota
+	// timestampKernel uses the kernel timestamps even when the controller
+	// stamps the frames.
+	timestampKernel
+	// timestampPTP keeps the hardware timestamps as is, for controllers whose
+	// clock is disciplined by PTP (eg phc2sys) to the system clock.
+	timestampPTP
+)
+
+var timestampModes = []string{"synced", "kernel", "ptp"}
+
+// maxGatewayLatency bounds the time from a source frame to the frame a gateway
+// forwards it as, older source frames are not paired.
+const maxGatewayLatency = time.Second
+
+// TimestampStatus is the time base of the frames of an interface.
+type TimestampStatus struct {
+	Interface string `json:"interface"`
+	Mode      string `json:"mode"`
+	// ClockOffsetUs is the offset measured from the controller clock to the
+	// system clock in synced mode, Synced false until a hardware-stamped frame
+	// was received.
+	ClockOffsetUs float64 `json:"clockOffsetUs"`
+	Synced        bool    `json:"synced"`
+}
+
+// GatewayLatency is the delay of a gateway forwarding a message from one bus to
+// another, measured by MeasureGatewayLatency.
+type GatewayLatency struct {
+	SourceInterface      string `json:"sourceInterface"`
+	DestinationInterface string `json:"destinationInterface"`
+	SourceID             uint32 `json:"sourceId"`
+	DestinationID        uint32 `json:"destinationId"`
+	// Pairs is the number of forwarded frames measured. UnmatchedSource are
+	// the source frames not forwarded, UnmatchedDestination the destination
+	// frames without source frame before them.
+	Pairs                int `json:"pairs"`
+	UnmatchedSource      int `json:"unmatchedSource"`
+	UnmatchedDestination int `json:"unmatchedDestination"`
+	// JitterUs is the standard deviation of the latency.
+	MinUs    float64 `json:"minUs"`
+	MeanUs   float64 `json:"meanUs"`
+	P50Us    float64 `json:"p50Us"`
+	P99Us    float64 `json:"p99Us"`
+	MaxUs    float64 `json:"maxUs"`
+	JitterUs float64 `json:"jitterUs"`
+}
+
+// SetTimestampMode selects how the frames of a started interface are stamped
+// when its controller reports hardware timestamps: "synced" (the default) maps
+// them onto the system clock from the kernel timestamps, so that the frames of
+// all the interfaces compare; "kernel" uses the kernel timestamps instead;
+// "ptp" keeps the hardware timestamps, for controller clocks synchronized to
+// the system clock with PTP.
+func (a *App) SetTimestampMode(iface, mode string) error {
+	sess, err := a.session(iface)
+	if err != nil {
+		return err
+	}
+	m := slices.Index(timestampModes, strings.ToLower(strings.TrimSpace(mode)))
+	if m < 0 {
+		return fmt.Errorf("unknown timestamp mode %q, want %s", mode, strings.Join(timestampModes, ", "))
+	}
+	sess.timestampMode.Store(int32(m))
+	return nil
+}
+
+// GetTimestampMode returns the time base of a started interface.
+func (a *App) GetTimestampMode(iface string) (TimestampStatus, error) {
+	sess, err := a.session(iface)
+	if err != nil {
+		return TimestampStatus{}, err
+	}
+	st := TimestampStatus{
+		Interface: sess.iface,
+		Mode:      timestampModes[sess.timestampMode.Load()],
+		Synced:    sess.clockSynced.Load(),
+	}
+	if st.Synced {
+		st.ClockOffsetUs = float64(sess.clockOffset.Load()) / float64(time.Microsecond)
+	}
+	return st, nil
+}
+
+// stampFrames applies the timestamp mode of sess to the frames it read, clock
+// maps the controller clock of its connection.
+func stampFrames(sess *canSession, clock *canbus.ClockMapper, infos []canbus.RxInfo) {
+	mode := sess.timestampMode.Load()
+	for i := range infos {
+		info := &infos[i]
+		if info.Source != canbus.TimestampHardware || info.Kernel.IsZero() {
+			continue
+		}
+		switch mode {
+		case timestampSynced:
+			info.Time = clock.Map(info.Time, info.Kernel)
+		case timestampKernel:
+			info.Time, info.Source = info.Kernel, canbus.TimestampKernel
+		}
+	}
+	if off, ok := clock.Offset(); ok {
+		sess.clockOffset.Store(int64(off))
+		sess.clockSynced.Store(true)
+	}
+}
+
+// MeasureGatewayLatency measures from the capture buffer the delay of a gateway
+// forwarding the frames of idSrc as idDst (standard or extended IDs, which may
+// be equal). The source interface is the one idSrc was received on first, the
+// destination another interface receiving idDst, or the same one when the IDs
+// differ. Each destination frame is paired with the last source frame before
+// it, preferably one with the same payload, and the source frames skipped are
+// counted as not forwarded. The interfaces should be captured with a common
+// time base, see SetTimestampMode.
+func (a *App) MeasureGatewayLatency(idSrc, idDst uint32) (GatewayLatency, error) {
+	records := a.capture.Select(func(r *capture.Record) bool {
+		return !r.TX && r.Packet == nil && !r.Frame.IsError && (r.Frame.ID == idSrc || r.Frame.ID == idDst)
+	})
+	slices.SortStableFunc(records, func(x, y capture.Record) int { return x.Timestamp.Compare(y.Timestamp) })
+
+	res := GatewayLatency{SourceID: idSrc, DestinationID: idDst}
+	for _, r := range records {
+		if r.Frame.ID == idSrc {
+			res.SourceInterface = r.Interface
+			break
+		}
+	}
+	if res.SourceInterface == "" {
+		return GatewayLatency{}, fmt.Errorf("no frame of ID %s in the capture buffer", formatCANID(idSrc, idSrc > 0x7ff))
+	}
+	for _, r := range records {
+		if r.Frame.ID == idDst && r.Interface != res.SourceInterface {
+			res.DestinationInterface = r.Interface
+			break
+		}
+	}
+	if res.DestinationInterface == "" {
+		if idSrc == idDst {
+			return GatewayLatency{}, fmt.Errorf("no frame of ID %s on another interface than %s", formatCANID(idDst, idDst > 0x7ff), res.SourceInterface)
+		}
+		res.DestinationInterface = res.SourceInterface
+	}
+
+	var pending []*capture.Record
+	var latencies []time.Duration
+	for i := range records {
+		r := &records[i]
+		switch {
+		case r.Interface == res.SourceInterface && r.Frame.ID == idSrc:
+			pending = append(pending, r)
+		case r.Interface == res.DestinationInterface && r.Frame.ID == idDst:
+			// source frames too old to be the one forwarded
+			for len(pending) > 0 && r.Timestamp.Sub(pending[0].Timestamp) > maxGatewayLatency {
+				pending = pending[1:]
+				res.UnmatchedSource++
+			}
+			if len(pending) == 0 {
+				res.UnmatchedDestination++
+				continue
+			}
+			match := len(pending) - 1
+			for j := match; j >= 0; j-- {
+				if slices.Equal(pending[j].Frame.Payload(), r.Frame.Payload()) {
+					match = j
+					break
+				}
+			}
+			latencies = append(latencies, r.Timestamp.Sub(pending[match].Timestamp))
+			res.UnmatchedSource += match
+			pending = pending[match+1:]
+		}
+	}
+	res.UnmatchedSource += len(pending)
+	if len(latencies) == 0 {
+		return GatewayLatency{}, errors.New("no forwarded frames in the capture buffer")
+	}
+
+	res.Pairs = len(latencies)
+	slices.Sort(latencies)
+	us := func(d time.Duration) float64 { return float64(d) / float64(time.Microsecond) }
+	pct := func(p float64) float64 { return us(latencies[int(p*float64(len(latencies)-1))]) }
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+	mean := sum / time.Duration(len(latencies))
+	var variance float64
+	for _, l := range latencies {
+		d := us(l - mean)
+		variance += d * d
+	}
+	res.MinUs, res.MaxUs = us(latencies[0]), us(latencies[len(latencies)-1])
+	res.MeanUs = us(mean)
+	res.P50Us, res.P99Us = pct(0.5), pct(0.99)
+	res.JitterUs = math.Sqrt(variance / float64(len(latencies)))
+	return res, nil
+}
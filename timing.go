@@ -0,0 +1,123 @@
+package main
+
+import (
+	"time"
+
+	"canproject/canbus"
+	"canproject/timing"
+)
+
+// timingCheckInterval is the period the messages which stopped, and the NM nodes
+// which went asleep, are looked for.
+const timingCheckInterval = 100 * time.Millisecond
+
+// MessageTiming is the learned timing of a CAN ID.
+type MessageTiming struct {
+	ID       uint32 `json:"id"`
+	Extended bool   `json:"extended"`
+	// Class is "periodic", "event", "mixed" (cyclic with event frames in between),
+	// or "unknown" until enough frames were received.
+	Class string `json:"class"`
+	Count uint64 `json:"count"`
+	// PeriodMs is the cycle time of periodic and mixed messages, JitterMs the standard
+	// deviation of their intervals.
+	PeriodMs float64 `json:"periodMs"`
+	JitterMs float64 `json:"jitterMs"`
+	// MinIntervalMs and MaxIntervalMs are the extreme intervals of the recent frames.
+	MinIntervalMs float64   `json:"minIntervalMs"`
+	MaxIntervalMs float64   `json:"maxIntervalMs"`
+	Late          uint64    `json:"late"`
+	Missed        uint64    `json:"missed"`
+	Timeouts      uint64    `json:"timeouts"`
+	Last          time.Time `json:"last"`
+}
+
+// TimingViolation is a cycle a periodic message missed or sent late, emitted on "can:timing".
+type TimingViolation struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	ID        uint32    `json:"id"`
+	Extended  bool      `json:"extended"`
+	// Kind is "late", "missed" when the frame came after one or more cycles without
+	// a frame, or "timeout" when the message stopped.
+	Kind       string  `json:"kind"`
+	PeriodMs   float64 `json:"periodMs"`
+	IntervalMs float64 `json:"intervalMs"`
+	Missed     int     `json:"missed"`
+}
+
+// GetTiming returns the learned cycle time and class of every CAN ID received on
+// a started interface, sorted by ID.
+func (a *App) GetTiming(iface string) ([]MessageTiming, error) {
+	sess, err := a.session(iface)
+	if err != nil {
+		return nil, err
+	}
+	stats := sess.timing.Snapshot()
+	out := make([]MessageTiming, len(stats))
+	for i, s := range stats {
+		out[i] = MessageTiming{
+			ID:            s.ID,
+			Extended:      s.Extended,
+			Class:         s.Class,
+			Count:         s.Count,
+			PeriodMs:      milliseconds(s.Period),
+			JitterMs:      milliseconds(s.Jitter),
+			MinIntervalMs: milliseconds(s.Min),
+			MaxIntervalMs: milliseconds(s.Max),
+			Late:          s.Late,
+			Missed:        s.Missed,
+			Timeouts:      s.Timeouts,
+			Last:          s.Last,
+		}
+	}
+	return out, nil
+}
+
+// ResetTiming forgets the learned timing of a started interface, eg after the ECUs
+// changed mode.
+func (a *App) ResetTiming(iface string) error {
+	sess, err := a.session(iface)
+	if err != nil {
+		return err
+	}
+	sess.timing.Reset()
+	return nil
+}
+
+// trackTiming adds a received frame to the timing of its session.
+func (a *App) trackTiming(sess *canSession, ts time.Time, f *canbus.Frame) {
+	if v, ok := sess.timing.Add(ts, f); ok {
+		a.emit("can:timing", timingEvent(sess.iface, v))
+	}
+}
+
+func (a *App) timingLoop(sess *canSession) {
+	ticker := time.NewTicker(timingCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sess.ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, v := range sess.timing.Check(now) {
+				a.emit("can:timing", timingEvent(sess.iface, v))
+			}
+			a.checkNM(sess, now)
+		}
+	}
+}
+
+func timingEvent(iface string, v timing.Violation) TimingViolation {
+	return TimingViolation{
+		Timestamp:  v.Time,
+		Interface:  iface,
+		ID:         v.ID,
+		Extended:   v.Extended,
+		Kind:       v.Kind,
+		PeriodMs:   milliseconds(v.Period),
+		IntervalMs: milliseconds(v.Interval),
+		Missed:     v.Missed,
+	}
+}
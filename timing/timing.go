@@ -0,0 +1,248 @@
+// Package timing learns the cycle time of every CAN ID of a bus, classifies the
+// messages as periodic, event-driven or mixed, and reports the cycles a periodic
+// message misses or sends late.
+package timing
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+)
+
+// Classes of a message.
+const (
+	// ClassUnknown is the class until MinIntervals intervals were seen.
+	ClassUnknown = "unknown"
+	// ClassPeriodic messages are sent at a fixed cycle time.
+	ClassPeriodic = "periodic"
+	// ClassEvent messages are sent on events, without a cycle time.
+	ClassEvent = "event"
+	// ClassMixed messages are sent at a cycle time and in between on events.
+	ClassMixed = "mixed"
+)
+
+// Kinds of a Violation.
+const (
+	// KindLate is a frame arriving later than the cycle time and its tolerance.
+	KindLate = "late"
+	// KindMissed is a frame arriving after one or more cycles without a frame.
+	KindMissed = "missed"
+	// KindTimeout is reported once when a message stops, before it resumes with KindMissed.
+	KindTimeout = "timeout"
+)
+
+const (
+	// MinIntervals is the number of intervals an ID is classified from.
+	MinIntervals = 8
+	// DefaultTolerance is the share of the cycle time an interval may deviate by.
+	DefaultTolerance = 0.2
+	// timeoutCycles is the number of cycles without a frame reported as KindTimeout.
+	timeoutCycles = 3
+	// window is the number of recent intervals the classification uses.
+	window = 32
+)
+
+// Stats describes the timing of one CAN ID.
+type Stats struct {
+	ID       uint32
+	Extended bool
+	Class    string
+	Count    uint64
+	// Period is the learned cycle time of periodic and mixed messages.
+	Period time.Duration
+	// Jitter is the standard deviation of the intervals matching the period.
+	Jitter time.Duration
+	// Min and Max are the shortest and longest intervals of the recent window.
+	Min, Max time.Duration
+	Late     uint64
+	Missed   uint64
+	Timeouts uint64
+	Last     time.Time
+}
+
+// Violation is a cycle a periodic message missed or sent late.
+type Violation struct {
+	Time     time.Time
+	ID       uint32
+	Extended bool
+	Kind     string
+	Period   time.Duration
+	// Interval is the time since the previous frame.
+	Interval time.Duration
+	// Missed is the number of cycles without a frame.
+	Missed int
+}
+
+type key struct {
+	id       uint32
+	extended bool
+}
+
+type message struct {
+	Stats
+	intervals [window]time.Duration
+	n         int
+	// seen is the local time of the last frame, hardware timestamps may use another clock
+	seen     time.Time
+	timedOut bool
+}
+
+// Monitor tracks the timing of the frames of one bus. It is safe for concurrent use.
+type Monitor struct {
+	mu        sync.Mutex
+	tolerance float64
+	messages  map[key]*message
+}
+
+// NewMonitor returns a monitor accepting intervals within tolerance times the cycle
+// time, DefaultTolerance when tolerance is not positive.
+func NewMonitor(tolerance float64) *Monitor {
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	return &Monitor{tolerance: tolerance, messages: make(map[key]*message)}
+}
+
+// Add records a frame received at ts and returns the violation it reveals, if any.
+// Error and remote frames are ignored.
+func (m *Monitor) Add(ts time.Time, f *canbus.Frame) (Violation, bool) {
+	if f.IsError || f.IsRemote {
+		return Violation{}, false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := key{f.ID, f.IsExtended}
+	msg := m.messages[k]
+	if msg == nil {
+		msg = &message{Stats: Stats{ID: f.ID, Extended: f.IsExtended, Class: ClassUnknown}}
+		m.messages[k] = msg
+	}
+	msg.Count++
+	prev := msg.Last
+	msg.Last, msg.seen = ts, time.Now()
+	timedOut := msg.timedOut
+	msg.timedOut = false
+	if prev.IsZero() {
+		return Violation{}, false
+	}
+	interval := ts.Sub(prev)
+	if interval < 0 {
+		return Violation{}, false
+	}
+
+	var v Violation
+	var late bool
+	if period := msg.Period; period > 0 && interval > period+time.Duration(m.tolerance*float64(period)) {
+		v = Violation{Time: ts, ID: f.ID, Extended: f.IsExtended, Kind: KindLate, Period: period, Interval: interval}
+		if cycles := int(math.Round(float64(interval)/float64(period))) - 1; cycles > 0 {
+			v.Kind, v.Missed = KindMissed, cycles
+			msg.Missed += uint64(cycles)
+		} else {
+			msg.Late++
+		}
+		late = true
+	}
+	if !late || !timedOut {
+		// a message resuming after a dropout keeps its period
+		msg.intervals[msg.n%window] = interval
+		msg.n++
+		m.classify(msg)
+	}
+	return v, late
+}
+
+// Check reports the periodic messages which sent no frame for several cycles up to
+// now, once per dropout.
+func (m *Monitor) Check(now time.Time) []Violation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []Violation
+	for _, msg := range m.messages {
+		if msg.Period == 0 || msg.timedOut {
+			continue
+		}
+		if silent := now.Sub(msg.seen); silent >= timeoutCycles*msg.Period {
+			msg.timedOut = true
+			msg.Timeouts++
+			out = append(out, Violation{
+				Time:     now,
+				ID:       msg.ID,
+				Extended: msg.Extended,
+				Kind:     KindTimeout,
+				Period:   msg.Period,
+				Interval: silent,
+				Missed:   int(silent/msg.Period) - 1,
+			})
+		}
+	}
+	return out
+}
+
+// Snapshot returns the timing of every ID, sorted by ID.
+func (m *Monitor) Snapshot() []Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]Stats, 0, len(m.messages))
+	for _, msg := range m.messages {
+		out = append(out, msg.Stats)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Extended != out[j].Extended {
+			return !out[i].Extended
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Reset forgets the learned timing.
+func (m *Monitor) Reset() {
+	m.mu.Lock()
+	m.messages = make(map[key]*message)
+	m.mu.Unlock()
+}
+
+// classify updates the class, period and jitter of msg from its recent intervals.
+func (m *Monitor) classify(msg *message) {
+	n := min(msg.n, window)
+	recent := make([]time.Duration, n)
+	copy(recent, msg.intervals[:n])
+	sort.Slice(recent, func(i, j int) bool { return recent[i] < recent[j] })
+	msg.Min, msg.Max = recent[0], recent[n-1]
+	if n < MinIntervals {
+		return
+	}
+
+	// the cycle time is the median, event frames in between shorten some intervals
+	// but leave most of them at the cycle time
+	median := recent[n/2]
+	tol := time.Duration(m.tolerance * float64(median))
+	var matched int
+	var sum, sq float64
+	for _, d := range recent {
+		if d >= median-tol && d <= median+tol {
+			matched++
+			sum += float64(d)
+			sq += float64(d) * float64(d)
+		}
+	}
+	share := float64(matched) / float64(n)
+	switch {
+	case median <= 0 || share < 0.5:
+		msg.Class, msg.Period, msg.Jitter = ClassEvent, 0, 0
+		return
+	case share >= 0.8:
+		msg.Class = ClassPeriodic
+	default:
+		msg.Class = ClassMixed
+	}
+	mean := sum / float64(matched)
+	msg.Period = time.Duration(mean)
+	msg.Jitter = time.Duration(math.Sqrt(max(sq/float64(matched)-mean*mean, 0)))
+}
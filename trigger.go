@@ -0,0 +1,335 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+	"canproject/capture"
+)
+
+// maxTriggerFrames bounds the frames a trigger buffers, a pre-trigger window
+// longer than the bus fills at this count is cut.
+const maxTriggerFrames = 1 << 20
+
+// TriggerOptions configure ArmTrigger.
+type TriggerOptions struct {
+	// Interface is the interface whose frames can trigger, empty for all. The
+	// saved capture has the frames of all interfaces.
+	Interface string `json:"interface"`
+	// ErrorFrame triggers on error frames. Otherwise a received frame triggers
+	// when it matches any of IDs (any frame when empty) and its payload contains
+	// Data, a hex pattern such as "10 ?? 3E" where ?? matches any byte.
+	ErrorFrame bool        `json:"errorFrame"`
+	IDs        []CANFilter `json:"ids"`
+	Data       string      `json:"data"`
+	// PreTriggerMs and PostTriggerMs are the durations saved before and after the
+	// triggering frame.
+	PreTriggerMs  int `json:"preTriggerMs"`
+	PostTriggerMs int `json:"postTriggerMs"`
+	// Path is the capture file, written in the format of its extension as with
+	// StartLogging.
+	Path string `json:"path"`
+	// IncludeTx saves the frames sent by the app too.
+	IncludeTx bool `json:"includeTx"`
+	// Rearm arms the trigger again once a capture is saved; the captures are then
+	// numbered, eg trace-1.asc, trace-2.asc.
+	Rearm bool `json:"rearm"`
+}
+
+// TriggerStatus describes the trigger armed with ArmTrigger.
+type TriggerStatus struct {
+	// State is "armed", "triggered" while the post-trigger frames are recorded,
+	// or empty when no trigger is armed.
+	State    string `json:"state"`
+	Buffered int    `json:"buffered"`
+	// Captures is the number of captures saved.
+	Captures int    `json:"captures"`
+	LastFile string `json:"lastFile"`
+	Error    string `json:"error,omitempty"`
+}
+
+// TriggerEvent is emitted on "can:trigger" when a capture is saved.
+type TriggerEvent struct {
+	// Timestamp, Interface, ID, Extended, Error and Data describe the triggering frame.
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	ID        uint32    `json:"id"`
+	Extended  bool      `json:"extended"`
+	Error     bool      `json:"error"`
+	Data      []uint32  `json:"data"`
+	// File is the saved capture, with Frames frames.
+	File   string `json:"file"`
+	Frames int    `json:"frames"`
+	// Err is set when the capture could not be saved.
+	Err string `json:"err,omitempty"`
+}
+
+type frameTrigger struct {
+	opts    TriggerOptions
+	ids     []canbus.Filter
+	pattern dataPattern
+	pre     time.Duration
+	post    time.Duration
+	// saved is called with the frames of a capture, outside of mu.
+	saved func(t *frameTrigger, ev TriggerEvent, records []capture.Record)
+
+	mu sync.Mutex
+	// records[head:] are the buffered frames, oldest first.
+	records  []capture.Record
+	head     int
+	fired    *capture.Record
+	timer    *time.Timer
+	stopped  bool
+	captures int
+	lastFile string
+	err      error
+	// pending is held from the trigger until its capture is saved, so
+	// DisarmTrigger waits for it.
+	pending sync.WaitGroup
+}
+
+// ArmTrigger starts recording the frames of all started interfaces, like an
+// oscilloscope: when a frame matches the trigger condition, the frames from
+// PreTriggerMs before it to PostTriggerMs after it are saved to the capture file
+// and "can:trigger" is emitted. A single capture is saved unless Rearm is set.
+func (a *App) ArmTrigger(opts TriggerOptions) error {
+	opts.Interface = strings.TrimSpace(opts.Interface)
+	opts.Path = strings.TrimSpace(opts.Path)
+	if opts.Path == "" {
+		return errors.New("capture path is empty")
+	}
+	if opts.PreTriggerMs < 0 || opts.PostTriggerMs < 0 {
+		return errors.New("pre and post trigger durations must not be negative")
+	}
+	if opts.ErrorFrame && (len(opts.IDs) > 0 || strings.TrimSpace(opts.Data) != "") {
+		return errors.New("an error frame trigger has no IDs or data pattern")
+	}
+	pattern, err := parseDataPattern(opts.Data)
+	if err != nil {
+		return err
+	}
+	t := &frameTrigger{
+		opts:    opts,
+		pattern: pattern,
+		pre:     time.Duration(opts.PreTriggerMs) * time.Millisecond,
+		post:    time.Duration(opts.PostTriggerMs) * time.Millisecond,
+		saved:   a.saveTrigger,
+	}
+	for _, f := range opts.IDs {
+		t.ids = append(t.ids, canbus.Filter{ID: f.ID, Mask: f.Mask, Extended: f.Extended, Invert: f.Invert})
+	}
+
+	a.triggerMu.Lock()
+	defer a.triggerMu.Unlock()
+	if old := a.trigger.Load(); old != nil {
+		return fmt.Errorf("a trigger is already armed for %s", old.opts.Path)
+	}
+	a.trigger.Store(t)
+	return nil
+}
+
+// DisarmTrigger stops the armed trigger, waiting for a capture being saved. The
+// frames recorded after a trigger that is disarmed before the post-trigger time
+// are saved.
+func (a *App) DisarmTrigger() TriggerStatus {
+	a.triggerMu.Lock()
+	t := a.trigger.Swap(nil)
+	a.triggerMu.Unlock()
+
+	if t == nil {
+		return TriggerStatus{}
+	}
+	t.stop()
+	status := t.status()
+	status.State = ""
+	return status
+}
+
+// GetTriggerStatus returns the state of the trigger armed with ArmTrigger.
+func (a *App) GetTriggerStatus() TriggerStatus {
+	t := a.trigger.Load()
+	if t == nil {
+		return TriggerStatus{}
+	}
+	return t.status()
+}
+
+// add records a frame and checks the trigger condition.
+func (t *frameTrigger) add(ts time.Time, iface string, f *canbus.Frame, tx bool) {
+	if tx && !t.opts.IncludeTx {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return
+	}
+	if t.fired != nil && ts.After(t.fired.Timestamp.Add(t.post)) {
+		// frames arriving after the window while the timer fires
+		return
+	}
+
+	t.records = append(t.records, capture.Record{Timestamp: ts, Interface: iface, Frame: *f, TX: tx})
+	if t.fired == nil && !tx && t.matches(iface, f) {
+		rec := t.records[len(t.records)-1]
+		t.fired = &rec
+		t.pending.Add(1)
+		t.timer = time.AfterFunc(t.post, t.finish)
+	}
+	t.prune(ts)
+}
+
+func (t *frameTrigger) matches(iface string, f *canbus.Frame) bool {
+	if t.opts.Interface != "" && iface != t.opts.Interface {
+		return false
+	}
+	if t.opts.ErrorFrame {
+		return f.IsError
+	}
+	return !f.IsError && canbus.MatchAny(t.ids, f) && t.pattern.match(f.Payload())
+}
+
+// prune drops the frames older than the pre-trigger window, and the oldest ones
+// beyond maxTriggerFrames. It is called with mu held.
+func (t *frameTrigger) prune(now time.Time) {
+	oldest := now.Add(-t.pre)
+	if t.fired != nil {
+		oldest = t.fired.Timestamp.Add(-t.pre)
+	}
+	for t.head < len(t.records) && t.records[t.head].Timestamp.Before(oldest) {
+		t.head++
+	}
+	if n := len(t.records) - t.head; n > maxTriggerFrames {
+		t.head += n - maxTriggerFrames
+	}
+	if t.head > len(t.records)/2 {
+		t.records = append(t.records[:0], t.records[t.head:]...)
+		t.head = 0
+	}
+}
+
+// finish saves the capture of the trigger that fired, once the post-trigger time
+// has elapsed or the trigger is disarmed.
+func (t *frameTrigger) finish() {
+	defer t.pending.Done()
+
+	t.mu.Lock()
+	fired := *t.fired
+	records := append([]capture.Record(nil), t.records[t.head:]...)
+	t.records, t.head, t.fired, t.timer = t.records[:0], 0, nil, nil
+	t.captures++
+	path := t.opts.Path
+	if t.opts.Rearm {
+		ext := filepath.Ext(path)
+		path = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), t.captures, ext)
+	} else {
+		t.stopped = true
+	}
+	t.lastFile = path
+	t.mu.Unlock()
+
+	ev := TriggerEvent{
+		Timestamp: fired.Timestamp,
+		Interface: fired.Interface,
+		ID:        fired.Frame.ID,
+		Extended:  fired.Frame.IsExtended,
+		Error:     fired.Frame.IsError,
+		Data:      dataWords(fired.Frame.Payload()),
+		File:      path,
+		Frames:    len(records),
+	}
+	t.saved(t, ev, records)
+}
+
+// stop disarms the trigger, saving the capture in progress if it fired.
+func (t *frameTrigger) stop() {
+	t.mu.Lock()
+	t.stopped = true
+	fired := t.timer != nil && t.timer.Stop()
+	t.mu.Unlock()
+
+	if fired {
+		t.finish()
+	}
+	t.pending.Wait()
+}
+
+func (t *frameTrigger) status() TriggerStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := TriggerStatus{
+		State:    "armed",
+		Buffered: len(t.records) - t.head,
+		Captures: t.captures,
+		LastFile: t.lastFile,
+	}
+	switch {
+	case t.fired != nil:
+		s.State = "triggered"
+	case t.stopped:
+		s.State = ""
+	}
+	if t.err != nil {
+		s.Error = t.err.Error()
+	}
+	return s
+}
+
+// saveTrigger writes the frames of a capture and emits "can:trigger". A trigger
+// that does not rearm is disarmed.
+func (a *App) saveTrigger(t *frameTrigger, ev TriggerEvent, records []capture.Record) {
+	err := writeTraceFile(ev.File, records)
+	if err != nil {
+		ev.Err = err.Error()
+		a.emitError(fmt.Errorf("trigger capture %s: %w", ev.File, err))
+	}
+	t.mu.Lock()
+	t.err = err
+	t.mu.Unlock()
+	a.emit("can:trigger", ev)
+
+	if !t.opts.Rearm {
+		a.triggerMu.Lock()
+		a.trigger.CompareAndSwap(t, nil)
+		a.triggerMu.Unlock()
+	}
+}
+
+// writeTraceFile writes records to path in the format of its extension.
+func writeTraceFile(path string, records []capture.Record) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w, err := openTrace(logFormat(path), f)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	for i := range records {
+		r := &records[i]
+		if err = w.WriteFrame(r.Timestamp, r.Interface, r.Frame, r.TX); err != nil && err != errFrameSkipped {
+			break
+		}
+		err = nil
+	}
+	// formats with a trailer write it on Close
+	finish := w.Flush
+	if c, ok := w.(interface{ Close() error }); ok {
+		finish = c.Close
+	}
+	if ferr := finish(); err == nil {
+		err = ferr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMeasurement is the InfluxDB measurement when InfluxOptions.Measurement is empty.
+const DefaultMeasurement = "can_signals"
+
+// InfluxOptions configures an Influx writer. Bucket selects the InfluxDB 2 API,
+// Database the 1.x one.
+type InfluxOptions struct {
+	// Org, Bucket and Token address an InfluxDB 2 bucket.
+	Org    string
+	Bucket string
+	Token  string
+	// Database, Username and Password address an InfluxDB 1.x database.
+	Database string
+	Username string
+	Password string
+	// Measurement defaults to DefaultMeasurement.
+	Measurement string
+}
+
+// Influx writes points as line protocol to the HTTP API of InfluxDB, one line per
+// point: the measurement with the interface, message, signal and unit tags and the
+// value field.
+type Influx struct {
+	url         string
+	token       string
+	user        string
+	password    string
+	measurement string
+	client      *http.Client
+}
+
+var _ Writer = (*Influx)(nil)
+
+// NewInflux returns a writer to the InfluxDB server at rawURL, eg "http://localhost:8086".
+func NewInflux(rawURL string, opts InfluxOptions) (*Influx, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid InfluxDB URL %q: want http://host:8086", rawURL)
+	}
+	q := url.Values{"precision": {"ns"}}
+	switch {
+	case opts.Bucket != "":
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v2/write"
+		q.Set("bucket", opts.Bucket)
+		q.Set("org", opts.Org)
+	case opts.Database != "":
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/write"
+		q.Set("db", opts.Database)
+	default:
+		return nil, fmt.Errorf("InfluxDB needs a bucket (2.x) or a database (1.x)")
+	}
+	u.RawQuery = q.Encode()
+	w := &Influx{
+		url:         u.String(),
+		token:       opts.Token,
+		user:        opts.Username,
+		password:    opts.Password,
+		measurement: opts.Measurement,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+	if w.measurement == "" {
+		w.measurement = DefaultMeasurement
+	}
+	return w, nil
+}
+
+// Write posts the points. NaN and infinite values, which InfluxDB rejects, are skipped.
+func (w *Influx) Write(ctx context.Context, points []Point) error {
+	var body bytes.Buffer
+	for i := range points {
+		w.appendLine(&body, &points[i])
+	}
+	if body.Len() == 0 {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Token "+w.token)
+	} else if w.user != "" {
+		req.SetBasicAuth(w.user, w.password)
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("InfluxDB: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Close implements Writer, the HTTP client keeps no connection open.
+func (w *Influx) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
+
+func (w *Influx) appendLine(b *bytes.Buffer, p *Point) {
+	if math.IsNaN(p.Value) || math.IsInf(p.Value, 0) {
+		return
+	}
+	b.WriteString(measurementEscaper.Replace(w.measurement))
+	for _, tag := range [][2]string{{"interface", p.Interface}, {"message", p.Message}, {"signal", p.Signal}, {"unit", p.Unit}} {
+		// empty tag values are not valid line protocol
+		if tag[1] != "" {
+			b.WriteString("," + tag[0] + "=" + tagEscaper.Replace(tag[1]))
+		}
+	}
+	b.WriteString(" value=")
+	b.WriteString(strconv.FormatFloat(p.Value, 'g', -1, 64))
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatInt(p.Time.UnixNano(), 10))
+	b.WriteByte('\n')
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+)
@@ -0,0 +1,26 @@
+// Package tsdb writes decoded signal values to time series databases: InfluxDB
+// over its HTTP line protocol API, and PostgreSQL or TimescaleDB with a minimal
+// client of the PostgreSQL wire protocol.
+package tsdb
+
+import (
+	"context"
+	"time"
+)
+
+// Point is a signal value at a time.
+type Point struct {
+	Time      time.Time
+	Interface string
+	Message   string
+	Signal    string
+	Value     float64
+	Unit      string
+}
+
+// Writer stores points. Write either stores the whole batch or returns an error,
+// so a failed batch can be written again.
+type Writer interface {
+	Write(ctx context.Context, points []Point) error
+	Close() error
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"canproject/canbus"
+	"canproject/candb"
+)
+
+// Kinds of TXWarning.
+const (
+	txWarningUnknownID = "unknown-id"
+	txWarningLength    = "length"
+	txWarningRange     = "range"
+)
+
+// TXWarning is a problem found checking a frame to send against the loaded
+// databases.
+type TXWarning struct {
+	// Step is the number of the send step of a sequence, 0 for a single frame.
+	Step      int    `json:"step,omitempty"`
+	Interface string `json:"interface"`
+	ID        uint32 `json:"id"`
+	Extended  bool   `json:"extended"`
+	// Kind is "unknown-id" for an ID no database defines, "length" for a
+	// payload shorter or longer than the message and "range" for a signal
+	// value outside the range of the signal.
+	Kind    string `json:"kind"`
+	Message string `json:"message,omitempty"`
+	// Signal, Value, Min and Max are the signal out of range, its physical
+	// value and its range.
+	Signal string  `json:"signal,omitempty"`
+	Value  float64 `json:"value"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	// Text describes the warning, eg "EngineData: EngineSpeed 9000 is out of
+	// range [0, 8000]".
+	Text string `json:"text"`
+}
+
+// SendOptions are the options of SendFrameWithOptions.
+type SendOptions struct {
+	// FD sends a CAN FD frame, zero-padded to the next valid length, and BRS
+	// requests the bit rate switch.
+	FD  bool `json:"fd"`
+	BRS bool `json:"brs"`
+	// ValidateOnly checks the frame without sending it, the interface does not
+	// need to be started.
+	ValidateOnly bool `json:"validateOnly"`
+}
+
+// SequenceOptions are the options of RunSequenceWithOptions.
+type SequenceOptions struct {
+	// ValidateOnly checks the frames of the send steps without running the
+	// sequence.
+	ValidateOnly bool `json:"validateOnly"`
+}
+
+// SendFrameWithOptions checks a frame against the loaded databases, that its ID
+// is defined, its length is the length of the message and its signals are in
+// range, and sends it like SendFrame unless opts.ValidateOnly is set. The
+// warnings do not prevent sending; invalid frames, such as an 11-bit ID out of
+// range, fail.
+func (a *App) SendFrameWithOptions(iface string, id uint32, data []byte, extended bool, opts SendOptions) ([]TXWarning, error) {
+	iface = strings.TrimSpace(iface)
+	f, err := newFrame(id, data, extended, opts.FD || len(data) > canbus.MaxDataLength, opts.BRS)
+	if err != nil {
+		return nil, err
+	}
+	warnings := a.checkFrame(iface, &f, 0, []TXWarning{})
+	if opts.ValidateOnly {
+		return warnings, nil
+	}
+	return warnings, a.transmitRecorded(iface, f)
+}
+
+// RunSequenceWithOptions checks the frames of the send steps of a loaded
+// sequence like SendFrameWithOptions, each repeated block once, and runs it
+// like RunSequence unless opts.ValidateOnly is set.
+func (a *App) RunSequenceWithOptions(name string, opts SequenceOptions) ([]TXWarning, error) {
+	a.seqMu.Lock()
+	s := a.sequences[name]
+	a.seqMu.Unlock()
+	if s == nil {
+		return nil, fmt.Errorf("no sequence %q", name)
+	}
+	warnings := []TXWarning{}
+	for _, snd := range s.Sends() {
+		warnings = a.checkFrame(snd.Interface, &snd.Frame, snd.Step, warnings)
+	}
+	if opts.ValidateOnly {
+		return warnings, nil
+	}
+	return warnings, a.RunSequence(name)
+}
+
+// checkFrame appends the warnings about a frame to send to warnings.
+func (a *App) checkFrame(iface string, f *canbus.Frame, step int, warnings []TXWarning) []TXWarning {
+	if f.IsError {
+		return warnings
+	}
+	w := TXWarning{Step: step, Interface: iface, ID: f.ID, Extended: f.IsExtended}
+	add := func(kind, text string) {
+		w.Kind, w.Text = kind, text
+		warnings = append(warnings, w)
+	}
+	m, ok := a.lookupMessage(f.ID, f.IsExtended)
+	if !ok {
+		add(txWarningUnknownID, fmt.Sprintf("no message with ID %s in the loaded databases", formatCANID(f.ID, f.IsExtended)))
+		return warnings
+	}
+	w.Message = m.Name
+	// CAN FD payloads are padded to the next valid length
+	length := int(f.Length)
+	if length != m.Length && !(f.IsFD && length == canbus.PaddedLength(m.Length)) {
+		add(txWarningLength, fmt.Sprintf("%s: payload of %d bytes, the message has %d", m.Name, length, m.Length))
+	}
+	if f.IsRemote || m.ContainerHeader != candb.ContainerHeaderNone {
+		return warnings
+	}
+	data := f.Payload()
+	for _, s := range m.Signals {
+		if s.Max <= s.Min || !m.Present(s, data) {
+			continue
+		}
+		v, ok := s.Decode(data)
+		if !ok || (v.Physical >= s.Min && v.Physical <= s.Max) {
+			continue
+		}
+		w.Signal, w.Value, w.Min, w.Max = s.Name, v.Physical, s.Min, s.Max
+		add(txWarningRange, fmt.Sprintf("%s: %s %g is out of range [%g, %g]", m.Name, s.Name, v.Physical, s.Min, s.Max))
+		w.Signal, w.Value, w.Min, w.Max = "", 0, 0, 0
+	}
+	return warnings
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"canproject/canbus"
+)
+
+// txHistorySize bounds the number of frames kept by the TX history.
+const txHistorySize = 500
+
+// TxHistoryEntry is a frame sent with SendFrame, SendFDFrame or ResendFrame, or
+// through the TX queue.
+type TxHistoryEntry struct {
+	// Index identifies the entry for ResendFrame; it keeps increasing when old entries are dropped.
+	Index     int       `json:"index"`
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	ID        uint32    `json:"id"`
+	Extended  bool      `json:"extended"`
+	FD        bool      `json:"fd"`
+	BRS       bool      `json:"brs"`
+	Data      []uint32  `json:"data"`
+	// Error is set when the frame could not be written.
+	Error string `json:"error,omitempty"`
+}
+
+type txRecord struct {
+	index int
+	ts    time.Time
+	iface string
+	frame canbus.Frame
+	err   error
+}
+
+// GetTxHistory returns the frames sent from the UI and through the TX queue of
+// QueueFrame, oldest first.
+func (a *App) GetTxHistory() []TxHistoryEntry {
+	a.txHistMu.Lock()
+	defer a.txHistMu.Unlock()
+
+	entries := make([]TxHistoryEntry, len(a.txHistory))
+	for i, r := range a.txHistory {
+		entries[i] = TxHistoryEntry{
+			Index:     r.index,
+			Timestamp: r.ts,
+			Interface: r.iface,
+			ID:        r.frame.ID,
+			Extended:  r.frame.IsExtended,
+			FD:        r.frame.IsFD,
+			BRS:       r.frame.BRS,
+			Data:      dataWords(r.frame.Data[:r.frame.Length]),
+		}
+		if r.err != nil {
+			entries[i].Error = r.err.Error()
+		}
+	}
+	return entries
+}
+
+// ResendFrame sends the frame of a TX history entry again on its interface.
+func (a *App) ResendFrame(index int) error {
+	a.txHistMu.Lock()
+	var rec *txRecord
+	for i := range a.txHistory {
+		if a.txHistory[i].index == index {
+			rec = &a.txHistory[i]
+			break
+		}
+	}
+	var iface string
+	var f canbus.Frame
+	if rec != nil {
+		iface, f = rec.iface, rec.frame
+	}
+	a.txHistMu.Unlock()
+
+	if rec == nil {
+		return fmt.Errorf("no TX history entry %d", index)
+	}
+	return a.transmitRecorded(iface, f)
+}
+
+// ClearTxHistory forgets the sent frames.
+func (a *App) ClearTxHistory() {
+	a.txHistMu.Lock()
+	a.txHistory = nil
+	a.txHistMu.Unlock()
+}
+
+// transmitRecorded is transmit for the frames sent from the UI, which are kept in the TX history.
+func (a *App) transmitRecorded(iface string, f canbus.Frame) error {
+	err := a.transmit(iface, f)
+	if err == nil {
+		a.recordFrame(iface, &f)
+	}
+	a.recordTx(iface, f, err)
+	return err
+}
+
+// recordTx keeps a frame sent on iface in the TX history, err is the error of its write.
+func (a *App) recordTx(iface string, f canbus.Frame, err error) {
+	a.txHistMu.Lock()
+	defer a.txHistMu.Unlock()
+	a.nextTxHistory++
+	if len(a.txHistory) >= txHistorySize {
+		a.txHistory = append(a.txHistory[:0], a.txHistory[len(a.txHistory)-txHistorySize+1:]...)
+	}
+	a.txHistory = append(a.txHistory, txRecord{
+		index: a.nextTxHistory,
+		ts:    time.Now(),
+		iface: iface,
+		frame: f,
+		err:   err,
+	})
+}
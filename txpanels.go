@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"canproject/canbus"
+)
+
+// TXPanel is a named set of frames to send, kept by the backend for the
+// frontend and saved in the session profiles.
+type TXPanel struct {
+	Name  string        `json:"name"`
+	Items []TXPanelItem `json:"items"`
+}
+
+// TXPanelItem is a frame of a TXPanel: a message of the loaded databases
+// encoded from signal values, or a raw frame.
+type TXPanelItem struct {
+	// Label names the item in the panel, optional.
+	Label     string `json:"label"`
+	Interface string `json:"interface"`
+	// Message is the name of a message of the loaded databases, encoded from
+	// Signals over its transmit template. It is empty for a raw frame of ID,
+	// Extended, FD and Data.
+	Message  string             `json:"message"`
+	Signals  map[string]float64 `json:"signals"`
+	ID       uint32             `json:"id"`
+	Extended bool               `json:"extended"`
+	FD       bool               `json:"fd"`
+	Data     []uint32           `json:"data"`
+	// CycleMs is the period of a cyclic item, 0 for a frame sent on demand.
+	CycleMs int `json:"cycleMs"`
+}
+
+// ListTXPanels returns the TX panels by name.
+func (a *App) ListTXPanels() []TXPanel {
+	a.txPanelMu.Lock()
+	defer a.txPanelMu.Unlock()
+
+	panels := make([]TXPanel, 0, len(a.txPanels))
+	for _, p := range a.txPanels {
+		panels = append(panels, p.clone())
+	}
+	sort.Slice(panels, func(i, j int) bool { return panels[i].Name < panels[j].Name })
+	return panels
+}
+
+// GetTXPanel returns the TX panel name.
+func (a *App) GetTXPanel(name string) (TXPanel, error) {
+	a.txPanelMu.Lock()
+	defer a.txPanelMu.Unlock()
+
+	p, ok := a.txPanels[strings.TrimSpace(name)]
+	if !ok {
+		return TXPanel{}, fmt.Errorf("no TX panel %q", name)
+	}
+	return p.clone(), nil
+}
+
+// SaveTXPanel creates a TX panel or replaces the panel of the same name. The
+// messages of the items are looked up when they are sent, so a panel can be
+// saved before its databases are loaded.
+func (a *App) SaveTXPanel(panel TXPanel) error {
+	p, err := normalizeTXPanel(panel)
+	if err != nil {
+		return err
+	}
+	a.txPanelMu.Lock()
+	defer a.txPanelMu.Unlock()
+	if a.txPanels == nil {
+		a.txPanels = make(map[string]TXPanel)
+	}
+	a.txPanels[p.Name] = p
+	return nil
+}
+
+// RenameTXPanel renames a TX panel, failing if the new name is taken.
+func (a *App) RenameTXPanel(name, newName string) error {
+	name, newName = strings.TrimSpace(name), strings.TrimSpace(newName)
+	if newName == "" {
+		return fmt.Errorf("TX panel without name")
+	}
+	a.txPanelMu.Lock()
+	defer a.txPanelMu.Unlock()
+
+	p, ok := a.txPanels[name]
+	if !ok {
+		return fmt.Errorf("no TX panel %q", name)
+	}
+	if newName == name {
+		return nil
+	}
+	if _, ok := a.txPanels[newName]; ok {
+		return fmt.Errorf("TX panel %q already exists", newName)
+	}
+	delete(a.txPanels, name)
+	p.Name = newName
+	a.txPanels[newName] = p
+	return nil
+}
+
+// DeleteTXPanel removes a TX panel. The cyclic frames started from it keep running.
+func (a *App) DeleteTXPanel(name string) error {
+	a.txPanelMu.Lock()
+	defer a.txPanelMu.Unlock()
+
+	name = strings.TrimSpace(name)
+	if _, ok := a.txPanels[name]; !ok {
+		return fmt.Errorf("no TX panel %q", name)
+	}
+	delete(a.txPanels, name)
+	return nil
+}
+
+// SendTXPanelItem sends the item at index of a TX panel. An item with a cycle
+// time is started as a cyclic frame and its handle returned for
+// StopCyclicFrame, the other items are sent once and return 0.
+func (a *App) SendTXPanelItem(name string, index int) (int, error) {
+	p, err := a.GetTXPanel(name)
+	if err != nil {
+		return 0, err
+	}
+	if index < 0 || index >= len(p.Items) {
+		return 0, fmt.Errorf("TX panel %s has no item %d", p.Name, index)
+	}
+	it := &p.Items[index]
+	id, extended, fd, data := it.ID, it.Extended, it.FD, wordsBytes(it.Data)
+	if it.Message != "" {
+		m, payload, err := a.encodeSignals(it.Message, it.Signals)
+		if err != nil {
+			return 0, err
+		}
+		id, extended, fd, data = m.ID, m.IsExtended, len(payload) > canbus.MaxDataLength, payload
+	}
+	if it.CycleMs > 0 {
+		return a.StartCyclicFrame(it.Interface, id, data, extended, it.CycleMs)
+	}
+	return 0, a.sendFrame(it.Interface, id, data, extended, fd, false)
+}
+
+// normalizeTXPanel validates a panel and returns a copy of it.
+func normalizeTXPanel(panel TXPanel) (TXPanel, error) {
+	p := panel.clone()
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return TXPanel{}, fmt.Errorf("TX panel without name")
+	}
+	for i := range p.Items {
+		it := &p.Items[i]
+		it.Label = strings.TrimSpace(it.Label)
+		it.Interface = strings.TrimSpace(it.Interface)
+		it.Message = strings.TrimSpace(it.Message)
+		if it.CycleMs < 0 {
+			return TXPanel{}, fmt.Errorf("TX panel %s: item %d: cycle time must be >= 0 (got %d)", p.Name, i, it.CycleMs)
+		}
+		if it.Message != "" {
+			continue
+		}
+		for _, b := range it.Data {
+			if b > 0xff {
+				return TXPanel{}, fmt.Errorf("TX panel %s: item %d: data value %d is not a byte", p.Name, i, b)
+			}
+		}
+		if _, err := newFrame(it.ID, wordsBytes(it.Data), it.Extended, it.FD || len(it.Data) > canbus.MaxDataLength, false); err != nil {
+			return TXPanel{}, fmt.Errorf("TX panel %s: item %d: %w", p.Name, i, err)
+		}
+	}
+	return p, nil
+}
+
+// clone returns a deep copy of the panel.
+func (p TXPanel) clone() TXPanel {
+	p.Items = slices.Clone(p.Items)
+	if p.Items == nil {
+		p.Items = []TXPanelItem{}
+	}
+	for i := range p.Items {
+		it := &p.Items[i]
+		it.Signals = maps.Clone(it.Signals)
+		it.Data = slices.Clone(it.Data)
+	}
+	return p
+}
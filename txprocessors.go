@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+
+	"canproject/canbus"
+	"canproject/checksum"
+)
+
+// TXProcessor updates the rolling counter and the checksum of a message before
+// every cyclic transmission, so the ECUs checking them accept the frames.
+type TXProcessor struct {
+	ID       uint32      `json:"id"`
+	Extended bool        `json:"extended"`
+	Counter  *TXCounter  `json:"counter,omitempty"`
+	Checksum *TXChecksum `json:"checksum,omitempty"`
+}
+
+// TXCounter is a rolling counter of a TXProcessor.
+type TXCounter struct {
+	// Byte and Mask locate the counter, a group of contiguous bits of the byte;
+	// a zero Mask is the whole byte.
+	Byte int   `json:"byte"`
+	Mask uint8 `json:"mask"`
+	// Min and Max bound the values, the counter wrapping around from Max to
+	// Min. Both zero is the full range of Mask.
+	Min int `json:"min"`
+	Max int `json:"max"`
+	// Step is added every cycle, 1 when zero; a negative step counts down.
+	Step int `json:"step"`
+}
+
+// TXChecksum is a checksum byte of a TXProcessor, computed after the counter.
+type TXChecksum struct {
+	Byte int `json:"byte"`
+	// Algorithm is "xor", "sum", "crc8-j1850" or "crc8-autosar".
+	Algorithm string `json:"algorithm"`
+	// Seed replaces the initial value of the algorithm when set.
+	Seed *uint8 `json:"seed,omitempty"`
+	// From and To bound the bytes the checksum covers, To excluded and 0 for the
+	// end of the payload. The checksum byte itself is left out.
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// txProcessor is a validated TXProcessor.
+type txProcessor struct {
+	cfg TXProcessor
+
+	counterShift        int
+	counterMask         byte
+	counterMin, counter int
+	counterSpan, step   int
+
+	algorithm checksum.Algorithm
+	seed      byte
+}
+
+// SetTXProcessor sets the counter and checksum of a message the cyclic
+// transmissions update, replacing the processor of its ID. The cyclic frames
+// of the ID are then sent by the app rather than the kernel broadcast manager,
+// the payload changing every cycle. The counter starts from Min for each
+// transmission; the parts past the payload of a frame are left out.
+func (a *App) SetTXProcessor(p TXProcessor) error {
+	proc, err := newTXProcessor(p)
+	if err != nil {
+		return err
+	}
+	key := messageKey{id: p.ID, extended: p.Extended}
+	a.updateTXProcessors(func(procs map[messageKey]*txProcessor) { procs[key] = proc })
+	a.rescheduleCyclic(key)
+	return nil
+}
+
+// RemoveTXProcessor removes the processor of an ID set with SetTXProcessor.
+func (a *App) RemoveTXProcessor(id uint32, extended bool) error {
+	key := messageKey{id: id, extended: extended}
+	if procs := a.txProcs.Load(); procs == nil || (*procs)[key] == nil {
+		return fmt.Errorf("no TX processor for %s", formatCANID(id, extended))
+	}
+	a.updateTXProcessors(func(procs map[messageKey]*txProcessor) { delete(procs, key) })
+	a.rescheduleCyclic(key)
+	return nil
+}
+
+// ListTXProcessors returns the processors of SetTXProcessor, ordered by ID.
+func (a *App) ListTXProcessors() []TXProcessor {
+	list := []TXProcessor{}
+	if procs := a.txProcs.Load(); procs != nil {
+		for _, p := range *procs {
+			list = append(list, p.cfg)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Extended != list[j].Extended {
+			return !list[i].Extended
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// updateTXProcessors applies change to a copy of the processors and publishes it.
+func (a *App) updateTXProcessors(change func(map[messageKey]*txProcessor)) {
+	a.txProcMu.Lock()
+	defer a.txProcMu.Unlock()
+
+	procs := make(map[messageKey]*txProcessor)
+	if old := a.txProcs.Load(); old != nil {
+		for k, p := range *old {
+			procs[k] = p
+		}
+	}
+	change(procs)
+	a.txProcs.Store(&procs)
+}
+
+// txProcessorFor returns the processor of the ID of f, nil if none.
+func (a *App) txProcessorFor(f *canbus.Frame) *txProcessor {
+	procs := a.txProcs.Load()
+	if procs == nil {
+		return nil
+	}
+	return (*procs)[messageKey{id: f.ID, extended: f.IsExtended}]
+}
+
+// rescheduleCyclic restarts the cyclic transmissions of key, which moves them
+// between the kernel broadcast manager and the app.
+func (a *App) rescheduleCyclic(key messageKey) {
+	a.cyclicMu.Lock()
+	defer a.cyclicMu.Unlock()
+
+	for _, job := range a.cyclicJobs {
+		if job.frame.ID == key.id && job.frame.IsExtended == key.extended {
+			job.stop()
+			a.schedule(job)
+		}
+	}
+}
+
+func newTXProcessor(p TXProcessor) (*txProcessor, error) {
+	name := formatCANID(p.ID, p.Extended)
+	if p.Counter == nil && p.Checksum == nil {
+		return nil, fmt.Errorf("TX processor for %s has neither a counter nor a checksum", name)
+	}
+	proc := &txProcessor{cfg: p}
+	if c := p.Counter; c != nil {
+		c := *c
+		proc.cfg.Counter = &c
+		if c.Byte < 0 || c.Byte >= canbus.MaxFDDataLength {
+			return nil, fmt.Errorf("TX processor for %s: counter byte %d out of range", name, c.Byte)
+		}
+		mask := c.Mask
+		if mask == 0 {
+			mask = 0xff
+		}
+		shift := bits.TrailingZeros8(mask)
+		width := bits.OnesCount8(mask)
+		if int(mask>>shift) != 1<<width-1 {
+			return nil, fmt.Errorf("TX processor for %s: counter mask 0x%02X is not contiguous", name, c.Mask)
+		}
+		lo, hi := c.Min, c.Max
+		if lo == 0 && hi == 0 {
+			hi = 1<<width - 1
+		}
+		if lo < 0 || hi < lo || hi >= 1<<width {
+			return nil, fmt.Errorf("TX processor for %s: counter range %d-%d does not fit mask 0x%02X", name, lo, hi, mask)
+		}
+		step := c.Step
+		if step == 0 {
+			step = 1
+		}
+		proc.counterShift, proc.counterMask = shift, mask
+		proc.counterMin, proc.counterSpan, proc.step = lo, hi-lo+1, step
+	}
+	if c := p.Checksum; c != nil {
+		c := *c
+		proc.cfg.Checksum = &c
+		alg, err := checksum.Lookup(strings.ToLower(strings.TrimSpace(c.Algorithm)))
+		if err != nil {
+			return nil, fmt.Errorf("TX processor for %s: %w", name, err)
+		}
+		if c.Byte < 0 || c.Byte >= canbus.MaxFDDataLength {
+			return nil, fmt.Errorf("TX processor for %s: checksum byte %d out of range", name, c.Byte)
+		}
+		if c.From < 0 || c.To < 0 || c.To != 0 && c.To <= c.From {
+			return nil, fmt.Errorf("TX processor for %s: invalid checksum range %d-%d", name, c.From, c.To)
+		}
+		proc.algorithm, proc.seed = alg, alg.Seed
+		if c.Seed != nil {
+			proc.seed = *c.Seed
+		}
+	}
+	return proc, nil
+}
+
+// apply writes the counter value of *count and the checksum into f, then
+// advances *count. *count is the index of the value from Min.
+func (p *txProcessor) apply(f *canbus.Frame, count *int) {
+	payload := f.Payload()
+	if c := p.cfg.Counter; c != nil && c.Byte < len(payload) {
+		v := byte(p.counterMin+*count) << p.counterShift
+		payload[c.Byte] = payload[c.Byte]&^p.counterMask | v&p.counterMask
+		*count = ((*count+p.step)%p.counterSpan + p.counterSpan) % p.counterSpan
+	}
+	if c := p.cfg.Checksum; c != nil && c.Byte < len(payload) {
+		to := len(payload)
+		if c.To != 0 {
+			to = min(c.To, to)
+		}
+		var buf [canbus.MaxFDDataLength]byte
+		covered := buf[:0]
+		for i := c.From; i < to; i++ {
+			if i != c.Byte {
+				covered = append(covered, payload[i])
+			}
+		}
+		payload[c.Byte] = p.algorithm.SumSeed(p.seed, covered)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+)
+
+// defaultTxQueueDepth is the depth of a TX queue created by QueueFrame.
+const defaultTxQueueDepth = 1000
+
+// TxQueueStatus describes the TX queue of an interface, and is emitted on
+// "can:txoverflow" when a frame is rejected because the queue is full.
+type TxQueueStatus struct {
+	Interface string `json:"interface"`
+	Depth     int    `json:"depth"`
+	// MaxFramesPerSec limits the transmission rate, 0 for no limit.
+	MaxFramesPerSec int    `json:"maxFramesPerSec"`
+	Pending         int    `json:"pending"`
+	Sent            uint64 `json:"sent"`
+	Failed          uint64 `json:"failed"`
+	Overflows       uint64 `json:"overflows"`
+}
+
+// CANTxEvent reports a frame of the TX queue on "can:tx" once it was written.
+type CANTxEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	// Seq is the sequence number returned by QueueFrame.
+	Seq      uint64 `json:"seq"`
+	ID       uint32 `json:"id"`
+	Extended bool   `json:"extended"`
+	// Error is set when the frame could not be written.
+	Error string `json:"error,omitempty"`
+}
+
+type queuedFrame struct {
+	seq   uint64
+	frame canbus.Frame
+}
+
+// txQueue transmits the frames queued on an interface from a goroutine.
+type txQueue struct {
+	iface  string
+	signal chan struct{}
+	// room is signalled when a frame leaves the queue.
+	room   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu        sync.Mutex
+	items     []queuedFrame
+	depth     int
+	rate      int
+	seq       uint64
+	sent      uint64
+	failed    uint64
+	overflows uint64
+}
+
+// ConfigureTxQueue sets the depth and rate limit of the TX queue of a started interface.
+// maxFramesPerSec 0 sends as fast as the interface accepts frames. Frames already
+// queued are kept when the depth is reduced.
+func (a *App) ConfigureTxQueue(iface string, depth int, maxFramesPerSec int) error {
+	if depth < 1 {
+		return fmt.Errorf("queue depth must be >= 1 (got %d)", depth)
+	}
+	if maxFramesPerSec < 0 {
+		return fmt.Errorf("max frames per second must be >= 0 (got %d)", maxFramesPerSec)
+	}
+	q, err := a.txQueue(iface)
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	q.depth = depth
+	q.rate = maxFramesPerSec
+	q.mu.Unlock()
+	return nil
+}
+
+// QueueFrame queues a frame for transmission on a started interface and returns its
+// sequence number immediately. Completed transmissions are reported on "can:tx";
+// when the queue is full the frame is rejected and "can:txoverflow" is emitted.
+func (a *App) QueueFrame(iface string, id uint32, data []byte, extended bool) (uint64, error) {
+	f, err := newFrame(id, data, extended, len(data) > canbus.MaxDataLength, false)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := a.txConn(iface, f.IsFD); err != nil {
+		return 0, err
+	}
+	q, err := a.txQueue(iface)
+	if err != nil {
+		return 0, err
+	}
+	seq, ok := q.push(f)
+	if !ok {
+		q.mu.Lock()
+		q.overflows++
+		st := q.statusLocked()
+		q.mu.Unlock()
+		a.emit("can:txoverflow", st)
+		return 0, fmt.Errorf("TX queue of %s is full (%d frames)", q.iface, st.Depth)
+	}
+	return seq, nil
+}
+
+// queueFrameWait queues f like QueueFrame, waiting for room in the queue
+// instead of rejecting it.
+func (a *App) queueFrameWait(ctx context.Context, iface string, f canbus.Frame) (uint64, error) {
+	if _, err := a.txConn(iface, f.IsFD); err != nil {
+		return 0, err
+	}
+	for {
+		q, err := a.txQueue(iface)
+		if err != nil {
+			return 0, err
+		}
+		if seq, ok := q.push(f); ok {
+			return seq, nil
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-q.done:
+		case <-q.room:
+		}
+	}
+}
+
+// GetTxQueueStatus returns the state of the TX queue of a started interface.
+func (a *App) GetTxQueueStatus(iface string) (TxQueueStatus, error) {
+	q, err := a.txQueue(iface)
+	if err != nil {
+		return TxQueueStatus{}, err
+	}
+	return q.status(), nil
+}
+
+// ClearTxQueue drops the frames waiting in the TX queue of a started interface.
+func (a *App) ClearTxQueue(iface string) error {
+	q, err := a.txQueue(iface)
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	q.items = nil
+	q.mu.Unlock()
+	return nil
+}
+
+// txQueue returns the TX queue of a started interface, creating it on first use.
+func (a *App) txQueue(iface string) (*txQueue, error) {
+	iface = strings.TrimSpace(iface)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	sess := a.sessions[iface]
+	if sess == nil || sess.conn == nil {
+		return nil, fmt.Errorf("CAN not started on %s", iface)
+	}
+	if sess.txq == nil {
+		ctx, cancel := context.WithCancel(sess.ctx)
+		sess.txq = &txQueue{
+			iface:  iface,
+			signal: make(chan struct{}, 1),
+			room:   make(chan struct{}, 1),
+			cancel: cancel,
+			done:   make(chan struct{}),
+			depth:  defaultTxQueueDepth,
+		}
+		go a.txQueueLoop(ctx, sess.txq)
+	}
+	return sess.txq, nil
+}
+
+// stopTxQueue stops the TX queue of a session, dropping the frames it still holds.
+func (a *App) stopTxQueue(sess *canSession) {
+	a.mu.Lock()
+	q := sess.txq
+	sess.txq = nil
+	a.mu.Unlock()
+
+	if q != nil {
+		q.cancel()
+		<-q.done
+	}
+}
+
+func (a *App) txQueueLoop(ctx context.Context, q *txQueue) {
+	defer close(q.done)
+
+	var last time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.signal:
+		}
+
+		for {
+			q.mu.Lock()
+			if len(q.items) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			item := q.items[0]
+			q.items = q.items[1:]
+			rate := q.rate
+			q.mu.Unlock()
+			select {
+			case q.room <- struct{}{}:
+			default:
+			}
+
+			if rate > 0 {
+				wait := time.Until(last.Add(time.Second / time.Duration(rate)))
+				if err := sleepCtx(ctx, wait); err != nil {
+					return
+				}
+			}
+			err := a.send(q.iface, item.frame)
+			last = time.Now()
+			a.recordTx(q.iface, item.frame, err)
+
+			ev := CANTxEvent{
+				Timestamp: last,
+				Interface: q.iface,
+				Seq:       item.seq,
+				ID:        item.frame.ID,
+				Extended:  item.frame.IsExtended,
+			}
+			q.mu.Lock()
+			if err != nil {
+				q.failed++
+				ev.Error = err.Error()
+			} else {
+				q.sent++
+			}
+			q.mu.Unlock()
+			a.emit("can:tx", ev)
+		}
+	}
+}
+
+// push appends f to the queue and returns its sequence number, or false when
+// the queue is full.
+func (q *txQueue) push(f canbus.Frame) (uint64, bool) {
+	q.mu.Lock()
+	if len(q.items) >= q.depth {
+		q.mu.Unlock()
+		return 0, false
+	}
+	q.seq++
+	seq := q.seq
+	q.items = append(q.items, queuedFrame{seq: seq, frame: f})
+	q.mu.Unlock()
+
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+	return seq, true
+}
+
+func (q *txQueue) status() TxQueueStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.statusLocked()
+}
+
+// statusLocked is status with q.mu held.
+func (q *txQueue) statusLocked() TxQueueStatus {
+	return TxQueueStatus{
+		Interface:       q.iface,
+		Depth:           q.depth,
+		MaxFramesPerSec: q.rate,
+		Pending:         len(q.items),
+		Sent:            q.sent,
+		Failed:          q.failed,
+		Overflows:       q.overflows,
+	}
+}
+
+// sleepCtx sleeps for d or until ctx is cancelled.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
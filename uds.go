@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"canproject/dtc"
+	"canproject/seedkey"
+	"canproject/uds"
+)
+
+// udsRequestTimeout bounds a whole UDS request, including "response pending" extensions.
+const udsRequestTimeout = 30 * time.Second
+
+// UDSResponse is the positive response to a UDS request.
+type UDSResponse struct {
+	// Service is the positive response SID (request SID + 0x40).
+	Service uint8 `json:"service"`
+	// Data is the response after the SID.
+	Data []uint32 `json:"data"`
+}
+
+// UDSSessionTiming is the timing reported by DiagnosticSessionControl.
+type UDSSessionTiming struct {
+	P2Ms     int64 `json:"p2Ms"`
+	P2StarMs int64 `json:"p2StarMs"`
+}
+
+// UDSDTC is a diagnostic trouble code read with UDSReadDTCs.
+type UDSDTC struct {
+	Code uint32 `json:"code"`
+	// Name is the code string, eg "P0123-13".
+	Name string `json:"name"`
+	// Description is the description of the DTC database of LoadDTCDatabase, if any.
+	Description string `json:"description,omitempty"`
+	// FailureType describes the failure type byte, eg "circuit open".
+	FailureType string `json:"failureType,omitempty"`
+	Status      uint8  `json:"status"`
+	// StatusFlags are the names of the bits set in Status, eg "confirmedDTC".
+	StatusFlags []string `json:"statusFlags"`
+}
+
+// UDSRequest sends a raw UDS request on an ISO-TP channel opened with OpenIsoTP,
+// or a DoIP connection opened with OpenDoIP, and returns the positive response.
+// Negative responses are returned as errors.
+func (a *App) UDSRequest(handle int, data []byte) (UDSResponse, error) {
+	var resp []byte
+	err := a.withUDS(handle, func(ctx context.Context, c *uds.Client) (err error) {
+		resp, err = c.Request(ctx, data)
+		return err
+	})
+	if err != nil {
+		return UDSResponse{}, err
+	}
+	return UDSResponse{Service: resp[0], Data: dataWords(resp[1:])}, nil
+}
+
+// UDSDiagnosticSessionControl switches the ECU into a diagnostic session (1 default,
+// 2 programming, 3 extended) and returns the session timing it reports.
+// The timing is applied to the following requests on the channel.
+func (a *App) UDSDiagnosticSessionControl(handle int, session uint8) (UDSSessionTiming, error) {
+	var t uds.SessionTiming
+	err := a.withUDS(handle, func(ctx context.Context, c *uds.Client) (err error) {
+		t, err = c.DiagnosticSessionControl(ctx, session)
+		return err
+	})
+	if err != nil {
+		return UDSSessionTiming{}, err
+	}
+	return UDSSessionTiming{P2Ms: t.P2.Milliseconds(), P2StarMs: t.P2Star.Milliseconds()}, nil
+}
+
+// UDSECUReset resets the ECU (1 hard, 2 key off/on, 3 soft).
+func (a *App) UDSECUReset(handle int, resetType uint8) error {
+	return a.withUDS(handle, func(ctx context.Context, c *uds.Client) error {
+		return c.ECUReset(ctx, resetType)
+	})
+}
+
+// UDSReadDataByIdentifier reads the value of a data identifier, eg 0xF190 for the VIN.
+func (a *App) UDSReadDataByIdentifier(handle int, did uint16) ([]uint32, error) {
+	var data []byte
+	err := a.withUDS(handle, func(ctx context.Context, c *uds.Client) (err error) {
+		data, err = c.ReadDataByIdentifier(ctx, did)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dataWords(data), nil
+}
+
+// UDSReadDTCs returns the DTCs whose status matches statusMask (0xFF for all).
+func (a *App) UDSReadDTCs(handle int, statusMask uint8) ([]UDSDTC, error) {
+	var dtcs []uds.DTC
+	err := a.withUDS(handle, func(ctx context.Context, c *uds.Client) (err error) {
+		dtcs, err = c.ReadDTCs(ctx, statusMask)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.describeDTCs(dtcs), nil
+}
+
+// describeDTCs names dtcs with the DTC database of LoadDTCDatabase.
+func (a *App) describeDTCs(dtcs []uds.DTC) []UDSDTC {
+	db := a.dtcDatabase.Load()
+	out := make([]UDSDTC, len(dtcs))
+	for i, d := range dtcs {
+		name := d.String()
+		out[i] = UDSDTC{
+			Code:        d.Code,
+			Name:        name,
+			Description: db.Describe(name),
+			FailureType: db.FailureType(byte(d.Code)),
+			Status:      d.Status,
+			StatusFlags: dtc.StatusFlags(d.Status),
+		}
+	}
+	return out
+}
+
+// UDSTesterPresent keeps a non-default diagnostic session alive.
+func (a *App) UDSTesterPresent(handle int) error {
+	return a.withUDS(handle, func(ctx context.Context, c *uds.Client) error {
+		return c.TesterPresent(ctx)
+	})
+}
+
+// UDSMemoryFormat is the addressAndLengthFormatIdentifier of the memory
+// services: the number of bytes of the address (1-8) and size (1-4) fields of
+// the request, 0 for the fewest bytes holding the value.
+type UDSMemoryFormat struct {
+	AddressBytes int `json:"addressBytes"`
+	SizeBytes    int `json:"sizeBytes"`
+}
+
+// UDSMemoryDump is the memory read or written by UDSReadMemoryByAddress and
+// UDSWriteMemoryByAddress, emitted on "uds:memory".
+type UDSMemoryDump struct {
+	Handle  int    `json:"handle"`
+	Address uint64 `json:"address"`
+	// Write is set for the memory written.
+	Write bool     `json:"write"`
+	Data  []uint32 `json:"data"`
+	// Hexdump shows Data 16 bytes a line, each line starting with the address
+	// of its first byte and ending with its printable characters.
+	Hexdump string `json:"hexdump"`
+}
+
+// UDSReadMemoryByAddress reads size bytes of the ECU memory at address and
+// emits them on "uds:memory".
+func (a *App) UDSReadMemoryByAddress(handle int, address uint64, size uint32, format UDSMemoryFormat) (UDSMemoryDump, error) {
+	var data []byte
+	err := a.withUDS(handle, func(ctx context.Context, c *uds.Client) (err error) {
+		data, err = c.ReadMemoryByAddress(ctx, uds.MemoryFormat(format), address, size)
+		return err
+	})
+	if err != nil {
+		return UDSMemoryDump{}, err
+	}
+	d := memoryDump(handle, address, data, false, format)
+	a.emit("uds:memory", d)
+	return d, nil
+}
+
+// UDSWriteMemoryByAddress writes data to the ECU memory at address and emits it
+// on "uds:memory". It fails on ISO-TP channels of interfaces started read-only.
+func (a *App) UDSWriteMemoryByAddress(handle int, address uint64, data []byte, format UDSMemoryFormat) (UDSMemoryDump, error) {
+	if err := a.checkUDSWritable(handle); err != nil {
+		return UDSMemoryDump{}, err
+	}
+	err := a.withUDS(handle, func(ctx context.Context, c *uds.Client) error {
+		return c.WriteMemoryByAddress(ctx, uds.MemoryFormat(format), address, data)
+	})
+	if err != nil {
+		return UDSMemoryDump{}, err
+	}
+	d := memoryDump(handle, address, data, true, format)
+	a.emit("uds:memory", d)
+	return d, nil
+}
+
+// checkUDSWritable fails if handle is an ISO-TP channel of an interface
+// started read-only.
+func (a *App) checkUDSWritable(handle int) error {
+	a.isotpMu.Lock()
+	c := a.isotpChannels[handle]
+	a.isotpMu.Unlock()
+	if c == nil {
+		return nil
+	}
+	return a.checkWritable(c.info.Interface)
+}
+
+func memoryDump(handle int, address uint64, data []byte, write bool, format UDSMemoryFormat) UDSMemoryDump {
+	// the addresses are as wide as the address field, 4 bytes at least
+	width := 2 * max(format.AddressBytes, 4)
+	for address+uint64(len(data)) > 1<<(4*width) && width < 16 {
+		width += 2
+	}
+	var sb strings.Builder
+	for off := 0; off < len(data); off += 16 {
+		line := data[off:min(off+16, len(data))]
+		fmt.Fprintf(&sb, "%0*X ", width, address+uint64(off))
+		for i := range 16 {
+			if i == 8 {
+				sb.WriteByte(' ')
+			}
+			if i < len(line) {
+				fmt.Fprintf(&sb, " %02X", line[i])
+			} else {
+				sb.WriteString("   ")
+			}
+		}
+		sb.WriteString("  |")
+		for _, b := range line {
+			if b < 0x20 || b > 0x7e {
+				b = '.'
+			}
+			sb.WriteByte(b)
+		}
+		sb.WriteString("|\n")
+	}
+	return UDSMemoryDump{Handle: handle, Address: address, Write: write, Data: dataWords(data), Hexdump: sb.String()}
+}
+
+// securityAlgorithm is the seed-key algorithm of LoadSecurityAlgorithm.
+type securityAlgorithm struct {
+	path string
+	algo seedkey.Algorithm
+}
+
+// LoadSecurityAlgorithm registers the seed-key algorithm of UDSSecurityAccess: a Lua
+// script defining key(level, seed) when path ends with .lua, otherwise an external
+// program run as "program <level> <seed hex>" which prints the key in hex.
+// It replaces the algorithm registered before.
+func (a *App) LoadSecurityAlgorithm(path string) error {
+	path = strings.TrimSpace(path)
+	algo, err := seedkey.Load(path)
+	if err != nil {
+		return err
+	}
+	if old := a.securityAlgo.Swap(&securityAlgorithm{path: path, algo: algo}); old != nil {
+		old.algo.Close()
+	}
+	return nil
+}
+
+// UnloadSecurityAlgorithm removes the registered seed-key algorithm.
+func (a *App) UnloadSecurityAlgorithm() {
+	if old := a.securityAlgo.Swap(nil); old != nil {
+		old.algo.Close()
+	}
+}
+
+// GetSecurityAlgorithm returns the path of the registered seed-key algorithm, empty when none.
+func (a *App) GetSecurityAlgorithm() string {
+	if s := a.securityAlgo.Load(); s != nil {
+		return s.path
+	}
+	return ""
+}
+
+// UDSSecurityAccess unlocks a security level (odd, 0x01 0x03 ...) with the seed/key
+// handshake: the seed is requested with level, its key is computed by the algorithm
+// of LoadSecurityAlgorithm and sent with level+1.
+func (a *App) UDSSecurityAccess(handle int, level uint8) error {
+	s := a.securityAlgo.Load()
+	if s == nil {
+		return errors.New("no security algorithm loaded")
+	}
+	return a.withUDS(handle, func(ctx context.Context, c *uds.Client) error {
+		return c.SecurityAccess(ctx, level, s.algo.Key)
+	})
+}
+
+func (a *App) withUDS(handle int, fn func(ctx context.Context, c *uds.Client) error) error {
+	c, err := a.udsClient(handle)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), udsRequestTimeout)
+	defer cancel()
+	return fn(ctx, c)
+}
@@ -0,0 +1,43 @@
+package uds
+
+import "fmt"
+
+// didNames are the data identifiers of ISO 14229-1 annex C.
+var didNames = map[uint16]string{
+	0xf180: "BootSoftwareIdentification",
+	0xf181: "ApplicationSoftwareIdentification",
+	0xf182: "ApplicationDataIdentification",
+	0xf183: "BootSoftwareFingerprint",
+	0xf184: "ApplicationSoftwareFingerprint",
+	0xf185: "ApplicationDataFingerprint",
+	0xf186: "ActiveDiagnosticSession",
+	0xf187: "VehicleManufacturerSparePartNumber",
+	0xf188: "VehicleManufacturerECUSoftwareNumber",
+	0xf189: "VehicleManufacturerECUSoftwareVersionNumber",
+	0xf18a: "SystemSupplierIdentifier",
+	0xf18b: "ECUManufacturingDate",
+	0xf18c: "ECUSerialNumber",
+	0xf18d: "SupportedFunctionalUnits",
+	0xf18e: "VehicleManufacturerKitAssemblyPartNumber",
+	0xf190: "VIN",
+	0xf191: "VehicleManufacturerECUHardwareNumber",
+	0xf192: "SystemSupplierECUHardwareNumber",
+	0xf193: "SystemSupplierECUHardwareVersionNumber",
+	0xf194: "SystemSupplierECUSoftwareNumber",
+	0xf195: "SystemSupplierECUSoftwareVersionNumber",
+	0xf196: "ExhaustRegulationOrTypeApprovalNumber",
+	0xf197: "SystemNameOrEngineType",
+	0xf198: "RepairShopCodeOrTesterSerialNumber",
+	0xf199: "ProgrammingDate",
+	0xf19d: "ECUInstallationDate",
+	0xf19e: "ODXFile",
+}
+
+// DIDName returns the ISO 14229 name of a data identifier, or its number for
+// the identifiers the standard leaves to the manufacturers.
+func DIDName(did uint16) string {
+	if name, ok := didNames[did]; ok {
+		return name
+	}
+	return fmt.Sprintf("DID 0x%04X", did)
+}
@@ -0,0 +1,35 @@
+package uds
+
+import "time"
+
+// Exchange is a request sent by a client and its outcome, see Observe.
+type Exchange struct {
+	Request []byte
+	// Response is the final positive or negative response, nil when none was
+	// received: on errors and for requests whose response is suppressed.
+	Response []byte
+	// Sent is when the request was sent, Received when Response was.
+	Sent     time.Time
+	Received time.Time
+	// Pending counts the "response pending" answers before Response.
+	Pending int
+	Err     error
+}
+
+// Observe makes the client call fn with every request it sent once it is
+// answered or failed, nil stops it. fn runs on the goroutine of the request
+// and must not send requests on the client.
+func (c *Client) Observe(fn func(Exchange)) {
+	c.mu.Lock()
+	c.observer = fn
+	c.mu.Unlock()
+}
+
+func (c *Client) observe(x Exchange) {
+	c.mu.Lock()
+	fn := c.observer
+	c.mu.Unlock()
+	if fn != nil {
+		fn(x)
+	}
+}
@@ -0,0 +1,85 @@
+package uds
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultSession is the diagnostic session the server starts in, which needs
+// no TesterPresent to be kept.
+const DefaultSession = 0x01
+
+// suppressPositiveResponse is the suppressPosRspMsgIndicationBit of a
+// sub-function: the server does not send the positive response.
+const suppressPositiveResponse = 0x80
+
+// send sends a request and notes when it was sent.
+func (c *Client) send(ctx context.Context, req []byte) error {
+	if err := c.tr.Send(ctx, req); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.last = time.Now()
+	c.mu.Unlock()
+	return nil
+}
+
+// noteSession tracks the session the server is in from the positive response
+// to req: what DiagnosticSessionControl selected, the default session after
+// an ECUReset.
+func (c *Client) noteSession(req []byte) {
+	var session byte
+	switch {
+	case req[0] == DiagnosticSessionControl && len(req) >= 2:
+		session = req[1] &^ suppressPositiveResponse
+	case req[0] == ECUReset:
+		session = DefaultSession
+	default:
+		return
+	}
+	c.mu.Lock()
+	c.session = session
+	c.mu.Unlock()
+}
+
+// Session returns the diagnostic session the server was last switched to by
+// a request of the client, DefaultSession before any.
+func (c *Client) Session() byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.session == 0 {
+		return DefaultSession
+	}
+	return c.session
+}
+
+// Idle returns the time since the client last sent a request. The server
+// keeps a non-default session for S3 (5 s) after each request.
+func (c *Client) Idle() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last.IsZero() {
+		return time.Duration(1<<63 - 1)
+	}
+	return time.Since(c.last)
+}
+
+// KeepAlive sends a TesterPresent unless a request is outstanding, which keeps
+// the session alive itself, and reports whether it was sent. With suppress the
+// positive response is suppressed (0x3E 0x80) and KeepAlive returns once the
+// request is sent, otherwise it waits for the response like TesterPresent.
+func (c *Client) KeepAlive(ctx context.Context, suppress bool) (bool, error) {
+	if !c.reqMu.TryLock() {
+		return false, nil
+	}
+	defer c.reqMu.Unlock()
+	if suppress {
+		req := []byte{TesterPresent, suppressPositiveResponse}
+		x := Exchange{Request: req, Sent: time.Now()}
+		x.Err = c.send(ctx, req)
+		c.observe(x)
+		return true, x.Err
+	}
+	_, err := c.do(ctx, []byte{TesterPresent, 0x00})
+	return true, err
+}
@@ -0,0 +1,96 @@
+package uds
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// MemoryFormat is the addressAndLengthFormatIdentifier of the memory
+// services: the number of bytes of the memoryAddress and memorySize fields.
+// Zero uses the fewest bytes holding the value.
+type MemoryFormat struct {
+	AddressBytes int
+	SizeBytes    int
+}
+
+// memoryRequest returns the addressAndLengthFormatIdentifier, memoryAddress and
+// memorySize fields of a memory request.
+func memoryRequest(f MemoryFormat, address uint64, size uint32) ([]byte, error) {
+	na, err := fieldBytes("address", f.AddressBytes, address, 8)
+	if err != nil {
+		return nil, err
+	}
+	ns, err := fieldBytes("size", f.SizeBytes, uint64(size), 4)
+	if err != nil {
+		return nil, err
+	}
+	b := []byte{byte(ns<<4 | na)}
+	for i := na - 1; i >= 0; i-- {
+		b = append(b, byte(address>>(8*i)))
+	}
+	for i := ns - 1; i >= 0; i-- {
+		b = append(b, byte(size>>(8*i)))
+	}
+	return b, nil
+}
+
+// fieldBytes returns the length of a field holding v, n when set.
+func fieldBytes(field string, n int, v uint64, max int) (int, error) {
+	need := 1
+	for v>>(8*need) != 0 {
+		need++
+	}
+	switch {
+	case n == 0:
+		return need, nil
+	case n < 0 || n > max:
+		return 0, fmt.Errorf("uds: memory %s length %d out of range 1-%d", field, n, max)
+	case n < need:
+		return 0, fmt.Errorf("uds: memory %s 0x%X does not fit in %d bytes", field, v, n)
+	}
+	return n, nil
+}
+
+// ReadMemoryByAddress reads size bytes of the server memory at address.
+func (c *Client) ReadMemoryByAddress(ctx context.Context, f MemoryFormat, address uint64, size uint32) ([]byte, error) {
+	if size == 0 {
+		return nil, fmt.Errorf("uds: memory size is 0")
+	}
+	fields, err := memoryRequest(f, address, size)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.request(ctx, 1, append([]byte{ReadMemoryByAddress}, fields...)...)
+	if err != nil {
+		return nil, err
+	}
+	if got := len(resp) - 1; got != int(size) {
+		return nil, fmt.Errorf("uds: read %d bytes of memory, requested %d", got, size)
+	}
+	return resp[1:], nil
+}
+
+// WriteMemoryByAddress writes data to the server memory at address.
+func (c *Client) WriteMemoryByAddress(ctx context.Context, f MemoryFormat, address uint64, data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("uds: no data to write")
+	}
+	if uint64(len(data)) > 1<<32-1 {
+		return fmt.Errorf("uds: %d bytes do not fit in a memory size", len(data))
+	}
+	fields, err := memoryRequest(f, address, uint32(len(data)))
+	if err != nil {
+		return err
+	}
+	req := append(append([]byte{WriteMemoryByAddress}, fields...), data...)
+	resp, err := c.request(ctx, 1+len(fields), req...)
+	if err != nil {
+		return err
+	}
+	// the response echoes the format, address and size
+	if !bytes.Equal(resp[1:1+len(fields)], fields) {
+		return fmt.Errorf("uds: WriteMemoryByAddress response % X does not match the request", resp[1:])
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package uds
+
+import "fmt"
+
+// Negative response codes.
+const (
+	GeneralReject                          = 0x10
+	ServiceNotSupported                    = 0x11
+	SubFunctionNotSupported                = 0x12
+	IncorrectMessageLengthOrInvalidFormat  = 0x13
+	ResponseTooLong                        = 0x14
+	BusyRepeatRequest                      = 0x21
+	ConditionsNotCorrect                   = 0x22
+	RequestSequenceError                   = 0x24
+	NoResponseFromSubnetComponent          = 0x25
+	FailurePreventsExecution               = 0x26
+	RequestOutOfRange                      = 0x31
+	SecurityAccessDenied                   = 0x33
+	InvalidKey                             = 0x35
+	ExceededNumberOfAttempts               = 0x36
+	RequiredTimeDelayNotExpired            = 0x37
+	UploadDownloadNotAccepted              = 0x70
+	TransferDataSuspended                  = 0x71
+	GeneralProgrammingFailure              = 0x72
+	WrongBlockSequenceCounter              = 0x73
+	ResponsePending                        = 0x78
+	SubFunctionNotSupportedInActiveSession = 0x7e
+	ServiceNotSupportedInActiveSession     = 0x7f
+)
+
+var nrcNames = map[byte]string{
+	GeneralReject:                          "generalReject",
+	ServiceNotSupported:                    "serviceNotSupported",
+	SubFunctionNotSupported:                "subFunctionNotSupported",
+	IncorrectMessageLengthOrInvalidFormat:  "incorrectMessageLengthOrInvalidFormat",
+	ResponseTooLong:                        "responseTooLong",
+	BusyRepeatRequest:                      "busyRepeatRequest",
+	ConditionsNotCorrect:                   "conditionsNotCorrect",
+	RequestSequenceError:                   "requestSequenceError",
+	NoResponseFromSubnetComponent:          "noResponseFromSubnetComponent",
+	FailurePreventsExecution:               "failurePreventsExecutionOfRequestedAction",
+	RequestOutOfRange:                      "requestOutOfRange",
+	SecurityAccessDenied:                   "securityAccessDenied",
+	InvalidKey:                             "invalidKey",
+	ExceededNumberOfAttempts:               "exceededNumberOfAttempts",
+	RequiredTimeDelayNotExpired:            "requiredTimeDelayNotExpired",
+	UploadDownloadNotAccepted:              "uploadDownloadNotAccepted",
+	TransferDataSuspended:                  "transferDataSuspended",
+	GeneralProgrammingFailure:              "generalProgrammingFailure",
+	WrongBlockSequenceCounter:              "wrongBlockSequenceCounter",
+	ResponsePending:                        "requestCorrectlyReceived-ResponsePending",
+	SubFunctionNotSupportedInActiveSession: "subFunctionNotSupportedInActiveSession",
+	ServiceNotSupportedInActiveSession:     "serviceNotSupportedInActiveSession",
+}
+
+var serviceNames = map[byte]string{
+	DiagnosticSessionControl: "DiagnosticSessionControl",
+	ECUReset:                 "ECUReset",
+	ClearDiagnosticInfo:      "ClearDiagnosticInformation",
+	ReadDTCInformation:       "ReadDTCInformation",
+	ReadDataByIdentifier:     "ReadDataByIdentifier",
+	ReadMemoryByAddress:      "ReadMemoryByAddress",
+	SecurityAccess:           "SecurityAccess",
+	WriteDataByIdentifier:    "WriteDataByIdentifier",
+	RoutineControl:           "RoutineControl",
+	RequestDownload:          "RequestDownload",
+	TransferData:             "TransferData",
+	RequestTransferExit:      "RequestTransferExit",
+	WriteMemoryByAddress:     "WriteMemoryByAddress",
+	TesterPresent:            "TesterPresent",
+}
+
+// NRCName returns the ISO 14229 name of a negative response code.
+func NRCName(code byte) string {
+	if name, ok := nrcNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("NRC 0x%02X", code)
+}
+
+// ServiceName returns the ISO 14229 name of a service identifier.
+func ServiceName(sid byte) string {
+	if name, ok := serviceNames[sid]; ok {
+		return name
+	}
+	return fmt.Sprintf("service 0x%02X", sid)
+}
+
+// NegativeResponseError is a negative response of the server.
+type NegativeResponseError struct {
+	Service byte
+	Code    byte
+}
+
+func (e *NegativeResponseError) Error() string {
+	return fmt.Sprintf("uds: %s rejected: %s (0x%02X)", ServiceName(e.Service), NRCName(e.Code), e.Code)
+}
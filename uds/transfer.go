@@ -0,0 +1,121 @@
+package uds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// DefaultTransferRetries is the number of times Download repeats a failed TransferData block.
+const DefaultTransferRetries = 3
+
+// RequestDownload announces the download of size bytes to address with 4-byte
+// address and size fields and returns the maximum length of a TransferData
+// request reported by the server, including the SID and the block counter.
+// dataFormat is the compression and encryption method, 0 for none.
+func (c *Client) RequestDownload(ctx context.Context, dataFormat byte, address, size uint32) (int, error) {
+	resp, err := c.request(ctx, 3, RequestDownload, dataFormat, 0x44,
+		byte(address>>24), byte(address>>16), byte(address>>8), byte(address),
+		byte(size>>24), byte(size>>16), byte(size>>8), byte(size))
+	if err != nil {
+		return 0, err
+	}
+	n := int(resp[1] >> 4)
+	if n == 0 || n > 4 || len(resp) < 2+n {
+		return 0, fmt.Errorf("uds: malformed RequestDownload response % X", resp)
+	}
+	max := 0
+	for _, b := range resp[2 : 2+n] {
+		max = max<<8 | int(b)
+	}
+	if max < 3 {
+		return 0, fmt.Errorf("uds: maximum block length %d is too small", max)
+	}
+	return max, nil
+}
+
+// TransferData sends a block of a download with its block sequence counter and
+// returns the transferResponseParameterRecord of the response.
+func (c *Client) TransferData(ctx context.Context, counter byte, data []byte) ([]byte, error) {
+	resp, err := c.request(ctx, 2, append([]byte{TransferData, counter}, data...)...)
+	if err != nil {
+		return nil, err
+	}
+	if resp[1] != counter {
+		return nil, fmt.Errorf("uds: response for block 0x%02X, sent 0x%02X", resp[1], counter)
+	}
+	return resp[2:], nil
+}
+
+// RequestTransferExit ends a download and returns the transferResponseParameterRecord of the response.
+func (c *Client) RequestTransferExit(ctx context.Context, params []byte) ([]byte, error) {
+	resp, err := c.request(ctx, 1, append([]byte{RequestTransferExit}, params...)...)
+	if err != nil {
+		return nil, err
+	}
+	return resp[1:], nil
+}
+
+// StartRoutine starts the routine id (RoutineControl, startRoutine) with params and
+// returns the routineStatusRecord of the response.
+func (c *Client) StartRoutine(ctx context.Context, id uint16, params []byte) ([]byte, error) {
+	resp, err := c.request(ctx, 4, append([]byte{RoutineControl, 0x01, byte(id >> 8), byte(id)}, params...)...)
+	if err != nil {
+		return nil, err
+	}
+	if got := uint16(resp[2])<<8 | uint16(resp[3]); resp[1] != 0x01 || got != id {
+		return nil, fmt.Errorf("uds: response for routine 0x%02X 0x%04X, requested 0x01 0x%04X", resp[1], got, id)
+	}
+	return resp[4:], nil
+}
+
+// Transfer is a download of Data to Address run by Download.
+type Transfer struct {
+	Address uint32
+	Data    []byte
+	// DataFormat is the dataFormatIdentifier of RequestDownload, 0 for none.
+	DataFormat byte
+	// Offset is the number of bytes of Data already transferred. Download sends the
+	// rest and updates it after each block, so a failed transfer resumes where it stopped.
+	Offset int
+	// Retries is the number of times a failed block is repeated, 0 for
+	// DefaultTransferRetries and negative for none.
+	Retries int
+	// Progress, if set, is called after each block with Offset.
+	Progress func(offset int)
+}
+
+// Download transfers the rest of t.Data with RequestDownload, TransferData blocks
+// as large as the server accepts, and RequestTransferExit.
+// A block which fails is repeated with the same counter, as ISO 14229 allows.
+func (c *Client) Download(ctx context.Context, t *Transfer) error {
+	if t.Offset < 0 || t.Offset >= len(t.Data) {
+		return errors.New("uds: nothing to download")
+	}
+	retries := t.Retries
+	if retries == 0 {
+		retries = DefaultTransferRetries
+	}
+	max, err := c.RequestDownload(ctx, t.DataFormat, t.Address+uint32(t.Offset), uint32(len(t.Data)-t.Offset))
+	if err != nil {
+		return err
+	}
+	block := max - 2
+	for counter := byte(1); t.Offset < len(t.Data); counter++ {
+		chunk := t.Data[t.Offset:min(t.Offset+block, len(t.Data))]
+		for attempt := 0; ; attempt++ {
+			if _, err = c.TransferData(ctx, counter, chunk); err == nil {
+				break
+			}
+			if attempt >= retries || ctx.Err() != nil {
+				return fmt.Errorf("uds: block 0x%02X at offset %d: %w", counter, t.Offset, err)
+			}
+		}
+		t.Offset += len(chunk)
+		if t.Progress != nil {
+			t.Progress(t.Offset)
+		}
+	}
+	_, err = c.RequestTransferExit(ctx, nil)
+	return err
+}
@@ -0,0 +1,358 @@
+// Package uds implements a basic ISO 14229 (UDS) diagnostic client.
+//
+// Requests are sent on a Transport, usually an ISO-TP channel, and responses
+// are fed back with HandleMessage. A request waits for the positive response
+// of its service or a negative response to it; "response pending" (NRC 0x78)
+// answers extend the wait from P2 to P2*.
+package uds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Service identifiers.
+const (
+	DiagnosticSessionControl = 0x10
+	ECUReset                 = 0x11
+	ClearDiagnosticInfo      = 0x14
+	ReadDTCInformation       = 0x19
+	ReadDataByIdentifier     = 0x22
+	ReadMemoryByAddress      = 0x23
+	SecurityAccess           = 0x27
+	WriteDataByIdentifier    = 0x2e
+	RoutineControl           = 0x31
+	RequestDownload          = 0x34
+	TransferData             = 0x36
+	RequestTransferExit      = 0x37
+	WriteMemoryByAddress     = 0x3d
+	TesterPresent            = 0x3e
+
+	// negativeResponse is the SID of a negative response.
+	negativeResponse = 0x7f
+	// positiveOffset is added to a request SID to form the positive response SID.
+	positiveOffset = 0x40
+)
+
+const (
+	// DefaultP2 is the default time the server has to start its response.
+	DefaultP2 = time.Second
+	// DefaultP2Star is the default timeout after a "response pending" answer.
+	DefaultP2Star = 5 * time.Second
+)
+
+// ErrTimeout is returned when the server does not answer within P2 (or P2* when pending).
+var ErrTimeout = errors.New("uds: response timeout")
+
+// Transport sends diagnostic messages to the server.
+type Transport interface {
+	Send(ctx context.Context, data []byte) error
+}
+
+// Client sends UDS requests one at a time.
+type Client struct {
+	tr Transport
+	// P2 is the time the server has to answer a request, P2Star the time after a
+	// "response pending" answer. Zero selects DefaultP2 and DefaultP2Star.
+	// DiagnosticSessionControl updates them with the timing reported by the server.
+	P2     time.Duration
+	P2Star time.Duration
+
+	// reqMu serializes requests.
+	reqMu sync.Mutex
+
+	mu sync.Mutex
+	// sid is the service of the outstanding request, 0 when idle.
+	sid  byte
+	resp chan []byte
+	// last is when the last request was sent, session the session the
+	// server was last switched to, 0 for DefaultSession.
+	last    time.Time
+	session byte
+	// observer is the function of Observe.
+	observer func(Exchange)
+}
+
+// NewClient returns a client that sends requests on tr.
+func NewClient(tr Transport) *Client {
+	return &Client{tr: tr}
+}
+
+// HandleMessage passes a message received from the server to the client.
+// It reports whether the message answers the outstanding request.
+func (c *Client) HandleMessage(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sid == 0 || !matches(c.sid, data) {
+		return false
+	}
+	msg := append([]byte(nil), data...)
+	select {
+	case c.resp <- msg:
+	default:
+	}
+	return true
+}
+
+func matches(sid byte, data []byte) bool {
+	if data[0] == sid+positiveOffset {
+		return true
+	}
+	return data[0] == negativeResponse && len(data) >= 2 && data[1] == sid
+}
+
+// Request sends a raw request and returns the positive response including its SID.
+// A negative response is returned as a *NegativeResponseError.
+func (c *Client) Request(ctx context.Context, req []byte) ([]byte, error) {
+	if len(req) == 0 {
+		return nil, errors.New("uds: empty request")
+	}
+	sid := req[0]
+	if sid == negativeResponse || sid >= positiveOffset && sid < 0x80 {
+		return nil, fmt.Errorf("uds: invalid service 0x%02X", sid)
+	}
+
+	c.reqMu.Lock()
+	defer c.reqMu.Unlock()
+	return c.do(ctx, req)
+}
+
+// do sends a request and waits for its response, with reqMu held.
+func (c *Client) do(ctx context.Context, req []byte) ([]byte, error) {
+	x := Exchange{Request: append([]byte(nil), req...), Sent: time.Now()}
+	resp, err := c.exchange(ctx, req, &x)
+	x.Err = err
+	c.observe(x)
+	return resp, err
+}
+
+// exchange sends a request and waits for its response, noting them in x.
+func (c *Client) exchange(ctx context.Context, req []byte, x *Exchange) ([]byte, error) {
+	sid := req[0]
+	resp := make(chan []byte, 4)
+	c.mu.Lock()
+	c.sid = sid
+	c.resp = resp
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.sid = 0
+		c.resp = nil
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(ctx, req); err != nil {
+		return nil, err
+	}
+
+	timeout := c.p2()
+	for {
+		timer := time.NewTimer(timeout)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+			return nil, ErrTimeout
+		case msg := <-resp:
+			timer.Stop()
+			if msg[0] != negativeResponse {
+				x.Response, x.Received = msg, time.Now()
+				c.noteSession(req)
+				return msg, nil
+			}
+			var code byte
+			if len(msg) >= 3 {
+				code = msg[2]
+			}
+			if code == ResponsePending {
+				x.Pending++
+				timeout = c.p2Star()
+				continue
+			}
+			x.Response, x.Received = msg, time.Now()
+			return nil, &NegativeResponseError{Service: sid, Code: code}
+		}
+	}
+}
+
+func (c *Client) p2() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.P2 > 0 {
+		return c.P2
+	}
+	return DefaultP2
+}
+
+func (c *Client) p2Star() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.P2Star > 0 {
+		return c.P2Star
+	}
+	return DefaultP2Star
+}
+
+// request sends a service request and checks that the positive response is at least minLen bytes long.
+func (c *Client) request(ctx context.Context, minLen int, req ...byte) ([]byte, error) {
+	resp, err := c.Request(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < minLen {
+		return nil, fmt.Errorf("uds: %s response too short (%d bytes)", ServiceName(req[0]), len(resp))
+	}
+	return resp, nil
+}
+
+// SessionTiming holds the P2 and P2* values reported by DiagnosticSessionControl.
+type SessionTiming struct {
+	P2     time.Duration
+	P2Star time.Duration
+}
+
+// DiagnosticSessionControl switches the server into session (1 default, 2 programming,
+// 3 extended) and returns the session timing reported by the server, if any.
+// The reported timing is used for the following requests.
+func (c *Client) DiagnosticSessionControl(ctx context.Context, session byte) (SessionTiming, error) {
+	resp, err := c.request(ctx, 2, DiagnosticSessionControl, session)
+	if err != nil {
+		return SessionTiming{}, err
+	}
+	if resp[1]&0x7f != session&0x7f {
+		return SessionTiming{}, fmt.Errorf("uds: response for session 0x%02X, requested 0x%02X", resp[1], session)
+	}
+	var t SessionTiming
+	if len(resp) >= 6 {
+		t.P2 = time.Duration(uint16(resp[2])<<8|uint16(resp[3])) * time.Millisecond
+		t.P2Star = time.Duration(uint16(resp[4])<<8|uint16(resp[5])) * 10 * time.Millisecond
+		c.mu.Lock()
+		c.P2, c.P2Star = t.P2, t.P2Star
+		c.mu.Unlock()
+	}
+	return t, nil
+}
+
+// ECUReset requests a reset of the server (1 hard, 2 key off/on, 3 soft).
+func (c *Client) ECUReset(ctx context.Context, resetType byte) error {
+	resp, err := c.request(ctx, 2, ECUReset, resetType)
+	if err != nil {
+		return err
+	}
+	if resp[1]&0x7f != resetType&0x7f {
+		return fmt.Errorf("uds: response for reset type 0x%02X, requested 0x%02X", resp[1], resetType)
+	}
+	return nil
+}
+
+// ReadDataByIdentifier reads the value of a data identifier.
+func (c *Client) ReadDataByIdentifier(ctx context.Context, did uint16) ([]byte, error) {
+	resp, err := c.request(ctx, 3, ReadDataByIdentifier, byte(did>>8), byte(did))
+	if err != nil {
+		return nil, err
+	}
+	if got := uint16(resp[1])<<8 | uint16(resp[2]); got != did {
+		return nil, fmt.Errorf("uds: response for DID 0x%04X, requested 0x%04X", got, did)
+	}
+	return resp[3:], nil
+}
+
+// TesterPresent keeps a non-default session alive.
+func (c *Client) TesterPresent(ctx context.Context) error {
+	_, err := c.request(ctx, 2, TesterPresent, 0x00)
+	return err
+}
+
+// DTC is a diagnostic trouble code reported by ReadDTCs.
+type DTC struct {
+	// Code is the 3-byte DTC number: 2 bytes of SAE J2012 code and the failure type byte.
+	Code uint32
+	// Status is the DTC status byte (bit 0 testFailed, bit 3 confirmedDTC, ...).
+	Status byte
+}
+
+// String formats the DTC as its SAE J2012 code followed by the failure type, eg "P0123-00".
+func (d DTC) String() string {
+	const systems = "PCBU"
+	hi := d.Code >> 16
+	return fmt.Sprintf("%c%04X-%02X", systems[hi>>6&0x3], (d.Code>>8)&0x3fff, d.Code&0xff)
+}
+
+// ReadDTCs returns the DTCs matching statusMask (ReadDTCInformation, reportDTCByStatusMask).
+func (c *Client) ReadDTCs(ctx context.Context, statusMask byte) ([]DTC, error) {
+	resp, err := c.request(ctx, 3, ReadDTCInformation, 0x02, statusMask)
+	if err != nil {
+		return nil, err
+	}
+	if resp[1] != 0x02 {
+		return nil, fmt.Errorf("uds: response for sub-function 0x%02X, requested 0x02", resp[1])
+	}
+	return ParseDTCs(resp)
+}
+
+// ParseDTCs returns the DTCs of a positive reportDTCByStatusMask response,
+// starting with its SID.
+func ParseDTCs(resp []byte) ([]DTC, error) {
+	if len(resp) < 3 {
+		return nil, fmt.Errorf("uds: DTC list response too short (%d bytes)", len(resp))
+	}
+	records := resp[3:]
+	if len(records)%4 != 0 {
+		return nil, fmt.Errorf("uds: malformed DTC list (%d bytes)", len(records))
+	}
+	dtcs := make([]DTC, 0, len(records)/4)
+	for i := 0; i < len(records); i += 4 {
+		dtcs = append(dtcs, DTC{
+			Code:   uint32(records[i])<<16 | uint32(records[i+1])<<8 | uint32(records[i+2]),
+			Status: records[i+3],
+		})
+	}
+	return dtcs, nil
+}
+
+// KeyFunc computes the key of a SecurityAccess seed for level.
+type KeyFunc func(level byte, seed []byte) ([]byte, error)
+
+// SecurityAccess unlocks the security level (odd, 0x01 0x03 ...): it requests a
+// seed, computes its key with key and sends it with level+1. An all-zero seed means
+// the level is already unlocked, key is not called then.
+func (c *Client) SecurityAccess(ctx context.Context, level byte, key KeyFunc) error {
+	if level%2 == 0 || level > 0x7d {
+		return fmt.Errorf("uds: invalid security level 0x%02X, want an odd requestSeed level", level)
+	}
+	resp, err := c.request(ctx, 2, SecurityAccess, level)
+	if err != nil {
+		return err
+	}
+	if resp[1] != level {
+		return fmt.Errorf("uds: response for security level 0x%02X, requested 0x%02X", resp[1], level)
+	}
+	seed := resp[2:]
+	locked := false
+	for _, b := range seed {
+		locked = locked || b != 0
+	}
+	if !locked {
+		return nil
+	}
+	k, err := key(level, seed)
+	if err != nil {
+		return fmt.Errorf("uds: key for seed % X: %w", seed, err)
+	}
+	resp, err = c.request(ctx, 2, append([]byte{SecurityAccess, level + 1}, k...)...)
+	if err != nil {
+		return err
+	}
+	if resp[1] != level+1 {
+		return fmt.Errorf("uds: response for security level 0x%02X, requested 0x%02X", resp[1], level+1)
+	}
+	return nil
+}
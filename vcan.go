@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"canproject/canbus"
+)
+
+// CreateVcan creates a virtual CAN interface, eg vcan0, and brings it up. The new
+// interface list is emitted on "can:interfaces".
+//
+// Creating interfaces requires CAP_NET_ADMIN. Without it the app falls back to
+// running ip(8) through pkexec, which asks for a password with the polkit agent of
+// the desktop session, or through "sudo -n", which only works with a NOPASSWD
+// rule. Granting the binary the capability avoids the prompt:
+//
+//	sudo setcap cap_net_admin+ep ./CanSocket
+func (a *App) CreateVcan(name string) error {
+	name = strings.TrimSpace(name)
+	err := canbus.CreateVirtualLink(name)
+	if errors.Is(err, os.ErrPermission) {
+		err = privilegedIP(err, "link", "add", "dev", name, "type", "vcan")
+		if err == nil {
+			err = privilegedIP(nil, "link", "set", name, "up")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	a.emitInterfaces()
+	return nil
+}
+
+// DeleteVcan deletes a virtual CAN interface, stopping it first if it is started.
+// The new interface list is emitted on "can:interfaces". It needs the same
+// privileges as CreateVcan.
+func (a *App) DeleteVcan(name string) error {
+	name = strings.TrimSpace(name)
+	l, err := canbus.LinkByName(name)
+	if err != nil {
+		return err
+	}
+	if l.Kind != "vcan" {
+		return fmt.Errorf("%s is not a virtual CAN interface (kind %q)", name, l.Kind)
+	}
+	if err := a.StopCAN(name); err != nil {
+		return err
+	}
+	err = canbus.DeleteVirtualLink(name)
+	if errors.Is(err, os.ErrPermission) {
+		err = privilegedIP(err, "link", "delete", name)
+	}
+	if err != nil {
+		return err
+	}
+	a.emitInterfaces()
+	return nil
+}
+
+// emitInterfaces emits the interface list on "can:interfaces" after it changed.
+func (a *App) emitInterfaces() {
+	infos, err := a.ListCANInterfaces()
+	if err != nil {
+		a.emitError(err)
+		return
+	}
+	a.emit("can:interfaces", infos)
+}
+
+// privilegedIP runs "ip args..." with pkexec, or "sudo -n" when pkexec is not
+// installed. denied is the error of the unprivileged attempt, returned when
+// neither helper is available.
+func privilegedIP(denied error, args ...string) error {
+	ip, err := exec.LookPath("ip")
+	if err != nil {
+		ip = "/sbin/ip"
+	}
+	var cmd *exec.Cmd
+	switch {
+	case hasCommand("pkexec"):
+		cmd = exec.Command("pkexec", append([]string{ip}, args...)...)
+	case hasCommand("sudo"):
+		cmd = exec.Command("sudo", append([]string{"-n", ip}, args...)...)
+	default:
+		if denied == nil {
+			denied = errors.New("no privilege helper (pkexec or sudo) found")
+		}
+		return denied
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("ip %s: %s", strings.Join(args, " "), msg)
+		}
+		return fmt.Errorf("ip %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+func hasCommand(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"canproject/canbus"
+	"canproject/xcp"
+)
+
+// XCPSessionInfo describes a connected XCP slave.
+type XCPSessionInfo struct {
+	Handle    int    `json:"handle"`
+	Interface string `json:"interface"`
+	CRO       uint32 `json:"cro"`
+	DTO       uint32 `json:"dto"`
+	Extended  bool   `json:"extended"`
+	// CalPag, DAQ and Pgm are the resources of the slave.
+	CalPag             bool  `json:"calPag"`
+	DAQ                bool  `json:"daq"`
+	Pgm                bool  `json:"pgm"`
+	BigEndian          bool  `json:"bigEndian"`
+	AddressGranularity int   `json:"addressGranularity"`
+	MaxCTO             int   `json:"maxCto"`
+	MaxDTO             int   `json:"maxDto"`
+	ProtocolVersion    uint8 `json:"protocolVersion"`
+}
+
+// XCPDAQList is a DAQ list of XCPSetupDAQ.
+type XCPDAQList struct {
+	// Event is the event channel of the slave the list is sampled on.
+	Event     uint16        `json:"event"`
+	Prescaler uint8         `json:"prescaler"`
+	Priority  uint8         `json:"priority"`
+	Entries   []XCPDAQEntry `json:"entries"`
+}
+
+// XCPDAQEntry is a memory element of a DAQ list.
+type XCPDAQEntry struct {
+	Name      string `json:"name"`
+	Address   uint32 `json:"address"`
+	Extension uint8  `json:"extension"`
+	// Size is the size of the element in bytes.
+	Size int `json:"size"`
+}
+
+// XCPDAQEvent is a DAQ packet emitted on "xcp:daq".
+type XCPDAQEvent struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Handle    int           `json:"handle"`
+	List      int           `json:"list"`
+	ODT       int           `json:"odt"`
+	Values    []XCPDAQValue `json:"values"`
+}
+
+// XCPDAQValue is a sampled element of a DAQ packet.
+type XCPDAQValue struct {
+	Name    string   `json:"name"`
+	Address uint32   `json:"address"`
+	Data    []uint32 `json:"data"`
+	// Value is the element as an unsigned integer in the byte order of the slave,
+	// for elements of 1, 2, 4 or 8 bytes.
+	Value float64 `json:"value"`
+}
+
+type xcpSession struct {
+	info   XCPSessionInfo
+	master *xcp.Master
+	// names are the entry names by list and address.
+	names []map[uint32]string
+}
+
+// XCPConnect connects to an XCP on CAN slave that receives commands on croID and
+// answers on dtoID, and returns a handle for the other XCP methods.
+func (a *App) XCPConnect(iface string, croID uint32, dtoID uint32, extended bool) (XCPSessionInfo, error) {
+	iface = strings.TrimSpace(iface)
+	if _, err := a.txConn(iface, false); err != nil {
+		return XCPSessionInfo{}, err
+	}
+
+	s := &xcpSession{info: XCPSessionInfo{Interface: iface, CRO: croID, DTO: dtoID, Extended: extended}}
+	s.master = xcp.NewMaster(xcp.Config{
+		CRO:      croID,
+		DTO:      dtoID,
+		Extended: extended,
+		OnDAQ:    func(p xcp.DAQPacket) { a.emitXCPDAQ(s, p) },
+	}, func(f canbus.Frame) error {
+		return a.transmit(iface, f)
+	})
+
+	a.xcpMu.Lock()
+	for _, other := range a.xcpSessions {
+		if other.info.Interface == iface && other.info.DTO == dtoID && other.info.Extended == extended {
+			a.xcpMu.Unlock()
+			return XCPSessionInfo{}, fmt.Errorf("an XCP session already receives on 0x%X on %s", dtoID, iface)
+		}
+	}
+	a.nextXCP++
+	s.info.Handle = a.nextXCP
+	a.xcpSessions[s.info.Handle] = s
+	a.xcpMu.Unlock()
+
+	info, err := s.master.Connect(context.Background())
+	if err != nil {
+		a.xcpMu.Lock()
+		delete(a.xcpSessions, s.info.Handle)
+		a.xcpMu.Unlock()
+		return XCPSessionInfo{}, err
+	}
+	a.xcpMu.Lock()
+	defer a.xcpMu.Unlock()
+	s.info.CalPag, s.info.DAQ, s.info.Pgm = info.CalPag, info.DAQ, info.Pgm
+	s.info.BigEndian = info.BigEndian
+	s.info.AddressGranularity = info.AddressGranularity
+	s.info.MaxCTO, s.info.MaxDTO = info.MaxCTO, info.MaxDTO
+	s.info.ProtocolVersion = info.ProtocolVersion
+	return s.info, nil
+}
+
+// XCPDisconnect disconnects from the slave and closes the session.
+func (a *App) XCPDisconnect(handle int) error {
+	s, err := a.xcpSession(handle)
+	if err != nil {
+		return err
+	}
+	a.xcpMu.Lock()
+	delete(a.xcpSessions, handle)
+	a.xcpMu.Unlock()
+	return s.master.Disconnect(context.Background())
+}
+
+// ListXCPSessions returns the connected XCP slaves ordered by handle.
+func (a *App) ListXCPSessions() []XCPSessionInfo {
+	a.xcpMu.Lock()
+	defer a.xcpMu.Unlock()
+
+	infos := make([]XCPSessionInfo, 0, len(a.xcpSessions))
+	for _, s := range a.xcpSessions {
+		infos = append(infos, s.info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Handle < infos[j].Handle })
+	return infos
+}
+
+// XCPShortUpload reads size address units of the slave memory at address with
+// SHORT_UPLOAD commands.
+func (a *App) XCPShortUpload(handle int, address uint32, extension uint8, size int) ([]uint32, error) {
+	s, err := a.xcpSession(handle)
+	if err != nil {
+		return nil, err
+	}
+	if size < 1 {
+		return nil, fmt.Errorf("size must be >= 1 (got %d)", size)
+	}
+	data, err := s.master.Upload(context.Background(), address, extension, size)
+	if err != nil {
+		return nil, err
+	}
+	return dataWords(data), nil
+}
+
+// XCPDownload writes data to the slave memory at address. Classic CAN frames are too
+// short for SHORT_DOWNLOAD, so SET_MTA and DOWNLOAD commands are used.
+func (a *App) XCPDownload(handle int, address uint32, extension uint8, data []byte) error {
+	s, err := a.xcpSession(handle)
+	if err != nil {
+		return err
+	}
+	return s.master.Download(context.Background(), address, extension, data)
+}
+
+// XCPSetupDAQ configures dynamic DAQ lists on the slave, replacing the lists set up
+// before. XCPStartDAQ starts them; the samples are emitted on "xcp:daq".
+func (a *App) XCPSetupDAQ(handle int, lists []XCPDAQList) error {
+	s, err := a.xcpSession(handle)
+	if err != nil {
+		return err
+	}
+	daq := make([]xcp.List, len(lists))
+	names := make([]map[uint32]string, len(lists))
+	for i, l := range lists {
+		daq[i] = xcp.List{Event: l.Event, Prescaler: l.Prescaler, Priority: l.Priority}
+		names[i] = make(map[uint32]string)
+		for _, e := range l.Entries {
+			daq[i].Entries = append(daq[i].Entries, xcp.Entry{Address: e.Address, Extension: e.Extension, Size: e.Size})
+			names[i][e.Address] = e.Name
+		}
+	}
+	if err := s.master.SetupDAQ(context.Background(), daq); err != nil {
+		return err
+	}
+	a.xcpMu.Lock()
+	s.names = names
+	a.xcpMu.Unlock()
+	return nil
+}
+
+// XCPStartDAQ starts the DAQ lists set up with XCPSetupDAQ.
+func (a *App) XCPStartDAQ(handle int) error {
+	s, err := a.xcpSession(handle)
+	if err != nil {
+		return err
+	}
+	return s.master.StartDAQ(context.Background())
+}
+
+// XCPStopDAQ stops the DAQ lists of the slave.
+func (a *App) XCPStopDAQ(handle int) error {
+	s, err := a.xcpSession(handle)
+	if err != nil {
+		return err
+	}
+	return s.master.StopDAQ(context.Background())
+}
+
+func (a *App) xcpSession(handle int) (*xcpSession, error) {
+	a.xcpMu.Lock()
+	defer a.xcpMu.Unlock()
+
+	s := a.xcpSessions[handle]
+	if s == nil {
+		return nil, fmt.Errorf("no XCP session with handle %d", handle)
+	}
+	return s, nil
+}
+
+func (a *App) emitXCPDAQ(s *xcpSession, p xcp.DAQPacket) {
+	a.xcpMu.Lock()
+	var names map[uint32]string
+	if p.List < len(s.names) {
+		names = s.names[p.List]
+	}
+	order := binary.ByteOrder(binary.LittleEndian)
+	if s.info.BigEndian {
+		order = binary.BigEndian
+	}
+	a.xcpMu.Unlock()
+
+	ev := XCPDAQEvent{
+		Timestamp: time.Now(),
+		Handle:    s.info.Handle,
+		List:      p.List,
+		ODT:       p.ODT,
+		Values:    make([]XCPDAQValue, len(p.Entries)),
+	}
+	for i, e := range p.Entries {
+		v := XCPDAQValue{Name: names[e.Address], Address: e.Address, Data: dataWords(p.Values[i])}
+		switch data := p.Values[i]; len(data) {
+		case 1:
+			v.Value = float64(data[0])
+		case 2:
+			v.Value = float64(order.Uint16(data))
+		case 4:
+			v.Value = float64(order.Uint32(data))
+		case 8:
+			v.Value = float64(order.Uint64(data))
+		}
+		ev.Values[i] = v
+	}
+	a.emit("xcp:daq", ev)
+}
+
+// dispatchXCP hands a received frame to the XCP sessions of iface.
+func (a *App) dispatchXCP(iface string, f *canbus.Frame) {
+	a.xcpMu.Lock()
+	var masters []*xcp.Master
+	for _, s := range a.xcpSessions {
+		if s.info.Interface == iface {
+			masters = append(masters, s.master)
+		}
+	}
+	a.xcpMu.Unlock()
+
+	for _, m := range masters {
+		if m.HandleFrame(f) {
+			return
+		}
+	}
+}
+
+// closeXCPSessions closes every XCP session on iface.
+func (a *App) closeXCPSessions(iface string) {
+	a.xcpMu.Lock()
+	defer a.xcpMu.Unlock()
+
+	for handle, s := range a.xcpSessions {
+		if s.info.Interface == iface {
+			delete(a.xcpSessions, handle)
+		}
+	}
+}
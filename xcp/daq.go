@@ -0,0 +1,184 @@
+package xcp
+
+import (
+	"context"
+	"fmt"
+)
+
+// DAQ list modes of SET_DAQ_LIST_MODE and START_STOP_DAQ_LIST.
+const (
+	daqStop   = 0x00
+	daqStart  = 0x01
+	daqSelect = 0x02
+)
+
+// Entry is a memory element sampled by a DAQ list.
+type Entry struct {
+	Address   uint32
+	Extension uint8
+	// Size is the size of the element in bytes, a multiple of the address granularity.
+	Size int
+}
+
+// List is a DAQ list sampled on an event channel of the slave.
+type List struct {
+	Event     uint16
+	Prescaler uint8
+	Priority  uint8
+	Entries   []Entry
+}
+
+// DAQPacket is a DAQ packet (one ODT) received from the slave.
+type DAQPacket struct {
+	// List is the index of the list in the lists of SetupDAQ, ODT the index of the ODT.
+	List int
+	ODT  int
+	// Entries are the entries of the ODT, Values their data.
+	Entries []Entry
+	Values  [][]byte
+}
+
+type daqList struct {
+	firstPID int
+	// odts are the entries of the list packed into ODTs.
+	odts [][]Entry
+}
+
+// SetupDAQ configures dynamic DAQ lists: the entries of each list are packed into as
+// few ODTs as fit into a DTO, the lists are allocated and written and selected for
+// StartDAQ. It replaces the lists configured before.
+func (m *Master) SetupDAQ(ctx context.Context, lists []List) error {
+	info, err := m.slave()
+	if err != nil {
+		return err
+	}
+	if len(lists) == 0 {
+		return fmt.Errorf("xcp: no DAQ lists")
+	}
+	order := info.order()
+	g := info.AddressGranularity
+	// the PID takes the first address unit of a DTO
+	odtSize := info.MaxDTO - g
+
+	daq := make([]daqList, len(lists))
+	for i, l := range lists {
+		if len(l.Entries) == 0 {
+			return fmt.Errorf("xcp: DAQ list %d has no entries", i)
+		}
+		var odt []Entry
+		used := 0
+		for _, e := range l.Entries {
+			if e.Size <= 0 || e.Size%g != 0 || e.Size > odtSize || e.Size/g > 0xff {
+				return fmt.Errorf("xcp: invalid element size %d at 0x%X", e.Size, e.Address)
+			}
+			if used+e.Size > odtSize {
+				daq[i].odts = append(daq[i].odts, odt)
+				odt, used = nil, 0
+			}
+			odt = append(odt, e)
+			used += e.Size
+		}
+		daq[i].odts = append(daq[i].odts, odt)
+	}
+
+	cmd := func(req ...byte) ([]byte, error) { return m.command(ctx, req, 1) }
+	u16 := func(v int) []byte { b := make([]byte, 2); order.PutUint16(b, uint16(v)); return b }
+
+	m.mu.Lock()
+	m.daq = nil
+	m.mu.Unlock()
+	if _, err := cmd(cmdFreeDAQ); err != nil {
+		return err
+	}
+	if _, err := cmd(append([]byte{cmdAllocDAQ, 0}, u16(len(daq))...)...); err != nil {
+		return err
+	}
+	for i, l := range daq {
+		if _, err := cmd(append(append([]byte{cmdAllocODT, 0}, u16(i)...), byte(len(l.odts)))...); err != nil {
+			return err
+		}
+	}
+	for i, l := range daq {
+		for j, odt := range l.odts {
+			if _, err := cmd(append(append([]byte{cmdAllocODTEntry, 0}, u16(i)...), byte(j), byte(len(odt)))...); err != nil {
+				return err
+			}
+		}
+	}
+	for i, l := range daq {
+		for j, odt := range l.odts {
+			if _, err := cmd(append(append([]byte{cmdSetDAQPtr, 0}, u16(i)...), byte(j), 0)...); err != nil {
+				return err
+			}
+			for _, e := range odt {
+				req := []byte{cmdWriteDAQ, 0xff, byte(e.Size / g), e.Extension, 0, 0, 0, 0}
+				order.PutUint32(req[4:], e.Address)
+				if _, err := cmd(req...); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for i, l := range lists {
+		req := append(append([]byte{cmdSetDAQListMode, 0}, u16(i)...), u16(int(l.Event))...)
+		if _, err := cmd(append(req, max(l.Prescaler, 1), l.Priority)...); err != nil {
+			return err
+		}
+		resp, err := m.command(ctx, append([]byte{cmdStartStopDAQList, daqSelect}, u16(i)...), 2)
+		if err != nil {
+			return err
+		}
+		daq[i].firstPID = int(resp[1])
+	}
+
+	m.mu.Lock()
+	m.daq = daq
+	m.mu.Unlock()
+	return nil
+}
+
+// StartDAQ starts the lists configured with SetupDAQ.
+func (m *Master) StartDAQ(ctx context.Context) error {
+	m.mu.Lock()
+	configured := m.daq != nil
+	m.mu.Unlock()
+	if !configured {
+		return fmt.Errorf("xcp: no DAQ lists configured")
+	}
+	_, err := m.command(ctx, []byte{cmdStartStopSynch, daqStart}, 1)
+	return err
+}
+
+// StopDAQ stops all the DAQ lists.
+func (m *Master) StopDAQ(ctx context.Context) error {
+	_, err := m.command(ctx, []byte{cmdStartStopSynch, daqStop}, 1)
+	return err
+}
+
+// handleDAQ delivers a DAQ packet with the absolute ODT number pid.
+func (m *Master) handleDAQ(pid byte, data []byte) {
+	m.mu.Lock()
+	daq, info := m.daq, m.info
+	m.mu.Unlock()
+	if m.cfg.OnDAQ == nil || info == nil {
+		return
+	}
+	// the PID is followed by fill bytes up to the address granularity
+	data = data[min(len(data), info.AddressGranularity-1):]
+	for i, l := range daq {
+		odt := int(pid) - l.firstPID
+		if odt < 0 || odt >= len(l.odts) {
+			continue
+		}
+		p := DAQPacket{List: i, ODT: odt, Entries: l.odts[odt]}
+		for _, e := range p.Entries {
+			if len(data) < e.Size {
+				return
+			}
+			p.Values = append(p.Values, append([]byte(nil), data[:e.Size]...))
+			data = data[e.Size:]
+		}
+		m.cfg.OnDAQ(p)
+		return
+	}
+}
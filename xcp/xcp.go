@@ -0,0 +1,341 @@
+// Package xcp implements a basic XCP on CAN master (ASAM MCD-1 XCP 1.x): connect,
+// memory upload and download and dynamic DAQ lists with absolute ODT numbers.
+//
+// A Master sends command packets (CTO) on the CRO ID and receives responses and
+// DAQ packets (DTO) from the slave on the DTO ID. Received frames are fed to the
+// master with HandleFrame; DAQ packets are delivered to Config.OnDAQ.
+package xcp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"canproject/canbus"
+)
+
+// command codes.
+const (
+	cmdConnect          = 0xff
+	cmdDisconnect       = 0xfe
+	cmdGetStatus        = 0xfd
+	cmdSetMTA           = 0xf6
+	cmdShortUpload      = 0xf4
+	cmdDownload         = 0xf0
+	cmdSetDAQPtr        = 0xe2
+	cmdWriteDAQ         = 0xe1
+	cmdSetDAQListMode   = 0xe0
+	cmdStartStopDAQList = 0xde
+	cmdStartStopSynch   = 0xdd
+	cmdFreeDAQ          = 0xd6
+	cmdAllocDAQ         = 0xd5
+	cmdAllocODT         = 0xd4
+	cmdAllocODTEntry    = 0xd3
+)
+
+// packet identifiers of slave to master packets.
+const (
+	pidResponse = 0xff
+	pidError    = 0xfe
+	pidEvent    = 0xfd
+	pidService  = 0xfc
+)
+
+// DefaultTimeout is the default timeout of a command (XCP t1).
+const DefaultTimeout = 100 * time.Millisecond
+
+// ErrTimeout is returned when the slave does not answer a command in time.
+var ErrTimeout = errors.New("xcp: timeout")
+
+// ErrNotConnected is returned by commands sent before Connect.
+var ErrNotConnected = errors.New("xcp: not connected")
+
+var errorNames = map[byte]string{
+	0x00: "ERR_CMD_SYNCH",
+	0x10: "ERR_CMD_BUSY",
+	0x11: "ERR_DAQ_ACTIVE",
+	0x12: "ERR_PGM_ACTIVE",
+	0x20: "ERR_CMD_UNKNOWN",
+	0x21: "ERR_CMD_SYNTAX",
+	0x22: "ERR_OUT_OF_RANGE",
+	0x23: "ERR_WRITE_PROTECTED",
+	0x24: "ERR_ACCESS_DENIED",
+	0x25: "ERR_ACCESS_LOCKED",
+	0x26: "ERR_PAGE_NOT_VALID",
+	0x27: "ERR_MODE_NOT_VALID",
+	0x28: "ERR_SEGMENT_NOT_VALID",
+	0x29: "ERR_SEQUENCE",
+	0x2a: "ERR_DAQ_CONFIG",
+	0x30: "ERR_MEMORY_OVERFLOW",
+	0x31: "ERR_GENERIC",
+	0x32: "ERR_VERIFY",
+}
+
+// Error is an error packet of the slave.
+type Error struct {
+	Command byte
+	Code    byte
+}
+
+func (e *Error) Error() string {
+	name, ok := errorNames[e.Code]
+	if !ok {
+		name = fmt.Sprintf("0x%02X", e.Code)
+	}
+	return fmt.Sprintf("xcp: command 0x%02X failed: %s", e.Command, name)
+}
+
+// Config describes the CAN IDs of a slave.
+type Config struct {
+	// CRO is the CAN ID of the commands, DTO the CAN ID of the slave packets.
+	CRO, DTO uint32
+	// Extended selects 29-bit CAN IDs.
+	Extended bool
+	// Timeout is the time to wait for a response, zero for DefaultTimeout.
+	Timeout time.Duration
+	// OnDAQ is called with every DAQ packet of the configured lists. It runs on the
+	// goroutine calling HandleFrame and must not block.
+	OnDAQ func(p DAQPacket)
+}
+
+// SlaveInfo is the response of CONNECT.
+type SlaveInfo struct {
+	// Resources supported by the slave.
+	CalPag, DAQ, Stim, Pgm bool
+	// BigEndian is true for Motorola byte order.
+	BigEndian bool
+	// AddressGranularity is the size in bytes of an address unit (1, 2 or 4).
+	AddressGranularity int
+	MaxCTO             int
+	MaxDTO             int
+	ProtocolVersion    uint8
+	TransportVersion   uint8
+}
+
+// Master is the XCP master of one slave. Commands are serialized.
+type Master struct {
+	cfg  Config
+	send func(canbus.Frame) error
+
+	cmd sync.Mutex
+
+	mu      sync.Mutex
+	pending chan []byte
+	info    *SlaveInfo
+	daq     []daqList
+}
+
+// NewMaster returns a master for the slave of cfg sending frames with send.
+func NewMaster(cfg Config, send func(canbus.Frame) error) *Master {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	return &Master{cfg: cfg, send: send}
+}
+
+// HandleFrame processes a frame and reports whether it was sent by the slave.
+func (m *Master) HandleFrame(f *canbus.Frame) bool {
+	if f.IsRemote || f.IsError || f.ID != m.cfg.DTO || f.IsExtended != m.cfg.Extended {
+		return false
+	}
+	data := f.Payload()
+	if len(data) == 0 {
+		return true
+	}
+	switch pid := data[0]; {
+	case pid == pidResponse || pid == pidError:
+		m.mu.Lock()
+		if m.pending != nil {
+			select {
+			case m.pending <- append([]byte(nil), data...):
+			default:
+			}
+		}
+		m.mu.Unlock()
+	case pid == pidEvent || pid == pidService:
+	default:
+		m.handleDAQ(pid, data[1:])
+	}
+	return true
+}
+
+// Connect connects to the slave in normal mode.
+func (m *Master) Connect(ctx context.Context) (SlaveInfo, error) {
+	resp, err := m.command(ctx, []byte{cmdConnect, 0x00}, 8)
+	if err != nil {
+		return SlaveInfo{}, err
+	}
+	res, mode := resp[1], resp[2]
+	info := SlaveInfo{
+		CalPag:             res&0x01 != 0,
+		DAQ:                res&0x04 != 0,
+		Stim:               res&0x08 != 0,
+		Pgm:                res&0x10 != 0,
+		BigEndian:          mode&0x01 != 0,
+		AddressGranularity: 1 << (mode >> 1 & 0x3),
+		MaxCTO:             int(resp[3]),
+		ProtocolVersion:    resp[6],
+		TransportVersion:   resp[7],
+	}
+	info.MaxDTO = int(info.order().Uint16(resp[4:]))
+	if info.MaxCTO < 8 || info.MaxDTO < 8 {
+		return SlaveInfo{}, fmt.Errorf("xcp: invalid MAX_CTO %d or MAX_DTO %d", info.MaxCTO, info.MaxDTO)
+	}
+	// CAN frames carry 8 bytes, or 64 with CAN FD which this master does not use
+	info.MaxCTO, info.MaxDTO = min(info.MaxCTO, canbus.MaxDataLength), min(info.MaxDTO, canbus.MaxDataLength)
+
+	m.mu.Lock()
+	m.info = &info
+	m.mu.Unlock()
+	return info, nil
+}
+
+// Disconnect ends the session.
+func (m *Master) Disconnect(ctx context.Context) error {
+	_, err := m.command(ctx, []byte{cmdDisconnect}, 1)
+	m.mu.Lock()
+	m.info = nil
+	m.daq = nil
+	m.mu.Unlock()
+	return err
+}
+
+// Status is the response of GET_STATUS.
+type Status struct {
+	SessionStatus  uint8
+	ProtectionMask uint8
+	ConfigID       uint16
+}
+
+// GetStatus returns the session status of the slave.
+func (m *Master) GetStatus(ctx context.Context) (Status, error) {
+	info, err := m.slave()
+	if err != nil {
+		return Status{}, err
+	}
+	resp, err := m.command(ctx, []byte{cmdGetStatus}, 6)
+	if err != nil {
+		return Status{}, err
+	}
+	return Status{SessionStatus: resp[1], ProtectionMask: resp[2], ConfigID: info.order().Uint16(resp[4:])}, nil
+}
+
+// Upload reads n address units of memory at address, with as many SHORT_UPLOAD
+// commands as needed.
+func (m *Master) Upload(ctx context.Context, address uint32, ext uint8, n int) ([]byte, error) {
+	info, err := m.slave()
+	if err != nil {
+		return nil, err
+	}
+	g := info.AddressGranularity
+	perCmd := (info.MaxCTO - 1) / g
+	var out []byte
+	for n > 0 {
+		count := min(n, perCmd)
+		req := make([]byte, 8)
+		req[0], req[1], req[3] = cmdShortUpload, byte(count), ext
+		info.order().PutUint32(req[4:], address)
+		resp, err := m.command(ctx, req, 1+count*g)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, resp[1:1+count*g]...)
+		n -= count
+		address += uint32(count)
+	}
+	return out, nil
+}
+
+// Download writes data at address with SET_MTA and DOWNLOAD commands. data must be a
+// multiple of the address granularity.
+func (m *Master) Download(ctx context.Context, address uint32, ext uint8, data []byte) error {
+	info, err := m.slave()
+	if err != nil {
+		return err
+	}
+	g := info.AddressGranularity
+	if len(data) == 0 || len(data)%g != 0 {
+		return fmt.Errorf("xcp: %d bytes are not a multiple of the address granularity %d", len(data), g)
+	}
+	req := make([]byte, 8)
+	req[0], req[3] = cmdSetMTA, ext
+	info.order().PutUint32(req[4:], address)
+	if _, err := m.command(ctx, req, 1); err != nil {
+		return err
+	}
+	// DOWNLOAD carries the command, the count and alignment padding for the granularity
+	perCmd := (info.MaxCTO - max(2, g)) / g
+	for len(data) > 0 {
+		count := min(len(data)/g, perCmd)
+		req := make([]byte, max(2, g), info.MaxCTO)
+		req[0], req[1] = cmdDownload, byte(count)
+		req = append(req, data[:count*g]...)
+		if _, err := m.command(ctx, req, 1); err != nil {
+			return err
+		}
+		data = data[count*g:]
+	}
+	return nil
+}
+
+func (m *Master) slave() (SlaveInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.info == nil {
+		return SlaveInfo{}, ErrNotConnected
+	}
+	return *m.info, nil
+}
+
+// command sends a command and waits for a positive response of at least n bytes.
+func (m *Master) command(ctx context.Context, req []byte, n int) ([]byte, error) {
+	m.cmd.Lock()
+	defer m.cmd.Unlock()
+
+	ch := make(chan []byte, 1)
+	m.mu.Lock()
+	m.pending = ch
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.pending = nil
+		m.mu.Unlock()
+	}()
+
+	f := canbus.Frame{ID: m.cfg.CRO, IsExtended: m.cfg.Extended, Length: uint8(len(req))}
+	copy(f.Data[:], req)
+	if err := m.send(f); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(m.cfg.Timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, ErrTimeout
+	case resp := <-ch:
+		if resp[0] == pidError {
+			code := byte(0x31)
+			if len(resp) > 1 {
+				code = resp[1]
+			}
+			return nil, &Error{Command: req[0], Code: code}
+		}
+		if len(resp) < n {
+			return nil, fmt.Errorf("xcp: response of command 0x%02X too short (%d bytes)", req[0], len(resp))
+		}
+		return resp, nil
+	}
+}
+
+func (info *SlaveInfo) order() binary.ByteOrder {
+	if info.BigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
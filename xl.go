@@ -0,0 +1,66 @@
+package main
+
+import (
+	"time"
+
+	"canproject/canbus"
+)
+
+// featureCANXL names CAN XL in the "can:unsupported" events.
+const featureCANXL = "CAN XL"
+
+// CANXLFrameEvent is a CAN XL frame received, emitted on "can:xl". CAN XL
+// frames are kept in the capture buffer, with the network "canxl", and not
+// decoded or logged.
+type CANXLFrameEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	// Priority is the 11-bit priority ID, VCID the virtual CAN network ID.
+	Priority uint16 `json:"priority"`
+	VCID     uint8  `json:"vcid"`
+	// SDT is the SDU type of the payload and AF the acceptance field.
+	SDT    uint8    `json:"sdt"`
+	AF     uint32   `json:"af"`
+	SEC    bool     `json:"sec"`
+	RRS    bool     `json:"rrs"`
+	Length int      `json:"length"`
+	Data   []uint32 `json:"data"`
+}
+
+// UnsupportedFeatureEvent is emitted on "can:unsupported" when an interface
+// offers a feature the kernel or the app cannot use, eg the CAN XL frames of a
+// CAN XL interface on a kernel without CAN XL sockets.
+type UnsupportedFeatureEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	Interface string    `json:"interface"`
+	Feature   string    `json:"feature"`
+	Reason    string    `json:"reason"`
+}
+
+// receiveXL keeps a received CAN XL frame in the capture buffer and emits it.
+// It runs in the receive pipeline at "xl".
+func (a *App) receiveXL(iface string, ts time.Time, xl *canbus.XLFrame) {
+	a.capture.AddPacket(ts, iface, &canbus.Packet{Network: canbus.NetworkCANXL, ID: uint32(xl.Priority), Payload: xl.Data}, false)
+	a.emit("can:xl", CANXLFrameEvent{
+		Timestamp: ts,
+		Interface: iface,
+		Priority:  xl.Priority,
+		VCID:      xl.VCID,
+		SDT:       xl.SDT,
+		AF:        xl.AF,
+		SEC:       xl.SEC,
+		RRS:       xl.RRS,
+		Length:    len(xl.Data),
+		Data:      dataWords(xl.Data),
+	})
+}
+
+// emitUnsupported reports a feature of iface that cannot be used.
+func (a *App) emitUnsupported(iface, feature string, err error) {
+	a.emit("can:unsupported", UnsupportedFeatureEvent{
+		Timestamp: time.Now(),
+		Interface: iface,
+		Feature:   feature,
+		Reason:    err.Error(),
+	})
+}